@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/cluster"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	checkpointNamespaces    []string
+	checkpointAllNamespaces bool
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Save the state of the containers of pods, to restore after a restart (experimental)",
+	Long: `Saves the state of the running containers of pods, including their memory, to the disk of the nodes.
+After 'minikube stop' and 'minikube start', 'minikube restore' brings them back to that state.
+Only the porto container runtime supports it, with --runtime-feature-gates=sandbox-checkpointing=true.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube checkpoint [-n namespace | -A]")
+		}
+		if checkpointAllNamespaces {
+			checkpointNamespaces = nil // all
+		} else if len(checkpointNamespaces) == 0 {
+			exit.Message(reason.Usage, "Use -A to specify all namespaces")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		exitUnlessSupported(co.Config, "checkpointing containers", func(c cruntime.Capabilities) bool { return c.SupportsCheckpoint })
+		count := 0
+		for _, n := range co.Config.Nodes {
+			out.Step(style.Pause, "Checkpointing node {{.name}} ...", out.V{"name": config.MachineName(*co.Config, n)})
+			cr, r := nodeRuntime(co, n)
+			cs, err := cluster.Checkpoint(cr, r, checkpointNamespaces)
+			if err != nil {
+				exitCheckpoint(co, reason.GuestCheckpoint, "Failed to checkpoint containers", err)
+			}
+			for _, c := range cs {
+				out.Styled(style.Indent, "{{.container}}", out.V{"container": c})
+			}
+			count += len(cs)
+		}
+		out.Step(style.Success, "Checkpointed {{.count}} containers", out.V{"count": count})
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the containers saved by 'minikube checkpoint' (experimental)",
+	Long: `Restores the containers of pods saved by 'minikube checkpoint' into the containers kubelet runs in their place.
+Run it once the pods are running again after 'minikube start'. Containers which are not running are skipped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube restore")
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		count := 0
+		for _, n := range co.Config.Nodes {
+			out.Step(style.Unpause, "Restoring node {{.name}} ...", out.V{"name": config.MachineName(*co.Config, n)})
+			cr, r := nodeRuntime(co, n)
+			cs, err := cluster.Restore(cr, r)
+			if err != nil {
+				exitCheckpoint(co, reason.GuestRestore, "Failed to restore containers", err)
+			}
+			for _, c := range cs {
+				out.Styled(style.Indent, "{{.container}}", out.V{"container": c})
+			}
+			count += len(cs)
+		}
+		out.Step(style.Success, "Restored {{.count}} containers", out.V{"count": count})
+	},
+}
+
+// nodeRuntime returns the container runtime of a node, with the runtime feature gates of the cluster
+func nodeRuntime(co mustload.ClusterController, n config.Node) (cruntime.Manager, command.Runner) {
+	h, err := machine.LoadHost(co.API, config.MachineName(*co.Config, n))
+	if err != nil {
+		exit.Error(reason.GuestLoadHost, "Error getting host", err)
+	}
+	r, err := machine.CommandRunner(h)
+	if err != nil {
+		exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+	}
+	gates, err := cruntime.ParseFeatureGates(co.Config.RuntimeFeatureGates)
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid runtime feature gates", err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: r, Socket: co.Config.KubernetesConfig.CRISocket, FeatureGates: gates})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+	return cr, r
+}
+
+// exitCheckpoint exits with a usage message if the runtime can't checkpoint containers, and with err otherwise
+func exitCheckpoint(co mustload.ClusterController, kind reason.Kind, msg string, err error) {
+	if errors.Is(err, cruntime.ErrCheckpointNotSupported) {
+		exit.Message(reason.Usage, "The {{.runtime}} container runtime can't checkpoint containers, only porto can", out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime})
+	}
+	exit.Error(kind, msg, err)
+}
+
+func init() {
+	checkpointCmd.Flags().StringSliceVarP(&checkpointNamespaces, "namespaces", "n", []string{"default"}, "namespaces to checkpoint")
+	checkpointCmd.Flags().BoolVarP(&checkpointAllNamespaces, "all-namespaces", "A", false, "If set, checkpoint all namespaces")
+}
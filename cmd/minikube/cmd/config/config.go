@@ -68,6 +68,12 @@ var settings = []Setting{
 		set:       SetString,
 		callbacks: []setFn{RequiresRestartMsg},
 	},
+	{
+		name:        "runtime-feature-gates",
+		set:         SetString,
+		validations: []setFn{IsValidRuntimeFeatureGates},
+		callbacks:   []setFn{RequiresRestartMsg},
+	},
 	{
 		name:        "v",
 		set:         SetInt,
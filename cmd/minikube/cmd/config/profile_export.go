@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+
+	"github.com/docker/machine/libmachine/state"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/bundle"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var profileExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Exports a stopped profile, with the disks of its nodes, to a single archive",
+	Long: `Exports a stopped profile to a single archive: its configuration, and the disks of its nodes with the images of the container runtime.
+The archive can be imported on another machine with 'minikube profile import', where the cluster starts without downloading anything.
+Only profiles of the docker, podman and qemu2 drivers can be exported.`,
+	Example: "minikube stop -p workshop && minikube profile export -p workshop workshop.tar.gz",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exit.Message(reason.Usage, "Usage: minikube profile export FILE")
+		}
+		cname := ClusterFlagValue()
+		api, cc := mustload.Partial(cname)
+		defer api.Close()
+		if err := bundle.Supported(cc.Driver); err != nil {
+			exit.Message(reason.Usage, "{{.error}}", out.V{"error": err})
+		}
+		for _, n := range cc.Nodes {
+			machineName := config.MachineName(*cc, n)
+			st, err := machine.Status(api, machineName)
+			if err != nil {
+				exit.Error(reason.GuestStatus, "Error getting host status", err)
+			}
+			if st != state.Stopped.String() {
+				exit.Message(reason.GuestStatus, "{{.name}} is {{.state}}, stop it first: {{.cmd}}", out.V{"name": machineName, "state": st, "cmd": mustload.ExampleCmd(cname, "stop")})
+			}
+		}
+
+		f, err := os.Create(args[0])
+		if err != nil {
+			exit.Error(reason.HostSaveProfile, "Failed to create the archive", err)
+		}
+		out.Step(style.Copying, "Exporting profile {{.name}} to {{.file}} ...", out.V{"name": cname, "file": args[0]})
+		err = bundle.Export(cc, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(args[0])
+			exit.Error(reason.HostSaveProfile, "Failed to export the profile", err)
+		}
+		out.Step(style.Success, "Exported profile {{.name}} to {{.file}}", out.V{"name": cname, "file": args[0]})
+	},
+}
+
+var profileImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Imports a profile exported with 'minikube profile export'",
+	Long:  "Imports a profile exported with 'minikube profile export', under the name it was exported with. Start it with 'minikube start -p <name>'.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exit.Message(reason.Usage, "Usage: minikube profile import FILE")
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			exit.Error(reason.Usage, "Failed to open the archive", err)
+		}
+		defer f.Close()
+
+		out.Step(style.Copying, "Importing profile from {{.file}} ...", out.V{"file": args[0]})
+		m, err := bundle.Import(f)
+		if err != nil {
+			exit.Error(reason.HostSaveProfile, "Failed to import the profile", err)
+		}
+		out.Step(style.Success, "Imported profile {{.name}}, start it with: {{.cmd}}", out.V{"name": m.Profile, "cmd": mustload.ExampleCmd(m.Profile, "start")})
+	},
+}
+
+func init() {
+	ProfileCmd.AddCommand(profileExportCmd)
+	ProfileCmd.AddCommand(profileImportCmd)
+}
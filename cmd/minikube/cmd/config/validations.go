@@ -147,8 +147,17 @@ func IsValidPath(name, path string) error {
 	return nil
 }
 
+// IsValidRuntimeFeatureGates checks if a string is a valid list of runtime feature gates
+func IsValidRuntimeFeatureGates(_, gates string) error {
+	_, err := cruntime.ParseFeatureGates(gates)
+	return err
+}
+
 // IsValidRuntime checks if a string is a valid runtime
 func IsValidRuntime(_, runtime string) error {
+	if runtime == constants.AutoContainerRuntime {
+		return nil
+	}
 	_, err := cruntime.New(cruntime.Config{Type: runtime})
 	if err != nil {
 		return fmt.Errorf("invalid runtime: %v", err)
@@ -120,6 +120,10 @@ func TestValidRuntime(t *testing.T) {
 			value:     "docker",
 			shouldErr: false,
 		},
+		{
+			value:     "auto",
+			shouldErr: false,
+		},
 	}
 
 	runValidations(t, tests, "container-runtime", IsValidRuntime)
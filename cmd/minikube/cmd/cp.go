@@ -29,6 +29,8 @@ import (
 
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
 	"k8s.io/minikube/pkg/minikube/mustload"
@@ -40,18 +42,26 @@ import (
 type remotePath struct {
 	node string
 	path string
+	// namespace and pod are set for pod/<namespace>/<name>:<path> destinations
+	namespace string
+	pod       string
 }
 
+// cpContainer is the container of a pod destination to copy into
+var cpContainer string
+
 // cpCmd represents the cp command, similar to docker cp
 var cpCmd = &cobra.Command{
-	Use:   "cp <source node name>:<source file path> <target node name>:<target file absolute path>",
+	Use:   "cp <source node name>:<source file path> <target node name>:<target file absolute path>|pod/<namespace>/<pod name>:<target file absolute path>",
 	Short: "Copy the specified file into minikube",
 	Long: `Copy the specified file into minikube, it will be saved at path <target file absolute path> in your minikube.
 Default target node controlplane and If <source node name> is omitted, It will trying to copy from host.
+A pod/<namespace>/<pod name> target copies a host file into a running container of that pod, use --container to pick one when the pod runs several.
 
 Example Command : "minikube cp a.txt /home/docker/b.txt" +
                   "minikube cp a.txt minikube-m02:/home/docker/b.txt"
-                  "minikube cp minikube-m01:a.txt minikube-m02:/home/docker/b.txt"`,
+                  "minikube cp minikube-m01:a.txt minikube-m02:/home/docker/b.txt"
+                  "minikube cp a.txt pod/default/nginx:/usr/share/nginx/html/a.txt"`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 2 {
 			exit.Message(reason.Usage, `Please specify the path to copy: 
@@ -65,6 +75,10 @@ Example Command : "minikube cp a.txt /home/docker/b.txt" +
 		validateArgs(src, dst)
 
 		co := mustload.Running(ClusterFlagValue())
+		if dst.pod != "" {
+			copyToPod(&co, src, dst)
+			return
+		}
 		var runner command.Runner
 
 		if dst.node != "" {
@@ -85,6 +99,7 @@ Example Command : "minikube cp a.txt /home/docker/b.txt" +
 }
 
 func init() {
+	cpCmd.Flags().StringVarP(&cpContainer, "container", "c", "", "Container of the target pod to copy into, required when the pod runs several containers")
 }
 
 // setDstFileNameFromSrc sets the src filename as dst filename
@@ -137,6 +152,10 @@ func setDstFileNameFromSrc(dst, src string) string {
 
 // split path to node name and file path
 func newRemotePath(path string) *remotePath {
+	if rp := newPodPath(path); rp != nil {
+		return rp
+	}
+
 	// if destination path is not a absolute path, trying to parse with <node>:<abs path> format
 	sp := strings.SplitN(path, ":", 2)
 	if len(sp) == 2 && len(sp[0]) > 0 && !strings.Contains(sp[0], "/") && strings.HasPrefix(sp[1], "/") {
@@ -146,6 +165,42 @@ func newRemotePath(path string) *remotePath {
 	return &remotePath{node: "", path: path}
 }
 
+// newPodPath parses a pod/<namespace>/<name>:<path> destination, returning nil for other paths
+func newPodPath(path string) *remotePath {
+	sp := strings.SplitN(path, ":", 2)
+	if len(sp) != 2 {
+		return nil
+	}
+	ps := strings.Split(sp[0], "/")
+	if len(ps) != 3 || ps[0] != "pod" || ps[1] == "" || ps[2] == "" {
+		return nil
+	}
+	return &remotePath{namespace: ps[1], pod: ps[2], path: sp[1]}
+}
+
+// copyToPod copies the host file src into the pod of dst, looking for the node running it
+func copyToPod(co *mustload.ClusterController, src, dst *remotePath) {
+	if _, err := os.Stat(src.path); err != nil {
+		if os.IsNotExist(err) {
+			exit.Message(reason.HostPathMissing, "Cannot find directory {{.path}} for copy", out.V{"path": src.path})
+		}
+		exit.Error(reason.HostPathStat, "stat failed", err)
+	}
+
+	for _, n := range co.Config.Nodes {
+		runner := remoteCommandRunner(co, config.MachineName(*co.Config, n))
+		err := machine.CopyToPod(runner, src.path, dst.namespace, dst.pod, cpContainer, dst.path)
+		if errors.Is(err, cruntime.ErrPodNotFound) {
+			continue
+		}
+		if err != nil {
+			exit.Error(reason.InternalCommandRunner, fmt.Sprintf("Fail to copy file %s into pod %s/%s", src.path, dst.namespace, dst.pod), err)
+		}
+		return
+	}
+	exit.Message(reason.Usage, "Pod {{.namespace}}/{{.pod}} is not running on any node", out.V{"namespace": dst.namespace, "pod": dst.pod})
+}
+
 func remoteCommandRunner(co *mustload.ClusterController, nodeName string) command.Runner {
 	n, _, err := node.Retrieve(*co.Config, nodeName)
 	if err != nil {
@@ -210,6 +265,16 @@ func validateArgs(src, dst *remotePath) {
 		exit.Message(reason.Usage, "Target {{.path}} can not be empty", out.V{"path": dst.path})
 	}
 
+	if dst.pod != "" {
+		if src.node != "" || src.pod != "" {
+			exit.Message(reason.Usage, "Only host files can be copied into a pod")
+		}
+		if !strings.HasPrefix(dst.path, "/") {
+			exit.Message(reason.Usage, `Target <pod file path> must be an absolute Path (example: "pod/default/nginx:/tmp/copied.txt")`)
+		}
+		return
+	}
+
 	// if node name not explicitly specified in both of source and target,
 	// consider target node is controlpanel for backward compatibility.
 	if src.node == "" && dst.node == "" && !strings.HasPrefix(dst.path, "/") {
@@ -47,6 +47,9 @@ func TestParsePath(t *testing.T) {
 		{"minikube:/a", "minikube", "/a"},
 		{"minikube:/a/b", "minikube", "/a/b"},
 		{"minikube:/a/b:c", "minikube", "/a/b:c"},
+		{"pod/default:/a", "", "pod/default:/a"},
+		{"pod//nginx:/a", "", "pod//nginx:/a"},
+		{"pods/default/nginx:/a", "", "pods/default/nginx:/a"},
 	}
 
 	for _, c := range passedCases {
@@ -61,6 +64,24 @@ func TestParsePath(t *testing.T) {
 	}
 }
 
+func TestParsePodPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want remotePath
+	}{
+		{"pod/default/nginx:/a", remotePath{namespace: "default", pod: "nginx", path: "/a"}},
+		{"pod/kube-system/etcd-minikube:/a/b:c", remotePath{namespace: "kube-system", pod: "etcd-minikube", path: "/a/b:c"}},
+		{"pod/default/nginx:a", remotePath{namespace: "default", pod: "nginx", path: "a"}},
+	}
+
+	for _, c := range cases {
+		rp := newRemotePath(c.path)
+		if *rp != c.want {
+			t.Errorf("parsePath %q expected: %+v, got: %+v", c.path, c.want, *rp)
+		}
+	}
+}
+
 func TestSetDstFileNameFromSrc(t *testing.T) {
 	cases := []struct {
 		src  string
@@ -74,6 +95,7 @@ func TestSetDstFileNameFromSrc(t *testing.T) {
 		{"./a/b", "", ""},
 		{"./a/b", "/c", "/c"},
 		{"./a/", "/c/", "/c/"},
+		{"./a/b", "pod/default/nginx:/c/", "pod/default/nginx:/c/b"},
 	}
 
 	for _, c := range cases {
@@ -235,6 +235,23 @@ func mustRestartDockerd(name string, runner command.Runner) {
 	}
 }
 
+// ensurePortoDockerShim exits unless the docker API shim, started by the porto-docker-shim addon, is running
+func ensurePortoDockerShim(name string, r command.Runner) {
+	if sysinit.New(r).Active(constants.PortoDockerShim) {
+		return
+	}
+	exit.Message(reason.EnvPortoUnavailable, "The docker API shim of the porto runtime is not running within '{{.cluster}}', start it with 'minikube -p {{.cluster}} addons enable {{.addon}}'",
+		out.V{"cluster": name, "addon": constants.PortoDockerShim})
+}
+
+// mustRestartPortoDockerShim restarts the docker API shim, such as to have it load renewed certificates
+func mustRestartPortoDockerShim(name string, r command.Runner) {
+	klog.Warningf("restarting the docker API shim...")
+	if err := sysinit.New(r).Restart(constants.PortoDockerShim); err != nil {
+		klog.Warningf("Couldn't restart the docker API shim inside minikube within '%v' because: %v", name, err)
+	}
+}
+
 func waitForAPIServerProcess(cr command.Runner, start time.Time, timeout time.Duration) error {
 	klog.Infof("waiting for apiserver process to appear ...")
 	err := apiWait.PollUntilContextTimeout(context.Background(), time.Millisecond*500, timeout, true, func(_ context.Context) (bool, error) {
@@ -346,6 +363,14 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 			ensureDockerd(cname, r)
 		}
 
+		// porto has no docker daemon, its docker API shim serves the docker clients instead
+		if cr == constants.Porto {
+			if sshHost {
+				exit.Message(reason.Usage, "The docker API shim of the porto runtime is only served over TLS, run docker-env without --ssh-host")
+			}
+			ensurePortoDockerShim(cname, r)
+		}
+
 		d := co.CP.Host.Driver
 		port := constants.DockerDaemonPort
 		if driver.NeedsPortForward(driverName) {
@@ -397,7 +422,11 @@ docker-cli install instructions: https://minikube.sigs.k8s.io/docs/tutorials/doc
 				// to fix issues like this #8185
 				// even though docker maybe running just fine it could be holding on to old certs and needs a refresh
 				klog.Warningf("couldn't connect to docker inside minikube.  output: %s error: %v", string(out), err)
-				mustRestartDockerd(cname, co.CP.Runner)
+				if cr == constants.Porto {
+					mustRestartPortoDockerShim(cname, co.CP.Runner)
+				} else {
+					mustRestartDockerd(cname, co.CP.Runner)
+				}
 			}
 		}
 
@@ -678,8 +707,8 @@ func dockerEnvSupported(containerRuntime, driverName string) error {
 	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
 		return fmt.Errorf("the docker-env command only supports amd64 & arm64 architectures")
 	}
-	if containerRuntime != constants.Docker && containerRuntime != constants.Containerd {
-		return fmt.Errorf("the docker-env command only supports the docker and containerd runtimes")
+	if containerRuntime != constants.Docker && containerRuntime != constants.Containerd && containerRuntime != constants.Porto {
+		return fmt.Errorf("the docker-env command only supports the docker, containerd and porto runtimes")
 	}
 	// we only support containerd-env on the Docker driver
 	if containerRuntime == constants.Containerd && driverName != driver.Docker {
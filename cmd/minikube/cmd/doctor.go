@@ -0,0 +1,441 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/state"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/proxy"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/registry"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+const (
+	// doctorDNSTimeout is how long the host may take to resolve the image repository
+	doctorDNSTimeout = 5 * time.Second
+	// doctorDiskThreshold is the usage of the layer store, in percent, from which images may fail to pull,
+	// as minikube start warns about it
+	doctorDiskThreshold = 85
+	// proxyDocs documents running minikube behind a proxy
+	proxyDocs = "https://minikube.sigs.k8s.io/docs/handbook/vpn_and_proxy/"
+)
+
+// kubeletCgroupControllers are the cgroup controllers kubelet can't run pods without
+var kubeletCgroupControllers = []string{"cpu", "memory", "pids"}
+
+var (
+	doctorOutput string
+	// doctorFix makes doctor repair the problems it knows how to, such as leftover temporary files
+	doctorFix bool
+)
+
+// doctorCheck is the outcome of one of the checks of minikube doctor
+type doctorCheck struct {
+	// Node is the node the check ran on, empty for the checks of the host
+	Node   string `json:"node,omitempty"`
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Skipped is set when the check could not run, such as on a stopped node
+	Skipped bool `json:"skipped,omitempty"`
+	// Detail is what the check found, and Hint how to fix it when it failed
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the host and the cluster for common problems",
+	Long: `Checks the host and, when the cluster exists and runs, each of its nodes for common problems: the health of the driver, DNS and proxy settings, the cgroup controllers, the kernel features porto needs, the disk space of the layer store of the container runtime, the temporary files left behind by interrupted image operations, and access to the image registry.
+
+Every check prints whether it passed, with a hint to fix it when it did not. With --fix, the problems minikube can repair are repaired. The exit code is non-zero when any check failed.`,
+	Example: `minikube doctor
+minikube doctor --fix
+minikube doctor -p dev -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube doctor [--output text|json] [--fix]")
+		}
+		runDoctor(doctorOutput, doctorFix)
+	},
+}
+
+// runDoctor runs the checks of the host and of the nodes of the cluster and prints them in output, repairing what it
+// can with fix. It exits non-zero when any check failed.
+func runDoctor(output string, fix bool) {
+	if output != "text" && output != "json" {
+		exit.Message(reason.Usage, "Invalid output format {{.format}}, valid ones are text and json", out.V{"format": output})
+	}
+
+	cname := ClusterFlagValue()
+	cc, err := config.Load(cname)
+	if err != nil {
+		if !config.IsNotExist(err) {
+			exit.Error(reason.HostConfigLoad, "Error getting cluster config", err)
+		}
+		klog.Infof("profile %q does not exist, checking the host alone", cname)
+		cc = nil
+	}
+
+	checks := hostChecks(cc)
+	if cc != nil {
+		checks = append(checks, clusterChecks(cc, fix)...)
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if !c.Passed && !c.Skipped {
+			failed++
+		}
+	}
+	if output == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(checks); err != nil {
+			exit.Error(reason.InternalJSONMarshal, "Failed to encode the checks", err)
+		}
+	} else {
+		printDoctorChecks(checks)
+	}
+	if failed > 0 {
+		exit.Message(reason.HostDoctorProblems, "{{.failed}} of {{.total}} checks failed", out.V{"failed": failed, "total": len(checks)})
+	}
+}
+
+// hostChecks checks the host minikube runs on for the cluster cc, which is nil when it does not exist yet
+func hostChecks(cc *config.ClusterConfig) []doctorCheck {
+	repo := images.DefaultKubernetesRepo
+	ip := ""
+	if cc != nil {
+		if cc.KubernetesConfig.ImageRepository != "" {
+			repo = cc.KubernetesConfig.ImageRepository
+		}
+		if len(cc.Nodes) > 0 {
+			ip = cc.Nodes[0].IP
+		}
+	}
+	return []doctorCheck{driverCheck(cc), hostDNSCheck(repoHost(repo)), proxyCheck(ip)}
+}
+
+// clusterChecks checks each running node of the cluster cc, repairing what it can with fix
+func clusterChecks(cc *config.ClusterConfig, fix bool) []doctorCheck {
+	api, err := machine.NewAPIClient()
+	if err != nil {
+		exit.Error(reason.NewAPIClient, "libmachine failed", err)
+	}
+	defer api.Close()
+
+	var checks []doctorCheck
+	for _, n := range cc.Nodes {
+		machineName := config.MachineName(*cc, n)
+		st, err := machine.Status(api, machineName)
+		if err != nil || st != state.Running.String() {
+			klog.Infof("node %s is %s (%v), skipping its checks", machineName, st, err)
+			checks = append(checks, doctorCheck{Node: machineName, Name: "node", Skipped: true, Detail: fmt.Sprintf("%s, skipping its checks", st)})
+			continue
+		}
+		h, err := machine.LoadHost(api, machineName)
+		if err != nil {
+			exit.Error(reason.GuestLoadHost, "Error getting host", err)
+		}
+		r, err := machine.CommandRunner(h)
+		if err != nil {
+			exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+		}
+		for _, c := range nodeChecks(r, *cc, fix) {
+			c.Node = machineName
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+// nodeChecks checks a running node of the cluster cc through its runner, repairing what it can with fix
+func nodeChecks(r command.Runner, cc config.ClusterConfig, fix bool) []doctorCheck {
+	checks := []doctorCheck{runtimeCheck(r, cc), cgroupCheck(r)}
+	if cc.KubernetesConfig.ContainerRuntime == constants.Porto {
+		checks = append(checks, portoKernelCheck(r))
+	}
+	repo := images.DefaultKubernetesRepo
+	if cc.KubernetesConfig.ImageRepository != "" {
+		repo = cc.KubernetesConfig.ImageRepository
+	}
+	return append(checks, layerStoreCheck(r, cc), stagingCheck(r, fix), registryCheck(r, repoHost(repo)))
+}
+
+// repoHost returns the host of an image repository, such as registry.k8s.io of registry.k8s.io/sig-storage
+func repoHost(repo string) string {
+	host, _, _ := strings.Cut(repo, "/")
+	return host
+}
+
+// driverCheck checks that the driver of the cluster cc works, or that any driver does before there is a cluster
+func driverCheck(cc *config.ClusterConfig) doctorCheck {
+	c := doctorCheck{Name: "driver"}
+	if cc == nil {
+		var usable []string
+		for _, ds := range registry.Available(false) {
+			if ds.State.Healthy {
+				usable = append(usable, ds.Name)
+			}
+		}
+		if len(usable) == 0 {
+			c.Detail = "no usable driver found"
+			c.Hint = "Install one of the drivers listed at https://minikube.sigs.k8s.io/docs/drivers/"
+			return c
+		}
+		c.Passed = true
+		c.Detail = "usable: " + strings.Join(usable, ", ")
+		return c
+	}
+
+	st := registry.Status(cc.Driver)
+	switch {
+	case !st.Installed:
+		c.Detail = fmt.Sprintf("%s is not installed", cc.Driver)
+	case !st.Healthy:
+		c.Detail = fmt.Sprintf("%s is unhealthy: %v", cc.Driver, st.Error)
+	default:
+		c.Passed = true
+		c.Detail = strings.TrimSpace(cc.Driver + " " + st.Version)
+		return c
+	}
+	c.Hint = st.Fix
+	if st.Doc != "" {
+		c.Hint = strings.TrimSpace(c.Hint + " " + st.Doc)
+	}
+	return c
+}
+
+// hostDNSCheck checks that the host resolves the image registry
+func hostDNSCheck(host string) doctorCheck {
+	c := doctorCheck{Name: "dns"}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorDNSTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		c.Detail = err.Error()
+		c.Hint = "Check the DNS servers of the host, or configure a proxy: " + proxyDocs
+		return c
+	}
+	c.Passed = true
+	c.Detail = fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))
+	return c
+}
+
+// proxyCheck checks that the cluster at ip, if known, is not reached through the proxy the host is configured with
+func proxyCheck(ip string) doctorCheck {
+	c := doctorCheck{Name: "proxy", Passed: true}
+	var set []string
+	for _, env := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		if v := os.Getenv(env); v != "" {
+			set = append(set, env+"="+v)
+		}
+	}
+	if len(set) == 0 {
+		c.Detail = "no proxy configured"
+		return c
+	}
+	c.Detail = strings.Join(set, ", ")
+	if ip == "" || proxy.IsIPExcluded(ip) {
+		return c
+	}
+	c.Passed = false
+	c.Detail = fmt.Sprintf("%s, but NO_PROXY does not include the minikube IP %s", c.Detail, ip)
+	c.Hint = fmt.Sprintf("Run: export NO_PROXY=$NO_PROXY,%s (see %s)", ip, proxyDocs)
+	return c
+}
+
+// runtimeCheck checks that the container runtime of a node serves requests
+func runtimeCheck(r command.Runner, cc config.ClusterConfig) doctorCheck {
+	c := doctorCheck{Name: "runtime", Detail: cc.KubernetesConfig.ContainerRuntime}
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: r, Socket: cc.KubernetesConfig.CRISocket})
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	health, why := runtimeHealth(cr)
+	if health != Healthy {
+		c.Detail = fmt.Sprintf("%s is %s: %s", cc.KubernetesConfig.ContainerRuntime, strings.ToLower(health), why)
+		c.Hint = "Run 'minikube status' for the state of the runtime, and 'minikube logs' for its logs"
+		return c
+	}
+	c.Passed = true
+	return c
+}
+
+// cgroupCheck checks the cgroup layout of a node, and that the controllers kubelet needs are enabled
+func cgroupCheck(r command.Runner) doctorCheck {
+	c := doctorCheck{Name: "cgroups"}
+	layout := "v1"
+	if rr, err := r.RunCmd(exec.Command("stat", "-fc", "%T", "/sys/fs/cgroup/")); err == nil && strings.TrimSpace(rr.Stdout.String()) == "cgroup2fs" {
+		layout = "v2"
+	}
+	enabled, err := cruntime.EnabledCgroupControllers(r)
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	var missing []string
+	for _, ctrl := range kubeletCgroupControllers {
+		if !enabled[ctrl] {
+			missing = append(missing, ctrl)
+		}
+	}
+	if len(missing) > 0 {
+		c.Detail = fmt.Sprintf("cgroup %s, missing controllers: %s", layout, strings.Join(missing, ", "))
+		c.Hint = fmt.Sprintf("Enable the controllers on the kernel command line of the host, such as with cgroup_enable=%s", missing[0])
+		return c
+	}
+	c.Passed = true
+	c.Detail = fmt.Sprintf("cgroup %s", layout)
+	return c
+}
+
+// portoKernelCheck checks that the kernel of a node has the modules, cgroup controllers and filesystems porto needs
+func portoKernelCheck(r command.Runner) doctorCheck {
+	c := doctorCheck{Name: "porto kernel"}
+	if err := cruntime.CheckPortoKernel(r); err != nil {
+		c.Detail = err.Error()
+		c.Hint = "Use a kernel with these features, such as the one of the minikube ISO, or another --container-runtime"
+		return c
+	}
+	c.Passed = true
+	c.Detail = "modules, cgroup controllers and overlayfs are available"
+	return c
+}
+
+// layerStoreCheck checks that the filesystem the container runtime of a node keeps images in is not nearly full
+func layerStoreCheck(r command.Runner, cc config.ClusterConfig) doctorCheck {
+	c := doctorCheck{Name: "layer store"}
+	dir := "/var"
+	if cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: r, Socket: cc.KubernetesConfig.CRISocket}); err == nil {
+		if info, err := cr.RuntimeInfo(); err == nil && info.StorageRoot != "" {
+			dir = info.StorageRoot
+		}
+	}
+	used, err := machine.DiskUsed(r, dir)
+	if err != nil {
+		c.Detail = fmt.Sprintf("unable to tell the usage of %s: %v", dir, err)
+		return c
+	}
+	c.Detail = fmt.Sprintf("%s is %d%% full", dir, used)
+	if used >= doctorDiskThreshold {
+		c.Hint = "Remove unused images with 'minikube image rm', or recreate the cluster with a larger --disk-size"
+		return c
+	}
+	c.Passed = true
+	return c
+}
+
+// stagingCheck checks that a node has no temporary files left behind by interrupted image operations, and removes them
+// with fix
+func stagingCheck(r command.Runner, fix bool) doctorCheck {
+	c := doctorCheck{Name: "staging"}
+	entries, err := machine.ListStaging(r)
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	stale := machine.StaleStaging(entries, time.Now())
+	if len(stale) == 0 {
+		c.Passed = true
+		c.Detail = "no leftover temporary files"
+		return c
+	}
+	if fix {
+		if err := machine.RemoveStaging(r, stale); err != nil {
+			c.Detail = err.Error()
+			return c
+		}
+		c.Passed = true
+		c.Detail = fmt.Sprintf("removed %d leftover temporary directories", len(stale))
+		return c
+	}
+	var left []string
+	for _, e := range stale {
+		left = append(left, fmt.Sprintf("%s of an interrupted image %s of %s", e.Dir, e.Operation, e.Subject))
+	}
+	c.Detail = "left behind: " + strings.Join(left, ", ")
+	c.Hint = "Run 'minikube doctor --fix' to remove them"
+	return c
+}
+
+// registryCheck checks that a node reaches the image registry at host, which fails as well when the node can't resolve it
+func registryCheck(r command.Runner, host string) doctorCheck {
+	c := doctorCheck{Name: "registry"}
+	opts := []string{"-sS", "-m", "2"}
+	if p := os.Getenv("HTTPS_PROXY"); p != "" && !strings.HasPrefix(p, "localhost") && !strings.HasPrefix(p, "127.0") {
+		opts = append([]string{"-x", p}, opts...)
+	}
+	rr, err := r.RunCmd(exec.Command("curl", append(opts, fmt.Sprintf("https://%s/", host))...))
+	if err != nil {
+		c.Detail = fmt.Sprintf("https://%s/ is unreachable", host)
+		if msg := strings.TrimSpace(rr.Stderr.String()); msg != "" {
+			c.Detail += ": " + msg
+		}
+		c.Hint = "Check the DNS and network access of the node, or configure a proxy: " + proxyDocs
+		return c
+	}
+	c.Passed = true
+	c.Detail = fmt.Sprintf("https://%s/ is reachable", host)
+	return c
+}
+
+// printDoctorChecks prints the outcome of checks, with the hints of the failed ones
+func printDoctorChecks(checks []doctorCheck) {
+	for _, c := range checks {
+		name := c.Name
+		if c.Node != "" {
+			name = c.Node + " " + name
+		}
+		v := out.V{"check": name, "detail": c.Detail}
+		switch {
+		case c.Skipped:
+			out.Styled(style.Shrug, "{{.check}}: {{.detail}}", v)
+		case c.Passed:
+			out.Styled(style.Check, "{{.check}}: {{.detail}}", v)
+		default:
+			out.Styled(style.Failure, "{{.check}}: {{.detail}}", v)
+			if c.Hint != "" {
+				out.Styled(style.Tip, "  {{.hint}}", out.V{"hint": c.Hint})
+			}
+		}
+	}
+}
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "If set, repair the problems found that minikube can, such as the temporary files left behind by interrupted image operations")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// procCgroups is /proc/cgroups of a node, with memory the enabled column of the memory controller
+func procCgroups(memory string) string {
+	return "#subsys_name\thierarchy\tnum_cgroups\tenabled\ncpu\t0\t90\t1\npids\t0\t90\t1\nmemory\t0\t90\t" + memory + "\n"
+}
+
+func TestCgroupCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		fs         string
+		cgroups    string
+		wantPassed bool
+		wantDetail string
+	}{
+		{"v2", "cgroup2fs", procCgroups("1"), true, "cgroup v2"},
+		{"v1", "tmpfs", procCgroups("1"), true, "cgroup v1"},
+		{"memory disabled", "cgroup2fs", procCgroups("0"), false, "cgroup v2, missing controllers: memory"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(map[string]string{
+				"stat -fc %T /sys/fs/cgroup/": tc.fs + "\n",
+				"cat /proc/cgroups":           tc.cgroups,
+			})
+			got := cgroupCheck(runner)
+			if got.Passed != tc.wantPassed || got.Detail != tc.wantDetail {
+				t.Errorf("cgroupCheck() = %+v, want passed=%v with detail %q", got, tc.wantPassed, tc.wantDetail)
+			}
+			if !got.Passed && !strings.Contains(got.Hint, "cgroup_enable=memory") {
+				t.Errorf("cgroupCheck() hint = %q, want it to enable the memory controller", got.Hint)
+			}
+		})
+	}
+}
+
+func TestProxyCheck(t *testing.T) {
+	for _, env := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(env, "")
+	}
+	if got := proxyCheck("192.168.49.2"); !got.Passed || got.Detail != "no proxy configured" {
+		t.Errorf("proxyCheck() without a proxy = %+v, want it to pass", got)
+	}
+
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	if got := proxyCheck(""); !got.Passed {
+		t.Errorf("proxyCheck() before there is a cluster = %+v, want it to pass", got)
+	}
+	got := proxyCheck("192.168.49.2")
+	if got.Passed || !strings.Contains(got.Hint, "NO_PROXY=$NO_PROXY,192.168.49.2") {
+		t.Errorf("proxyCheck() with the minikube IP proxied = %+v, want it to fail with a NO_PROXY hint", got)
+	}
+
+	t.Setenv("NO_PROXY", "localhost,192.168.49.2")
+	if got := proxyCheck("192.168.49.2"); !got.Passed {
+		t.Errorf("proxyCheck() with the minikube IP in NO_PROXY = %+v, want it to pass", got)
+	}
+}
+
+func TestLayerStoreCheck(t *testing.T) {
+	cc := config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ContainerRuntime: constants.Containerd}}
+	// the runtime does not answer the fake runner, so the check falls back to /var
+	t.Setenv(constants.TestDiskUsedEnv, "42")
+	if got := layerStoreCheck(command.NewFakeCommandRunner(), cc); !got.Passed || got.Detail != "/var is 42% full" {
+		t.Errorf("layerStoreCheck() = %+v, want /var 42%% full to pass", got)
+	}
+	t.Setenv(constants.TestDiskUsedEnv, "93")
+	if got := layerStoreCheck(command.NewFakeCommandRunner(), cc); got.Passed || got.Hint == "" {
+		t.Errorf("layerStoreCheck() = %+v, want /var 93%% full to fail with a hint", got)
+	}
+}
+
+func TestRegistryCheck(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{"curl -sS -m 2 https://registry.k8s.io/": ""})
+	if got := registryCheck(runner, "registry.k8s.io"); !got.Passed {
+		t.Errorf("registryCheck() = %+v, want it to pass", got)
+	}
+	if got := registryCheck(runner, "registry.invalid"); got.Passed || !strings.Contains(got.Hint, "proxy") {
+		t.Errorf("registryCheck() of an unreachable registry = %+v, want it to fail with a hint", got)
+	}
+}
+
+func TestRepoHost(t *testing.T) {
+	for repo, want := range map[string]string{"registry.k8s.io": "registry.k8s.io", "registry.example.com:5000/mirror/k8s": "registry.example.com:5000"} {
+		if got := repoHost(repo); got != want {
+			t.Errorf("repoHost(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestStagingCheck(t *testing.T) {
+	const find = "sudo find /var/lib/minikube/staging -mindepth 1 -maxdepth 1 -type d"
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		find: "/var/lib/minikube/staging/load-1\n",
+		"sudo cat /var/lib/minikube/staging/load-1/manifest.json": `{"operation":"load","subject":"busybox:latest","created":"2024-03-01T10:00:00Z"}`,
+		"sudo rm -rf /var/lib/minikube/staging/load-1":            "",
+	})
+	got := stagingCheck(runner, false)
+	if got.Passed || !strings.Contains(got.Detail, "/var/lib/minikube/staging/load-1 of an interrupted image load of busybox:latest") || !strings.Contains(got.Hint, "--fix") {
+		t.Errorf("stagingCheck() = %+v, want the leftover load to fail with a hint", got)
+	}
+	if got := stagingCheck(runner, true); !got.Passed || got.Detail != "removed 1 leftover temporary directories" {
+		t.Errorf("stagingCheck(fix) = %+v, want the leftover load removed", got)
+	}
+
+	runner.SetCommandToOutput(map[string]string{find: ""})
+	if got := stagingCheck(runner, false); !got.Passed {
+		t.Errorf("stagingCheck() without leftovers = %+v, want it to pass", got)
+	}
+}
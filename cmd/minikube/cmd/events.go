@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+var (
+	eventsOutput  string
+	eventsRuntime bool
+)
+
+// nodeContainerEvent is a container event of the runtime of a node
+type nodeContainerEvent struct {
+	Node string `json:"node"`
+	cruntime.ContainerEvent
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show the events of the cluster",
+	Long: `Shows the events minikube recorded while managing the cluster.
+With --runtime, streams the lifecycle events of the containers on the nodes as the container runtime reports them, until interrupted, which helps to tell why containers crash loop.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube events [--runtime] [--output text|json]")
+		}
+		if eventsOutput != "text" && eventsOutput != "json" {
+			exit.Message(reason.Usage, "Invalid output format {{.format}}, valid ones are text and json", out.V{"format": eventsOutput})
+		}
+		if eventsRuntime {
+			streamRuntimeEvents()
+			return
+		}
+		printEventLog()
+	},
+}
+
+// printEventLog prints the events minikube recorded for the cluster
+func printEventLog() {
+	cname := ClusterFlagValue()
+	evs, _, err := readEventLog(cname)
+	if errors.Is(err, os.ErrNotExist) {
+		exit.Message(reason.Usage, `No events were recorded for the "{{.name}}" cluster, start it with: "minikube start -p {{.name}}"`, out.V{"name": cname})
+	}
+	if err != nil {
+		exit.Error(reason.GuestStatus, "Failed to read the events of the cluster", err)
+	}
+	for _, ev := range evs {
+		if eventsOutput == "json" {
+			b, err := json.Marshal(ev)
+			if err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to encode events", err)
+			}
+			fmt.Println(string(b))
+			continue
+		}
+		var data map[string]string
+		if err := ev.DataAs(&data); err != nil {
+			continue
+		}
+		msg := data["message"]
+		if msg == "" {
+			msg = data["name"]
+		}
+		kind := ev.Type()[strings.LastIndex(ev.Type(), ".")+1:]
+		fmt.Printf("%s  %-8s %s\n", ev.Time().Local().Format(time.RFC3339), kind, strings.TrimSpace(msg))
+	}
+}
+
+// streamRuntimeEvents prints the container events of the runtimes of all nodes as they come, until interrupted
+func streamRuntimeEvents() {
+	co := mustload.Running(ClusterFlagValue())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	events := make(chan nodeContainerEvent)
+	var wg sync.WaitGroup
+	for _, n := range co.Config.Nodes {
+		cr, _ := nodeRuntime(co, n)
+		ch, err := cr.Events(ctx)
+		if err != nil {
+			exit.Error(reason.GuestStatus, "Failed to stream container events", err)
+		}
+		node := config.MachineName(*co.Config, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range ch {
+				events <- nodeContainerEvent{Node: node, ContainerEvent: e}
+			}
+			if ctx.Err() == nil {
+				out.WarningT("The container runtime of {{.node}} stopped reporting events", out.V{"node": node})
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	if eventsOutput == "text" {
+		out.Styled(style.Waiting, "Watching container events, press Ctrl-C to stop ...")
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for e := range events {
+		if eventsOutput == "json" {
+			if err := enc.Encode(e); err != nil {
+				exit.Error(reason.InternalJSONMarshal, "Failed to encode container events", err)
+			}
+			continue
+		}
+		printContainerEvent(os.Stdout, e)
+	}
+}
+
+// printContainerEvent prints a container event as a line, with the exit code and reason of stopped containers
+func printContainerEvent(w io.Writer, e nodeContainerEvent) {
+	id := e.ContainerID
+	if len(id) > 13 {
+		id = id[:13]
+	}
+	line := fmt.Sprintf("%s  %s  %s/%s  %s (%s)  %s", e.Time.Local().Format(time.RFC3339), e.Node, e.PodNamespace, e.PodName, e.Container, id, e.Type)
+	if e.Type == "stopped" {
+		line += fmt.Sprintf(": exit code %d", e.ExitCode)
+		if e.Reason != "" {
+			line += ", " + e.Reason
+		}
+	}
+	fmt.Fprintln(w, line)
+}
+
+func init() {
+	eventsCmd.Flags().BoolVar(&eventsRuntime, "runtime", false, "Stream the lifecycle events of the containers on the nodes as the container runtime reports them, until interrupted")
+	eventsCmd.Flags().StringVarP(&eventsOutput, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
+}
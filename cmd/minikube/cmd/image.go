@@ -24,14 +24,17 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/image"
 	"k8s.io/minikube/pkg/minikube/machine"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/style"
 	docker "k8s.io/minikube/third_party/go-dockerclient"
 )
 
@@ -39,6 +42,9 @@ var (
 	allNodes bool
 )
 
+// allProfiles is the --profile value of 'minikube image ls' listing the images of every profile
+const allProfiles = "all"
+
 // imageCmd represents the image command
 var imageCmd = &cobra.Command{
 	Use:   "image COMMAND",
@@ -55,7 +61,19 @@ var (
 	dockerFile string
 	buildEnv   []string
 	buildOpt   []string
+	noCache    bool
 	format     string
+	saveOutput string
+	saveFormat string
+	// inspectFormat is the output of minikube image inspect
+	inspectFormat string
+)
+
+const (
+	// saveFormatDockerArchive saves images into a docker-archive tarball, as docker save does
+	saveFormatDockerArchive = "docker-archive"
+	// saveFormatOCILayout saves images into an OCI image layout directory, as skopeo and buildah write them
+	saveFormatOCILayout = "oci-layout"
 )
 
 func saveFile(r io.Reader) (string, error) {
@@ -76,10 +94,10 @@ func saveFile(r io.Reader) (string, error) {
 
 // loadImageCmd represents the image load command
 var loadImageCmd = &cobra.Command{
-	Use:     "load IMAGE | ARCHIVE | -",
+	Use:     "load IMAGE | ARCHIVE | OCI_LAYOUT_DIR | -",
 	Short:   "Load an image into minikube",
-	Long:    "Load an image into minikube",
-	Example: "minikube image load image\nminikube image load image.tar",
+	Long:    "Load an image into minikube, from the local daemon, a registry, a docker-archive tarball or an OCI image layout directory as ko and buildah write them",
+	Example: "minikube image load image\nminikube image load image.tar\nminikube image load ./oci-layout-dir",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			exit.Message(reason.Usage, "Please provide an image in your local daemon to load into minikube via <minikube image load IMAGE_NAME>")
@@ -89,6 +107,7 @@ var loadImageCmd = &cobra.Command{
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
+		exitUnlessSupported(profile.Config, "loading images", func(c cruntime.Capabilities) bool { return c.SupportsLoad })
 
 		if pull {
 			// Pull image from remote registry, without doing any caching except in container runtime.
@@ -134,21 +153,67 @@ var loadImageCmd = &cobra.Command{
 		}
 
 		if imgDaemon || imgRemote {
-			image.UseDaemon(imgDaemon)
-			image.UseRemote(imgRemote)
-			if err := machine.CacheAndLoadImages(args, []*config.Profile{profile}, overwrite); err != nil {
-				exit.Error(reason.GuestImageLoad, "Failed to load image", err)
-			}
+			loadImagesWithProgress(func(progress machine.LoadProgress) ([]machine.LoadResult, error) {
+				profiles := []*config.Profile{profile}
+				var streamed []machine.LoadResult
+				var err error
+				if imgDaemon && !imgRemote {
+					// porto streams images straight from the daemon, other runtimes load them through the image cache
+					profiles, streamed, err = machine.StreamDaemonImages(args, profiles, progress)
+					if err != nil || len(profiles) == 0 {
+						return streamed, err
+					}
+				}
+				image.UseDaemon(imgDaemon)
+				image.UseRemote(imgRemote)
+				loaded, err := machine.CacheAndLoadImagesReporting(args, profiles, overwrite, progress)
+				return append(streamed, loaded...), err
+			})
 		} else if local {
+			for _, img := range args {
+				if cruntime.IsOCILayout(img) {
+					exitUnlessSupported(profile.Config, "loading OCI image layouts", func(c cruntime.Capabilities) bool { return c.SupportsLoadLayout })
+					break
+				}
+			}
 			// Load images from local files, without doing any caching or checks in container runtime
 			// This is similar to tarball.Image but it is done by the container runtime in the cluster.
-			if err := machine.DoLoadImages(args, []*config.Profile{profile}, "", overwrite); err != nil {
-				exit.Error(reason.GuestImageLoad, "Failed to load image", err)
-			}
+			loadImagesWithProgress(func(progress machine.LoadProgress) ([]machine.LoadResult, error) {
+				return machine.LoadImagesReporting(args, []*config.Profile{profile}, "", overwrite, progress)
+			})
 		}
 	},
 }
 
+// loadImagesWithProgress runs load rendering a progress bar for each image it loads into each node, then summarizes
+// which loads succeeded and exits if any failed
+func loadImagesWithProgress(load func(machine.LoadProgress) ([]machine.LoadResult, error)) {
+	progress, stop := machine.LoadProgressBars()
+	results, err := load(progress)
+	stop()
+	if err != nil {
+		exit.Error(reason.GuestImageLoad, "Failed to load image", err)
+	}
+	if failed := printLoadSummary(results); failed > 0 {
+		exit.Message(reason.GuestImageLoad, "Failed to load {{.failed}} of {{.total}} images", out.V{"failed": failed, "total": len(results)})
+	}
+}
+
+// printLoadSummary prints why each failed load failed and how many images were loaded, returning how many failed
+func printLoadSummary(results []machine.LoadResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			out.FailureT("Failed to load {{.image}} into {{.node}}: {{.error}}", out.V{"image": r.Image, "node": r.Node, "error": r.Err})
+		}
+	}
+	if len(results) > failed {
+		out.Step(style.Success, "Loaded {{.loaded}} of {{.total}} images", out.V{"loaded": len(results) - failed, "total": len(results)})
+	}
+	return failed
+}
+
 func readFile(w io.Writer, tmp string) error {
 	r, err := os.Open(tmp)
 	if err != nil {
@@ -165,53 +230,90 @@ func readFile(w io.Writer, tmp string) error {
 	return nil
 }
 
-// saveImageCmd represents the image load command
+// exitUnlessSupported exits with a clear message when the container runtime of cc lacks feature, as told by supported,
+// rather than letting the command fail on the nodes with an error of the runtime
+func exitUnlessSupported(cc *config.ClusterConfig, feature string, supported func(cruntime.Capabilities) bool) {
+	if cc == nil {
+		return
+	}
+	gates, err := cruntime.ParseFeatureGates(cc.RuntimeFeatureGates)
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid runtime feature gates", err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, FeatureGates: gates})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+	if !supported(cr.Capabilities()) {
+		exit.Message(reason.RuntimeUnsupportedFeature, "The {{.runtime}} container runtime does not support {{.feature}}", out.V{"runtime": cr.Name(), "feature": feature})
+	}
+}
+
+// saveImageCmd represents the image save command
 var saveImageCmd = &cobra.Command{
-	Use:     "save IMAGE [ARCHIVE | -]",
-	Short:   "Save a image from minikube",
-	Long:    "Save a image from minikube",
-	Example: "minikube image save image\nminikube image save image image.tar",
+	Use:   "save IMAGE [ARCHIVE | -] | IMAGE... -o ARCHIVE",
+	Short: "Save a image from minikube",
+	Long:  "Save images from minikube. Several images are saved into one archive: a docker-archive tarball, or an OCI image layout directory with --format=oci-layout, both of which minikube image load loads back",
+	Example: "minikube image save image\nminikube image save image image.tar\n" +
+		"minikube image save app db -o bundle.tar\nminikube image save app db --format=oci-layout -o ./bundle",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			exit.Message(reason.Usage, "Please provide an image in the container runtime to save from minikube via <minikube image save IMAGE_NAME>")
 		}
+		if saveFormat != saveFormatDockerArchive && saveFormat != saveFormatOCILayout {
+			exit.Message(reason.Usage, "Invalid --format {{.format}}, valid ones are: {{.formats}}", out.V{"format": saveFormat, "formats": saveFormatDockerArchive + ", " + saveFormatOCILayout})
+		}
+		images, archive := args, saveOutput
+		if archive == "" && len(args) == 2 {
+			images, archive = args[:1], args[1]
+		}
+		if archive == "" && len(images) > 1 {
+			exit.Message(reason.Usage, "Please provide the archive to save several images into via <minikube image save IMAGE... -o ARCHIVE>")
+		}
+		if saveFormat == saveFormatOCILayout && (archive == "" || archive == "-") {
+			exit.Message(reason.Usage, "Please provide the directory to write the OCI image layout to via -o DIR")
+		}
 		// Save images from container runtime
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
+		exitUnlessSupported(profile.Config, "saving images", func(c cruntime.Capabilities) bool { return c.SupportsSave })
 
-		if len(args) > 1 {
-			output = args[1]
-
-			if args[1] == "-" {
+		if archive != "" {
+			tarball := archive
+			if archive == "-" || saveFormat == saveFormatOCILayout {
 				tmp, err := os.CreateTemp("", "image.*.tar")
 				if err != nil {
 					exit.Error(reason.GuestImageSave, "Failed to get temp", err)
 				}
 				tmp.Close()
-				output = tmp.Name()
+				tarball = tmp.Name()
+				defer os.Remove(tarball)
 			}
 
-			if err := machine.DoSaveImages([]string{args[0]}, output, []*config.Profile{profile}, ""); err != nil {
+			if err := machine.DoSaveImages(images, tarball, []*config.Profile{profile}, ""); err != nil {
 				exit.Error(reason.GuestImageSave, "Failed to save image", err)
 			}
 
-			if args[1] == "-" {
-				err := readFile(os.Stdout, output)
+			if archive == "-" {
+				err := readFile(os.Stdout, tarball)
 				if err != nil {
 					exit.Error(reason.GuestImageSave, "Failed to read temp", err)
 				}
-				os.Remove(output)
+			} else if saveFormat == saveFormatOCILayout {
+				if err := cruntime.WriteOCILayout(tarball, archive); err != nil {
+					exit.Error(reason.GuestImageSave, "Failed to write the OCI image layout", err)
+				}
 			}
 		} else {
-			if err := machine.SaveAndCacheImages([]string{args[0]}, []*config.Profile{profile}); err != nil {
+			if err := machine.SaveAndCacheImages(images, []*config.Profile{profile}); err != nil {
 				exit.Error(reason.GuestImageSave, "Failed to save image", err)
 			}
 			if imgDaemon || imgRemote {
 				image.UseDaemon(imgDaemon)
 				image.UseRemote(imgRemote)
-				err := image.UploadCachedImage(args[0])
+				err := image.UploadCachedImage(images[0])
 				if err != nil {
 					exit.Error(reason.GuestImageSave, "Failed to save image", err)
 				}
@@ -282,6 +384,10 @@ var buildImageCmd = &cobra.Command{
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
+		exitUnlessSupported(profile.Config, "building images", func(c cruntime.Capabilities) bool { return c.SupportsBuild })
+		if push {
+			exitUnlessSupported(profile.Config, "pushing images", func(c cruntime.Capabilities) bool { return c.SupportsPush })
+		}
 
 		img := args[0]
 		var tmp string
@@ -317,6 +423,9 @@ var buildImageCmd = &cobra.Command{
 			out.String("minikube detects that you are using DOS-style path %s. minikube will convert it to UNIX-style by replacing all \\ to /", dockerFile)
 			dockerFile = strings.ReplaceAll(dockerFile, "\\", "/")
 		}
+		if noCache {
+			buildOpt = append(buildOpt, "no-cache")
+		}
 		if err := machine.BuildImage(img, dockerFile, tag, push, buildEnv, buildOpt, []*config.Profile{profile}, allNodes, nodeName); err != nil {
 			exit.Error(reason.GuestImageBuild, "Failed to build image", err)
 		}
@@ -329,11 +438,21 @@ var buildImageCmd = &cobra.Command{
 var listImageCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List images",
+	Long:  "Lists the images of a profile. With --profile all, lists the images of every profile, with the profile and container runtime holding them, largest first.",
 	Example: `
 $ minikube image ls
+
+$ minikube image ls --profile all --format table
 `,
 	Aliases: []string{"list"},
 	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetString(config.ProfileName) == allProfiles {
+			if err := machine.ListAllImages(format); err != nil {
+				exit.Error(reason.GuestImageList, "Failed to list images", err)
+			}
+			return
+		}
+
 		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
@@ -367,6 +486,38 @@ $ minikube image tag source target
 	},
 }
 
+var inspectImageCmd = &cobra.Command{
+	Use:   "inspect IMAGE",
+	Short: "Inspect an image",
+	Long:  "Shows the config, layers, digests, platform and creation time of an image, as the container runtime of the cluster knows it.",
+	Example: `
+$ minikube image inspect busybox
+
+$ minikube image inspect registry.k8s.io/pause:3.9 -o json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			exit.Message(reason.Usage, "Please provide an image to inspect")
+		}
+		switch inspectFormat {
+		case "text", "json", "yaml":
+		default:
+			exit.Message(reason.Usage, "Invalid --output {{.output}}, valid ones are: text, json, yaml", out.V{"output": inspectFormat})
+		}
+		profile, err := config.LoadProfile(viper.GetString(config.ProfileName))
+		if err != nil {
+			exit.Error(reason.Usage, "loading profile", err)
+		}
+
+		if err := machine.InspectImage(profile, args[0], inspectFormat); err != nil {
+			if errors.Is(err, cruntime.ErrImageNotFound) {
+				exit.Message(reason.GuestImageNotFound, "Image {{.image}} is not in the cluster", out.V{"image": args[0]})
+			}
+			exit.Error(reason.GuestImageInspect, "Failed to inspect image", err)
+		}
+	},
+}
+
 var pushImageCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push images",
@@ -378,6 +529,7 @@ $ minikube image push busybox
 		if err != nil {
 			exit.Error(reason.Usage, "loading profile", err)
 		}
+		exitUnlessSupported(profile.Config, "pushing images", func(c cruntime.Capabilities) bool { return c.SupportsPush })
 
 		if err := machine.PushImages(args, profile); err != nil {
 			exit.Error(reason.GuestImagePush, "Failed to push images", err)
@@ -387,7 +539,7 @@ $ minikube image push busybox
 
 func init() {
 	loadImageCmd.Flags().BoolVar(&pull, "pull", false, "Pull the remote image (no caching)")
-	loadImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image from docker daemon")
+	loadImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image from docker daemon (porto streams it from the docker or podman daemon without caching)")
 	loadImageCmd.Flags().BoolVar(&imgRemote, "remote", false, "Cache image from remote registry")
 	loadImageCmd.Flags().BoolVar(&overwrite, "overwrite", true, "Overwrite image even if same image:tag name exists")
 	imageCmd.AddCommand(loadImageCmd)
@@ -398,14 +550,19 @@ func init() {
 	buildImageCmd.Flags().StringVarP(&dockerFile, "file", "f", "", "Path to the Dockerfile to use (optional)")
 	buildImageCmd.Flags().StringArrayVar(&buildEnv, "build-env", nil, "Environment variables to pass to the build. (format: key=value)")
 	buildImageCmd.Flags().StringArrayVar(&buildOpt, "build-opt", nil, "Specify arbitrary flags to pass to the build. (format: key=value)")
+	buildImageCmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not reuse the layers of previous builds; they are still cached for the next build.")
 	buildImageCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to build on. Defaults to the primary control plane.")
 	buildImageCmd.Flags().BoolVar(&allNodes, "all", false, "Build image on all nodes.")
 	imageCmd.AddCommand(buildImageCmd)
 	saveImageCmd.Flags().BoolVar(&imgDaemon, "daemon", false, "Cache image to docker daemon")
 	saveImageCmd.Flags().BoolVar(&imgRemote, "remote", false, "Cache image to remote registry")
+	saveImageCmd.Flags().StringVarP(&saveOutput, "output", "o", "", "Archive to save the images into, - for stdout")
+	saveImageCmd.Flags().StringVar(&saveFormat, "format", saveFormatDockerArchive, "Format of the archive. One of: docker-archive|oci-layout")
 	imageCmd.AddCommand(saveImageCmd)
 	listImageCmd.Flags().StringVar(&format, "format", "short", "Format output. One of: short|table|json|yaml")
 	imageCmd.AddCommand(listImageCmd)
 	imageCmd.AddCommand(tagImageCmd)
+	inspectImageCmd.Flags().StringVarP(&inspectFormat, "output", "o", "text", "Format output. One of: text|json|yaml")
+	imageCmd.AddCommand(inspectImageCmd)
 	imageCmd.AddCommand(pushImageCmd)
 }
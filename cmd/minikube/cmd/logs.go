@@ -91,7 +91,7 @@ var logsCmd = &cobra.Command{
 			}
 			return
 		}
-		logs.OutputOffline(numberOfLines, logOutput)
+		logs.OutputOffline(ClusterFlagValue(), numberOfLines, logOutput)
 
 		if shouldSilentFail() {
 			return
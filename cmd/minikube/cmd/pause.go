@@ -54,6 +54,7 @@ func runPause(_ *cobra.Command, _ []string) {
 	co := mustload.Running(ClusterFlagValue())
 	register.SetEventLogPath(localpath.EventLog(ClusterFlagValue()))
 	register.Reg.SetStep(register.Pausing)
+	exitUnlessSupported(co.Config, "pausing containers", func(c cruntime.Capabilities) bool { return c.SupportsPause })
 
 	klog.InfoS("namespaces", namespaces, "keys", viper.AllSettings())
 	if allNamespaces {
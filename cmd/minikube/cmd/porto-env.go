@@ -0,0 +1,229 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/spf13/cobra"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/shell"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+var portoEnvTmpl = fmt.Sprintf(
+	"{{ .Prefix }}%s{{ .Delimiter }}{{ .PortoSocket }}{{ .Suffix }}"+
+		"{{ .Prefix }}%s{{ .Delimiter }}{{ .PortoshimSocket }}{{ .Suffix }}"+
+		"{{ .Prefix }}%s{{ .Delimiter }}{{ .Portoctl }}{{ .Suffix }}"+
+		"{{ .Prefix }}%s{{ .Delimiter }}{{ .Crictl }}{{ .Suffix }}"+
+		"{{ .Prefix }}%s{{ .Delimiter }}{{ .MinikubePortoProfile }}{{ .Suffix }}"+
+		"{{ .UsageHint }}"+
+		"{{ .AliasHint }}",
+	constants.PortoSocketEnv,
+	constants.PortoshimSocketEnv,
+	constants.PortoctlEnv,
+	constants.PortoCrictlEnv,
+	constants.MinikubeActivePortoEnv)
+
+// portoAliasHints tell how to run the porto tools of the node by their names in each shell,
+// as the variables hold whole commands rather than paths which could be added to PATH
+var portoAliasHints = map[string]string{
+	"bash":       "# %s\n# alias portoctl=\"$%s\" crictl=\"$%s\"\n",
+	"fish":       "# %s\n# alias portoctl \"$%s\"; alias crictl \"$%s\"\n",
+	"tcsh":       ": \"%s\"\n: alias portoctl \"$%s\"; alias crictl \"$%s\"\n",
+	"powershell": "# %s\n# function portoctl { Invoke-Expression \"$Env:%s $args\" }; function crictl { Invoke-Expression \"$Env:%s $args\" }\n",
+	"cmd":        "REM %s\nREM doskey portoctl=%%%s%% $* & doskey crictl=%%%s%% $*\n",
+	"emacs":      "",
+}
+
+// PortoShellConfig represents the shell config for porto
+type PortoShellConfig struct {
+	shell.Config
+	PortoSocket          string
+	PortoshimSocket      string
+	Portoctl             string
+	Crictl               string
+	MinikubePortoProfile string
+	AliasHint            string
+}
+
+var portoUnset bool
+
+// portoShellCfgSet generates context variables for "porto-env"
+func portoShellCfgSet(ec PortoEnvConfig, envMap map[string]string) *PortoShellConfig {
+	profile := ec.profile
+	const usgPlz = "To point your shell to minikube's porto runtime, run:"
+	usgCmd := fmt.Sprintf("minikube -p %s porto-env", profile)
+	s := &PortoShellConfig{
+		Config: *shell.CfgSet(ec.EnvConfig, usgPlz, usgCmd),
+	}
+	s.PortoSocket = envMap[constants.PortoSocketEnv]
+	s.PortoshimSocket = envMap[constants.PortoshimSocketEnv]
+	s.Portoctl = envMap[constants.PortoctlEnv]
+	s.Crictl = envMap[constants.PortoCrictlEnv]
+	s.MinikubePortoProfile = envMap[constants.MinikubeActivePortoEnv]
+
+	if s.UsageHint != "" {
+		hint, ok := portoAliasHints[ec.Shell]
+		if !ok {
+			hint = portoAliasHints["bash"]
+		}
+		if hint != "" {
+			s.AliasHint = fmt.Sprintf(hint, "To run the porto tools of the node as if they were installed here, run:", constants.PortoctlEnv, constants.PortoCrictlEnv)
+		}
+	}
+	return s
+}
+
+// portoEnvCmd represents the porto-env command
+var portoEnvCmd = &cobra.Command{
+	Use:   "porto-env",
+	Short: "Configure environment to use minikube's porto runtime",
+	Long: `Sets up env variables pointing host-side tools at the portod and portoshim of the control plane node:
+the paths of their sockets within the node, and commands running portoctl and crictl there as root over ssh.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sh := shell.EnvConfig{
+			Shell: shell.ForceShell,
+		}
+
+		if portoUnset {
+			if err := portoUnsetScript(PortoEnvConfig{EnvConfig: sh}, os.Stdout); err != nil {
+				exit.Error(reason.InternalEnvScript, "Error generating unset output", err)
+			}
+			return
+		}
+
+		if !out.IsTerminal(os.Stdout) {
+			out.SetSilent(true)
+			exit.SetShell(true)
+		}
+
+		cname := ClusterFlagValue()
+		co := mustload.Running(cname)
+
+		if co.Config.KubernetesConfig.ContainerRuntime != constants.Porto {
+			exit.Message(reason.Usage, `The porto-env command is only compatible with the "porto" runtime, but this cluster was configured to use the "{{.runtime}}" runtime.`,
+				out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime})
+		}
+
+		r := co.CP.Runner
+		if !sysinit.New(r).Active("porto") {
+			exit.Message(reason.EnvPortoUnavailable, `The porto service within '{{.cluster}}' is not active`, out.V{"cluster": cname})
+		}
+
+		cr, err := cruntime.New(cruntime.Config{Type: constants.Porto, Runner: r, Socket: co.Config.KubernetesConfig.CRISocket})
+		if err != nil {
+			exit.Error(reason.InternalNewRuntime, "Failed to create runtime", err)
+		}
+
+		ec := PortoEnvConfig{
+			EnvConfig:       sh,
+			profile:         cname,
+			portodSocket:    cr.(*cruntime.Porto).PortodSocketPath(),
+			portoshimSocket: cr.SocketPath(),
+		}
+
+		// with the none driver the node is this host, which needs no ssh
+		if co.CP.Host.DriverName != driver.None {
+			ec.client, err = createExternalSSHClient(co.CP.Host.Driver)
+			if err != nil {
+				exit.Error(reason.IfSSHClient, "Error getting ssh client", err)
+			}
+		}
+
+		if ec.Shell == "" {
+			ec.Shell, err = shell.Detect()
+			if err != nil {
+				exit.Error(reason.InternalShellDetect, "Error detecting shell", err)
+			}
+		}
+
+		if err := portoSetScript(ec, os.Stdout); err != nil {
+			exit.Error(reason.InternalEnvScript, "Error generating set output", err)
+		}
+	},
+}
+
+// PortoEnvConfig encapsulates all external inputs into shell generation for porto
+type PortoEnvConfig struct {
+	shell.EnvConfig
+	profile         string
+	client          *ssh.ExternalClient
+	portodSocket    string
+	portoshimSocket string
+}
+
+// portoSetScript writes out a shell-compatible 'porto-env' script
+func portoSetScript(ec PortoEnvConfig, w io.Writer) error {
+	envVars := portoEnvVars(ec)
+	return shell.SetScript(w, portoEnvTmpl, portoShellCfgSet(ec, envVars))
+}
+
+// portoUnsetScript writes out a shell-compatible 'porto-env unset' script
+func portoUnsetScript(ec PortoEnvConfig, w io.Writer) error {
+	return shell.UnsetScript(ec.EnvConfig, w, portoEnvNames())
+}
+
+// portoNodeCommand returns the command running args as root on the node, over ssh unless the node is this host
+func portoNodeCommand(client *ssh.ExternalClient, args ...string) string {
+	command := []string{}
+	if client != nil {
+		command = append(command, client.BinaryPath)
+		command = append(command, client.BaseArgs...)
+		command = append(command, "--")
+	}
+	command = append(command, "sudo")
+	command = append(command, args...)
+	return strings.Join(command, " ")
+}
+
+// portoEnvVars gets the necessary porto env variables to allow the use of minikube's porto runtime
+func portoEnvVars(ec PortoEnvConfig) map[string]string {
+	return map[string]string{
+		constants.PortoSocketEnv:         ec.portodSocket,
+		constants.PortoshimSocketEnv:     ec.portoshimSocket,
+		constants.PortoctlEnv:            portoNodeCommand(ec.client, "portoctl"),
+		constants.PortoCrictlEnv:         portoNodeCommand(ec.client, "crictl", "--runtime-endpoint", "unix://"+ec.portoshimSocket),
+		constants.MinikubeActivePortoEnv: ec.profile,
+	}
+}
+
+// portoEnvNames gets the porto env variables to reset after using minikube's porto runtime
+func portoEnvNames() []string {
+	return []string{
+		constants.PortoSocketEnv,
+		constants.PortoshimSocketEnv,
+		constants.PortoctlEnv,
+		constants.PortoCrictlEnv,
+		constants.MinikubeActivePortoEnv,
+	}
+}
+
+func init() {
+	portoEnvCmd.Flags().StringVar(&shell.ForceShell, "shell", "", "Force environment to be configured for a specified shell: [fish, cmd, powershell, tcsh, bash, zsh], default is auto-detect")
+	portoEnvCmd.Flags().BoolVarP(&portoUnset, "unset", "u", false, "Unset variables instead of setting them")
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGeneratePortoScripts(t *testing.T) {
+	unset := map[string]string{
+		"bash": `unset PORTO_SOCKET;
+unset PORTOSHIM_SOCKET;
+unset PORTOCTL;
+unset PORTO_CRICTL;
+unset MINIKUBE_ACTIVE_PORTO;
+`,
+		"fish": `set -e PORTO_SOCKET;
+set -e PORTOSHIM_SOCKET;
+set -e PORTOCTL;
+set -e PORTO_CRICTL;
+set -e MINIKUBE_ACTIVE_PORTO;
+`,
+		"powershell": `Remove-Item Env:\\PORTO_SOCKET
+Remove-Item Env:\\PORTOSHIM_SOCKET
+Remove-Item Env:\\PORTOCTL
+Remove-Item Env:\\PORTO_CRICTL
+Remove-Item Env:\\MINIKUBE_ACTIVE_PORTO
+`,
+		"none": `PORTO_SOCKET
+PORTOSHIM_SOCKET
+PORTOCTL
+PORTO_CRICTL
+MINIKUBE_ACTIVE_PORTO
+`,
+	}
+	var tests = []struct {
+		shell   string
+		config  PortoEnvConfig
+		wantSet string
+	}{
+		{
+			"bash",
+			PortoEnvConfig{profile: "bash", client: newFakeClient(), portodSocket: "/run/portod.socket", portoshimSocket: "/run/portoshim.sock"},
+			`export PORTO_SOCKET="/run/portod.socket"
+export PORTOSHIM_SOCKET="/run/portoshim.sock"
+export PORTOCTL="/usr/bin/ssh root@host -- sudo portoctl"
+export PORTO_CRICTL="/usr/bin/ssh root@host -- sudo crictl --runtime-endpoint unix:///run/portoshim.sock"
+export MINIKUBE_ACTIVE_PORTO="bash"
+
+# To point your shell to minikube's porto runtime, run:
+# eval $(minikube -p bash porto-env)
+# To run the porto tools of the node as if they were installed here, run:
+# alias portoctl="$PORTOCTL" crictl="$PORTO_CRICTL"
+`,
+		},
+		{
+			"fish",
+			PortoEnvConfig{profile: "fish", client: newFakeClient(), portodSocket: "/run/portod.socket", portoshimSocket: "/run/portoshim.sock"},
+			`set -gx PORTO_SOCKET "/run/portod.socket";
+set -gx PORTOSHIM_SOCKET "/run/portoshim.sock";
+set -gx PORTOCTL "/usr/bin/ssh root@host -- sudo portoctl";
+set -gx PORTO_CRICTL "/usr/bin/ssh root@host -- sudo crictl --runtime-endpoint unix:///run/portoshim.sock";
+set -gx MINIKUBE_ACTIVE_PORTO "fish";
+
+# To point your shell to minikube's porto runtime, run:
+# minikube -p fish porto-env | source
+# To run the porto tools of the node as if they were installed here, run:
+# alias portoctl "$PORTOCTL"; alias crictl "$PORTO_CRICTL"
+`,
+		},
+		{
+			"powershell",
+			PortoEnvConfig{profile: "powershell", client: newFakeClient(), portodSocket: "/run/portod.socket", portoshimSocket: "/run/portoshim.sock"},
+			`$Env:PORTO_SOCKET = "/run/portod.socket"
+$Env:PORTOSHIM_SOCKET = "/run/portoshim.sock"
+$Env:PORTOCTL = "/usr/bin/ssh root@host -- sudo portoctl"
+$Env:PORTO_CRICTL = "/usr/bin/ssh root@host -- sudo crictl --runtime-endpoint unix:///run/portoshim.sock"
+$Env:MINIKUBE_ACTIVE_PORTO = "powershell"
+# To point your shell to minikube's porto runtime, run:
+# & minikube -p powershell porto-env --shell powershell | Invoke-Expression
+# To run the porto tools of the node as if they were installed here, run:
+# function portoctl { Invoke-Expression "$Env:PORTOCTL $args" }; function crictl { Invoke-Expression "$Env:PORTO_CRICTL $args" }
+`,
+		},
+		{
+			"none",
+			PortoEnvConfig{profile: "none", portodSocket: "/run/portod.socket", portoshimSocket: "/run/portoshim.sock"},
+			`PORTO_SOCKET=/run/portod.socket
+PORTOSHIM_SOCKET=/run/portoshim.sock
+PORTOCTL=sudo portoctl
+PORTO_CRICTL=sudo crictl --runtime-endpoint unix:///run/portoshim.sock
+MINIKUBE_ACTIVE_PORTO=none
+`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.config.profile, func(t *testing.T) {
+			tc.config.EnvConfig.Shell = tc.shell
+			var b []byte
+			buf := bytes.NewBuffer(b)
+			if err := portoSetScript(tc.config, buf); err != nil {
+				t.Errorf("setScript(%+v) error: %v", tc.config, err)
+			}
+			got := buf.String()
+			if diff := cmp.Diff(tc.wantSet, got); diff != "" {
+				t.Errorf("setScript(%+v) mismatch (-want +got):\n%s\n\nraw output:\n%s\nquoted: %q", tc.config, diff, got, got)
+			}
+
+			buf = bytes.NewBuffer(b)
+			if err := portoUnsetScript(tc.config, buf); err != nil {
+				t.Errorf("unsetScript(%+v) error: %v", tc.config, err)
+			}
+			got = buf.String()
+			if diff := cmp.Diff(unset[tc.shell], got); diff != "" {
+				t.Errorf("unsetScript(%+v) mismatch (-want +got):\n%s\n\nraw output:\n%s\nquoted: %q", tc.config, diff, got, got)
+			}
+		})
+	}
+}
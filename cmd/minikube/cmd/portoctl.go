@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/node"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+)
+
+// portoctlCmd represents the portoctl command
+var portoctlCmd = &cobra.Command{
+	Use:   "portoctl",
+	Short: "Run portoctl inside a node",
+	Long: `Run the porto client as root inside a node of a cluster using the porto runtime. Remember -- before the portoctl arguments!
+
+It runs on the primary control plane, unless --node selects another node.`,
+	Example: "minikube portoctl -- list\nminikube portoctl --node m02 -- get / porto_stat",
+	Run: func(cmd *cobra.Command, args []string) {
+		cname := ClusterFlagValue()
+		co := mustload.Running(cname)
+		if co.Config.KubernetesConfig.ContainerRuntime != constants.Porto {
+			exit.Message(reason.Usage, `The portoctl command is only compatible with the "porto" runtime, but this cluster was configured to use the "{{.runtime}}" runtime.`,
+				out.V{"runtime": co.Config.KubernetesConfig.ContainerRuntime})
+		}
+
+		n := co.CP.Node
+		if nodeName != "" {
+			var err error
+			n, _, err = node.Retrieve(*co.Config, nodeName)
+			if err != nil {
+				exit.Message(reason.GuestNodeRetrieve, "Node {{.nodeName}} does not exist.", out.V{"nodeName": nodeName})
+			}
+		}
+
+		os.Exit(runPortoctl(co, *n, args))
+	},
+}
+
+// runPortoctl runs portoctl with args on the node, attached to the terminal, and returns its exit code
+func runPortoctl(co mustload.ClusterController, n config.Node, args []string) int {
+	// with the none driver the node is this host
+	if co.CP.Host.DriverName == driver.None {
+		c := exec.Command("sudo", append([]string{"portoctl"}, args...)...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		klog.Infof("Running %v", c.Args)
+		return portoctlExitCode(c.Run())
+	}
+
+	// the ssh client runs the command through the shell of the node, which would split and expand the arguments
+	nonRoot, err := cruntime.NonRootSocket(co.Config.KubernetesConfig.ExtraOptions.Get(cruntime.PortoNonRootSocketOption, bsutil.Porto))
+	if err != nil {
+		klog.Warningf("porto extra-config: %v", err)
+	}
+	remote := portoctlRemoteCommand(args, !nonRoot)
+	klog.Infof("Running SSH %v", remote)
+	return portoctlExitCode(machine.CreateSSHShell(co.API, *co.Config, n, remote, false))
+}
+
+// portoctlExitCode returns the exit code of portoctl from the error of running it
+func portoctlExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	out.ErrLn("portoctl: %v", err)
+	return 1
+}
+
+// shellSafe matches the arguments the shell of the node passes on as they are
+var shellSafe = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// portoctlRemoteCommand returns the command running portoctl with args on the node, as root with sudo,
+// with each argument quoted for the shell of the node
+func portoctlRemoteCommand(args []string, sudo bool) []string {
+	remote := []string{"portoctl"}
+	if sudo {
+		remote = append([]string{"sudo"}, remote...)
+	}
+	for _, a := range args {
+		if !shellSafe.MatchString(a) {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		remote = append(remote, a)
+	}
+	return remote
+}
+
+func init() {
+	portoctlCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to run portoctl on. Defaults to the primary control plane.")
+}
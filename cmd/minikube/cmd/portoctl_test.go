@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPortoctlRemoteCommand(t *testing.T) {
+	var tests = []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"sudo", "portoctl"}},
+		{[]string{"get", "/", "porto_stat"}, []string{"sudo", "portoctl", "get", "/", "porto_stat"}},
+		{[]string{"set", "a/b", "command", "sh -c 'echo $HOME'"}, []string{"sudo", "portoctl", "set", "a/b", "command", `'sh -c '\''echo $HOME'\'''`}},
+		{[]string{"list", "*"}, []string{"sudo", "portoctl", "list", "'*'"}},
+		{[]string{"exec", "t", "command=true;false"}, []string{"sudo", "portoctl", "exec", "t", "'command=true;false'"}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, portoctlRemoteCommand(tc.args, true)); diff != "" {
+			t.Errorf("portoctlRemoteCommand(%q) returned diff (-want +got):\n%s", tc.args, diff)
+		}
+	}
+	// with porto.non-root-socket the user of the node reaches portod by itself
+	if diff := cmp.Diff([]string{"portoctl", "list", "'*'"}, portoctlRemoteCommand([]string{"list", "*"}, false)); diff != "" {
+		t.Errorf("portoctlRemoteCommand without sudo returned diff (-want +got):\n%s", diff)
+	}
+}
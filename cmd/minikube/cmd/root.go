@@ -246,6 +246,7 @@ func init() {
 			Commands: []*cobra.Command{
 				dockerEnvCmd,
 				podmanEnvCmd,
+				portoEnvCmd,
 				cacheCmd,
 				imageCmd,
 			},
@@ -273,6 +274,11 @@ func init() {
 				sshCmd,
 				kubectlCmd,
 				nodeCmd,
+				runtimeCmd,
+				portoctlCmd,
+				statsCmd,
+				checkpointCmd,
+				restoreCmd,
 				cpCmd,
 			},
 		},
@@ -283,6 +289,8 @@ func init() {
 				sshHostCmd,
 				ipCmd,
 				logsCmd,
+				eventsCmd,
+				doctorCmd,
 				updateCheckCmd,
 				versionCmd,
 				optionsCmd,
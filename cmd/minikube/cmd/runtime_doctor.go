@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/reason"
+)
+
+var runtimeDoctorFix bool
+
+// runtimeDoctorCmd runs the checks of minikube doctor, which include those of the container runtime of the nodes
+var runtimeDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the container runtime of the nodes for problems, as minikube doctor does",
+	Long:  "Runs the checks of 'minikube doctor', which cover the container runtime of every node along with the host, such as the temporary files left behind by interrupted image operations. With --fix, the problems found are repaired.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube runtime doctor [--fix]")
+		}
+		runDoctor("text", runtimeDoctorFix)
+	},
+}
+
+func init() {
+	runtimeDoctorCmd.Flags().BoolVar(&runtimeDoctorFix, "fix", false, "If set, repair the problems found")
+	runtimeCmd.AddCommand(runtimeDoctorCmd)
+}
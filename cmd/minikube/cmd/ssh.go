@@ -18,10 +18,12 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/machine"
@@ -31,7 +33,10 @@ import (
 	"k8s.io/minikube/pkg/minikube/reason"
 )
 
-var nativeSSHClient bool
+var (
+	nativeSSHClient bool
+	sshContainer    string
+)
 
 // sshCmd represents the docker-ssh command
 var sshCmd = &cobra.Command{
@@ -56,6 +61,11 @@ var sshCmd = &cobra.Command{
 			}
 		}
 
+		if sshContainer != "" {
+			execInContainer(co, *n, sshContainer, args)
+			return
+		}
+
 		err = machine.CreateSSHShell(co.API, *co.Config, *n, args, nativeSSHClient)
 		if err != nil {
 			// This is typically due to a non-zero exit code, so no need for flourish.
@@ -66,7 +76,54 @@ var sshCmd = &cobra.Command{
 	},
 }
 
+// execInContainer runs a command in the running container of the node with the given name, and exits with its exit code
+func execInContainer(co mustload.ClusterController, n config.Node, name string, args []string) {
+	if len(args) == 0 {
+		exit.Message(reason.Usage, "Usage: minikube ssh --container <name> -- <command>")
+	}
+
+	machineName := config.MachineName(*co.Config, n)
+	h, err := machine.LoadHost(co.API, machineName)
+	if err != nil {
+		exit.Error(reason.GuestLoadHost, "Error getting host", err)
+	}
+	r, err := machine.CommandRunner(h)
+	if err != nil {
+		exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: r, Socket: co.Config.KubernetesConfig.CRISocket})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+
+	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, Name: name})
+	if err != nil {
+		exit.Error(reason.GuestListContainers, "Failed to list containers", err)
+	}
+	switch len(ids) {
+	case 0:
+		exit.Message(reason.Usage, "No running container named {{.name}} on {{.node}}", out.V{"name": name, "node": machineName})
+	case 1:
+	default:
+		exit.Message(reason.Usage, "{{.count}} running containers are named {{.name}} on {{.node}}: {{.ids}}", out.V{"count": len(ids), "name": name, "node": machineName, "ids": strings.Join(ids, ", ")})
+	}
+
+	rr, err := cr.ExecContainer(ids[0], args)
+	if rr != nil {
+		os.Stdout.Write(rr.Stdout.Bytes())
+		os.Stderr.Write(rr.Stderr.Bytes())
+	}
+	if err != nil {
+		out.ErrLn("ssh: %v", err)
+		if rr != nil && rr.ExitCode > 0 {
+			os.Exit(rr.ExitCode)
+		}
+		os.Exit(1)
+	}
+}
+
 func init() {
 	sshCmd.Flags().BoolVar(&nativeSSHClient, "native-ssh", true, "Use native Golang SSH client (default true). Set to 'false' to use the command line 'ssh' command when accessing the docker machine. Useful for the machine drivers when they will not start with 'Waiting for SSH'.")
 	sshCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to ssh into. Defaults to the primary control plane.")
+	sshCmd.Flags().StringVar(&sshContainer, "container", "", "Run the command in the running container with this name on the node, rather than on the node itself. Example: minikube ssh --container etcd -- etcdctl version")
 }
@@ -26,6 +26,7 @@ import (
 	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"sort"
@@ -80,6 +81,7 @@ import (
 
 	"k8s.io/minikube/pkg/minikube/registry"
 	"k8s.io/minikube/pkg/minikube/translate"
+	"k8s.io/minikube/pkg/minikube/vmpath"
 	"k8s.io/minikube/pkg/util"
 	"k8s.io/minikube/pkg/version"
 )
@@ -162,6 +164,7 @@ func runStart(cmd *cobra.Command, _ []string) {
 	defer pkgtrace.Cleanup()
 
 	displayVersion(version.GetVersion())
+	download.SetDownloadRetries(viper.GetInt(downloadRetries))
 	go download.CleanUpOlderPreloads()
 
 	// Avoid blocking execution on optional HTTP fetches
@@ -267,13 +270,11 @@ func runStart(cmd *cobra.Command, _ []string) {
 	}
 
 	validateBuiltImageVersion(starter.Runner, ds.Name)
+	validatePortoISO(starter.Runner, starter.Cfg)
 
 	if existing != nil && driver.IsKIC(existing.Driver) {
 		if viper.GetBool(createMount) {
-			old := ""
-			if len(existing.ContainerVolumeMounts) > 0 {
-				old = existing.ContainerVolumeMounts[0]
-			}
+			old := containerMount(existing)
 			if mount := viper.GetString(mountString); old != mount {
 				exit.Message(reason.GuestMountConflict, "Sorry, {{.driver}} does not allow mounts to be changed after container creation (previous mount: '{{.old}}', new mount: '{{.new}})'", out.V{
 					"driver": existing.Driver,
@@ -295,6 +296,17 @@ func runStart(cmd *cobra.Command, _ []string) {
 	}
 }
 
+// containerMount returns the --mount of a cluster created by a container driver, or "" if it has none.
+// The image cache the host shares with --shared-image-cache is mounted along with it, and may be the only mount.
+func containerMount(cc *config.ClusterConfig) string {
+	for _, m := range cc.ContainerVolumeMounts {
+		if !strings.HasSuffix(m, ":"+vmpath.GuestImageCacheDir+":ro") {
+			return m
+		}
+	}
+	return ""
+}
+
 func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *config.ClusterConfig) (node.Starter, error) {
 	driverName := ds.Name
 	klog.Infof("selected driver: %s", driverName)
@@ -305,6 +317,7 @@ func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *
 	}
 
 	virtualBoxMacOS13PlusWarning(driverName)
+	resolveAutoRuntime(existing, driverName)
 	validateFlags(cmd, driverName)
 	validateUser(driverName)
 	if driverName == oci.Docker {
@@ -335,6 +348,7 @@ func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *
 		stopk8s = true
 	}
 
+	selectRuntimeInteractively(cmd, existing, driverName)
 	rtime := getContainerRuntime(existing)
 	cc, n, err := generateClusterConfig(cmd, existing, k8sVersion, rtime, driverName)
 	if err != nil {
@@ -393,6 +407,7 @@ func provisionWithDriver(cmd *cobra.Command, ds registry.DriverState, existing *
 		ExistingAddons: existingAddons,
 		Cfg:            &cc,
 		Node:           &n,
+		MigrateFrom:    runtimeMigration(existing, rtime),
 	}, nil
 }
 
@@ -433,6 +448,31 @@ func validateBuiltImageVersion(r command.Runner, driverName string) {
 	}
 }
 
+// validatePortoISO exits with the ISO to start with instead when the ISO of a porto node predates the porto packages,
+// rather than letting the start fail on a missing binary deep in the configuration of the runtime
+func validatePortoISO(r command.Runner, cc *config.ClusterConfig) {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto || !driver.IsVM(cc.Driver) {
+		return
+	}
+	missing := cruntime.MissingPortoBinaries(r)
+	if len(missing) == 0 {
+		return
+	}
+	iso := "of unknown version"
+	if rr, err := r.RunCmd(exec.Command("cat", "/version.json")); err == nil {
+		var v versionJSON
+		if err := json.Unmarshal(rr.Stdout.Bytes(), &v); err == nil && v.IsoVersion != "" {
+			iso = v.IsoVersion
+		}
+	}
+	exit.Message(reason.RuntimePortoISO, "The ISO {{.iso}} of the node lacks {{.missing}}, which --container-runtime=porto needs. porto comes with ISO {{.required}} and newer: run 'minikube delete --purge' and start again, or start with --iso-url={{.url}}", out.V{
+		"iso":      iso,
+		"missing":  strings.Join(missing, ", "),
+		"required": cruntime.PortoMinISOVersion,
+		"url":      download.DefaultISOURLs()[0],
+	})
+}
+
 func imageMatchesBinaryVersion(imageVersion, binaryVersion string) bool {
 	if binaryVersion == imageVersion {
 		return true
@@ -1307,6 +1347,34 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 		validateCNI(cmd, viper.GetString(containerRuntime))
 	}
 
+	if cmd.Flags().Changed(preloadConcurrency) && viper.GetInt(preloadConcurrency) < 1 {
+		exit.Message(reason.Usage, "Sorry, --preload-concurrency must be at least 1, got {{.n}}", out.V{"n": viper.GetInt(preloadConcurrency)})
+	}
+
+	if viper.GetBool(sharedImageCache) && (!driver.IsKIC(drvName) || viper.GetString(containerRuntime) != constants.Porto) {
+		out.WarningT("Ignoring --shared-image-cache, it is only supported by the porto container runtime with the docker and podman drivers")
+	}
+
+	if _, err := cruntime.ParseFeatureGates(viper.GetString(runtimeFeatureGates)); err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+
+	if hook := viper.GetString(postRuntimeHook); hook != "" {
+		validatePostRuntimeHook(hook)
+	}
+
+	if shim := viper.GetString(wasmShim); shim != "" {
+		validateWasmShim(shim)
+	}
+
+	if rc := viper.GetString(runtimeClass); rc != "" {
+		validateRuntimeClass(rc)
+	}
+
+	if viper.GetInt(downloadRetries) < 0 {
+		exit.Message(reason.Usage, "Sorry, --download-retries can't be negative, got {{.n}}", out.V{"n": viper.GetInt(downloadRetries)})
+	}
+
 	if cmd.Flags().Changed(staticIP) {
 		if err := validateStaticIP(viper.GetString(staticIP), drvName, viper.GetString(subnet)); err != nil {
 			exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
@@ -1354,6 +1422,29 @@ func validateFlags(cmd *cobra.Command, drvName string) {
 		}
 	}
 
+	// check that porto extra args are known and well formed
+	for param, value := range config.ExtraOptions.AsMap().Get(bsutil.Porto) {
+		var err error
+		switch param {
+		case cruntime.PortoDownloadTmpfsOption:
+			_, err = cruntime.DownloadTmpfsSize(value, 0)
+		case cruntime.PortoUlimitsOption:
+			_, err = cruntime.ParseUlimits(value)
+		case cruntime.PortoAllowedUnsafeSysctlsOption:
+		case cruntime.PortoBuildCacheOption:
+			_, err = cruntime.BuildCacheSize(value)
+		case cruntime.PortoNonRootSocketOption:
+			_, err = cruntime.NonRootSocket(value)
+		case cruntime.PortoRegistryCertsOption:
+			_, err = cruntime.RegistryCertsDir(value, viper.GetString(mountString))
+		default:
+			exit.Message(reason.Usage, "Sorry, the porto.{{.parameter_name}} parameter is currently not supported by --extra-config", out.V{"parameter_name": param})
+		}
+		if err != nil {
+			exit.Message(reason.Usage, "Invalid value for porto.{{.parameter_name}}: {{.error}}", out.V{"parameter_name": param, "error": err})
+		}
+	}
+
 	if outputFormat != "text" && outputFormat != "json" {
 		exit.Message(reason.Usage, "Sorry, please set the --output flag to one of the following valid options: [text,json]")
 	}
@@ -1428,7 +1519,7 @@ func validateRuntime(rtime string) error {
 	// `crio` is accepted as an alternative spelling to `cri-o`
 	validOptions = append(validOptions, constants.CRIO)
 
-	if rtime == constants.DefaultContainerRuntime {
+	if rtime == constants.DefaultContainerRuntime || rtime == constants.AutoContainerRuntime {
 		return nil
 	}
 
@@ -1455,6 +1546,56 @@ func validateRuntime(rtime string) error {
 	return nil
 }
 
+// validatePostRuntimeHook validates that the post-runtime hook is a regular file, and makes its path absolute,
+// so that later starts from other directories find it
+func validatePostRuntimeHook(hook string) {
+	abs, err := filepath.Abs(hook)
+	if err != nil {
+		exit.Message(reason.Usage, "Invalid --post-runtime-hook {{.path}}: {{.err}}", out.V{"path": hook, "err": err})
+	}
+	fi, err := os.Stat(abs)
+	if err != nil || !fi.Mode().IsRegular() {
+		exit.Message(reason.Usage, "Sorry, --post-runtime-hook must be a script on the host, {{.path}} is not a file", out.V{"path": hook})
+	}
+	viper.Set(postRuntimeHook, abs)
+	if rtime := viper.GetString(containerRuntime); rtime != "" && rtime != constants.Porto {
+		out.WarningT("Ignoring --post-runtime-hook, it is only supported by the porto container runtime")
+	}
+}
+
+// validateWasmShim exits unless shim is a known WebAssembly shim which the container runtime can install
+func validateWasmShim(shim string) {
+	if _, err := cruntime.WasmShimNamed(shim); err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+	if cr := selectedRuntime(); !cr.Capabilities().SupportsWasm {
+		exit.Message(reason.RuntimeUnsupportedFeature, "The {{.runtime}} container runtime can't run WebAssembly workloads, --wasm-shim requires --container-runtime=containerd", out.V{"runtime": cr.Name()})
+	}
+}
+
+// validateRuntimeClass exits unless rc is a known sandboxed runtime which the container runtime can install
+func validateRuntimeClass(rc string) {
+	if rc != cruntime.KataRuntimeClass {
+		exit.Message(reason.Usage, "Unknown --runtime-class {{.class}}, valid ones are: {{.valid}}", out.V{"class": rc, "valid": cruntime.KataRuntimeClass})
+	}
+	if cr := selectedRuntime(); !cr.Capabilities().SupportsKata {
+		exit.Message(reason.RuntimeUnsupportedFeature, "The {{.runtime}} container runtime can't run pods in Kata Containers, --runtime-class=kata requires --container-runtime=containerd or cri-o", out.V{"runtime": cr.Name()})
+	}
+}
+
+// selectedRuntime returns the container runtime --container-runtime selects, for asking it about its capabilities
+func selectedRuntime() cruntime.Manager {
+	rtime := viper.GetString(containerRuntime)
+	if rtime == constants.DefaultContainerRuntime {
+		rtime = defaultRuntime()
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: rtime})
+	if err != nil {
+		exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+	}
+	return cr
+}
+
 // validateGPUs validates that a valid option was given, and if so, can it be used with the given configuration
 func validateGPUs(value, drvName, rtime string) error {
 	if value == "" {
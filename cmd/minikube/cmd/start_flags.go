@@ -44,6 +44,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/proxy"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/vmpath"
 	pkgutil "k8s.io/minikube/pkg/util"
 	"k8s.io/minikube/pkg/version"
 )
@@ -60,6 +61,15 @@ const (
 	hostOnlyCIDR            = "host-only-cidr"
 	containerRuntime        = "container-runtime"
 	criSocket               = "cri-socket"
+	runtimeDebug            = "runtime-debug"
+	preloadConcurrency      = "preload-concurrency"
+	downloadRetries         = "download-retries"
+	sharedImageCache        = "shared-image-cache"
+	runtimeFeatureGates     = "runtime-feature-gates"
+	upgradePorto            = "upgrade-porto"
+	postRuntimeHook         = "post-runtime-hook"
+	wasmShim                = "wasm-shim"
+	runtimeClass            = "runtime-class"
 	networkPlugin           = "network-plugin"
 	enableDefaultCNI        = "enable-default-cni"
 	cniFlag                 = "cni"
@@ -169,7 +179,7 @@ func initMinikubeFlags() {
 	startCmd.Flags().String(kicBaseImage, kic.BaseImage, "The base image to use for docker/podman drivers. Intended for local development.")
 	startCmd.Flags().Bool(keepContext, false, "This will keep the existing kubectl context and will create a minikube context.")
 	startCmd.Flags().Bool(embedCerts, false, "if true, will embed the certs in kubeconfig.")
-	startCmd.Flags().String(containerRuntime, constants.DefaultContainerRuntime, fmt.Sprintf("The container runtime to be used. Valid options: %s (default: auto)", strings.Join(cruntime.ValidRuntimes(), ", ")))
+	startCmd.Flags().String(containerRuntime, constants.DefaultContainerRuntime, fmt.Sprintf("The container runtime to be used. Valid options: %s, or %s to pick the first of %s the driver and host support (default: docker)", strings.Join(cruntime.ValidRuntimes(), ", "), constants.AutoContainerRuntime, strings.Join(autoRuntimeOrder, ", ")))
 	startCmd.Flags().Bool(createMount, false, "This will start the mount daemon and automatically mount files into minikube.")
 	startCmd.Flags().String(mountString, constants.DefaultMountDir+":/minikube-host", "The argument to pass the minikube mount command on start.")
 	startCmd.Flags().String(mount9PVersion, defaultMount9PVersion, mount9PVersionDescription)
@@ -182,6 +192,15 @@ func initMinikubeFlags() {
 	startCmd.Flags().String(mountUID, defaultMountUID, mountUIDDescription)
 	startCmd.Flags().StringSlice(config.AddonListFlag, nil, "Enable addons. see `minikube addons list` for a list of valid addon names.")
 	startCmd.Flags().String(criSocket, "", "The cri socket path to be used.")
+	startCmd.Flags().Bool(runtimeDebug, false, "If set, raise the log levels of the container runtime, log CRI requests, keep the temporary files of interrupted image operations and record the commands run while provisioning the node, so they show up in 'minikube logs'. Defaults to false.")
+	startCmd.Flags().Int(preloadConcurrency, constants.DefaultPreloadConcurrency, "Number of images to pull at once when no preload tarball is available for the container runtime (currently porto only)")
+	startCmd.Flags().Bool(sharedImageCache, false, "If set, mount the image cache of the host read-only into the node, so that porto loads cached images from it instead of every profile receiving its own copy (docker and podman drivers only)")
+	startCmd.Flags().String(runtimeFeatureGates, "", fmt.Sprintf("A set of name=true|false pairs enabling experimental features of the container runtime (currently porto only). Known gates: %s", strings.Join(cruntime.KnownFeatureGates(), ", ")))
+	startCmd.Flags().String(postRuntimeHook, "", "Path to a script on the host which is copied to each node and run as root once the container runtime is enabled, with MINIKUBE_RUNTIME, MINIKUBE_CRI_SOCKET, MINIKUBE_RUNTIME_VERSION, MINIKUBE_CGROUP_DRIVER and MINIKUBE_PROFILE set, for site-specific tweaks such as mirrors, certificates or monitoring agents (currently porto only)")
+	startCmd.Flags().String(wasmShim, "", fmt.Sprintf("A WebAssembly shim to install into the nodes, with a RuntimeClass of the same name for pods to run WebAssembly workloads with (currently containerd only). Valid options: %s", strings.Join(cruntime.WasmShimNames(), ", ")))
+	startCmd.Flags().String(runtimeClass, "", fmt.Sprintf("A sandboxed runtime to install into the nodes, with a RuntimeClass of the same name for pods to opt into (containerd and cri-o only, needs KVM on the nodes). Valid options: %s", cruntime.KataRuntimeClass))
+	startCmd.Flags().Bool(upgradePorto, false, "If set, upgrade porto in place on an existing cluster whose node runs an older porto than this minikube ships, instead of asking")
+	startCmd.Flags().Int(downloadRetries, constants.DefaultDownloadRetries, "Number of times to retry a failed download of the ISO, kubernetes binaries or preload tarball, resuming it where the server allows")
 	startCmd.Flags().String(networkPlugin, "", "DEPRECATED: Replaced by --cni")
 	startCmd.Flags().Bool(enableDefaultCNI, false, "DEPRECATED: Replaced by --cni=bridge")
 	startCmd.Flags().String(cniFlag, "", "CNI plug-in to use. Valid options: auto, bridge, calico, cilium, flannel, kindnet, or path to a CNI manifest (default: auto)")
@@ -215,8 +234,14 @@ func initKubernetesFlags() {
 	startCmd.Flags().Var(&config.ExtraOptions, "extra-config",
 		`A set of key=value pairs that describe configuration that may be passed to different components.
 		The key should be '.' separated, and the first part before the dot is the component to apply the configuration to.
-		Valid components are: kubelet, kubeadm, apiserver, controller-manager, etcd, proxy, scheduler
-		Valid kubeadm parameters: `+fmt.Sprintf("%s, %s", strings.Join(bsutil.KubeadmExtraArgsAllowed[bsutil.KubeadmCmdParam], ", "), strings.Join(bsutil.KubeadmExtraArgsAllowed[bsutil.KubeadmConfigParam], ",")))
+		Valid components are: kubelet, kubeadm, apiserver, controller-manager, etcd, proxy, scheduler, porto
+		Valid kubeadm parameters: `+fmt.Sprintf("%s, %s", strings.Join(bsutil.KubeadmExtraArgsAllowed[bsutil.KubeadmCmdParam], ", "), strings.Join(bsutil.KubeadmExtraArgsAllowed[bsutil.KubeadmConfigParam], ","))+`
+		Valid porto parameters: `+cruntime.PortoDownloadTmpfsOption+` (true, or a tmpfs size such as 2g, for image layer downloads), `+
+			cruntime.PortoUlimitsOption+` (default container ulimits such as nofile=1048576,nproc=65536:131072), `+
+			cruntime.PortoAllowedUnsafeSysctlsOption+` (comma-separated sysctls pods may set, also passed to kubelet), `+
+			cruntime.PortoBuildCacheOption+` (size of the layer cache of image builds such as 20g, or false to disable it), `+
+			cruntime.PortoNonRootSocketOption+` (true to let the user of the node reach the porto sockets without sudo), `+
+			cruntime.PortoRegistryCertsOption+` (directory of the node with registry certificates in the docker certs.d layout, or true for the target of --mount-string)`)
 	startCmd.Flags().String(featureGates, "", "A set of key=value pairs that describe feature gates for alpha/experimental features.")
 	startCmd.Flags().String(dnsDomain, constants.ClusterDNSDomain, "The cluster dns domain name used in the Kubernetes cluster")
 	startCmd.Flags().Int(apiServerPort, constants.APIServerPort, "The apiserver listening port")
@@ -584,6 +609,12 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 		SocketVMnetClientPath:   detect.SocketVMNetClientPath(),
 		SocketVMnetPath:         detect.SocketVMNetPath(),
 		StaticIP:                viper.GetString(staticIP),
+		RuntimeDebug:            viper.GetBool(runtimeDebug),
+		PreloadConcurrency:      viper.GetInt(preloadConcurrency),
+		RuntimeFeatureGates:     viper.GetString(runtimeFeatureGates),
+		PostRuntimeHook:         viper.GetString(postRuntimeHook),
+		WasmShim:                viper.GetString(wasmShim),
+		RuntimeClass:            viper.GetString(runtimeClass),
 		KubernetesConfig: config.KubernetesConfig{
 			KubernetesVersion:      k8sVersion,
 			ClusterName:            ClusterFlagValue(),
@@ -611,6 +642,10 @@ func generateNewConfigFromFlags(cmd *cobra.Command, k8sVersion string, rtime str
 	if viper.GetBool(createMount) && driver.IsKIC(drvName) {
 		cc.ContainerVolumeMounts = []string{viper.GetString(mountString)}
 	}
+	if viper.GetBool(sharedImageCache) && driver.IsKIC(drvName) && rtime == constants.Porto {
+		cc.SharedImageCache = true
+		cc.ContainerVolumeMounts = append(cc.ContainerVolumeMounts, fmt.Sprintf("%s:%s:ro", detect.ImageCacheDir(), vmpath.GuestImageCacheDir))
+	}
 
 	if driver.IsKIC(drvName) {
 		si, err := oci.CachedDaemonInfo(drvName)
@@ -821,6 +856,12 @@ func updateExistingConfigFromFlags(cmd *cobra.Command, existing *config.ClusterC
 	updateStringFromFlag(cmd, &cc.SocketVMnetClientPath, socketVMnetClientPath)
 	updateStringFromFlag(cmd, &cc.SocketVMnetPath, socketVMnetPath)
 	updateDurationFromFlag(cmd, &cc.AutoPauseInterval, autoPauseInterval)
+	updateBoolFromFlag(cmd, &cc.RuntimeDebug, runtimeDebug)
+	updateIntFromFlag(cmd, &cc.PreloadConcurrency, preloadConcurrency)
+	updateStringFromFlag(cmd, &cc.RuntimeFeatureGates, runtimeFeatureGates)
+	updateStringFromFlag(cmd, &cc.PostRuntimeHook, postRuntimeHook)
+	updateStringFromFlag(cmd, &cc.WasmShim, wasmShim)
+	updateStringFromFlag(cmd, &cc.RuntimeClass, runtimeClass)
 
 	if cmd.Flags().Changed(kubernetesVersion) {
 		kubeVer, err := getKubernetesVersion(existing)
@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/drivers/kic"
+	"k8s.io/minikube/pkg/drivers/kic/oci"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+)
+
+// runtimeChoice is a container runtime offered when asking which one to use, with what it is good and bad at
+type runtimeChoice struct {
+	name      string
+	tradeoffs string
+}
+
+// runtimeChoices are the container runtimes offered, in the order of preference, the default first
+var runtimeChoices = []runtimeChoice{
+	{constants.Docker, "the default; works with docker-env and docker-based tooling, but adds the cri-dockerd shim between kubelet and the runtime"},
+	{constants.Containerd, "what most Kubernetes distributions run; lighter than docker, images are built with minikube image build"},
+	{constants.CRIO, "built for Kubernetes only and follows its releases closely; works with podman-env"},
+	{constants.Porto, "runs pods as porto containers with strict resource isolation and portoctl for inspection; docker-env only through the porto-docker-shim addon"},
+}
+
+// viableRuntimes returns the runtime choices which can run on the driver, none when there is no choice to make
+func viableRuntimes(drvName string) []runtimeChoice {
+	if driver.BareMetal(drvName) || driver.IsSSH(drvName) {
+		// the runtime is whatever the machine has installed
+		return nil
+	}
+	if viper.GetString(gpus) != "" {
+		// GPUs are only passed through to the docker runtime
+		return nil
+	}
+	var viable []runtimeChoice
+	for _, c := range runtimeChoices {
+		if err := validateRuntime(c.name); err != nil {
+			klog.Infof("not offering the %s runtime: %v", c.name, err)
+			continue
+		}
+		viable = append(viable, c)
+	}
+	if len(viable) < 2 {
+		return nil
+	}
+	return viable
+}
+
+// selectRuntimeInteractively asks which container runtime a new cluster should use, when none was given and there is more
+// than one the driver can run, and saves the answer to the minikube config so that it becomes the default of new clusters.
+// It never asks when minikube is not allowed to prompt, so non-interactive starts keep the docker default.
+func selectRuntimeInteractively(cmd *cobra.Command, existing *config.ClusterConfig, drvName string) {
+	if existing != nil || cmd.Flags().Changed(containerRuntime) || viper.GetString(containerRuntime) != constants.DefaultContainerRuntime {
+		return
+	}
+	if !viper.GetBool(interactive) || viper.GetBool(dryRun) || outputFormat == "json" || !out.IsTerminal(os.Stdin) || !out.IsTerminal(os.Stdout) {
+		return
+	}
+	choices := viableRuntimes(drvName)
+	if len(choices) == 0 {
+		return
+	}
+
+	out.Step(style.Notice, "Which container runtime should Kubernetes use?")
+	for i, c := range choices {
+		out.Infof("{{.n}}) {{.name}}: {{.tradeoffs}}", out.V{"n": i + 1, "name": c.name, "tradeoffs": c.tradeoffs})
+	}
+	rtime := askRuntime(bufio.NewReader(os.Stdin), choices)
+	viper.Set(containerRuntime, rtime)
+
+	if err := saveRuntime(rtime); err != nil {
+		klog.Warningf("failed to save the container runtime: %v", err)
+		return
+	}
+	out.Styled(style.Tip, "New clusters will use the {{.runtime}} runtime. To be asked again, run: minikube config unset container-runtime", out.V{"runtime": rtime})
+}
+
+// saveRuntime sets the container runtime in the minikube config, as minikube config set container-runtime does
+func saveRuntime(rtime string) error {
+	m, err := config.ReadConfig(localpath.ConfigFile())
+	if err != nil {
+		return err
+	}
+	m[containerRuntime] = rtime
+	return config.WriteConfig(localpath.ConfigFile(), m)
+}
+
+// askRuntime reads the number or name of one of choices from r, the first one if the answer is empty
+func askRuntime(r *bufio.Reader, choices []runtimeChoice) string {
+	for {
+		out.String("Container runtime [1]: ")
+		answer, err := r.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "" {
+			return choices[0].name
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1].name
+		}
+		for _, c := range choices {
+			if answer == c.name || (c.name == constants.CRIO && answer == "cri-o") {
+				return c.name
+			}
+		}
+		if err != nil {
+			klog.Warningf("reading the container runtime: %v", err)
+			return choices[0].name
+		}
+		out.Err("Please enter a number between 1 and %d, or a runtime name\n", len(choices))
+	}
+}
+
+// autoRuntimeOrder is the order in which --container-runtime=auto prefers the runtimes, picking the first one the setup
+// supports: porto, which this fork is built around, then containerd, which needs no shim between kubelet and the runtime,
+// then docker, which every driver supports
+var autoRuntimeOrder = []string{constants.Porto, constants.Containerd, constants.Docker}
+
+// kicImageHasPorto returns if the kic base image ships porto. Only an image which is present already is probed, so
+// that this does not pull it: the pinned one is decided from its version otherwise, and any other one is unknown.
+var kicImageHasPorto = func(ociBin, image string) (bool, error) {
+	if oci.ImageExistsLocally(ociBin, image) {
+		return oci.ImageHasExecutable(ociBin, image, "/usr/sbin/portod")
+	}
+	if image == kic.BaseImage || slices.Contains(kic.FallbackImages, image) {
+		return kicVersionHasPorto(kic.Version, kic.PortoVersion), nil
+	}
+	return false, fmt.Errorf("%s is not present yet", image)
+}
+
+// kicVersionHasPorto returns if the kic base image of version ships porto, which it does from version since on
+func kicVersionHasPorto(version, since string) bool {
+	if since == "" {
+		return false
+	}
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	first, err := semver.ParseTolerant(since)
+	if err != nil {
+		return false
+	}
+	return v.GTE(first)
+}
+
+// autoRuntimeUnsupported returns why the driver, its ISO or the host can't run rtime, or "" when they can
+func autoRuntimeUnsupported(drvName, rtime string) string {
+	if rtime == constants.Docker {
+		return ""
+	}
+	if viper.GetString(gpus) != "" {
+		return "GPUs are only passed through to the docker runtime"
+	}
+	if err := validateRuntime(rtime); err != nil {
+		return err.Error()
+	}
+	binary := map[string]string{constants.Porto: "portod", constants.Containerd: "containerd"}[rtime]
+	switch {
+	case driver.IsSSH(drvName):
+		return fmt.Sprintf("the machine of the %s driver might not have %s installed", drvName, rtime)
+	case driver.BareMetal(drvName):
+		// the runtime is whatever this host has installed
+		if _, err := exec.LookPath(binary); err != nil {
+			return fmt.Sprintf("%s is not installed on this host", binary)
+		}
+		return ""
+	}
+	if rtime != constants.Porto {
+		return ""
+	}
+	if arch := detect.RuntimeArch(); arch != "amd64" && arch != "arm64" {
+		return fmt.Sprintf("porto is not released for %s", arch)
+	}
+	if driver.IsKIC(drvName) && runtime.GOOS != "linux" {
+		return fmt.Sprintf("the %s driver only runs nodes on the kernel of the host, which porto needs, on Linux", drvName)
+	}
+	if driver.IsKIC(drvName) {
+		image := viper.GetString(kicBaseImage)
+		ok, err := kicImageHasPorto(drvName, image)
+		if err != nil {
+			klog.Infof("unable to check %s for porto: %v", image, err)
+			return fmt.Sprintf("the base image %s could not be checked for porto", image)
+		}
+		if !ok {
+			return fmt.Sprintf("the base image %s does not ship porto", image)
+		}
+	}
+	if driver.IsVM(drvName) && !slices.Equal(viper.GetStringSlice(isoURL), download.DefaultISOURLs()) {
+		return "the ISO of --iso-url might predate porto"
+	}
+	return ""
+}
+
+// autoRuntime returns the first runtime of autoRuntimeOrder the driver, its ISO and the host support,
+// along with why it was picked over those before it
+func autoRuntime(drvName string) (string, string) {
+	var skipped []string
+	for _, rtime := range autoRuntimeOrder {
+		why := autoRuntimeUnsupported(drvName, rtime)
+		if why == "" {
+			if len(skipped) == 0 {
+				return rtime, fmt.Sprintf("the %s driver supports it", drvName)
+			}
+			return rtime, strings.Join(skipped, "; ")
+		}
+		klog.Infof("--container-runtime=auto skips %s: %s", rtime, why)
+		skipped = append(skipped, fmt.Sprintf("not %s, as %s", rtime, why))
+	}
+	// docker is last and supported everywhere
+	return constants.Docker, strings.Join(skipped, "; ")
+}
+
+// resolveAutoRuntime replaces --container-runtime=auto with the runtime autoRuntime picks and tells why, so that the
+// profile records the runtime the cluster runs. Existing clusters keep their runtime, which auto does not change.
+func resolveAutoRuntime(existing *config.ClusterConfig, drvName string) {
+	if viper.GetString(containerRuntime) != constants.AutoContainerRuntime {
+		return
+	}
+	if existing != nil && existing.KubernetesConfig.ContainerRuntime != "" {
+		klog.Infof("--container-runtime=auto keeps the %s runtime of the existing cluster", existing.KubernetesConfig.ContainerRuntime)
+		viper.Set(containerRuntime, existing.KubernetesConfig.ContainerRuntime)
+		return
+	}
+	rtime, why := autoRuntime(drvName)
+	viper.Set(containerRuntime, rtime)
+	out.Step(style.Notice, "Using the {{.runtime}} container runtime, picked by --container-runtime=auto: {{.why}}", out.V{"runtime": rtime, "why": why})
+}
+
+// runtimeMigration returns the container runtime an existing cluster moves away from when it is started with another one,
+// if minikube can carry its images over, see cruntime.Migration
+func runtimeMigration(existing *config.ClusterConfig, rtime string) string {
+	if existing == nil {
+		return ""
+	}
+	from := existing.KubernetesConfig.ContainerRuntime
+	if from == "" || from == rtime {
+		return ""
+	}
+	if !cruntime.MigrationSupported(from, rtime) {
+		out.WarningT("The cluster moves from the {{.from}} to the {{.to}} runtime without its images; minikube only migrates clusters from containerd to porto", out.V{"from": from, "to": rtime})
+		return ""
+	}
+	return from
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+func TestViableRuntimes(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skipf("the cri-o runtime is not offered on %s", runtime.GOARCH)
+	}
+	defer viper.Set(gpus, "")
+
+	if got := viableRuntimes(driver.None); got != nil {
+		t.Errorf("viableRuntimes(none) = %v, want no choice", got)
+	}
+	if got := viableRuntimes(driver.SSH); got != nil {
+		t.Errorf("viableRuntimes(ssh) = %v, want no choice", got)
+	}
+	got := viableRuntimes(driver.Docker)
+	if len(got) != len(runtimeChoices) || got[0].name != constants.Docker {
+		t.Errorf("viableRuntimes(docker) = %v, want all runtimes with docker first", got)
+	}
+	viper.Set(gpus, "all")
+	if got := viableRuntimes(driver.Docker); got != nil {
+		t.Errorf("viableRuntimes(docker) with GPUs = %v, want no choice", got)
+	}
+}
+
+func TestAskRuntime(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{answer: "\n", want: constants.Docker},
+		{answer: "", want: constants.Docker},
+		{answer: "4\n", want: constants.Porto},
+		{answer: "Porto\n", want: constants.Porto},
+		{answer: "cri-o\n", want: constants.CRIO},
+		{answer: "9\nkata\n2\n", want: constants.Containerd},
+	}
+	for _, tc := range tests {
+		t.Run(strings.TrimSpace(tc.answer), func(t *testing.T) {
+			if got := askRuntime(bufio.NewReader(strings.NewReader(tc.answer)), runtimeChoices); got != tc.want {
+				t.Errorf("askRuntime(%q) = %q, want %q", tc.answer, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAutoRuntime(t *testing.T) {
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skipf("porto nodes do not run on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	defer viper.Set(gpus, "")
+	defer viper.Set(isoURL, nil)
+	defer func(f func(string, string) (bool, error)) { kicImageHasPorto = f }(kicImageHasPorto)
+
+	tests := []struct {
+		driver  string
+		gpus    string
+		iso     []string
+		noPorto bool
+		want    string
+	}{
+		{driver: driver.Docker, want: constants.Porto},
+		{driver: driver.Docker, noPorto: true, want: constants.Containerd},
+		{driver: driver.KVM2, want: constants.Porto},
+		{driver: driver.KVM2, iso: []string{"file:///tmp/old.iso"}, want: constants.Containerd},
+		{driver: driver.Docker, gpus: "all", want: constants.Docker},
+		{driver: driver.SSH, want: constants.Docker},
+	}
+	for _, tc := range tests {
+		t.Run(tc.driver, func(t *testing.T) {
+			viper.Set(gpus, tc.gpus)
+			viper.Set(isoURL, download.DefaultISOURLs())
+			kicImageHasPorto = func(string, string) (bool, error) { return !tc.noPorto, nil }
+			if tc.iso != nil {
+				viper.Set(isoURL, tc.iso)
+			}
+			got, why := autoRuntime(tc.driver)
+			if got != tc.want {
+				t.Errorf("autoRuntime(%s) = %s (%s), want %s", tc.driver, got, why, tc.want)
+			}
+			if why == "" {
+				t.Errorf("autoRuntime(%s) gave no reason for %s", tc.driver, got)
+			}
+		})
+	}
+}
+
+func TestKicVersionHasPorto(t *testing.T) {
+	tests := []struct {
+		version string
+		since   string
+		want    bool
+	}{
+		{version: "v0.0.42-1704751654-17830", since: "", want: false},
+		{version: "v0.0.42-1704751654-17830", since: "v0.0.43", want: false},
+		{version: "v0.0.43", since: "v0.0.43", want: true},
+		{version: "v0.0.44-1718000000-18000", since: "v0.0.43", want: true},
+		{version: "latest", since: "v0.0.43", want: false},
+	}
+	for _, tc := range tests {
+		if got := kicVersionHasPorto(tc.version, tc.since); got != tc.want {
+			t.Errorf("kicVersionHasPorto(%q, %q) = %v, want %v", tc.version, tc.since, got, tc.want)
+		}
+	}
+}
+
+func TestResolveAutoRuntime(t *testing.T) {
+	defer viper.Set(containerRuntime, constants.DefaultContainerRuntime)
+
+	viper.Set(containerRuntime, constants.AutoContainerRuntime)
+	existing := &config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ContainerRuntime: constants.CRIO}}
+	resolveAutoRuntime(existing, driver.Docker)
+	if got := viper.GetString(containerRuntime); got != constants.CRIO {
+		t.Errorf("resolveAutoRuntime of an existing crio cluster set %s, want it kept", got)
+	}
+
+	viper.Set(containerRuntime, constants.AutoContainerRuntime)
+	resolveAutoRuntime(nil, driver.SSH)
+	if got := viper.GetString(containerRuntime); got != constants.Docker {
+		t.Errorf("resolveAutoRuntime(ssh) set %s, want %s", got, constants.Docker)
+	}
+}
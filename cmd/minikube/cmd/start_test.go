@@ -444,6 +444,10 @@ func TestValidateRuntime(t *testing.T) {
 			runtime:  "docker",
 			errorMsg: "",
 		},
+		{
+			runtime:  "auto",
+			errorMsg: "",
+		},
 		{
 			runtime:  "test",
 			errorMsg: fmt.Sprintf("Invalid Container Runtime: test. Valid runtimes are: %v", cruntime.ValidRuntimes()),
@@ -888,3 +892,20 @@ func TestValidateGPUs(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerMount(t *testing.T) {
+	cache := "/home/user/.minikube/cache/images:/var/lib/minikube/image-cache:ro"
+	tests := []struct {
+		mounts []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{cache}, ""},
+		{[]string{"/src:/minikube-host", cache}, "/src:/minikube-host"},
+	}
+	for _, tc := range tests {
+		if got := containerMount(&cfg.ClusterConfig{ContainerVolumeMounts: tc.mounts}); got != tc.want {
+			t.Errorf("containerMount(%q) = %q, want %q", tc.mounts, got, tc.want)
+		}
+	}
+}
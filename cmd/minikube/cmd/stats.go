@@ -0,0 +1,281 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/exit"
+	"k8s.io/minikube/pkg/minikube/machine"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/reason"
+)
+
+// statsSampleInterval is how long to wait between the two samples CPU usage is derived from,
+// for runtimes which only report cumulative CPU time and for the nodes
+const statsSampleInterval = time.Second
+
+// statsWatchInterval is how often --watch refreshes the usage
+const statsWatchInterval = 2 * time.Second
+
+var (
+	statsOutput string
+	statsWatch  bool
+)
+
+// clusterUsage is the resource usage of the nodes of a cluster, and of their containers
+type clusterUsage struct {
+	Nodes      []nodeUsage      `json:"nodes"`
+	Containers []containerUsage `json:"containers"`
+}
+
+// nodeUsage is the resource usage of a node
+type nodeUsage struct {
+	Node             string `json:"node"`
+	CPUs             int    `json:"cpus"`
+	CPUNanoCore      uint64 `json:"cpuNanoCores"`
+	MemoryBytes      uint64 `json:"memoryBytes"`
+	MemoryTotalBytes uint64 `json:"memoryTotalBytes"`
+	DiskBytes        uint64 `json:"diskBytes"`
+	DiskTotalBytes   uint64 `json:"diskTotalBytes"`
+}
+
+// containerUsage is the resource usage of a container on a node
+type containerUsage struct {
+	Node        string `json:"node"`
+	Namespace   string `json:"namespace"`
+	Pod         string `json:"pod"`
+	Container   string `json:"container"`
+	ID          string `json:"id"`
+	CPUNanoCore uint64 `json:"cpuNanoCores"`
+	MemoryBytes uint64 `json:"memoryBytes"`
+	// DiskBytes is the disk used by the writable layer of the container
+	DiskBytes uint64 `json:"diskBytes"`
+}
+
+// statsNode is a node whose usage is sampled
+type statsNode struct {
+	name   string
+	runner command.Runner
+	cr     cruntime.Manager
+}
+
+// statsSample is the usage of a node, and of its containers, at a point in time
+type statsSample struct {
+	node       machine.NodeSample
+	containers []cruntime.ContainerStats
+}
+
+var statsCmd = &cobra.Command{
+	Use:     "stats",
+	Aliases: []string{"top"},
+	Short:   "Show the CPU, memory and disk usage of the nodes and their containers",
+	Long: `Shows the CPU, memory and disk usage of every node, and of every container on the nodes, as reported by the nodes and the container runtime. Unlike 'kubectl top', it does not need metrics-server.
+
+With --watch, the usage is refreshed every 2 seconds until interrupted; with --output json, every refresh is printed as a line of JSON.`,
+	Example: `minikube stats
+minikube stats --watch
+minikube stats -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			exit.Message(reason.Usage, "Usage: minikube stats [--watch] [--output text|json]")
+		}
+		if statsOutput != "text" && statsOutput != "json" {
+			exit.Message(reason.Usage, "Invalid output format {{.format}}, valid ones are text and json", out.V{"format": statsOutput})
+		}
+
+		co := mustload.Running(ClusterFlagValue())
+		var nodes []statsNode
+		for _, n := range co.Config.Nodes {
+			machineName := config.MachineName(*co.Config, n)
+			h, err := machine.LoadHost(co.API, machineName)
+			if err != nil {
+				exit.Error(reason.GuestLoadHost, "Error getting host", err)
+			}
+			r, err := machine.CommandRunner(h)
+			if err != nil {
+				exit.Error(reason.InternalCommandRunner, "Failed to get command runner", err)
+			}
+			cr, err := cruntime.New(cruntime.Config{Type: co.Config.KubernetesConfig.ContainerRuntime, Runner: r, Socket: co.Config.KubernetesConfig.CRISocket})
+			if err != nil {
+				exit.Error(reason.InternalNewRuntime, "Failed runtime", err)
+			}
+			nodes = append(nodes, statsNode{name: machineName, runner: r, cr: cr})
+		}
+
+		prev := mustSampleStats(nodes)
+		time.Sleep(statsSampleInterval)
+		for {
+			cur := mustSampleStats(nodes)
+			printClusterUsage(usageBetween(nodes, prev, cur))
+			if !statsWatch {
+				return
+			}
+			prev = cur
+			time.Sleep(statsWatchInterval)
+		}
+	},
+}
+
+// mustSampleStats samples the usage of nodes and of their containers, exiting if any can't be sampled
+func mustSampleStats(nodes []statsNode) []statsSample {
+	samples := []statsSample{}
+	for _, n := range nodes {
+		ns, err := machine.SampleNode(n.runner)
+		if err != nil {
+			exit.Error(reason.GuestStatus, "Failed to get node stats", err)
+		}
+		cs, err := n.cr.ContainerStats(nil)
+		if err != nil {
+			exit.Error(reason.GuestStatus, "Failed to get container stats", err)
+		}
+		samples = append(samples, statsSample{node: ns, containers: cs})
+	}
+	return samples
+}
+
+// usageBetween returns the usage of nodes as of the samples cur, deriving CPU usage from the earlier samples prev
+func usageBetween(nodes []statsNode, prev []statsSample, cur []statsSample) clusterUsage {
+	usage := clusterUsage{Nodes: []nodeUsage{}, Containers: []containerUsage{}}
+	for i, n := range nodes {
+		p, c := prev[i], cur[i]
+		usage.Nodes = append(usage.Nodes, nodeUsage{
+			Node:             n.name,
+			CPUs:             c.node.CPUs,
+			CPUNanoCore:      c.node.CPUNanoCores(p.node),
+			MemoryBytes:      c.node.MemoryUsedBytes(),
+			MemoryTotalBytes: c.node.MemoryTotalBytes,
+			DiskBytes:        c.node.DiskUsedBytes,
+			DiskTotalBytes:   c.node.DiskTotalBytes,
+		})
+
+		earlier := map[string]cruntime.ContainerStats{}
+		for _, s := range p.containers {
+			earlier[s.ID] = s
+		}
+		for _, s := range c.containers {
+			usage.Containers = append(usage.Containers, containerUsage{
+				Node:        n.name,
+				Namespace:   s.PodNamespace,
+				Pod:         s.PodName,
+				Container:   s.Name,
+				ID:          s.ID,
+				CPUNanoCore: s.CPUNanoCores(earlier[s.ID]),
+				MemoryBytes: s.MemoryWorkingSetBytes,
+				DiskBytes:   s.WritableLayerBytes,
+			})
+		}
+	}
+	sort.Slice(usage.Containers, func(i, j int) bool {
+		a, b := usage.Containers[i], usage.Containers[j]
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		return a.Container < b.Container
+	})
+	return usage
+}
+
+// printClusterUsage prints usage in the format of --output, clearing the terminal first when watching it
+func printClusterUsage(usage clusterUsage) {
+	if statsOutput == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(usage); err != nil {
+			exit.Error(reason.InternalJSONMarshal, "Failed to encode stats", err)
+		}
+		return
+	}
+	if statsWatch && out.IsTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J")
+	}
+	renderNodeUsage(os.Stdout, usage.Nodes)
+	renderContainerUsage(os.Stdout, usage.Containers)
+}
+
+// newUsageTable returns a table of usage with header, in the style of 'minikube image ls --format table'
+func newUsageTable(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("|")
+	return table
+}
+
+// millicores formats CPU usage in the units of 'kubectl top'
+func millicores(nanoCores uint64) string {
+	return fmt.Sprintf("%dm", nanoCores/1000000)
+}
+
+// mebibytes formats a size in the units of 'kubectl top'
+func mebibytes(b uint64) string {
+	return fmt.Sprintf("%dMi", b/1024/1024)
+}
+
+// percentOf formats part as a percentage of total
+func percentOf(part uint64, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", part*100/total)
+}
+
+// renderNodeUsage prints the usage of nodes as a table, in the units of 'kubectl top node'
+func renderNodeUsage(w io.Writer, usage []nodeUsage) {
+	table := newUsageTable(w, []string{"Node", "CPU(cores)", "CPU%", "Memory(bytes)", "Memory%", "Disk(bytes)", "Disk%"})
+	for _, u := range usage {
+		table.Append([]string{
+			u.Node,
+			millicores(u.CPUNanoCore), percentOf(u.CPUNanoCore, uint64(u.CPUs)*1000000000),
+			mebibytes(u.MemoryBytes), percentOf(u.MemoryBytes, u.MemoryTotalBytes),
+			mebibytes(u.DiskBytes), percentOf(u.DiskBytes, u.DiskTotalBytes),
+		})
+	}
+	table.Render()
+}
+
+// renderContainerUsage prints usage as a table, in the units of 'kubectl top'
+func renderContainerUsage(w io.Writer, usage []containerUsage) {
+	table := newUsageTable(w, []string{"Node", "Namespace", "Pod", "Container", "CPU(cores)", "Memory(bytes)", "Disk(bytes)"})
+	for _, u := range usage {
+		table.Append([]string{u.Node, u.Namespace, u.Pod, u.Container, millicores(u.CPUNanoCore), mebibytes(u.MemoryBytes), mebibytes(u.DiskBytes)})
+	}
+	table.Render()
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "text", "Format to print stdout in. Options include: [text,json]")
+	statsCmd.Flags().BoolVarP(&statsWatch, "watch", "w", false, "Refresh the usage every 2 seconds until interrupted")
+}
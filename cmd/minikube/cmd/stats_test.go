@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/machine"
+)
+
+func TestUsageBetween(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	nodes := []statsNode{{name: "minikube"}}
+	prev := []statsSample{{
+		node: machine.NodeSample{CPUs: 2, CPUBusy: 100, CPUTotal: 1000},
+		containers: []cruntime.ContainerStats{
+			{ID: "b", Name: "app", PodName: "web", PodNamespace: "default", Timestamp: t0, CPUUsageCoreNanoSeconds: 1000000000},
+		},
+	}}
+	cur := []statsSample{{
+		node: machine.NodeSample{CPUs: 2, CPUBusy: 150, CPUTotal: 1200, MemoryTotalBytes: 4 << 30, MemoryAvailableBytes: 3 << 30, DiskTotalBytes: 20 << 30, DiskUsedBytes: 5 << 30},
+		containers: []cruntime.ContainerStats{
+			{ID: "b", Name: "app", PodName: "web", PodNamespace: "default", Timestamp: t0.Add(2 * time.Second), CPUUsageCoreNanoSeconds: 2000000000, MemoryWorkingSetBytes: 64 << 20, WritableLayerBytes: 8 << 20},
+			{ID: "a", Name: "etcd", PodName: "etcd-minikube", PodNamespace: "kube-system", CPUUsageNanoCores: 30000000},
+		},
+	}}
+
+	got := usageBetween(nodes, prev, cur)
+	want := clusterUsage{
+		Nodes: []nodeUsage{{Node: "minikube", CPUs: 2, CPUNanoCore: 500000000, MemoryBytes: 1 << 30, MemoryTotalBytes: 4 << 30, DiskBytes: 5 << 30, DiskTotalBytes: 20 << 30}},
+		Containers: []containerUsage{
+			{Node: "minikube", Namespace: "default", Pod: "web", Container: "app", ID: "b", CPUNanoCore: 500000000, MemoryBytes: 64 << 20, DiskBytes: 8 << 20},
+			{Node: "minikube", Namespace: "kube-system", Pod: "etcd-minikube", Container: "etcd", ID: "a", CPUNanoCore: 30000000},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("usageBetween mismatch (-want +got):\n%s", diff)
+	}
+
+	var b bytes.Buffer
+	renderNodeUsage(&b, got.Nodes)
+	for _, cell := range []string{"500m", "25%", "1024Mi", "5120Mi"} {
+		if !strings.Contains(b.String(), cell) {
+			t.Errorf("node table is missing %q:\n%s", cell, b.String())
+		}
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/cluster"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
 	"k8s.io/minikube/pkg/minikube/kubeconfig"
@@ -57,6 +58,8 @@ var (
 	output       string
 	layout       string
 	watch        time.Duration
+	// watchTransitions makes --watch print the changes of the states only
+	watchTransitions bool
 )
 
 // Additional legacy states
@@ -69,6 +72,10 @@ const (
 	Nonexistent = "Nonexistent" // ~state.None
 	// Irrelevant is used for statuses that aren't meaningful for worker nodes
 	Irrelevant = "Irrelevant"
+	// Healthy means the container runtime serves requests
+	Healthy = "Healthy"
+	// Unhealthy means the container runtime runs but fails to serve requests, its "health error" component tells why
+	Unhealthy = "Unhealthy"
 )
 
 // New status modes, based roughly on HTTP/SMTP standards
@@ -141,6 +148,12 @@ type Status struct {
 	TimeToStop string `json:",omitempty"`
 	DockerEnv  string `json:",omitempty"`
 	PodManEnv  string `json:",omitempty"`
+	// Runtime is the state of the container runtime, and RuntimeComponents those of its services, daemons and sockets,
+	// and its health
+	Runtime           string            `json:",omitempty"`
+	RuntimeComponents map[string]string `json:",omitempty"`
+	// RuntimeInfo describes the container runtime, when it is running
+	RuntimeInfo *cruntime.RuntimeInfo `json:",omitempty"`
 }
 
 // ClusterState holds a cluster state representation
@@ -187,6 +200,12 @@ host: {{.Host}}
 kubelet: {{.Kubelet}}
 apiserver: {{.APIServer}}
 kubeconfig: {{.Kubeconfig}}
+{{- if .RuntimeComponents.health }}
+runtime: {{.RuntimeComponents.health}}{{ with index .RuntimeComponents "health error" }} ({{.}}){{ end }}
+{{- range $c, $s := .RuntimeComponents }}{{ if and (ne $c "health") (ne $c "health error") }}
+  {{$c}}: {{$s}}
+{{- end }}{{ end }}
+{{- end }}
 {{- if .TimeToStop }}
 timeToStop: {{.TimeToStop}}
 {{- end }}
@@ -202,6 +221,12 @@ podman-env: {{.PodManEnv}}
 type: Worker
 host: {{.Host}}
 kubelet: {{.Kubelet}}
+{{- if .RuntimeComponents.health }}
+runtime: {{.RuntimeComponents.health}}{{ with index .RuntimeComponents "health error" }} ({{.}}){{ end }}
+{{- range $c, $s := .RuntimeComponents }}{{ if and (ne $c "health") (ne $c "health error") }}
+  {{$c}}: {{$s}}
+{{- end }}{{ end }}
+{{- end }}
 
 `
 )
@@ -226,7 +251,7 @@ var statusCmd = &cobra.Command{
 		api, cc := mustload.Partial(cname)
 
 		duration := watch
-		if !cmd.Flags().Changed("watch") || watch < 0 {
+		if !cmd.Flags().Changed("watch") && !watchTransitions || watch < 0 {
 			duration = 0
 		}
 		writeStatusesAtInterval(duration, api, cc)
@@ -235,6 +260,7 @@ var statusCmd = &cobra.Command{
 
 // writeStatusesAtInterval writes statuses in a given output format - at intervals defined by duration
 func writeStatusesAtInterval(duration time.Duration, api libmachine.API, cc *config.ClusterConfig) {
+	var states map[[2]string]string
 	for {
 		var statuses []*Status
 
@@ -266,14 +292,23 @@ func writeStatusesAtInterval(duration time.Duration, api libmachine.API, cc *con
 			}
 		}
 
-		switch output {
-		case "text":
+		if watchTransitions && output != "text" && output != "json" {
+			exit.Message(reason.Usage, fmt.Sprintf("invalid output format: %s. Valid values: 'text', 'json'", output))
+		}
+		switch {
+		case watchTransitions:
+			cur := componentStates(statuses)
+			if err := writeTransitions(statusTransitions(time.Now(), states, cur), output, os.Stdout); err != nil {
+				exit.Error(reason.InternalStatusText, "status transitions failure", err)
+			}
+			states = cur
+		case output == "text":
 			for _, st := range statuses {
 				if err := statusText(st, os.Stdout); err != nil {
 					exit.Error(reason.InternalStatusText, "status text failure", err)
 				}
 			}
-		case "json":
+		case output == "json":
 			// Layout is currently only supported for JSON mode
 			if layout == "cluster" {
 				if err := clusterStatusJSON(statuses, os.Stdout); err != nil {
@@ -344,6 +379,7 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 		st.APIServer = st.Host
 		st.Kubelet = st.Host
 		st.Kubeconfig = st.Host
+		st.Runtime = st.Host
 		return st, nil
 	}
 
@@ -383,6 +419,7 @@ func nodeStatus(api libmachine.API, cc config.ClusterConfig, n config.Node) (*St
 
 	stk := kverify.ServiceStatus(cr, "kubelet")
 	st.Kubelet = stk.String()
+	st.Runtime, st.RuntimeComponents, st.RuntimeInfo = runtimeStatus(cr, cc)
 	if cc.ScheduledStop != nil {
 		initiationTime := time.Unix(cc.ScheduledStop.InitiationTime, 0)
 		st.TimeToStop = time.Until(initiationTime.Add(cc.ScheduledStop.Duration)).String()
@@ -441,6 +478,7 @@ For the list accessible variables for the template, see the struct values here:
 	statusCmd.Flags().StringVarP(&nodeName, "node", "n", "", "The node to check status for. Defaults to control plane. Leave blank with default format for status on all nodes.")
 	statusCmd.Flags().DurationVarP(&watch, "watch", "w", 1*time.Second, "Continuously listing/getting the status with optional interval duration.")
 	statusCmd.Flags().Lookup("watch").NoOptDefVal = "1s"
+	statusCmd.Flags().BoolVar(&watchTransitions, "transitions", false, "Watch the status, printing only the changes of the host, Kubernetes and container runtime states with their timestamps. Implies --watch.")
 }
 
 func statusText(st *Status, w io.Writer) error {
@@ -543,6 +581,9 @@ func clusterState(sts []*Status) ClusterState {
 		if st.APIServer != Irrelevant {
 			ns.Components["apiserver"] = BaseState{Name: "apiserver", StatusCode: statusCode(st.APIServer)}
 		}
+		if h := st.RuntimeComponents["health"]; h != "" {
+			ns.Components["runtime"] = BaseState{Name: "runtime", StatusCode: statusCode(h), StatusDetail: st.RuntimeComponents["health error"]}
+		}
 
 		// Convert status codes to status names
 		ns.StatusName = codeNames[ns.StatusCode]
@@ -638,9 +679,9 @@ func clusterState(sts []*Status) ClusterState {
 func statusCode(st string) int {
 	// legacy names
 	switch st {
-	case "Running", "Configured":
+	case "Running", "Configured", Healthy:
 		return OK
-	case "Misconfigured":
+	case "Misconfigured", Unhealthy:
 		return Error
 	}
 
@@ -20,6 +20,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestExitCode(t *testing.T) {
@@ -59,6 +62,19 @@ func TestStatusText(t *testing.T) {
 			state: &Status{Name: "minikube", Host: "Running", Kubelet: "Stopped", APIServer: "Paused", Kubeconfig: Configured},
 			want:  "minikube\ntype: Control Plane\nhost: Running\nkubelet: Stopped\napiserver: Paused\nkubeconfig: Configured\n\n",
 		},
+		{
+			name:  "unhealthy runtime",
+			state: &Status{Name: "minikube", Host: "Running", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured, RuntimeComponents: map[string]string{"health": Unhealthy, "health error": "portoshim: no answer to CRI requests on /run/portoshim.sock"}},
+			want:  "minikube\ntype: Control Plane\nhost: Running\nkubelet: Running\napiserver: Running\nkubeconfig: Configured\nruntime: Unhealthy (portoshim: no answer to CRI requests on /run/portoshim.sock)\n\n",
+		},
+		{
+			name: "porto components",
+			state: &Status{Name: "minikube", Host: "Running", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured,
+				RuntimeComponents: map[string]string{"porto": "Running", "portoshim": "Running", "/run/portod.socket": "Reachable", "portod version": "5.3.30", "/run/portoshim.sock": "Unreachable",
+					"health": Unhealthy, "health error": "portoshim: no answer to CRI requests on /run/portoshim.sock"}},
+			want: "minikube\ntype: Control Plane\nhost: Running\nkubelet: Running\napiserver: Running\nkubeconfig: Configured\nruntime: Unhealthy (portoshim: no answer to CRI requests on /run/portoshim.sock)\n" +
+				"  /run/portod.socket: Reachable\n  /run/portoshim.sock: Unreachable\n  porto: Running\n  portod version: 5.3.30\n  portoshim: Running\n\n",
+		},
 		{
 			name:  "down",
 			state: &Status{Name: "minikube", Host: "Stopped", Kubelet: "Stopped", APIServer: "Stopped", Kubeconfig: Misconfigured},
@@ -105,3 +121,45 @@ func TestStatusJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusTransitions(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	up := &Status{Name: "minikube", Host: "Running", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured, Runtime: "Running",
+		RuntimeComponents: map[string]string{"porto": "Running", "portoshim": "Running", "/run/portoshim.sock": "Listening"}}
+	flap := &Status{Name: "minikube", Host: "Running", Kubelet: "Running", APIServer: "Running", Kubeconfig: Configured, Runtime: "Stopped",
+		RuntimeComponents: map[string]string{"porto": "Running", "portoshim": "Stopped"}}
+	worker := &Status{Name: "minikube-m02", Host: "Running", Kubelet: "Running", APIServer: Irrelevant, Kubeconfig: Irrelevant, Worker: true}
+
+	initial := statusTransitions(now, nil, componentStates([]*Status{up, worker}))
+	if len(initial) != 10 {
+		t.Errorf("initial transitions = %d, want every component of both nodes: %+v", len(initial), initial)
+	}
+	if ts := statusTransitions(now, componentStates([]*Status{up, worker}), componentStates([]*Status{up, worker})); len(ts) != 0 {
+		t.Errorf("transitions of an unchanged cluster = %+v, want none", ts)
+	}
+
+	ts := statusTransitions(now, componentStates([]*Status{up, worker}), componentStates([]*Status{flap, worker}))
+	var b bytes.Buffer
+	if err := writeTransitions(ts, "text", &b); err != nil {
+		t.Fatalf("writeTransitions: %v", err)
+	}
+	want := `2024-03-01T10:00:00Z minikube runtime: Running -> Stopped
+2024-03-01T10:00:00Z minikube runtime /run/portoshim.sock: Listening -> Nonexistent
+2024-03-01T10:00:00Z minikube runtime portoshim: Running -> Stopped
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("writeTransitions text diff (-want +got):\n%s", diff)
+	}
+
+	b.Reset()
+	if err := writeTransitions(ts[:1], "json", &b); err != nil {
+		t.Fatalf("writeTransitions: %v", err)
+	}
+	var got statusTransition
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("writeTransitions json %q: %v", b.String(), err)
+	}
+	if diff := cmp.Diff(ts[0], got); diff != "" {
+		t.Errorf("writeTransitions json diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/docker/machine/libmachine/state"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/kverify"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// runtimeServices are the services of each container runtime whose states status reports
+var runtimeServices = map[string][]string{
+	constants.Docker:     {"docker"},
+	constants.Containerd: {"containerd"},
+	constants.CRIO:       {"crio"},
+	constants.Porto:      {"porto", "portoshim"},
+}
+
+// runtimeStatus probes the container runtime of a node once, returning its state along with the states of its
+// components: its services, whether it serves requests as "health", and why not as "health error", and, for runtimes
+// made of several daemons such as porto, whether each answers on its socket and its version, as either fails without
+// the other. The runtime is described as well when it runs.
+func runtimeStatus(runner command.Runner, cc config.ClusterConfig) (string, map[string]string, *cruntime.RuntimeInfo) {
+	rt := cc.KubernetesConfig.ContainerRuntime
+	components := map[string]string{}
+	overall := state.Running.String()
+	for _, svc := range runtimeServices[rt] {
+		st := kverify.ServiceStatus(runner, svc).String()
+		components[svc] = st
+		if st != state.Running.String() {
+			overall = state.Stopped.String()
+		}
+	}
+
+	cr, err := cruntime.New(cruntime.Config{Type: rt, Runner: runner, Socket: cc.KubernetesConfig.CRISocket})
+	if err != nil {
+		klog.Errorf("runtime status: %v", err)
+		return state.Error.String(), components, nil
+	}
+	health, why := runtimeHealth(cr)
+	components["health"] = health
+	if why != "" {
+		components["health error"] = why
+	}
+
+	if d, ok := cr.(interface {
+		Daemons(context.Context) []cruntime.DaemonStatus
+	}); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+		defer cancel()
+		for _, d := range d.Daemons(ctx) {
+			if !d.Reachable {
+				components[d.Socket] = "Unreachable"
+				if overall == state.Running.String() {
+					overall = state.Error.String()
+				}
+				continue
+			}
+			components[d.Socket] = "Reachable"
+			if d.Version != "" {
+				components[d.Name+" version"] = d.Version
+			}
+		}
+	}
+
+	if overall != state.Running.String() {
+		return overall, components, nil
+	}
+	info, err := cr.RuntimeInfo()
+	if err != nil {
+		klog.Errorf("runtime info: %v", err)
+		return overall, components, nil
+	}
+	return overall, components, &info
+}
+
+// runtimeHealth returns whether a container runtime serves requests, as it answers them rather than as kubelet sees it,
+// and what is wrong with it when it doesn't
+func runtimeHealth(cr cruntime.Manager) (string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+	defer cancel()
+	if err := cr.Healthy(ctx); err != nil {
+		klog.Warningf("%s is unhealthy: %v", cr.Name(), err)
+		return Unhealthy, err.Error()
+	}
+	return Healthy, ""
+}
+
+// statusTransition is a change of the state of a component of a node between two polls of status --watch
+type statusTransition struct {
+	Time      time.Time `json:"time"`
+	Node      string    `json:"node"`
+	Component string    `json:"component"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+}
+
+// componentStates flattens statuses to the state of each component of each node, keyed by node and component
+func componentStates(statuses []*Status) map[[2]string]string {
+	m := map[[2]string]string{}
+	for _, st := range statuses {
+		if st == nil {
+			continue
+		}
+		for c, v := range map[string]string{"host": st.Host, "kubelet": st.Kubelet, "apiserver": st.APIServer, "kubeconfig": st.Kubeconfig, "runtime": st.Runtime} {
+			if v != "" && v != Irrelevant {
+				m[[2]string{st.Name, c}] = v
+			}
+		}
+		for c, v := range st.RuntimeComponents {
+			m[[2]string{st.Name, "runtime " + c}] = v
+		}
+	}
+	return m
+}
+
+// statusTransitions returns the components whose states differ between prev and cur, ordered by node and component.
+// Components which disappear, such as the runtime of a node which stopped, transition to Nonexistent.
+func statusTransitions(now time.Time, prev, cur map[[2]string]string) []statusTransition {
+	var ts []statusTransition
+	for k, v := range cur {
+		if prev[k] != v {
+			ts = append(ts, statusTransition{Time: now, Node: k[0], Component: k[1], From: prev[k], To: v})
+		}
+	}
+	for k, v := range prev {
+		if _, ok := cur[k]; !ok {
+			ts = append(ts, statusTransition{Time: now, Node: k[0], Component: k[1], From: v, To: Nonexistent})
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].Node != ts[j].Node {
+			return ts[i].Node < ts[j].Node
+		}
+		return ts[i].Component < ts[j].Component
+	})
+	return ts
+}
+
+// writeTransitions writes transitions, as lines of text or one JSON object per line
+func writeTransitions(ts []statusTransition, output string, w io.Writer) error {
+	for _, t := range ts {
+		if output == "json" {
+			b, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, string(b)); err != nil {
+				return err
+			}
+			continue
+		}
+		change := t.To
+		if t.From != "" {
+			change = t.From + " -> " + t.To
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %s: %s\n", t.Time.Format(time.RFC3339), t.Node, t.Component, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
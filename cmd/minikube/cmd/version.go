@@ -21,6 +21,7 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/mustload"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/reason"
+	"k8s.io/minikube/pkg/minikube/sbom"
 	"k8s.io/minikube/pkg/version"
 )
 
@@ -36,6 +38,8 @@ var (
 	versionOutput          string
 	shortVersion           bool
 	listComponentsVersions bool
+	printSBOM              bool
+	sbomFormat             string
 )
 
 var versionCmd = &cobra.Command{
@@ -45,6 +49,10 @@ var versionCmd = &cobra.Command{
 	Run: func(command *cobra.Command, args []string) {
 		minikubeVersion := version.GetVersion()
 		gitCommitID := version.GetGitCommitID()
+		if printSBOM {
+			writeSBOM(minikubeVersion)
+			return
+		}
 		data := map[string]interface{}{
 			"minikubeVersion": minikubeVersion,
 			"commit":          gitCommitID,
@@ -125,8 +133,23 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// writeSBOM prints the software bill of materials of the third party binaries in the ISO of this minikube version
+func writeSBOM(minikubeVersion string) {
+	cs, err := sbom.Components()
+	if err != nil {
+		exit.Error(reason.InternalJSONMarshal, "sbom failure", err)
+	}
+	doc, err := sbom.Render(sbomFormat, cs, minikubeVersion, time.Now())
+	if err != nil {
+		exit.Message(reason.InternalOutputUsage, "error: --sbom-format must be one of {{.formats}}", out.V{"formats": strings.Join(sbom.Formats, ", ")})
+	}
+	out.Ln("%s", doc)
+}
+
 func init() {
 	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "", "One of 'yaml' or 'json'.")
 	versionCmd.Flags().BoolVar(&shortVersion, "short", false, "Print just the version number.")
 	versionCmd.Flags().BoolVar(&listComponentsVersions, "components", false, "list versions of all components included with minikube. (the cluster must be running)")
+	versionCmd.Flags().BoolVar(&printSBOM, "sbom", false, "Print the software bill of materials of the third party binaries in the ISO of this minikube version, such as porto and portoshim.")
+	versionCmd.Flags().StringVar(&sbomFormat, "sbom-format", sbom.SPDX, "Format of --sbom, one of 'spdx' or 'cyclonedx'.")
 }
@@ -0,0 +1,824 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// porto-docker-shim serves the common endpoints of the Docker Engine API on a porto node, so that docker clients
+// pointed at the node by "minikube docker-env" can list, pull, load, build, tag and remove images and list and stop
+// containers. Requests are translated to crictl calls against portoshim, to portoctl, which has no Docker API of its
+// own, and to the BuildKit of porto builds. Containers are created by kubelet only: "docker run" is not supported.
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+const (
+	// apiVersion is the Docker Engine API version the shim implements; clients negotiate down to it
+	apiVersion = "1.41"
+	// minAPIVersion is the oldest Docker Engine API version the shim accepts
+	minAPIVersion = "1.24"
+)
+
+var listen = flag.String("listen", fmt.Sprintf("0.0.0.0:%d", constants.DockerDaemonPort), "Address to serve the Docker Engine API on")
+var criSocket = flag.String("cri-socket", "/run/portoshim.sock", "CRI socket of portoshim")
+var tlsCACert = flag.String("tlscacert", "", "CA certificate client certificates must be signed by; TLS is disabled without it")
+var tlsCert = flag.String("tlscert", "", "TLS certificate of the server")
+var tlsKey = flag.String("tlskey", "", "TLS key of the server")
+
+// runFunc runs a command with stdin and returns its stdout
+type runFunc func(stdin io.Reader, name string, args ...string) ([]byte, error)
+
+// runCommand runs a command on the node, returning its stderr as the error
+func runCommand(stdin io.Reader, name string, args ...string) ([]byte, error) {
+	c := exec.Command(name, args...)
+	c.Stdin = stdin
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %s", name, args[0], msg)
+		}
+		return nil, fmt.Errorf("%s %s: %v", name, args[0], err)
+	}
+	return out, nil
+}
+
+// buildFunc builds the image of the context in dir with the Dockerfile file, tags it as tags and writes the build
+// log to out
+type buildFunc func(ctx context.Context, dir string, file string, tags []string, opts []string, out io.Writer) error
+
+// portoBuilder returns the build of porto, run on this node
+func portoBuilder() buildFunc {
+	cr, err := cruntime.New(cruntime.Config{Type: constants.Porto, Runner: command.NewExecRunner(false), Socket: *criSocket})
+	if err != nil {
+		log.Printf("builds are disabled: %v", err)
+		return nil
+	}
+	b, ok := cr.(interface {
+		BuildImageStream(ctx context.Context, dir string, file string, tags []string, opts []string, out io.Writer) error
+	})
+	if !ok {
+		log.Printf("builds are disabled: %s can't build images", cr.Name())
+		return nil
+	}
+	return b.BuildImageStream
+}
+
+func main() {
+	flag.Parse()
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           &shim{run: runCommand, build: portoBuilder(), criSocket: *criSocket},
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if *tlsCACert == "" {
+		log.Printf("serving the Docker Engine API without TLS on %s", *listen)
+		log.Fatal(srv.ListenAndServe())
+	}
+
+	ca, err := os.ReadFile(*tlsCACert)
+	if err != nil {
+		log.Fatalf("reading the CA certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		log.Fatalf("no certificates in %s", *tlsCACert)
+	}
+	// like dockerd with --tlsverify, only clients with a certificate of the minikube CA get in
+	srv.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert, MinVersion: tls.VersionTLS12}
+	log.Printf("serving the Docker Engine API on %s", *listen)
+	log.Fatal(srv.ListenAndServeTLS(*tlsCert, *tlsKey))
+}
+
+// shim translates Docker Engine API requests to porto
+type shim struct {
+	run       runFunc
+	build     buildFunc
+	criSocket string
+}
+
+// versionPrefix matches the API version clients prefix paths with, such as "/v1.41"
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+\.[0-9]+/`)
+
+// apiError is the body of Docker Engine API errors
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *shim) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	if loc := versionPrefix.FindStringIndex(p); loc != nil {
+		p = p[loc[1]-1:]
+	}
+	w.Header().Set("Api-Version", apiVersion)
+	w.Header().Set("Server", "porto-docker-shim")
+	log.Printf("%s %s", r.Method, r.URL)
+
+	switch {
+	case p == "/_ping":
+		// the classic builder API, "POST /build", is the one the shim serves; BuildKit sessions are not
+		w.Header().Set("Builder-Version", "1")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.Method != http.MethodHead {
+			io.WriteString(w, "OK")
+		}
+	case p == "/version" && r.Method == http.MethodGet:
+		s.version(w)
+	case p == "/info" && r.Method == http.MethodGet:
+		s.info(w)
+	case p == "/images/json" && r.Method == http.MethodGet:
+		s.listImages(w)
+	case p == "/images/create" && r.Method == http.MethodPost:
+		s.pullImage(w, r)
+	case p == "/images/load" && r.Method == http.MethodPost:
+		s.loadImage(w, r)
+	case p == "/build" && r.Method == http.MethodPost:
+		s.buildImage(w, r)
+	case strings.HasPrefix(p, "/images/") && strings.HasSuffix(p, "/json") && r.Method == http.MethodGet:
+		s.inspectImage(w, strings.TrimSuffix(strings.TrimPrefix(p, "/images/"), "/json"))
+	case strings.HasPrefix(p, "/images/") && strings.HasSuffix(p, "/tag") && r.Method == http.MethodPost:
+		s.tagImage(w, r, strings.TrimSuffix(strings.TrimPrefix(p, "/images/"), "/tag"))
+	case strings.HasPrefix(p, "/images/") && r.Method == http.MethodDelete:
+		s.removeImage(w, strings.TrimPrefix(p, "/images/"))
+	case p == "/containers/json" && r.Method == http.MethodGet:
+		s.listContainers(w, r)
+	case strings.HasPrefix(p, "/containers/") && strings.HasSuffix(p, "/stop") && r.Method == http.MethodPost:
+		s.stopContainer(w, r, strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/stop"), false)
+	case strings.HasPrefix(p, "/containers/") && strings.HasSuffix(p, "/kill") && r.Method == http.MethodPost:
+		s.stopContainer(w, r, strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/kill"), true)
+	default:
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("%s %s is not supported by the porto runtime", r.Method, p))
+	}
+}
+
+// writeJSON writes v as the response body with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	// image names such as "<none>:<none>" are shown to users as is
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
+
+// writeError writes err as a Docker Engine API error
+func writeError(w http.ResponseWriter, status int, err error) {
+	log.Printf("error: %v", err)
+	writeJSON(w, status, apiError{Message: err.Error()})
+}
+
+// crictl runs crictl against portoshim
+func (s *shim) crictl(args ...string) ([]byte, error) {
+	return s.run(nil, "crictl", append([]string{"--runtime-endpoint", "unix://" + s.criSocket}, args...)...)
+}
+
+// portoVersion returns the version of portod, such as "5.3.30"
+func (s *shim) portoVersion() string {
+	out, err := s.run(nil, "portod", "version")
+	if err != nil {
+		log.Printf("porto version: %v", err)
+		return "unknown"
+	}
+	// "version: 5.3.30 ..." on the first line
+	fields := strings.Fields(strings.SplitN(string(out), "\n", 2)[0])
+	if len(fields) < 2 {
+		return "unknown"
+	}
+	return fields[1]
+}
+
+func (s *shim) version(w http.ResponseWriter) {
+	v := s.portoVersion()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Version":       v,
+		"ApiVersion":    apiVersion,
+		"MinAPIVersion": minAPIVersion,
+		"Os":            "linux",
+		"Arch":          goruntime.GOARCH,
+		"KernelVersion": kernelVersion(),
+		"Components":    []map[string]string{{"Name": "porto", "Version": v}},
+	})
+}
+
+// kernelVersion returns the release of the running kernel
+func kernelVersion() string {
+	b, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (s *shim) info(w http.ResponseWriter) {
+	hostname, _ := os.Hostname()
+	info := map[string]interface{}{
+		"ID":              hostname,
+		"Name":            hostname,
+		"Driver":          "porto",
+		"OSType":          "linux",
+		"Architecture":    goruntime.GOARCH,
+		"NCPU":            goruntime.NumCPU(),
+		"KernelVersion":   kernelVersion(),
+		"ServerVersion":   s.portoVersion(),
+		"OperatingSystem": "minikube",
+	}
+	if images, err := s.images(); err == nil {
+		info["Images"] = len(images)
+	}
+	if containers, err := s.containers(true); err == nil {
+		running := 0
+		for _, c := range containers {
+			if c.State == "CONTAINER_RUNNING" {
+				running++
+			}
+		}
+		info["Containers"] = len(containers)
+		info["ContainersRunning"] = running
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// criImage is an image in the output of "crictl images -o json"
+type criImage struct {
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repoTags"`
+	RepoDigests []string `json:"repoDigests"`
+	Size        string   `json:"size"`
+}
+
+// images lists the images of portoshim
+func (s *shim) images() ([]criImage, error) {
+	out, err := s.crictl("images", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Images []criImage `json:"images"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("parsing crictl images: %v", err)
+	}
+	return list.Images, nil
+}
+
+// imageSummary is an image as listed by the Docker Engine API
+type imageSummary struct {
+	ID          string            `json:"Id"`
+	ParentID    string            `json:"ParentId"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	SharedSize  int64             `json:"SharedSize"`
+	VirtualSize int64             `json:"VirtualSize"`
+	Labels      map[string]string `json:"Labels"`
+	Containers  int64             `json:"Containers"`
+}
+
+// toImageSummary converts a CRI image to the Docker Engine API
+func toImageSummary(img criImage) imageSummary {
+	size, _ := strconv.ParseInt(img.Size, 10, 64)
+	tags := img.RepoTags
+	if len(tags) == 0 {
+		tags = []string{"<none>:<none>"}
+	}
+	return imageSummary{
+		ID:          img.ID,
+		RepoTags:    tags,
+		RepoDigests: img.RepoDigests,
+		Size:        size,
+		SharedSize:  -1,
+		VirtualSize: size,
+		Labels:      map[string]string{},
+		Containers:  -1,
+	}
+}
+
+func (s *shim) listImages(w http.ResponseWriter) {
+	images, err := s.images()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summaries := []imageSummary{}
+	for _, img := range images {
+		summaries = append(summaries, toImageSummary(img))
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *shim) inspectImage(w http.ResponseWriter, name string) {
+	out, err := s.crictl("inspecti", "-o", "json", name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("No such image: %s", name))
+		return
+	}
+	var inspect struct {
+		Status criImage `json:"status"`
+	}
+	if err := json.Unmarshal(out, &inspect); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("parsing crictl inspecti: %v", err))
+		return
+	}
+	sum := toImageSummary(inspect.Status)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"Id":           sum.ID,
+		"RepoTags":     inspect.Status.RepoTags,
+		"RepoDigests":  sum.RepoDigests,
+		"Size":         sum.Size,
+		"VirtualSize":  sum.Size,
+		"Os":           "linux",
+		"Architecture": goruntime.GOARCH,
+		"Config":       map[string]interface{}{"Labels": map[string]string{}},
+	})
+}
+
+// pullReference returns the image a pull request asks for, as "docker pull" sends the tag or digest apart
+func pullReference(r *http.Request) string {
+	ref := r.URL.Query().Get("fromImage")
+	tag := r.URL.Query().Get("tag")
+	switch {
+	case tag == "":
+		return ref
+	case strings.HasPrefix(tag, "sha256:"):
+		return ref + "@" + tag
+	default:
+		return ref + ":" + tag
+	}
+}
+
+// progressMessage is a line of the JSON progress stream of pulls, loads and builds
+type progressMessage struct {
+	Status      string       `json:"status,omitempty"`
+	Stream      string       `json:"stream,omitempty"`
+	Aux         *buildResult `json:"aux,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	ErrorDetail *apiError    `json:"errorDetail,omitempty"`
+}
+
+// buildResult is the image a build produced, which "docker build -q" prints
+type buildResult struct {
+	ID string `json:"ID"`
+}
+
+// writeProgress writes msg to the JSON progress stream, flushing it to the client right away
+func writeProgress(w http.ResponseWriter, msg progressMessage) {
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		log.Printf("writing progress: %v", err)
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeProgressError ends a JSON progress stream with err; the status code has already been sent by then
+func writeProgressError(w http.ResponseWriter, err error) {
+	log.Printf("error: %v", err)
+	writeProgress(w, progressMessage{Error: err.Error(), ErrorDetail: &apiError{Message: err.Error()}})
+}
+
+func (s *shim) pullImage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("fromSrc") != "" {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("importing images is not supported by the porto runtime"))
+		return
+	}
+	ref := pullReference(r)
+	if ref == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no image to pull"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeProgress(w, progressMessage{Status: "Pulling " + ref})
+	if _, err := s.crictl("pull", ref); err != nil {
+		writeProgressError(w, err)
+		return
+	}
+	writeProgress(w, progressMessage{Status: "Status: Downloaded image for " + ref})
+}
+
+func (s *shim) loadImage(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.run(r.Body, "portoctl", "docker-load", "/dev/stdin"); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeProgress(w, progressMessage{Stream: "Loaded image\n"})
+}
+
+// buildOptions returns the buildctl options of the query of a build request
+func buildOptions(q url.Values) ([]string, error) {
+	var opts []string
+	if q.Get("target") != "" {
+		opts = append(opts, "opt=target="+q.Get("target"))
+	}
+	if q.Get("platform") != "" {
+		opts = append(opts, "opt=platform="+q.Get("platform"))
+	}
+	if nocache, _ := strconv.ParseBool(q.Get("nocache")); nocache {
+		opts = append(opts, "no-cache")
+	}
+	// build arguments without a value are unset, as with dockerd
+	var args map[string]*string
+	if err := unmarshalQuery(q.Get("buildargs"), &args); err != nil {
+		return nil, fmt.Errorf("parsing buildargs: %v", err)
+	}
+	for _, k := range sortedKeys(args) {
+		if args[k] != nil {
+			opts = append(opts, fmt.Sprintf("opt=build-arg:%s=%s", k, *args[k]))
+		}
+	}
+	var labels map[string]*string
+	if err := unmarshalQuery(q.Get("labels"), &labels); err != nil {
+		return nil, fmt.Errorf("parsing labels: %v", err)
+	}
+	for _, k := range sortedKeys(labels) {
+		if labels[k] != nil {
+			opts = append(opts, fmt.Sprintf("opt=label:%s=%s", k, *labels[k]))
+		}
+	}
+	return opts, nil
+}
+
+// unmarshalQuery unmarshals the JSON of a query parameter into v, leaving v alone if the parameter is empty
+func unmarshalQuery(param string, v interface{}) error {
+	if param == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(param), v)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]*string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxContextSize caps the bytes of the files of a build context, which is extracted to the disk of the node
+var maxContextSize int64 = 8 << 30
+
+// extractContext extracts the build context tar of a build request, gzipped or not, into dir. Entries are not written
+// through symlinks leaving dir, symlinks pointing out of dir are rejected, and the files may add up to maxContextSize.
+func extractContext(body io.Reader, dir string) error {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(body)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	left := maxContextSize
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside of the build context", h.Name)
+		}
+		target := filepath.Join(dir, name)
+		// a symlink extracted before may have taken the place of a parent directory
+		if err := within(dir, filepath.Dir(target)); err != nil {
+			return fmt.Errorf("extracting %s: %v", h.Name, err)
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := within(dir, target); err != nil {
+				return fmt.Errorf("extracting %s: %v", h.Name, err)
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if h.Size > left {
+				return fmt.Errorf("the build context is larger than %d bytes", maxContextSize)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			// replaces a symlink of the same name instead of writing to where it points
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(h.Mode)&os.ModePerm)
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(f, tr)
+			left -= n
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			link := h.Linkname
+			if !filepath.IsAbs(link) {
+				// not joined, which would clean the ".." of the link without resolving the symlinks before them
+				link = filepath.Dir(target) + string(filepath.Separator) + link
+			}
+			if err := within(dir, link); err != nil {
+				return fmt.Errorf("%s links to %s: %v", h.Name, h.Linkname, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(h.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			log.Printf("skipping %s of the build context, of type %c", h.Name, h.Typeflag)
+		}
+	}
+}
+
+// within returns an error unless p, with the symlinks of the part of it which exists resolved, is dir or below it.
+// A ".." in the part which does not exist could be resolved against a symlink extracted later, so it is not within.
+func within(dir string, p string) error {
+	outside := fmt.Errorf("%s is outside of the build context", p)
+	rest := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			p = filepath.Join(resolved, rest)
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		i := strings.LastIndex(p, string(filepath.Separator))
+		if i < 0 || p[i+1:] == ".." {
+			return outside
+		}
+		rest = filepath.Join(p[i+1:], rest)
+		p = p[:i]
+	}
+	if rel, err := filepath.Rel(dir, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return outside
+	}
+	return nil
+}
+
+// streamWriter writes the build log to a JSON progress stream
+type streamWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+}
+
+// Write implements io.Writer; buildctl writes the log to stdout and stderr at once
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	writeProgress(sw.w, progressMessage{Stream: string(p)})
+	return len(p), nil
+}
+
+func (s *shim) buildImage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if s.build == nil {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("building images is not supported on this node"))
+		return
+	}
+	if q.Get("remote") != "" {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("building from a remote context is not supported by the porto runtime"))
+		return
+	}
+	opts, err := buildOptions(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	dir, err := os.MkdirTemp("", "porto-docker-shim-build-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	if err := extractContext(r.Body, dir); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("extracting the build context: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	tags := q["t"]
+	if err := s.build(r.Context(), dir, q.Get("dockerfile"), tags, opts, &streamWriter{w: w}); err != nil {
+		writeProgressError(w, err)
+		return
+	}
+	if len(tags) == 0 {
+		return
+	}
+	if out, err := s.crictl("inspecti", "-o", "json", tags[0]); err == nil {
+		var inspect struct {
+			Status criImage `json:"status"`
+		}
+		if json.Unmarshal(out, &inspect) == nil && inspect.Status.ID != "" {
+			writeProgress(w, progressMessage{Aux: &buildResult{ID: inspect.Status.ID}})
+			writeProgress(w, progressMessage{Stream: "Successfully built " + inspect.Status.ID + "\n"})
+		}
+	}
+	for _, t := range tags {
+		writeProgress(w, progressMessage{Stream: "Successfully tagged " + t + "\n"})
+	}
+}
+
+func (s *shim) tagImage(w http.ResponseWriter, r *http.Request, name string) {
+	target := r.URL.Query().Get("repo")
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		target += ":" + tag
+	}
+	if target == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no repository to tag %s as", name))
+		return
+	}
+	if _, err := s.run(nil, "portoctl", "docker-tag", name, target); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *shim) removeImage(w http.ResponseWriter, name string) {
+	if _, err := s.crictl("rmi", name); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, []map[string]string{{"Untagged": name}})
+}
+
+// criContainer is a container in the output of "crictl ps -o json"
+type criContainer struct {
+	ID       string `json:"id"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Image struct {
+		Image string `json:"image"`
+	} `json:"image"`
+	ImageRef  string            `json:"imageRef"`
+	State     string            `json:"state"`
+	CreatedAt string            `json:"createdAt"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// containers lists the containers of portoshim, only the running ones unless all
+func (s *shim) containers(all bool) ([]criContainer, error) {
+	args := []string{"ps", "-o", "json"}
+	if all {
+		args = append(args, "-a")
+	}
+	out, err := s.crictl(args...)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Containers []criContainer `json:"containers"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("parsing crictl ps: %v", err)
+	}
+	return list.Containers, nil
+}
+
+// containerStates maps CRI container states to the states and statuses of the Docker Engine API
+var containerStates = map[string][2]string{
+	"CONTAINER_CREATED": {"created", "Created"},
+	"CONTAINER_RUNNING": {"running", "Up"},
+	"CONTAINER_EXITED":  {"exited", "Exited"},
+	"CONTAINER_UNKNOWN": {"dead", "Unknown"},
+}
+
+// containerSummary is a container as listed by the Docker Engine API
+type containerSummary struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	Command string            `json:"Command"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+	Ports   []interface{}     `json:"Ports"`
+	Mounts  []interface{}     `json:"Mounts"`
+}
+
+// toContainerSummary converts a CRI container to the Docker Engine API
+func toContainerSummary(c criContainer) containerSummary {
+	state, ok := containerStates[c.State]
+	if !ok {
+		state = containerStates["CONTAINER_UNKNOWN"]
+	}
+	// nanoseconds in CRI, seconds in Docker
+	created, _ := strconv.ParseInt(c.CreatedAt, 10, 64)
+	labels := c.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return containerSummary{
+		ID:      c.ID,
+		Names:   []string{"/" + c.Metadata.Name},
+		Image:   c.Image.Image,
+		ImageID: c.ImageRef,
+		Created: created / int64(time.Second),
+		State:   state[0],
+		Status:  state[1],
+		Labels:  labels,
+		Ports:   []interface{}{},
+		Mounts:  []interface{}{},
+	}
+}
+
+func (s *shim) listContainers(w http.ResponseWriter, r *http.Request) {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	containers, err := s.containers(all)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summaries := []containerSummary{}
+	for _, c := range containers {
+		summaries = append(summaries, toContainerSummary(c))
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *shim) stopContainer(w http.ResponseWriter, r *http.Request, id string, kill bool) {
+	args := []string{"stop"}
+	if t := r.URL.Query().Get("t"); t != "" && !kill {
+		args = append(args, "--timeout", t)
+	}
+	if kill {
+		args = append(args, "--timeout", "0")
+	}
+	if _, err := s.crictl(append(args, id)...); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRun returns the output of commands from outputs, and records the commands it was asked to run
+func fakeRun(outputs map[string]string, ran *[]string) runFunc {
+	return func(_ io.Reader, name string, args ...string) ([]byte, error) {
+		cmd := strings.Join(append([]string{name}, args...), " ")
+		*ran = append(*ran, cmd)
+		out, ok := outputs[cmd]
+		if !ok {
+			return nil, fmt.Errorf("%s: not found", cmd)
+		}
+		return []byte(out), nil
+	}
+}
+
+const crictl = "crictl --runtime-endpoint unix:///run/portoshim.sock"
+
+func TestShim(t *testing.T) {
+	outputs := map[string]string{
+		crictl + " images -o json": `{"images": [
+			{"id": "sha256:aaa", "repoTags": ["registry.k8s.io/pause:3.9"], "repoDigests": ["registry.k8s.io/pause@sha256:bbb"], "size": "321"},
+			{"id": "sha256:ccc", "repoTags": [], "repoDigests": [], "size": "7"}
+		]}`,
+		crictl + " ps -o json -a": `{"containers": [
+			{"id": "c1", "metadata": {"name": "etcd"}, "image": {"image": "sha256:ddd"}, "imageRef": "registry.k8s.io/etcd@sha256:eee",
+			 "state": "CONTAINER_RUNNING", "createdAt": "1700000000123456789", "labels": {"io.kubernetes.pod.namespace": "kube-system"}}
+		]}`,
+		crictl + " pull nginx:1.25":                      "",
+		crictl + " rmi nginx:1.25":                       "",
+		crictl + " stop --timeout 0 c1":                  "",
+		"portoctl docker-tag nginx:1.25 example/nginx:x": "",
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+		ran          []string
+	}{
+		{
+			method: "GET", path: "/_ping", status: 200, body: "OK",
+		},
+		{
+			method: "GET", path: "/v1.43/images/json", status: 200,
+			body: `[{"Id":"sha256:aaa","ParentId":"","RepoTags":["registry.k8s.io/pause:3.9"],"RepoDigests":["registry.k8s.io/pause@sha256:bbb"],"Created":0,"Size":321,"SharedSize":-1,"VirtualSize":321,"Labels":{},"Containers":-1},` +
+				`{"Id":"sha256:ccc","ParentId":"","RepoTags":["<none>:<none>"],"RepoDigests":[],"Created":0,"Size":7,"SharedSize":-1,"VirtualSize":7,"Labels":{},"Containers":-1}]` + "\n",
+			ran: []string{crictl + " images -o json"},
+		},
+		{
+			method: "GET", path: "/v1.41/containers/json?all=1", status: 200,
+			body: `[{"Id":"c1","Names":["/etcd"],"Image":"sha256:ddd","ImageID":"registry.k8s.io/etcd@sha256:eee","Command":"","Created":1700000000,"State":"running","Status":"Up",` +
+				`"Labels":{"io.kubernetes.pod.namespace":"kube-system"},"Ports":[],"Mounts":[]}]` + "\n",
+			ran: []string{crictl + " ps -o json -a"},
+		},
+		{
+			method: "POST", path: "/v1.41/images/create?fromImage=nginx&tag=1.25", status: 200,
+			body: `{"status":"Pulling nginx:1.25"}` + "\n" + `{"status":"Status: Downloaded image for nginx:1.25"}` + "\n",
+			ran:  []string{crictl + " pull nginx:1.25"},
+		},
+		{
+			method: "POST", path: "/v1.41/images/create?fromImage=missing&tag=1", status: 200,
+			body: `{"status":"Pulling missing:1"}` + "\n" +
+				`{"error":"` + crictl + ` pull missing:1: not found","errorDetail":{"message":"` + crictl + ` pull missing:1: not found"}}` + "\n",
+			ran: []string{crictl + " pull missing:1"},
+		},
+		{
+			method: "POST", path: "/v1.41/images/nginx:1.25/tag?repo=example/nginx&tag=x", status: 201,
+			ran: []string{"portoctl docker-tag nginx:1.25 example/nginx:x"},
+		},
+		{
+			method: "DELETE", path: "/v1.41/images/nginx:1.25", status: 200,
+			body: `[{"Untagged":"nginx:1.25"}]` + "\n",
+			ran:  []string{crictl + " rmi nginx:1.25"},
+		},
+		{
+			method: "POST", path: "/v1.41/containers/c1/kill", status: 204,
+			ran: []string{crictl + " stop --timeout 0 c1"},
+		},
+		{
+			method: "POST", path: "/v1.41/containers/create?name=web", status: 501,
+			body: `{"message":"POST /containers/create is not supported by the porto runtime"}` + "\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			var ran []string
+			s := &shim{run: fakeRun(outputs, &ran), criSocket: "/run/portoshim.sock"}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if w.Code != tc.status {
+				t.Errorf("status = %d, want %d", w.Code, tc.status)
+			}
+			if w.Header().Get("Api-Version") != apiVersion {
+				t.Errorf("Api-Version = %q, want %q", w.Header().Get("Api-Version"), apiVersion)
+			}
+			if diff := cmp.Diff(tc.body, w.Body.String()); diff != "" {
+				t.Errorf("body diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.ran, ran); diff != "" {
+				t.Errorf("commands diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullReference(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"fromImage=nginx", "nginx"},
+		{"fromImage=nginx&tag=1.25", "nginx:1.25"},
+		{"fromImage=nginx&tag=sha256:abc", "nginx@sha256:abc"},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/images/create?"+tc.query, nil)
+		if got := pullReference(r); got != tc.want {
+			t.Errorf("pullReference(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+// contextTar returns a build context tar of files, gzipped if compress
+func contextTar(t *testing.T, files map[string]string, compress bool) *bytes.Buffer {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestBuildImage(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gzip=%v", compress), func(t *testing.T) {
+			var ran []string
+			var gotTags, gotOpts []string
+			var gotFile, gotDockerfile string
+			s := &shim{
+				run: fakeRun(map[string]string{
+					crictl + " inspecti -o json example/app:1": `{"status": {"id": "sha256:fff", "repoTags": ["example/app:1"]}}`,
+				}, &ran),
+				build: func(_ context.Context, dir string, file string, tags []string, opts []string, out io.Writer) error {
+					b, err := os.ReadFile(filepath.Join(dir, "build", "Containerfile"))
+					if err != nil {
+						return err
+					}
+					gotDockerfile, gotFile, gotTags, gotOpts = string(b), file, tags, opts
+					_, err = io.WriteString(out, "#1 DONE\n")
+					return err
+				},
+				criSocket: "/run/portoshim.sock",
+			}
+			body := contextTar(t, map[string]string{"build/Containerfile": "FROM scratch\n"}, compress)
+			path := "/v1.41/build?t=example/app:1&t=example/app:latest&dockerfile=build/Containerfile&nocache=1&target=final" +
+				`&buildargs={"VERSION":"1","UNSET":null}`
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, body))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+			}
+			if gotDockerfile != "FROM scratch\n" || gotFile != "build/Containerfile" {
+				t.Errorf("built %q with %q, want the extracted build/Containerfile", gotDockerfile, gotFile)
+			}
+			if diff := cmp.Diff([]string{"example/app:1", "example/app:latest"}, gotTags); diff != "" {
+				t.Errorf("tags diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"opt=target=final", "no-cache", "opt=build-arg:VERSION=1"}, gotOpts); diff != "" {
+				t.Errorf("options diff (-want +got):\n%s", diff)
+			}
+			want := `{"stream":"#1 DONE\n"}` + "\n" + `{"aux":{"ID":"sha256:fff"}}` + "\n" + `{"stream":"Successfully built sha256:fff\n"}` + "\n" +
+				`{"stream":"Successfully tagged example/app:1\n"}` + "\n" + `{"stream":"Successfully tagged example/app:latest\n"}` + "\n"
+			if diff := cmp.Diff(want, w.Body.String()); diff != "" {
+				t.Errorf("body diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtractContextOutside(t *testing.T) {
+	body := contextTar(t, map[string]string{"../escape": "x"}, false)
+	if err := extractContext(body, t.TempDir()); err == nil {
+		t.Errorf("extractContext() of ../escape succeeded")
+	}
+}
+
+// entriesTar returns a tar of headers, the regular files of which hold their Linkname
+func entriesTar(t *testing.T, headers ...tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		content := ""
+		if h.Typeflag == tar.TypeReg {
+			content, h.Linkname = h.Linkname, ""
+			h.Size = int64(len(content))
+		}
+		h.Mode = 0o644
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractContextSymlinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []tar.Header
+		valid   bool
+	}{
+		{"inside", []tar.Header{
+			{Name: "lib", Typeflag: tar.TypeDir},
+			{Name: "lib/app", Typeflag: tar.TypeReg, Linkname: "x"},
+			{Name: "app", Typeflag: tar.TypeSymlink, Linkname: "lib/app"},
+			{Name: "lib/self", Typeflag: tar.TypeSymlink, Linkname: "../lib"},
+			{Name: "lib/self/more", Typeflag: tar.TypeReg, Linkname: "y"},
+		}, true},
+		{"absolute", []tar.Header{
+			{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+		}, false},
+		{"relative", []tar.Header{
+			{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		}, false},
+		// the ".." after the symlinks resolve against where they point, not lexically against the link
+		{"through symlinks", []tar.Header{
+			{Name: "s", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "s/s/s/../.."},
+		}, false},
+		{"not yet extracted", []tar.Header{
+			{Name: "later", Typeflag: tar.TypeSymlink, Linkname: "missing/.."},
+		}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := extractContext(entriesTar(t, tc.headers...), dir)
+			if tc.valid && err != nil {
+				t.Errorf("extractContext() = %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("extractContext() succeeded, want the symlink leaving the build context rejected")
+			}
+		})
+	}
+}
+
+func TestExtractContextReplacesSymlinks(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "outside")
+	if err := os.WriteFile(outside, []byte("kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	// a symlink left in the build directory by something else than the tar
+	if err := os.Symlink(outside, filepath.Join(dir, "file")); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractContext(entriesTar(t, tar.Header{Name: "file", Typeflag: tar.TypeReg, Linkname: "new"}), dir); err != nil {
+		t.Fatalf("extractContext() = %v", err)
+	}
+	if b, err := os.ReadFile(outside); err != nil || string(b) != "kept" {
+		t.Errorf("%s = %q, %v, want it kept", outside, b, err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "file")); err != nil || string(b) != "new" {
+		t.Errorf("file = %q, %v, want new", b, err)
+	}
+}
+
+func TestExtractContextSize(t *testing.T) {
+	defer func(size int64) { maxContextSize = size }(maxContextSize)
+	maxContextSize = 8
+	body := contextTar(t, map[string]string{"a": "12345", "b": "67890"}, false)
+	if err := extractContext(body, t.TempDir()); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("extractContext() of 10 bytes = %v, want the build context too large", err)
+	}
+	body = contextTar(t, map[string]string{"a": "1234", "b": "5678"}, false)
+	if err := extractContext(body, t.TempDir()); err != nil {
+		t.Errorf("extractContext() of 8 bytes = %v", err)
+	}
+}
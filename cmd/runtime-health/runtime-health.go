@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// runtime-health serves the health of the container runtime services of a node in the Prometheus text format.
+// It runs as a systemd unit on the node, so it keeps reporting when the runtime, and with it the cluster, is down.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// service is a systemd unit of a container runtime and the socket it serves
+type service struct {
+	Name   string
+	Unit   string
+	Socket string
+}
+
+// status is the health of a service at the time it was probed
+type status struct {
+	service
+	Active        bool
+	Restarts      int
+	SocketUp      bool
+	SocketLatency time.Duration
+}
+
+// runtimeServices are the services which make up each container runtime
+var runtimeServices = map[string][]service{
+	constants.Porto: {
+		{Name: "portod", Unit: "porto", Socket: "/run/portod.socket"},
+		{Name: "portoshim", Unit: "portoshim", Socket: "/run/portoshim.sock"},
+	},
+	constants.Containerd: {
+		{Name: "containerd", Unit: "containerd", Socket: "/run/containerd/containerd.sock"},
+	},
+	constants.CRIO: {
+		{Name: "crio", Unit: "crio", Socket: "/var/run/crio/crio.sock"},
+	},
+	constants.Docker: {
+		{Name: "docker", Unit: "docker", Socket: "/var/run/docker.sock"},
+		{Name: "cri-docker", Unit: "cri-docker", Socket: "/var/run/cri-dockerd.sock"},
+	},
+}
+
+var runtime = flag.String("container-runtime", constants.Docker, "Container runtime whose services are reported")
+var listen = flag.String("listen", fmt.Sprintf("0.0.0.0:%d", constants.RuntimeHealthPort), "Address to serve metrics on")
+var portoStats = flag.Bool("porto-stats", false, "Also report the statistics of portod, such as its containers, volumes and layer imports")
+
+func main() {
+	flag.Parse()
+
+	services, ok := runtimeServices[*runtime]
+	if !ok {
+		log.Fatalf("unknown container runtime %q", *runtime)
+	}
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		var statuses []status
+		for _, s := range services {
+			statuses = append(statuses, probe(s))
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, statuses)
+		if *portoStats {
+			stats, err := readPortoStats()
+			if err != nil {
+				log.Printf("reading porto statistics: %v", err)
+				return
+			}
+			writePortoStats(w, stats)
+		}
+	})
+	log.Printf("serving %s runtime health on %s", *runtime, *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
+
+// probe checks the unit and socket of a service
+func probe(s service) status {
+	st := status{service: s}
+	st.Active = exec.Command("systemctl", "is-active", "--quiet", s.Unit).Run() == nil
+
+	out, err := exec.Command("systemctl", "show", "--property=NRestarts", "--value", s.Unit).Output()
+	if err == nil {
+		st.Restarts, _ = strconv.Atoi(strings.TrimSpace(string(out)))
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("unix", s.Socket, 2*time.Second)
+	if err == nil {
+		st.SocketUp = true
+		st.SocketLatency = time.Since(start)
+		conn.Close()
+	}
+	return st
+}
+
+// writeMetrics writes the statuses in the Prometheus text exposition format
+func writeMetrics(w io.Writer, statuses []status) {
+	metric := func(name, typ, help string, value func(status) string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s{service=%q} %s\n", name, s.Name, value(s))
+		}
+	}
+	metric("minikube_runtime_up", "gauge", "Whether the systemd unit of the runtime service is active.", func(s status) string {
+		return boolValue(s.Active)
+	})
+	metric("minikube_runtime_restarts_total", "counter", "Number of times systemd restarted the runtime service.", func(s status) string {
+		return strconv.Itoa(s.Restarts)
+	})
+	metric("minikube_runtime_socket_up", "gauge", "Whether the socket of the runtime service accepts connections.", func(s status) string {
+		return boolValue(s.SocketUp)
+	})
+	metric("minikube_runtime_socket_latency_seconds", "gauge", "Time it took to connect to the socket of the runtime service.", func(s status) string {
+		return strconv.FormatFloat(s.SocketLatency.Seconds(), 'f', -1, 64)
+	})
+}
+
+// readPortoStats reads the counters portod keeps about itself from the porto_stat property of the root container
+func readPortoStats() (map[string]string, error) {
+	out, err := exec.Command("portoctl", "get", "/", "porto_stat").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePortoStats(string(out)), nil
+}
+
+// parsePortoStats parses porto_stat, "name: value" pairs separated by semicolons, keeping the numeric ones
+func parsePortoStats(out string) map[string]string {
+	stats := map[string]string{}
+	for _, pair := range strings.Split(out, ";") {
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			continue
+		}
+		stats[strings.TrimSpace(name)] = value
+	}
+	return stats
+}
+
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// writePortoStats writes each porto statistic as a metric of its own, untyped as porto mixes counters and gauges
+func writePortoStats(w io.Writer, stats map[string]string) {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metric := "minikube_porto_" + invalidMetricChars.ReplaceAllString(name, "_")
+		fmt.Fprintf(w, "# HELP %s The %s statistic of portod.\n# TYPE %s untyped\n%s %s\n", metric, name, metric, metric, stats[name])
+	}
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	statuses := []status{
+		{service: runtimeServices["porto"][0], Active: true, Restarts: 2, SocketUp: true, SocketLatency: 1500 * time.Microsecond},
+		{service: runtimeServices["porto"][1]},
+	}
+	var b bytes.Buffer
+	writeMetrics(&b, statuses)
+
+	want := `# HELP minikube_runtime_up Whether the systemd unit of the runtime service is active.
+# TYPE minikube_runtime_up gauge
+minikube_runtime_up{service="portod"} 1
+minikube_runtime_up{service="portoshim"} 0
+# HELP minikube_runtime_restarts_total Number of times systemd restarted the runtime service.
+# TYPE minikube_runtime_restarts_total counter
+minikube_runtime_restarts_total{service="portod"} 2
+minikube_runtime_restarts_total{service="portoshim"} 0
+# HELP minikube_runtime_socket_up Whether the socket of the runtime service accepts connections.
+# TYPE minikube_runtime_socket_up gauge
+minikube_runtime_socket_up{service="portod"} 1
+minikube_runtime_socket_up{service="portoshim"} 0
+# HELP minikube_runtime_socket_latency_seconds Time it took to connect to the socket of the runtime service.
+# TYPE minikube_runtime_socket_latency_seconds gauge
+minikube_runtime_socket_latency_seconds{service="portod"} 0.0015
+minikube_runtime_socket_latency_seconds{service="portoshim"} 0
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("writeMetrics returned diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortoStats(t *testing.T) {
+	stats := parsePortoStats("spawned: 1; containers: 12; layer_import: 3; version: 5.3.30; volumes.count: 4\n")
+	var b bytes.Buffer
+	writePortoStats(&b, stats)
+
+	want := `# HELP minikube_porto_containers The containers statistic of portod.
+# TYPE minikube_porto_containers untyped
+minikube_porto_containers 12
+# HELP minikube_porto_layer_import The layer_import statistic of portod.
+# TYPE minikube_porto_layer_import untyped
+minikube_porto_layer_import 3
+# HELP minikube_porto_spawned The spawned statistic of portod.
+# TYPE minikube_porto_spawned untyped
+minikube_porto_spawned 1
+# HELP minikube_porto_volumes_count The volumes.count statistic of portod.
+# TYPE minikube_porto_volumes_count untyped
+minikube_porto_volumes_count 4
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("writePortoStats returned diff (-want +got):\n%s", diff)
+	}
+}
@@ -174,4 +174,16 @@ var (
 	// YakdAssets assets for yakd addon
 	//go:embed yakd/*.yaml yakd/*.tmpl
 	YakdAssets embed.FS
+
+	// RuntimeHealthAssets assets for runtime-health addon
+	//go:embed runtime-health/*.tmpl
+	RuntimeHealthAssets embed.FS
+
+	// PortoMetricsAssets assets for porto-metrics addon
+	//go:embed porto-metrics/*.tmpl porto-metrics/*.yaml
+	PortoMetricsAssets embed.FS
+
+	// PortoDockerShimAssets assets for porto-docker-shim addon
+	//go:embed porto-docker-shim/*.tmpl
+	PortoDockerShimAssets embed.FS
 )
@@ -0,0 +1,43 @@
+################################################################################
+#
+# porto-bin
+#
+################################################################################
+
+PORTO_BIN_AARCH64_VERSION = v5.3.33-alpha.3
+PORTO_BIN_AARCH64_DISTRO = focal
+PORTO_BIN_AARCH64_SITE = https://github.com/go-faster/porto/releases/download/$(PORTO_BIN_AARCH64_VERSION)
+PORTO_BIN_AARCH64_SOURCE = porto_$(PORTO_BIN_AARCH64_DISTRO)_$(PORTO_BIN_AARCH64_VERSION)_arm64.tgz
+# the release assets of every architecture, which kicbase installs as well, hold the binaries at their top level
+PORTO_BIN_AARCH64_STRIP_COMPONENTS = 0
+
+define PORTO_BIN_AARCH64_USERS
+	- -1 porto -1 - - - - -
+endef
+
+define PORTO_BIN_AARCH64_INSTALL_TARGET_CMDS
+	$(INSTALL) -D -m 0755 \
+		$(@D)/portod \
+		$(TARGET_DIR)/sbin/portod
+	$(INSTALL) -D -m 0755 \
+		$(@D)/portoctl \
+		$(TARGET_DIR)/sbin/portoctl
+	$(INSTALL) -D -m 0755 \
+		$(@D)/portoinit \
+		$(TARGET_DIR)/sbin/portoinit
+	$(INSTALL) -Dm644 \
+		$(PORTO_BIN_AARCH64_PKGDIR)/k8s.conf \
+		$(TARGET_DIR)/etc/portod.conf.d/k8s.conf
+endef
+
+define PORTO_BIN_AARCH64_INSTALL_INIT_SYSTEMD
+	$(INSTALL) -D -m 644 \
+		$(PORTO_BIN_AARCH64_PKGDIR)/porto.service \
+		$(TARGET_DIR)/usr/lib/systemd/system/porto.service
+
+	$(INSTALL) -D -m 644 \
+		$(PORTO_BIN_AARCH64_PKGDIR)/porto.conf \
+		$(TARGET_DIR)/etc/sysctl.d/porto.conf
+endef
+
+$(eval $(generic-package))
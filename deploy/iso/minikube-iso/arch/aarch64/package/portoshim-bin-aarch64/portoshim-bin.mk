@@ -0,0 +1,32 @@
+################################################################################
+#
+# portoshim-bin
+#
+################################################################################
+
+PORTOSHIM_BIN_AARCH64_VERSION = v1.0.11-alpha.11
+PORTOSHIM_BIN_AARCH64_DISTRO = focal
+PORTOSHIM_BIN_AARCH64_SITE = https://github.com/go-faster/portoshim/releases/download/$(PORTOSHIM_BIN_AARCH64_VERSION)
+PORTOSHIM_BIN_AARCH64_SOURCE = portoshim_$(PORTOSHIM_BIN_AARCH64_DISTRO)_$(PORTOSHIM_BIN_AARCH64_VERSION)_arm64.tgz
+# the release assets of every architecture, which kicbase installs as well, hold the binaries at their top level
+PORTOSHIM_BIN_AARCH64_STRIP_COMPONENTS = 0
+
+define PORTOSHIM_BIN_AARCH64_INSTALL_TARGET_CMDS
+	$(INSTALL) -D -m 0755 \
+		$(@D)/portoshim \
+		$(TARGET_DIR)/sbin/portoshim
+	$(INSTALL) -D -m 0755 \
+		$(@D)/logshim \
+		$(TARGET_DIR)/sbin/logshim
+	$(INSTALL) -D -m 644 \
+		$(PORTOSHIM_BIN_AARCH64_PKGDIR)/crictl.yaml \
+		$(TARGET_DIR)/etc/crictl.yaml
+endef
+
+define PORTOSHIM_BIN_AARCH64_INSTALL_INIT_SYSTEMD
+	$(INSTALL) -D -m 644 \
+		$(PORTOSHIM_BIN_AARCH64_PKGDIR)/portoshim.service \
+		$(TARGET_DIR)/usr/lib/systemd/system/portoshim.service
+endef
+
+$(eval $(generic-package))
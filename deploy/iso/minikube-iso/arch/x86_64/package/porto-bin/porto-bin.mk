@@ -5,8 +5,11 @@
 ################################################################################
 
 PORTO_BIN_VERSION = v5.3.33-alpha.3
-PORTO_BIN_SITE = https://ytsaurus.hb.ru-msk.vkcs.cloud/porto
-PORTO_BIN_SOURCE = porto-$(PORTO_BIN_VERSION).tgz
+PORTO_BIN_DISTRO = focal
+PORTO_BIN_SITE = https://github.com/go-faster/porto/releases/download/$(PORTO_BIN_VERSION)
+PORTO_BIN_SOURCE = porto_$(PORTO_BIN_DISTRO)_$(PORTO_BIN_VERSION)_amd64.tgz
+# the release assets of every architecture, which kicbase installs as well, hold the binaries at their top level
+PORTO_BIN_STRIP_COMPONENTS = 0
 
 define PORTO_BIN_USERS
 	- -1 porto -1 - - - - -
@@ -5,8 +5,11 @@
 ################################################################################
 
 PORTOSHIM_BIN_VERSION = v1.0.11-alpha.11
-PORTOSHIM_BIN_SITE = https://ytsaurus.hb.ru-msk.vkcs.cloud/portoshim
-PORTOSHIM_BIN_SOURCE = portoshim-$(PORTOSHIM_BIN_VERSION).tgz
+PORTOSHIM_BIN_DISTRO = focal
+PORTOSHIM_BIN_SITE = https://github.com/go-faster/portoshim/releases/download/$(PORTOSHIM_BIN_VERSION)
+PORTOSHIM_BIN_SOURCE = portoshim_$(PORTOSHIM_BIN_DISTRO)_$(PORTOSHIM_BIN_VERSION)_amd64.tgz
+# the release assets of every architecture, which kicbase installs as well, hold the binaries at their top level
+PORTOSHIM_BIN_STRIP_COMPONENTS = 0
 
 define PORTOSHIM_BIN_INSTALL_TARGET_CMDS
 	$(INSTALL) -D -m 0755 \
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/util"
@@ -95,7 +97,7 @@ func generateTarball(kubernetesVersion, containerRuntime, tarballFilename string
 		return errors.Wrap(err, "failed create new runtime")
 	}
 
-	if err := cr.Enable(true, detect.CgroupDriver(), false); err != nil {
+	if err := cr.Enable(context.Background(), true, detect.CgroupDriver(), false); err != nil {
 		return errors.Wrap(err, "enable container runtime")
 	}
 
@@ -167,6 +169,10 @@ func imagePullCommand(containerRuntime, img string) *exec.Cmd {
 	if containerRuntime == "cri-o" {
 		return exec.Command("docker", "exec", profile, "sudo", "crictl", "pull", img)
 	}
+
+	if containerRuntime == "porto" {
+		return exec.Command("docker", "exec", profile, "sudo", "crictl", "pull", img)
+	}
 	return nil
 }
 
@@ -188,7 +194,12 @@ func createImageTarball(tarballFilename, containerRuntime string) error {
 		dirs = append(dirs, "./lib/containers")
 	}
 
-	args := []string{"exec", profile, "sudo", "tar", "--xattrs", "--xattrs-include", "security.capability", "-I", "lz4", "-C", "/var", "-cf", tarballFilename}
+	// porto keeps its stores in /place, so its tarball is rooted at /
+	if containerRuntime == "porto" {
+		dirs = []string{"./var/lib/minikube/binaries", "./place/porto_docker", "./place/porto_layers"}
+	}
+
+	args := []string{"exec", profile, "sudo", "tar", "--xattrs", "--xattrs-include", "security.capability", "-I", "lz4", "-C", download.TarballRoot(containerRuntime), "-cf", tarballFilename}
 	args = append(args, dirs...)
 	cmd := exec.Command("docker", args...)
 	cmd.Stdout = os.Stdout
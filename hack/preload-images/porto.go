@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/download"
+)
+
+// portoVersion is the version in the output of portod --version
+var portoVersion = regexp.MustCompile(`(\d\.\S*)`)
+
+// describePortoPreload returns the manifest entry of the porto preload tarballFilename of out/, with the porto
+// version of the node which wrote its store, and writes the sha256 file published next to it
+func describePortoPreload(tarballFilename, k8sVer, arch string) (download.PortoPreload, error) {
+	hostPath := path.Join("out/", tarballFilename)
+	sum, err := sha256File(hostPath)
+	if err != nil {
+		return download.PortoPreload{}, err
+	}
+	if err := os.WriteFile(hostPath+".sha256", []byte(fmt.Sprintf("%s  %s\n", sum, tarballFilename)), 0o644); err != nil {
+		return download.PortoPreload{}, errors.Wrap(err, "writing the sha256 file")
+	}
+	p := download.PortoPreload{KubernetesVersion: k8sVer, Arch: arch, Name: tarballFilename, SHA256: sum}
+	if b, err := exec.Command("docker", "exec", profile, "portod", "--version").Output(); err == nil {
+		p.Porto = portoVersion.FindString(string(b))
+	} else {
+		fmt.Printf("unable to get the porto version of the preload: %v\n", err)
+	}
+	return p, nil
+}
+
+// sha256File returns the hex sha256 of the file at p
+func sha256File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", errors.Wrap(err, "opening tarball")
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrap(err, "hashing tarball")
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// portoManifestGCS is the object of the manifest of the porto preloads of download.PreloadVersion
+func portoManifestGCS(preloadVersion string) string {
+	return fmt.Sprintf("gs://%s/%s/%s", download.PreloadBucket, preloadVersion, download.PortoPreloadManifestName)
+}
+
+// publishPortoPreload uploads the sha256 file of the porto preload p of preloadVersion, whose tarball has been uploaded
+// from dir already, then adds p to the manifest. The manifest goes last, so that it never lists a tarball which
+// can't be downloaded and verified, and it is uploaded only if nobody changed it since it was read, so that concurrent
+// uploads of the preloads of other architectures are not lost.
+func publishPortoPreload(dir, preloadVersion string, p download.PortoPreload) error {
+	sumPath := path.Join(dir, p.Name+".sha256")
+	gcsDir := fmt.Sprintf("gs://%s/%s/%s/", download.PreloadBucket, preloadVersion, p.KubernetesVersion)
+	if err := gsutil("cp", sumPath, gcsDir); err != nil {
+		return err
+	}
+
+	manifest, generation, err := readPortoManifest(preloadVersion)
+	if err != nil {
+		return err
+	}
+	manifest.Add(p)
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding the porto preload manifest")
+	}
+	manifestPath := path.Join(dir, download.PortoPreloadManifestName)
+	if err := os.WriteFile(manifestPath, append(b, '\n'), 0o644); err != nil {
+		return errors.Wrap(err, "writing the porto preload manifest")
+	}
+	// minikube reads the manifest on every start, which must not see a stale copy from a cache
+	return gsutil("-h", "Cache-Control:no-cache", "-h", "x-goog-if-generation-match:"+generation, "cp", manifestPath, portoManifestGCS(preloadVersion))
+}
+
+// readPortoManifest returns the published manifest of the porto preloads of preloadVersion, or an empty one,
+// and its generation, which is 0 when there is none
+func readPortoManifest(preloadVersion string) (*download.PortoPreloadManifest, string, error) {
+	object := portoManifestGCS(preloadVersion)
+	stat, err := exec.Command("gsutil", "stat", object).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(stat), "No URLs matched") {
+			return &download.PortoPreloadManifest{PreloadVersion: preloadVersion}, "0", nil
+		}
+		return nil, "", errors.Wrapf(err, "gsutil stat %s:\n%s", object, stat)
+	}
+	generation := ""
+	for _, line := range strings.Split(string(stat), "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && k == "Generation" {
+			generation = strings.TrimSpace(v)
+		}
+	}
+	if generation == "" {
+		return nil, "", fmt.Errorf("no generation of %s in:\n%s", object, stat)
+	}
+	b, err := exec.Command("gsutil", "cat", fmt.Sprintf("%s#%s", object, generation)).Output()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "gsutil cat %s", object)
+	}
+	var m download.PortoPreloadManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, "", errors.Wrapf(err, "parsing %s", object)
+	}
+	if m.PreloadVersion != preloadVersion {
+		return nil, "", fmt.Errorf("%s is the manifest of preload version %q", object, m.PreloadVersion)
+	}
+	return &m, generation, nil
+}
+
+// gsutil runs gsutil with args
+func gsutil(args ...string) error {
+	cmd := exec.Command("gsutil", args...)
+	fmt.Printf("Running: %v\n", cmd.Args)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "%v:\n%s", cmd.Args, string(output))
+	}
+	return nil
+}
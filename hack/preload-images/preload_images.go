@@ -31,6 +31,7 @@ import (
 
 	"github.com/spf13/viper"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/util"
 )
@@ -44,7 +45,7 @@ var (
 	dockerStorageDriver   = "overlay2"
 	containerdSnapshotter = "overlayfs"
 	podmanStorageDriver   = "overlay"
-	containerRuntimes     = []string{"docker", "containerd", "cri-o"}
+	containerRuntimes     = []string{"docker", "containerd", "cri-o", "porto"}
 	k8sVersions           []string
 	k8sVersion            = flag.String("kubernetes-version", "", "desired Kubernetes version, for example `v1.17.2`")
 	noUpload              = flag.Bool("no-upload", false, "Do not upload tarballs to GCS")
@@ -154,6 +155,14 @@ func makePreload(cfg preloadCfg) error {
 	if err := generateTarball(kv, cr, tf); err != nil {
 		return errors.Wrap(err, fmt.Sprintf("generating tarball for k8s version %s with %s", kv, cr))
 	}
+	var porto download.PortoPreload
+	if cr == "porto" {
+		p, err := describePortoPreload(tf, kv, detect.EffectiveArch())
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("describing porto tarball for k8s version %s", kv))
+		}
+		porto = p
+	}
 
 	if *noUpload {
 		fmt.Printf("skip upload of %q\n", tf)
@@ -162,6 +171,11 @@ func makePreload(cfg preloadCfg) error {
 	if err := uploadTarball(tf, kv); err != nil {
 		return errors.Wrap(err, fmt.Sprintf("uploading tarball for k8s version %s with %s", kv, cr))
 	}
+	if cr == "porto" {
+		if err := publishPortoPreload("out/", download.PreloadVersion, porto); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("publishing porto tarball for k8s version %s", kv))
+		}
+	}
 	return nil
 }
 
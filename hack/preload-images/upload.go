@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path"
 	"strings"
@@ -50,6 +51,10 @@ func uploadArmTarballs(preloadsDir string) error {
 	// remove trailing whitespace entry
 	files = files[:len(files)-1]
 	for _, file := range files {
+		// published along with the manifest by publishPortoPreload
+		if strings.HasSuffix(file, ".sha256") {
+			continue
+		}
 		preloadVersion, k8sVersion := getVersionsFromFilename(file)
 		hostPath := path.Join(preloadsDir, file)
 		gcsDest := fmt.Sprintf("gs://%s/%s/%s/", download.PreloadBucket, preloadVersion, k8sVersion)
@@ -58,6 +63,31 @@ func uploadArmTarballs(preloadsDir string) error {
 		if output, err := cmd.CombinedOutput(); err != nil {
 			return errors.Wrapf(err, "uploading %s to GCS bucket: %v\n%s", hostPath, err, string(output))
 		}
+		if strings.Contains(file, "-porto-") {
+			if err := publishArmPortoPreload(preloadsDir, file, preloadVersion, k8sVersion); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// publishArmPortoPreload publishes the sha256 file and the manifest entry of the uploaded porto preload file of preloadsDir,
+// writing the sha256 file from the tarball itself rather than trusting one the job which built it left next to it
+func publishArmPortoPreload(preloadsDir, file, preloadVersion, k8sVersion string) error {
+	hostPath := path.Join(preloadsDir, file)
+	sum, err := sha256File(hostPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(hostPath+".sha256", []byte(fmt.Sprintf("%s  %s\n", sum, file)), 0o644); err != nil {
+		return errors.Wrap(err, "writing the sha256 file")
+	}
+	// preloaded-images-k8s-<preload version>-<k8s version>-porto-overlay-<arch>.tar.lz4
+	parts := strings.Split(strings.TrimSuffix(file, ".tar.lz4"), "-")
+	p := download.PortoPreload{KubernetesVersion: k8sVersion, Arch: parts[len(parts)-1], Name: file, SHA256: sum}
+	if err := publishPortoPreload(preloadsDir, preloadVersion, p); err != nil {
+		return errors.Wrapf(err, "publishing %s", file)
 	}
 	return nil
 }
@@ -70,14 +70,15 @@ func main() {
 
 	data := Data{Version: stable.Tag, Commit: stable.Commit}
 
-	update.Apply(schema, data)
-
-	if err := updateHashFile(data.Version, "amd64", "x86_64/package/buildkit-bin/buildkit-bin.hash"); err != nil {
-		klog.Fatalf("failed to update amd64 hash file: %v", err)
-	}
-	if err := updateHashFile(data.Version, "arm64", "aarch64/package/buildkit-bin-aarch64/buildkit-bin.hash"); err != nil {
-		klog.Fatalf("failed to update arm64 hash file: %v", err)
-	}
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFile(data.Version, "amd64", "x86_64/package/buildkit-bin/buildkit-bin.hash"); err != nil {
+			return fmt.Errorf("failed to update amd64 hash file: %w", err)
+		}
+		if err := updateHashFile(data.Version, "arm64", "aarch64/package/buildkit-bin-aarch64/buildkit-bin.hash"); err != nil {
+			return fmt.Errorf("failed to update arm64 hash file: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFile(version, arch, filePath string) error {
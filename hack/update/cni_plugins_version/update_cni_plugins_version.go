@@ -65,16 +65,26 @@ func main() {
 		klog.Fatalf("Unable to get stable version: %v", err)
 	}
 
-	data := Data{Version: stable.Tag}
-
-	update.Apply(schema, data)
-
-	if err := updateHashFile(data.Version, "arm64", "aarch64/package/cni-plugins-aarch64"); err != nil {
-		klog.Fatalf("failed to update hash files: %v", err)
+	// the CNI plugins are validated with the portoshim of the ISO, so only versions of the lines it works with are taken
+	tools, err := update.PortoToolsFor()
+	if err != nil {
+		klog.Fatalf("Unable to get the CNI plugins versions validated with porto: %v", err)
 	}
-	if err := updateHashFile(data.Version, "amd64", "x86_64/package/cni-plugins"); err != nil {
-		klog.Fatalf("failed to update hash files: %v", err)
+	if !update.InLines(stable.Tag, tools.CNIPlugins) {
+		klog.Fatalf("CNI plugins %s are not validated with the portoshim of the ISO, which works with %v: update within them with 'make update-porto-tools-version'", stable.Tag, tools.CNIPlugins)
 	}
+
+	data := Data{Version: stable.Tag}
+
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFile(data.Version, "arm64", "aarch64/package/cni-plugins-aarch64"); err != nil {
+			return fmt.Errorf("failed to update hash files: %w", err)
+		}
+		if err := updateHashFile(data.Version, "amd64", "x86_64/package/cni-plugins"); err != nil {
+			return fmt.Errorf("failed to update hash files: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFile(version, arch, packagePath string) error {
@@ -65,11 +65,12 @@ func main() {
 
 	data := Data{Version: stable.Tag, Commit: stable.Commit}
 
-	update.Apply(schema, data)
-
-	if err := updateHashFiles(data.Version); err != nil {
-		klog.Fatalf("failed to update hash files: %v", err)
-	}
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFiles(data.Version); err != nil {
+			return fmt.Errorf("failed to update hash files: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFiles(version string) error {
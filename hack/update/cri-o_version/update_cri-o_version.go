@@ -67,11 +67,12 @@ func main() {
 
 	data := Data{Version: stable.Tag, MMVersion: mmVersion, Commit: stable.Commit}
 
-	update.Apply(schema, data)
-
-	if err := updateHashFile(data.Version); err != nil {
-		klog.Fatalf("failed to update hash file: %v", err)
-	}
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFile(data.Version); err != nil {
+			return fmt.Errorf("failed to update hash file: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFile(version string) error {
@@ -93,11 +93,12 @@ func main() {
 
 	data := Data{Version: version, FullCommit: stable.Commit, ShortCommit: stable.Commit[:7]}
 
-	update.Apply(schema, data)
-
-	if err := updateHashFiles(stable.Commit); err != nil {
-		klog.Fatalf("failed to update hash files: %v", err)
-	}
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFiles(stable.Commit); err != nil {
+			return fmt.Errorf("failed to update hash files: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFiles(commit string) error {
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// distroEnv overrides the distro whose release assets the ISO packages download, to move the ISO to another base
+const distroEnv = "ISO_DISTRO"
+
+// Distros are the distros go-faster releases porto and portoshim for, newest first
+var Distros = []string{"noble", "jammy", "focal"}
+
+// ReleaseAssets returns the names of the assets of the release tag of the GitHub repo owner/repo
+func ReleaseAssets(ctx context.Context, owner, repo, tag string) (map[string]bool, error) {
+	rl, _, err := GHClient().Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s of %s/%s: %w", tag, owner, repo, err)
+	}
+	assets := map[string]bool{}
+	for _, a := range rl.Assets {
+		assets[a.GetName()] = true
+	}
+	return assets, nil
+}
+
+// ReleaseDistros returns the Distros, newest first, for which assets hold the asset of every one of arches,
+// as named by asset
+func ReleaseDistros(assets map[string]bool, asset func(distro, arch string) string, arches []string) []string {
+	var distros []string
+	for _, d := range Distros {
+		complete := true
+		for _, arch := range arches {
+			if !assets[asset(d, arch)] {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			distros = append(distros, d)
+		}
+	}
+	return distros
+}
+
+// MkDistro returns the <PKG>_DISTRO the buildroot package makefile at path, relative to FSRoot, sets,
+// or "" when its source does not depend on a distro
+func MkDistro(path string) (string, error) {
+	mk, err := readMk(filepath.Join(FSRoot, path))
+	if err != nil {
+		return "", err
+	}
+	return mk.vars[mk.prefix+"_DISTRO"], nil
+}
+
+// PickDistro returns the distro to update the ISO packages to: the one ISO_DISTRO names, or else current,
+// the distro they download now. It must be among available, the distros the release has all the assets of.
+func PickDistro(current string, available []string) (string, error) {
+	want := current
+	if d := os.Getenv(distroEnv); d != "" {
+		want = d
+	}
+	if want == "" {
+		return "", fmt.Errorf("no distro to update to, set %s to one of %s", distroEnv, strings.Join(available, ", "))
+	}
+	if !slices.Contains(available, want) {
+		if len(available) == 0 {
+			return "", fmt.Errorf("the release has no complete set of assets for any of %s", strings.Join(Distros, ", "))
+		}
+		return "", fmt.Errorf("the release has no %s assets, only %s ones: set %s to move the ISO to another base", want, strings.Join(available, ", "), distroEnv)
+	}
+	return want, nil
+}
@@ -60,14 +60,15 @@ func main() {
 
 	data := Data{Version: strings.TrimPrefix(stable.Tag, "v")}
 
-	update.Apply(schema, data)
-
-	if err := updateHashFile(data.Version, "aarch64", "-aarch64"); err != nil {
-		klog.Fatalf("failed to update hash file: %v", err)
-	}
-	if err := updateHashFile(data.Version, "x86_64", ""); err != nil {
-		klog.Fatalf("failed to update hash file: %v", err)
-	}
+	update.ApplyWithHashes(schema, data, func() error {
+		if err := updateHashFile(data.Version, "aarch64", "-aarch64"); err != nil {
+			return fmt.Errorf("failed to update hash file: %w", err)
+		}
+		if err := updateHashFile(data.Version, "x86_64", ""); err != nil {
+			return fmt.Errorf("failed to update hash file: %w", err)
+		}
+		return nil
+	})
 }
 
 func updateHashFile(version, arch, folderSuffix string) error {
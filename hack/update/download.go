@@ -0,0 +1,399 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ghReleaseURL is the prefix of GitHub release asset links
+	ghReleaseURL = "https://github.com/"
+
+	// releaseMirrorsEnv lists base URLs, comma-separated, which serve GitHub release assets under the same paths,
+	// such as a remote repository of an artifact proxy, for networks where GitHub is blocked.
+	releaseMirrorsEnv = "GITHUB_RELEASE_MIRRORS"
+
+	// downloadAttempts is how many times a transfer from one location is resumed before trying the next one
+	downloadAttempts = 5
+	// downloadMaxBackoff caps the wait between attempts, which doubles from a second
+	downloadMaxBackoff = 30 * time.Second
+
+	// downloadAttemptTimeout bounds an attempt of a transfer, which the next attempt resumes where it stopped
+	downloadAttemptTimeout = 5 * time.Minute
+	// fetchSmallTimeout bounds a request for a checksum file or signature bundle
+	fetchSmallTimeout = 30 * time.Second
+)
+
+// downloadClient is the client of the downloads of release assets. Servers which stall before they answer fail the
+// attempt well before its downloadAttemptTimeout, so that it is retried rather than waited for.
+var downloadClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+// ReleaseAssetURLs returns the locations to download a GitHub release asset from: the mirror of --mirror and those
+// listed in GITHUB_RELEASE_MIRRORS first, as they are set when GitHub can't be reached directly, then GitHub itself.
+func ReleaseAssetURLs(link string) []string {
+	urls := []string{}
+	if strings.HasPrefix(link, ghReleaseURL) {
+		for _, m := range append([]string{*mirrorFlag}, strings.Split(os.Getenv(releaseMirrorsEnv), ",")...) {
+			if m = strings.TrimSpace(m); m != "" {
+				urls = append(urls, strings.TrimSuffix(m, "/")+"/"+strings.TrimPrefix(link, ghReleaseURL))
+			}
+		}
+	}
+	return append(urls, link)
+}
+
+// DownloadSHA256 downloads the file at the first of urls which serves it and returns its sha256 sum.
+// Interrupted transfers are resumed with range requests where the server allows it.
+// The last of urls is the origin of the file, as ReleaseAssetURLs lists them: the checksum and the cosign bundle are
+// always fetched from there rather than from a mirror, which could serve a file and a checksum of its own.
+// If the origin publishes a checksum of the file, the sum must match it, and p can require one as well as a signature.
+// Files named like gzip tarballs must be ones, holding files with each of the base names in contents, as caches in front of
+// GitHub sometimes serve an error page with a 200 in place of a release asset, which would otherwise be hashed just the same.
+func DownloadSHA256(ctx context.Context, contents []string, p Provenance, urls ...string) (string, error) {
+	if len(urls) == 0 {
+		return "", fmt.Errorf("no location to download from")
+	}
+	origin := urls[len(urls)-1]
+	var errs []string
+	for _, u := range urls {
+		sum, err := downloadSHA256(ctx, u, origin, contents, p)
+		if err == nil {
+			if err = verifySHA256(ctx, origin, sum, p.Checksum); err == nil {
+				return sum, nil
+			}
+		}
+		klog.Warningf("failed to download %s: %v", u, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", u, err))
+	}
+	return "", fmt.Errorf("failed to download from any location:\n%s", strings.Join(errs, "\n"))
+}
+
+// download is a file being downloaded: its sha256 and a copy of it to check the contents of once it is complete
+type download struct {
+	h hash.Hash
+	f *os.File
+}
+
+func (d *download) Write(p []byte) (int, error) {
+	d.h.Write(p)
+	return d.f.Write(p)
+}
+
+// reset discards what has been downloaded so far
+func (d *download) reset() error {
+	d.h.Reset()
+	if err := d.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := d.f.Seek(0, io.SeekStart)
+	return err
+}
+
+// retryDownload runs op up to downloadAttempts times with exponential backoff, until it succeeds, ctx is done or op
+// fails with a backoff.Permanent error, which trying again does not fix
+func retryDownload(ctx context.Context, u string, op func() error) error {
+	be := backoff.NewExponentialBackOff()
+	be.InitialInterval = time.Second
+	be.Multiplier = 2
+	be.MaxInterval = downloadMaxBackoff
+	be.MaxElapsedTime = 0
+	bc := backoff.WithContext(backoff.WithMaxRetries(be, downloadAttempts-1), ctx)
+
+	notify := func(err error, wait time.Duration) {
+		klog.Warningf("Temporary error downloading %s (will retry in %s): %v", u, wait, err)
+	}
+	return backoff.RetryNotify(op, bc, notify)
+}
+
+// statusError returns the error of an unexpected response status, which is permanent unless the server may answer
+// differently later: on a timeout, a rate limit or a server error
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("unexpected status: %s", resp.Status)
+	if resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return err
+	}
+	return backoff.Permanent(err)
+}
+
+// downloadSHA256 hashes the file at u, resuming the transfer after it breaks off, and checks it holds contents
+// and the signature p requires of the file at origin
+func downloadSHA256(ctx context.Context, u, origin string, contents []string, p Provenance) (string, error) {
+	f, err := os.CreateTemp("", "update-download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	d := &download{h: sha256.New(), f: f}
+	var written int64
+	err = retryDownload(ctx, u, func() error {
+		if err := fetchRange(ctx, u, d, &written); err != nil {
+			return fmt.Errorf("after %d bytes: %w", written, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := checkTarball(u, f, contents); err != nil {
+		return "", err
+	}
+	if err := verifyCosign(ctx, origin, f.Name(), p.CosignIdentity); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(d.h.Sum(nil)), nil
+}
+
+// fetchRange writes the body of u from byte *written on to d, advancing *written, within downloadAttemptTimeout.
+// It succeeds once the whole file has been downloaded.
+func fetchRange(ctx context.Context, u string, d *download, written *int64) error {
+	ctx, cancel := context.WithTimeout(ctx, downloadAttemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	if *written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", *written))
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if *written > 0 {
+			// the server ignored the range, start over
+			if err := d.reset(); err != nil {
+				return backoff.Permanent(err)
+			}
+			*written = 0
+		}
+	case http.StatusPartialContent:
+	default:
+		return statusError(resp)
+	}
+	n, err := io.Copy(d, resp.Body)
+	*written += n
+	return err
+}
+
+// isTarball returns whether the file at u is named like a gzip tarball
+func isTarball(u string) bool {
+	return strings.HasSuffix(u, ".tgz") || strings.HasSuffix(u, ".tar.gz")
+}
+
+// checkTarball checks that the file downloaded from u to f is a gzip tarball which can be read to its end and holds files
+// with each of the base names in contents. Files not named like tarballs are only checked when contents are expected.
+func checkTarball(u string, f io.ReadSeeker, contents []string) error {
+	if !isTarball(u) && len(contents) == 0 {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	head := make([]byte, 64)
+	n, _ := io.ReadFull(f, head)
+	if head = head[:n]; !bytes.HasPrefix(head, []byte{0x1f, 0x8b}) {
+		return fmt.Errorf("not a gzip tarball, it starts with %q", head)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a gzip tarball: %w", err)
+	}
+	missing := map[string]bool{}
+	for _, c := range contents {
+		missing[c] = true
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("not a valid gzip tarball: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			delete(missing, path.Base(hdr.Name))
+		}
+	}
+	// reading up to the end of the gzip stream verifies its checksum
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return fmt.Errorf("not a valid gzip tarball: %w", err)
+	}
+	if len(missing) != 0 {
+		var names []string
+		for _, c := range contents {
+			if missing[c] {
+				names = append(names, c)
+			}
+		}
+		return fmt.Errorf("the tarball is missing %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// checksumFiles are the files of a release listing the sha256 of its assets, as "<sum>  <asset>" lines
+var checksumFiles = []string{"SHA256SUMS", "checksums.txt"}
+
+// fetchSmall returns the body of the small file at u, or nil if there is none. Failures which may be transient are
+// retried like downloads, as a checksum or signature missing for a moment would fail or weaken the verification.
+func fetchSmall(ctx context.Context, u string) ([]byte, error) {
+	var b []byte
+	err := retryDownload(ctx, u, func() error {
+		ctx, cancel := context.WithTimeout(ctx, fetchSmallTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			var permanent *backoff.PermanentError
+			if err := statusError(resp); !errors.As(err, &permanent) {
+				return err
+			}
+			klog.Infof("no %s (%s)", u, resp.Status)
+			b = nil
+			return nil
+		}
+		b, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return err
+	})
+	return b, err
+}
+
+// publishedSHA256 returns the checksum published for the file at u: in "<u>.sha256", holding "<sum>" or
+// "<sum>  <file name>", or else in a checksum file of the release next to it. It returns "" if there is none.
+func publishedSHA256(ctx context.Context, u string) (string, error) {
+	b, err := fetchSmall(ctx, u+".sha256")
+	if err != nil {
+		return "", err
+	}
+	if fields := strings.Fields(string(b)); len(fields) > 0 {
+		return fields[0], nil
+	}
+	dir, name := path.Split(u)
+	for _, c := range checksumFiles {
+		b, err := fetchSmall(ctx, dir+c)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			// sha256sum marks files hashed in binary mode with a "*"
+			if fields := strings.Fields(line); len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+				return fields[0], nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// verifySHA256 compares sum against the checksum published for the file at u. Without one, the file is only accepted
+// unless required.
+func verifySHA256(ctx context.Context, u, sum string, required bool) error {
+	published, err := publishedSHA256(ctx, u)
+	if err != nil {
+		return err
+	}
+	if published == "" {
+		if required {
+			return fmt.Errorf("no checksum is published for it, in %s.sha256 or %s next to it", path.Base(u), strings.Join(checksumFiles, " or "))
+		}
+		klog.Infof("no checksum file for %s, not verifying it", u)
+		return nil
+	}
+	if !strings.EqualFold(published, sum) {
+		return fmt.Errorf("sha256 %s does not match the published checksum %s", sum, published)
+	}
+	return nil
+}
+
+// cosignIssuer is the OIDC issuer of the certificates GitHub Actions workflows sign release assets with
+const cosignIssuer = "https://token.actions.githubusercontent.com"
+
+// verifyCosign checks the signature of the file downloaded to file against the cosign bundle "<u>.bundle" next to
+// the file at u: it must be signed by a GitHub Actions workflow whose identity matches the regular expression identity.
+// Nothing is checked when identity is empty, and otherwise a missing bundle is an error.
+func verifyCosign(ctx context.Context, u, file, identity string) error {
+	if identity == "" {
+		return nil
+	}
+	b, err := fetchSmall(ctx, u+".bundle")
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("%s must be signed, but there is no cosign bundle at %s.bundle", path.Base(u), u)
+	}
+	bundle, err := os.CreateTemp("", "update-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(bundle.Name())
+	defer bundle.Close()
+	if _, err := bundle.Write(b); err != nil {
+		return err
+	}
+	cosign, err := exec.LookPath("cosign")
+	if err != nil {
+		return fmt.Errorf("%s is signed, cosign is needed to verify it: %w", path.Base(u), err)
+	}
+	cmd := exec.CommandContext(ctx, cosign, "verify-blob", "--bundle", bundle.Name(),
+		"--certificate-identity-regexp", identity, "--certificate-oidc-issuer", cosignIssuer, file)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cosign verify-blob: %w: %s", err, bytes.TrimSpace(out))
+	}
+	klog.Infof("verified the cosign signature of %s", u)
+	return nil
+}
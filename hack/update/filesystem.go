@@ -18,6 +18,7 @@ package update
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -62,6 +63,51 @@ func fsUpdate(fsRoot string, schema map[string]Item, data interface{}) (changed
 	return changed, nil
 }
 
+// fileBackup is the content and mode of a file, or a file that does not exist when content is nil
+type fileBackup struct {
+	content []byte
+	mode    os.FileMode
+}
+
+// snapshot backs up the files at paths, relative to fsRoot, for restore to put them back
+func snapshot(fsRoot string, paths []string) (map[string]fileBackup, error) {
+	backup := map[string]fileBackup{}
+	for _, p := range paths {
+		p = filepath.Join(fsRoot, p)
+		info, err := os.Stat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			backup[p] = fileBackup{}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		backup[p] = fileBackup{content: content, mode: info.Mode()}
+	}
+	return backup, nil
+}
+
+// restore puts back the files of a snapshot, removing those which did not exist
+func restore(backup map[string]fileBackup) error {
+	var errs []error
+	for p, b := range backup {
+		if b.content == nil {
+			if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := os.WriteFile(p, b.content, b.mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Loadf returns the file content read as byte slice
 func Loadf(path string) []byte {
 	blob, err := os.ReadFile(path)
@@ -19,6 +19,8 @@ package update
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"golang.org/x/mod/semver"
@@ -40,66 +42,152 @@ type Release struct {
 	Commit string
 }
 
-// GHReleases returns greatest current stable release and greatest latest rc or beta pre-release from GitHub owner/repo repository, and any error occurred.
-// If latest pre-release version is lower than the current stable release, then it will return current stable release for both.
-func GHReleases(ctx context.Context, owner, repo string) (stable, latest, edge Release, err error) {
-	ghc := github.NewClient(nil)
+// GHTags returns the tags of up to ghSearchLimit newest releases from GitHub owner/repo repository which are semantic versions,
+// greatest first, with their Tag normalized to start with "v".
+func GHTags(ctx context.Context, owner, repo string) ([]Release, error) {
+	ghc := GHClient()
 
+	var tags []Release
 	// walk through the paginated list of up to ghSearchLimit newest releases
 	opts := &github.ListOptions{PerPage: ghListPerPage}
 	for (opts.Page+1)*ghListPerPage <= ghSearchLimit {
 		rls, resp, err := ghc.Repositories.ListTags(ctx, owner, repo, opts)
 		if err != nil {
-			return stable, latest, edge, err
+			return nil, err
 		}
 		for _, rl := range rls {
 			ver := rl.GetName()
-			commit := rl.GetCommit().GetSHA()
 			if !semver.IsValid(ver) {
 				ver = fmt.Sprintf("v%s", ver)
 				if !semver.IsValid(ver) {
 					continue
 				}
 			}
-			// check if ver version is release (ie, 'v1.19.2') or pre-release (ie, 'v1.19.3-rc.0' or 'v1.19.0-beta.2')
-			prerls := semver.Prerelease(ver)
-			if prerls == "" {
-				if semver.Compare(ver, stable.Tag) == 1 {
-					stable.Tag = ver
-					stable.Commit = commit
-				}
-			} else if strings.HasPrefix(prerls, "-rc") || strings.HasPrefix(prerls, "-beta") {
-				if semver.Compare(ver, latest.Tag) == 1 {
-					latest.Tag = ver
-					latest.Commit = commit
-				}
-			} else if strings.Contains(prerls, "-alpha") {
-				if semver.Compare(ver, edge.Tag) == 1 {
-					edge.Tag = ver
-					edge.Commit = commit
-				}
-			}
-
-			// make sure that latest >= stable
-			if semver.Compare(latest.Tag, stable.Tag) == -1 {
-				latest.Tag = stable.Tag
-				latest.Commit = stable.Commit
-			}
-			// make sure that edge >= latest
-			if semver.Compare(edge.Tag, latest.Tag) == -1 {
-				edge.Tag = latest.Tag
-				edge.Commit = latest.Commit
-			}
+			tags = append(tags, Release{Tag: ver, Commit: rl.GetCommit().GetSHA()})
 		}
 		if resp.NextPage == 0 {
 			break
 		}
 		opts.Page = resp.NextPage
 	}
+	sort.SliceStable(tags, func(i, j int) bool { return semver.Compare(tags[i].Tag, tags[j].Tag) == 1 })
+	return tags, nil
+}
+
+// GHReleases returns greatest current stable release and greatest latest rc or beta pre-release from GitHub owner/repo repository, and any error occurred.
+// If latest pre-release version is lower than the current stable release, then it will return current stable release for both.
+func GHReleases(ctx context.Context, owner, repo string) (stable, latest, edge Release, err error) {
+	tags, err := GHTags(ctx, owner, repo)
+	if err != nil {
+		return stable, latest, edge, err
+	}
+	for _, rl := range tags {
+		ver := rl.Tag
+		// check if ver version is release (ie, 'v1.19.2') or pre-release (ie, 'v1.19.3-rc.0' or 'v1.19.0-beta.2')
+		prerls := semver.Prerelease(ver)
+		if prerls == "" {
+			if semver.Compare(ver, stable.Tag) == 1 {
+				stable = rl
+			}
+		} else if strings.HasPrefix(prerls, "-rc") || strings.HasPrefix(prerls, "-beta") {
+			if semver.Compare(ver, latest.Tag) == 1 {
+				latest = rl
+			}
+		} else if strings.Contains(prerls, "-alpha") {
+			if semver.Compare(ver, edge.Tag) == 1 {
+				edge = rl
+			}
+		}
+
+		// make sure that latest >= stable
+		if semver.Compare(latest.Tag, stable.Tag) == -1 {
+			latest = stable
+		}
+		// make sure that edge >= latest
+		if semver.Compare(edge.Tag, latest.Tag) == -1 {
+			edge = latest
+		}
+	}
 
 	return stable, latest, edge, nil
 }
 
+// Channels are the release channels of GHReleases, from the most to the least stable
+var Channels = []string{"stable", "latest", "edge"}
+
+// InChannel returns whether tag is a release of channel: stable has releases only, latest rc and beta pre-releases
+// as well, and edge alpha pre-releases too, as GHReleases tells them apart
+func InChannel(tag, channel string) bool {
+	prerls := semver.Prerelease(tag)
+	switch channel {
+	case "stable":
+		return prerls == ""
+	case "latest":
+		return InChannel(tag, "stable") || strings.HasPrefix(prerls, "-rc") || strings.HasPrefix(prerls, "-beta")
+	case "edge":
+		return InChannel(tag, "latest") || strings.Contains(prerls, "-alpha")
+	}
+	return false
+}
+
+// Channel returns the release channel --channel picks, def when it is not set
+func Channel(def string) (string, error) {
+	channel := *channelFlag
+	if channel == "" {
+		channel = def
+	}
+	if !slices.Contains(Channels, channel) {
+		return "", fmt.Errorf("unknown release channel %q, must be one of %s", channel, strings.Join(Channels, ", "))
+	}
+	return channel, nil
+}
+
+// PinnedRelease returns the release of GitHub owner/repo repository --version pins, with the commit --commit pins
+// or else the one its tag points to, and whether there is one
+func PinnedRelease(ctx context.Context, owner, repo string) (Release, bool, error) {
+	if *versionFlag == "" {
+		if *commitFlag != "" {
+			return Release{}, false, fmt.Errorf("--commit pins the commit of --version, which is not set")
+		}
+		return Release{}, false, nil
+	}
+	if *commitFlag != "" {
+		return Release{Tag: *versionFlag, Commit: *commitFlag}, true, nil
+	}
+	tags, err := GHTags(ctx, owner, repo)
+	if err != nil {
+		return Release{}, true, err
+	}
+	for _, rl := range tags {
+		// GHTags adds the "v" some tags lack
+		if rl.Tag == *versionFlag || rl.Tag == "v"+*versionFlag {
+			return rl, true, nil
+		}
+	}
+	return Release{}, true, fmt.Errorf("%s/%s has no release %s among its newest %d", owner, repo, *versionFlag, ghSearchLimit)
+}
+
+// PickRelease returns the release of GitHub owner/repo repository to update to: the one pinned with --version,
+// or else the greatest release of the channel --channel picks, def when it is not set
+func PickRelease(ctx context.Context, owner, repo, def string) (Release, error) {
+	if rl, ok, err := PinnedRelease(ctx, owner, repo); ok || err != nil {
+		return rl, err
+	}
+	channel, err := Channel(def)
+	if err != nil {
+		return Release{}, err
+	}
+	stable, latest, edge, err := GHReleases(ctx, owner, repo)
+	if err != nil {
+		return Release{}, err
+	}
+	rl := map[string]Release{"stable": stable, "latest": latest, "edge": edge}[channel]
+	if rl.Tag == "" {
+		return Release{}, fmt.Errorf("%s/%s has no %s release", owner, repo, channel)
+	}
+	return rl, nil
+}
+
 func StableVersion(ctx context.Context, owner, repo string) (string, error) {
 	stable, _, _, err := GHReleases(ctx, owner, repo)
 	if err != nil || !semver.IsValid(stable.Tag) {
@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ghCacheDirEnv overrides the directory GitHub API responses are cached in, so that CI can keep it between scheduled jobs
+	ghCacheDirEnv = "GITHUB_CACHE_DIR"
+
+	// ghAttempts is how many times a GitHub API request is sent before its last response is returned as is
+	ghAttempts = 5
+
+	// ghMaxWait is the longest a request waits for the rate limit to reset; beyond it the rate limit error is returned
+	ghMaxWait = 15 * time.Minute
+)
+
+// ghTokenEnvs are the environment variables a GitHub token is read from, in order.
+// Authenticated requests get 5000 instead of 60 requests an hour, which is why the update workflows set GITHUB_TOKEN.
+var ghTokenEnvs = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+var (
+	ghClient     *github.Client
+	ghClientOnce sync.Once
+)
+
+// GHClient returns the GitHub client all updaters share. It authenticates with the token in GITHUB_TOKEN or GH_TOKEN,
+// revalidates responses cached on disk with their ETags, which GitHub does not count against the rate limit,
+// and waits out rate limits and server errors instead of failing.
+func GHClient() *github.Client {
+	ghClientOnce.Do(func() {
+		ghClient = github.NewClient(&http.Client{Transport: &ghTransport{base: http.DefaultTransport, cacheDir: ghCacheDir()}})
+		for _, env := range ghTokenEnvs {
+			if token := os.Getenv(env); token != "" {
+				klog.Infof("authenticating to GitHub with the token in %s", env)
+				ghClient = ghClient.WithAuthToken(token)
+				break
+			}
+		}
+	})
+	return ghClient
+}
+
+// ghCacheDir returns the directory of cached GitHub API responses, or "" to not cache them
+func ghCacheDir() string {
+	if dir := os.Getenv(ghCacheDirEnv); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		klog.Warningf("not caching GitHub responses: %v", err)
+		return ""
+	}
+	return filepath.Join(dir, "minikube-update", "github")
+}
+
+// ghCachedResponse is a GitHub API response as stored on disk
+type ghCachedResponse struct {
+	ETag   string
+	Header http.Header
+	Body   []byte
+}
+
+// ghTransport makes GitHub API requests conditional on the ETags of cached responses and retries them when rate limited
+type ghTransport struct {
+	base     http.RoundTripper
+	cacheDir string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ghTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// only GETs are cached, and only requests without a body can be sent again
+	if req.Method != http.MethodGet || req.Body != nil {
+		return t.base.RoundTrip(req)
+	}
+
+	cached := t.load(req)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 1; attempt <= ghAttempts; attempt++ {
+		resp, err = t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, retry := ghRetryAfter(resp, attempt, time.Now())
+		if !retry || attempt == ghAttempts {
+			break
+		}
+		if wait > ghMaxWait {
+			klog.Warningf("GitHub rate limit resets in %s, not waiting for it; set GITHUB_TOKEN to get a higher limit", wait.Round(time.Second))
+			break
+		}
+		klog.Warningf("GitHub responded %s to %s, retrying in %s (attempt %d/%d)", resp.Status, req.URL, wait.Round(time.Second), attempt, ghAttempts)
+		resp.Body.Close()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		klog.Infof("using the cached GitHub response of %s", req.URL)
+		resp.Body.Close()
+		// keep the fresh rate limit headers
+		for k, v := range cached.Header {
+			if resp.Header.Get(k) == "" {
+				resp.Header[k] = v
+			}
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.store(req, &ghCachedResponse{ETag: resp.Header.Get("ETag"), Header: resp.Header, Body: body})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+// ghRetryAfter returns how long to wait before sending a request again after resp, and whether to send it again at all.
+// Rate limited requests wait for the limit to reset, or as long as GitHub asks for secondary limits,
+// while server errors back off exponentially.
+func ghRetryAfter(resp *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if secs, err := strconv.Atoi(s); err == nil {
+				return time.Duration(secs) * time.Second, true
+			}
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			// the reset is in whole seconds, so add one not to come back a little too early
+			return time.Unix(reset, 0).Sub(now) + time.Second, true
+		}
+		// a 403 for anything but a rate limit won't go away
+		return 0, false
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return time.Duration(1<<(attempt-1)) * time.Second, true
+	}
+	return 0, false
+}
+
+// cachePath returns the file the response to req is cached in
+func (t *ghTransport) cachePath(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(t.cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the cached response to req, or nil
+func (t *ghTransport) load(req *http.Request) *ghCachedResponse {
+	if t.cacheDir == "" {
+		return nil
+	}
+	b, err := os.ReadFile(t.cachePath(req))
+	if err != nil {
+		return nil
+	}
+	var cached ghCachedResponse
+	if err := json.Unmarshal(b, &cached); err != nil || cached.ETag == "" {
+		klog.Warningf("ignoring the corrupt cached GitHub response of %s: %v", req.URL, err)
+		return nil
+	}
+	return &cached
+}
+
+// store caches the response to req; failures only cost the next run a request
+func (t *ghTransport) store(req *http.Request, cached *ghCachedResponse) {
+	if t.cacheDir == "" {
+		return
+	}
+	if err := t.write(t.cachePath(req), cached); err != nil {
+		klog.Warningf("failed to cache the GitHub response of %s: %v", req.URL, err)
+	}
+}
+
+// write saves cached to path through a temporary file, so that concurrent updaters never read half of it
+func (t *ghTransport) write(path string, cached *ghCachedResponse) error {
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
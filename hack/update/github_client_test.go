@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// get sends a GET of url through transport and returns the status and body of the response
+func get(t *testing.T, transport http.RoundTripper, url string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestGHTransportCache(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, `{"tag_name":"v1.0.0"}`)
+	}))
+	defer srv.Close()
+	transport := &ghTransport{base: http.DefaultTransport, cacheDir: t.TempDir()}
+
+	// the first response is stored with its ETag
+	if code, body := get(t, transport, srv.URL+"/releases/latest"); code != http.StatusOK || body != `{"tag_name":"v1.0.0"}` {
+		t.Fatalf("first GET = %d %q", code, body)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/releases/latest", nil)
+	if cached := transport.load(req); cached == nil || cached.ETag != `"v1"` {
+		t.Fatalf("cached response = %+v, want one with ETag \"v1\"", cached)
+	}
+
+	// the second one is revalidated, and the 304 answered from the cache
+	if code, body := get(t, transport, srv.URL+"/releases/latest"); code != http.StatusOK || body != `{"tag_name":"v1.0.0"}` {
+		t.Errorf("cached GET = %d %q, want the cached body", code, body)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want 2", requests)
+	}
+}
+
+func TestGHTransportRetry(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			// a secondary rate limit, to retry right away
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+		case 2:
+			// an exhausted rate limit, which has just reset
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()-1, 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+	transport := &ghTransport{base: http.DefaultTransport}
+
+	if code, body := get(t, transport, srv.URL); code != http.StatusOK || body != "ok" {
+		t.Errorf("GET = %d %q, want the response to the retry", code, body)
+	}
+	if requests != 3 {
+		t.Errorf("sent %d requests, want 3", requests)
+	}
+}
+
+func TestGHRetryAfter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		attempt int
+		want    time.Duration
+		retry   bool
+	}{
+		{"retry after", http.StatusForbidden, map[string]string{"Retry-After": "30"}, 1, 30 * time.Second, true},
+		{"too many requests", http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}, 1, 5 * time.Second, true},
+		{"rate limit reset", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Unix()+60, 10)}, 1, 61 * time.Second, true},
+		{"rate limit without reset", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, 1, 0, false},
+		{"forbidden", http.StatusForbidden, nil, 1, 0, false},
+		{"server error", http.StatusBadGateway, nil, 3, 4 * time.Second, true},
+		{"not found", http.StatusNotFound, nil, 1, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+			for k, v := range tc.header {
+				resp.Header.Set(k, v)
+			}
+			wait, retry := ghRetryAfter(resp, tc.attempt, now)
+			if wait != tc.want || retry != tc.retry {
+				t.Errorf("ghRetryAfter() = %s, %v, want %s, %v", wait, retry, tc.want, tc.retry)
+			}
+		})
+	}
+}
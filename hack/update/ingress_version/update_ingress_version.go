@@ -84,7 +84,7 @@ func main() {
 }
 
 func LatestControllerTag(ctx context.Context) (string, error) {
-	ghc := github.NewClient(nil)
+	ghc := update.GHClient()
 
 	// walk through the paginated list of up to ghSearchLimit newest releases
 	opts := &github.ListOptions{PerPage: ghListPerPage}
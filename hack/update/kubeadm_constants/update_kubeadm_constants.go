@@ -61,7 +61,7 @@ func main() {
 
 	releases := []string{}
 
-	ghc := github.NewClient(nil)
+	ghc := update.GHClient()
 
 	opts := &github.ListOptions{PerPage: 100}
 	for {
@@ -0,0 +1,291 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	// mkAssignment matches the simple variable assignments of buildroot package makefiles, such as "FOO_VERSION = v1.0.0"
+	mkAssignment = regexp.MustCompile(`^([A-Z0-9_]+)\s*[:?]?=\s*(.*?)\s*$`)
+	// mkReference matches references to makefile variables, such as "$(FOO_VERSION)"
+	mkReference = regexp.MustCompile(`\$\(([A-Z0-9_]+)\)`)
+)
+
+// mkPackage is the part of a buildroot package makefile which says what to download
+type mkPackage struct {
+	// vars are the variables the makefile assigns
+	vars map[string]string
+	// prefix is the prefix of the variables of the package, such as "PORTO_BIN"
+	prefix string
+	// source is the unexpanded <PKG>_SOURCE
+	source string
+}
+
+// readMk reads the buildroot package makefile at path, where PKG is the name of the package directory
+func readMk(path string) (*mkPackage, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		if m := mkAssignment.FindStringSubmatch(line); m != nil {
+			vars[m[1]] = m[2]
+		}
+	}
+	pkg := strings.ToUpper(strings.ReplaceAll(filepath.Base(filepath.Dir(path)), "-", "_"))
+	if _, ok := vars[pkg+"_VERSION"]; !ok {
+		return nil, fmt.Errorf("%s does not set %s_VERSION", path, pkg)
+	}
+	src, ok := vars[pkg+"_SOURCE"]
+	if !ok {
+		// the default of buildroot
+		src = fmt.Sprintf("%s-$(%s_VERSION).tar.gz", filepath.Base(filepath.Dir(path)), pkg)
+	}
+	return &mkPackage{vars: vars, prefix: pkg, source: src}, nil
+}
+
+// MkSource returns where the buildroot package makefile at path downloads its source from: the <PKG>_SITE and <PKG>_SOURCE
+// variables with the variables they reference expanded, where PKG is the name of the package directory.
+func MkSource(path string) (site, source string, err error) {
+	mk, err := readMk(path)
+	if err != nil {
+		return "", "", err
+	}
+	if site, err = expandMk(mk.vars[mk.prefix+"_SITE"], mk.vars); err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	if source, err = expandMk(mk.source, mk.vars); err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	return site, source, nil
+}
+
+// mkSourcePattern returns the pattern of the files the buildroot package makefile at path downloads for any version,
+// which is <PKG>_SOURCE with <PKG>_VERSION left open
+func mkSourcePattern(path string) (*regexp.Regexp, error) {
+	mk, err := readMk(path)
+	if err != nil {
+		return nil, err
+	}
+	// a placeholder which survives expansion and quoting, to be opened up after both
+	const placeholder = "MKVERSIONPLACEHOLDER"
+	vars := map[string]string{}
+	for k, v := range mk.vars {
+		vars[k] = v
+	}
+	vars[mk.prefix+"_VERSION"] = placeholder
+	source, err := expandMk(mk.source, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !strings.Contains(source, placeholder) {
+		return nil, fmt.Errorf("%s: the source %q does not depend on %s_VERSION", path, source, mk.prefix)
+	}
+	return regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(source), placeholder, `[^/\s]+`) + "$")
+}
+
+// expandMk replaces the variable references in value with the values of vars
+func expandMk(value string, vars map[string]string) (string, error) {
+	// references nest only a few levels deep, deeper is a cycle
+	for i := 0; i < 10 && mkReference.MatchString(value); i++ {
+		var missing []string
+		value = mkReference.ReplaceAllStringFunc(value, func(ref string) string {
+			name := mkReference.FindStringSubmatch(ref)[1]
+			v, ok := vars[name]
+			if !ok {
+				missing = append(missing, name)
+				return ref
+			}
+			return v
+		})
+		if len(missing) > 0 {
+			return "", fmt.Errorf("undefined variables %s in %q", strings.Join(missing, ", "), value)
+		}
+	}
+	if mkReference.MatchString(value) {
+		return "", fmt.Errorf("unable to expand %q", value)
+	}
+	return value, nil
+}
+
+// mkHashPath returns the hash file of the buildroot package makefile at path, which is named after the makefile
+func mkHashPath(path string) string {
+	return strings.TrimSuffix(path, ".mk") + ".hash"
+}
+
+// UpdateMkHash adds the sha256 of the file the buildroot package makefile at path, relative to FSRoot, downloads
+// to the hash file next to it, unless the hash file already has an entry for that file. The file must hold item.ArchiveFiles.
+// Unless item.HashRetention is 0, it then prunes the hash file down to the entries of the newest versions, see pruneMkHash.
+func UpdateMkHash(ctx context.Context, path string, item Item) error {
+	if err := addMkHash(ctx, path, item); err != nil {
+		return err
+	}
+	if item.HashRetention == 0 {
+		return nil
+	}
+	return pruneMkHash(filepath.Join(FSRoot, path), item.HashRetention)
+}
+
+// addMkHash adds the sha256 of the file the buildroot package makefile at path, relative to FSRoot, downloads
+// to the hash file next to it, unless the hash file already has an entry for that file. The file must hold the ArchiveFiles
+// of item and pass the checks of its Provenance, see DownloadSHA256.
+func addMkHash(ctx context.Context, path string, item Item) error {
+	path = filepath.Join(FSRoot, path)
+	site, source, err := MkSource(path)
+	if err != nil {
+		return err
+	}
+	hashPath := mkHashPath(path)
+	b, err := os.ReadFile(hashPath)
+	if err != nil {
+		return fmt.Errorf("failed to read hash file: %w", err)
+	}
+	if hashEntry(string(b), source) != "" {
+		klog.Infof("%s already has the sha256 of %s", hashPath, source)
+		return nil
+	}
+	if site == "" {
+		return fmt.Errorf("%s does not set the site to download %s from", path, source)
+	}
+	sum, err := DownloadSHA256(ctx, item.ArchiveFiles, item.Provenance, ReleaseAssetURLs(strings.TrimSuffix(site, "/")+"/"+source)...)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	f, err := os.OpenFile(hashPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open hash file: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "sha256 %s  %s\n", sum, source); err != nil {
+		return fmt.Errorf("failed to write to hash file: %w", err)
+	}
+	return nil
+}
+
+// pruneMkHash removes all but the newest retention entries of the files the buildroot package makefile at path downloads
+// from the hash file next to it. Entries are added in the order of the versions, so the newest are the last ones.
+// The entry of the current version is kept wherever it is, such as after a downgrade, while entries of other files,
+// such as licenses or archives the package no longer downloads, are never removed.
+func pruneMkHash(path string, retention int) error {
+	if retention < 1 {
+		return fmt.Errorf("%s: hash retention must keep at least the current version, not %d", path, retention)
+	}
+	_, source, err := MkSource(path)
+	if err != nil {
+		return err
+	}
+	pattern, err := mkSourcePattern(path)
+	if err != nil {
+		return err
+	}
+	hashPath := mkHashPath(path)
+	b, err := os.ReadFile(hashPath)
+	if err != nil {
+		return fmt.Errorf("failed to read hash file: %w", err)
+	}
+	pruned, removed := pruneHashEntries(string(b), source, pattern, retention)
+	if len(removed) == 0 {
+		return nil
+	}
+	klog.Infof("pruning %s down to %d versions, removing %s", hashPath, retention, strings.Join(removed, ", "))
+	if err := os.WriteFile(hashPath, []byte(pruned), 0o644); err != nil {
+		return fmt.Errorf("failed to write hash file: %w", err)
+	}
+	return nil
+}
+
+// pruneHashEntries returns the buildroot hash file content with only the newest retention entries of the files matching
+// pattern, always including current, and the files whose entries it removed
+func pruneHashEntries(content, current string, pattern *regexp.Regexp, retention int) (string, []string) {
+	lines := strings.SplitAfter(content, "\n")
+	// walk from the newest entry, the current one taking its slot wherever it is
+	keep := map[int]bool{}
+	kept := 1
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) != 3 || fields[0] != "sha256" || !pattern.MatchString(fields[2]) {
+			keep[i] = true
+			continue
+		}
+		if fields[2] == current {
+			keep[i] = true
+			continue
+		}
+		if kept < retention {
+			keep[i] = true
+			kept++
+		}
+	}
+	var b strings.Builder
+	var removed []string
+	for i, line := range lines {
+		if keep[i] {
+			b.WriteString(line)
+			continue
+		}
+		removed = append(removed, strings.Fields(line)[2])
+	}
+	return b.String(), removed
+}
+
+// hashEntry returns the sha256 the buildroot hash file content has for file, or "" if it has none
+func hashEntry(content, file string) string {
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "sha256" && fields[2] == file {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// verifyMkHashes checks that each buildroot package makefile among paths, relative to fsRoot, has an entry
+// for the file it downloads in its hash file, as the ISO build fails on any file without one
+func verifyMkHashes(fsRoot string, paths []string) error {
+	var errs []error
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".mk") {
+			continue
+		}
+		p = filepath.Join(fsRoot, p)
+		_, source, err := MkSource(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		b, err := os.ReadFile(mkHashPath(p))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read hash file: %w", err))
+			continue
+		}
+		if sum := hashEntry(string(b), source); len(sum) != 64 {
+			errs = append(errs, fmt.Errorf("%s has no sha256 of %s, which %s downloads", mkHashPath(p), source, p))
+		}
+	}
+	return errors.Join(errs...)
+}
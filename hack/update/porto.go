@@ -0,0 +1,375 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// GoFasterArch is an architecture a go-faster component is released and packaged in the ISO for
+type GoFasterArch struct {
+	// GOARCH names the architecture in the release assets
+	GOARCH string
+	// Package is the buildroot package makefile installing the component on the architecture
+	Package string
+	// Prefix is the prefix of the variables of the makefile
+	Prefix string
+}
+
+// GoFasterComponent is a go-faster project the ISO installs from its per-distro release tarballs
+type GoFasterComponent struct {
+	// Name is the name of the GitHub repository, of the release assets and of the SBOM component
+	Name string
+	// Arches are the supported architectures, each of which must have a release asset
+	Arches []GoFasterArch
+	// KicbaseArg is the prefix of the ARGs of the kicbase Dockerfile installing the component in kicbase
+	KicbaseArg string
+	// HashRetention and ArchiveFiles are those of the Item of each package makefile
+	HashRetention int
+	ArchiveFiles  []string
+}
+
+var (
+	// Porto is portod, with portoctl and portoinit
+	Porto = GoFasterComponent{
+		Name: "porto",
+		Arches: []GoFasterArch{
+			{GOARCH: "amd64", Package: "deploy/iso/minikube-iso/arch/x86_64/package/porto-bin/porto-bin.mk", Prefix: "PORTO_BIN"},
+			{GOARCH: "arm64", Package: "deploy/iso/minikube-iso/arch/aarch64/package/porto-bin-aarch64/porto-bin.mk", Prefix: "PORTO_BIN_AARCH64"},
+		},
+		KicbaseArg:    "PORTO",
+		HashRetention: 5,
+		ArchiveFiles:  []string{"portod", "portoctl", "portoinit"},
+	}
+	// Portoshim is the CRI of porto, with logshim
+	Portoshim = GoFasterComponent{
+		Name: "portoshim",
+		Arches: []GoFasterArch{
+			{GOARCH: "amd64", Package: "deploy/iso/minikube-iso/arch/x86_64/package/portoshim-bin/portoshim-bin.mk", Prefix: "PORTOSHIM_BIN"},
+			{GOARCH: "arm64", Package: "deploy/iso/minikube-iso/arch/aarch64/package/portoshim-bin-aarch64/portoshim-bin.mk", Prefix: "PORTOSHIM_BIN_AARCH64"},
+		},
+		KicbaseArg:    "PORTOSHIM",
+		HashRetention: 3,
+		ArchiveFiles:  []string{"portoshim", "logshim"},
+	}
+)
+
+// PortoCompatibility is the matrix of the porto release lines each portoshim release line works with, which nodes check
+// in-place porto upgrades against as well
+var PortoCompatibility = cruntime.PortoCompatibility
+
+// PortoCompatible returns whether portoshim version portoshim works with porto version porto according to PortoCompatibility
+func PortoCompatible(porto, portoshim string) bool {
+	return cruntime.PortoCompatible(porto, portoshim)
+}
+
+// PortoTools are the release lines, major.minor, of the tools the ISO ships next to portoshim which it is validated with
+type PortoTools struct {
+	// Crictl are the lines of crictl, which talks to the CRI socket of portoshim
+	Crictl []string
+	// CNIPlugins are the lines of the CNI plugins, which portoshim sets up the network of pods with
+	CNIPlugins []string
+}
+
+// PortoshimTools are the PortoTools each portoshim release line is validated with. The tools stay where they are for
+// a portoshim line missing from it, and a new tool line has to be added here once it is validated with portoshim.
+var PortoshimTools = map[string]PortoTools{
+	"v1.0": {Crictl: []string{"v1.28"}, CNIPlugins: []string{"v1.4"}},
+}
+
+// PortoToolsFor returns the PortoTools the portoshim version of the ISO is validated with, failing unless the ISO
+// has a compatible pair of porto and portoshim to validate them against
+func PortoToolsFor() (PortoTools, error) {
+	porto, err := Porto.MkVersion()
+	if err != nil {
+		return PortoTools{}, fmt.Errorf("porto version of the ISO: %w", err)
+	}
+	portoshim, err := Portoshim.MkVersion()
+	if err != nil {
+		return PortoTools{}, fmt.Errorf("portoshim version of the ISO: %w", err)
+	}
+	if !PortoCompatible(porto, portoshim) {
+		return PortoTools{}, fmt.Errorf("porto %s and portoshim %s of the ISO do not work together, update them first with 'make update-porto-stack-version'", porto, portoshim)
+	}
+	tools, ok := PortoshimTools[semver.MajorMinor(portoshim)]
+	if !ok {
+		return PortoTools{}, fmt.Errorf("no crictl and CNI plugins are validated with portoshim %s, add its line to PortoshimTools", portoshim)
+	}
+	return tools, nil
+}
+
+// InLines returns whether version belongs to one of the release lines, major.minor, of lines
+func InLines(version string, lines []string) bool {
+	for _, l := range lines {
+		if semver.MajorMinor(version) == l {
+			return true
+		}
+	}
+	return false
+}
+
+// provenance returns the Provenance the release assets of c must have
+func (c GoFasterComponent) provenance() Provenance {
+	return Provenance{
+		Checksum:       true,
+		CosignIdentity: `^https://github\.com/go-faster/` + c.Name + `/`,
+	}
+}
+
+// releaseAssetURL returns the GitHub release download URL of the release asset of c for a distro, version and GOARCH
+func (c GoFasterComponent) releaseAssetURL(distro, version, arch string) string {
+	return fmt.Sprintf("%sgo-faster/%s/releases/download/%s/%s", ghReleaseURL, c.Name, version, c.asset(distro, version, arch))
+}
+
+// asset returns the name of the release asset of c for a distro, version and GOARCH
+func (c GoFasterComponent) asset(distro, version, arch string) string {
+	return fmt.Sprintf("%s_%s_%s_%s.tgz", c.Name, distro, version, arch)
+}
+
+// kicbaseDockerfile is the Dockerfile of kicbase, which installs the same go-faster releases as the ISO
+const kicbaseDockerfile = "deploy/kicbase/Dockerfile"
+
+// Schema returns the schema updating the package of every architecture of c and the kicbase ARGs of c, with the
+// version, commit and distro taken from the Data fields at dot of the template data, such as "" or ".Porto".
+// Both images are updated together, so that a node runs the same porto whichever driver it is started with.
+func (c GoFasterComponent) Schema(dot string) map[string]Item {
+	schema := map[string]Item{}
+	for _, a := range c.Arches {
+		schema[a.Package] = Item{
+			Replace: map[string]string{
+				a.Prefix + `_VERSION = .*`: a.Prefix + `_VERSION = {{` + dot + `.Version}}`,
+				a.Prefix + `_COMMIT = .*`:  a.Prefix + `_COMMIT = {{` + dot + `.Commit}}`,
+				a.Prefix + `_DISTRO = .*`:  a.Prefix + `_DISTRO = {{` + dot + `.Distro}}`,
+			},
+			HashRetention: c.HashRetention,
+			ArchiveFiles:  c.ArchiveFiles,
+			Provenance:    c.provenance(),
+		}
+	}
+	// the kicbase supports the same architectures, so the distro picked for the ISO has their assets as well
+	schema[kicbaseDockerfile] = Item{
+		Replace: map[string]string{
+			`ARG ` + c.KicbaseArg + `_VERSION=.*`: `ARG ` + c.KicbaseArg + `_VERSION="{{` + dot + `.Version}}"`,
+			`ARG ` + c.KicbaseArg + `_DISTRO=.*`:  `ARG ` + c.KicbaseArg + `_DISTRO="{{` + dot + `.Distro}}"`,
+		},
+	}
+	return schema
+}
+
+// MergeSchemas returns the schema updating the files of all of schemas, those of several components of one update.
+// The replacements of a file more than one of them updates, such as the kicbase Dockerfile, are all kept.
+func MergeSchemas(schemas ...map[string]Item) map[string]Item {
+	merged := map[string]Item{}
+	for _, schema := range schemas {
+		for path, item := range schema {
+			prev, ok := merged[path]
+			if !ok {
+				merged[path] = item
+				continue
+			}
+			replace := map[string]string{}
+			for src, dst := range prev.Replace {
+				replace[src] = dst
+			}
+			for src, dst := range item.Replace {
+				replace[src] = dst
+			}
+			prev.Replace = replace
+			merged[path] = prev
+		}
+	}
+	return merged
+}
+
+// MkVersion returns the version of c the ISO installs now, that of the package makefile of its first architecture
+func (c GoFasterComponent) MkVersion() (string, error) {
+	path := c.Arches[0].Package
+	mk, err := readMk(filepath.Join(FSRoot, path))
+	if err != nil {
+		return "", err
+	}
+	version, err := expandMk(mk.vars[mk.prefix+"_VERSION"], mk.vars)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return version, nil
+}
+
+// ReleaseDistro returns the distro of the release assets of version of c to update to, failing unless the release has
+// an asset for each of the Arches in it, so that no architecture is left behind on an older version
+func (c GoFasterComponent) ReleaseDistro(ctx context.Context, version string) (string, error) {
+	assets, err := ReleaseAssets(ctx, "go-faster", c.Name, version)
+	if err != nil {
+		return "", err
+	}
+	var arches []string
+	for _, a := range c.Arches {
+		arches = append(arches, a.GOARCH)
+	}
+	current, err := c.mkDistro()
+	if err != nil {
+		return "", err
+	}
+	asset := func(distro, arch string) string { return c.asset(distro, version, arch) }
+	distro, err := PickDistro(current, ReleaseDistros(assets, asset, arches))
+	if err != nil {
+		return "", fmt.Errorf("%s release %s: %w", c.Name, version, err)
+	}
+	return distro, nil
+}
+
+// mkDistro returns the distro of the release assets of c the ISO installs now, that of the first package makefile
+// which sets one, as the makefiles downloading from a mirror may not
+func (c GoFasterComponent) mkDistro() (string, error) {
+	for _, a := range c.Arches {
+		d, err := MkDistro(a.Package)
+		if err != nil {
+			return "", err
+		}
+		if d != "" {
+			return d, nil
+		}
+	}
+	return "", nil
+}
+
+// UpdateHashes adds the hashes of exactly the files the package makefiles of c download, wherever their site points to,
+// and updates their SBOM components. It is meant to run as the updateHashes of ApplyWithHashes with schema.
+func (c GoFasterComponent) UpdateHashes(ctx context.Context, schema map[string]Item) error {
+	for _, a := range c.Arches {
+		if err := UpdateMkHash(ctx, a.Package, schema[a.Package]); err != nil {
+			return fmt.Errorf("failed updating %s hash file of %s: %w", a.GOARCH, a.Package, err)
+		}
+		if err := c.UpdateSBOM(ctx, a); err != nil {
+			return fmt.Errorf("failed updating %s SBOM of %s: %w", a.GOARCH, a.Package, err)
+		}
+	}
+	return nil
+}
+
+// portoConfigProtoURL is the config.proto of a porto release, declaring the fields of the portod configuration
+const portoConfigProtoURL = "https://raw.githubusercontent.com/go-faster/porto/%s/src/config.proto"
+
+// PortoConfigFields returns the fields of each section of the portod configuration porto version knows,
+// from the config.proto of its tag
+func PortoConfigFields(ctx context.Context, version string) (map[string][]string, error) {
+	u := fmt.Sprintf(portoConfigProtoURL, version)
+	b, err := fetchSmall(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", u, err)
+	}
+	if b == nil {
+		return nil, fmt.Errorf("porto %s has no %s", version, u)
+	}
+	fields, err := ParseConfigProto(string(b), "TCfg")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", u, err)
+	}
+	return fields, nil
+}
+
+// protoToken is a token of a .proto file: a name, a string or a punctuation character
+var protoToken = regexp.MustCompile(`[A-Za-z_.][\w.]*|"[^"]*"|\S`)
+
+// protoComment is a comment of a .proto file
+var protoComment = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+
+// ParseConfigProto returns the fields of each message field of message root of proto, the sections of a configuration
+// in the protobuf text format, by section name. It knows enough of the proto2 syntax for the config.proto of porto.
+func ParseConfigProto(proto, root string) (map[string][]string, error) {
+	type field struct{ typ, name string }
+	messages := map[string][]field{}
+	// blocks is the stack of the open blocks, the message the fields in them belong to, or "" for other blocks
+	blocks := []string{}
+	statement := []string{}
+	for _, t := range protoToken.FindAllString(protoComment.ReplaceAllString(proto, ""), -1) {
+		switch t {
+		case "{":
+			message := ""
+			switch {
+			case len(statement) == 2 && statement[0] == "message":
+				message = statement[1]
+				if _, ok := messages[message]; !ok {
+					messages[message] = nil
+				}
+			case len(statement) == 2 && statement[0] == "oneof" && len(blocks) > 0:
+				message = blocks[len(blocks)-1]
+			}
+			blocks = append(blocks, message)
+			statement = nil
+		case "}":
+			if len(blocks) == 0 {
+				return nil, fmt.Errorf("unbalanced braces")
+			}
+			blocks = blocks[:len(blocks)-1]
+			statement = nil
+		case ";":
+			if len(blocks) > 0 && blocks[len(blocks)-1] != "" && len(statement) > 0 {
+				// [label] type name = number [options]
+				switch statement[0] {
+				case "option", "reserved", "extensions":
+				default:
+					for i, s := range statement {
+						if s == "=" && i >= 2 {
+							m := blocks[len(blocks)-1]
+							messages[m] = append(messages[m], field{typ: statement[i-2], name: statement[i-1]})
+							break
+						}
+					}
+				}
+			}
+			statement = nil
+		default:
+			statement = append(statement, t)
+		}
+	}
+	if len(blocks) != 0 {
+		return nil, fmt.Errorf("unbalanced braces")
+	}
+	if _, ok := messages[root]; !ok {
+		return nil, fmt.Errorf("no message %s", root)
+	}
+
+	sections := map[string][]string{}
+	for _, s := range messages[root] {
+		// the type may be qualified by the package or the enclosing message
+		typ := s.typ[strings.LastIndex(s.typ, ".")+1:]
+		fields, ok := messages[typ]
+		if !ok {
+			continue
+		}
+		names := []string{}
+		for _, f := range fields {
+			names = append(names, f.name)
+		}
+		sort.Strings(names)
+		sections[s.name] = names
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("message %s has no sections", root)
+	}
+	return sections, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/hack/update"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// keysFile is the file of cruntime.PortoConfigKeys
+const keysFile = "pkg/minikube/cruntime/porto_config_keys.go"
+
+// packagedConfigs are the portod configurations the ISO and kicbase install, which portod reads along with the ones
+// minikube writes, so they must not have fields the packaged porto does not know either
+var packagedConfigs = []string{
+	"deploy/iso/minikube-iso/arch/x86_64/package/porto-bin/k8s.conf",
+	"deploy/iso/minikube-iso/arch/aarch64/package/porto-bin-aarch64/k8s.conf",
+	"deploy/kicbase/porto/k8s.conf",
+}
+
+// Data holds the Go source of cruntime.PortoConfigKeys
+type Data struct {
+	Keys string
+}
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	version, err := update.Porto.MkVersion()
+	if err != nil {
+		klog.Fatalf("Unable to get the porto version of the ISO: %v", err)
+	}
+	line, err := cruntime.PortoConfigLine(version)
+	if err != nil {
+		klog.Fatalf("Unable to get the release line of porto %s: %v", version, err)
+	}
+	fields, err := update.PortoConfigFields(ctx, version)
+	if err != nil {
+		klog.Fatalf("Unable to get the configuration fields of porto %s: %v", version, err)
+	}
+
+	// the other lines are kept for the nodes still running them, until they are upgraded in place
+	keys := map[string]map[string][]string{}
+	for l, k := range cruntime.PortoConfigKeys {
+		keys[l] = k
+	}
+	keys[line] = fields
+
+	schema := map[string]update.Item{
+		keysFile: {
+			Replace: map[string]string{
+				`(?s)var PortoConfigKeys = .*`: `{{.Keys}}`,
+				// the notice of the table curated by hand before it was generated
+				`(?s)// This file is to be generated .*?\n\npackage`: "// Code generated by hack/update/porto_config_keys. DO NOT EDIT.\n\npackage",
+			},
+		},
+	}
+	for _, path := range packagedConfigs {
+		conf, dropped := cruntime.FilterPortoConfig(string(update.Loadf(filepath.Join(update.FSRoot, path))), fields)
+		if len(dropped) == 0 {
+			continue
+		}
+		klog.Warningf("porto %s does not know %s, removing them from %s", version, strings.Join(dropped, ", "), path)
+		schema[path] = update.Item{
+			Replace: map[string]string{
+				`(?s)\A.*\z`: strings.ReplaceAll(conf, "$", "$$"),
+			},
+		}
+	}
+	update.Apply(schema, Data{Keys: goSource(keys)})
+}
+
+// goSource returns the declaration of cruntime.PortoConfigKeys holding keys, formatted as gofmt would
+func goSource(keys map[string]map[string][]string) string {
+	var sb strings.Builder
+	sb.WriteString("var PortoConfigKeys = map[string]map[string][]string{\n")
+	for _, line := range sortedKeys(keys) {
+		fmt.Fprintf(&sb, "\t%q: {\n", line)
+		for _, section := range sortedKeys(keys[line]) {
+			fmt.Fprintf(&sb, "\t\t%q: {\n", section)
+			for _, k := range keys[line][section] {
+				fmt.Fprintf(&sb, "\t\t\t%q,\n", k)
+			}
+			sb.WriteString("\t\t},\n")
+		}
+		sb.WriteString("\t},\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
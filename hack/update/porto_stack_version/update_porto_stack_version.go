@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/hack/update"
+)
+
+// Release is the release of a component to update the ISO to
+type Release struct {
+	Version string
+	Commit  string
+	Distro  string
+}
+
+type Data struct {
+	Porto     Release
+	Portoshim Release
+}
+
+// releases looks up the distro of releases of a component, remembering the releases which lack assets
+type releases struct {
+	component update.GoFasterComponent
+	distros   map[string]string
+	missing   map[string]error
+}
+
+func newReleases(c update.GoFasterComponent) *releases {
+	return &releases{component: c, distros: map[string]string{}, missing: map[string]error{}}
+}
+
+// release returns the Release of tag, failing if it is not published with the assets of every architecture
+func (r *releases) release(ctx context.Context, tag update.Release) (Release, error) {
+	if err, ok := r.missing[tag.Tag]; ok {
+		return Release{}, err
+	}
+	distro, ok := r.distros[tag.Tag]
+	if !ok {
+		var err error
+		if distro, err = r.component.ReleaseDistro(ctx, tag.Tag); err != nil {
+			klog.Infof("skipping %s %s: %v", r.component.Name, tag.Tag, err)
+			r.missing[tag.Tag] = err
+			return Release{}, err
+		}
+		r.distros[tag.Tag] = distro
+	}
+	return Release{Version: tag.Tag, Commit: tag.Commit, Distro: distro}, nil
+}
+
+// channelTags returns the tags of the releases of the go-faster repository repo in channel, greatest first
+func channelTags(ctx context.Context, repo, channel string) ([]update.Release, error) {
+	tags, err := update.GHTags(ctx, "go-faster", repo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %s releases: %w", repo, err)
+	}
+	var in []update.Release
+	for _, rl := range tags {
+		if update.InChannel(rl.Tag, channel) {
+			in = append(in, rl)
+		}
+	}
+	return in, nil
+}
+
+// resolve returns the newest pair of porto and portoshim releases of the channel which update.PortoCompatibility says
+// work together, and which are both published with the assets of every architecture. The newest porto wins over the
+// newest portoshim; --version pins porto, leaving the portoshim to go with it to be resolved.
+func resolve(ctx context.Context) (Data, error) {
+	// default to the edge channel, since most of go-faster releases are `.alpha-vX`, recognized as edge
+	channel, err := update.Channel("edge")
+	if err != nil {
+		return Data{}, err
+	}
+	pinned, ok, err := update.PinnedRelease(ctx, "go-faster", update.Porto.Name)
+	if err != nil {
+		return Data{}, err
+	}
+	portoTags := []update.Release{pinned}
+	if !ok {
+		if portoTags, err = channelTags(ctx, update.Porto.Name, channel); err != nil {
+			return Data{}, err
+		}
+	}
+	portoshimTags, err := channelTags(ctx, update.Portoshim.Name, channel)
+	if err != nil {
+		return Data{}, err
+	}
+	porto, portoshim := newReleases(update.Porto), newReleases(update.Portoshim)
+	for _, pt := range portoTags {
+		for _, st := range portoshimTags {
+			if !update.PortoCompatible(pt.Tag, st.Tag) {
+				continue
+			}
+			p, err := porto.release(ctx, pt)
+			if err != nil {
+				break
+			}
+			s, err := portoshim.release(ctx, st)
+			if err != nil {
+				continue
+			}
+			return Data{Porto: p, Portoshim: s}, nil
+		}
+	}
+	return Data{}, fmt.Errorf("no published pair of %s porto and portoshim releases is compatible according to %v", channel, update.PortoCompatibility)
+}
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	data, err := resolve(ctx)
+	if err != nil {
+		klog.Fatalf("Unable to resolve porto and portoshim versions: %v", err)
+	}
+	klog.Infof("updating to porto %s and portoshim %s", data.Porto.Version, data.Portoshim.Version)
+
+	// one plan for both, so that either both makefiles and their hash files are updated or neither is
+	schema := update.MergeSchemas(update.Porto.Schema(".Porto"), update.Portoshim.Schema(".Portoshim"))
+	update.ApplyWithHashes(schema, data, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		if err := update.Porto.UpdateHashes(ctx, schema); err != nil {
+			return err
+		}
+		return update.Portoshim.UpdateHashes(ctx, schema)
+	})
+
+	if update.Verify() {
+		// building the ISO takes most of it
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
+		defer cancel()
+		if err := update.VerifyRuntime(ctx, "porto"); err != nil {
+			klog.Fatalf("The update to porto %s and portoshim %s failed verification: %v", data.Porto.Version, data.Portoshim.Version, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/hack/update"
+)
+
+var (
+	crictlItem = update.Item{
+		ArchiveFiles: []string{"crictl"},
+		Provenance:   update.Provenance{Checksum: true},
+	}
+	cniPluginsItem = update.Item{
+		ArchiveFiles: []string{"bridge", "host-local", "loopback", "portmap"},
+		Provenance:   update.Provenance{Checksum: true},
+	}
+
+	// packages are the buildroot package makefiles of crictl and of the CNI plugins, whose hashes are updated
+	packages = map[string]update.Item{
+		"deploy/iso/minikube-iso/arch/aarch64/package/crictl-bin-aarch64/crictl-bin.mk": withReplace(crictlItem, map[string]string{
+			`CRICTL_BIN_AARCH64_VERSION = .*`: `CRICTL_BIN_AARCH64_VERSION = {{.Crictl}}`,
+		}),
+		"deploy/iso/minikube-iso/arch/x86_64/package/crictl-bin/crictl-bin.mk": withReplace(crictlItem, map[string]string{
+			`CRICTL_BIN_VERSION = .*`: `CRICTL_BIN_VERSION = {{.Crictl}}`,
+		}),
+		"deploy/iso/minikube-iso/arch/aarch64/package/cni-plugins-aarch64/cni-plugins.mk": withReplace(cniPluginsItem, map[string]string{
+			`CNI_PLUGINS_AARCH64_VERSION = .*`: `CNI_PLUGINS_AARCH64_VERSION = {{.CNIPlugins}}`,
+		}),
+		"deploy/iso/minikube-iso/arch/x86_64/package/cni-plugins/cni-plugins.mk": withReplace(cniPluginsItem, map[string]string{
+			`CNI_PLUGINS_VERSION = .*`: `CNI_PLUGINS_VERSION = {{.CNIPlugins}}`,
+		}),
+	}
+
+	// pins are the other files pinning the versions, which must move along with the ISO
+	pins = map[string]update.Item{
+		"deploy/kicbase/Dockerfile": {
+			Replace: map[string]string{
+				`CNI_PLUGINS_VERSION=.*`: `CNI_PLUGINS_VERSION="{{.CNIPlugins}}"`,
+			},
+		},
+		".github/workflows/master.yml": {
+			Replace: map[string]string{
+				`CRICTL_VERSION=.*`: `CRICTL_VERSION="{{.Crictl}}"`,
+			},
+		},
+		".github/workflows/pr.yml": {
+			Replace: map[string]string{
+				`CRICTL_VERSION=.*`: `CRICTL_VERSION="{{.Crictl}}"`,
+			},
+		},
+		"hack/jenkins/linux_integration_tests_none.sh": {
+			Replace: map[string]string{
+				`CRICTL_VERSION=.*`: `CRICTL_VERSION="{{.Crictl}}"`,
+			},
+		},
+	}
+)
+
+// Data holds the versions of crictl and of the CNI plugins to update to
+type Data struct {
+	Crictl     string
+	CNIPlugins string
+}
+
+// withReplace returns item replacing replace
+func withReplace(item update.Item, replace map[string]string) update.Item {
+	item.Replace = replace
+	return item
+}
+
+// newestInLines returns the newest release of the GitHub repo owner/repo in channel which belongs to one of lines
+func newestInLines(ctx context.Context, owner, repo, channel string, lines []string) (string, error) {
+	tags, err := update.GHTags(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	for _, rl := range tags {
+		if update.InChannel(rl.Tag, channel) && update.InLines(rl.Tag, lines) {
+			return rl.Tag, nil
+		}
+	}
+	return "", fmt.Errorf("no %s release of %s/%s in the lines %v validated with portoshim", channel, owner, repo, lines)
+}
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tools, err := update.PortoToolsFor()
+	if err != nil {
+		klog.Fatalf("Unable to get the crictl and CNI plugins versions validated with porto: %v", err)
+	}
+	channel, err := update.Channel("stable")
+	if err != nil {
+		klog.Fatalf("Unable to get the channel to update to: %v", err)
+	}
+	crictl, err := newestInLines(ctx, "kubernetes-sigs", "cri-tools", channel, tools.Crictl)
+	if err != nil {
+		klog.Fatalf("Unable to get the crictl version to update to: %v", err)
+	}
+	cniPlugins, err := newestInLines(ctx, "containernetworking", "plugins", channel, tools.CNIPlugins)
+	if err != nil {
+		klog.Fatalf("Unable to get the CNI plugins version to update to: %v", err)
+	}
+	data := Data{Crictl: crictl, CNIPlugins: cniPlugins}
+	klog.Infof("updating to crictl %s and CNI plugins %s", data.Crictl, data.CNIPlugins)
+
+	// one plan for both, so that the ISO never ships a crictl or CNI plugins the porto pair was not validated with
+	schema := update.MergeSchemas(packages, pins)
+	update.ApplyWithHashes(schema, data, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		for path := range packages {
+			if err := update.UpdateMkHash(ctx, path, schema[path]); err != nil {
+				return fmt.Errorf("failed updating the hash file of %s: %w", path, err)
+			}
+		}
+		return nil
+	})
+
+	if update.Verify() {
+		// building the ISO takes most of it
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
+		defer cancel()
+		if err := update.VerifyRuntime(ctx, "porto"); err != nil {
+			klog.Fatalf("The update to crictl %s and CNI plugins %s failed verification: %v", data.Crictl, data.CNIPlugins, err)
+		}
+	}
+}
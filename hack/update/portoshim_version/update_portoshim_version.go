@@ -18,12 +18,6 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -31,69 +25,49 @@ import (
 	"k8s.io/minikube/hack/update"
 )
 
-var schema = map[string]update.Item{
-	"deploy/iso/minikube-iso/arch/x86_64/package/portoshim-bin/portoshim-bin.mk": {
-		Replace: map[string]string{
-			`PORTOSHIM_BIN_VERSION = .*`: `PORTOSHIM_BIN_VERSION = {{.Version}}`,
-			`PORTOSHIM_BIN_COMMIT = .*`:  `PORTOSHIM_BIN_COMMIT = {{.Commit}}`,
-		},
-	},
-}
-
 type Data struct {
 	Version string
 	Commit  string
+	Distro  string
 }
 
 func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
 
-	// Get edge version, since most of go-faster releases are `.alpha-vX`, recognized as edge.
-	_, _, edge, err := update.GHReleases(ctx, "go-faster", "portoshim")
+	// Default to the edge channel, since most of go-faster releases are `.alpha-vX`, recognized as edge.
+	rl, err := update.PickRelease(ctx, "go-faster", "portoshim", "edge")
 	if err != nil {
-		klog.Fatalf("Unable to get edge version: %v", err)
-	}
-
-	version := edge.Tag
-	data := Data{Version: version, Commit: edge.Commit}
-	update.Apply(schema, data)
-
-	if err := updateHashFile(version, "amd64", "x86_64/package/portoshim-bin"); err != nil {
-		klog.Fatalf("failed updating amd64 hash file: %v", err)
+		klog.Fatalf("Unable to get the version to update to: %v", err)
 	}
-}
 
-func updateHashFile(version, arch, packagePath string) error {
-	link := fmt.Sprintf("https://github.com/go-faster/portoshim/releases/download/%[1]s/portoshim_focal_%[1]s_%[2]s.tgz", version, arch)
-	r, err := http.Get(link)
+	version := rl.Tag
+	// porto stays where it is, so the ISO must keep a pair which works together
+	porto, err := update.Porto.MkVersion()
 	if err != nil {
-		return fmt.Errorf("failed to download binary: %v", err)
+		klog.Fatalf("Unable to get the porto version of the ISO: %v", err)
 	}
-	defer r.Body.Close()
-	if r.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download binary: %s", r.Status)
+	if !update.PortoCompatible(porto, version) {
+		klog.Fatalf("portoshim %s does not work with porto %s of the ISO, update both with 'make update-porto-stack-version'", version, porto)
 	}
-	h := sha256.New()
-	if _, err := io.Copy(h, r.Body); err != nil {
-		return fmt.Errorf("failed to copy response body: %v", err)
-	}
-	filePath := fmt.Sprintf("../../../deploy/iso/minikube-iso/arch/%s/portoshim-bin.hash", packagePath)
-	b, err := os.ReadFile(filePath)
+	distro, err := update.Portoshim.ReleaseDistro(ctx, version)
 	if err != nil {
-		return fmt.Errorf("failed to read hash file: %v", err)
-	}
-	if strings.Contains(string(b), version) {
-		klog.Infof("hash file already contains %q", version)
-		return nil
+		klog.Fatalf("Unable to update portoshim: %v", err)
 	}
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return fmt.Errorf("failed to open hash file: %v", err)
-	}
-	defer f.Close()
-	if _, err := f.WriteString(fmt.Sprintf("sha256 %x  portoshim_focal_%s_%s.tgz\n", h.Sum(nil), version, arch)); err != nil {
-		return fmt.Errorf("failed to write to hash file: %v", err)
+	data := Data{Version: version, Commit: rl.Commit, Distro: distro}
+	schema := update.Portoshim.Schema("")
+	update.ApplyWithHashes(schema, data, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		return update.Portoshim.UpdateHashes(ctx, schema)
+	})
+
+	if update.Verify() {
+		// building the ISO takes most of it
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Hour)
+		defer cancel()
+		if err := update.VerifyRuntime(ctx, "porto"); err != nil {
+			klog.Fatalf("The update to portoshim %s failed verification: %v", version, err)
+		}
 	}
-	return nil
 }
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/sbom"
+)
+
+// sbomPath is the inventory of the third party binaries in the ISO, relative to FSRoot, which "minikube version --sbom" reports
+const sbomPath = "pkg/minikube/sbom/components.json"
+
+// UpdateSBOM records the release asset of c the package makefile of a installs in the SBOM: its version, GitHub
+// release URL and sha256, together with the license GitHub detects in the repo. Run it after UpdateMkHash.
+func (c GoFasterComponent) UpdateSBOM(ctx context.Context, a GoFasterArch) error {
+	comp, err := c.sbomComponent(ctx, a)
+	if err != nil {
+		return err
+	}
+	return upsertSBOM(filepath.Join(FSRoot, sbomPath), comp)
+}
+
+// GenerateSBOM writes the SBOM afresh from the release assets of every architecture of components
+func GenerateSBOM(ctx context.Context, components ...GoFasterComponent) error {
+	var cs []sbom.Component
+	for _, c := range components {
+		for _, a := range c.Arches {
+			comp, err := c.sbomComponent(ctx, a)
+			if err != nil {
+				return err
+			}
+			cs = append(cs, comp)
+		}
+	}
+	return writeSBOM(filepath.Join(FSRoot, sbomPath), cs)
+}
+
+// sbomComponent returns the SBOM component of the release asset of c the package makefile of a installs. It is
+// described by its GitHub release, wherever the makefile downloads it from, as mirrors may serve it under another
+// name. Its sha256 is the one of the hash file when the makefile downloads the asset itself, else that of the asset.
+func (c GoFasterComponent) sbomComponent(ctx context.Context, a GoFasterArch) (sbom.Component, error) {
+	full := filepath.Join(FSRoot, a.Package)
+	mk, err := readMk(full)
+	if err != nil {
+		return sbom.Component{}, err
+	}
+	version, err := expandMk(mk.vars[mk.prefix+"_VERSION"], mk.vars)
+	if err != nil {
+		return sbom.Component{}, fmt.Errorf("%s: %w", a.Package, err)
+	}
+	distro, err := c.mkDistro()
+	if err != nil {
+		return sbom.Component{}, err
+	}
+	if distro == "" {
+		return sbom.Component{}, fmt.Errorf("no package makefile of %s sets the distro of its release assets", c.Name)
+	}
+	b, err := os.ReadFile(mkHashPath(full))
+	if err != nil {
+		return sbom.Component{}, fmt.Errorf("failed to read hash file: %w", err)
+	}
+
+	asset := c.asset(distro, version, a.GOARCH)
+	u := c.releaseAssetURL(distro, version, a.GOARCH)
+	sum := hashEntry(string(b), asset)
+	if sum == "" {
+		klog.Infof("%s has no sha256 of %s, downloading it", mkHashPath(a.Package), asset)
+		if sum, err = DownloadSHA256(ctx, c.ArchiveFiles, c.provenance(), u); err != nil {
+			return sbom.Component{}, fmt.Errorf("failed to download %s: %w", asset, err)
+		}
+	}
+	comp := sbom.Component{
+		Name:    c.Name,
+		Version: version,
+		Arch:    a.GOARCH,
+		Repo:    "https://github.com/go-faster/" + c.Name,
+		URL:     u,
+		SHA256:  sum,
+	}
+	comp.License, err = ghLicense(ctx, "go-faster", c.Name)
+	if err != nil {
+		return sbom.Component{}, fmt.Errorf("failed to get the license of go-faster/%s: %w", c.Name, err)
+	}
+	return comp, nil
+}
+
+// ghLicense returns the SPDX identifier of the license GitHub detects in the repo owner/repo,
+// or sbom.NoAssertion if it has none GitHub recognizes
+func ghLicense(ctx context.Context, owner, repo string) (string, error) {
+	l, resp, err := GHClient().Repositories.License(ctx, owner, repo)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		klog.Warningf("GitHub finds no license in %s/%s", owner, repo)
+		return sbom.NoAssertion, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if id := l.GetLicense().GetSPDXID(); id != "" {
+		return id, nil
+	}
+	return sbom.NoAssertion, nil
+}
+
+// upsertSBOM replaces the entry of the same component and arch as c in the SBOM at path, or adds c to it
+func upsertSBOM(path string, c sbom.Component) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read SBOM: %w", err)
+	}
+	cs, err := sbom.ParseComponents(b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	found := false
+	for i := range cs {
+		if cs[i].Name == c.Name && cs[i].Arch == c.Arch {
+			cs[i] = c
+			found = true
+		}
+	}
+	if !found {
+		cs = append(cs, c)
+	}
+	return writeSBOM(path, cs)
+}
+
+// writeSBOM writes the components cs to the SBOM at path, in order of name and arch
+func writeSBOM(path string, cs []sbom.Component) error {
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Name != cs[j].Name {
+			return cs[i].Name < cs[j].Name
+		}
+		return cs[i].Arch < cs[j].Arch
+	})
+	out, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ import (
 	"io"
 	"os/exec"
 	"regexp"
+	"strings"
+	"testing"
 	"text/template"
 	"time"
 
@@ -38,6 +40,26 @@ const (
 	FSRoot = "../../../"
 )
 
+// the flags every update tool takes, see PickRelease, DryRun and Verify
+var (
+	channelFlag = flag.String("channel", "", "release channel to update to: stable, latest or edge; each tool has a default")
+	versionFlag = flag.String("version", "", "version to update to, instead of the newest one of the channel")
+	commitFlag  = flag.String("commit", "", "commit of --version, looked up from its tag when not set")
+	dryRunFlag  = flag.Bool("dry-run", false, "print the planned replacements without changing any file")
+	verifyFlag  = flag.Bool("verify", false, "after updating, smoke test the new versions on a local cluster, for the tools which support it")
+	mirrorFlag  = flag.String("mirror", "", "base URL serving GitHub release assets under the same paths, tried before the mirrors of "+releaseMirrorsEnv+" and GitHub")
+)
+
+// Verify returns whether --verify asks to smoke test the update on a cluster before it is merged, which a dry run never does
+func Verify() bool {
+	return *verifyFlag && !DryRun()
+}
+
+// DryRun returns whether --dry-run asks to print the plan rather than apply it
+func DryRun() bool {
+	return *dryRunFlag
+}
+
 // init klog and check general requirements
 func init() {
 	klog.InitFlags(nil)
@@ -50,7 +72,10 @@ func init() {
 
 	// used in update_kubeadm_constants.go
 	flag.String("kubernetes-version", "latest", "kubernetes-version")
-	flag.Parse()
+	// test binaries define their flags after init, and parse them themselves
+	if !testing.Testing() {
+		flag.Parse()
+	}
 	defer klog.Flush()
 }
 
@@ -58,9 +83,26 @@ func init() {
 // corresponding to GitHub TreeEntry.Path and/or local filesystem repo file path (prefixed with FSRoot),
 // would be swapped with its respective actual map value (having placeholders replaced with data), creating a concrete update plan.
 // Replace map keys can use RegExp and map values can use Golang Text Template.
+// HashRetention is how many of the newest versions UpdateMkHash keeps in the hash file of a buildroot package makefile,
+// so that older ISOs can still be built when bisecting; 0 keeps all of them.
+// ArchiveFiles are the base names of files, such as binaries, the release tarball the makefile downloads must hold
+// for UpdateMkHash to write its sha256.
+// Provenance is how UpdateMkHash verifies the release tarball before writing its sha256.
 type Item struct {
-	Content []byte
-	Replace map[string]string
+	Content       []byte
+	Replace       map[string]string
+	HashRetention int
+	ArchiveFiles  []string
+	Provenance    Provenance
+}
+
+// Provenance is how the release assets an updater downloads are verified, before their sha256 is written into the tree
+type Provenance struct {
+	// Checksum requires a checksum to be published with the asset, rather than only comparing against one if there is
+	Checksum bool
+	// CosignIdentity is the regular expression the identity of the GitHub Actions workflow signing the asset must match.
+	// When set, a cosign bundle must be published with the asset.
+	CosignIdentity string
 }
 
 // apply updates Item Content by replacing all occurrences of Replace map's keys with their actual map values (with placeholders replaced with data).
@@ -89,6 +131,10 @@ func Apply(schema map[string]Item, data interface{}) {
 		klog.Fatalf("Unable to parse schema: %v\n%s", err, pretty)
 	}
 	klog.Infof("The Plan:\n%s", pretty)
+	if DryRun() {
+		printPlan(pretty)
+		return
+	}
 
 	changed, err := fsUpdate(FSRoot, schema, data)
 	if err != nil {
@@ -100,6 +146,61 @@ func Apply(schema map[string]Item, data interface{}) {
 	}
 }
 
+// ApplyWithHashes applies concrete update plan (schema + data) to local filesystem repo like Apply, then runs updateHashes
+// to add the checksums of the new versions and checks that the hash file of every buildroot package makefile in the plan
+// has one for the file the makefile downloads. If any step fails, all files of the plan and their hash files are restored
+// and the update fails, so that a makefile and its hash file never go out of sync and break the ISO build later.
+func ApplyWithHashes(schema map[string]Item, data interface{}, updateHashes func() error) {
+	schema, pretty, err := GetPlan(schema, data)
+	if err != nil {
+		klog.Fatalf("Unable to parse schema: %v\n%s", err, pretty)
+	}
+	klog.Infof("The Plan:\n%s", pretty)
+	if DryRun() {
+		// the hashes are those of the files of the new versions, so they are neither downloaded nor verified
+		printPlan(pretty)
+		return
+	}
+
+	// the SBOM is derived from the makefiles and their hash files
+	paths := []string{sbomPath}
+	for path := range schema {
+		paths = append(paths, path)
+		if strings.HasSuffix(path, ".mk") {
+			paths = append(paths, mkHashPath(path))
+		}
+	}
+	backup, err := snapshot(FSRoot, paths)
+	if err != nil {
+		klog.Fatalf("Unable to back up local repo files: %v", err)
+	}
+
+	changed, err := fsUpdate(FSRoot, schema, data)
+	if err == nil {
+		err = updateHashes()
+	}
+	if err == nil {
+		err = verifyMkHashes(FSRoot, paths)
+	}
+	if err != nil {
+		if rerr := restore(backup); rerr != nil {
+			klog.Errorf("Unable to revert local repo files, check them with 'git status': %v", rerr)
+		}
+		klog.Fatalf("Local repo update failed and was reverted: %v", err)
+	}
+	if !changed {
+		klog.Infof("Local repo update skipped: nothing changed")
+	} else {
+		klog.Infof("Local repo successfully updated")
+	}
+}
+
+// printPlan prints the plan of a dry run to stdout, for it to be read or piped rather than logged
+func printPlan(pretty string) {
+	fmt.Println(pretty)
+	klog.Infof("Dry run: local repo left untouched")
+}
+
 // GetPlan returns concrete plan replacing placeholders in schema with actual data values, returns JSON-formatted representation of the plan and any error occurred.
 func GetPlan(schema map[string]Item, data interface{}) (plan map[string]Item, prettyprint string, err error) {
 	plan = make(map[string]Item)
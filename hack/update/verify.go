@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// verifyProfile is the minikube profile of the cluster VerifyRuntime starts
+const verifyProfile = "update-verify"
+
+// verifyStep is a command VerifyRuntime runs from FSRoot
+type verifyStep struct {
+	name string
+	args []string
+}
+
+// minikubeStep returns the step running the minikube built by VerifyRuntime against verifyProfile
+func minikubeStep(name, minikube string, args ...string) verifyStep {
+	return verifyStep{name: name, args: append([]string{minikube, "-p", verifyProfile}, args...)}
+}
+
+// run runs the step, streaming its output to the log of the update tool
+func (s verifyStep) run(ctx context.Context) error {
+	klog.Infof("verify: %s", s.name)
+	root, err := filepath.Abs(FSRoot)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, s.args[0], s.args[1:]...)
+	cmd.Dir = root
+	// the Makefile puts its output under $(PWD), which the environment rather than Dir sets
+	cmd.Env = append(os.Environ(), "PWD="+root)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	return nil
+}
+
+// isoArch returns the architecture the ISO targets of the Makefile name the GOARCH of the host by
+func isoArch() string {
+	if runtime.GOARCH == "arm64" {
+		return "aarch64"
+	}
+	return "x86_64"
+}
+
+// verifyDriver returns the VM driver to start the cluster of VerifyRuntime with: kvm2 where there is KVM, qemu2 elsewhere
+func verifyDriver() string {
+	if runtime.GOOS == "linux" {
+		if _, err := os.Stat("/dev/kvm"); err == nil {
+			return "kvm2"
+		}
+	}
+	return "qemu2"
+}
+
+// VerifyRuntime builds the ISO, which installs the versions of the package makefiles the update changed, and minikube,
+// starts a cluster of the container runtime cr on them with a VM driver and runs a short smoke suite against it:
+// a pod is started, exec'd into and logged, and the images are listed. Broken upstream releases are caught this way
+// before the update is merged. The cluster is deleted afterwards, whether or not the suite passes.
+func VerifyRuntime(ctx context.Context, cr string) error {
+	minikube := fmt.Sprintf("out/minikube-%s-%s", runtime.GOOS, runtime.GOARCH)
+	iso, err := filepath.Abs(filepath.Join(FSRoot, "out", fmt.Sprintf("minikube-%s.iso", runtime.GOARCH)))
+	if err != nil {
+		return err
+	}
+	build := []verifyStep{
+		{name: "build the ISO", args: []string{"make", fmt.Sprintf("out/minikube-%s.iso", isoArch())}},
+		{name: "build minikube", args: []string{"make", minikube}},
+	}
+	for _, s := range build {
+		if err := s.run(ctx); err != nil {
+			return err
+		}
+	}
+
+	defer func() {
+		// a context of its own, so that the cluster is deleted after the smoke suite timed out as well
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := minikubeStep("delete the cluster", minikube, "delete").run(ctx); err != nil {
+			klog.Errorf("Unable to delete the %s cluster, delete it with 'minikube delete -p %s': %v", verifyProfile, verifyProfile, err)
+		}
+	}()
+	suite := []verifyStep{
+		minikubeStep("start the cluster", minikube, "start", "--driver="+verifyDriver(), "--container-runtime="+cr, "--iso-url=file://"+filepath.ToSlash(iso), "--wait=all"),
+		minikubeStep("check the CRI", minikube, "ssh", "--", "sudo", "crictl", "version"),
+		minikubeStep("start a pod", minikube, "kubectl", "--", "run", "smoke", "--image=busybox", "--restart=Never", "--", "sleep", "3600"),
+		minikubeStep("wait for the pod", minikube, "kubectl", "--", "wait", "--for=condition=Ready", "pod/smoke", "--timeout=3m"),
+		minikubeStep("exec into the pod", minikube, "kubectl", "--", "exec", "smoke", "--", "echo", "smoke"),
+		minikubeStep("read the logs of the pod", minikube, "kubectl", "--", "logs", "smoke"),
+		minikubeStep("list the images", minikube, "image", "ls"),
+	}
+	for _, s := range suite {
+		if err := s.run(ctx); err != nil {
+			return fmt.Errorf("%s smoke test failed: %w", cr, err)
+		}
+	}
+	klog.Infof("verify: %s passed the smoke tests", cr)
+	return nil
+}
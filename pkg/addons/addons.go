@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -305,10 +306,58 @@ func EnableOrDisableAddon(cc *config.ClusterConfig, name string, val string) err
 		out.WarningT("At least needs control plane nodes to enable addon")
 	}
 
+	if enable && cc.KubernetesConfig.ContainerRuntime == constants.Porto {
+		prefetchAddonImages(api, cc, addon, assets.AddonImageRefs(addon, cc, images, customRegistries))
+	}
+
 	data := assets.GenerateTemplateData(addon, cc, networkInfo, images, customRegistries, enable)
 	return enableOrDisableAddonInternal(cc, addon, runner, data, enable)
 }
 
+// prefetchAddonImages pulls the images of an addon on every running node before it is deployed.
+// porto only pulls images once pods ask for them, which leaves the pods of heavy addons Pending for long.
+// Failures are not fatal: the pods pull whatever is still missing themselves.
+func prefetchAddonImages(api libmachine.API, cc *config.ClusterConfig, addon *assets.Addon, imgs []string) {
+	if len(imgs) == 0 {
+		return
+	}
+	out.Step(style.Pulling, "Prefetching {{.count}} images for {{.addon}} ...", out.V{"count": len(imgs), "addon": addon.Name()})
+	for _, n := range cc.Nodes {
+		mName := config.MachineName(*cc, n)
+		host, err := machine.LoadHost(api, mName)
+		if err != nil || !machine.IsRunning(api, mName) {
+			klog.Warningf("%q is not running, not prefetching images for %s (err=%v)", mName, addon.Name(), err)
+			continue
+		}
+		runner, err := machine.CommandRunner(host)
+		if err != nil {
+			klog.Warningf("command runner for %q: %v", mName, err)
+			continue
+		}
+		cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: runner, Socket: cc.KubernetesConfig.CRISocket, ImageRepository: cc.KubernetesConfig.ImageRepository})
+		if err != nil {
+			klog.Warningf("runtime of %q: %v", mName, err)
+			continue
+		}
+		r, ok := cr.(interface {
+			PrefetchImages(imgs []string, concurrency int, progress cruntime.ImagePullProgress) error
+		})
+		if !ok {
+			continue
+		}
+		progress := func(done, total int, image string, err error) {
+			if err != nil {
+				out.Styled(style.Option, "[{{.done}}/{{.total}}] failed to pull {{.image}}", out.V{"done": done, "total": total, "image": image})
+				return
+			}
+			out.Styled(style.Option, "[{{.done}}/{{.total}}] pulled {{.image}}", out.V{"done": done, "total": total, "image": image})
+		}
+		if err := r.PrefetchImages(imgs, cc.PreloadConcurrency, progress); err != nil {
+			klog.Warningf("prefetching images for %s on %q: %v", addon.Name(), mName, err)
+		}
+	}
+}
+
 func addonSpecificChecks(cc *config.ClusterConfig, name string, enable bool, runner command.Runner) (bool, error) {
 	// to match both ingress and ingress-dns addons
 	if strings.HasPrefix(name, "ingress") && enable {
@@ -319,6 +368,12 @@ func addonSpecificChecks(cc *config.ClusterConfig, name string, enable bool, run
 		}
 	}
 
+	if enable {
+		if err := applyNodeSysctls(runner, name); err != nil {
+			klog.Warningf("failed to prepare node for %s addon: %v", name, err)
+		}
+	}
+
 	if strings.HasPrefix(name, "istio") && enable {
 		minMem := 8192
 		minCPUs := 4
@@ -344,9 +399,9 @@ func addonSpecificChecks(cc *config.ClusterConfig, name string, enable bool, run
 		return false, nil
 	}
 
-	if name == "auto-pause" && !enable { // needs to be disabled before deleting the service file in the internal disable
-		if err := sysinit.New(runner).DisableNow("auto-pause"); err != nil {
-			klog.ErrorS(err, "failed to disable", "service", "auto-pause")
+	if (name == "auto-pause" || name == "runtime-health" || name == "porto-metrics" || name == "porto-docker-shim") && !enable { // needs to be disabled before deleting the service file in the internal disable
+		if err := sysinit.New(runner).DisableNow(name); err != nil {
+			klog.ErrorS(err, "failed to disable", "service", name)
 		}
 		return false, nil
 	}
@@ -443,6 +498,11 @@ func enableOrDisableAddonInternal(cc *config.ClusterConfig, addon *assets.Addon,
 		}
 	}
 
+	// addons made of systemd units only have nothing to apply
+	if len(deployFiles) == 0 {
+		return nil
+	}
+
 	// on the first attempt try without force, but on subsequent attempts use force
 	force := false
 
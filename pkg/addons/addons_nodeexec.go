@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// nodeSysctl is a kernel parameter an addon needs raised on the node
+type nodeSysctl struct {
+	Key   string
+	Value int64
+}
+
+// addonNodeSysctls lists the kernel parameters that have to be raised on the node before an addon is enabled,
+// whatever the driver and the container runtime of the node
+var addonNodeSysctls = map[string][]nodeSysctl{
+	// ingress-nginx watches every config map and secret, which exhausts the default inotify limits
+	"ingress": {
+		{Key: "fs.inotify.max_user_watches", Value: 524288},
+		{Key: "fs.inotify.max_user_instances", Value: 512},
+	},
+}
+
+// NodeExec runs a privileged command on the node over the command runner.
+// It does not make any assumption about the driver or the container runtime of the node.
+func NodeExec(runner command.Runner, args ...string) (*command.RunResult, error) {
+	rr, err := runner.RunCmd(exec.Command("sudo", args...))
+	if err != nil {
+		return rr, errors.Wrapf(err, "node exec %q", strings.Join(args, " "))
+	}
+	return rr, nil
+}
+
+// raiseNodeSysctl sets the kernel parameter to value, unless the node already has a higher one
+func raiseNodeSysctl(runner command.Runner, s nodeSysctl) error {
+	rr, err := NodeExec(runner, "sysctl", "-n", s.Key)
+	if err != nil {
+		return err
+	}
+	current, err := strconv.ParseInt(strings.TrimSpace(rr.Stdout.String()), 10, 64)
+	if err == nil && current >= s.Value {
+		klog.Infof("%s is already %d, not lowering it to %d", s.Key, current, s.Value)
+		return nil
+	}
+	_, err = NodeExec(runner, "sysctl", "-w", s.Key+"="+strconv.FormatInt(s.Value, 10))
+	return err
+}
+
+// applyNodeSysctls raises the kernel parameters the addon requires on the node
+func applyNodeSysctls(runner command.Runner, name string) error {
+	for _, s := range addonNodeSysctls[name] {
+		if err := raiseNodeSysctl(runner, s); err != nil {
+			return errors.Wrapf(err, "raising %s for %s addon", s.Key, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// enableOrDisablePortoDockerShim starts the Docker Engine API shim after its unit file was copied by generic enable.
+// On disable, addonSpecificChecks has already stopped it before the unit file is removed.
+func enableOrDisablePortoDockerShim(cc *config.ClusterConfig, name, val string) error {
+	enable, err := strconv.ParseBool(val)
+	if err != nil {
+		return errors.Wrapf(err, "parsing bool: %s", name)
+	}
+	if !enable {
+		return nil
+	}
+
+	co := mustload.Running(cc.Name)
+	if err := sysinit.New(co.CP.Runner).EnableNow("porto-docker-shim"); err != nil {
+		klog.ErrorS(err, "failed to enable", "service", "porto-docker-shim")
+		return err
+	}
+	out.Styled(style.Tip, "Point your docker client at the cluster with 'eval $(minikube -p {{.profile}} docker-env)'", out.V{"profile": cc.Name})
+	return nil
+}
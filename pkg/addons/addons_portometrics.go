@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/deploy/addons"
+	"k8s.io/minikube/pkg/kapi"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// serviceMonitorCRD is the CRD of the Prometheus Operator the porto-metrics ServiceMonitor needs
+const serviceMonitorCRD = "servicemonitors.monitoring.coreos.com"
+
+// portoMetricsServiceMonitor is applied apart from the addon assets, as only clusters running the Prometheus Operator know its kind
+var portoMetricsServiceMonitor = assets.MustBinAsset(addons.PortoMetricsAssets, "porto-metrics/porto-metrics-servicemonitor.yaml", vmpath.GuestAddonsDir, "porto-metrics-servicemonitor.yaml", "0640")
+
+// enableOrDisablePortoMetrics starts the porto-metrics exporter after its unit file was copied by generic enable,
+// and manages its ServiceMonitor when the Prometheus Operator is installed.
+// On disable, addonSpecificChecks has already stopped the exporter before the unit file is removed.
+func enableOrDisablePortoMetrics(cc *config.ClusterConfig, name, val string) error {
+	enable, err := strconv.ParseBool(val)
+	if err != nil {
+		return errors.Wrapf(err, "parsing bool: %s", name)
+	}
+
+	co := mustload.Running(cc.Name)
+	if !enable {
+		if err := deletePortoMetricsServiceMonitor(cc, co.CP.Runner); err != nil {
+			klog.Warningf("failed to delete the porto-metrics ServiceMonitor: %v", err)
+		}
+		return nil
+	}
+
+	if err := sysinit.New(co.CP.Runner).EnableNow("porto-metrics"); err != nil {
+		klog.ErrorS(err, "failed to enable", "service", "porto-metrics")
+		return err
+	}
+	if !hasServiceMonitorCRD(cc, co.CP.Runner) {
+		out.Styled(style.Tip, "The Prometheus Operator is not installed, so no ServiceMonitor was created; scrape the porto-metrics service in kube-system, annotated with prometheus.io/scrape, instead")
+		return nil
+	}
+	if err := co.CP.Runner.Copy(portoMetricsServiceMonitor); err != nil {
+		return errors.Wrap(err, "copy ServiceMonitor")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if _, err := co.CP.Runner.RunCmd(kubectlCommand(ctx, cc, []string{portoMetricsServiceMonitorPath()}, true, false)); err != nil {
+		return errors.Wrap(err, "apply ServiceMonitor")
+	}
+	out.Styled(style.Tip, "The porto metrics are scraped through the porto-metrics ServiceMonitor in kube-system")
+	return nil
+}
+
+// hasServiceMonitorCRD returns whether the cluster knows the ServiceMonitor kind of the Prometheus Operator
+func hasServiceMonitorCRD(cc *config.ClusterConfig, runner command.Runner) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	_, err := runner.RunCmd(exec.CommandContext(ctx, "sudo", fmt.Sprintf("KUBECONFIG=%s", path.Join(vmpath.GuestPersistentDir, "kubeconfig")),
+		kapi.KubectlBinaryPath(cc.KubernetesConfig.KubernetesVersion), "get", "crd", serviceMonitorCRD))
+	return err == nil
+}
+
+// deletePortoMetricsServiceMonitor removes the ServiceMonitor and its manifest, if enable created them
+func deletePortoMetricsServiceMonitor(cc *config.ClusterConfig, runner command.Runner) error {
+	if !hasServiceMonitorCRD(cc, runner) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if _, err := runner.RunCmd(exec.CommandContext(ctx, "sudo", fmt.Sprintf("KUBECONFIG=%s", path.Join(vmpath.GuestPersistentDir, "kubeconfig")),
+		kapi.KubectlBinaryPath(cc.KubernetesConfig.KubernetesVersion), "delete", "--ignore-not-found", "-n", "kube-system", "servicemonitor", "porto-metrics")); err != nil {
+		return err
+	}
+	return runner.Remove(portoMetricsServiceMonitor)
+}
+
+// portoMetricsServiceMonitorPath is where the ServiceMonitor manifest is copied to on the node
+func portoMetricsServiceMonitorPath() string {
+	return path.Join(portoMetricsServiceMonitor.GetTargetDir(), portoMetricsServiceMonitor.GetTargetName())
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/mustload"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// enableOrDisableRuntimeHealth starts the runtime-health exporter after its unit file was copied by generic enable.
+// On disable, addonSpecificChecks has already stopped it before the unit file is removed.
+func enableOrDisableRuntimeHealth(cc *config.ClusterConfig, name, val string) error {
+	enable, err := strconv.ParseBool(val)
+	if err != nil {
+		return errors.Wrapf(err, "parsing bool: %s", name)
+	}
+	if !enable {
+		return nil
+	}
+
+	co := mustload.Running(cc.Name)
+	if err := sysinit.New(co.CP.Runner).EnableNow("runtime-health"); err != nil {
+		klog.ErrorS(err, "failed to enable", "service", "runtime-health")
+		return err
+	}
+	out.Styled(style.Tip, "The runtime health metrics are served by the runtime-health service, run 'minikube service runtime-health -n kube-system --url' to get their address")
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/tests"
@@ -160,3 +161,22 @@ func TestStartWithAllAddonsDisabled(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyNodeSysctls(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo sysctl -n fs.inotify.max_user_watches":        "8192\n",
+		"sudo sysctl -w fs.inotify.max_user_watches=524288": "fs.inotify.max_user_watches = 524288\n",
+		// already above the required value, so it must not be lowered
+		"sudo sysctl -n fs.inotify.max_user_instances": "1024\n",
+	})
+
+	if err := applyNodeSysctls(runner, "ingress"); err != nil {
+		t.Fatalf("applyNodeSysctls(ingress): %v", err)
+	}
+
+	// addons without node requirements must not run anything
+	if err := applyNodeSysctls(command.NewFakeCommandRunner(), "dashboard"); err != nil {
+		t.Fatalf("applyNodeSysctls(dashboard): %v", err)
+	}
+}
@@ -237,4 +237,21 @@ var Addons = []*Addon{
 		set:       SetBool,
 		callbacks: []setFn{EnableOrDisableAddon},
 	},
+	{
+		name:      "runtime-health",
+		set:       SetBool,
+		callbacks: []setFn{EnableOrDisableAddon, enableOrDisableRuntimeHealth},
+	},
+	{
+		name:        "porto-metrics",
+		set:         SetBool,
+		validations: []setFn{IsRuntimePorto},
+		callbacks:   []setFn{EnableOrDisableAddon, enableOrDisablePortoMetrics},
+	},
+	{
+		name:        "porto-docker-shim",
+		set:         SetBool,
+		validations: []setFn{IsRuntimePorto},
+		callbacks:   []setFn{EnableOrDisableAddon, enableOrDisablePortoDockerShim},
+	},
 }
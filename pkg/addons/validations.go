@@ -41,6 +41,16 @@ and then start minikube again with the following flags:
 
 minikube start --container-runtime=containerd --docker-opt containerd=/var/run/containerd/containerd.sock`
 
+// portoOnlyAddonMsg is the message shown when a porto-only addon is enabled
+const portoOnlyAddonMsg = `
+This addon can only be enabled with the porto runtime backend. To enable this backend, please first stop minikube with:
+
+minikube stop
+
+and then start minikube again with the following flags:
+
+minikube start --container-runtime=porto`
+
 // volumesnapshotsDisabledMsg is the message shown when csi-hostpath-driver addon is enabled without the volumesnapshots addon
 const volumesnapshotsDisabledMsg = `[WARNING] For full functionality, the 'csi-hostpath-driver' addon requires the 'volumesnapshots' addon to be enabled.
 
@@ -60,6 +70,19 @@ func IsRuntimeContainerd(cc *config.ClusterConfig, _, _ string) error {
 	return nil
 }
 
+// IsRuntimePorto is a validator which returns an error if the current runtime is not porto
+func IsRuntimePorto(cc *config.ClusterConfig, _, _ string) error {
+	r, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime})
+	if err != nil {
+		return err
+	}
+	_, ok := r.(*cruntime.Porto)
+	if !ok {
+		return fmt.Errorf(portoOnlyAddonMsg)
+	}
+	return nil
+}
+
 // IsVolumesnapshotsEnabled is a validator that prints out a warning if the volumesnapshots addon
 // is disabled (does not return any errors!)
 func IsVolumesnapshotsEnabled(cc *config.ClusterConfig, _, value string) error {
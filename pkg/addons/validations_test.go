@@ -16,7 +16,11 @@ limitations under the License.
 
 package addons
 
-import "testing"
+import (
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/config"
+)
 
 func TestIsAddonValid(t *testing.T) {
 	tests := []struct {
@@ -76,3 +80,14 @@ func TestContains(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRuntimePorto(t *testing.T) {
+	for runtime, wantErr := range map[string]bool{"porto": false, "containerd": true, "docker": true} {
+		t.Run(runtime, func(t *testing.T) {
+			cc := &config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ContainerRuntime: runtime}}
+			if err := IsRuntimePorto(cc, "porto-metrics", "true"); (err != nil) != wantErr {
+				t.Errorf("IsRuntimePorto(%q) error = %v, wantErr %v", runtime, err, wantErr)
+			}
+		})
+	}
+}
@@ -190,6 +190,10 @@ func (d *Driver) Create() error {
 		if !download.PreloadExists(d.NodeConfig.KubernetesVersion, d.NodeConfig.ContainerRuntime, d.DriverName()) {
 			return
 		}
+		// The volume is mounted at /var, tarballs rooted elsewhere are extracted by the container runtime
+		if download.TarballRoot(d.NodeConfig.ContainerRuntime) != "/var" {
+			return
+		}
 		t := time.Now()
 		klog.Infof("Starting extracting preloaded images to volume ...")
 		// Extract preloaded images to container
@@ -461,7 +465,7 @@ func (d *Driver) Stop() error {
 		// even though we can't stop the cotainers inside, we still wanna stop the minikube container itself
 		klog.Errorf("unable to get container runtime: %v", err)
 	} else {
-		containers, err := runtime.ListContainers(cruntime.ListContainersOptions{Namespaces: constants.DefaultNamespaces})
+		containers, err := cruntime.PodContainers(runtime, cruntime.All, constants.DefaultNamespaces)
 		if err != nil {
 			klog.Infof("unable list containers : %v", err)
 		}
@@ -359,6 +359,26 @@ func ContainerID(ociBin string, nameOrID string) (string, error) {
 	return rr.Stdout.String(), nil
 }
 
+// ImageExistsLocally returns if the image is present in the image store of ociBin already, without pulling it
+func ImageExistsLocally(ociBin string, image string) bool {
+	_, err := runCmd(exec.Command(ociBin, "image", "inspect", "--format", "{{.Id}}", image))
+	return err == nil
+}
+
+// ImageHasExecutable returns if the image holds an executable file at path, checking it in a throwaway container,
+// which pulls the image if it is not present yet
+func ImageHasExecutable(ociBin string, image string, path string) (bool, error) {
+	rr, err := runCmd(exec.Command(ociBin, "run", "--rm", "--entrypoint", "test", image, "-x", path))
+	if err != nil {
+		// test exits with 1 when there is no such file, the runtime with 125 and above when it fails itself
+		if rr.ExitCode == 1 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // ContainerExists checks if container name exists (either running or exited)
 func ContainerExists(ociBin string, name string, warnSlow ...bool) (bool, error) {
 	rr, err := runCmd(exec.Command(ociBin, "ps", "-a", "--format", "{{.Names}}"), warnSlow...)
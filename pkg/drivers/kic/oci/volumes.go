@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -147,6 +148,37 @@ func ExtractTarballToVolume(ociBin string, tarballPath, volumeName, imageName st
 	return nil
 }
 
+// ExportVolume runs a docker image imageName which writes the content of the volume named volumeName
+// to w, as a tarball compressed the way ExtractTarballToVolume expects
+func ExportVolume(ociBin string, volumeName, imageName string, w io.Writer) error {
+	cmdArgs := []string{"run", "--rm", "--entrypoint", "/usr/bin/tar"}
+	if ociBin == Podman && runtime.GOOS == "linux" {
+		cmdArgs = append(cmdArgs, "--security-opt", "label=disable")
+	}
+	cmdArgs = append(cmdArgs, "-v", fmt.Sprintf("%s:/exportDir:ro", volumeName), imageName, "-I", "lz4", "-cf", "-", "-C", "/exportDir", ".")
+	// the output is not buffered by runCmd, as it is as large as the volume
+	cmd := PrefixCmd(exec.Command(ociBin, cmdArgs...))
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	klog.Infof("Run: %v", cmd.Args)
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "exporting volume %s: %s", volumeName, stderr.String())
+	}
+	return nil
+}
+
+// ImportVolume creates the volume of a node of profile, and extracts the tarball written by ExportVolume to it
+func ImportVolume(ociBin string, tarballPath, profile, nodeName, imageName string) error {
+	if volumeExists(ociBin, nodeName) {
+		return fmt.Errorf("volume %s already exists", nodeName)
+	}
+	if err := createVolume(ociBin, profile, nodeName); err != nil {
+		return errors.Wrapf(err, "creating volume %s", nodeName)
+	}
+	return ExtractTarballToVolume(ociBin, tarballPath, nodeName, imageName)
+}
+
 // createVolume creates a volume to be attached to the container with correct labels and prefixes based on profile name
 // Caution ! if volume already exists does NOT return an error and will not apply the minikube labels on it.
 // TODO: this should be fixed as a part of https://github.com/kubernetes/minikube/issues/6530
@@ -25,6 +25,8 @@ import (
 const (
 	// Version is the current version of kic
 	Version = "v0.0.42-1704751654-17830"
+	// PortoVersion is the first version of kic shipping porto, "" while Version predates it
+	PortoVersion = ""
 
 	// SHA of the kic base image
 	baseImageSHA = "cabd32f8d9e8d804966eb117ed5366660f6363a4d1415f0b5480de6e396be617"
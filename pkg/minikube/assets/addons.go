@@ -19,7 +19,9 @@ package assets
 import (
 	"fmt"
 	"os"
+	"path"
 	"runtime"
+	"sort"
 	"strings"
 
 	semver "github.com/blang/semver/v4"
@@ -800,6 +802,17 @@ var Addons = map[string]*Addon{
 		map[string]string{
 			"Yakd": "docker.io",
 		}),
+	"runtime-health": NewAddon([]*BinAsset{
+		MustBinAsset(addons.RuntimeHealthAssets, "runtime-health/runtime-health-svc.yaml.tmpl", vmpath.GuestAddonsDir, "runtime-health-svc.yaml", "0640"),
+		MustBinAsset(addons.RuntimeHealthAssets, "runtime-health/runtime-health.service.tmpl", "/etc/systemd/system/", "runtime-health.service", "0640"),
+	}, false, "runtime-health", "minikube", "", "", nil, nil),
+	"porto-metrics": NewAddon([]*BinAsset{
+		MustBinAsset(addons.PortoMetricsAssets, "porto-metrics/porto-metrics-svc.yaml.tmpl", vmpath.GuestAddonsDir, "porto-metrics-svc.yaml", "0640"),
+		MustBinAsset(addons.PortoMetricsAssets, "porto-metrics/porto-metrics.service.tmpl", "/etc/systemd/system/", "porto-metrics.service", "0640"),
+	}, false, "porto-metrics", "minikube", "", "https://minikube.sigs.k8s.io/docs/handbook/addons/porto-metrics/", nil, nil),
+	"porto-docker-shim": NewAddon([]*BinAsset{
+		MustBinAsset(addons.PortoDockerShimAssets, "porto-docker-shim/porto-docker-shim.service.tmpl", "/etc/systemd/system/", "porto-docker-shim.service", "0640"),
+	}, false, "porto-docker-shim", "minikube", "", "https://minikube.sigs.k8s.io/docs/handbook/addons/porto-docker-shim/", nil, nil),
 }
 
 // parseMapString creates a map based on `str` which is encoded as <key1>=<value1>,<key2>=<value2>,...
@@ -902,6 +915,30 @@ func SelectAndPersistImages(addon *Addon, cc *config.ClusterConfig) (images, cus
 	return images, customRegistries, nil
 }
 
+// AddonImageRefs returns the full references of the images an addon runs,
+// resolving their registries the same way the addon templates do
+func AddonImageRefs(addon *Addon, cc *config.ClusterConfig, images, customRegistries map[string]string) []string {
+	var refs []string
+	for name, image := range images {
+		registry := addon.Registries[name]
+		// a custom image carries its own registry
+		if _, ok := cc.CustomAddonImages[name]; ok {
+			registry = ""
+		}
+		if override := customRegistries[name]; override != "" {
+			registry = override
+		} else if cc.KubernetesConfig.ImageRepository != "" {
+			registry = cc.KubernetesConfig.ImageRepository
+		}
+		if registry != "" {
+			image = path.Join(registry, image)
+		}
+		refs = append(refs, image)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
 // GenerateTemplateData generates template data for template assets
 func GenerateTemplateData(addon *Addon, cc *config.ClusterConfig, netInfo NetworkInfo, images, customRegistries map[string]string, enable bool) interface{} {
 	cfg := cc.KubernetesConfig
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/spf13/viper"
@@ -373,3 +374,51 @@ func checkMatches(t *testing.T, name string, got, expected map[string]string) {
 		t.Errorf("expected %q to be %q, but got %q", name, expected[name], got[name])
 	}
 }
+
+func TestAddonImageRefs(t *testing.T) {
+	addon := &Addon{
+		Images: map[string]string{
+			"Controller": "ingress-nginx/controller:v1.10.1",
+			"Certgen":    "ingress-nginx/kube-webhook-certgen:v1.4.1",
+		},
+		Registries: map[string]string{
+			"Controller": "registry.k8s.io",
+			"Certgen":    "registry.k8s.io",
+		},
+	}
+	tests := []struct {
+		description      string
+		cc               *config.ClusterConfig
+		images           map[string]string
+		customRegistries map[string]string
+		want             []string
+	}{
+		{
+			description: "Defaults",
+			cc:          &config.ClusterConfig{},
+			images:      addon.Images,
+			want:        []string{"registry.k8s.io/ingress-nginx/controller:v1.10.1", "registry.k8s.io/ingress-nginx/kube-webhook-certgen:v1.4.1"},
+		},
+		{
+			description: "ImageRepository",
+			cc:          &config.ClusterConfig{KubernetesConfig: config.KubernetesConfig{ImageRepository: "mirror.example.com/"}},
+			images:      addon.Images,
+			want:        []string{"mirror.example.com/ingress-nginx/controller:v1.10.1", "mirror.example.com/ingress-nginx/kube-webhook-certgen:v1.4.1"},
+		},
+		{
+			description:      "CustomImageAndRegistry",
+			cc:               &config.ClusterConfig{CustomAddonImages: map[string]string{"Controller": "example.com/controller:dev"}},
+			images:           map[string]string{"Controller": "example.com/controller:dev", "Certgen": addon.Images["Certgen"]},
+			customRegistries: map[string]string{"Certgen": "registry.example.com"},
+			want:             []string{"example.com/controller:dev", "registry.example.com/ingress-nginx/kube-webhook-certgen:v1.4.1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got := AddonImageRefs(addon, tc.cc, tc.images, tc.customRegistries)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("AddonImageRefs() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
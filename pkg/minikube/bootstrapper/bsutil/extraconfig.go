@@ -55,6 +55,8 @@ var componentToKubeadmConfigKey = map[string]string{
 	Kubeproxy: "",
 	// The Kubelet is not configured in kubeadm, only in systemd.
 	Kubelet: "",
+	// The porto runtime is configured when it is enabled, not by kubeadm.
+	Porto: "",
 }
 
 // KubeadmExtraArgsAllowed is a list of supported kubeadm params that can be supplied to kubeadm through
@@ -34,6 +34,8 @@ const (
 	InitRestartWrapper = "/etc/init.d/.restart_wrapper.sh"
 	// KubeletInitPath is where Sys-V style init script is installed
 	KubeletInitPath = "/etc/init.d/kubelet"
+	// RuntimeClassesFile is the manifest of the RuntimeClasses of the container runtime
+	RuntimeClassesFile = "/etc/kubernetes/addons/minikube-runtimeclasses.yaml"
 )
 
 // CopyFiles combines mkdir requests into a single call to reduce load
@@ -18,13 +18,21 @@ package ktmpl
 
 import "text/template"
 
-// KubeletSystemdTemplate hosts the override kubelet flags, written to kubeletSystemdConfFile
+// KubeletSystemdTemplate hosts the override kubelet flags, written to kubeletSystemdConfFile.
+// With PortoExec, kubelet runs in a porto container which the command starts.
 var KubeletSystemdTemplate = template.Must(template.New("kubeletSystemdTemplate").Parse(`[Unit]
 {{if or (eq .ContainerRuntime "cri-o") (eq .ContainerRuntime "crio")}}Wants=crio.service{{else if eq .ContainerRuntime "containerd"}}Wants=containerd.service{{else}}Wants=docker.socket{{end}}
+{{- if .PortoExec}}
+Requires=porto.service
+After=porto.service{{end}}
 
 [Service]
 ExecStart=
+{{- if .PortoExec}}
+ExecStart={{.PortoExec}} "command={{.KubeletPath}}{{if .ExtraOptions}} {{.ExtraOptions}}{{end}}"
+{{- else}}
 ExecStart={{.KubeletPath}}{{if .ExtraOptions}} {{.ExtraOptions}}{{end}}
+{{- end}}
 
 [Install]
 `))
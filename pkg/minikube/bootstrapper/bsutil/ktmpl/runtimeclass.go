@@ -0,0 +1,30 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ktmpl
+
+import "text/template"
+
+// RuntimeClassTemplate is the RuntimeClasses mapped to the runtime handlers of the container runtime, written to RuntimeClassesFile
+var RuntimeClassTemplate = template.Must(template.New("runtimeClassTemplate").Parse(`{{range .Handlers}}---
+apiVersion: node.k8s.io/v1{{if $.LegacyRuntimeClass}}beta1{{end}}
+kind: RuntimeClass
+metadata:
+  name: {{.}}
+  labels:
+    app.kubernetes.io/managed-by: minikube
+handler: {{.}}
+{{end}}`))
@@ -192,6 +192,7 @@ const (
 	Kubeadm           = "kubeadm"
 	Kubeproxy         = "kube-proxy"
 	Kubelet           = "kubelet"
+	Porto             = "porto"
 )
 
 // KubeadmExtraConfigOpts is a list of allowed "extra-config" components
@@ -203,6 +204,7 @@ var KubeadmExtraConfigOpts = []string{
 	Kubeadm,
 	Kubelet,
 	Kubeproxy,
+	Porto,
 }
 
 // InvokeKubeadm returns the invocation command for Kubeadm
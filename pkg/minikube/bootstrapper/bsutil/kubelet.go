@@ -46,6 +46,12 @@ var kubeletConfigParams = []string{
 	"hairpin-mode",
 }
 
+// runtimeDefaultKubeletOptions are the kubelet options container runtimes set which users may override
+var runtimeDefaultKubeletOptions = map[string]bool{
+	"system-reserved": true,
+	"kube-reserved":   true,
+}
+
 func extraKubeletOpts(mc config.ClusterConfig, nc config.Node, r cruntime.Manager) (map[string]string, error) {
 	k8s := mc.KubernetesConfig
 	version, err := util.ParseKubernetesVersion(k8s.KubernetesVersion)
@@ -59,6 +65,10 @@ func extraKubeletOpts(mc config.ClusterConfig, nc config.Node, r cruntime.Manage
 	}
 
 	for k, v := range r.KubeletOptions() {
+		// the reservations of the runtime are defaults, which --extra-config overrides
+		if _, ok := extraOpts[k]; ok && runtimeDefaultKubeletOptions[k] {
+			continue
+		}
 		extraOpts[k] = v
 	}
 
@@ -129,11 +139,21 @@ func NewKubeletConfig(mc config.ClusterConfig, nc config.Node, r cruntime.Manage
 		ExtraOptions     string
 		ContainerRuntime string
 		KubeletPath      string
+		PortoExec        string
 	}{
 		ExtraOptions:     convertToFlags(extraOpts),
 		ContainerRuntime: k8s.ContainerRuntime,
 		KubeletPath:      path.Join(binRoot(k8s.KubernetesVersion), "kubelet"),
 	}
+	if k8s.ContainerRuntime == constants.Porto {
+		gates, err := cruntime.ParseFeatureGates(mc.RuntimeFeatureGates)
+		if err != nil {
+			return nil, errors.Wrap(err, "runtime feature gates")
+		}
+		if gates.Enabled(cruntime.FeatureKubeletMetaContainer) {
+			opts.PortoExec = cruntime.PortoKubeletExec
+		}
+	}
 	if err := ktmpl.KubeletSystemdTemplate.Execute(&b, opts); err != nil {
 		return nil, err
 	}
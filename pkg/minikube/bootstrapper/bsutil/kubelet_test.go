@@ -18,6 +18,7 @@ limitations under the License.
 package bsutil
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/pmezard/go-difflib/difflib"
@@ -203,3 +204,67 @@ ExecStart=/var/lib/minikube/binaries/v1.18.2/kubelet --authorization-mode=Webhoo
 		})
 	}
 }
+
+func TestGenerateKubeletConfigPortoMetaContainer(t *testing.T) {
+	cfg := config.ClusterConfig{
+		Name:                "minikube",
+		RuntimeFeatureGates: "kubelet-meta-container=true",
+		KubernetesConfig: config.KubernetesConfig{
+			KubernetesVersion: constants.DefaultKubernetesVersion,
+			ContainerRuntime:  constants.Porto,
+		},
+		Nodes: []config.Node{{IP: "192.168.1.100", Name: "minikube", ControlPlane: true}},
+	}
+	runtime, err := cruntime.New(cruntime.Config{Type: constants.Porto})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	got, err := NewKubeletConfig(cfg, cfg.Nodes[0], runtime)
+	if err != nil {
+		t.Fatalf("NewKubeletConfig: %v", err)
+	}
+	want := []string{
+		"Requires=porto.service\nAfter=porto.service\n",
+		`ExecStart=portoctl exec minikube/kubelet virt_mode=host isolate=false "command=/var/lib/minikube/binaries/` + constants.DefaultKubernetesVersion + "/kubelet --",
+	}
+	for _, w := range want {
+		if !strings.Contains(string(got), w) {
+			t.Errorf("kubelet config does not contain %q:\n%s", w, got)
+		}
+	}
+
+	cfg.RuntimeFeatureGates = ""
+	got, err = NewKubeletConfig(cfg, cfg.Nodes[0], runtime)
+	if err != nil {
+		t.Fatalf("NewKubeletConfig: %v", err)
+	}
+	if strings.Contains(string(got), "portoctl") {
+		t.Errorf("kubelet runs in a porto container without the feature gate:\n%s", got)
+	}
+}
+
+func TestGenerateKubeletConfigPortoReserved(t *testing.T) {
+	cfg := config.ClusterConfig{
+		Name: "minikube",
+		KubernetesConfig: config.KubernetesConfig{
+			KubernetesVersion: constants.DefaultKubernetesVersion,
+			ContainerRuntime:  constants.Porto,
+			ExtraOptions:      config.ExtraOptionSlice{{Component: Kubelet, Key: "kube-reserved", Value: "cpu=200m,memory=1Gi"}},
+		},
+		Nodes: []config.Node{{IP: "192.168.1.100", Name: "minikube", ControlPlane: true}},
+	}
+	runtime, err := cruntime.New(cruntime.Config{Type: constants.Porto})
+	if err != nil {
+		t.Fatalf("runtime: %v", err)
+	}
+	got, err := NewKubeletConfig(cfg, cfg.Nodes[0], runtime)
+	if err != nil {
+		t.Fatalf("NewKubeletConfig: %v", err)
+	}
+	// --extra-config overrides the reservation of porto for kubelet, but not the one it does not set
+	for _, want := range []string{"--kube-reserved=cpu=200m,memory=1Gi ", "--system-reserved=cpu=100m,memory=128Mi"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("kubelet config does not contain %q:\n%s", want, got)
+		}
+	}
+}
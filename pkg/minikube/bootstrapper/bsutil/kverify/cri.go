@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kverify
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util/retry"
+)
+
+// WaitForCRI waits for the container runtime serving socket, portoshim for porto, to answer CRI version and status
+// requests and report itself ready to run containers
+func WaitForCRI(cr command.Runner, socket string, timeout time.Duration) error {
+	klog.Infof("waiting for the CRI on %s to be ready ...", socket)
+	start := time.Now()
+
+	checkCRI := func() error {
+		err := cruntime.CRIReady(cr, socket)
+		if err != nil {
+			klog.Warningf("CRI is not ready: %v", err)
+		}
+		return err
+	}
+
+	if err := retry.Local(checkCRI, timeout); err != nil {
+		return errors.Wrapf(err, "CRI on %s", socket)
+	}
+	klog.Infof("duration metric: took %s to wait for the CRI to be ready ...", time.Since(start))
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kverify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util/retry"
+)
+
+// mirrorPodAnnotation holds the UID the kubelet gives a static pod, which the CRI labels its sandbox with,
+// instead of the UID of its mirror pod in the apiserver
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// WaitForCRIPods waits for the CRI of the node to run a ready sandbox and every container of each kube-system pod
+// scheduled to it, catching pods the apiserver lists while they failed at the runtime level.
+func WaitForCRIPods(cr command.Runner, cs *kubernetes.Clientset, nodeName string, timeout time.Duration) error {
+	klog.Infof("waiting for the CRI of %q to run its kube-system pods ...", nodeName)
+	start := time.Now()
+
+	checkCRI := func() error {
+		pods, err := cs.CoreV1().Pods("kube-system").List(context.Background(), meta.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+		if err != nil {
+			klog.Warningf("pod list returned error: %v", err)
+			return err
+		}
+		running, err := cruntime.CRIPods(cr, "kube-system")
+		if err != nil {
+			klog.Warningf("CRI pod list returned error: %v", err)
+			return err
+		}
+		if missing := missingCRIPods(pods.Items, running); len(missing) > 0 {
+			return fmt.Errorf("not running in the CRI: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}
+
+	if err := retry.Local(checkCRI, timeout); err != nil {
+		return errors.Wrapf(err, "kube-system pods of %s", nodeName)
+	}
+	klog.Infof("duration metric: took %s to wait for the CRI to run kube-system pods ...", time.Since(start))
+	return nil
+}
+
+// missingCRIPods returns the pods expected to run which the CRI does not run all the containers of, with the reason
+func missingCRIPods(pods []core.Pod, running map[string]cruntime.CRIPod) []string {
+	var missing []string
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == core.PodSucceeded || pod.Status.Phase == core.PodFailed {
+			continue
+		}
+		uid := string(pod.UID)
+		if m, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			uid = m
+		}
+		p, ok := running[uid]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("%s (no sandbox)", pod.Name))
+		case !p.Ready:
+			missing = append(missing, fmt.Sprintf("%s (sandbox not ready)", pod.Name))
+		case p.Running < len(pod.Spec.Containers):
+			missing = append(missing, fmt.Sprintf("%s (%d/%d containers running)", pod.Name, p.Running, len(pod.Spec.Containers)))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
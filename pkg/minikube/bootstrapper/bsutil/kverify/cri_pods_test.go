@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kverify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func TestMissingCRIPods(t *testing.T) {
+	pod := func(name, uid string, containers int, mutate ...func(*core.Pod)) core.Pod {
+		p := core.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: name, UID: types.UID(uid)},
+			Spec:       core.PodSpec{Containers: make([]core.Container, containers)},
+			Status:     core.PodStatus{Phase: core.PodRunning},
+		}
+		for _, m := range mutate {
+			m(&p)
+		}
+		return p
+	}
+	static := func(hash string) func(*core.Pod) {
+		return func(p *core.Pod) { p.Annotations = map[string]string{mirrorPodAnnotation: hash} }
+	}
+	deleted := func(p *core.Pod) { p.DeletionTimestamp = &meta.Time{Time: time.Now()} }
+	succeeded := func(p *core.Pod) { p.Status.Phase = core.PodSucceeded }
+
+	pods := []core.Pod{
+		pod("etcd-minikube", "m1", 1, static("h1")),
+		pod("coredns-5dd5756b68-x2x4q", "u2", 1),
+		pod("kindnet-8bq5d", "u3", 2),
+		pod("kube-proxy-vw7fn", "u4", 1),
+		pod("storage-provisioner", "u5", 1),
+		pod("old-coredns", "u6", 1, deleted),
+		pod("job", "u7", 1, succeeded),
+	}
+	running := map[string]cruntime.CRIPod{
+		"h1": {Name: "etcd-minikube", Ready: true, Running: 1},
+		"u2": {Name: "coredns-5dd5756b68-x2x4q", Ready: true, Running: 1},
+		"u3": {Name: "kindnet-8bq5d", Ready: true, Running: 1},
+		"u4": {Name: "kube-proxy-vw7fn", Running: 0},
+	}
+
+	want := []string{
+		"kindnet-8bq5d (1/2 containers running)",
+		"kube-proxy-vw7fn (sandbox not ready)",
+		"storage-provisioner (no sandbox)",
+	}
+	if diff := cmp.Diff(want, missingCRIPods(pods, running)); diff != "" {
+		t.Errorf("missingCRIPods mismatch (-want +got):\n%s", diff)
+	}
+
+	running["u3"] = cruntime.CRIPod{Name: "kindnet-8bq5d", Ready: true, Running: 2}
+	running["u4"] = cruntime.CRIPod{Name: "kube-proxy-vw7fn", Ready: true, Running: 1}
+	running["u5"] = cruntime.CRIPod{Name: "storage-provisioner", Ready: true, Running: 1}
+	if got := missingCRIPods(pods, running); len(got) != 0 {
+		t.Errorf("missingCRIPods = %v, want none", got)
+	}
+}
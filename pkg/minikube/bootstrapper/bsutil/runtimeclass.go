@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"bytes"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil/ktmpl"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/util"
+)
+
+// runtimeHandlers returns the runtime handlers of the container runtime pods can select with a RuntimeClass,
+// including the ones of the WebAssembly shim and of the sandboxed runtime of the cluster
+func runtimeHandlers(cc config.ClusterConfig) []string {
+	handlers := []string{}
+	if cc.KubernetesConfig.ContainerRuntime == constants.Porto {
+		for _, i := range cruntime.PortoIsolations {
+			handlers = append(handlers, i.Handler)
+		}
+	}
+	if shim, err := cruntime.WasmShimNamed(cc.WasmShim); err == nil {
+		handlers = append(handlers, shim.Handler)
+	}
+	if cc.RuntimeClass == cruntime.KataRuntimeClass {
+		handlers = append(handlers, cruntime.KataRuntimeClass)
+	}
+	return handlers
+}
+
+// NewRuntimeClasses returns the manifest of a RuntimeClass for each runtime handler of the cluster,
+// or nil if it has none besides the default one
+func NewRuntimeClasses(cc config.ClusterConfig) ([]byte, error) {
+	handlers := runtimeHandlers(cc)
+	if len(handlers) == 0 {
+		return nil, nil
+	}
+	k8s := cc.KubernetesConfig
+	version, err := util.ParseKubernetesVersion(k8s.KubernetesVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing Kubernetes version")
+	}
+
+	opts := struct {
+		Handlers []string
+		// LegacyRuntimeClass is set for Kubernetes versions without node.k8s.io/v1
+		LegacyRuntimeClass bool
+	}{
+		Handlers:           handlers,
+		LegacyRuntimeClass: version.LT(semver.Version{Major: 1, Minor: 20}),
+	}
+	var b bytes.Buffer
+	if err := ktmpl.RuntimeClassTemplate.Execute(&b, opts); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bsutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/config"
+)
+
+func TestNewRuntimeClasses(t *testing.T) {
+	var tests = []struct {
+		description string
+		runtime     string
+		wasmShim    string
+		class       string
+		version     string
+		want        string
+	}{
+		{"Docker", "docker", "", "", "v1.30.0", ""},
+		{"WasmShim", "containerd", "spin", "", "v1.30.0", `---
+apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: spin
+  labels:
+    app.kubernetes.io/managed-by: minikube
+handler: spin
+`},
+		{"Kata", "crio", "", "kata", "v1.30.0", `---
+apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: kata
+  labels:
+    app.kubernetes.io/managed-by: minikube
+handler: kata
+`},
+		{"Porto", "porto", "", "", "v1.30.0", `---
+apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: porto-container
+  labels:
+    app.kubernetes.io/managed-by: minikube
+handler: porto-container
+---
+apiVersion: node.k8s.io/v1
+kind: RuntimeClass
+metadata:
+  name: porto-vm
+  labels:
+    app.kubernetes.io/managed-by: minikube
+handler: porto-vm
+`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := NewRuntimeClasses(config.ClusterConfig{
+				WasmShim:         tc.wasmShim,
+				RuntimeClass:     tc.class,
+				KubernetesConfig: config.KubernetesConfig{ContainerRuntime: tc.runtime, KubernetesVersion: tc.version},
+			})
+			if err != nil {
+				t.Fatalf("NewRuntimeClasses: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("NewRuntimeClasses returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
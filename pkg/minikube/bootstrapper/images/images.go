@@ -59,7 +59,6 @@ func essentials(mirror string, v semver.Version) []string {
 		componentImage("kube-scheduler", v, mirror),
 		componentImage("kube-proxy", v, mirror),
 		Pause(v, mirror),
-		PauseExact("3.7", mirror),
 		etcd(v, mirror),
 		coreDNS(v, mirror),
 	}
@@ -73,6 +73,8 @@ type Bootstrapper struct {
 	c           command.Runner
 	k8sClient   *kubernetes.Clientset // Kubernetes client used to verify pods inside cluster
 	contextName string
+	// kubeletRestart is set when the container runtime changed what only takes effect on a kubelet restart
+	kubeletRestart bool
 }
 
 // NewBootstrapper creates a new kubeadm.Bootstrapper
@@ -216,7 +218,9 @@ func (k *Bootstrapper) init(cfg config.ClusterConfig) error {
 		skipSystemVerification = true
 	}
 	if driver.BareMetal(cfg.Driver) && r.Name() == "Docker" {
-		if v, err := r.Version(); err == nil && strings.Contains(v, "azure") {
+		ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+		defer cancel()
+		if v, err := r.Version(ctx); err == nil && strings.Contains(v, "azure") {
 			klog.Infof("ignoring SystemVerification for kubeadm because of unknown docker version %s", v)
 			skipSystemVerification = true
 		}
@@ -270,7 +274,7 @@ func (k *Bootstrapper) init(cfg config.ClusterConfig) error {
 		return errors.Wrap(err, "apply cni")
 	}
 
-	wg.Add(3)
+	wg.Add(4)
 
 	go func() {
 		// we need to have cluster role binding before applying overlay to avoid #7428
@@ -294,6 +298,13 @@ func (k *Bootstrapper) init(cfg config.ClusterConfig) error {
 		wg.Done()
 	}()
 
+	go func() {
+		if err := k.applyRuntimeClasses(cfg); err != nil {
+			klog.Warningf("unable to create runtime classes: %v", err)
+		}
+		wg.Done()
+	}()
+
 	wg.Wait()
 	// Tunnel apiserver to guest, if necessary
 	if cfg.APIServerPort != 0 {
@@ -385,7 +396,7 @@ func (k *Bootstrapper) unpause(cfg config.ClusterConfig) error {
 		return err
 	}
 
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: []string{"kube-system"}})
+	ids, err := cruntime.PodContainers(cr, cruntime.Paused, []string{"kube-system"})
 	if err != nil {
 		return errors.Wrap(err, "list paused")
 	}
@@ -537,11 +548,17 @@ func (k *Bootstrapper) WaitForNode(cfg config.ClusterConfig, n config.Node, time
 		}
 	}
 
-	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c})
+	cr, err := cruntime.New(cruntime.Config{Type: cfg.KubernetesConfig.ContainerRuntime, Runner: k.c, Socket: cfg.KubernetesConfig.CRISocket})
 	if err != nil {
 		return errors.Wrapf(err, "create runtme-manager %s", cfg.KubernetesConfig.ContainerRuntime)
 	}
 
+	if cfg.VerifyComponents[kverify.CRIKey] {
+		if err := kverify.WaitForCRI(k.c, cr.SocketPath(), timeout); err != nil {
+			return errors.Wrap(err, "waiting for cri")
+		}
+	}
+
 	if n.ControlPlane {
 		if cfg.VerifyComponents[kverify.APIServerWaitKey] {
 			if err := kverify.WaitForAPIServerProcess(cr, k, cfg, k.c, start, timeout); err != nil {
@@ -578,6 +595,12 @@ func (k *Bootstrapper) WaitForNode(cfg config.ClusterConfig, n config.Node, time
 		}
 	}
 
+	if cfg.VerifyComponents[kverify.CRIPodsKey] {
+		if err := kverify.WaitForCRIPods(k.c, client, bsutil.KubeNodeName(cfg, n), timeout); err != nil {
+			return errors.Wrap(err, "waiting for cri_pods")
+		}
+	}
+
 	klog.Infof("duration metric: took %s to wait for : %+v ...", time.Since(start), cfg.VerifyComponents)
 
 	if err := kverify.NodePressure(client); err != nil {
@@ -682,6 +705,16 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 
 	if !k.needsReconfigure(conf, hostname, port, client, cfg.KubernetesConfig.KubernetesVersion) {
 		klog.Infof("Taking a shortcut, as the cluster seems to be properly configured")
+		// kubelet is not restarted by kubeadm then, but the runtime may need it to be
+		if k.kubeletRestart {
+			sm := sysinit.New(k.c)
+			if sm.Active("kubelet") {
+				klog.Infof("restarting kubelet for the changed container runtime configuration")
+				if err := sm.Restart("kubelet"); err != nil {
+					return errors.Wrap(err, "restarting kubelet")
+				}
+			}
+		}
 		return nil
 	}
 
@@ -705,6 +738,7 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 	cmds := []string{
 		fmt.Sprintf("%s phase certs all --config %s", baseCmd, conf),
 		fmt.Sprintf("%s phase kubeconfig all --config %s", baseCmd, conf),
+		// restarts kubelet, which takes what the container runtime changed into account as well
 		fmt.Sprintf("%s phase kubelet-start --config %s", baseCmd, conf),
 		fmt.Sprintf("%s phase %s all --config %s", baseCmd, controlPlane, conf),
 		fmt.Sprintf("%s phase etcd local --config %s", baseCmd, conf),
@@ -796,9 +830,23 @@ func (k *Bootstrapper) restartControlPlane(cfg config.ClusterConfig) error {
 		klog.Warningf("unable to adjust resource limits: %v", err)
 	}
 
+	// clusters created before the container runtime had runtime handlers get their RuntimeClasses on restart
+	if err := k.applyRuntimeClasses(cfg); err != nil {
+		klog.Warningf("unable to create runtime classes: %v", err)
+	}
+
 	return nil
 }
 
+// RuntimeChanged makes the kubelet restarts of the bootstrapper cover what the last Enable of cr changed on the node
+func (k *Bootstrapper) RuntimeChanged(cr cruntime.Manager) {
+	change := cruntime.AppliedChange(cr)
+	k.kubeletRestart = cr.ShouldRestartKubelet(change)
+	if !k.kubeletRestart {
+		klog.Infof("%s changes need no kubelet restart: %+v", cr.Name(), change)
+	}
+}
+
 // JoinCluster adds new node to an existing cluster.
 func (k *Bootstrapper) JoinCluster(cc config.ClusterConfig, n config.Node, joinCmd string) error {
 	// Join the master by specifying its token
@@ -808,6 +856,7 @@ func (k *Bootstrapper) JoinCluster(cc config.ClusterConfig, n config.Node, joinC
 		return errors.Wrapf(err, "kubeadm join")
 	}
 
+	// kubeadm join restarted kubelet already, after the container runtime changed, so it only has to be kept running
 	if _, err := k.c.RunCmd(exec.Command("/bin/bash", "-c", "sudo systemctl daemon-reload && sudo systemctl enable kubelet && sudo systemctl start kubelet")); err != nil {
 		return errors.Wrap(err, "starting kubelet")
 	}
@@ -859,7 +908,7 @@ func StopKubernetes(runner command.Runner, cr cruntime.Manager) {
 	}
 
 	// Stop each Kubernetes container.
-	containers, err := cr.ListContainers(cruntime.ListContainersOptions{Namespaces: []string{"kube-system"}})
+	containers, err := cruntime.PodContainers(cr, cruntime.All, []string{"kube-system"})
 	if err != nil {
 		klog.Warningf("unable to list kube-system containers: %v", err)
 	}
@@ -918,17 +967,29 @@ func (k *Bootstrapper) UpdateCluster(cfg config.ClusterConfig) error {
 	if err != nil {
 		return errors.Wrap(err, "parsing Kubernetes version")
 	}
+	gates, err := cruntime.ParseFeatureGates(cfg.RuntimeFeatureGates)
+	if err != nil {
+		return errors.Wrap(err, "runtime feature gates")
+	}
+	// the runtime renders the kubelet flags below, so it needs the same gates and sysctls the node was started with
 	r, err := cruntime.New(cruntime.Config{
-		Type:              cfg.KubernetesConfig.ContainerRuntime,
-		Runner:            k.c,
-		Socket:            cfg.KubernetesConfig.CRISocket,
-		KubernetesVersion: version,
+		Type:                 cfg.KubernetesConfig.ContainerRuntime,
+		Runner:               k.c,
+		Socket:               cfg.KubernetesConfig.CRISocket,
+		KubernetesVersion:    version,
+		FeatureGates:         gates,
+		AllowedUnsafeSysctls: cruntime.AllowedUnsafeSysctls(cfg.KubernetesConfig.ExtraOptions),
 	})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
 
-	if err := r.Preload(cfg); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	progress, stop := machine.PullProgressBars()
+	err = r.Preload(cruntime.WithPullProgress(ctx, progress), cfg)
+	stop()
+	if err != nil {
 		switch err.(type) {
 		case *cruntime.ErrISOFeature:
 			out.ErrT(style.Tip, "Existing disk is missing new features ({{.error}}). To upgrade, run 'minikube delete'", out.V{"error": err})
@@ -1130,6 +1191,36 @@ func (k *Bootstrapper) elevateKubeSystemPrivileges(cfg config.ClusterConfig) err
 	return nil
 }
 
+// applyRuntimeClasses creates a RuntimeClass for each runtime handler of the cluster, such as the isolation
+// levels of porto, a WebAssembly shim or Kata Containers, so that pods can select one with runtimeClassName
+func (k *Bootstrapper) applyRuntimeClasses(cfg config.ClusterConfig) error {
+	manifest, err := bsutil.NewRuntimeClasses(cfg)
+	if err != nil {
+		return errors.Wrap(err, "generating runtime classes")
+	}
+	if manifest == nil {
+		return nil
+	}
+
+	f := assets.NewMemoryAssetTarget(manifest, bsutil.RuntimeClassesFile, "0640")
+	defer f.Close()
+	if err := bsutil.CopyFiles(k.c, []assets.CopyableFile{f}); err != nil {
+		return errors.Wrap(err, "copying runtime classes")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applyTimeoutSeconds*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sudo", kubectlPath(cfg), "apply",
+		fmt.Sprintf("--kubeconfig=%s", path.Join(vmpath.GuestPersistentDir, "kubeconfig")), "-f", bsutil.RuntimeClassesFile)
+	if rr, err := k.c.RunCmd(cmd); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Wrapf(err, "timeout apply runtime classes")
+		}
+		return errors.Wrapf(err, "apply runtime classes: %s", rr.Output())
+	}
+	return nil
+}
+
 // stopKubeSystem stops all the containers in the kube-system to prevent #8740 when doing hot upgrade
 func (k *Bootstrapper) stopKubeSystem(cfg config.ClusterConfig) error {
 	klog.Info("stopping kube-system containers ...")
@@ -1138,7 +1229,7 @@ func (k *Bootstrapper) stopKubeSystem(cfg config.ClusterConfig) error {
 		return errors.Wrap(err, "new cruntime")
 	}
 
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{Namespaces: []string{"kube-system"}})
+	ids, err := cruntime.PodContainers(cr, cruntime.All, []string{"kube-system"})
 	if err != nil {
 		return errors.Wrap(err, "list")
 	}
@@ -0,0 +1,337 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bundle exports a profile, with the state of its nodes, to a single archive which can be imported on another machine
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/drivers/kic/oci"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+const (
+	// manifestName is the first entry of a bundle
+	manifestName = "bundle.json"
+	// manifestVersion is the version of the bundle format
+	manifestVersion = 1
+
+	// profilesDir, machinesDir and volumesDir are the directories of a bundle, laid out like the minikube home directory
+	profilesDir = "profiles"
+	machinesDir = "machines"
+	volumesDir  = "volumes"
+)
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	Version int
+	// Profile is the name of the exported profile
+	Profile string
+	// Driver is the driver of the profile
+	Driver string
+	// MiniPath is the minikube home directory the profile was exported from, which paths in the configuration refer to
+	MiniPath string
+	// Machines are the machines of the nodes of the profile
+	Machines []string
+}
+
+// Supported returns an error if profiles of the driver can't be exported. The state of such profiles lives outside
+// the minikube home directory, such as in a hypervisor, and can't be moved to another machine.
+func Supported(driverName string) error {
+	if driver.IsKIC(driverName) || driver.IsQEMU(driverName) {
+		return nil
+	}
+	return fmt.Errorf("exporting profiles of the %s driver is not supported, only of the docker, podman and qemu2 drivers", driverName)
+}
+
+// Export writes the profile of cc to w as a gzipped tarball: its configuration, and the machines of its nodes.
+// The machine directories hold the disks of VM drivers; for the kic drivers the volumes of the nodes,
+// which hold the image stores of the container runtimes, are exported as well.
+// The cluster must be stopped, so that the disks are consistent.
+func Export(cc *config.ClusterConfig, w io.Writer) error {
+	if err := Supported(cc.Driver); err != nil {
+		return err
+	}
+	m := Manifest{Version: manifestVersion, Profile: cc.Name, Driver: cc.Driver, MiniPath: localpath.MiniPath()}
+	for _, n := range cc.Nodes {
+		m.Machines = append(m.Machines, config.MachineName(*cc, n))
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "marshal manifest")
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(b))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+
+	if err := addDir(tw, localpath.Profile(cc.Name), path.Join(profilesDir, cc.Name)); err != nil {
+		return errors.Wrap(err, "profile")
+	}
+	for _, machine := range m.Machines {
+		if err := addDir(tw, localpath.MachinePath(machine), path.Join(machinesDir, machine)); err != nil {
+			return errors.Wrapf(err, "machine %s", machine)
+		}
+		if driver.IsKIC(cc.Driver) {
+			if err := addVolume(tw, cc.Driver, machine, cc.KicBaseImage); err != nil {
+				return errors.Wrapf(err, "volume of %s", machine)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addDir adds the regular files under dir to the tarball, under name
+func addDir(tw *tar.Writer, dir string, name string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return addFile(tw, p, path.Join(name, filepath.ToSlash(rel)))
+	})
+}
+
+// addFile adds the file at p to the tarball, under name
+func addFile(tw *tar.Writer, p string, name string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// addVolume adds the volume of a kic node to the tarball. It is staged in a temporary file, as a tarball entry needs its size upfront.
+func addVolume(tw *tar.Writer, ociBin string, machine string, kicImage string) error {
+	tmp, err := os.CreateTemp("", "minikube-volume-*.tar.lz4")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	klog.Infof("exporting volume %s to %s", machine, tmp.Name())
+	if err := oci.ExportVolume(ociBin, machine, kicImage, tmp); err != nil {
+		return err
+	}
+	return addFile(tw, tmp.Name(), path.Join(volumesDir, machine+".tar.lz4"))
+}
+
+// Import creates the profile exported to r by Export, and returns its manifest.
+// Paths to the minikube home directory the profile was exported from are rewritten to the one of this machine.
+// Whatever was imported is removed again if the import fails.
+func Import(r io.Reader) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "not a profile bundle")
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("not a profile bundle: %s is missing", manifestName)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, errors.Wrap(err, "manifest")
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, expected %d", m.Version, manifestVersion)
+	}
+	if !config.ProfileNameValid(m.Profile) {
+		return nil, fmt.Errorf("invalid profile name %q", m.Profile)
+	}
+	for _, machine := range m.Machines {
+		// machines are named after their profile, such as minikube-m02
+		if !config.ProfileNameValid(machine) || !strings.HasPrefix(machine, m.Profile) {
+			return nil, fmt.Errorf("invalid machine name %q", machine)
+		}
+	}
+	if config.ProfileExists(m.Profile) {
+		return nil, fmt.Errorf("profile %q already exists, delete it first", m.Profile)
+	}
+	if err := Supported(m.Driver); err != nil {
+		return nil, err
+	}
+
+	if err := extract(tr, &m); err != nil {
+		cleanup(&m)
+		return nil, err
+	}
+	return &m, nil
+}
+
+// extract writes the entries of a bundle following its manifest
+func extract(tr *tar.Reader, m *Manifest) error {
+	owned := map[string]bool{path.Join(profilesDir, m.Profile): true}
+	for _, machine := range m.Machines {
+		owned[path.Join(machinesDir, machine)] = true
+		owned[path.Join(volumesDir, machine+".tar.lz4")] = true
+	}
+
+	var kicImage string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// entries must belong to the profile or one of its machines
+		name := path.Clean(hdr.Name)
+		parts := strings.SplitN(name, "/", 3)
+		if path.IsAbs(hdr.Name) || len(parts) < 2 || !owned[path.Join(parts[0], parts[1])] {
+			return fmt.Errorf("invalid bundle entry %q", hdr.Name)
+		}
+
+		if parts[0] != volumesDir {
+			if err := extractFile(tr, hdr, filepath.Join(localpath.MiniPath(), filepath.FromSlash(name)), m.MiniPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if kicImage == "" {
+			cc, err := config.Load(m.Profile)
+			if err != nil {
+				return errors.Wrap(err, "profile configuration must come before the volumes")
+			}
+			kicImage = cc.KicBaseImage
+		}
+		if err := importVolume(tr, m.Driver, m.Profile, strings.TrimSuffix(parts[1], ".tar.lz4"), kicImage); err != nil {
+			return errors.Wrapf(err, "volume %s", parts[1])
+		}
+	}
+}
+
+// cleanup removes what a failed import created
+func cleanup(m *Manifest) {
+	dirs := []string{localpath.Profile(m.Profile)}
+	for _, machine := range m.Machines {
+		dirs = append(dirs, localpath.MachinePath(machine))
+		if driver.IsKIC(m.Driver) {
+			if err := oci.RemoveVolume(m.Driver, machine); err != nil {
+				klog.Warningf("unable to remove volume %s: %v", machine, err)
+			}
+		}
+	}
+	for _, d := range dirs {
+		if err := os.RemoveAll(d); err != nil {
+			klog.Warningf("unable to remove %s: %v", d, err)
+		}
+	}
+}
+
+// extractFile writes a tarball entry to p. JSON files, the configuration of profiles and machines,
+// have paths to the minikube home directory of the exporting machine, oldMiniPath, replaced.
+func extractFile(r io.Reader, hdr *tar.Header, p string, oldMiniPath string) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if strings.HasSuffix(p, ".json") && oldMiniPath != "" {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		b = rewriteMiniPath(b, oldMiniPath, localpath.MiniPath())
+		return os.WriteFile(p, b, hdr.FileInfo().Mode().Perm())
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, hdr.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewriteMiniPath replaces the minikube home directory oldPath by newPath in the JSON document b
+func rewriteMiniPath(b []byte, oldPath, newPath string) []byte {
+	if oldPath == newPath {
+		return b
+	}
+	// paths are escaped in JSON, which matters for the backslashes of Windows paths
+	quote := func(s string) string {
+		q, _ := json.Marshal(s)
+		return strings.Trim(string(q), `"`)
+	}
+	return []byte(strings.ReplaceAll(string(b), quote(oldPath), quote(newPath)))
+}
+
+// importVolume creates the volume of a kic node from the tarball read from r
+func importVolume(r io.Reader, ociBin string, profile string, machine string, kicImage string) error {
+	tmp, err := os.CreateTemp("", "minikube-volume-*.tar.lz4")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	klog.Infof("importing volume %s from %s", machine, tmp.Name())
+	return oci.ImportVolume(ociBin, tmp.Name(), profile, machine, kicImage)
+}
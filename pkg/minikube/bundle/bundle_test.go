@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/driver"
+	"k8s.io/minikube/pkg/minikube/localpath"
+)
+
+func TestExportImport(t *testing.T) {
+	src := t.TempDir()
+	t.Setenv("MINIKUBE_HOME", src)
+	cc := &config.ClusterConfig{Name: "workshop", Driver: driver.QEMU2, Nodes: []config.Node{{Name: "", ControlPlane: true}, {Name: "m02"}}}
+	if err := config.SaveProfile(cc.Name, cc); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	for _, machine := range []string{"workshop", "workshop-m02"} {
+		dir := localpath.MachinePath(machine)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		machineConfig := `{"Driver":{"DiskPath":` + string(mustJSON(t, filepath.Join(dir, "disk.qcow2"))) + `}}`
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(machineConfig), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "disk.qcow2"), []byte("disk of "+machine), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := Export(cc, &b); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := t.TempDir()
+	t.Setenv("MINIKUBE_HOME", dst)
+	m, err := Import(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if m.Profile != "workshop" {
+		t.Errorf("imported profile %q, want workshop", m.Profile)
+	}
+	if !config.ProfileExists("workshop") {
+		t.Errorf("profile workshop does not exist after the import")
+	}
+	disk, err := os.ReadFile(filepath.Join(localpath.MachinePath("workshop-m02"), "disk.qcow2"))
+	if err != nil || string(disk) != "disk of workshop-m02" {
+		t.Errorf("disk of workshop-m02 = %q, %v", disk, err)
+	}
+	machineConfig, err := os.ReadFile(filepath.Join(localpath.MachinePath("workshop"), "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := string(mustJSON(t, filepath.Join(localpath.MachinePath("workshop"), "disk.qcow2"))); !strings.Contains(string(machineConfig), want) {
+		t.Errorf("machine config %s does not have the path rewritten to %s", machineConfig, want)
+	}
+
+	if _, err := Import(bytes.NewReader(b.Bytes())); err == nil {
+		t.Errorf("Import of an existing profile: expected an error")
+	}
+}
+
+func TestImportRejectsForeignEntries(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	add := func(name string, content []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(manifestName, mustJSON(t, Manifest{Version: manifestVersion, Profile: "workshop", Driver: driver.QEMU2, Machines: []string{"workshop"}}))
+	add("profiles/workshop/config.json", []byte("{}"))
+	add("machines/workshop/../../../escaped", []byte("x"))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Import(&b); err == nil || !strings.Contains(err.Error(), "invalid bundle entry") {
+		t.Errorf("Import = %v, want an invalid bundle entry error", err)
+	}
+	// the partial import is removed
+	if _, err := os.Stat(localpath.Profile("workshop")); !os.IsNotExist(err) {
+		t.Errorf("profile directory left behind after the failed import: %v", err)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+var (
+	// checkpointDir is where container checkpoints are kept on the node, on the persistent disk so that they survive a restart
+	checkpointDir = path.Join(vmpath.GuestPersistentDir, "checkpoints")
+	// checkpointManifest lists the checkpoints in checkpointDir
+	checkpointManifest = path.Join(checkpointDir, "manifest.json")
+)
+
+// CheckpointedContainer is a container whose state was saved by Checkpoint.
+// Containers are known by name rather than ID, as kubelet recreates them after a restart.
+type CheckpointedContainer struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Dir is the directory on the node the state is saved in
+	Dir string
+}
+
+func (c CheckpointedContainer) String() string {
+	return fmt.Sprintf("%s/%s/%s", c.Namespace, c.Pod, c.Container)
+}
+
+// Checkpoint saves the state of the running containers of the namespaces, or of all namespaces if there are none,
+// replacing the checkpoints saved before
+func Checkpoint(cr cruntime.Manager, r command.Runner, namespaces []string) ([]CheckpointedContainer, error) {
+	ids, err := cruntime.PodContainers(cr, cruntime.Running, namespaces)
+	if err != nil {
+		return nil, errors.Wrap(err, "list running")
+	}
+	if len(ids) == 0 {
+		klog.Warningf("no running containers to checkpoint")
+		return nil, nil
+	}
+	// the stats carry the pod and container names the checkpoints are matched by
+	stats, err := cr.ContainerStats(ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "container names")
+	}
+
+	if _, err := r.RunCmd(exec.Command("sudo", "rm", "-rf", checkpointDir)); err != nil {
+		return nil, errors.Wrap(err, "removing old checkpoints")
+	}
+	checkpointed := []CheckpointedContainer{}
+	for _, s := range stats {
+		c := CheckpointedContainer{Namespace: s.PodNamespace, Pod: s.PodName, Container: s.Name, Dir: path.Join(checkpointDir, s.ID)}
+		if _, err := r.RunCmd(exec.Command("sudo", "mkdir", "-p", c.Dir)); err != nil {
+			return checkpointed, errors.Wrapf(err, "creating %s", c.Dir)
+		}
+		klog.Infof("checkpointing %s (%s) to %s", c, s.ID, c.Dir)
+		if err := cr.CheckpointContainer(s.ID, c.Dir); err != nil {
+			return checkpointed, errors.Wrapf(err, "checkpointing %s", c)
+		}
+		checkpointed = append(checkpointed, c)
+	}
+
+	b, err := json.Marshal(checkpointed)
+	if err != nil {
+		return checkpointed, errors.Wrap(err, "marshal")
+	}
+	manifest := assets.NewMemoryAssetTarget(b, checkpointManifest, "0644")
+	defer manifest.Close()
+	if err := r.Copy(manifest); err != nil {
+		return checkpointed, errors.Wrap(err, "writing the checkpoint manifest")
+	}
+	return checkpointed, nil
+}
+
+// Restore restores the checkpoints saved by Checkpoint into the running containers of the same names.
+// Checkpoints of containers which are not running are skipped.
+func Restore(cr cruntime.Manager, r command.Runner) ([]CheckpointedContainer, error) {
+	rr, err := r.RunCmd(exec.Command("sudo", "cat", checkpointManifest))
+	if err != nil {
+		return nil, errors.Wrap(err, "no checkpoints to restore")
+	}
+	var checkpointed []CheckpointedContainer
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &checkpointed); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", checkpointManifest)
+	}
+
+	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running})
+	if err != nil {
+		return nil, errors.Wrap(err, "list running")
+	}
+	stats, err := cr.ContainerStats(ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "container names")
+	}
+	running := map[CheckpointedContainer]string{}
+	for _, s := range stats {
+		running[CheckpointedContainer{Namespace: s.PodNamespace, Pod: s.PodName, Container: s.Name}] = s.ID
+	}
+
+	restored := []CheckpointedContainer{}
+	for _, c := range checkpointed {
+		id, ok := running[CheckpointedContainer{Namespace: c.Namespace, Pod: c.Pod, Container: c.Container}]
+		if !ok {
+			klog.Warningf("%s is not running, not restoring its checkpoint", c)
+			continue
+		}
+		klog.Infof("restoring %s (%s) from %s", c, id, c.Dir)
+		if err := cr.RestoreContainer(id, c.Dir); err != nil {
+			return restored, errors.Wrapf(err, "restoring %s", c)
+		}
+		restored = append(restored, c)
+	}
+	return restored, nil
+}
@@ -54,7 +54,7 @@ func pause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string
 		return ids, errors.Wrap(err, "kubelet disable --now")
 	}
 
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Running, Namespaces: namespaces})
+	ids, err := cruntime.PodContainers(cr, cruntime.Running, namespaces)
 	if err != nil {
 		return ids, errors.Wrap(err, "list running")
 	}
@@ -91,7 +91,7 @@ func Unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]stri
 
 // unpause unpauses a Kubernetes cluster
 func unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]string, error) {
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces})
+	ids, err := cruntime.PodContainers(cr, cruntime.Paused, namespaces)
 	if err != nil {
 		return ids, errors.Wrap(err, "list paused")
 	}
@@ -117,7 +117,7 @@ func unpause(cr cruntime.Manager, r command.Runner, namespaces []string) ([]stri
 
 // CheckIfPaused checks if the Kubernetes cluster is paused
 func CheckIfPaused(cr cruntime.Manager, namespaces []string) (bool, error) {
-	ids, err := cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused, Namespaces: namespaces})
+	ids, err := cruntime.PodContainers(cr, cruntime.Paused, namespaces)
 	if err != nil {
 		return true, errors.Wrap(err, "list paused")
 	}
@@ -19,8 +19,10 @@ package command
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"strconv"
@@ -85,6 +87,13 @@ type Runner interface {
 	ReadableFile(sourcePath string) (assets.ReadableFile, error)
 }
 
+// SocketDialer is implemented by runners which can connect to unix sockets on the machine they run commands on,
+// so that APIs served over them can be used without shelling out to a client
+type SocketDialer interface {
+	// DialSocket connects to the unix socket at the given path
+	DialSocket(ctx context.Context, socket string) (net.Conn, error)
+}
+
 // Command returns a human readable command string that does not induce eye fatigue
 func (rr RunResult) Command() string {
 	var sb strings.Builder
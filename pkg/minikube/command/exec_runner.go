@@ -18,8 +18,10 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -45,6 +47,12 @@ func NewExecRunner(sudo bool) Runner {
 	return &execRunner{sudo: sudo}
 }
 
+// DialSocket implements the SocketDialer interface, connecting to a unix socket on this machine
+func (e *execRunner) DialSocket(ctx context.Context, socket string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", socket)
+}
+
 // RunCmd implements the Command Runner interface to run a exec.Cmd object
 func (e *execRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
 	rr := &RunResult{Args: cmd.Args}
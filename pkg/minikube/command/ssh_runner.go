@@ -19,8 +19,10 @@ package command
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"os/exec"
 	"path"
 	"strconv"
@@ -140,6 +142,34 @@ func (s *SSHRunner) session() (*ssh.Session, error) {
 	return sess, nil
 }
 
+// DialSocket implements the SocketDialer interface, forwarding a connection to a unix socket on the remote over ssh
+func (s *SSHRunner) DialSocket(ctx context.Context, socket string) (net.Conn, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, errors.Wrap(err, "new client")
+	}
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial("unix", socket)
+		ch <- result{conn, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
 // Remove runs a command to delete a file on the remote.
 func (s *SSHRunner) Remove(f assets.CopyableFile) error {
 	dst := path.Join(f.GetTargetDir(), f.GetTargetName())
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+)
+
+// TranscriptRunner is a Runner which records every command it runs, along with its output, to a file.
+// It is used to collect the provisioning transcript of a node for debugging.
+type TranscriptRunner struct {
+	Runner
+	path string
+	mu   sync.Mutex
+}
+
+// NewTranscriptRunner returns a runner which records the commands run by r to the file at path
+func NewTranscriptRunner(r Runner, path string) *TranscriptRunner {
+	return &TranscriptRunner{Runner: r, path: path}
+}
+
+// RunCmd implements the Command Runner interface to run a exec.Cmd object
+func (t *TranscriptRunner) RunCmd(cmd *exec.Cmd) (*RunResult, error) {
+	start := time.Now()
+	rr, err := t.Runner.RunCmd(cmd)
+	t.recordResult(start, rr, err)
+	return rr, err
+}
+
+// WaitCmd implements the Command Runner interface to wait until a started exec.Cmd object finishes
+func (t *TranscriptRunner) WaitCmd(sc *StartedCmd) (*RunResult, error) {
+	start := time.Now()
+	rr, err := t.Runner.WaitCmd(sc)
+	t.recordResult(start, rr, err)
+	return rr, err
+}
+
+// Copy copies a file and records the transfer
+func (t *TranscriptRunner) Copy(f assets.CopyableFile) error {
+	err := t.Runner.Copy(f)
+	t.record(fmt.Sprintf("copy %s -> %s (%d bytes): %s\n", f.GetSourcePath(), f.GetTargetPath(), f.GetLength(), transcriptStatus(err)))
+	return err
+}
+
+// Remove removes a file and records the removal
+func (t *TranscriptRunner) Remove(f assets.CopyableFile) error {
+	err := t.Runner.Remove(f)
+	t.record(fmt.Sprintf("remove %s: %s\n", f.GetTargetPath(), transcriptStatus(err)))
+	return err
+}
+
+// DialSocket connects to a unix socket if the wrapped runner can, and records the connection
+func (t *TranscriptRunner) DialSocket(ctx context.Context, socket string) (net.Conn, error) {
+	d, ok := t.Runner.(SocketDialer)
+	if !ok {
+		return nil, fmt.Errorf("%T can't dial sockets", t.Runner)
+	}
+	conn, err := d.DialSocket(ctx, socket)
+	t.record(fmt.Sprintf("dial %s: %s\n", socket, transcriptStatus(err)))
+	return conn, err
+}
+
+func (t *TranscriptRunner) recordResult(start time.Time, rr *RunResult, err error) {
+	if rr == nil || len(rr.Args) == 0 {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("$ %s\n", rr.Command()))
+	if out := rr.Output(); out != "" {
+		sb.WriteString(strings.TrimPrefix(out, "\n"))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("exit code %d after %s: %s\n", rr.ExitCode, time.Since(start).Round(time.Millisecond), transcriptStatus(err)))
+	t.record(sb.String())
+}
+
+// record appends an entry to the transcript, opening the file each time so that an interrupted start leaves a complete record
+func (t *TranscriptRunner) record(entry string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		klog.Warningf("unable to open transcript %s: %v", t.path, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(entry + "\n"); err != nil {
+		klog.Warningf("unable to write transcript %s: %v", t.path, err)
+	}
+}
+
+func transcriptStatus(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+)
+
+func TestTranscriptRunner(t *testing.T) {
+	fake := NewFakeCommandRunner()
+	fake.SetCommandToOutput(map[string]string{"portod version": "version: 5.3.31"})
+	path := filepath.Join(t.TempDir(), "transcript.txt")
+	r := NewTranscriptRunner(fake, path)
+
+	if _, err := r.RunCmd(exec.Command("portod", "version")); err != nil {
+		t.Fatalf("RunCmd: %v", err)
+	}
+	if _, err := r.RunCmd(exec.Command("portoctl", "list")); err == nil {
+		t.Fatalf("RunCmd: expected an error for an unregistered command")
+	}
+	if err := r.Copy(assets.NewMemoryAssetTarget([]byte("debug: true"), "/etc/crictl.yaml", "0644")); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading transcript: %v", err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		"$ portod version\n",
+		"version: 5.3.31",
+		"$ portoctl list\n",
+		"unregistered command",
+		"copy memory -> /etc/crictl.yaml (11 bytes): ok",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("transcript does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -109,6 +109,13 @@ type ClusterConfig struct {
 	SSHAgentPID             int
 	AutoPauseInterval       time.Duration // Specifies interval of time to wait before checking if cluster should be paused
 	GPUs                    string
+	RuntimeDebug            bool   // Enables debug logging of the container runtime and records the provisioning transcript
+	PreloadConcurrency      int    // Number of images pulled at once when the runtime has no preload tarball
+	SharedImageCache        bool   // The host image cache is mounted into the node, so porto loads cached images from it without copying
+	RuntimeFeatureGates     string // Comma-separated name=bool pairs enabling experimental container runtime features
+	PostRuntimeHook         string // Script on the host run on each node once the container runtime is enabled
+	WasmShim                string // WebAssembly shim installed into the nodes, with a RuntimeClass of the same name
+	RuntimeClass            string // Sandboxed runtime, such as kata, installed into the nodes with a RuntimeClass of the same name
 }
 
 // KubernetesConfig contains the parameters used to configure the VM Kubernetes.
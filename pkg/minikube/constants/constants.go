@@ -52,6 +52,10 @@ const (
 	APIServerPort = 8443
 	// AutoPauseProxyPort is the port to be used as a reverse proxy for apiserver port
 	AutoPauseProxyPort = 32443
+	// RuntimeHealthPort is the port the runtime-health exporter serves metrics on within the node
+	RuntimeHealthPort = 9256
+	// PortoMetricsPort is the port the porto-metrics exporter serves metrics on within the node
+	PortoMetricsPort = 9257
 
 	// SSHPort is the SSH serviceport on the node vm and container
 	SSHPort = 22
@@ -66,8 +70,16 @@ const (
 	Docker = "docker"
 	// Porto is the default name and spelling for the porto container runtime
 	Porto = "porto"
+	// PortoDockerShim is the addon and systemd unit of the docker API shim serving docker-env on porto nodes
+	PortoDockerShim = "porto-docker-shim"
 	// DefaultContainerRuntime is our default container runtime
 	DefaultContainerRuntime = ""
+	// AutoContainerRuntime is the container runtime value asking minikube to pick the best runtime the setup supports
+	AutoContainerRuntime = "auto"
+	// DefaultPreloadConcurrency is the number of images pulled at once when there is no preload tarball
+	DefaultPreloadConcurrency = 4
+	// DefaultDownloadRetries is the number of times a failed download is retried
+	DefaultDownloadRetries = 3
 
 	// cgroup drivers
 	DefaultCgroupDriver  = "systemd"
@@ -108,6 +120,17 @@ const (
 	// MinikubeActivePodmanEnv holds the podman service that the user's shell is pointing at
 	// value would be profile or empty if pointing to the user's host.
 	MinikubeActivePodmanEnv = "MINIKUBE_ACTIVE_PODMAN"
+	// PortoSocketEnv is the path of the portod socket within the node
+	PortoSocketEnv = "PORTO_SOCKET"
+	// PortoshimSocketEnv is the path of the portoshim socket within the node
+	PortoshimSocketEnv = "PORTOSHIM_SOCKET"
+	// PortoctlEnv is the command running portoctl as root within the node
+	PortoctlEnv = "PORTOCTL"
+	// PortoCrictlEnv is the command running crictl against portoshim as root within the node
+	PortoCrictlEnv = "PORTO_CRICTL"
+	// MinikubeActivePortoEnv holds the porto runtime that the user's shell is pointing at
+	// value would be profile or empty if pointing to the user's host.
+	MinikubeActivePortoEnv = "MINIKUBE_ACTIVE_PORTO"
 	// MinikubeForceSystemdEnv is used to force systemd as cgroup manager for the container runtime
 	MinikubeForceSystemdEnv = "MINIKUBE_FORCE_SYSTEMD"
 	// TestDiskUsedEnv is used in integration tests for insufficient storage with 'minikube status' (in %)
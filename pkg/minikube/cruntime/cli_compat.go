@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// cliOutput is the machine-readable output mode of a command minikube parses, and the first version of the tool having it
+type cliOutput struct {
+	// Tool is the command line tool, whose version is read from "<tool> --version"
+	Tool string
+	// Flags select the machine-readable output, none for commands which only have a human output
+	Flags []string
+	// Since is the first version of Tool supporting Flags
+	Since semver.Version
+}
+
+// cliOutputs is the compatibility matrix of the commands whose human output minikube parses, keyed by command.
+// Their human output changes between releases, so the machine-readable modes are used wherever the installed tool has them.
+// Commands minikube only ever runs in their machine-readable mode, such as crictl images, are not listed.
+var cliOutputs = map[string]cliOutput{
+	"crictl version": {Tool: "crictl", Flags: []string{"--output", "json"}, Since: semver.MustParse("1.22.0")},
+	// the version of portod is read over the porto API first, parsePortoVersion reads the human output otherwise
+	"portod version": {Tool: "portod"},
+	// ParsePortoImages reads the table by its header rather than by position
+	"portoctl docker-images": {Tool: "portoctl"},
+}
+
+// toolVersions caches the versions of the tools of each node, as read by toolVersion, by the unwrapped runner of the node
+var toolVersions = struct {
+	sync.Mutex
+	m map[CommandRunner]map[string]semver.Version
+}{m: map[CommandRunner]map[string]semver.Version{}}
+
+// toolVersionRe matches the version in the output of "<tool> --version", such as "crictl version v1.28.0"
+var toolVersionRe = regexp.MustCompile(`v?(\d+\.\d+\.\d+)`)
+
+// toolVersion returns the version of a command line tool of the node. Only found versions are cached,
+// as the tool may be installed later on, for instance by the provisioning of the node.
+func toolVersion(cr CommandRunner, tool string) (semver.Version, error) {
+	key := unwrapRunner(cr)
+	toolVersions.Lock()
+	defer toolVersions.Unlock()
+	if v, ok := toolVersions.m[key][tool]; ok {
+		return v, nil
+	}
+
+	path := tool
+	if tool == "crictl" {
+		path = getCrictlPath(cr)
+	}
+	rr, err := cr.RunCmd(exec.Command(path, "--version"))
+	if err != nil {
+		return semver.Version{}, errors.Wrapf(err, "%s --version", tool)
+	}
+	m := toolVersionRe.FindStringSubmatch(rr.Stdout.String())
+	if m == nil {
+		return semver.Version{}, fmt.Errorf("unknown %s version: %q", tool, rr.Stdout.String())
+	}
+	v, err := semver.Parse(m[1])
+	if err != nil {
+		return semver.Version{}, errors.Wrapf(err, "parsing %s version", tool)
+	}
+	if toolVersions.m[key] == nil {
+		toolVersions.m[key] = map[string]semver.Version{}
+	}
+	toolVersions.m[key][tool] = v
+	return v, nil
+}
+
+// machineReadable returns the flags selecting the machine-readable output of command, or nil when the tool
+// of the node is too old for them, or its version is unknown, and the human output has to be parsed instead.
+func machineReadable(cr CommandRunner, command string) []string {
+	o, ok := cliOutputs[command]
+	if !ok || len(o.Flags) == 0 {
+		return nil
+	}
+	v, err := toolVersion(cr, o.Tool)
+	if err != nil {
+		klog.Infof("parsing the human output of %s: %v", command, err)
+		return nil
+	}
+	if v.LT(o.Since) {
+		klog.Infof("parsing the human output of %s: %s %s predates %s", command, o.Tool, v, o.Since)
+		return nil
+	}
+	return o.Flags
+}
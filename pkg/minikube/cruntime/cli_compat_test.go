@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestMachineReadable(t *testing.T) {
+	tests := []struct {
+		description string
+		version     string
+		want        []string
+	}{
+		{description: "Supported", version: "crictl version v1.28.0\n", want: []string{"--output", "json"}},
+		{description: "TooOld", version: "crictl version v1.21.0\n", want: nil},
+		{description: "Unknown", version: "crictl version devel\n", want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(map[string]string{"crictl --version": tc.version})
+			if diff := cmp.Diff(tc.want, machineReadable(runner, "crictl version")); diff != "" {
+				t.Errorf("machineReadable diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got := machineReadable(command.NewFakeCommandRunner(), "crictl version"); got != nil {
+		t.Errorf("machineReadable without crictl = %v, want the human output", got)
+	}
+	if got := machineReadable(command.NewFakeCommandRunner(), "portoctl docker-images"); got != nil {
+		t.Errorf("machineReadable(portoctl docker-images) = %v, portoctl has no machine-readable output", got)
+	}
+}
+
+func TestToolVersionBound(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{"crictl --version": "crictl version v1.28.0\n"})
+	if _, err := toolVersion(withContext(context.Background(), runner), "crictl"); err != nil {
+		t.Fatalf("toolVersion: %v", err)
+	}
+	// runners are bound anew for every call, the version read through one of them is the node's
+	runner.SetCommandToOutput(map[string]string{"crictl --version": "crictl version v1.21.0\n"})
+	v, err := toolVersion(withContext(context.Background(), runner), "crictl")
+	if err != nil || v.String() != "1.28.0" {
+		t.Errorf("toolVersion of a rebound runner = %s, %v, want the cached 1.28.0", v, err)
+	}
+}
+
+func TestGetCRIVersion(t *testing.T) {
+	want := criVersion{RuntimeName: "portoshim", RuntimeVersion: "v1.0.11", RuntimeAPIVersion: "v1"}
+
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"crictl --version":                  "crictl version v1.28.0\n",
+		"sudo crictl version --output json": `{"version":"0.1.0","runtimeName":"portoshim","runtimeVersion":"v1.0.11","runtimeApiVersion":"v1"}`,
+	})
+	v, err := getCRIVersion(runner, "/run/portoshim.sock")
+	if err != nil {
+		t.Fatalf("getCRIVersion: %v", err)
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("getCRIVersion with JSON output diff (-want +got):\n%s", diff)
+	}
+
+	runner = command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"crictl --version":    "crictl version v1.19.0\n",
+		"sudo crictl version": "Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  v1.0.11\nRuntimeApiVersion:  v1\n",
+	})
+	v, err = getCRIVersion(runner, "/run/portoshim.sock")
+	if err != nil {
+		t.Fatalf("getCRIVersion: %v", err)
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("getCRIVersion with human output diff (-want +got):\n%s", diff)
+	}
+}
@@ -18,10 +18,12 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
@@ -65,6 +67,14 @@ type Containerd struct {
 	InsecureRegistry  []string
 }
 
+// bind returns a copy of r which runs its commands within ctx
+func (r *Containerd) bind(ctx context.Context) *Containerd {
+	b := *r
+	b.Runner = withContext(ctx, r.Runner)
+	b.Init = sysinit.WithRunner(r.Init, b.Runner)
+	return &b
+}
+
 // Name is a human readable name for containerd
 func (r *Containerd) Name() string {
 	return "containerd"
@@ -92,7 +102,8 @@ func parseContainerdVersion(line string) (string, error) {
 }
 
 // Version retrieves the current version of this runtime
-func (r *Containerd) Version() (string, error) {
+func (r *Containerd) Version(ctx context.Context) (string, error) {
+	r = r.bind(ctx)
 	c := exec.Command("containerd", "--version")
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
@@ -118,6 +129,11 @@ func (r *Containerd) Active() bool {
 	return r.Init.Active("containerd")
 }
 
+// ShouldRestartKubelet returns false, as nothing kubelet reads is in the containerd configuration
+func (r *Containerd) ShouldRestartKubelet(change ConfigChange) bool {
+	return false
+}
+
 // Available returns an error if it is not possible to use this runtime on a host
 func (r *Containerd) Available() error {
 	c := exec.Command("which", "containerd")
@@ -210,7 +226,8 @@ func generateContainerdConfig(cr CommandRunner, imageRepository string, kv semve
 
 // Enable idempotently enables containerd on a host
 // It is also called by docker.Enable() - if bound to containerd, to enforce proper containerd configuration completed by service restart.
-func (r *Containerd) Enable(disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+func (r *Containerd) Enable(ctx context.Context, disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+	r = r.bind(ctx)
 	if inUserNamespace {
 		if err := CheckKernelCompatibility(r.Runner, 5, 11); err != nil {
 			// For using overlayfs
@@ -226,7 +243,7 @@ func (r *Containerd) Enable(disOthers bool, cgroupDriver string, inUserNamespace
 			klog.Warningf("disableOthers: %v", err)
 		}
 	}
-	if err := populateCRIConfig(r.Runner, r.SocketPath()); err != nil {
+	if err := populateCRIConfig(r.Runner, r.SocketPath(), false); err != nil {
 		return err
 	}
 
@@ -242,30 +259,29 @@ func (r *Containerd) Enable(disOthers bool, cgroupDriver string, inUserNamespace
 }
 
 // Disable idempotently disables containerd on a host
-func (r *Containerd) Disable() error {
+func (r *Containerd) Disable(ctx context.Context) error {
+	r = r.bind(ctx)
 	return r.Init.ForceStop("containerd")
 }
 
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *Containerd) ImageExists(name string, sha string) bool {
-	klog.Infof("Checking existence of image with name %q and sha %q", name, sha)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "check")
-	// note: image name and image id's sha can be on different lines in ctr output
-	if rr, err := r.Runner.RunCmd(c); err != nil ||
-		!strings.Contains(rr.Output(), name) ||
-		(sha != "" && !strings.Contains(rr.Output(), sha)) {
-		return false
-	}
-	return true
+	return imageExists(r, name, sha)
+}
+
+// ImageDigest returns the ID of an image based on name
+func (r *Containerd) ImageDigest(name string) (string, error) {
+	return criImageDigest(r.Runner, r.SocketPath(), name)
 }
 
 // ListImages lists images managed by this container runtime
 func (r *Containerd) ListImages(ListImagesOptions) ([]ListImage, error) {
-	return listCRIImages(r.Runner)
+	return listCRIImages(r.Runner, r.SocketPath())
 }
 
 // LoadImage loads an image into this runtime
-func (r *Containerd) LoadImage(path string) error {
+func (r *Containerd) LoadImage(ctx context.Context, path string) error {
+	r = r.bind(ctx)
 	klog.Infof("Loading image: %s", path)
 	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "import", path)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -274,15 +290,36 @@ func (r *Containerd) LoadImage(path string) error {
 	return nil
 }
 
+// LoadImageFromLayout loads the images of an OCI image layout directory on the host into this runtime
+func (r *Containerd) LoadImageFromLayout(ctx context.Context, dir string) error {
+	r = r.bind(ctx)
+	klog.Infof("Loading OCI layout: %s", dir)
+	return loadOCILayout(dir, func(stream io.Reader) error {
+		c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "import", "-")
+		c.Stdin = stream
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrapf(err, "ctr images import")
+		}
+		return nil
+	})
+}
+
 // PullImage pulls an image into this runtime
-func (r *Containerd) PullImage(name string) error {
-	return pullCRIImage(r.Runner, name)
+func (r *Containerd) PullImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	return pullCRIImage(r.Runner, r.SocketPath(), name)
 }
 
 // SaveImage save an image from this runtime
-func (r *Containerd) SaveImage(name string, path string) error {
-	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "export", path, name)
+func (r *Containerd) SaveImage(ctx context.Context, name string, path string) error {
+	return r.SaveImages(ctx, []string{name}, path)
+}
+
+// SaveImages saves images from this runtime into one archive, which docker can load as well
+func (r *Containerd) SaveImages(ctx context.Context, names []string, path string) error {
+	r = r.bind(ctx)
+	klog.Infof("Saving images %s: %s", names, path)
+	c := exec.Command("sudo", append([]string{"ctr", "-n=k8s.io", "images", "export", path}, names...)...)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "ctr images export")
 	}
@@ -290,12 +327,14 @@ func (r *Containerd) SaveImage(name string, path string) error {
 }
 
 // RemoveImage removes a image
-func (r *Containerd) RemoveImage(name string) error {
-	return removeCRIImage(r.Runner, name)
+func (r *Containerd) RemoveImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	return removeCRIImage(r.Runner, r.SocketPath(), name)
 }
 
 // TagImage tags an image in this runtime
-func (r *Containerd) TagImage(source string, target string) error {
+func (r *Containerd) TagImage(ctx context.Context, source string, target string) error {
+	r = r.bind(ctx)
 	klog.Infof("Tagging image %s: %s", source, target)
 	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "tag", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -356,7 +395,8 @@ func downloadRemote(cr CommandRunner, src string) (string, error) {
 }
 
 // BuildImage builds an image into this runtime
-func (r *Containerd) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *Containerd) BuildImage(ctx context.Context, src string, file string, tag string, push bool, env []string, opts []string) error {
+	r = r.bind(ctx)
 	// download url if not already present
 	dir, err := downloadRemote(r.Runner, src)
 	if err != nil {
@@ -408,7 +448,8 @@ func (r *Containerd) BuildImage(src string, file string, tag string, push bool,
 }
 
 // PushImage pushes an image
-func (r *Containerd) PushImage(name string) error {
+func (r *Containerd) PushImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
 	klog.Infof("Pushing image %s", name)
 	c := exec.Command("sudo", "ctr", "-n=k8s.io", "images", "push", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -417,47 +458,106 @@ func (r *Containerd) PushImage(name string) error {
 	return nil
 }
 
-// CGroupDriver returns cgroup driver ("cgroupfs" or "systemd")
-func (r *Containerd) CGroupDriver() (string, error) {
-	info, err := getCRIInfo(r.Runner)
+// containerdInfo is what minikube reads of the configuration containerd reports through crictl info
+type containerdInfo struct {
+	Config struct {
+		Containerd struct {
+			Snapshotter string `json:"snapshotter"`
+			Runtimes    struct {
+				Runc struct {
+					Options struct {
+						SystemdCgroup bool `json:"SystemdCgroup"`
+					} `json:"options"`
+				} `json:"runc"`
+			} `json:"runtimes"`
+		} `json:"containerd"`
+		RootDir string `json:"containerdRootDir"`
+	} `json:"config"`
+}
+
+// parseContainerdInfo extracts containerdInfo from the output of crictl info
+func parseContainerdInfo(info map[string]interface{}) (containerdInfo, error) {
+	var s containerdInfo
+	j, err := json.Marshal(info)
 	if err != nil {
-		return "", err
+		return s, fmt.Errorf("marshalling: %v", err)
 	}
+	if err := json.Unmarshal(j, &s); err != nil {
+		return s, fmt.Errorf("unmarshalling: %v", err)
+	}
+	return s, nil
+}
 
+// cgroupDriver returns the cgroup driver of the runc runtime of containerd
+func (s containerdInfo) cgroupDriver() string {
 	// crictl also returns default ('false') value for "systemdCgroup" - deprecated "systemd_cgroup" config param that is now irrelevant
 	// ref: https://github.com/containerd/containerd/blob/5e7baa2eb3dab4c4365dd63c05ed8b3fa94b9271/pkg/cri/config/config.go#L277-L280
 	// ref: https://github.com/containerd/containerd/issues/4574#issuecomment-1298727099
 	// so, we try to extract runc's "SystemdCgroup" option that we care about
 	// ref: https://github.com/containerd/containerd/issues/4203#issuecomment-651532765
-	j, err := json.Marshal(info)
+	// note: if "path" does not exists, SystemdCgroup will evaluate to false as 'default' value for bool => constants.CgroupfsCgroupDriver
+	if s.Config.Containerd.Runtimes.Runc.Options.SystemdCgroup {
+		return constants.SystemdCgroupDriver
+	}
+	return constants.CgroupfsCgroupDriver
+}
+
+// CGroupDriver returns cgroup driver ("cgroupfs" or "systemd")
+func (r *Containerd) CGroupDriver() (string, error) {
+	info, err := getCRIInfo(r.Runner)
 	if err != nil {
-		return "", fmt.Errorf("marshalling: %v", err)
-	}
-	s := struct {
-		Config struct {
-			Containerd struct {
-				Runtimes struct {
-					Runc struct {
-						Options struct {
-							SystemdCgroup bool `json:"SystemdCgroup"`
-						} `json:"options"`
-					} `json:"runc"`
-				} `json:"runtimes"`
-			} `json:"containerd"`
-		} `json:"config"`
-	}{}
-	if err := json.Unmarshal(j, &s); err != nil {
-		return "", fmt.Errorf("unmarshalling: %v", err)
+		return "", err
 	}
-	// note: if "path" does not exists, SystemdCgroup will evaluate to false as 'default' value for bool => constants.CgroupfsCgroupDriver
-	switch s.Config.Containerd.Runtimes.Runc.Options.SystemdCgroup {
-	case true:
-		return constants.SystemdCgroupDriver, nil
-	case false:
-		return constants.CgroupfsCgroupDriver, nil
-	default:
-		return constants.DefaultCgroupDriver, nil
+	s, err := parseContainerdInfo(info)
+	if err != nil {
+		return "", err
+	}
+	return s.cgroupDriver(), nil
+}
+
+// Capabilities returns the optional features containerd supports, all but checkpoints
+func (r *Containerd) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsBuild:      true,
+		SupportsLoad:       true,
+		SupportsLoadLayout: true,
+		SupportsSave:       true,
+		SupportsPush:       true,
+		SupportsPause:      true,
+		SupportsWasm:       true,
+		SupportsKata:       true,
+	}
+}
+
+// RuntimeInfo describes containerd on the host
+func (r *Containerd) RuntimeInfo() (RuntimeInfo, error) {
+	version, err := r.Version(contextOf(r.Runner))
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	info, err := getCRIInfo(r.Runner)
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	s, err := parseContainerdInfo(info)
+	if err != nil {
+		return RuntimeInfo{}, err
 	}
+	return RuntimeInfo{
+		Name:          r.Name(),
+		Version:       version,
+		Socket:        r.SocketPath(),
+		CgroupDriver:  s.cgroupDriver(),
+		StorageDriver: s.Config.Containerd.Snapshotter,
+		StorageRoot:   s.Config.RootDir,
+		Rootless:      nodeRootless(r.Runner),
+	}, nil
+}
+
+// Healthy returns an error unless containerd answers CRI requests
+func (r *Containerd) Healthy(ctx context.Context) error {
+	r = r.bind(ctx)
+	return criHealthy(r.Runner, r.SocketPath())
 }
 
 // KubeletOptions returns kubelet options for a containerd
@@ -467,7 +567,17 @@ func (r *Containerd) KubeletOptions() map[string]string {
 
 // ListContainers returns a list of managed by this container runtime
 func (r *Containerd) ListContainers(o ListContainersOptions) ([]string, error) {
-	return listCRIContainers(r.Runner, containerdNamespaceRoot, o)
+	return listCRIContainers(r.Runner, r.SocketPath(), containerdNamespaceRoot, o)
+}
+
+// ListPods returns the pod sandboxes of containerd
+func (r *Containerd) ListPods(o ListPodsOptions) ([]Pod, error) {
+	return listCRIPods(r.Runner, r.SocketPath(), o)
+}
+
+// ContainersForPod returns the IDs of the containers in state of a pod sandbox based on ID
+func (r *Containerd) ContainersForPod(id string, state ContainerState) ([]string, error) {
+	return r.ListContainers(ListContainersOptions{State: state, PodIDs: []string{id}})
 }
 
 // PauseContainers pauses a running container based on ID
@@ -482,12 +592,37 @@ func (r *Containerd) UnpauseContainers(ids []string) error {
 
 // KillContainers removes containers based on ID
 func (r *Containerd) KillContainers(ids []string) error {
-	return killCRIContainers(r.Runner, ids)
+	return killCRIContainers(r.Runner, r.SocketPath(), ids)
 }
 
 // StopContainers stops containers based on ID
 func (r *Containerd) StopContainers(ids []string) error {
-	return stopCRIContainers(r.Runner, ids)
+	return stopCRIContainers(r.Runner, r.SocketPath(), ids)
+}
+
+// ContainerStats returns the resource usage of containers based on ID, or of all containers if none are given
+func (r *Containerd) ContainerStats(ids []string) ([]ContainerStats, error) {
+	return criContainerStats(r.Runner, r.SocketPath(), ids)
+}
+
+// Events streams the lifecycle events of the containers until ctx is done
+func (r *Containerd) Events(ctx context.Context) (<-chan ContainerEvent, error) {
+	return criEvents(ctx, r.Runner, r.SocketPath())
+}
+
+// ExecContainer runs a command in a container based on ID
+func (r *Containerd) ExecContainer(id string, cmd []string) (*command.RunResult, error) {
+	return execCRIContainer(r.Runner, r.SocketPath(), id, cmd)
+}
+
+// CheckpointContainer is not supported by containerd
+func (r *Containerd) CheckpointContainer(string, string) error {
+	return ErrCheckpointNotSupported
+}
+
+// RestoreContainer is not supported by containerd
+func (r *Containerd) RestoreContainer(string, string) error {
+	return ErrCheckpointNotSupported
 }
 
 // ContainerLogCmd returns the command to retrieve the log for a container based on ID
@@ -501,7 +636,8 @@ func (r *Containerd) SystemLogCmd(len int) string {
 }
 
 // Preload preloads the container runtime with k8s images
-func (r *Containerd) Preload(cc config.ClusterConfig) error {
+func (r *Containerd) Preload(ctx context.Context, cc config.ClusterConfig) error {
+	r = r.bind(ctx)
 	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
 		return nil
 	}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// contextRunner is a CommandRunner bound to a context: its commands and copies return the error of the context
+// as soon as it is done. The command is not stopped then, see await.
+type contextRunner struct {
+	CommandRunner
+	ctx context.Context
+}
+
+// withContext returns cr bound to ctx
+func withContext(ctx context.Context, cr CommandRunner) CommandRunner {
+	return &contextRunner{CommandRunner: unwrapRunner(cr), ctx: ctx}
+}
+
+// unwrapRunner returns the runner cr was bound to a context by withContext, or cr itself. Whatever is cached per
+// runner is keyed by it, as bound runners are created anew for every call.
+func unwrapRunner(cr CommandRunner) CommandRunner {
+	if c, ok := cr.(*contextRunner); ok {
+		return c.CommandRunner
+	}
+	return cr
+}
+
+// contextOf returns the context cr was bound to by withContext, or the background context
+func contextOf(cr CommandRunner) context.Context {
+	if c, ok := cr.(*contextRunner); ok {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// await returns what f returns, unless ctx is done before or while f runs. f is not interrupted then: runners have no
+// way to stop a command they started, so the goroutine running f lives on until the command exits by itself, on the
+// node for runners of remote machines, and its result is dropped.
+func await[T any](ctx context.Context, what string, f func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, errors.Wrap(err, what)
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		return zero, errors.Wrap(ctx.Err(), what)
+	}
+}
+
+// RunCmd runs cmd, returning early once the context is done
+func (c *contextRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	rr := &command.RunResult{Args: cmd.Args}
+	r, err := await(c.ctx, rr.Command(), func() (*command.RunResult, error) { return c.CommandRunner.RunCmd(cmd) })
+	if r == nil {
+		return rr, err
+	}
+	return r, err
+}
+
+// WaitCmd waits for sc, returning early once the context is done
+func (c *contextRunner) WaitCmd(sc *command.StartedCmd) (*command.RunResult, error) {
+	return await(c.ctx, "wait", func() (*command.RunResult, error) { return c.CommandRunner.WaitCmd(sc) })
+}
+
+// Copy copies f, returning early once the context is done
+func (c *contextRunner) Copy(f assets.CopyableFile) error {
+	_, err := await(c.ctx, fmt.Sprintf("copy %s", f.GetTargetPath()), func() (struct{}, error) { return struct{}{}, c.CommandRunner.Copy(f) })
+	return err
+}
+
+// CopyFrom copies f back, returning early once the context is done
+func (c *contextRunner) CopyFrom(f assets.CopyableFile) error {
+	_, err := await(c.ctx, fmt.Sprintf("copy %s", f.GetTargetPath()), func() (struct{}, error) { return struct{}{}, c.CommandRunner.CopyFrom(f) })
+	return err
+}
+
+// DialSocket dials the socket with the runner bound, if it can, within the context as well as ctx
+func (c *contextRunner) DialSocket(ctx context.Context, socket string) (net.Conn, error) {
+	d, ok := c.CommandRunner.(command.SocketDialer)
+	if !ok {
+		return nil, fmt.Errorf("%T can't dial %s", c.CommandRunner, socket)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+	return d.DialSocket(ctx, socket)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// hangingRunner runs no command to completion before it is released, like a hung portoctl
+type hangingRunner struct {
+	*FakeRunner
+	release chan struct{}
+}
+
+func (h *hangingRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	<-h.release
+	return h.FakeRunner.RunCmd(cmd)
+}
+
+func TestPortoVersionCanceled(t *testing.T) {
+	runner := &hangingRunner{FakeRunner: NewFakeRunner(t), release: make(chan struct{})}
+	defer close(runner.release)
+	r := &Porto{Runner: runner}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Version(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Version: got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Version did not return once its context was done")
+	}
+}
+
+func TestWithContextRebinds(t *testing.T) {
+	runner := NewFakeRunner(t)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bound := withContext(context.Background(), withContext(canceled, runner))
+	if err := contextOf(bound).Err(); err != nil {
+		t.Errorf("rebound runner kept the context it was bound to first: %v", err)
+	}
+	if c, ok := bound.(*contextRunner); !ok || c.CommandRunner != CommandRunner(runner) {
+		t.Errorf("rebound runner wraps %T, want the runner it was bound from", bound)
+	}
+}
@@ -18,18 +18,25 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"os"
 	"os/exec"
 	"path"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/kballard/go-shellquote"
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/util/retry"
 )
 
 // container maps to 'runc list -f json'
@@ -49,6 +56,130 @@ type crictlImages struct {
 	} `json:"images"`
 }
 
+// criVersion is the version information a CRI runtime announces, as printed by 'crictl version'
+type criVersion struct {
+	RuntimeName       string `json:"runtimeName"`
+	RuntimeVersion    string `json:"runtimeVersion"`
+	RuntimeAPIVersion string `json:"runtimeApiVersion"`
+}
+
+// getCRIVersion returns the version information announced by the runtime serving socket
+func getCRIVersion(cr CommandRunner, socket string) (criVersion, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		v, err := c.Version()
+		if err == nil {
+			return v, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	crictl := getCrictlPath(cr)
+	flags := machineReadable(cr, "crictl version")
+	rr, err := cr.RunCmd(exec.Command("sudo", append([]string{crictl, "version"}, flags...)...))
+	if err != nil {
+		return criVersion{}, errors.Wrap(err, "crictl version")
+	}
+	if flags == nil {
+		return parseCRIVersion(rr.Stdout.String()), nil
+	}
+	var v criVersion
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &v); err != nil {
+		return criVersion{}, errors.Wrap(err, "parsing crictl version")
+	}
+	return v, nil
+}
+
+// criHealthy returns an error unless the CRI runtime serving socket answers version requests
+func criHealthy(cr CommandRunner, socket string) error {
+	if _, err := getCRIVersion(cr, socket); err != nil {
+		return errors.Wrapf(err, "no answer to CRI requests on %s", socket)
+	}
+	return nil
+}
+
+// criCondition is a condition of a CRI runtime, as reported by the CRI status and by crictl info
+type criCondition struct {
+	Type    string `json:"type"`
+	Status  bool   `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// criRuntimeReady is the condition a CRI runtime reports once it can run containers
+const criRuntimeReady = "RuntimeReady"
+
+// getCRIConditions returns the conditions the CRI runtime serving socket reports on its status
+func getCRIConditions(cr CommandRunner, socket string) ([]criCondition, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		conds, err := c.Conditions()
+		if err == nil {
+			return conds, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	rr, err := cr.RunCmd(exec.Command("sudo", getCrictlPath(cr), "info", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl info")
+	}
+	var info struct {
+		Status struct {
+			Conditions []criCondition `json:"conditions"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &info); err != nil {
+		return nil, errors.Wrap(err, "parsing crictl info")
+	}
+	return info.Status.Conditions, nil
+}
+
+// CRIReady returns an error unless the CRI runtime serving socket answers version and status requests, reporting
+// itself ready to run containers. Whether the pod network is ready is up to the CNI, which is waited for apart.
+func CRIReady(cr CommandRunner, socket string) error {
+	if err := criHealthy(cr, socket); err != nil {
+		return err
+	}
+	conds, err := getCRIConditions(cr, socket)
+	if err != nil {
+		return errors.Wrapf(err, "no answer to CRI status requests on %s", socket)
+	}
+	for _, c := range conds {
+		if c.Type != criRuntimeReady {
+			continue
+		}
+		if !c.Status {
+			return errors.Errorf("runtime on %s is not ready: %s %s", socket, c.Reason, c.Message)
+		}
+		return nil
+	}
+	return errors.Errorf("runtime on %s does not report the %s condition", socket, criRuntimeReady)
+}
+
+// parseCRIVersion parses the output of 'crictl version'
+func parseCRIVersion(s string) criVersion {
+	// Version:  0.1.0
+	// RuntimeName:  portoshim
+	// RuntimeVersion:  v1.0.11
+	// RuntimeApiVersion:  v1
+	var v criVersion
+	for _, line := range strings.Split(s, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "RuntimeName":
+			v.RuntimeName = value
+		case "RuntimeVersion":
+			v.RuntimeVersion = value
+		case "RuntimeApiVersion":
+			v.RuntimeAPIVersion = value
+		}
+	}
+	return v
+}
+
 // crictlList returns the output of 'crictl ps' in an efficient manner
 func crictlList(cr CommandRunner, root string, o ListContainersOptions) (*command.RunResult, error) {
 	klog.Infof("listing CRI containers in root %s: %+v", root, o)
@@ -60,32 +191,61 @@ func crictlList(cr CommandRunner, root string, o ListContainersOptions) (*comman
 		baseCmd = append(baseCmd, fmt.Sprintf("--name=%s", o.Name))
 	}
 
-	// shortcut for all namespaces
-	if len(o.Namespaces) == 0 {
+	// shortcut for all namespaces and pods
+	if len(o.Namespaces) == 0 && len(o.PodIDs) == 0 {
 		return cr.RunCmd(exec.Command("sudo", baseCmd...))
 	}
 
-	// Gather containers for all namespaces without causing extraneous shells to be launched
-	cmds := []string{}
-	for _, ns := range o.Namespaces {
-		cmd := fmt.Sprintf("%s --label io.kubernetes.pod.namespace=%s", strings.Join(baseCmd, " "), ns)
-		cmds = append(cmds, cmd)
+	// Gather containers for all namespaces and pods without causing extraneous shells to be launched
+	cmds := []string{strings.Join(baseCmd, " ")}
+	if len(o.Namespaces) > 0 {
+		var nsCmds []string
+		for _, ns := range o.Namespaces {
+			nsCmds = append(nsCmds, fmt.Sprintf("%s --label io.kubernetes.pod.namespace=%s", cmds[0], ns))
+		}
+		cmds = nsCmds
+	}
+	if len(o.PodIDs) > 0 {
+		var podCmds []string
+		for _, cmd := range cmds {
+			for _, id := range o.PodIDs {
+				podCmds = append(podCmds, fmt.Sprintf("%s --pod=%s", cmd, id))
+			}
+		}
+		cmds = podCmds
 	}
 
 	return cr.RunCmd(exec.Command("sudo", "-s", "eval", strings.Join(cmds, "; ")))
 }
 
-// listCRIContainers returns a list of containers
-func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) ([]string, error) {
+// criContainerIDs returns the ids of all containers matching the name, namespaces and pods of o, whatever their state
+func criContainerIDs(cr CommandRunner, socket string, root string, o ListContainersOptions) ([]string, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		ids, err := c.ListContainers(o)
+		if err == nil {
+			return ids, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
 	rr, err := crictlList(cr, root, o)
 	if err != nil {
 		return nil, errors.Wrap(err, "crictl list")
 	}
+	return strings.Split(rr.Stdout.String(), "\n"), nil
+}
+
+// listCRIContainers returns a list of containers
+func listCRIContainers(cr CommandRunner, socket string, root string, o ListContainersOptions) ([]string, error) {
+	found, err := criContainerIDs(cr, socket, root, o)
+	if err != nil {
+		return nil, err
+	}
 
 	// Avoid an id named ""
 	var ids []string
 	seen := map[string]bool{}
-	for _, id := range strings.Split(rr.Stdout.String(), "\n") {
+	for _, id := range found {
 		klog.Infof("found id: %q", id)
 		if id != "" && !seen[id] {
 			ids = append(ids, id)
@@ -108,7 +268,7 @@ func listCRIContainers(cr CommandRunner, root string, o ListContainersOptions) (
 	}
 
 	args = append(args, "list", "-f", "json")
-	rr, err = cr.RunCmd(exec.Command("sudo", args...))
+	rr, err := cr.RunCmd(exec.Command("sudo", args...))
 	if err != nil {
 		return nil, errors.Wrap(err, "runc")
 	}
@@ -184,12 +344,19 @@ func unpauseCRIContainers(cr CommandRunner, root string, ids []string) error {
 	return nil
 }
 
-// criCRIContainers kills a list of containers using crictl
-func killCRIContainers(cr CommandRunner, ids []string) error {
+// killCRIContainers kills a list of containers over the CRI socket, or using crictl
+func killCRIContainers(cr CommandRunner, socket string, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
 	klog.Infof("Killing containers: %s", ids)
+	if c, ok := criClientFor(cr, socket); ok {
+		err := c.RemoveContainers(ids)
+		if err == nil {
+			return nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
 
 	crictl := getCrictlPath(cr)
 	args := append([]string{crictl, "rm", "--force"}, ids...)
@@ -200,9 +367,220 @@ func killCRIContainers(cr CommandRunner, ids []string) error {
 	return nil
 }
 
-// pullCRIImage pulls image using crictl
-func pullCRIImage(cr CommandRunner, name string) error {
+// execCRIContainer runs a command in a container over the CRI socket, or using crictl
+func execCRIContainer(cr CommandRunner, socket string, id string, cmd []string) (*command.RunResult, error) {
+	klog.Infof("Running %v in container %s", cmd, id)
+	if c, ok := criClientFor(cr, socket); ok {
+		rr, err := c.ExecSync(id, cmd)
+		if err == nil {
+			if rr.ExitCode != 0 {
+				return rr, fmt.Errorf("%v in container %s: exit status %d", cmd, id, rr.ExitCode)
+			}
+			return rr, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	crictl := getCrictlPath(cr)
+	args := append([]string{crictl, "exec", id}, cmd...)
+	rr, err := cr.RunCmd(exec.Command("sudo", args...))
+	if err != nil {
+		return rr, errors.Wrap(err, "crictl exec")
+	}
+	return rr, nil
+}
+
+// crictlContainerList is the output of 'crictl ps --output json'
+type crictlContainerList struct {
+	Containers []struct {
+		ID           string `json:"id"`
+		PodSandboxID string `json:"podSandboxId"`
+		Metadata     struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	} `json:"containers"`
+}
+
+// PodContainer returns the ID of the running container named container in the pod namespace/pod, or of the only running
+// container of the pod when container is empty. It returns ErrPodNotFound when the node does not run the pod.
+func PodContainer(cr CommandRunner, namespace, pod, container string) (string, error) {
+	crictl := getCrictlPath(cr)
+	// the filters are regular expressions, anchored to match the names only
+	rr, err := cr.RunCmd(exec.Command("sudo", crictl, "pods", "--namespace", "^"+namespace+"$", "--name", "^"+pod+"$", "--state", "ready", "--quiet"))
+	if err != nil {
+		return "", errors.Wrap(err, "crictl pods")
+	}
+	pods := strings.Fields(rr.Stdout.String())
+	if len(pods) == 0 {
+		return "", ErrPodNotFound
+	}
+
+	args := []string{crictl, "ps", "--pod", pods[0], "--state", "running", "--output", "json"}
+	if container != "" {
+		args = append(args, "--name", "^"+container+"$")
+	}
+	rr, err = cr.RunCmd(exec.Command("sudo", args...))
+	if err != nil {
+		return "", errors.Wrap(err, "crictl ps")
+	}
+	var list crictlContainerList
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &list); err != nil {
+		return "", errors.Wrap(err, "parsing crictl ps")
+	}
+	switch len(list.Containers) {
+	case 0:
+		if container != "" {
+			return "", fmt.Errorf("pod %s/%s has no running container %q", namespace, pod, container)
+		}
+		return "", fmt.Errorf("pod %s/%s has no running containers", namespace, pod)
+	case 1:
+		return list.Containers[0].ID, nil
+	}
+	var names []string
+	for _, c := range list.Containers {
+		names = append(names, c.Metadata.Name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("pod %s/%s runs several containers, choose one of: %s", namespace, pod, strings.Join(names, ", "))
+}
+
+// crictlPodList is the output of 'crictl pods --output json'
+type crictlPodList struct {
+	Items []struct {
+		ID       string `json:"id"`
+		Metadata struct {
+			Name      string `json:"name"`
+			UID       string `json:"uid"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		State string `json:"state"`
+	} `json:"items"`
+}
+
+// listCRIPods returns the pod sandboxes of the namespaces of o, over the CRI socket or using crictl
+func listCRIPods(cr CommandRunner, socket string, o ListPodsOptions) ([]Pod, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		pods, err := c.ListPods(o)
+		if err == nil {
+			return pods, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	crictl := getCrictlPath(cr)
+	namespaces := o.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	var pods []Pod
+	for _, ns := range namespaces {
+		args := []string{crictl, "pods", "--output", "json"}
+		if ns != "" {
+			// the filter is a regular expression, anchored to match the name only
+			args = append(args, "--namespace", "^"+ns+"$")
+		}
+		if o.Ready {
+			args = append(args, "--state", "ready")
+		}
+		rr, err := cr.RunCmd(exec.Command("sudo", args...))
+		if err != nil {
+			return nil, errors.Wrap(err, "crictl pods")
+		}
+		var list crictlPodList
+		if err := json.Unmarshal(rr.Stdout.Bytes(), &list); err != nil {
+			return nil, errors.Wrap(err, "parsing crictl pods")
+		}
+		for _, p := range list.Items {
+			pods = append(pods, Pod{
+				ID:        p.ID,
+				Name:      p.Metadata.Name,
+				Namespace: p.Metadata.Namespace,
+				UID:       p.Metadata.UID,
+				Ready:     p.State == "SANDBOX_READY",
+			})
+		}
+	}
+	return pods, nil
+}
+
+// CRIPod is the state of a pod as the CRI of the node reports it
+type CRIPod struct {
+	Name string
+	// Ready is whether any sandbox of the pod is ready
+	Ready bool
+	// Running is the number of running containers across the sandboxes of the pod
+	Running int
+}
+
+// CRIPods returns the pods of namespace the CRI of the node runs, by pod UID. A pod restarted by the kubelet
+// may have several sandboxes, which are merged.
+func CRIPods(cr CommandRunner, namespace string) (map[string]CRIPod, error) {
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(exec.Command("sudo", crictl, "pods", "--namespace", "^"+namespace+"$", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl pods")
+	}
+	var sandboxes crictlPodList
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &sandboxes); err != nil {
+		return nil, errors.Wrap(err, "parsing crictl pods")
+	}
+
+	pods := map[string]CRIPod{}
+	uids := map[string]string{}
+	for _, s := range sandboxes.Items {
+		uids[s.ID] = s.Metadata.UID
+		p := pods[s.Metadata.UID]
+		p.Name = s.Metadata.Name
+		p.Ready = p.Ready || s.State == "SANDBOX_READY"
+		pods[s.Metadata.UID] = p
+	}
+	if len(pods) == 0 {
+		return pods, nil
+	}
+
+	rr, err = cr.RunCmd(exec.Command("sudo", crictl, "ps", "--state", "running", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl ps")
+	}
+	var containers crictlContainerList
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &containers); err != nil {
+		return nil, errors.Wrap(err, "parsing crictl ps")
+	}
+	for _, c := range containers.Containers {
+		uid, ok := uids[c.PodSandboxID]
+		if !ok {
+			continue
+		}
+		p := pods[uid]
+		p.Running++
+		pods[uid] = p
+	}
+	return pods, nil
+}
+
+// CopyIntoContainer copies the file or directory src on the node into the directory dstDir of the running container id.
+// It is streamed as a tarball through crictl exec, so the container needs tar, but no volume shared with the node.
+func CopyIntoContainer(cr CommandRunner, id, src, dstDir string) error {
+	klog.Infof("Copying %s into %s of container %s", src, dstDir, id)
+	crictl := getCrictlPath(cr)
+	c := fmt.Sprintf("tar -C %s -cf - %s | %s exec -i %s tar -xf - -C %s",
+		shellquote.Join(path.Dir(src)), shellquote.Join(path.Base(src)), crictl, id, shellquote.Join(dstDir))
+	if _, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrapf(err, "copying into container %s", id)
+	}
+	return nil
+}
+
+// pullCRIImage pulls image over the CRI socket, or using crictl
+func pullCRIImage(cr CommandRunner, socket string, name string) error {
 	klog.Infof("Pulling image: %s", name)
+	if c, ok := criClientFor(cr, socket); ok {
+		err := c.PullImage(name)
+		if err == nil {
+			return nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
 
 	crictl := getCrictlPath(cr)
 	args := append([]string{crictl, "pull"}, name)
@@ -213,9 +591,16 @@ func pullCRIImage(cr CommandRunner, name string) error {
 	return nil
 }
 
-// removeCRIImage remove image using crictl
-func removeCRIImage(cr CommandRunner, name string) error {
+// removeCRIImage remove image over the CRI socket, or using crictl
+func removeCRIImage(cr CommandRunner, socket string, name string) error {
 	klog.Infof("Removing image: %s", name)
+	if c, ok := criClientFor(cr, socket); ok {
+		err := c.RemoveImage(name)
+		if err == nil {
+			return nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
 
 	crictl := getCrictlPath(cr)
 	args := append([]string{crictl, "rmi"}, name)
@@ -226,12 +611,19 @@ func removeCRIImage(cr CommandRunner, name string) error {
 	return nil
 }
 
-// stopCRIContainers stops containers using crictl
-func stopCRIContainers(cr CommandRunner, ids []string) error {
+// stopCRIContainers stops containers over the CRI socket, or using crictl
+func stopCRIContainers(cr CommandRunner, socket string, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
 	klog.Infof("Stopping containers: %s", ids)
+	if c, ok := criClientFor(cr, socket); ok {
+		err := c.StopContainers(ids)
+		if err == nil {
+			return nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
 
 	crictl := getCrictlPath(cr)
 	// bring crictl stop timeout on par with docker:
@@ -246,26 +638,233 @@ func stopCRIContainers(cr CommandRunner, ids []string) error {
 	return nil
 }
 
-// populateCRIConfig sets up /etc/crictl.yaml
-func populateCRIConfig(cr CommandRunner, socket string) error {
-	cPath := "/etc/crictl.yaml"
-	tmpl := "runtime-endpoint: unix://{{.Socket}}\n"
+// crictlStats is the output of 'crictl stats --output json', in which 64 bit integers are strings
+type crictlStats struct {
+	Stats []struct {
+		Attributes struct {
+			ID       string `json:"id"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Labels map[string]string `json:"labels"`
+		} `json:"attributes"`
+		CPU struct {
+			Timestamp            json.Number `json:"timestamp"`
+			UsageCoreNanoSeconds struct {
+				Value json.Number `json:"value"`
+			} `json:"usageCoreNanoSeconds"`
+			UsageNanoCores struct {
+				Value json.Number `json:"value"`
+			} `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Memory struct {
+			WorkingSetBytes struct {
+				Value json.Number `json:"value"`
+			} `json:"workingSetBytes"`
+		} `json:"memory"`
+		WritableLayer struct {
+			UsedBytes struct {
+				Value json.Number `json:"value"`
+			} `json:"usedBytes"`
+		} `json:"writableLayer"`
+	} `json:"stats"`
+}
+
+// parseCrictlStats parses the output of 'crictl stats --output json'
+func parseCrictlStats(b []byte) ([]ContainerStats, error) {
+	var cs crictlStats
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return nil, err
+	}
+	num := func(n json.Number) uint64 {
+		v, _ := strconv.ParseUint(string(n), 10, 64)
+		return v
+	}
+	stats := []ContainerStats{}
+	for _, s := range cs.Stats {
+		stats = append(stats, ContainerStats{
+			ID:                      s.Attributes.ID,
+			Name:                    s.Attributes.Metadata.Name,
+			PodName:                 s.Attributes.Labels[criPodNameLabel],
+			PodNamespace:            s.Attributes.Labels[criPodNamespaceLabel],
+			Timestamp:               time.Unix(0, int64(num(s.CPU.Timestamp))),
+			CPUUsageNanoCores:       num(s.CPU.UsageNanoCores.Value),
+			CPUUsageCoreNanoSeconds: num(s.CPU.UsageCoreNanoSeconds.Value),
+			MemoryWorkingSetBytes:   num(s.Memory.WorkingSetBytes.Value),
+			WritableLayerBytes:      num(s.WritableLayer.UsedBytes.Value),
+		})
+	}
+	return stats, nil
+}
+
+// filterContainerStats returns the stats of the containers with the given ids, or all stats if there are none
+func filterContainerStats(stats []ContainerStats, ids []string) []ContainerStats {
+	if len(ids) == 0 {
+		return stats
+	}
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	filtered := []ContainerStats{}
+	for _, s := range stats {
+		if want[s.ID] {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// criContainerStats returns the resource usage of containers over the CRI socket, or using crictl
+func criContainerStats(cr CommandRunner, socket string, ids []string) ([]ContainerStats, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		stats, err := c.ContainerStats(ids)
+		if err == nil {
+			return stats, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	crictl := getCrictlPath(cr)
+	rr, err := cr.RunCmd(exec.Command("sudo", crictl, "stats", "--output", "json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "crictl stats")
+	}
+	stats, err := parseCrictlStats(rr.Stdout.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing crictl stats")
+	}
+	return filterContainerStats(stats, ids), nil
+}
+
+// criEvents streams the container events of the CRI runtime serving socket until ctx is done.
+// crictl can't stream them, so the runner has to be able to dial the socket.
+func criEvents(ctx context.Context, cr CommandRunner, socket string) (<-chan ContainerEvent, error) {
+	c, ok := criClientFor(cr, socket)
+	if !ok {
+		return nil, errors.Errorf("unable to reach %s to stream container events", socket)
+	}
+	events := make(chan ContainerEvent)
+	go func() {
+		defer close(events)
+		if err := c.ContainerEvents(ctx, events); err != nil && ctx.Err() == nil {
+			klog.Warningf("container events stopped: %v", err)
+		}
+	}()
+	return events, nil
+}
+
+// criConfigPath is the configuration of crictl, telling it the socket of the runtime
+const criConfigPath = "/etc/crictl.yaml"
+
+// crictlConfig returns the content of criConfigPath for the CRI socket, debug makes crictl log the CRI requests it sends
+func crictlConfig(socket string, debug bool) (string, error) {
+	tmpl := "runtime-endpoint: unix://{{.Socket}}\n{{if .Debug}}debug: true\n{{end}}"
 	t, err := template.New("crictl").Parse(tmpl)
 	if err != nil {
-		return err
+		return "", err
 	}
-	opts := struct{ Socket string }{Socket: socket}
+	opts := struct {
+		Socket string
+		Debug  bool
+	}{Socket: socket, Debug: debug}
 	var b bytes.Buffer
 	if err := t.Execute(&b, opts); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// populateCRIConfig sets up /etc/crictl.yaml, debug makes crictl log the CRI requests it sends
+func populateCRIConfig(cr CommandRunner, socket string, debug bool) error {
+	content, err := crictlConfig(socket, debug)
+	if err != nil {
 		return err
 	}
-	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo mkdir -p %s && printf %%s \"%s\" | sudo tee %s", path.Dir(cPath), b.String(), cPath))
+	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo mkdir -p %s && printf %%s \"%s\" | sudo tee %s", path.Dir(criConfigPath), content, criConfigPath))
 	if rr, err := cr.RunCmd(c); err != nil {
 		return errors.Wrapf(err, "Run: %q", rr.Command())
 	}
 	return nil
 }
 
+const (
+	// criConfigAttempts is how many times populateCRIConfigChecked writes criConfigPath before giving up
+	criConfigAttempts = 5
+	// firstBootWait bounds how long populateCRIConfigChecked waits for the first boot of the node to finish
+	firstBootWait = 2 * time.Minute
+)
+
+// criConfigRetryInterval is how long populateCRIConfigChecked waits before writing criConfigPath again
+var criConfigRetryInterval = time.Second
+
+// waitForFirstBoot waits for the boot of the node to finish, so that the units generating default configurations on
+// the first boot, which packages may ship, are done before minikube writes its own. It gives up after firstBootWait,
+// and nodes without systemd have nothing to wait for.
+func waitForFirstBoot(cr CommandRunner) {
+	// is-system-running exits non-zero for a degraded system, which is booted all the same
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("timeout %d systemctl is-system-running --wait 2>/dev/null; true", int(firstBootWait.Seconds()))))
+	if err != nil {
+		klog.Warningf("unable to wait for the boot to finish: %v", err)
+		return
+	}
+	switch state := strings.TrimSpace(rr.Stdout.String()); state {
+	case "initializing", "starting":
+		klog.Warningf("the node is still booting after %s (%s), writing %s anyway", firstBootWait, state, criConfigPath)
+	default:
+		klog.Infof("node boot state: %q", state)
+	}
+}
+
+// checkCRIConfig returns an error unless criConfigPath holds exactly content, and crictl reads the socket from it
+func checkCRIConfig(cr CommandRunner, content, socket string) error {
+	rr, err := cr.RunCmd(exec.Command("sudo", "cat", criConfigPath))
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", criConfigPath)
+	}
+	if got := rr.Stdout.String(); got != content {
+		return fmt.Errorf("%s was changed while it was written: %q", criConfigPath, got)
+	}
+	crictl := getCrictlPath(cr)
+	rr, err = cr.RunCmd(exec.Command("sudo", crictl, "config", "--get", "runtime-endpoint"))
+	if err != nil {
+		return errors.Wrapf(err, "crictl config")
+	}
+	if got, want := strings.TrimSpace(rr.Stdout.String()), "unix://"+socket; got != want {
+		return fmt.Errorf("crictl reads the runtime endpoint %q from %s, not %q", got, criConfigPath, want)
+	}
+	return nil
+}
+
+// populateCRIConfigChecked sets up /etc/crictl.yaml like populateCRIConfig for a runtime whose packages may generate
+// a default one on the first boot, which would get mixed with it when both are written at once. It waits for the
+// first boot to finish, regenerates the whole file from the template, replacing it by a rename, and checks it with
+// crictl afterwards, writing it again until it is right for up to criConfigAttempts times.
+func populateCRIConfigChecked(cr CommandRunner, socket string, debug bool) error {
+	content, err := crictlConfig(socket, debug)
+	if err != nil {
+		return err
+	}
+	waitForFirstBoot(cr)
+
+	tmp := criConfigPath + ".minikube"
+	write := func() error {
+		c := exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("mkdir -p %s && printf %%s %s > %s && mv -f %s %s", path.Dir(criConfigPath), shellquote.Join(content), tmp, tmp, criConfigPath))
+		if _, err := cr.RunCmd(c); err != nil {
+			return errors.Wrapf(err, "writing %s", criConfigPath)
+		}
+		if err := checkCRIConfig(cr, content, socket); err != nil {
+			klog.Warningf("%s is not right, writing it again: %v", criConfigPath, err)
+			return err
+		}
+		return nil
+	}
+	if err := retry.Expo(write, criConfigRetryInterval, firstBootWait, criConfigAttempts-1); err != nil {
+		return errors.Wrapf(err, "%s is not right after %d attempts", criConfigPath, criConfigAttempts)
+	}
+	return nil
+}
+
 // getCRIInfo returns current information
 func getCRIInfo(cr CommandRunner) (map[string]interface{}, error) {
 	args := []string{"crictl", "info"}
@@ -283,8 +882,16 @@ func getCRIInfo(cr CommandRunner) (map[string]interface{}, error) {
 	return jsonMap, nil
 }
 
-// listCRIImages lists images using crictl
-func listCRIImages(cr CommandRunner) ([]ListImage, error) {
+// listCRIImages lists images over the CRI socket, or using crictl
+func listCRIImages(cr CommandRunner, socket string) ([]ListImage, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		images, err := c.ListImages()
+		if err == nil {
+			return images, nil
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
 	c := exec.Command("sudo", "crictl", "images", "--output", "json")
 	rr, err := cr.RunCmd(c)
 	if err != nil {
@@ -310,6 +917,34 @@ func listCRIImages(cr CommandRunner) ([]ListImage, error) {
 	return images, nil
 }
 
+// criImageDigest returns the ID of an image, asking over the CRI socket or using crictl
+func criImageDigest(cr CommandRunner, socket string, name string) (string, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		id, err := c.ImageID(name)
+		if err == nil || errors.Is(err, ErrImageNotFound) {
+			return id, err
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	rr, err := cr.RunCmd(exec.Command("sudo", getCrictlPath(cr), "inspecti", "--output", "json", name))
+	if err != nil {
+		if strings.Contains(rr.Stderr.String(), "no such image") {
+			return "", errors.Wrap(ErrImageNotFound, name)
+		}
+		return "", errors.Wrapf(err, "crictl inspecti")
+	}
+	var status struct {
+		Status struct {
+			ID string `json:"id"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &status); err != nil {
+		return "", errors.Wrap(err, "parsing crictl inspecti")
+	}
+	return status.Status.ID, nil
+}
+
 // criContainerLogCmd returns the command to retrieve the log for a container based on ID
 func criContainerLogCmd(cr CommandRunner, id string, len int, follow bool) string {
 	crictl := getCrictlPath(cr)
@@ -351,10 +986,75 @@ func kubeletCRIOptions(cr Manager, kubernetesVersion semver.Version) map[string]
 	return opts
 }
 
+// cniBinDir is where the runtimes and kubelet look for CNI plugin binaries
+const cniBinDir = "/opt/cni/bin"
+
+// cniPlugins are the CNI plugins the network configurations written by minikube use,
+// with the CNI spec version each of them is configured with
+var cniPlugins = []struct {
+	name    string
+	version string
+}{
+	{"bridge", "0.3.1"},
+	{"host-local", "0.3.1"},
+	{"loopback", "1.0.0"},
+	{"portmap", "0.3.1"},
+}
+
+// cniVersionInfo maps to the output of a CNI plugin run with CNI_COMMAND=VERSION
+type cniVersionInfo struct {
+	SupportedVersions []string `json:"supportedVersions"`
+}
+
 func checkCNIPlugins(kubernetesVersion semver.Version) error {
 	if kubernetesVersion.LT(semver.Version{Major: 1, Minor: 24}) {
 		return nil
 	}
-	_, err := os.Stat("/opt/cni/bin")
+	_, err := os.Stat(cniBinDir)
 	return err
 }
+
+// checkPortoCNIPlugins checks the node has the CNI plugins minikube configures, in versions that support its configurations.
+// portoshim sets up the network of pods with them itself, failing only once pods start, so porto checks them up front.
+// The error lists every missing or incompatible plugin.
+func checkPortoCNIPlugins(cr CommandRunner, kubernetesVersion semver.Version) error {
+	if kubernetesVersion.LT(semver.Version{Major: 1, Minor: 24}) {
+		return nil
+	}
+
+	rr, err := cr.RunCmd(exec.Command("ls", "-1", cniBinDir))
+	if err != nil {
+		return errors.Wrapf(err, "CNI plugins directory %s is missing", cniBinDir)
+	}
+	present := map[string]bool{}
+	for _, name := range strings.Fields(rr.Stdout.String()) {
+		present[name] = true
+	}
+
+	var problems []string
+	for _, p := range cniPlugins {
+		if !present[p.name] {
+			problems = append(problems, fmt.Sprintf("%s: missing", p.name))
+			continue
+		}
+		rr, err := cr.RunCmd(exec.Command("env", "CNI_COMMAND=VERSION", path.Join(cniBinDir, p.name)))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: version check failed: %v", p.name, err))
+			continue
+		}
+		var info cniVersionInfo
+		if err := json.Unmarshal(rr.Stdout.Bytes(), &info); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: unable to parse version %q: %v", p.name, strings.TrimSpace(rr.Stdout.String()), err))
+			continue
+		}
+		if !slices.Contains(info.SupportedVersions, p.version) {
+			problems = append(problems, fmt.Sprintf("%s: CNI spec %s not supported, only %s", p.name, p.version, strings.Join(info.SupportedVersions, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("CNI plugins in %s are unusable: %s", cniBinDir, strings.Join(problems, "; "))
+	}
+
+	klog.Infof("CNI plugins in %s are usable", cniBinDir)
+	return nil
+}
@@ -0,0 +1,443 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+const (
+	// criTimeout bounds the calls made to a CRI runtime over its socket
+	criTimeout = 10 * time.Second
+	// criPullTimeout bounds image pulls, which take as long as the registry needs
+	criPullTimeout = 15 * time.Minute
+	// criStopTimeout is how many seconds containers get to stop gracefully, on par with 'docker stop'
+	criStopTimeout = 10
+	// criExecTimeout bounds commands run in containers, which the runtime lets run for as long as they like
+	criExecTimeout = time.Hour
+)
+
+// Labels kubelet puts on the containers it creates
+const (
+	criPodNameLabel      = "io.kubernetes.pod.name"
+	criPodNamespaceLabel = "io.kubernetes.pod.namespace"
+)
+
+// criClient talks to a CRI runtime over gRPC, instead of running crictl for every call
+type criClient struct {
+	socket  string
+	runtime runtimeapi.RuntimeServiceClient
+	image   runtimeapi.ImageServiceClient
+	// ctx is the context of the runner the client was asked for, which calls give up with
+	ctx context.Context
+}
+
+// criClientKey identifies the socket of a CRI runtime on the node a runner runs commands on
+type criClientKey struct {
+	dialer command.SocketDialer
+	socket string
+}
+
+// criClients are the clients created so far. Every client multiplexes all calls over one connection,
+// which is kept open for the lifetime of the process and re-established by gRPC if it breaks.
+var criClients = struct {
+	sync.Mutex
+	m map[criClientKey]*criClient
+}{m: map[criClientKey]*criClient{}}
+
+// criClientFor returns the client of the CRI runtime serving socket, and false if the runner can't dial sockets.
+// Callers fall back to crictl in that case, and when a call fails, as the socket may not be accessible
+// to the user the runner connects as. Calls of the client give up once the context the runner is bound to is done.
+func criClientFor(runner CommandRunner, socket string) (*criClient, bool) {
+	ctx := contextOf(runner)
+	// the connection outlives the context, so it is keyed and dialed with the runner the context was bound to
+	d, ok := unwrapRunner(runner).(command.SocketDialer)
+	if !ok || socket == "" {
+		return nil, false
+	}
+
+	criClients.Lock()
+	defer criClients.Unlock()
+	key := criClientKey{dialer: d, socket: socket}
+	if c, ok := criClients.m[key]; ok {
+		return c.within(ctx), true
+	}
+	// dialing is lazy, errors surface on the first call
+	conn, err := grpc.Dial("passthrough:///"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return d.DialSocket(ctx, socket)
+		}))
+	if err != nil {
+		klog.Warningf("unable to create a CRI client for %s: %v", socket, err)
+		return nil, false
+	}
+	c := &criClient{
+		socket:  socket,
+		runtime: runtimeapi.NewRuntimeServiceClient(conn),
+		image:   runtimeapi.NewImageServiceClient(conn),
+	}
+	criClients.m[key] = c
+	return c.within(ctx), true
+}
+
+// within returns a copy of c whose calls give up once ctx is done
+func (c *criClient) within(ctx context.Context) *criClient {
+	b := *c
+	b.ctx = ctx
+	return &b
+}
+
+// Version returns the version information announced by the runtime
+func (c *criClient) Version() (criVersion, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.runtime.Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		return criVersion{}, errors.Wrapf(err, "CRI version over %s", c.socket)
+	}
+	return criVersion{RuntimeName: resp.RuntimeName, RuntimeVersion: resp.RuntimeVersion, RuntimeAPIVersion: resp.RuntimeApiVersion}, nil
+}
+
+// Conditions returns the conditions the runtime reports on its status
+func (c *criClient) Conditions() ([]criCondition, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.runtime.Status(ctx, &runtimeapi.StatusRequest{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "CRI status over %s", c.socket)
+	}
+	conds := []criCondition{}
+	for _, rc := range resp.GetStatus().GetConditions() {
+		conds = append(conds, criCondition{Type: rc.Type, Status: rc.Status, Reason: rc.Reason, Message: rc.Message})
+	}
+	return conds, nil
+}
+
+// ListImages lists the images known to the runtime
+func (c *criClient) ListImages() ([]ListImage, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.image.ListImages(ctx, &runtimeapi.ListImagesRequest{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "CRI list images over %s", c.socket)
+	}
+	images := []ListImage{}
+	for _, img := range resp.Images {
+		images = append(images, ListImage{
+			ID:          img.Id,
+			RepoDigests: img.RepoDigests,
+			RepoTags:    img.RepoTags,
+			Size:        strconv.FormatUint(img.Size_, 10),
+		})
+	}
+	return images, nil
+}
+
+// ImageID returns the ID of an image, or ErrImageNotFound
+func (c *criClient) ImageID(name string) (string, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.image.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{Image: &runtimeapi.ImageSpec{Image: name}})
+	if err != nil {
+		return "", errors.Wrapf(err, "CRI image status of %s over %s", name, c.socket)
+	}
+	if resp.Image == nil {
+		return "", errors.Wrap(ErrImageNotFound, name)
+	}
+	return resp.Image.Id, nil
+}
+
+// PullImage pulls an image
+func (c *criClient) PullImage(name string) error {
+	ctx, cancel := context.WithTimeout(c.ctx, criPullTimeout)
+	defer cancel()
+	if _, err := c.image.PullImage(ctx, &runtimeapi.PullImageRequest{Image: &runtimeapi.ImageSpec{Image: name}}); err != nil {
+		return errors.Wrapf(err, "CRI pull image %s over %s", name, c.socket)
+	}
+	return nil
+}
+
+// ImageFsUsedBytes returns how many bytes the image filesystems of the runtime use
+func (c *criClient) ImageFsUsedBytes() (uint64, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.image.ImageFsInfo(ctx, &runtimeapi.ImageFsInfoRequest{})
+	if err != nil {
+		return 0, errors.Wrapf(err, "CRI image filesystem info over %s", c.socket)
+	}
+	var used uint64
+	for _, fs := range resp.GetImageFilesystems() {
+		used += fs.GetUsedBytes().GetValue()
+	}
+	return used, nil
+}
+
+// RemoveImage removes an image
+func (c *criClient) RemoveImage(name string) error {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	if _, err := c.image.RemoveImage(ctx, &runtimeapi.RemoveImageRequest{Image: &runtimeapi.ImageSpec{Image: name}}); err != nil {
+		return errors.Wrapf(err, "CRI remove image %s over %s", name, c.socket)
+	}
+	return nil
+}
+
+// ListContainers returns the ids of all containers matching the name, namespaces and pods of o, whatever their state
+func (c *criClient) ListContainers(o ListContainersOptions) ([]string, error) {
+	var nameRE *regexp.Regexp
+	if o.Name != "" {
+		// crictl ps --name matches a regular expression as well
+		re, err := regexp.Compile(o.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "container name %q", o.Name)
+		}
+		nameRE = re
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	filters := []*runtimeapi.ContainerFilter{{}}
+	if len(o.Namespaces) > 0 {
+		filters = nil
+		for _, ns := range o.Namespaces {
+			filters = append(filters, &runtimeapi.ContainerFilter{LabelSelector: map[string]string{criPodNamespaceLabel: ns}})
+		}
+	}
+	if len(o.PodIDs) > 0 {
+		var podFilters []*runtimeapi.ContainerFilter
+		for _, f := range filters {
+			for _, id := range o.PodIDs {
+				podFilters = append(podFilters, &runtimeapi.ContainerFilter{LabelSelector: f.LabelSelector, PodSandboxId: id})
+			}
+		}
+		filters = podFilters
+	}
+	var ids []string
+	for _, f := range filters {
+		resp, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{Filter: f})
+		if err != nil {
+			return nil, errors.Wrapf(err, "CRI list containers over %s", c.socket)
+		}
+		for _, ctr := range resp.Containers {
+			if nameRE != nil && (ctr.Metadata == nil || !nameRE.MatchString(ctr.Metadata.Name)) {
+				continue
+			}
+			ids = append(ids, ctr.Id)
+		}
+	}
+	return ids, nil
+}
+
+// ListPods returns the pod sandboxes of the namespaces of o, or of all namespaces if there are none
+func (c *criClient) ListPods(o ListPodsOptions) ([]Pod, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	filter := &runtimeapi.PodSandboxFilter{}
+	if o.Ready {
+		filter.State = &runtimeapi.PodSandboxStateValue{State: runtimeapi.PodSandboxState_SANDBOX_READY}
+	}
+	filters := []*runtimeapi.PodSandboxFilter{filter}
+	if len(o.Namespaces) > 0 {
+		filters = nil
+		for _, ns := range o.Namespaces {
+			filters = append(filters, &runtimeapi.PodSandboxFilter{State: filter.State, LabelSelector: map[string]string{criPodNamespaceLabel: ns}})
+		}
+	}
+	var pods []Pod
+	for _, f := range filters {
+		resp, err := c.runtime.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{Filter: f})
+		if err != nil {
+			return nil, errors.Wrapf(err, "CRI list pod sandboxes over %s", c.socket)
+		}
+		for _, sb := range resp.Items {
+			pods = append(pods, Pod{
+				ID:        sb.Id,
+				Name:      sb.GetMetadata().GetName(),
+				Namespace: sb.GetMetadata().GetNamespace(),
+				UID:       sb.GetMetadata().GetUid(),
+				Ready:     sb.State == runtimeapi.PodSandboxState_SANDBOX_READY,
+			})
+		}
+	}
+	return pods, nil
+}
+
+// StopContainers stops containers, giving them criStopTimeout seconds before they are killed
+func (c *criClient) StopContainers(ids []string) error {
+	for _, id := range ids {
+		ctx, cancel := context.WithTimeout(c.ctx, criTimeout+criStopTimeout*time.Second)
+		_, err := c.runtime.StopContainer(ctx, &runtimeapi.StopContainerRequest{ContainerId: id, Timeout: criStopTimeout})
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "CRI stop container %s over %s", id, c.socket)
+		}
+	}
+	return nil
+}
+
+// RemoveContainers removes containers, killing them first if they are running
+func (c *criClient) RemoveContainers(ids []string) error {
+	for _, id := range ids {
+		ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+		_, err := c.runtime.RemoveContainer(ctx, &runtimeapi.RemoveContainerRequest{ContainerId: id})
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "CRI remove container %s over %s", id, c.socket)
+		}
+	}
+	return nil
+}
+
+// ContainerStats returns the resource usage of the containers with the given ids, or of all containers if there are none
+func (c *criClient) ContainerStats(ids []string) ([]ContainerStats, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	filter := &runtimeapi.ContainerStatsFilter{}
+	if len(ids) == 1 {
+		filter.Id = ids[0]
+	}
+	resp, err := c.runtime.ListContainerStats(ctx, &runtimeapi.ListContainerStatsRequest{Filter: filter})
+	if err != nil {
+		return nil, errors.Wrapf(err, "CRI list container stats over %s", c.socket)
+	}
+	stats := []ContainerStats{}
+	for _, cs := range resp.Stats {
+		a := cs.GetAttributes()
+		s := ContainerStats{
+			ID:                      a.GetId(),
+			Name:                    a.GetMetadata().GetName(),
+			PodName:                 a.GetLabels()[criPodNameLabel],
+			PodNamespace:            a.GetLabels()[criPodNamespaceLabel],
+			Timestamp:               time.Unix(0, cs.GetCpu().GetTimestamp()),
+			CPUUsageNanoCores:       cs.GetCpu().GetUsageNanoCores().GetValue(),
+			CPUUsageCoreNanoSeconds: cs.GetCpu().GetUsageCoreNanoSeconds().GetValue(),
+			MemoryWorkingSetBytes:   cs.GetMemory().GetWorkingSetBytes().GetValue(),
+			WritableLayerBytes:      cs.GetWritableLayer().GetUsedBytes().GetValue(),
+		}
+		stats = append(stats, s)
+	}
+	return filterContainerStats(stats, ids), nil
+}
+
+// ContainerEvents streams the lifecycle events of the containers to events until ctx is done or the stream breaks,
+// and returns why it stopped. Unlike the other calls, it is bounded by ctx alone.
+func (c *criClient) ContainerEvents(ctx context.Context, events chan<- ContainerEvent) error {
+	stream, err := c.runtime.GetContainerEvents(ctx, &runtimeapi.GetEventsRequest{})
+	if err != nil {
+		return errors.Wrapf(err, "CRI get container events over %s", c.socket)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Wrapf(err, "CRI get container events over %s", c.socket)
+		}
+		e := ContainerEvent{
+			Time:         time.Unix(0, resp.CreatedAt),
+			Type:         criEventTypes[resp.ContainerEventType],
+			ContainerID:  resp.ContainerId,
+			PodName:      resp.GetPodSandboxStatus().GetMetadata().GetName(),
+			PodNamespace: resp.GetPodSandboxStatus().GetMetadata().GetNamespace(),
+		}
+		for _, cs := range resp.ContainersStatuses {
+			if cs.Id != resp.ContainerId {
+				continue
+			}
+			e.Container = cs.GetMetadata().GetName()
+			if resp.ContainerEventType == runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT {
+				e.ExitCode, e.Reason = cs.ExitCode, cs.Reason
+			}
+		}
+		select {
+		case events <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// criEventTypes are the ContainerEvent types of the CRI event types
+var criEventTypes = map[runtimeapi.ContainerEventType]string{
+	runtimeapi.ContainerEventType_CONTAINER_CREATED_EVENT: "created",
+	runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT: "started",
+	runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT: "stopped",
+	runtimeapi.ContainerEventType_CONTAINER_DELETED_EVENT: "deleted",
+}
+
+// ExecSync runs a command in a container and waits for it to exit.
+// A command which fails is not an error, its exit code is in the result.
+func (c *criClient) ExecSync(id string, cmd []string) (*command.RunResult, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criExecTimeout)
+	defer cancel()
+	resp, err := c.runtime.ExecSync(ctx, &runtimeapi.ExecSyncRequest{ContainerId: id, Cmd: cmd})
+	if err != nil {
+		return nil, errors.Wrapf(err, "CRI exec in container %s over %s", id, c.socket)
+	}
+	rr := &command.RunResult{ExitCode: int(resp.ExitCode), Args: cmd}
+	rr.Stdout.Write(resp.Stdout)
+	rr.Stderr.Write(resp.Stderr)
+	return rr, nil
+}
+
+// imageRefs returns the set of tags and digests the listed images are known by
+func imageRefs(listed []ListImage) map[string]bool {
+	refs := map[string]bool{}
+	for _, img := range listed {
+		for _, t := range img.RepoTags {
+			refs[t] = true
+		}
+		for _, d := range img.RepoDigests {
+			refs[d] = true
+		}
+	}
+	return refs
+}
+
+// missingImage returns the first of images not among refs, or "" if all are present.
+// Images pinned by digest only match that exact digest, whatever their tag.
+func missingImage(images []string, refs map[string]bool) string {
+	for _, i := range images {
+		name := addRepoTagToImageName(i)
+		if at := strings.Index(name, "@sha256:"); at != -1 {
+			repo := name[:at]
+			if colon := strings.LastIndex(repo, ":"); colon > strings.LastIndex(repo, "/") {
+				repo = repo[:colon]
+			}
+			name = repo + name[at:]
+		}
+		if !refs[name] {
+			return i
+		}
+	}
+	return ""
+}
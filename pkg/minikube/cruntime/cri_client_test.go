@@ -0,0 +1,538 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// fakeImageService serves a fixed image list over the CRI ImageService, and records pulls and removals
+type fakeImageService struct {
+	runtimeapi.UnimplementedImageServiceServer
+	images []*runtimeapi.Image
+	// info is the verbose info of the status of the images, by ID
+	info map[string]map[string]string
+
+	mu      sync.Mutex
+	pulled  []string
+	removed []string
+}
+
+func (f *fakeImageService) ListImages(context.Context, *runtimeapi.ListImagesRequest) (*runtimeapi.ListImagesResponse, error) {
+	return &runtimeapi.ListImagesResponse{Images: f.images}, nil
+}
+
+func (f *fakeImageService) ImageStatus(_ context.Context, req *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error) {
+	for _, img := range f.images {
+		if slices.Contains(img.RepoTags, req.Image.Image) {
+			resp := &runtimeapi.ImageStatusResponse{Image: img}
+			if req.Verbose {
+				resp.Info = f.info[img.Id]
+			}
+			return resp, nil
+		}
+	}
+	return &runtimeapi.ImageStatusResponse{}, nil
+}
+
+func (f *fakeImageService) PullImage(_ context.Context, req *runtimeapi.PullImageRequest) (*runtimeapi.PullImageResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulled = append(f.pulled, req.Image.Image)
+	return &runtimeapi.PullImageResponse{ImageRef: req.Image.Image}, nil
+}
+
+func (f *fakeImageService) RemoveImage(_ context.Context, req *runtimeapi.RemoveImageRequest) (*runtimeapi.RemoveImageResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, req.Image.Image)
+	return &runtimeapi.RemoveImageResponse{}, nil
+}
+
+// fakeRuntimeService serves fixed containers over the CRI RuntimeService, and records stops and removals
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	containers []*runtimeapi.Container
+	sandboxes  []*runtimeapi.PodSandbox
+	// events are sent to every subscriber, which is then kept waiting until it goes away
+	events []*runtimeapi.ContainerEventResponse
+	// conditions are the status conditions of the runtime, which is ready when there are none
+	conditions []*runtimeapi.RuntimeCondition
+
+	mu      sync.Mutex
+	stopped []string
+	removed []string
+}
+
+func (f *fakeRuntimeService) Version(context.Context, *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{Version: "0.1.0", RuntimeName: "portoshim", RuntimeVersion: "v1.0.11", RuntimeApiVersion: "v1"}, nil
+}
+
+func (f *fakeRuntimeService) Status(context.Context, *runtimeapi.StatusRequest) (*runtimeapi.StatusResponse, error) {
+	conds := f.conditions
+	if conds == nil {
+		conds = []*runtimeapi.RuntimeCondition{{Type: "RuntimeReady", Status: true}, {Type: "NetworkReady", Status: true}}
+	}
+	return &runtimeapi.StatusResponse{Status: &runtimeapi.RuntimeStatus{Conditions: conds}}, nil
+}
+
+func (f *fakeRuntimeService) ListContainers(_ context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	var cs []*runtimeapi.Container
+	for _, c := range f.containers {
+		match := true
+		for k, v := range req.GetFilter().GetLabelSelector() {
+			if c.Labels[k] != v {
+				match = false
+			}
+		}
+		if id := req.GetFilter().GetPodSandboxId(); id != "" && c.PodSandboxId != id {
+			match = false
+		}
+		if match {
+			cs = append(cs, c)
+		}
+	}
+	return &runtimeapi.ListContainersResponse{Containers: cs}, nil
+}
+
+func (f *fakeRuntimeService) ListPodSandbox(_ context.Context, req *runtimeapi.ListPodSandboxRequest) (*runtimeapi.ListPodSandboxResponse, error) {
+	var sbs []*runtimeapi.PodSandbox
+	for _, sb := range f.sandboxes {
+		match := true
+		for k, v := range req.GetFilter().GetLabelSelector() {
+			if sb.Labels[k] != v {
+				match = false
+			}
+		}
+		if st := req.GetFilter().GetState(); st != nil && sb.State != st.State {
+			match = false
+		}
+		if match {
+			sbs = append(sbs, sb)
+		}
+	}
+	return &runtimeapi.ListPodSandboxResponse{Items: sbs}, nil
+}
+
+func (f *fakeRuntimeService) ListContainerStats(_ context.Context, req *runtimeapi.ListContainerStatsRequest) (*runtimeapi.ListContainerStatsResponse, error) {
+	var stats []*runtimeapi.ContainerStats
+	for _, c := range f.containers {
+		if id := req.GetFilter().GetId(); id != "" && id != c.Id {
+			continue
+		}
+		stats = append(stats, &runtimeapi.ContainerStats{
+			Attributes:    &runtimeapi.ContainerAttributes{Id: c.Id, Metadata: c.Metadata, Labels: c.Labels},
+			Cpu:           &runtimeapi.CpuUsage{Timestamp: 1700000000000000000, UsageCoreNanoSeconds: &runtimeapi.UInt64Value{Value: 5000000000}},
+			Memory:        &runtimeapi.MemoryUsage{WorkingSetBytes: &runtimeapi.UInt64Value{Value: 64 << 20}},
+			WritableLayer: &runtimeapi.FilesystemUsage{UsedBytes: &runtimeapi.UInt64Value{Value: 4 << 20}},
+		})
+	}
+	return &runtimeapi.ListContainerStatsResponse{Stats: stats}, nil
+}
+
+func (f *fakeRuntimeService) StopContainer(_ context.Context, req *runtimeapi.StopContainerRequest) (*runtimeapi.StopContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = append(f.stopped, req.ContainerId)
+	return &runtimeapi.StopContainerResponse{}, nil
+}
+
+func (f *fakeRuntimeService) RemoveContainer(_ context.Context, req *runtimeapi.RemoveContainerRequest) (*runtimeapi.RemoveContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, req.ContainerId)
+	return &runtimeapi.RemoveContainerResponse{}, nil
+}
+
+func (f *fakeRuntimeService) GetContainerEvents(_ *runtimeapi.GetEventsRequest, stream runtimeapi.RuntimeService_GetContainerEventsServer) error {
+	for _, e := range f.events {
+		if err := stream.Send(e); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+// ExecSync runs "echo" and "false" in the containers
+func (f *fakeRuntimeService) ExecSync(_ context.Context, req *runtimeapi.ExecSyncRequest) (*runtimeapi.ExecSyncResponse, error) {
+	if len(req.Cmd) > 0 && req.Cmd[0] == "echo" {
+		return &runtimeapi.ExecSyncResponse{Stdout: []byte(strings.Join(req.Cmd[1:], " ") + "\n")}, nil
+	}
+	return &runtimeapi.ExecSyncResponse{Stderr: []byte("failed in " + req.ContainerId), ExitCode: 1}, nil
+}
+
+// serveFakeCRI serves the fake CRI services on a unix socket until the test ends, and returns its path
+func serveFakeCRI(t *testing.T, images *fakeImageService, runtime *fakeRuntimeService) string {
+	t.Helper()
+	// t.TempDir can exceed the maximum length of a socket path
+	dir, err := os.MkdirTemp("", "cri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "cri.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	runtimeapi.RegisterImageServiceServer(srv, images)
+	runtimeapi.RegisterRuntimeServiceServer(srv, runtime)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		os.RemoveAll(dir)
+	})
+	return socket
+}
+
+func TestCRIClient(t *testing.T) {
+	container := func(id, name, ns string) *runtimeapi.Container {
+		return &runtimeapi.Container{
+			Id:       id,
+			Metadata: &runtimeapi.ContainerMetadata{Name: name},
+			Labels:   map[string]string{"io.kubernetes.pod.namespace": ns},
+		}
+	}
+	images := &fakeImageService{}
+	runtime := &fakeRuntimeService{containers: []*runtimeapi.Container{
+		container("1", "etcd", "kube-system"),
+		container("2", "kube-apiserver", "kube-system"),
+		container("3", "dashboard", "kubernetes-dashboard"),
+		container("4", "nginx", "default"),
+	}}
+	socket := serveFakeCRI(t, images, runtime)
+	runner := command.NewExecRunner(false)
+
+	v, err := getCRIVersion(runner, socket)
+	if err != nil {
+		t.Fatalf("getCRIVersion: %v", err)
+	}
+	if diff := cmp.Diff(criVersion{RuntimeName: "portoshim", RuntimeVersion: "v1.0.11", RuntimeAPIVersion: "v1"}, v); diff != "" {
+		t.Errorf("getCRIVersion mismatch (-want +got):\n%s", diff)
+	}
+
+	rr, err := execCRIContainer(runner, socket, "1", []string{"echo", "hello", "etcd"})
+	if err != nil {
+		t.Errorf("execCRIContainer: %v", err)
+	} else if rr.Stdout.String() != "hello etcd\n" {
+		t.Errorf("execCRIContainer stdout = %q, want %q", rr.Stdout.String(), "hello etcd\n")
+	}
+	rr, err = execCRIContainer(runner, socket, "1", []string{"false"})
+	if err == nil || rr == nil || rr.ExitCode != 1 || rr.Stderr.String() != "failed in 1" {
+		t.Errorf("execCRIContainer of a failing command = %+v, %v, want exit code 1", rr, err)
+	}
+
+	tests := []struct {
+		opts ListContainersOptions
+		want []string
+	}{
+		{ListContainersOptions{State: All}, []string{"1", "2", "3", "4"}},
+		{ListContainersOptions{State: All, Namespaces: []string{"kube-system", "kubernetes-dashboard"}}, []string{"1", "2", "3"}},
+		{ListContainersOptions{State: All, Name: "kube-"}, []string{"2"}},
+		{ListContainersOptions{State: All, Namespaces: []string{"default"}, Name: "etcd"}, nil},
+	}
+	for _, tc := range tests {
+		got, err := listCRIContainers(runner, socket, "", tc.opts)
+		if err != nil {
+			t.Fatalf("listCRIContainers(%+v): %v", tc.opts, err)
+		}
+		sort.Strings(got)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("listCRIContainers(%+v) mismatch (-want +got):\n%s", tc.opts, diff)
+		}
+	}
+
+	stats, err := criContainerStats(runner, socket, []string{"1", "4"})
+	if err != nil {
+		t.Fatalf("criContainerStats: %v", err)
+	}
+	wantStats := []ContainerStats{
+		{ID: "1", Name: "etcd", PodNamespace: "kube-system", Timestamp: time.Unix(0, 1700000000000000000), CPUUsageCoreNanoSeconds: 5000000000, MemoryWorkingSetBytes: 64 << 20, WritableLayerBytes: 4 << 20},
+		{ID: "4", Name: "nginx", PodNamespace: "default", Timestamp: time.Unix(0, 1700000000000000000), CPUUsageCoreNanoSeconds: 5000000000, MemoryWorkingSetBytes: 64 << 20, WritableLayerBytes: 4 << 20},
+	}
+	if diff := cmp.Diff(wantStats, stats); diff != "" {
+		t.Errorf("criContainerStats mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := stopCRIContainers(runner, socket, []string{"1", "2"}); err != nil {
+		t.Errorf("stopCRIContainers: %v", err)
+	}
+	if err := killCRIContainers(runner, socket, []string{"3"}); err != nil {
+		t.Errorf("killCRIContainers: %v", err)
+	}
+	if err := pullCRIImage(runner, socket, "registry.k8s.io/pause:3.9"); err != nil {
+		t.Errorf("pullCRIImage: %v", err)
+	}
+	if err := removeCRIImage(runner, socket, "registry.k8s.io/etcd:3.5.10-0"); err != nil {
+		t.Errorf("removeCRIImage: %v", err)
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, runtime.stopped); diff != "" {
+		t.Errorf("stopped containers mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"3"}, runtime.removed); diff != "" {
+		t.Errorf("removed containers mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"registry.k8s.io/pause:3.9"}, images.pulled); diff != "" {
+		t.Errorf("pulled images mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"registry.k8s.io/etcd:3.5.10-0"}, images.removed); diff != "" {
+		t.Errorf("removed images mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCRIPodContainers(t *testing.T) {
+	sandbox := func(id, name, ns string, state runtimeapi.PodSandboxState) *runtimeapi.PodSandbox {
+		return &runtimeapi.PodSandbox{
+			Id:       id,
+			Metadata: &runtimeapi.PodSandboxMetadata{Name: name, Namespace: ns, Uid: name + "-uid"},
+			Labels:   map[string]string{criPodNamespaceLabel: ns},
+			State:    state,
+		}
+	}
+	container := func(id, sandbox, ns string) *runtimeapi.Container {
+		return &runtimeapi.Container{
+			Id:           id,
+			PodSandboxId: sandbox,
+			Metadata:     &runtimeapi.ContainerMetadata{Name: "c" + id},
+			Labels:       map[string]string{criPodNamespaceLabel: ns},
+		}
+	}
+	runtime := &fakeRuntimeService{
+		sandboxes: []*runtimeapi.PodSandbox{
+			sandbox("s1", "etcd", "kube-system", runtimeapi.PodSandboxState_SANDBOX_READY),
+			sandbox("s2", "coredns", "kube-system", runtimeapi.PodSandboxState_SANDBOX_NOTREADY),
+			sandbox("s3", "nginx", "default", runtimeapi.PodSandboxState_SANDBOX_READY),
+		},
+		containers: []*runtimeapi.Container{
+			container("1", "s1", "kube-system"),
+			container("2", "s2", "kube-system"),
+			container("3", "s2", "kube-system"),
+			container("4", "s3", "default"),
+		},
+	}
+	socket := serveFakeCRI(t, &fakeImageService{}, runtime)
+	r := &Porto{Runner: command.NewExecRunner(false), Socket: socket}
+
+	pods, err := r.ListPods(ListPodsOptions{Namespaces: []string{"kube-system"}})
+	if err != nil {
+		t.Fatalf("ListPods: %v", err)
+	}
+	want := []Pod{
+		{ID: "s1", Name: "etcd", Namespace: "kube-system", UID: "etcd-uid", Ready: true},
+		{ID: "s2", Name: "coredns", Namespace: "kube-system", UID: "coredns-uid"},
+	}
+	if diff := cmp.Diff(want, pods); diff != "" {
+		t.Errorf("ListPods mismatch (-want +got):\n%s", diff)
+	}
+	pods, err = r.ListPods(ListPodsOptions{Ready: true})
+	if err != nil {
+		t.Fatalf("ListPods of ready pods: %v", err)
+	}
+	if len(pods) != 2 || pods[0].ID != "s1" || pods[1].ID != "s3" {
+		t.Errorf("ListPods of ready pods = %+v, want s1 and s3", pods)
+	}
+
+	ids, err := r.ContainersForPod("s2", All)
+	if err != nil {
+		t.Fatalf("ContainersForPod: %v", err)
+	}
+	if diff := cmp.Diff([]string{"2", "3"}, ids); diff != "" {
+		t.Errorf("ContainersForPod mismatch (-want +got):\n%s", diff)
+	}
+	ids, err = PodContainers(r, All, []string{"kube-system"})
+	if err != nil {
+		t.Fatalf("PodContainers: %v", err)
+	}
+	sort.Strings(ids)
+	if diff := cmp.Diff([]string{"1", "2", "3"}, ids); diff != "" {
+		t.Errorf("PodContainers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCRIImageDigest(t *testing.T) {
+	const id = "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c"
+	images := &fakeImageService{images: []*runtimeapi.Image{
+		{Id: id, RepoTags: []string{"registry.k8s.io/pause:3.9", "mirror.example.com/k8s/pause:3.9"}},
+	}}
+	socket := serveFakeCRI(t, images, &fakeRuntimeService{})
+	r := &Porto{Runner: command.NewExecRunner(false), Socket: socket}
+
+	got, err := r.ImageDigest("mirror.example.com/k8s/pause:3.9")
+	if err != nil {
+		t.Fatalf("ImageDigest: %v", err)
+	}
+	if got != id {
+		t.Errorf("ImageDigest = %q, want %q", got, id)
+	}
+	if _, err := r.ImageDigest("registry.k8s.io/etcd:3.5.9-0"); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("ImageDigest of a missing image = %v, want ErrImageNotFound", err)
+	}
+	if !r.ImageExists("registry.k8s.io/pause:3.9", "e6f18168") {
+		t.Errorf("ImageExists(pause) = false, want true")
+	}
+}
+
+func TestCRIEvents(t *testing.T) {
+	runtime := &fakeRuntimeService{
+		events: []*runtimeapi.ContainerEventResponse{
+			{
+				ContainerId:        "1",
+				ContainerEventType: runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT,
+				CreatedAt:          1700000000000000000,
+				PodSandboxStatus:   &runtimeapi.PodSandboxStatus{Metadata: &runtimeapi.PodSandboxMetadata{Name: "nginx", Namespace: "default"}},
+				ContainersStatuses: []*runtimeapi.ContainerStatus{{Id: "1", Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"}}},
+			},
+			{
+				ContainerId:        "1",
+				ContainerEventType: runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT,
+				CreatedAt:          1700000001000000000,
+				PodSandboxStatus:   &runtimeapi.PodSandboxStatus{Metadata: &runtimeapi.PodSandboxMetadata{Name: "nginx", Namespace: "default"}},
+				ContainersStatuses: []*runtimeapi.ContainerStatus{
+					{Id: "2", Metadata: &runtimeapi.ContainerMetadata{Name: "sidecar"}},
+					{Id: "1", Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"}, ExitCode: 137, Reason: "OOMKilled"},
+				},
+			},
+		},
+	}
+	socket := serveFakeCRI(t, &fakeImageService{}, runtime)
+	r := &Porto{Runner: command.NewExecRunner(false), Socket: socket}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := r.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	var got []ContainerEvent
+	for len(got) < len(runtime.events) {
+		got = append(got, <-events)
+	}
+	want := []ContainerEvent{
+		{Time: time.Unix(0, 1700000000000000000), Type: "started", ContainerID: "1", Container: "nginx", PodName: "nginx", PodNamespace: "default"},
+		{Time: time.Unix(0, 1700000001000000000), Type: "stopped", ContainerID: "1", Container: "nginx", PodName: "nginx", PodNamespace: "default", ExitCode: 137, Reason: "OOMKilled"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Events mismatch (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("Events sent an event once its context was done")
+	}
+}
+
+func TestCRIReady(t *testing.T) {
+	runtime := &fakeRuntimeService{}
+	socket := serveFakeCRI(t, &fakeImageService{}, runtime)
+	runner := command.NewExecRunner(false)
+	if err := CRIReady(runner, socket); err != nil {
+		t.Errorf("CRIReady: %v", err)
+	}
+
+	// the pod network is the CNI's business
+	runtime.conditions = []*runtimeapi.RuntimeCondition{{Type: "RuntimeReady", Status: true}, {Type: "NetworkReady", Reason: "NetworkPluginNotReady"}}
+	if err := CRIReady(runner, socket); err != nil {
+		t.Errorf("CRIReady without a pod network: %v", err)
+	}
+
+	runtime.conditions = []*runtimeapi.RuntimeCondition{{Type: "RuntimeReady", Reason: "PortodUnavailable", Message: "no answer from portod"}}
+	if err := CRIReady(runner, socket); err == nil || !strings.Contains(err.Error(), "PortodUnavailable") {
+		t.Errorf("CRIReady of a runtime which is not ready = %v, want an error with the reason", err)
+	}
+}
+
+func TestCRIReadyCrictl(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo crictl version":            "Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  v1.0.11\nRuntimeApiVersion:  v1\n",
+		"sudo crictl info --output json": `{"status": {"conditions": [{"type": "RuntimeReady", "status": true}, {"type": "NetworkReady", "status": false}]}}`,
+	})
+	if err := CRIReady(runner, "/run/portoshim.sock"); err != nil {
+		t.Errorf("CRIReady: %v", err)
+	}
+}
+
+func TestParseCrictlStats(t *testing.T) {
+	out := `{
+  "stats": [
+    {
+      "attributes": {
+        "id": "5f6a",
+        "metadata": {"name": "kube-apiserver", "attempt": 0},
+        "labels": {"io.kubernetes.pod.name": "kube-apiserver-minikube", "io.kubernetes.pod.namespace": "kube-system"},
+        "annotations": {}
+      },
+      "cpu": {"timestamp": "1700000000000000000", "usageCoreNanoSeconds": {"value": "41000000000"}, "usageNanoCores": {"value": "125000000"}},
+      "memory": {"timestamp": "1700000000000000000", "workingSetBytes": {"value": "268435456"}},
+      "writableLayer": {"timestamp": "1700000000000000000", "fsId": {"mountpoint": "/var/lib/containerd"}, "usedBytes": {"value": "8192"}, "inodesUsed": {"value": "3"}}
+    }
+  ]
+}`
+	got, err := parseCrictlStats([]byte(out))
+	if err != nil {
+		t.Fatalf("parseCrictlStats: %v", err)
+	}
+	want := []ContainerStats{{
+		ID:                      "5f6a",
+		Name:                    "kube-apiserver",
+		PodName:                 "kube-apiserver-minikube",
+		PodNamespace:            "kube-system",
+		Timestamp:               time.Unix(0, 1700000000000000000),
+		CPUUsageNanoCores:       125000000,
+		CPUUsageCoreNanoSeconds: 41000000000,
+		MemoryWorkingSetBytes:   268435456,
+		WritableLayerBytes:      8192,
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseCrictlStats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContainerStatsCPUNanoCores(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	prev := ContainerStats{Timestamp: t0, CPUUsageCoreNanoSeconds: 1000000000}
+	tests := []struct {
+		description string
+		cur         ContainerStats
+		want        uint64
+	}{
+		{"reported", ContainerStats{Timestamp: t0.Add(time.Second), CPUUsageNanoCores: 42}, 42},
+		{"derived", ContainerStats{Timestamp: t0.Add(2 * time.Second), CPUUsageCoreNanoSeconds: 2000000000}, 500000000},
+		{"same sample", ContainerStats{Timestamp: t0, CPUUsageCoreNanoSeconds: 2000000000}, 0},
+		{"restarted", ContainerStats{Timestamp: t0.Add(time.Second), CPUUsageCoreNanoSeconds: 100}, 0},
+	}
+	for _, tc := range tests {
+		if got := tc.cur.CPUNanoCores(prev); got != tc.want {
+			t.Errorf("%s: CPUNanoCores = %d, want %d", tc.description, got, tc.want)
+		}
+	}
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package cruntime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -92,6 +94,14 @@ func generateCRIOConfig(cr CommandRunner, imageRepository string, kv semver.Vers
 	return nil
 }
 
+// bind returns a copy of r which runs its commands within ctx
+func (r *CRIO) bind(ctx context.Context) *CRIO {
+	b := *r
+	b.Runner = withContext(ctx, r.Runner)
+	b.Init = sysinit.WithRunner(r.Init, b.Runner)
+	return &b
+}
+
 // Name is a human readable name for CRIO
 func (r *CRIO) Name() string {
 	return "CRI-O"
@@ -103,7 +113,8 @@ func (r *CRIO) Style() style.Enum {
 }
 
 // Version retrieves the current version of this runtime
-func (r *CRIO) Version() (string, error) {
+func (r *CRIO) Version(ctx context.Context) (string, error) {
+	r = r.bind(ctx)
 	c := exec.Command("crio", "--version")
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
@@ -124,6 +135,11 @@ func (r *CRIO) SocketPath() string {
 	return "/var/run/crio/crio.sock"
 }
 
+// ShouldRestartKubelet returns false, as nothing kubelet reads is in the cri-o configuration
+func (r *CRIO) ShouldRestartKubelet(change ConfigChange) bool {
+	return false
+}
+
 // Available returns an error if it is not possible to use this runtime on a host
 func (r *CRIO) Available() error {
 	c := exec.Command("which", "crio")
@@ -196,13 +212,14 @@ Environment="_CRIO_ROOTLESS=1"
 }
 
 // Enable idempotently enables CRIO on a host
-func (r *CRIO) Enable(disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+func (r *CRIO) Enable(ctx context.Context, disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+	r = r.bind(ctx)
 	if disOthers {
 		if err := disableOthers(r, r.Runner); err != nil {
 			klog.Warningf("disableOthers: %v", err)
 		}
 	}
-	if err := populateCRIConfig(r.Runner, r.SocketPath()); err != nil {
+	if err := populateCRIConfig(r.Runner, r.SocketPath(), false); err != nil {
 		return err
 	}
 	if err := generateCRIOConfig(r.Runner, r.ImageRepository, r.KubernetesVersion, cgroupDriver); err != nil {
@@ -229,31 +246,29 @@ func (r *CRIO) Enable(disOthers bool, cgroupDriver string, inUserNamespace bool)
 }
 
 // Disable idempotently disables CRIO on a host
-func (r *CRIO) Disable() error {
+func (r *CRIO) Disable(ctx context.Context) error {
+	r = r.bind(ctx)
 	return r.Init.ForceStop("crio")
 }
 
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *CRIO) ImageExists(name string, sha string) bool {
-	// expected output looks like [NAME@sha256:SHA]
-	c := exec.Command("sudo", "podman", "image", "inspect", "--format", "{{.Id}}", name)
-	rr, err := r.Runner.RunCmd(c)
-	if err != nil {
-		return false
-	}
-	if sha != "" && !strings.Contains(rr.Output(), sha) {
-		return false
-	}
-	return true
+	return imageExists(r, name, sha)
+}
+
+// ImageDigest returns the ID of an image based on name
+func (r *CRIO) ImageDigest(name string) (string, error) {
+	return criImageDigest(r.Runner, r.SocketPath(), name)
 }
 
 // ListImages returns a list of images managed by this container runtime
 func (r *CRIO) ListImages(ListImagesOptions) ([]ListImage, error) {
-	return listCRIImages(r.Runner)
+	return listCRIImages(r.Runner, r.SocketPath())
 }
 
 // LoadImage loads an image into this runtime
-func (r *CRIO) LoadImage(path string) error {
+func (r *CRIO) LoadImage(ctx context.Context, path string) error {
+	r = r.bind(ctx)
 	klog.Infof("Loading image: %s", path)
 	c := exec.Command("sudo", "podman", "load", "-i", path)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -262,15 +277,41 @@ func (r *CRIO) LoadImage(path string) error {
 	return nil
 }
 
+// LoadImageFromLayout loads the images of an OCI image layout directory on the host into this runtime
+func (r *CRIO) LoadImageFromLayout(ctx context.Context, dir string) error {
+	r = r.bind(ctx)
+	klog.Infof("Loading OCI layout: %s", dir)
+	return loadOCILayout(dir, func(stream io.Reader) error {
+		c := exec.Command("sudo", "podman", "load")
+		c.Stdin = stream
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "crio load image")
+		}
+		return nil
+	})
+}
+
 // PullImage pulls an image
-func (r *CRIO) PullImage(name string) error {
-	return pullCRIImage(r.Runner, name)
+func (r *CRIO) PullImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	return pullCRIImage(r.Runner, r.SocketPath(), name)
 }
 
 // SaveImage saves an image from this runtime
-func (r *CRIO) SaveImage(name string, path string) error {
-	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("sudo", "podman", "save", name, "-o", path)
+func (r *CRIO) SaveImage(ctx context.Context, name string, path string) error {
+	return r.SaveImages(ctx, []string{name}, path)
+}
+
+// SaveImages saves images from this runtime into one docker-archive
+func (r *CRIO) SaveImages(ctx context.Context, names []string, path string) error {
+	r = r.bind(ctx)
+	klog.Infof("Saving images %s: %s", names, path)
+	args := []string{"podman", "save"}
+	if len(names) > 1 {
+		// podman only keeps the first image of an archive without it
+		args = append(args, "--multi-image-archive")
+	}
+	c := exec.Command("sudo", append(append(args, names...), "-o", path)...)
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "crio save image")
 	}
@@ -278,12 +319,14 @@ func (r *CRIO) SaveImage(name string, path string) error {
 }
 
 // RemoveImage removes a image
-func (r *CRIO) RemoveImage(name string) error {
-	return removeCRIImage(r.Runner, name)
+func (r *CRIO) RemoveImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	return removeCRIImage(r.Runner, r.SocketPath(), name)
 }
 
 // TagImage tags an image in this runtime
-func (r *CRIO) TagImage(source string, target string) error {
+func (r *CRIO) TagImage(ctx context.Context, source string, target string) error {
+	r = r.bind(ctx)
 	klog.Infof("Tagging image %s: %s", source, target)
 	c := exec.Command("sudo", "podman", "tag", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -293,7 +336,8 @@ func (r *CRIO) TagImage(source string, target string) error {
 }
 
 // BuildImage builds an image into this runtime
-func (r *CRIO) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *CRIO) BuildImage(ctx context.Context, src string, file string, tag string, push bool, env []string, opts []string) error {
+	r = r.bind(ctx)
 	klog.Infof("Building image: %s", src)
 	args := []string{"podman", "build"}
 	if file != "" {
@@ -328,7 +372,8 @@ func (r *CRIO) BuildImage(src string, file string, tag string, push bool, env []
 }
 
 // PushImage pushes an image
-func (r *CRIO) PushImage(name string) error {
+func (r *CRIO) PushImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
 	klog.Infof("Pushing image %s", name)
 	c := exec.Command("sudo", "podman", "push", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -339,22 +384,75 @@ func (r *CRIO) PushImage(name string) error {
 
 // CGroupDriver returns cgroup driver ("cgroupfs" or "systemd")
 func (r *CRIO) CGroupDriver() (string, error) {
-	c := exec.Command("crio", "config")
-	rr, err := r.Runner.RunCmd(c)
+	conf, err := r.config()
+	if err != nil {
+		return "", err
+	}
+	return crioConfigValue(conf, "cgroup_manager", "systemd"), nil
+}
+
+// Capabilities returns the optional features CRIO supports, all but checkpoints
+func (r *CRIO) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsBuild:      true,
+		SupportsLoad:       true,
+		SupportsLoadLayout: true,
+		SupportsSave:       true,
+		SupportsPush:       true,
+		SupportsPause:      true,
+		SupportsKata:       true,
+	}
+}
+
+// RuntimeInfo describes CRIO on the host
+func (r *CRIO) RuntimeInfo() (RuntimeInfo, error) {
+	version, err := r.Version(contextOf(r.Runner))
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	conf, err := r.config()
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	return RuntimeInfo{
+		Name:          r.Name(),
+		Version:       version,
+		Socket:        r.SocketPath(),
+		CgroupDriver:  crioConfigValue(conf, "cgroup_manager", "systemd"),
+		StorageDriver: crioConfigValue(conf, "storage_driver", ""),
+		StorageRoot:   crioConfigValue(conf, "root", ""),
+		Rootless:      nodeRootless(r.Runner),
+	}, nil
+}
+
+// Healthy returns an error unless CRI-O answers CRI requests
+func (r *CRIO) Healthy(ctx context.Context) error {
+	r = r.bind(ctx)
+	return criHealthy(r.Runner, r.SocketPath())
+}
+
+// config returns the configuration CRIO runs with, in TOML
+func (r *CRIO) config() (string, error) {
+	rr, err := r.Runner.RunCmd(exec.Command("crio", "config"))
 	if err != nil {
 		return "", err
 	}
-	cgroupManager := "systemd" // default
-	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
-		if strings.HasPrefix(line, "cgroup_manager") {
+	return rr.Stdout.String(), nil
+}
+
+// crioConfigValue returns the value of key in the output of crio config, or def when it is not set
+func crioConfigValue(conf string, key string, def string) string {
+	v := def
+	for _, line := range strings.Split(conf, "\n") {
+		if strings.HasPrefix(line, key+" ") {
 			// cgroup_manager = "cgroupfs"
 			f := strings.Split(strings.TrimSpace(line), " = ")
 			if len(f) == 2 {
-				cgroupManager = strings.Trim(f[1], "\"")
+				v = strings.Trim(f[1], "\"")
 			}
 		}
 	}
-	return cgroupManager, nil
+	return v
 }
 
 // KubeletOptions returns kubelet options for a runtime.
@@ -364,7 +462,17 @@ func (r *CRIO) KubeletOptions() map[string]string {
 
 // ListContainers returns a list of managed by this container runtime
 func (r *CRIO) ListContainers(o ListContainersOptions) ([]string, error) {
-	return listCRIContainers(r.Runner, "", o)
+	return listCRIContainers(r.Runner, r.SocketPath(), "", o)
+}
+
+// ListPods returns the pod sandboxes of CRIO
+func (r *CRIO) ListPods(o ListPodsOptions) ([]Pod, error) {
+	return listCRIPods(r.Runner, r.SocketPath(), o)
+}
+
+// ContainersForPod returns the IDs of the containers in state of a pod sandbox based on ID
+func (r *CRIO) ContainersForPod(id string, state ContainerState) ([]string, error) {
+	return r.ListContainers(ListContainersOptions{State: state, PodIDs: []string{id}})
 }
 
 // PauseContainers pauses a running container based on ID
@@ -379,12 +487,37 @@ func (r *CRIO) UnpauseContainers(ids []string) error {
 
 // KillContainers removes containers based on ID
 func (r *CRIO) KillContainers(ids []string) error {
-	return killCRIContainers(r.Runner, ids)
+	return killCRIContainers(r.Runner, r.SocketPath(), ids)
 }
 
 // StopContainers stops containers based on ID
 func (r *CRIO) StopContainers(ids []string) error {
-	return stopCRIContainers(r.Runner, ids)
+	return stopCRIContainers(r.Runner, r.SocketPath(), ids)
+}
+
+// ContainerStats returns the resource usage of containers based on ID, or of all containers if none are given
+func (r *CRIO) ContainerStats(ids []string) ([]ContainerStats, error) {
+	return criContainerStats(r.Runner, r.SocketPath(), ids)
+}
+
+// Events streams the lifecycle events of the containers until ctx is done
+func (r *CRIO) Events(ctx context.Context) (<-chan ContainerEvent, error) {
+	return criEvents(ctx, r.Runner, r.SocketPath())
+}
+
+// ExecContainer runs a command in a container based on ID
+func (r *CRIO) ExecContainer(id string, cmd []string) (*command.RunResult, error) {
+	return execCRIContainer(r.Runner, r.SocketPath(), id, cmd)
+}
+
+// CheckpointContainer is not supported by cri-o
+func (r *CRIO) CheckpointContainer(string, string) error {
+	return ErrCheckpointNotSupported
+}
+
+// RestoreContainer is not supported by cri-o
+func (r *CRIO) RestoreContainer(string, string) error {
+	return ErrCheckpointNotSupported
 }
 
 // ContainerLogCmd returns the command to retrieve the log for a container based on ID
@@ -398,7 +531,8 @@ func (r *CRIO) SystemLogCmd(len int) string {
 }
 
 // Preload preloads the container runtime with k8s images
-func (r *CRIO) Preload(cc config.ClusterConfig) error {
+func (r *CRIO) Preload(ctx context.Context, cc config.ClusterConfig) error {
+	r = r.bind(ctx)
 	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
 		return nil
 	}
@@ -501,3 +635,8 @@ func crioImagesPreloaded(runner command.Runner, images []string) bool {
 func (r *CRIO) ImagesPreloaded(images []string) bool {
 	return crioImagesPreloaded(r.Runner, images)
 }
+
+// InstallWasmShim is not supported by CRIO
+func (r *CRIO) InstallWasmShim(context.Context, WasmShim) error {
+	return ErrWasmNotSupported
+}
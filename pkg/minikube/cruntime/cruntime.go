@@ -18,9 +18,11 @@ limitations under the License.
 package cruntime
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/pkg/errors"
@@ -54,6 +56,14 @@ func ValidRuntimes() []string {
 	return []string{"docker", "cri-o", "containerd", "porto"}
 }
 
+const (
+	// QueryTimeout bounds a quick query of a runtime, such as its version
+	QueryTimeout = time.Minute
+	// OperationTimeout bounds an operation of a runtime minikube start waits on, such as enabling it or preloading
+	// its images, so that a hung runtime tool fails the start instead of blocking it
+	OperationTimeout = 15 * time.Minute
+)
+
 // CommandRunner is the subset of command.Runner this package consumes
 type CommandRunner interface {
 	// RunCmd is a blocking method that runs a command
@@ -75,20 +85,25 @@ type CommandRunner interface {
 	ReadableFile(sourcePath string) (assets.ReadableFile, error)
 }
 
-// Manager is a common interface for container runtimes
+// Manager is a common interface for container runtimes.
+// Methods taking a context return its error once it is done, rather than wait on a hung runtime.
 type Manager interface {
 	// Name is a human readable name for a runtime
 	Name() string
 	// Version retrieves the current version of this runtime
-	Version() (string, error)
+	Version(context.Context) (string, error)
 	// Enable idempotently enables this runtime on a host
-	Enable(bool, string, bool) error
+	Enable(context.Context, bool, string, bool) error
 	// Disable idempotently disables this runtime on a host
-	Disable() error
+	Disable(context.Context) error
 	// Active returns whether or not a runtime is active on a host
 	Active() bool
 	// Available returns an error if it is not possible to use this runtime on a host
 	Available() error
+	// ShouldRestartKubelet returns whether kubelet has to be restarted for a change Enable applied to take effect.
+	// kubelet reconnects to a restarted runtime on its own, so only changes to what kubelet reads itself, such as
+	// the CRI endpoint or the cgroups of pod containers, call for it.
+	ShouldRestartKubelet(ConfigChange) bool
 	// Style is an associated StyleEnum for Name()
 	Style() style.Enum
 
@@ -98,30 +113,48 @@ type Manager interface {
 	KubeletOptions() map[string]string
 	// SocketPath returns the path to the socket file for a given runtime
 	SocketPath() string
+	// RuntimeInfo describes the runtime on a host
+	RuntimeInfo() (RuntimeInfo, error)
+	// Capabilities returns the optional features the runtime supports
+	Capabilities() Capabilities
+	// Healthy returns what is wrong with the runtime on a host, nil when it serves requests
+	Healthy(context.Context) error
 
 	// Load an image idempotently into the runtime on a host
-	LoadImage(string) error
+	LoadImage(context.Context, string) error
+	// LoadImageFromLayout loads the images of an OCI image layout directory on the host into the runtime on a host
+	LoadImageFromLayout(context.Context, string) error
 	// Pull an image to the runtime from the container registry
-	PullImage(string) error
+	PullImage(context.Context, string) error
 	// Build an image idempotently into the runtime on a host
-	BuildImage(string, string, string, bool, []string, []string) error
+	BuildImage(context.Context, string, string, string, bool, []string, []string) error
 	// Save an image from the runtime on a host
-	SaveImage(string, string) error
+	SaveImage(context.Context, string, string) error
+	// Save images from the runtime on a host into one archive
+	SaveImages(context.Context, []string, string) error
 	// Tag an image
-	TagImage(string, string) error
+	TagImage(context.Context, string, string) error
 	// Push an image from the runtime to the container registry
-	PushImage(string) error
+	PushImage(context.Context, string) error
 
 	// ImageExists takes image name and optionally image sha to check if an image exists
 	ImageExists(string, string) bool
+	// InspectImage returns the metadata of an image, or ErrImageNotFound
+	InspectImage(context.Context, string) (*ImageInspect, error)
+	// ImageDigest returns the ID of an image based on name, the digest of its config, or ErrImageNotFound
+	ImageDigest(string) (string, error)
 	// ListImages returns a list of images managed by this container runtime
 	ListImages(ListImagesOptions) ([]ListImage, error)
 
 	// RemoveImage remove image based on name
-	RemoveImage(string) error
+	RemoveImage(context.Context, string) error
 
 	// ListContainers returns a list of containers managed by this container runtime
 	ListContainers(ListContainersOptions) ([]string, error)
+	// ListPods returns the pod sandboxes of this container runtime
+	ListPods(ListPodsOptions) ([]Pod, error)
+	// ContainersForPod returns the IDs of the containers in the given state of a pod sandbox based on ID
+	ContainersForPod(string, ContainerState) ([]string, error)
 	// KillContainers removes containers based on ID
 	KillContainers([]string) error
 	// StopContainers stops containers based on ID
@@ -130,16 +163,56 @@ type Manager interface {
 	PauseContainers([]string) error
 	// UnpauseContainers unpauses containers based on ID
 	UnpauseContainers([]string) error
+	// ContainerStats returns the resource usage of containers based on ID, or of all containers if none are given
+	ContainerStats([]string) ([]ContainerStats, error)
+	// Events streams the lifecycle events of the containers until the context is done
+	Events(context.Context) (<-chan ContainerEvent, error)
+	// ExecContainer runs a command in a container based on ID, and returns its output
+	ExecContainer(string, []string) (*command.RunResult, error)
+	// CheckpointContainer saves the state of a running container based on ID to a directory on the node
+	CheckpointContainer(string, string) error
+	// RestoreContainer restores the state saved by CheckpointContainer into a container based on ID
+	RestoreContainer(string, string) error
 	// ContainerLogCmd returns the command to retrieve the log for a container based on ID
 	ContainerLogCmd(string, int, bool) string
 	// SystemLogCmd returns the command to return the system logs
 	SystemLogCmd(int) string
 	// Preload preloads the container runtime with k8s images
-	Preload(config.ClusterConfig) error
+	Preload(context.Context, config.ClusterConfig) error
 	// ImagesPreloaded returns true if all images have been preloaded
 	ImagesPreloaded([]string) bool
+	// InstallWasmShim installs a WebAssembly shim and declares its runtime handler
+	InstallWasmShim(context.Context, WasmShim) error
+	// InstallKata installs Kata Containers and declares the kata runtime handler
+	InstallKata(context.Context) error
+}
+
+// ConfigChange is what Enable changed about a runtime on a node
+type ConfigChange struct {
+	// Files are the configuration files of the runtime which Enable created, changed or removed
+	Files []string
+	// Restarted is set when Enable restarted the runtime
+	Restarted bool
+}
+
+// changeTracker is implemented by runtimes which track what Enable changed on the node
+type changeTracker interface {
+	appliedChange() ConfigChange
+}
+
+// AppliedChange returns what the last Enable of cr changed on the node.
+// Runtimes which don't track it are restarted by every Enable, with unknown changes to their files.
+func AppliedChange(cr Manager) ConfigChange {
+	if t, ok := cr.(changeTracker); ok {
+		return t.appliedChange()
+	}
+	return ConfigChange{Restarted: true}
 }
 
+// ImagePullProgress is called as each of a batch of total image pulls finishes, done counting the finished ones.
+// err is set when the pull of image failed.
+type ImagePullProgress func(done, total int, image string, err error)
+
 // Config is runtime configuration
 type Config struct {
 	// Type of runtime to create ("docker, "crio", etc)
@@ -158,6 +231,22 @@ type Config struct {
 	InsecureRegistry []string
 	// GPUs add GPU devices to the container
 	GPUs bool
+	// Debug enables debug logging of the runtime and of CRI requests
+	Debug bool
+	// DownloadTmpfsMB is the size of the tmpfs porto downloads image layers to, 0 disables it
+	DownloadTmpfsMB int
+	// FeatureGates enables experimental runtime features
+	FeatureGates FeatureGates
+	// Ulimits are the default ulimits of porto containers, in the format of ParseUlimits
+	Ulimits string
+	// AllowedUnsafeSysctls are the sysctls outside the safe set pods may set
+	AllowedUnsafeSysctls []string
+	// BuildCacheMB is the size the layer cache of image builds is kept under, 0 disables it
+	BuildCacheMB int
+	// SocketUser is the user given access to the runtime sockets without sudo, none when empty
+	SocketUser string
+	// RegistryCertsDir is the directory of the node registry certificates are read from, none when empty
+	RegistryCertsDir string
 }
 
 // ListContainersOptions are the options to use for listing containers
@@ -168,6 +257,43 @@ type ListContainersOptions struct {
 	Name string
 	// Namespaces is the namespaces to look into
 	Namespaces []string
+	// PodIDs are the IDs of the pod sandboxes to look into, all when empty
+	PodIDs []string
+}
+
+// ListPodsOptions are the options to use for listing pod sandboxes
+type ListPodsOptions struct {
+	// Namespaces is the namespaces to look into, all when empty
+	Namespaces []string
+	// Ready skips the sandboxes which are not ready, such as those of pods kubelet recreated
+	Ready bool
+}
+
+// Pod is a pod sandbox of a runtime. A pod restarted by kubelet may have several, of which one at most is ready.
+type Pod struct {
+	// ID is the ID of the sandbox, which its containers refer to
+	ID        string
+	Name      string
+	Namespace string
+	UID       string
+	Ready     bool
+}
+
+// PodContainers returns the IDs of the containers in state of the pods of the namespaces, or of all namespaces if there
+// are none. Containers are found through their pod sandboxes, so that they are told apart by pod rather than by name.
+func PodContainers(cr Manager, state ContainerState, namespaces []string) ([]string, error) {
+	pods, err := cr.ListPods(ListPodsOptions{Namespaces: namespaces})
+	if err != nil {
+		return nil, errors.Wrap(err, "list pods")
+	}
+	if len(pods) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(pods))
+	for i, p := range pods {
+		ids[i] = p.ID
+	}
+	return cr.ListContainers(ListContainersOptions{State: state, PodIDs: ids})
 }
 
 // ListImagesOptions are the options to use for listing images
@@ -179,11 +305,162 @@ type ListImage struct {
 	RepoDigests []string `json:"repoDigests" yaml:"repoDigests"`
 	RepoTags    []string `json:"repoTags" yaml:"repoTags"`
 	Size        string   `json:"size" yaml:"size"`
+	// Created is when the image was built in RFC 3339, empty when the runtime does not report it
+	Created string `json:"created,omitempty" yaml:"created,omitempty"`
+}
+
+// imageCreated formats the creation time of an image for ListImage, the zero time being unknown
+func imageCreated(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ContainerStats is the resource usage of a container, as reported by the runtime
+type ContainerStats struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	// Timestamp is when the usage was sampled
+	Timestamp time.Time `json:"timestamp"`
+	// CPUUsageNanoCores is the CPU usage averaged over a short window, 0 if the runtime does not report it
+	CPUUsageNanoCores uint64 `json:"cpuUsageNanoCores"`
+	// CPUUsageCoreNanoSeconds is the CPU time used since the container started
+	CPUUsageCoreNanoSeconds uint64 `json:"cpuUsageCoreNanoSeconds"`
+	// MemoryWorkingSetBytes is the memory in use that can't be reclaimed, which is what the memory limit applies to
+	MemoryWorkingSetBytes uint64 `json:"memoryWorkingSetBytes"`
+	// WritableLayerBytes is the disk used by the writable layer of the container, 0 if the runtime does not report it
+	WritableLayerBytes uint64 `json:"writableLayerBytes"`
+}
+
+// CPUNanoCores returns the CPU usage of the container in billionths of a core. Runtimes which don't average it
+// themselves only report cumulative CPU time, from which it is derived using an earlier sample prev.
+func (s ContainerStats) CPUNanoCores(prev ContainerStats) uint64 {
+	if s.CPUUsageNanoCores != 0 {
+		return s.CPUUsageNanoCores
+	}
+	elapsed := s.Timestamp.Sub(prev.Timestamp)
+	if elapsed <= 0 || s.CPUUsageCoreNanoSeconds < prev.CPUUsageCoreNanoSeconds {
+		return 0
+	}
+	return uint64(float64(s.CPUUsageCoreNanoSeconds-prev.CPUUsageCoreNanoSeconds) / elapsed.Seconds())
+}
+
+// RuntimeInfo describes a container runtime as it runs on a node
+type RuntimeInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Socket is the CRI socket kubelet talks to
+	Socket string `json:"socket"`
+	// CgroupDriver is "cgroupfs" or "systemd"
+	CgroupDriver string `json:"cgroupDriver"`
+	// StorageDriver is the storage driver or snapshotter of images, empty when the runtime has no choice of one
+	StorageDriver string `json:"storageDriver,omitempty"`
+	// StorageRoot is the directory the runtime keeps images and containers in
+	StorageRoot string `json:"storageRoot,omitempty"`
+	// Rootless is set when the node runs in a user namespace, as with the rootless KIC drivers
+	Rootless bool `json:"rootless"`
+}
+
+// DaemonStatus is the state of one of the daemons a container runtime is made of, such as portod or portoshim
+type DaemonStatus struct {
+	Name string `json:"name"`
+	// Active is set when the systemd service of the daemon runs
+	Active bool `json:"active"`
+	// Version is what the daemon announces on its socket, empty when it does not answer
+	Version string `json:"version,omitempty"`
+	// Socket is where the daemon serves requests, and Reachable is set when it answers them there
+	Socket    string `json:"socket"`
+	Reachable bool   `json:"reachable"`
+	// Error tells why the daemon does not answer on Socket
+	Error string `json:"error,omitempty"`
+}
+
+// Capabilities are the optional features of a runtime, so that commands can tell up front which ones it lacks
+type Capabilities struct {
+	// SupportsBuild is set when BuildImage builds images
+	SupportsBuild bool `json:"supportsBuild"`
+	// SupportsLoad is set when LoadImage loads image archives
+	SupportsLoad bool `json:"supportsLoad"`
+	// SupportsLoadLayout is set when LoadImageFromLayout loads OCI image layout directories
+	SupportsLoadLayout bool `json:"supportsLoadLayout"`
+	// SupportsSave is set when SaveImage and SaveImages save images to archives
+	SupportsSave bool `json:"supportsSave"`
+	// SupportsPush is set when PushImage pushes images to registries
+	SupportsPush bool `json:"supportsPush"`
+	// SupportsPause is set when PauseContainers and UnpauseContainers pause and unpause containers
+	SupportsPause bool `json:"supportsPause"`
+	// SupportsCheckpoint is set when CheckpointContainer and RestoreContainer checkpoint and restore containers
+	SupportsCheckpoint bool `json:"supportsCheckpoint"`
+	// SupportsWasm is set when InstallWasmShim installs WebAssembly shims
+	SupportsWasm bool `json:"supportsWasm"`
+	// SupportsKata is set when InstallKata installs Kata Containers
+	SupportsKata bool `json:"supportsKata"`
+}
+
+// fullUIDMap is the uid_map of a process outside of any user namespace
+const fullUIDMap = "0 0 4294967295"
+
+// nodeRootless returns whether the node runs in a user namespace. The node is assumed not to when that can't be told.
+func nodeRootless(cr CommandRunner) bool {
+	rr, err := cr.RunCmd(exec.Command("cat", "/proc/self/uid_map"))
+	if err != nil {
+		klog.Warningf("unable to tell whether the node is rootless: %v", err)
+		return false
+	}
+	return strings.Join(strings.Fields(rr.Stdout.String()), " ") != fullUIDMap
+}
+
+// ContainerEvent is a lifecycle event of a container, as reported by the runtime
+type ContainerEvent struct {
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+	// Type is what happened to the container: created, started, stopped or deleted
+	Type         string `json:"type"`
+	ContainerID  string `json:"containerID"`
+	Container    string `json:"container"`
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	// ExitCode and Reason tell why a stopped container exited
+	ExitCode int32  `json:"exitCode,omitempty"`
+	Reason   string `json:"reason,omitempty"`
 }
 
 // ErrContainerRuntimeNotRunning is thrown when container runtime is not running
 var ErrContainerRuntimeNotRunning = errors.New("container runtime is not running")
 
+// ErrPodNotFound is returned by PodContainer when the node does not run the pod
+var ErrPodNotFound = errors.New("pod not found on the node")
+
+// ErrImageNotFound is returned by ImageDigest when the runtime lacks the image
+var ErrImageNotFound = errors.New("image not found in the container runtime")
+
+// SameDigest returns whether the digests a and b are the same, with or without their "sha256:" algorithm prefix.
+// Unlike a substring match it tells apart digests sharing a prefix, and an empty digest matches nothing.
+func SameDigest(a, b string) bool {
+	a, b = strings.TrimPrefix(strings.ToLower(a), "sha256:"), strings.TrimPrefix(strings.ToLower(b), "sha256:")
+	return a != "" && a == b
+}
+
+// imageExists returns whether cr has the image name, at a digest containing sha unless it is empty.
+// Callers which know the whole digest compare it with SameDigest instead.
+func imageExists(cr Manager, name string, sha string) bool {
+	klog.Infof("Checking existence of image with name %q and sha %q", name, sha)
+	d, err := cr.ImageDigest(name)
+	if err != nil {
+		if !errors.Is(err, ErrImageNotFound) {
+			klog.Infof("digest of %s: %v", name, err)
+		}
+		return false
+	}
+	return sha == "" || strings.Contains(d, sha)
+}
+
+// ErrCheckpointNotSupported is returned by runtimes which can't checkpoint and restore containers
+var ErrCheckpointNotSupported = errors.New("checkpointing containers is not supported by the container runtime")
+
 // ErrServiceVersion is the error returned when disk image has incompatible version of service
 type ErrServiceVersion struct {
 	// Service is the name of the incompatible service
@@ -251,12 +528,20 @@ func New(c Config) (Manager, error) {
 		}, nil
 	case "porto":
 		return &Porto{
-			Socket:            c.Socket,
-			Runner:            c.Runner,
-			ImageRepository:   c.ImageRepository,
-			KubernetesVersion: c.KubernetesVersion,
-			Init:              sm,
-			InsecureRegistry:  c.InsecureRegistry,
+			Socket:               c.Socket,
+			Runner:               c.Runner,
+			ImageRepository:      c.ImageRepository,
+			KubernetesVersion:    c.KubernetesVersion,
+			Init:                 sm,
+			InsecureRegistry:     c.InsecureRegistry,
+			Debug:                c.Debug,
+			DownloadTmpfsMB:      c.DownloadTmpfsMB,
+			FeatureGates:         c.FeatureGates,
+			Ulimits:              c.Ulimits,
+			AllowedUnsafeSysctls: c.AllowedUnsafeSysctls,
+			BuildCacheMB:         c.BuildCacheMB,
+			SocketUser:           c.SocketUser,
+			RegistryCertsDir:     c.RegistryCertsDir,
 		}, nil
 	default:
 		return nil, fmt.Errorf("unknown runtime type: %q", c.Type)
@@ -296,7 +581,7 @@ func disableOthers(me Manager, cr CommandRunner) error {
 			continue
 		}
 
-		if err = r.Disable(); err != nil {
+		if err = r.Disable(contextOf(cr)); err != nil {
 			klog.Warningf("disable failed: %v", err)
 		}
 
@@ -325,13 +610,19 @@ func compatibleWithVersion(runtime, v string) error {
 }
 
 // CheckCompatibility checks if the container runtime managed by "cr" is compatible with current minikube code
-// returns: NewErrServiceVersion if not
-func CheckCompatibility(cr Manager) error {
-	v, err := cr.Version()
+// returns: NewErrServiceVersion if not, or ErrCRIVersion if its CRI implementation is too old for the Kubernetes version
+func CheckCompatibility(ctx context.Context, cr Manager) error {
+	v, err := cr.Version(ctx)
 	if err != nil {
 		return errors.Wrap(err, "Failed to check container runtime version")
 	}
-	return compatibleWithVersion(cr.Name(), v)
+	if err := compatibleWithVersion(cr.Name(), v); err != nil {
+		return err
+	}
+	if p, ok := cr.(*Porto); ok {
+		return p.checkCRIVersion()
+	}
+	return nil
 }
 
 // CheckKernelCompatibility returns an error when the kernel is older than the specified version.
@@ -18,10 +18,12 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/google/go-cmp/cmp"
@@ -118,6 +120,27 @@ func TestImageExists(t *testing.T) {
 	}
 }
 
+func TestSameDigest(t *testing.T) {
+	const d = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	var tests = []struct {
+		a, b string
+		want bool
+	}{
+		{d, d, true},
+		{"sha256:" + d, d, true},
+		{"sha256:" + strings.ToUpper(d), "sha256:" + d, true},
+		// a prefix is not the digest
+		{d[:12], d, false},
+		{"", "", false},
+		{"sha256:", "", false},
+	}
+	for _, tc := range tests {
+		if got := SameDigest(tc.a, tc.b); got != tc.want {
+			t.Errorf("SameDigest(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
 func TestCGroupDriver(t *testing.T) {
 	var tests = []struct {
 		runtime string
@@ -481,6 +504,12 @@ func (f *FakeRunner) crictl(args []string, _ bool) (string, error) {
 			delete(f.containers, id)
 
 		}
+	case "inspecti":
+		name := args[len(args)-1]
+		if f.images[name] == "" {
+			return "", fmt.Errorf("no such image %q present", name)
+		}
+		return fmt.Sprintf(`{"status": {"id": "sha256:%s"}}`, f.images[name]), nil
 	case "rmi":
 		for _, id := range args[1:] {
 			f.t.Logf("fake crictl: Removing id %q", id)
@@ -602,7 +631,7 @@ func TestVersion(t *testing.T) {
 			if err != nil {
 				t.Fatalf("New(%s): %v", tc.runtime, err)
 			}
-			got, err := r.Version()
+			got, err := r.Version(context.Background())
 			if err != nil {
 				t.Fatalf("Version(%s): %v", tc.runtime, err)
 			}
@@ -651,7 +680,7 @@ func TestDisable(t *testing.T) {
 			if err != nil {
 				t.Fatalf("New(%s): %v", tc.runtime, err)
 			}
-			err = cr.Disable()
+			err = cr.Disable(context.Background())
 			if err != nil {
 				t.Errorf("%s disable unexpected error: %v", tc.runtime, err)
 			}
@@ -712,7 +741,7 @@ func TestEnable(t *testing.T) {
 			if err != nil {
 				t.Fatalf("New(%s): %v", tc.runtime, err)
 			}
-			err = cr.Enable(true, constants.CgroupfsCgroupDriver, false)
+			err = cr.Enable(context.Background(), true, constants.CgroupfsCgroupDriver, false)
 			if err != nil {
 				t.Errorf("%s disable unexpected error: %v", tc.runtime, err)
 			}
@@ -799,7 +828,7 @@ func TestContainerFunctions(t *testing.T) {
 			}
 
 			// Remove a image
-			if err := cr.RemoveImage("image1"); err != nil {
+			if err := cr.RemoveImage(context.Background(), "image1"); err != nil {
 				t.Fatalf("RemoveImage: %v", err)
 			}
 			if len(runner.images) > 0 {
@@ -808,3 +837,275 @@ func TestContainerFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPortoCNIPlugins(t *testing.T) {
+	const (
+		v100 = `{"cniVersion":"1.0.0","supportedVersions":["0.1.0","0.2.0","0.3.0","0.3.1","0.4.0","1.0.0"]}`
+		v040 = `{"cniVersion":"0.4.0","supportedVersions":["0.1.0","0.2.0","0.3.0","0.3.1","0.4.0"]}`
+	)
+	k8s := semver.MustParse("1.28.0")
+
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"ls -1 /opt/cni/bin":                              "bridge\nhost-local\nloopback\n",
+		"env CNI_COMMAND=VERSION /opt/cni/bin/bridge":     v100,
+		"env CNI_COMMAND=VERSION /opt/cni/bin/host-local": v100,
+		"env CNI_COMMAND=VERSION /opt/cni/bin/loopback":   v040,
+	})
+	err := checkPortoCNIPlugins(runner, k8s)
+	if err == nil {
+		t.Fatalf("checkPortoCNIPlugins: expected an error for the missing and incompatible plugins")
+	}
+	for _, want := range []string{"portmap: missing", "loopback: CNI spec 1.0.0 not supported"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("checkPortoCNIPlugins error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "bridge") || strings.Contains(err.Error(), "host-local") {
+		t.Errorf("checkPortoCNIPlugins error %q mentions usable plugins", err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		"ls -1 /opt/cni/bin":                            "bridge\nhost-local\nloopback\nportmap\n",
+		"env CNI_COMMAND=VERSION /opt/cni/bin/loopback": v100,
+		"env CNI_COMMAND=VERSION /opt/cni/bin/portmap":  v100,
+	})
+	if err := checkPortoCNIPlugins(runner, k8s); err != nil {
+		t.Fatalf("checkPortoCNIPlugins: unexpected error: %v", err)
+	}
+	if err := checkPortoCNIPlugins(command.NewFakeCommandRunner(), semver.MustParse("1.23.0")); err != nil {
+		t.Errorf("checkPortoCNIPlugins: Kubernetes before 1.24 should not be checked, got %v", err)
+	}
+}
+
+func TestPodContainer(t *testing.T) {
+	const (
+		pods = "sudo crictl pods --namespace ^default$ --name ^web$ --state ready --quiet"
+		ps   = "sudo crictl ps --pod 8c1e --state running --output json"
+	)
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		pods:                     "8c1e\n",
+		ps:                       `{"containers":[{"id":"a1","metadata":{"name":"nginx"}},{"id":"b2","metadata":{"name":"sidecar"}}]}`,
+		ps + " --name ^sidecar$": `{"containers":[{"id":"b2","metadata":{"name":"sidecar"}}]}`,
+		ps + " --name ^db$":      `{"containers":[]}`,
+	})
+
+	if id, err := PodContainer(runner, "default", "web", "sidecar"); err != nil || id != "b2" {
+		t.Errorf("PodContainer(sidecar) = %q, %v, want b2", id, err)
+	}
+	if _, err := PodContainer(runner, "default", "web", ""); err == nil || !strings.Contains(err.Error(), "nginx, sidecar") {
+		t.Errorf("PodContainer without a container should list them, got %v", err)
+	}
+	if _, err := PodContainer(runner, "default", "web", "db"); err == nil {
+		t.Errorf("PodContainer(db): expected an error for a missing container")
+	}
+
+	runner.SetCommandToOutput(map[string]string{pods: ""})
+	if _, err := PodContainer(runner, "default", "web", ""); !errors.Is(err, ErrPodNotFound) {
+		t.Errorf("PodContainer on a node without the pod = %v, want ErrPodNotFound", err)
+	}
+}
+
+func TestCRIPods(t *testing.T) {
+	const pods = "sudo crictl pods --namespace ^kube-system$ --output json"
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		pods: `{"items":[
+			{"id":"s1","metadata":{"name":"etcd-minikube","uid":"u1"},"state":"SANDBOX_NOTREADY"},
+			{"id":"s2","metadata":{"name":"etcd-minikube","uid":"u1"},"state":"SANDBOX_READY"},
+			{"id":"s3","metadata":{"name":"coredns-5dd5756b68-x2x4q","uid":"u2"},"state":"SANDBOX_READY"}]}`,
+		"sudo crictl ps --state running --output json": `{"containers":[
+			{"id":"c1","podSandboxId":"s2","metadata":{"name":"etcd"}},
+			{"id":"c2","podSandboxId":"d9","metadata":{"name":"nginx"}}]}`,
+	})
+
+	got, err := CRIPods(runner, "kube-system")
+	if err != nil {
+		t.Fatalf("CRIPods: %v", err)
+	}
+	want := map[string]CRIPod{
+		"u1": {Name: "etcd-minikube", Ready: true, Running: 1},
+		"u2": {Name: "coredns-5dd5756b68-x2x4q", Ready: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CRIPods mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerPods(t *testing.T) {
+	const (
+		etcd    = "3f1b2c9d8e7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c"
+		coredns = "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b"
+	)
+	key := func(args ...string) string {
+		return (&command.RunResult{Args: append([]string{"docker"}, args...)}).Command()
+	}
+	ps := []string{"ps", "-a", "--filter=name=k8s_", "--format={{.ID}}"}
+	runner := command.NewFakeCommandRunner()
+	// as printed by docker 24 for the pause containers of a control plane node
+	runner.SetCommandToOutput(map[string]string{
+		key("ps", "-a", "--no-trunc", "--filter=name=k8s_POD_",
+			`--format={{.ID}}|{{.Label "io.kubernetes.pod.name"}}|{{.Label "io.kubernetes.pod.namespace"}}|{{.Label "io.kubernetes.pod.uid"}}|{{.State}}`): etcd + "|etcd-minikube|kube-system|a4b8e1f0c2d3|running\n" +
+			coredns + "|coredns-5dd5756b68-x2x4q|kube-system|7c1d2e3f4a5b|exited\n",
+		key(append(ps, "--filter=label=io.kubernetes.sandbox.id="+etcd)...):    "0c5a7f6e5d4c\n6e0b1a2c3d4e\n",
+		key(append(ps, "--filter=label=io.kubernetes.sandbox.id="+coredns)...): "",
+	})
+	r, err := New(Config{Type: "docker", Runner: runner})
+	if err != nil {
+		t.Fatalf("New(docker): %v", err)
+	}
+
+	pods, err := r.ListPods(ListPodsOptions{Namespaces: []string{"kube-system"}})
+	if err != nil {
+		t.Fatalf("ListPods: %v", err)
+	}
+	want := []Pod{
+		{ID: etcd, Name: "etcd-minikube", Namespace: "kube-system", UID: "a4b8e1f0c2d3", Ready: true},
+		{ID: coredns, Name: "coredns-5dd5756b68-x2x4q", Namespace: "kube-system", UID: "7c1d2e3f4a5b"},
+	}
+	if diff := cmp.Diff(want, pods); diff != "" {
+		t.Fatalf("ListPods mismatch (-want +got):\n%s", diff)
+	}
+
+	ids, err := r.ListContainers(ListContainersOptions{State: All, PodIDs: []string{pods[0].ID, pods[1].ID}})
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if diff := cmp.Diff([]string{"0c5a7f6e5d4c", "6e0b1a2c3d4e"}, ids); diff != "" {
+		t.Errorf("ListContainers of the pods mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPopulateCRIConfigChecked(t *testing.T) {
+	criConfigRetryInterval = time.Millisecond
+	const (
+		config = "runtime-endpoint: unix:///run/portoshim.sock\n"
+		cat    = "sudo cat /etc/crictl.yaml"
+	)
+	write := command.RunResult{Args: []string{"sudo", "/bin/bash", "-c", "mkdir -p /etc && printf %s 'runtime-endpoint: unix:///run/portoshim.sock\n' > /etc/crictl.yaml.minikube && mv -f /etc/crictl.yaml.minikube /etc/crictl.yaml"}}
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "timeout 120 systemctl is-system-running --wait 2>/dev/null; true"`: "degraded",
+		write.Command(): "",
+		cat:             config,
+		"sudo crictl config --get runtime-endpoint": "unix:///run/portoshim.sock",
+	})
+	if err := populateCRIConfigChecked(runner, "/run/portoshim.sock", false); err != nil {
+		t.Fatalf("populateCRIConfigChecked: %v", err)
+	}
+
+	// a default configuration generated at the same time got mixed into it
+	runner.SetCommandToOutput(map[string]string{cat: config + "runtime-endpoint: unix:///run/containerd/containerd.sock\n"})
+	if err := populateCRIConfigChecked(runner, "/run/portoshim.sock", false); err == nil || !strings.Contains(err.Error(), "after 5 attempts") {
+		t.Errorf("populateCRIConfigChecked: expected to give up on a configuration which keeps being changed, got %v", err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		cat: config,
+		"sudo crictl config --get runtime-endpoint": "unix:///run/containerd/containerd.sock",
+	})
+	if err := populateCRIConfigChecked(runner, "/run/portoshim.sock", false); err == nil {
+		t.Errorf("populateCRIConfigChecked: expected an error when crictl reads another endpoint")
+	}
+}
+
+func TestCopyIntoContainer(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "tar -C /var/lib/minikube/staging/cp-1 -cf - index.html | crictl exec -i b2 tar -xf - -C /usr/share/nginx/html"`: "",
+	})
+	if err := CopyIntoContainer(runner, "b2", "/var/lib/minikube/staging/cp-1/index.html", "/usr/share/nginx/html"); err != nil {
+		t.Errorf("CopyIntoContainer: %v", err)
+	}
+	if err := CopyIntoContainer(runner, "b2", "/var/lib/minikube/staging/cp-1/index.html", "/srv"); err == nil {
+		t.Errorf("CopyIntoContainer: expected the failing copy to be reported")
+	}
+}
+
+func TestRuntimeInfo(t *testing.T) {
+	const uidMap = "cat /proc/self/uid_map"
+	var tests = []struct {
+		runtime string
+		outputs map[string]string
+		want    RuntimeInfo
+	}{
+		{
+			runtime: "docker",
+			outputs: map[string]string{
+				`docker info --format "{{json .}}"`: `{"ServerVersion":"27.2.0","CgroupDriver":"cgroupfs","Driver":"overlay2","DockerRootDir":"/var/lib/docker"}`,
+				uidMap:                              "         0          0 4294967295\n",
+			},
+			want: RuntimeInfo{Name: "Docker", Version: "27.2.0", Socket: InternalDockerCRISocket, CgroupDriver: "cgroupfs", StorageDriver: "overlay2", StorageRoot: "/var/lib/docker"},
+		},
+		{
+			runtime: "containerd",
+			outputs: map[string]string{
+				"containerd --version": "containerd github.com/containerd/containerd v1.7.22 7f7fdf5fed64eb6a7caf99b3e12efcf9d60e311c",
+				"sudo crictl info":     `{"config":{"containerd":{"snapshotter":"overlayfs","runtimes":{"runc":{"options":{"SystemdCgroup":true}}}},"containerdRootDir":"/var/lib/containerd"}}`,
+				uidMap:                 "         0     100000      65536\n",
+			},
+			want: RuntimeInfo{Name: "containerd", Version: "1.7.22", Socket: "/run/containerd/containerd.sock", CgroupDriver: "systemd", StorageDriver: "overlayfs", StorageRoot: "/var/lib/containerd", Rootless: true},
+		},
+		{
+			runtime: "crio",
+			outputs: map[string]string{
+				"crio --version": "crio version 1.29.1\n",
+				"crio config":    "[crio]\nroot = \"/var/lib/containers/storage\"\nroot_dir = \"/x\"\nstorage_driver = \"overlay\"\n[crio.runtime]\ncgroup_manager = \"cgroupfs\"\n",
+				uidMap:           "         0          0 4294967295\n",
+			},
+			want: RuntimeInfo{Name: "CRI-O", Version: "1.29.1", Socket: "/var/run/crio/crio.sock", CgroupDriver: "cgroupfs", StorageDriver: "overlay", StorageRoot: "/var/lib/containers/storage"},
+		},
+		{
+			runtime: "porto",
+			outputs: map[string]string{
+				"portod version": "running: 5.3.31  /usr/sbin/portod",
+				uidMap:           "         0          0 4294967295\n",
+			},
+			want: RuntimeInfo{Name: "porto", Version: "5.3.31", Socket: "/run/portoshim.sock", CgroupDriver: "systemd", StorageRoot: portoPlace},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(tc.outputs)
+			cr, err := New(Config{Type: tc.runtime, Runner: runner})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			got, err := cr.RuntimeInfo()
+			if err != nil {
+				t.Fatalf("RuntimeInfo: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RuntimeInfo mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	all := Capabilities{SupportsBuild: true, SupportsLoad: true, SupportsLoadLayout: true, SupportsSave: true, SupportsPush: true, SupportsPause: true}
+	tests := []struct {
+		runtime string
+		gates   FeatureGates
+		want    Capabilities
+	}{
+		{"docker", nil, all},
+		{"containerd", nil, Capabilities{SupportsBuild: true, SupportsLoad: true, SupportsLoadLayout: true, SupportsSave: true, SupportsPush: true, SupportsPause: true, SupportsWasm: true, SupportsKata: true}},
+		{"crio", nil, Capabilities{SupportsBuild: true, SupportsLoad: true, SupportsLoadLayout: true, SupportsSave: true, SupportsPush: true, SupportsPause: true, SupportsKata: true}},
+		{"porto", nil, Capabilities{SupportsBuild: true, SupportsLoad: true, SupportsLoadLayout: true, SupportsSave: true, SupportsPause: true}},
+		{"porto", FeatureGates{FeatureSandboxCheckpointing: true}, Capabilities{SupportsBuild: true, SupportsLoad: true, SupportsLoadLayout: true, SupportsSave: true, SupportsPause: true, SupportsCheckpoint: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.runtime, func(t *testing.T) {
+			cr, err := New(Config{Type: tc.runtime, FeatureGates: tc.gates})
+			if err != nil {
+				t.Fatalf("New(%s): %v", tc.runtime, err)
+			}
+			if diff := cmp.Diff(tc.want, cr.Capabilities()); diff != "" {
+				t.Errorf("Capabilities(%s) mismatch (-want +got):\n%s", tc.runtime, diff)
+			}
+		})
+	}
+}
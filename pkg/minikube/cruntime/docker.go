@@ -18,8 +18,10 @@ package cruntime
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -78,6 +80,14 @@ type Docker struct {
 	GPUs              bool
 }
 
+// bind returns a copy of r which runs its commands within ctx
+func (r *Docker) bind(ctx context.Context) *Docker {
+	b := *r
+	b.Runner = withContext(ctx, r.Runner)
+	b.Init = sysinit.WithRunner(r.Init, b.Runner)
+	return &b
+}
+
 // Name is a human readable name for Docker
 func (r *Docker) Name() string {
 	return "Docker"
@@ -89,7 +99,8 @@ func (r *Docker) Style() style.Enum {
 }
 
 // Version retrieves the current version of this runtime
-func (r *Docker) Version() (string, error) {
+func (r *Docker) Version(ctx context.Context) (string, error) {
+	r = r.bind(ctx)
 	// Note: the server daemon has to be running, for this call to return successfully
 	c := exec.Command("docker", "version", "--format", "{{.Server.Version}}")
 	rr, err := r.Runner.RunCmd(c)
@@ -107,6 +118,11 @@ func (r *Docker) SocketPath() string {
 	return InternalDockerCRISocket
 }
 
+// ShouldRestartKubelet returns false, as nothing kubelet reads is in the docker configuration
+func (r *Docker) ShouldRestartKubelet(change ConfigChange) bool {
+	return false
+}
+
 // Available returns an error if it is not possible to use this runtime on a host
 func (r *Docker) Available() error {
 	// If Kubernetes version >= 1.24, require both cri-dockerd and dockerd.
@@ -131,7 +147,8 @@ func (r *Docker) Active() bool {
 }
 
 // Enable idempotently enables Docker on a host
-func (r *Docker) Enable(disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+func (r *Docker) Enable(ctx context.Context, disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+	r = r.bind(ctx)
 	if inUserNamespace {
 		if err := CheckKernelCompatibility(r.Runner, 5, 11); err != nil {
 			// For using overlayfs
@@ -148,7 +165,7 @@ func (r *Docker) Enable(disOthers bool, cgroupDriver string, inUserNamespace boo
 		}
 	}
 
-	if err := populateCRIConfig(r.Runner, r.SocketPath()); err != nil {
+	if err := populateCRIConfig(r.Runner, r.SocketPath(), false); err != nil {
 		return err
 	}
 	if err := generateCRIDockerdConfig(r.Runner, r.ImageRepository, r.KubernetesVersion, r.NetworkPlugin); err != nil {
@@ -198,7 +215,8 @@ func (r *Docker) Restart() error {
 }
 
 // Disable idempotently disables Docker on a host
-func (r *Docker) Disable() error {
+func (r *Docker) Disable(ctx context.Context) error {
+	r = r.bind(ctx)
 	// even if r.CRIService is undefined, it might still be available, so try to disable it and just warn then fallthrough if unsuccessful
 	klog.Info("disabling cri-docker service (if available) ...")
 	criSocket := "cri-docker.socket"
@@ -233,16 +251,20 @@ func (r *Docker) Disable() error {
 
 // ImageExists checks if image exists based on image name and optionally image sha
 func (r *Docker) ImageExists(name string, sha string) bool {
+	return imageExists(r, name, sha)
+}
+
+// ImageDigest returns the ID of an image based on name
+func (r *Docker) ImageDigest(name string) (string, error) {
 	// expected output looks like [SHA_ALGO:SHA]
-	c := exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", name)
-	rr, err := r.Runner.RunCmd(c)
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", name))
 	if err != nil {
-		return false
-	}
-	if sha != "" && !strings.Contains(rr.Output(), sha) {
-		return false
+		if strings.Contains(rr.Stderr.String(), "No such image") {
+			return "", errors.Wrap(ErrImageNotFound, name)
+		}
+		return "", errors.Wrap(err, "docker image inspect")
 	}
-	return true
+	return strings.TrimSpace(rr.Stdout.String()), nil
 }
 
 // ListImages returns a list of images managed by this container runtime
@@ -257,6 +279,7 @@ func (r *Docker) ListImages(ListImagesOptions) ([]ListImage, error) {
 		Repository string `json:"Repository"`
 		Tag        string `json:"Tag"`
 		Size       string `json:"Size"`
+		CreatedAt  string `json:"CreatedAt"`
 	}
 	images := strings.Split(rr.Stdout.String(), "\n")
 	result := []ListImage{}
@@ -280,13 +303,15 @@ func (r *Docker) ListImages(ListImagesOptions) ([]ListImage, error) {
 			RepoDigests: []string{},
 			RepoTags:    []string{addDockerIO(repoTag)},
 			Size:        fmt.Sprintf("%d", size),
+			Created:     imageCreated(parseImageCreated(jsonImage.CreatedAt)),
 		})
 	}
 	return result, nil
 }
 
 // LoadImage loads an image into this runtime
-func (r *Docker) LoadImage(path string) error {
+func (r *Docker) LoadImage(ctx context.Context, path string) error {
+	r = r.bind(ctx)
 	klog.Infof("Loading image: %s", path)
 	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("sudo cat %s | docker load", path))
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -295,11 +320,26 @@ func (r *Docker) LoadImage(path string) error {
 	return nil
 }
 
+// LoadImageFromLayout loads the images of an OCI image layout directory on the host into this runtime
+func (r *Docker) LoadImageFromLayout(ctx context.Context, dir string) error {
+	r = r.bind(ctx)
+	klog.Infof("Loading OCI layout: %s", dir)
+	return loadOCILayout(dir, func(stream io.Reader) error {
+		c := exec.Command("docker", "load")
+		c.Stdin = stream
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrap(err, "loadimage docker")
+		}
+		return nil
+	})
+}
+
 // PullImage pulls an image
-func (r *Docker) PullImage(name string) error {
+func (r *Docker) PullImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
 	klog.Infof("Pulling image: %s", name)
 	if r.UseCRI {
-		return pullCRIImage(r.Runner, name)
+		return pullCRIImage(r.Runner, r.SocketPath(), name)
 	}
 	c := exec.Command("docker", "pull", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -309,9 +349,15 @@ func (r *Docker) PullImage(name string) error {
 }
 
 // SaveImage saves an image from this runtime
-func (r *Docker) SaveImage(name string, path string) error {
-	klog.Infof("Saving image %s: %s", name, path)
-	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("docker save '%s' | sudo tee %s >/dev/null", name, path))
+func (r *Docker) SaveImage(ctx context.Context, name string, path string) error {
+	return r.SaveImages(ctx, []string{name}, path)
+}
+
+// SaveImages saves images from this runtime into one docker-archive
+func (r *Docker) SaveImages(ctx context.Context, names []string, path string) error {
+	r = r.bind(ctx)
+	klog.Infof("Saving images %s: %s", names, path)
+	c := exec.Command("/bin/bash", "-c", fmt.Sprintf("docker save '%s' | sudo tee %s >/dev/null", strings.Join(names, "' '"), path))
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "saveimage docker")
 	}
@@ -319,10 +365,11 @@ func (r *Docker) SaveImage(name string, path string) error {
 }
 
 // RemoveImage removes a image
-func (r *Docker) RemoveImage(name string) error {
+func (r *Docker) RemoveImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
 	klog.Infof("Removing image: %s", name)
 	if r.UseCRI {
-		return removeCRIImage(r.Runner, name)
+		return removeCRIImage(r.Runner, r.SocketPath(), name)
 	}
 	c := exec.Command("docker", "rmi", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -332,7 +379,8 @@ func (r *Docker) RemoveImage(name string) error {
 }
 
 // TagImage tags an image in this runtime
-func (r *Docker) TagImage(source string, target string) error {
+func (r *Docker) TagImage(ctx context.Context, source string, target string) error {
+	r = r.bind(ctx)
 	klog.Infof("Tagging image %s: %s", source, target)
 	c := exec.Command("docker", "tag", source, target)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -342,7 +390,8 @@ func (r *Docker) TagImage(source string, target string) error {
 }
 
 // BuildImage builds an image into this runtime
-func (r *Docker) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
+func (r *Docker) BuildImage(ctx context.Context, src string, file string, tag string, push bool, env []string, opts []string) error {
+	r = r.bind(ctx)
 	klog.Infof("Building image: %s", src)
 	args := []string{"build"}
 	if file != "" {
@@ -376,7 +425,8 @@ func (r *Docker) BuildImage(src string, file string, tag string, push bool, env
 }
 
 // PushImage pushes an image
-func (r *Docker) PushImage(name string) error {
+func (r *Docker) PushImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
 	klog.Infof("Pushing image: %s", name)
 	c := exec.Command("docker", "push", name)
 	if _, err := r.Runner.RunCmd(c); err != nil {
@@ -396,6 +446,57 @@ func (r *Docker) CGroupDriver() (string, error) {
 	return strings.Split(rr.Stdout.String(), "\n")[0], nil
 }
 
+// Capabilities returns the optional features docker supports, all but checkpoints
+func (r *Docker) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsBuild:      true,
+		SupportsLoad:       true,
+		SupportsLoadLayout: true,
+		SupportsSave:       true,
+		SupportsPush:       true,
+		SupportsPause:      true,
+	}
+}
+
+// RuntimeInfo describes docker on the host, from a single call of docker info
+func (r *Docker) RuntimeInfo() (RuntimeInfo, error) {
+	// Note: the server daemon has to be running, for this call to return successfully
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "info", "--format", "{{json .}}"))
+	if err != nil {
+		return RuntimeInfo{}, errors.Wrap(err, "docker info")
+	}
+	var info struct {
+		ServerVersion string
+		CgroupDriver  string
+		Driver        string
+		DockerRootDir string
+	}
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &info); err != nil {
+		return RuntimeInfo{}, errors.Wrap(err, "parsing docker info")
+	}
+	return RuntimeInfo{
+		Name:          r.Name(),
+		Version:       info.ServerVersion,
+		Socket:        r.SocketPath(),
+		CgroupDriver:  info.CgroupDriver,
+		StorageDriver: info.Driver,
+		StorageRoot:   info.DockerRootDir,
+		Rootless:      nodeRootless(r.Runner),
+	}, nil
+}
+
+// Healthy returns an error unless dockerd answers requests, and cri-dockerd CRI requests when kubelet talks to it
+func (r *Docker) Healthy(ctx context.Context) error {
+	r = r.bind(ctx)
+	if _, err := r.Version(ctx); err != nil {
+		return errors.Wrap(err, "dockerd")
+	}
+	if r.UseCRI {
+		return errors.Wrap(criHealthy(r.Runner, r.SocketPath()), "cri-dockerd")
+	}
+	return nil
+}
+
 // KubeletOptions returns kubelet options for a runtime.
 func (r *Docker) KubeletOptions() map[string]string {
 	if r.UseCRI {
@@ -409,7 +510,7 @@ func (r *Docker) KubeletOptions() map[string]string {
 // ListContainers returns a list of containers
 func (r *Docker) ListContainers(o ListContainersOptions) ([]string, error) {
 	if r.UseCRI {
-		return listCRIContainers(r.Runner, "", o)
+		return listCRIContainers(r.Runner, r.SocketPath(), "", o)
 	}
 	args := []string{"ps"}
 	switch o.State {
@@ -428,23 +529,78 @@ func (r *Docker) ListContainers(o ListContainersOptions) ([]string, error) {
 	}
 
 	args = append(args, fmt.Sprintf("--filter=name=%s", nameFilter), "--format={{.ID}}")
+	// label filters of docker all have to match, so every pod takes a call of its own
+	podFilters := []string{""}
+	if len(o.PodIDs) > 0 {
+		podFilters = nil
+		for _, id := range o.PodIDs {
+			podFilters = append(podFilters, fmt.Sprintf("--filter=label=%s=%s", dockerSandboxIDLabel, id))
+		}
+	}
+	var ids []string
+	for _, f := range podFilters {
+		cmd := args
+		if f != "" {
+			cmd = append(append([]string{}, args...), f)
+		}
+		rr, err := r.Runner.RunCmd(exec.Command("docker", cmd...))
+		if err != nil {
+			return nil, errors.Wrapf(err, "docker")
+		}
+		for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+			if line != "" {
+				ids = append(ids, line)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// dockerSandboxIDLabel is the label dockershim puts on containers with the ID of their pod sandbox
+const dockerSandboxIDLabel = "io.kubernetes.sandbox.id"
+
+// ListPods returns the pod sandboxes of docker. Without cri-dockerd they are the pause containers of dockershim.
+func (r *Docker) ListPods(o ListPodsOptions) ([]Pod, error) {
+	if r.UseCRI {
+		return listCRIPods(r.Runner, r.SocketPath(), o)
+	}
+	// the full IDs, which the io.kubernetes.sandbox.id labels of the containers of the pods hold
+	args := []string{"ps", "-a", "--no-trunc", "--filter=name=" + KubernetesContainerPrefix + "POD_",
+		`--format={{.ID}}|{{.Label "io.kubernetes.pod.name"}}|{{.Label "io.kubernetes.pod.namespace"}}|{{.Label "io.kubernetes.pod.uid"}}|{{.State}}`}
+	if o.Ready {
+		args = append(args, "--filter", "status=running")
+	}
 	rr, err := r.Runner.RunCmd(exec.Command("docker", args...))
 	if err != nil {
 		return nil, errors.Wrapf(err, "docker")
 	}
-	var ids []string
+	namespaces := map[string]bool{}
+	for _, ns := range o.Namespaces {
+		namespaces[ns] = true
+	}
+	var pods []Pod
 	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
-		if line != "" {
-			ids = append(ids, line)
+		f := strings.Split(line, "|")
+		if len(f) != 5 {
+			continue
+		}
+		if len(namespaces) > 0 && !namespaces[f[2]] {
+			continue
 		}
+		pods = append(pods, Pod{ID: f[0], Name: f[1], Namespace: f[2], UID: f[3], Ready: f[4] == "running"})
 	}
-	return ids, nil
+	return pods, nil
+}
+
+// ContainersForPod returns the IDs of the containers in state of a pod sandbox based on ID
+func (r *Docker) ContainersForPod(id string, state ContainerState) ([]string, error) {
+	return r.ListContainers(ListContainersOptions{State: state, PodIDs: []string{id}})
 }
 
 // KillContainers forcibly removes a running container based on ID
 func (r *Docker) KillContainers(ids []string) error {
 	if r.UseCRI {
-		return killCRIContainers(r.Runner, ids)
+		return killCRIContainers(r.Runner, r.SocketPath(), ids)
 	}
 	if len(ids) == 0 {
 		return nil
@@ -461,7 +617,7 @@ func (r *Docker) KillContainers(ids []string) error {
 // StopContainers stops a running container based on ID
 func (r *Docker) StopContainers(ids []string) error {
 	if r.UseCRI {
-		return stopCRIContainers(r.Runner, ids)
+		return stopCRIContainers(r.Runner, r.SocketPath(), ids)
 	}
 	if len(ids) == 0 {
 		return nil
@@ -509,6 +665,45 @@ func (r *Docker) UnpauseContainers(ids []string) error {
 	return nil
 }
 
+// ContainerStats returns the resource usage of containers based on ID, or of all containers if none are given
+func (r *Docker) ContainerStats(ids []string) ([]ContainerStats, error) {
+	if r.UseCRI {
+		return criContainerStats(r.Runner, r.SocketPath(), ids)
+	}
+	return nil, errors.New("container stats require cri-dockerd")
+}
+
+// Events streams the lifecycle events of the containers until ctx is done
+func (r *Docker) Events(ctx context.Context) (<-chan ContainerEvent, error) {
+	if r.UseCRI {
+		return criEvents(ctx, r.Runner, r.SocketPath())
+	}
+	return nil, errors.New("container events require cri-dockerd")
+}
+
+// ExecContainer runs a command in a container based on ID
+func (r *Docker) ExecContainer(id string, cmd []string) (*command.RunResult, error) {
+	if r.UseCRI {
+		return execCRIContainer(r.Runner, r.SocketPath(), id, cmd)
+	}
+	args := append([]string{"exec", id}, cmd...)
+	rr, err := r.Runner.RunCmd(exec.Command("docker", args...))
+	if err != nil {
+		return rr, errors.Wrap(err, "docker exec")
+	}
+	return rr, nil
+}
+
+// CheckpointContainer is not supported by docker
+func (r *Docker) CheckpointContainer(string, string) error {
+	return ErrCheckpointNotSupported
+}
+
+// RestoreContainer is not supported by docker
+func (r *Docker) RestoreContainer(string, string) error {
+	return ErrCheckpointNotSupported
+}
+
 // ContainerLogCmd returns the command to retrieve the log for a container based on ID
 func (r *Docker) ContainerLogCmd(id string, len int, follow bool) string {
 	if r.UseCRI {
@@ -585,7 +780,8 @@ func (r *Docker) configureDocker(driver string) error {
 // 1. Copy over the preloaded tarball into the VM
 // 2. Extract the preloaded tarball to the correct directory
 // 3. Remove the tarball within the VM
-func (r *Docker) Preload(cc config.ClusterConfig) error {
+func (r *Docker) Preload(ctx context.Context, cc config.ClusterConfig) error {
+	r = r.bind(ctx)
 	if !download.PreloadExists(cc.KubernetesConfig.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
 		return nil
 	}
@@ -784,3 +980,13 @@ ExecStart={{.ExecPath}} --container-runtime-endpoint fd:// --pod-infra-container
 	}
 	return nil
 }
+
+// InstallWasmShim is not supported by docker
+func (r *Docker) InstallWasmShim(context.Context, WasmShim) error {
+	return ErrWasmNotSupported
+}
+
+// InstallKata is not supported by docker
+func (r *Docker) InstallKata(context.Context) error {
+	return ErrKataNotSupported
+}
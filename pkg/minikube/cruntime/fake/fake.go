@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake emulates a node running porto, so that code built on the cruntime package
+// can exercise the porto runtime in unit tests without a live machine.
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+const (
+	// PortoVersion is the version portod reports unless set otherwise with SetVersion
+	PortoVersion = "5.3.30"
+	// PortoshimVersion is the version portoshim reports over the CRI
+	PortoshimVersion = "v1.0.11"
+	// CrictlVersion is the version of crictl on the node
+	CrictlVersion = "v1.28.0"
+)
+
+// scripted is the result of a command scripted with Script
+type scripted struct {
+	stdout string
+	err    error
+}
+
+// Runner is a command.Runner emulating a node which runs porto and portoshim under systemd.
+// It answers the portod, portoctl, crictl, runc, systemctl, stat and which commands of the porto runtime from
+// the images, containers and services it holds, and any other command from the results scripted with Script.
+// Unknown commands fail, listing the scripted ones.
+type Runner struct {
+	mu sync.Mutex
+
+	version    string
+	cmds       []string
+	scripts    map[string]scripted
+	files      map[string]string
+	services   map[string]bool
+	images     map[string]*Image
+	containers map[string]*Container
+	archives   map[string][]Image
+	started    map[*command.StartedCmd]started
+}
+
+// started is the result of a command started with StartCmd
+type started struct {
+	rr  *command.RunResult
+	err error
+}
+
+// NewRunner returns a Runner of a node with porto and portoshim running and no images or containers
+func NewRunner() *Runner {
+	return &Runner{
+		version:    PortoVersion,
+		scripts:    map[string]scripted{},
+		files:      map[string]string{},
+		services:   map[string]bool{"porto": true, "portoshim": true},
+		images:     map[string]*Image{},
+		containers: map[string]*Container{},
+		archives:   map[string][]Image{},
+		started:    map[*command.StartedCmd]started{},
+	}
+}
+
+// NewPorto returns the porto runtime of the node emulated by r
+func NewPorto(r *Runner, kubernetesVersion semver.Version) (cruntime.Manager, error) {
+	return cruntime.New(cruntime.Config{Type: "porto", Runner: r, KubernetesVersion: kubernetesVersion})
+}
+
+// Script makes the command cmd, in the form of command.RunResult.Command, print stdout and fail with err.
+// Scripted commands take precedence over the emulated ones.
+func (r *Runner) Script(cmd string, stdout string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[cmd] = scripted{stdout: stdout, err: err}
+}
+
+// SetVersion sets the version portod reports
+func (r *Runner) SetVersion(v string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version = v
+}
+
+// SetService sets whether the systemd service name is active
+func (r *Runner) SetService(name string, active bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.services[name] = active
+}
+
+// ServiceActive returns whether the systemd service name is active
+func (r *Runner) ServiceActive(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.services[name]
+}
+
+// Commands returns the commands run so far, in the form of command.RunResult.Command
+func (r *Runner) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.cmds...)
+}
+
+// File returns the contents of the file copied to path, and false if none was
+func (r *Runner) File(path string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.files[path]
+	return c, ok
+}
+
+// RunCmd runs cmd against the emulated node
+func (r *Runner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	rr := &command.RunResult{Args: cmd.Args}
+	key := rr.Command()
+	klog.Infof("(fake porto) Run: %s", key)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cmds = append(r.cmds, key)
+
+	out, err := r.run(key, cmd.Args)
+	rr.Stdout.WriteString(out)
+	if cmd.Stdout != nil {
+		if _, werr := cmd.Stdout.Write([]byte(out)); werr != nil {
+			return rr, werr
+		}
+	}
+	if err != nil {
+		rr.ExitCode = 1
+		rr.Stderr.WriteString(err.Error())
+		return rr, fmt.Errorf("%s: %w", key, err)
+	}
+	return rr, nil
+}
+
+// run returns the output of the command with args, keyed as key
+func (r *Runner) run(key string, args []string) (string, error) {
+	if s, ok := r.scripts[key]; ok {
+		return s.stdout, s.err
+	}
+
+	bin, args := args[0], args[1:]
+	if bin == "sudo" && len(args) > 0 {
+		bin, args = args[0], args[1:]
+	}
+	switch bin {
+	case "systemctl":
+		return r.systemctl(args)
+	case "which":
+		return r.which(args)
+	case "portod":
+		return r.portod(args)
+	case "portoctl":
+		return r.portoctl(args)
+	case "crictl", crictlPath:
+		return r.crictl(args)
+	case "runc":
+		return r.runc(args)
+	case "test":
+		return r.test(args)
+	case "stat":
+		return r.stat(args)
+	case "tar":
+		return r.tar(args)
+	case "-s":
+		// crictl ps for several namespaces at once: sudo -s eval "crictl ps ...; crictl ps ..."
+		if len(args) == 2 && args[0] == "eval" {
+			return r.evalCrictl(args[1])
+		}
+	}
+	return "", r.unknown(key)
+}
+
+// unknown returns the error of a command the runner neither emulates nor has a script for
+func (r *Runner) unknown(key string) error {
+	var scripts []string
+	for k := range r.scripts {
+		scripts = append(scripts, "  `"+k+"`")
+	}
+	sort.Strings(scripts)
+	if len(scripts) == 0 {
+		return fmt.Errorf("unknown command %q, no commands are scripted", key)
+	}
+	return fmt.Errorf("unknown command %q, scripted are:\n%s", key, strings.Join(scripts, "\n"))
+}
+
+// StartCmd runs cmd to completion, as nothing on the emulated node takes time
+func (r *Runner) StartCmd(cmd *exec.Cmd) (*command.StartedCmd, error) {
+	rr, err := r.RunCmd(cmd)
+	sc := &command.StartedCmd{}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started[sc] = started{rr: rr, err: err}
+	return sc, nil
+}
+
+// WaitCmd returns the result of a command started with StartCmd
+func (r *Runner) WaitCmd(sc *command.StartedCmd) (*command.RunResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.started[sc]
+	if !ok {
+		return nil, fmt.Errorf("command was not started")
+	}
+	delete(r.started, sc)
+	return s.rr, s.err
+}
+
+// Copy stores the contents of f at its target path
+func (r *Runner) Copy(f assets.CopyableFile) error {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, f); err != nil {
+		return fmt.Errorf("reading %s: %w", f.GetSourcePath(), err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[f.GetTargetPath()] = b.String()
+	return nil
+}
+
+// CopyFrom writes the contents stored at the source path of f to it
+func (r *Runner) CopyFrom(f assets.CopyableFile) error {
+	r.mu.Lock()
+	c, ok := r.files[f.GetSourcePath()]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s: no such file", f.GetSourcePath())
+	}
+	_, err := io.WriteString(f, c)
+	return err
+}
+
+// Remove removes the file stored at the target path of f
+func (r *Runner) Remove(f assets.CopyableFile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.files, f.GetTargetPath())
+	return nil
+}
+
+// ReadableFile is not supported by the emulated node
+func (r *Runner) ReadableFile(sourcePath string) (assets.ReadableFile, error) {
+	return nil, fmt.Errorf("%s: reading files is not supported", sourcePath)
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+func newPorto(t *testing.T, r *Runner) cruntime.Manager {
+	t.Helper()
+	cr, err := NewPorto(r, semver.MustParse("1.28.0"))
+	if err != nil {
+		t.Fatalf("NewPorto: %v", err)
+	}
+	return cr
+}
+
+func TestPortoVersion(t *testing.T) {
+	r := NewRunner()
+	r.SetVersion("5.4.1")
+	cr := newPorto(t, r)
+
+	got, err := cr.Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if got != "5.4.1" {
+		t.Errorf("Version = %q, want 5.4.1", got)
+	}
+	if !cr.Active() {
+		t.Errorf("Active = false, want true")
+	}
+	if err := cr.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if cr.Active() || r.ServiceActive("porto") {
+		t.Errorf("porto is active after Disable")
+	}
+}
+
+func TestPortoImages(t *testing.T) {
+	r := NewRunner()
+	r.AddImage(Image{ID: "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c", Tags: []string{"registry.k8s.io/pause:3.9"}, Size: 744 << 10, Created: time.Date(2023, 5, 17, 9, 21, 46, 0, time.UTC)})
+	cr := newPorto(t, r)
+
+	if err := cr.PullImage(context.Background(), "registry.k8s.io/etcd:3.5.9-0"); err != nil {
+		t.Fatalf("PullImage: %v", err)
+	}
+	if err := cr.TagImage(context.Background(), "registry.k8s.io/etcd:3.5.9-0", "localhost:5000/etcd:latest"); err != nil {
+		t.Fatalf("TagImage: %v", err)
+	}
+	if !cr.ImageExists("registry.k8s.io/pause:3.9", "e6f18168") {
+		t.Errorf("ImageExists(pause) = false, want true")
+	}
+
+	imgs, err := cr.ListImages(cruntime.ListImagesOptions{})
+	if err != nil {
+		t.Fatalf("ListImages: %v", err)
+	}
+	var tags []string
+	for _, img := range imgs {
+		tags = append(tags, img.RepoTags...)
+		if slices.Contains(img.RepoTags, "registry.k8s.io/pause:3.9") && (img.Size != "761900" || img.Created != "2023-05-17T09:21:46Z") {
+			t.Errorf("ListImages(pause) size %s created %q, want 761900 created 2023-05-17T09:21:46Z", img.Size, img.Created)
+		}
+	}
+	want := []string{"registry.k8s.io/etcd:3.5.9-0", "localhost:5000/etcd:latest", "registry.k8s.io/pause:3.9"}
+	if diff := cmp.Diff(want, tags); diff != "" {
+		t.Errorf("ListImages tags diff (-want +got):\n%s", diff)
+	}
+
+	if err := cr.RemoveImage(context.Background(), "registry.k8s.io/pause:3.9"); err != nil {
+		t.Fatalf("RemoveImage: %v", err)
+	}
+	if cr.ImageExists("registry.k8s.io/pause:3.9", "") {
+		t.Errorf("ImageExists(pause) = true after RemoveImage")
+	}
+}
+
+func TestPortoLoadImage(t *testing.T) {
+	r := NewRunner()
+	img := Image{ID: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", Tags: []string{"example.com/app:v1"}}
+	r.AddArchive("/var/lib/minikube/images/app_v1", img)
+	cr := newPorto(t, r)
+
+	if err := cr.LoadImage(context.Background(), "/var/lib/minikube/images/app_v1"); err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if diff := cmp.Diff([]Image{img}, r.Images()); diff != "" {
+		t.Errorf("images diff after LoadImage (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortoSaveImages(t *testing.T) {
+	r := NewRunner()
+	app := Image{ID: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", Tags: []string{"example.com/app:v1"}}
+	db := Image{ID: "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", Tags: []string{"example.com/db:v1"}}
+	r.AddImage(app)
+	r.AddImage(db)
+	cr := newPorto(t, r)
+
+	if err := cr.SaveImages(context.Background(), []string{"example.com/app:v1", "example.com/db:v1"}, "/tmp/bundle.tar"); err != nil {
+		t.Fatalf("SaveImages: %v", err)
+	}
+	if diff := cmp.Diff([]Image{app, db}, r.Archive("/tmp/bundle.tar")); diff != "" {
+		t.Errorf("archive diff after SaveImages (-want +got):\n%s", diff)
+	}
+	if err := cr.SaveImages(context.Background(), []string{"example.com/missing:v1"}, "/tmp/missing.tar"); err == nil {
+		t.Errorf("SaveImages of a missing image succeeded")
+	}
+
+	// the archive loads back with the tags of both images
+	loaded := NewRunner()
+	loaded.AddArchive("/tmp/bundle.tar", r.Archive("/tmp/bundle.tar")...)
+	if err := newPorto(t, loaded).LoadImage(context.Background(), "/tmp/bundle.tar"); err != nil {
+		t.Fatalf("LoadImage: %v", err)
+	}
+	if diff := cmp.Diff([]Image{app, db}, loaded.Images()); diff != "" {
+		t.Errorf("images diff after LoadImage (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortoContainers(t *testing.T) {
+	r := NewRunner()
+	r.AddContainer(Container{ID: "a1", Name: "kube-apiserver", Namespace: "kube-system", State: Running})
+	r.AddContainer(Container{ID: "b2", Name: "coredns", Namespace: "kube-system", State: Running})
+	r.AddContainer(Container{ID: "c3", Name: "app", Namespace: "default", State: Running})
+	cr := newPorto(t, r)
+
+	ids, err := cr.ListContainers(cruntime.ListContainersOptions{Namespaces: []string{"kube-system"}})
+	if err != nil {
+		t.Fatalf("ListContainers: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a1", "b2"}, ids); diff != "" {
+		t.Errorf("ListContainers diff (-want +got):\n%s", diff)
+	}
+
+	if err := cr.PauseContainers([]string{"a1"}); err != nil {
+		t.Fatalf("PauseContainers: %v", err)
+	}
+	ids, err = cr.ListContainers(cruntime.ListContainersOptions{State: cruntime.Paused})
+	if err != nil {
+		t.Fatalf("ListContainers(paused): %v", err)
+	}
+	if diff := cmp.Diff([]string{"a1"}, ids); diff != "" {
+		t.Errorf("ListContainers(paused) diff (-want +got):\n%s", diff)
+	}
+
+	if err := cr.StopContainers([]string{"c3"}); err != nil {
+		t.Fatalf("StopContainers: %v", err)
+	}
+	if err := cr.KillContainers([]string{"b2"}); err != nil {
+		t.Fatalf("KillContainers: %v", err)
+	}
+	want := []Container{
+		{ID: "a1", Name: "kube-apiserver", Namespace: "kube-system", State: Paused},
+		{ID: "c3", Name: "app", Namespace: "default", State: Exited},
+	}
+	if diff := cmp.Diff(want, r.Containers()); diff != "" {
+		t.Errorf("containers diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortoHealthy(t *testing.T) {
+	r := NewRunner()
+	cr := newPorto(t, r)
+	if err := cr.Healthy(context.Background()); err != nil {
+		t.Fatalf("Healthy: %v", err)
+	}
+
+	r.Script("sudo stat -c %F|%a|%G /run/portoshim.sock", "socket|600|porto\n", nil)
+	if err := cr.Healthy(context.Background()); err == nil || !strings.Contains(err.Error(), "/run/portoshim.sock belongs to the porto group") {
+		t.Errorf("Healthy with a socket the porto group can't use = %v, want an error naming the socket", err)
+	}
+
+	r.SetService("portoshim", false)
+	if err := cr.Healthy(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "portoshim") {
+		t.Errorf("Healthy with portoshim down = %v, want a portoshim error", err)
+	}
+	r.SetService("porto", false)
+	if err := cr.Healthy(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "portod") {
+		t.Errorf("Healthy with portod down = %v, want a portod error", err)
+	}
+}
+
+func TestPortoDaemons(t *testing.T) {
+	r := NewRunner()
+	cr := newPorto(t, r).(*cruntime.Porto)
+	want := []cruntime.DaemonStatus{
+		{Name: "portod", Active: true, Version: PortoVersion, Socket: "/run/portod.socket", Reachable: true},
+		{Name: "portoshim", Active: true, Version: PortoshimVersion, Socket: "/run/portoshim.sock", Reachable: true},
+	}
+	if diff := cmp.Diff(want, cr.Daemons(context.Background())); diff != "" {
+		t.Errorf("Daemons diff (-want +got):\n%s", diff)
+	}
+
+	// portod keeps serving while portoshim is down
+	r.SetService("portoshim", false)
+	got := cr.Daemons(context.Background())
+	if !got[0].Reachable || got[0].Version != PortoVersion {
+		t.Errorf("portod with portoshim down = %+v, want it reachable", got[0])
+	}
+	if got[1].Active || got[1].Reachable || got[1].Version != "" || !strings.Contains(got[1].Error, "/run/portoshim.sock") {
+		t.Errorf("portoshim down = %+v, want it inactive and unreachable with an error naming its socket", got[1])
+	}
+}
+
+func TestScript(t *testing.T) {
+	r := NewRunner()
+	r.AddImage(imageFor("registry.k8s.io/pause:3.9"))
+	cr := newPorto(t, r)
+
+	// scripted commands take precedence over the emulated ones
+	r.Script("sudo portoctl docker-images", "", errors.New("can't connect to portod"))
+	if cr.ImageExists("registry.k8s.io/pause:3.9", "") {
+		t.Errorf("ImageExists = true, want false when portoctl fails")
+	}
+
+	_, err := r.RunCmd(exec.Command("sudo", "lz4", "--version"))
+	if err == nil || !strings.Contains(err.Error(), "sudo portoctl docker-images") {
+		t.Errorf("unknown command: expected an error listing the scripted commands, got %v", err)
+	}
+	want := []string{"sudo portoctl docker-images", "sudo lz4 --version"}
+	if diff := cmp.Diff(want, r.Commands()[len(r.Commands())-2:]); diff != "" {
+		t.Errorf("Commands diff (-want +got):\n%s", diff)
+	}
+}
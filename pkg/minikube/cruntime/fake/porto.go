@@ -0,0 +1,549 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/kballard/go-shellquote"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// crictlPath is where which finds crictl on the emulated node
+const crictlPath = "/usr/bin/crictl"
+
+// Image is an image stored by portod
+type Image struct {
+	// ID is the image ID, "sha256:" followed by the hex digest of its config
+	ID string
+	// Tags are the references the image is tagged with
+	Tags []string
+	// Digests are the repository digests of the image
+	Digests []string
+	// Size is the size of the image in bytes
+	Size uint64
+	// Created is when the image was built, portoctl prints "<none>" when it is zero
+	Created time.Time
+}
+
+// ContainerState is the state of a container run by portoshim
+type ContainerState string
+
+const (
+	// Running containers run
+	Running ContainerState = "running"
+	// Paused containers are frozen
+	Paused ContainerState = "paused"
+	// Exited containers have stopped
+	Exited ContainerState = "exited"
+)
+
+// Container is a Kubernetes container run by portoshim
+type Container struct {
+	ID        string
+	Name      string
+	Namespace string
+	State     ContainerState
+}
+
+// imageFor returns an image tagged as ref, with an ID derived from it
+func imageFor(ref string) Image {
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(ref)))
+	name := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		name = ref[:i]
+	}
+	return Image{
+		ID:      "sha256:" + sum,
+		Tags:    []string{ref},
+		Digests: []string{name + "@sha256:" + sum},
+		Size:    uint64(len(ref)) << 20,
+	}
+}
+
+// AddImage stores img, replacing the image of the same ID and moving its tags from other images
+func (r *Runner) AddImage(img Image) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addImage(img)
+}
+
+func (r *Runner) addImage(img Image) {
+	img.Tags = append([]string(nil), img.Tags...)
+	img.Digests = append([]string(nil), img.Digests...)
+	for _, t := range img.Tags {
+		r.untag(t)
+	}
+	r.images[img.ID] = &img
+}
+
+// untag removes tag from the image it is on
+func (r *Runner) untag(tag string) {
+	for _, img := range r.images {
+		for i, t := range img.Tags {
+			if t == tag {
+				img.Tags = append(img.Tags[:i], img.Tags[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Images returns the images stored by portod, ordered by ID
+func (r *Runner) Images() []Image {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var imgs []Image
+	for _, img := range r.sortedImages() {
+		imgs = append(imgs, *img)
+	}
+	return imgs
+}
+
+// findImage returns the image ref refers to by ID, ID prefix, tag or digest
+func (r *Runner) findImage(ref string) *Image {
+	if ref == "" {
+		return nil
+	}
+	for _, img := range r.images {
+		if strings.HasPrefix(img.ID, ref) || strings.HasPrefix(strings.TrimPrefix(img.ID, "sha256:"), ref) {
+			return img
+		}
+		for _, t := range append(append([]string(nil), img.Tags...), img.Digests...) {
+			if t == ref {
+				return img
+			}
+		}
+	}
+	return nil
+}
+
+// AddArchive makes the docker-archive tarball at path hold imgs, for the runtime to load
+func (r *Runner) AddArchive(path string, imgs ...Image) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.archives[path] = imgs
+}
+
+// Archive returns the images of the docker-archive tarball at path, as added with AddArchive or saved by the runtime
+func (r *Runner) Archive(path string) []Image {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Image(nil), r.archives[path]...)
+}
+
+// AddContainer makes portoshim run c
+func (r *Runner) AddContainer(c Container) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.containers[c.ID] = &c
+}
+
+// Containers returns the containers run by portoshim, ordered by ID
+func (r *Runner) Containers() []Container {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var cs []Container
+	for _, c := range r.sortedContainers() {
+		cs = append(cs, *c)
+	}
+	return cs
+}
+
+// systemctl emulates systemctl managing the services of the node
+func (r *Runner) systemctl(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("systemctl: no action")
+	}
+	action, svcs := args[0], args[1:]
+	switch action {
+	case "--version":
+		return "systemd 252 (252.5)", nil
+	case "daemon-reload":
+		return "", nil
+	}
+	var names []string
+	for _, s := range svcs {
+		if strings.HasPrefix(s, "-") || s == "service" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(s, ".service"))
+	}
+	for _, svc := range names {
+		switch action {
+		case "is-active":
+			if !r.services[svc] {
+				return "", fmt.Errorf("%s is inactive", svc)
+			}
+		case "start", "restart", "reload":
+			r.services[svc] = true
+		case "stop":
+			r.services[svc] = false
+		case "enable", "disable":
+			if strings.Contains(strings.Join(svcs, " "), "--now") {
+				r.services[svc] = action == "enable"
+			}
+		case "mask", "unmask":
+		default:
+			return "", fmt.Errorf("systemctl %s is not emulated", action)
+		}
+	}
+	return "", nil
+}
+
+// which emulates which finding the porto tools
+func (r *Runner) which(args []string) (string, error) {
+	paths := map[string]string{
+		"crictl":    crictlPath,
+		"portoctl":  "/usr/sbin/portoctl",
+		"portod":    "/usr/sbin/portod",
+		"portoshim": "/usr/sbin/portoshim",
+	}
+	if len(args) == 1 {
+		if p, ok := paths[args[0]]; ok {
+			return p + "\n", nil
+		}
+	}
+	return "", fmt.Errorf("which %s: not found", strings.Join(args, " "))
+}
+
+// portod emulates portod version
+func (r *Runner) portod(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "version" {
+		if !r.services["porto"] {
+			return fmt.Sprintf("version: %s  /usr/sbin/portod\n", r.version), nil
+		}
+		return fmt.Sprintf("version: %s  /usr/sbin/portod\nrunning: %s  /usr/sbin/portod\n", r.version, r.version), nil
+	}
+	return "", fmt.Errorf("portod %s is not emulated", strings.Join(args, " "))
+}
+
+// portoctl emulates the portoctl commands of the porto runtime
+func (r *Runner) portoctl(args []string) (string, error) {
+	if !r.services["porto"] {
+		return "", fmt.Errorf("can't connect to portod")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("portoctl: no command")
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "list":
+		return "", nil
+	case "docker-images":
+		return r.dockerImages(), nil
+	case "docker-tag":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: portoctl docker-tag <image> <tag>")
+		}
+		img := r.findImage(args[0])
+		if img == nil {
+			return "", fmt.Errorf("ImageNotFound: %s", args[0])
+		}
+		r.untag(args[1])
+		img.Tags = append(img.Tags, args[1])
+		return "", nil
+	case "docker-load":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: portoctl docker-load <tarball>")
+		}
+		imgs, ok := r.archives[args[0]]
+		if !ok {
+			return "", fmt.Errorf("%s: not a docker archive", args[0])
+		}
+		for _, img := range imgs {
+			// portoctl docker-load does not always keep the tags, the runtime restores them from the manifest
+			img.Tags = nil
+			r.addImage(img)
+		}
+		return "", nil
+	case "docker-save":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: portoctl docker-save <tarball> <image>...")
+		}
+		var imgs []Image
+		for _, ref := range args[1:] {
+			img := r.findImage(ref)
+			if img == nil {
+				return "", fmt.Errorf("ImageNotFound: %s", ref)
+			}
+			imgs = append(imgs, *img)
+		}
+		r.archives[args[0]] = imgs
+		return "", nil
+	}
+	return "", fmt.Errorf("portoctl %s is not emulated", shellquote.Join(args...))
+}
+
+// sortedImages returns the images ordered by ID, r.mu is held
+func (r *Runner) sortedImages() []*Image {
+	var imgs []*Image
+	for _, img := range r.images {
+		imgs = append(imgs, img)
+	}
+	sort.Slice(imgs, func(i, j int) bool { return imgs[i].ID < imgs[j].ID })
+	return imgs
+}
+
+// crictl emulates crictl talking to portoshim
+func (r *Runner) crictl(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "--version" {
+		return fmt.Sprintf("crictl version %s\n", CrictlVersion), nil
+	}
+	if !r.services["portoshim"] {
+		return "", fmt.Errorf("connect: connect endpoint 'unix:///run/portoshim.sock', make sure you are running as root and the endpoint has been started")
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("crictl: no command")
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "version":
+		if len(args) == 2 && args[0] == "--output" && args[1] == "json" {
+			return fmt.Sprintf("{\n  \"version\": \"0.1.0\",\n  \"runtimeName\": \"portoshim\",\n  \"runtimeVersion\": \"%s\",\n  \"runtimeApiVersion\": \"v1\"\n}\n", PortoshimVersion), nil
+		}
+		return fmt.Sprintf("Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  %s\nRuntimeApiVersion:  v1\n", PortoshimVersion), nil
+	case "images":
+		return r.crictlImages()
+	case "pull":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: crictl pull <image>")
+		}
+		if r.findImage(args[0]) == nil {
+			r.addImage(imageFor(args[0]))
+		}
+		return fmt.Sprintf("Image is up to date for %s\n", r.findImage(args[0]).ID), nil
+	case "rmi":
+		for _, ref := range args {
+			img := r.findImage(ref)
+			if img == nil {
+				return "", fmt.Errorf("no such image %s present", ref)
+			}
+			delete(r.images, img.ID)
+		}
+		return "", nil
+	case "ps":
+		return r.crictlPs(args)
+	case "stop", "rm":
+		var ids []string
+		for _, a := range args {
+			if !strings.HasPrefix(a, "-") {
+				ids = append(ids, a)
+			}
+		}
+		for _, id := range ids {
+			c, ok := r.containers[id]
+			if !ok {
+				return "", fmt.Errorf("container %q not found", id)
+			}
+			if cmd == "rm" {
+				delete(r.containers, id)
+				continue
+			}
+			c.State = Exited
+		}
+		return strings.Join(ids, "\n"), nil
+	}
+	return "", fmt.Errorf("crictl %s is not emulated", shellquote.Join(args...))
+}
+
+// crictlImages emulates crictl images --output json
+func (r *Runner) crictlImages() (string, error) {
+	type image struct {
+		ID          string   `json:"id"`
+		RepoTags    []string `json:"repoTags"`
+		RepoDigests []string `json:"repoDigests"`
+		Size        string   `json:"size"`
+	}
+	out := struct {
+		Images []image `json:"images"`
+	}{Images: []image{}}
+	for _, img := range r.sortedImages() {
+		out.Images = append(out.Images, image{
+			ID:          img.ID,
+			RepoTags:    img.Tags,
+			RepoDigests: img.Digests,
+			Size:        strconv.FormatUint(img.Size, 10),
+		})
+	}
+	b, err := json.Marshal(out)
+	return string(b), err
+}
+
+// crictlPs emulates crictl ps -a --quiet, filtering by --name and the pod namespace label
+func (r *Runner) crictlPs(args []string) (string, error) {
+	var name, namespace string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case strings.HasPrefix(a, "--name="):
+			name = strings.TrimPrefix(a, "--name=")
+		case a == "--label" && i+1 < len(args):
+			i++
+			namespace = strings.TrimPrefix(args[i], "io.kubernetes.pod.namespace=")
+		}
+	}
+	var ids []string
+	for _, c := range r.containers {
+		if (name == "" || strings.Contains(c.Name, name)) && (namespace == "" || c.Namespace == namespace) {
+			ids = append(ids, c.ID)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, "\n"), nil
+}
+
+// evalCrictl emulates several crictl ps commands run at once through a shell
+func (r *Runner) evalCrictl(script string) (string, error) {
+	var out []string
+	for _, c := range strings.Split(script, "; ") {
+		args, err := shellquote.Split(c)
+		if err != nil || len(args) == 0 || args[0] != "crictl" {
+			return "", fmt.Errorf("eval %q is not emulated", script)
+		}
+		o, err := r.crictl(args[1:])
+		if err != nil {
+			return "", err
+		}
+		if o != "" {
+			out = append(out, o)
+		}
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// runc emulates runc listing, pausing and resuming the containers of portoshim
+func (r *Runner) runc(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("runc: no command")
+	}
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "list":
+		type state struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		}
+		list := []state{}
+		for _, c := range r.sortedContainers() {
+			if c.State != Exited {
+				list = append(list, state{ID: c.ID, Status: string(c.State)})
+			}
+		}
+		b, err := json.Marshal(list)
+		return string(b), err
+	case "pause", "resume":
+		for _, id := range args {
+			c, ok := r.containers[id]
+			if !ok {
+				return "", fmt.Errorf("container %q does not exist", id)
+			}
+			c.State = Running
+			if cmd == "pause" {
+				c.State = Paused
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("runc %s is not emulated", shellquote.Join(args...))
+}
+
+// test emulates test -S on the sockets of portod and portoshim, which exist while their services run
+func (r *Runner) test(args []string) (string, error) {
+	p := &cruntime.Porto{}
+	if len(args) == 2 && args[0] == "-S" {
+		switch {
+		case args[1] == p.PortodSocketPath() && r.services["porto"],
+			args[1] == p.SocketPath() && r.services["portoshim"]:
+			return "", nil
+		}
+		return "", fmt.Errorf("%s is not a socket", args[1])
+	}
+	return "", fmt.Errorf("test %s is not emulated", shellquote.Join(args...))
+}
+
+// stat emulates stat -c '%F|%a|%G' on the sockets of portod and portoshim, which root owns with mode 0660 while their services run
+func (r *Runner) stat(args []string) (string, error) {
+	if len(args) != 3 || args[0] != "-c" || args[1] != "%F|%a|%G" {
+		return "", fmt.Errorf("stat %s is not emulated", shellquote.Join(args...))
+	}
+	if _, err := r.test([]string{"-S", args[2]}); err != nil {
+		return "", fmt.Errorf("stat: cannot statx '%s': No such file or directory", args[2])
+	}
+	return "socket|660|root\n", nil
+}
+
+// tar emulates reading the manifest of the archives added with AddArchive
+func (r *Runner) tar(args []string) (string, error) {
+	if len(args) == 3 && args[0] == "-xOf" && args[2] == "manifest.json" {
+		imgs, ok := r.archives[args[1]]
+		if !ok {
+			return "", fmt.Errorf("%s: Cannot open: No such file or directory", args[1])
+		}
+		type manifest struct {
+			Config   string
+			RepoTags []string
+		}
+		var manifests []manifest
+		for _, img := range imgs {
+			manifests = append(manifests, manifest{Config: img.ID, RepoTags: img.Tags})
+		}
+		b, err := json.Marshal(manifests)
+		return string(b), err
+	}
+	return "", fmt.Errorf("tar %s is not emulated", shellquote.Join(args...))
+}
+
+// sortedContainers returns the containers ordered by ID, r.mu is held
+func (r *Runner) sortedContainers() []*Container {
+	var cs []*Container
+	for _, c := range r.containers {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+	return cs
+}
+
+// dockerImages emulates the table of portoctl docker-images, with a row for each tag of an image
+func (r *Runner) dockerImages() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDIGEST\tSIZE\tCREATED")
+	for _, img := range r.sortedImages() {
+		digest := "<none>"
+		if len(img.Digests) > 0 {
+			_, digest, _ = strings.Cut(img.Digests[0], "@")
+		}
+		size := units.HumanSize(float64(img.Size))
+		created := "<none>"
+		if !img.Created.IsZero() {
+			created = img.Created.UTC().Format(time.RFC3339)
+		}
+		if len(img.Tags) == 0 {
+			fmt.Fprintf(w, "%s\t<none>:<none>\t%s\t%s\t%s\n", img.ID, digest, size, created)
+		}
+		for _, t := range img.Tags {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", img.ID, t, digest, size, created)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
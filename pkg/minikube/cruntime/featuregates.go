@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Runtime feature gates, for experimental capabilities of the container runtimes
+const (
+	// FeaturePortoNativeNetworking lets portod set up the network namespaces of pods itself
+	FeaturePortoNativeNetworking = "porto-native-networking"
+	// FeatureSandboxCheckpointing lets portod checkpoint and restore pod sandboxes
+	FeatureSandboxCheckpointing = "sandbox-checkpointing"
+	// FeatureSharedLayerCache lets portod share unpacked image layers between images
+	FeatureSharedLayerCache = "shared-layer-cache"
+	// FeatureKubeletMetaContainer runs kubelet in a porto container, under a meta-container guaranteeing the resources of the node components
+	FeatureKubeletMetaContainer = "kubelet-meta-container"
+)
+
+// FeatureGates are the runtime feature gates, keyed by name
+type FeatureGates map[string]bool
+
+// knownFeatureGates are the runtime feature gates and their defaults
+var knownFeatureGates = FeatureGates{
+	FeaturePortoNativeNetworking: false,
+	FeatureSandboxCheckpointing:  false,
+	FeatureSharedLayerCache:      false,
+	FeatureKubeletMetaContainer:  false,
+}
+
+// KnownFeatureGates returns the names of the runtime feature gates
+func KnownFeatureGates() []string {
+	names := []string{}
+	for name := range knownFeatureGates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFeatureGates parses runtime feature gates given as a comma-separated list of name=bool pairs.
+// Gates which aren't mentioned keep their default.
+func ParseFeatureGates(s string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for name, enabled := range knownFeatureGates {
+		gates[name] = enabled
+	}
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("runtime feature gate %q is not of the form name=true|false", kv)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := knownFeatureGates[name]; !known {
+			return nil, fmt.Errorf("unknown runtime feature gate %q, known gates are: %s", name, strings.Join(KnownFeatureGates(), ", "))
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of runtime feature gate %q: %v", name, err)
+		}
+		gates[name] = enabled
+	}
+	return gates, nil
+}
+
+// Enabled returns whether the named feature gate is enabled
+func (g FeatureGates) Enabled(name string) bool {
+	return g[name]
+}
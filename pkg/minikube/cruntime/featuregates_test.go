@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FeatureGates
+		wantErr bool
+	}{
+		{in: "", want: FeatureGates{FeaturePortoNativeNetworking: false, FeatureSandboxCheckpointing: false, FeatureSharedLayerCache: false, FeatureKubeletMetaContainer: false}},
+		{in: "shared-layer-cache=true", want: FeatureGates{FeaturePortoNativeNetworking: false, FeatureSandboxCheckpointing: false, FeatureSharedLayerCache: true, FeatureKubeletMetaContainer: false}},
+		{in: " porto-native-networking = true ,sandbox-checkpointing=1,", want: FeatureGates{FeaturePortoNativeNetworking: true, FeatureSandboxCheckpointing: true, FeatureSharedLayerCache: false, FeatureKubeletMetaContainer: false}},
+		{in: "shared-layer-cache", wantErr: true},
+		{in: "shared-layer-cache=maybe", wantErr: true},
+		{in: "warp-drive=true", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := ParseFeatureGates(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseFeatureGates(%q) error = %v, wantErr %t", tc.in, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("ParseFeatureGates(%q) mismatch (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/klog/v2"
+)
+
+// ImageInspect is the metadata of an image as the runtime knows it
+type ImageInspect struct {
+	ID          string   `json:"id" yaml:"id"`
+	RepoTags    []string `json:"repoTags" yaml:"repoTags"`
+	RepoDigests []string `json:"repoDigests" yaml:"repoDigests"`
+	// Size is how many bytes the image takes in the runtime
+	Size uint64 `json:"size" yaml:"size"`
+	// Created is when the image was built in RFC 3339, empty when the runtime does not report it
+	Created      string `json:"created,omitempty" yaml:"created,omitempty"`
+	Architecture string `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	OS           string `json:"os,omitempty" yaml:"os,omitempty"`
+	Variant      string `json:"variant,omitempty" yaml:"variant,omitempty"`
+	// Config is how containers of the image run, nil when the runtime does not report it
+	Config *ImageConfig `json:"config,omitempty" yaml:"config,omitempty"`
+	// Layers are the digests of the uncompressed layers of the image, bottom first
+	Layers []string `json:"layers,omitempty" yaml:"layers,omitempty"`
+}
+
+// ImageConfig is how containers of an image run unless their spec says otherwise
+type ImageConfig struct {
+	User         string            `json:"user,omitempty" yaml:"user,omitempty"`
+	Env          []string          `json:"env,omitempty" yaml:"env,omitempty"`
+	Entrypoint   []string          `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	Cmd          []string          `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	WorkingDir   string            `json:"workingDir,omitempty" yaml:"workingDir,omitempty"`
+	ExposedPorts []string          `json:"exposedPorts,omitempty" yaml:"exposedPorts,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	StopSignal   string            `json:"stopSignal,omitempty" yaml:"stopSignal,omitempty"`
+}
+
+// imageConfigOf converts the config of an image, as docker and the OCI image spec both write it
+func imageConfigOf(c v1.Config) *ImageConfig {
+	ic := &ImageConfig{
+		User:       c.User,
+		Env:        c.Env,
+		Entrypoint: c.Entrypoint,
+		Cmd:        c.Cmd,
+		WorkingDir: c.WorkingDir,
+		Labels:     c.Labels,
+		StopSignal: c.StopSignal,
+	}
+	for port := range c.ExposedPorts {
+		ic.ExposedPorts = append(ic.ExposedPorts, port)
+	}
+	sort.Strings(ic.ExposedPorts)
+	return ic
+}
+
+// applyImageSpec fills the metadata of img from the OCI image config spec of the image
+func (img *ImageInspect) applyImageSpec(spec []byte) error {
+	var cf v1.ConfigFile
+	if err := json.Unmarshal(spec, &cf); err != nil {
+		return errors.Wrap(err, "parsing the image config")
+	}
+	img.Created = imageCreated(cf.Created.Time)
+	img.Architecture, img.OS, img.Variant = cf.Architecture, cf.OS, cf.Variant
+	img.Config = imageConfigOf(cf.Config)
+	img.Layers = nil
+	for _, l := range cf.RootFS.DiffIDs {
+		img.Layers = append(img.Layers, l.String())
+	}
+	return nil
+}
+
+// criImageInfo is the verbose info of the CRI image status of containerd and cri-o, holding the config spec of the image
+type criImageInfo struct {
+	ImageSpec json.RawMessage `json:"imageSpec"`
+}
+
+// InspectImage returns the metadata of an image, or ErrImageNotFound. The config spec of the image comes with the
+// verbose status, which not all runtimes fill in: Config and the fields depending on it are left empty then.
+func (c *criClient) InspectImage(name string) (*ImageInspect, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, criTimeout)
+	defer cancel()
+	resp, err := c.image.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{Image: &runtimeapi.ImageSpec{Image: name}, Verbose: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "CRI image status of %s over %s", name, c.socket)
+	}
+	if resp.Image == nil {
+		return nil, errors.Wrap(ErrImageNotFound, name)
+	}
+	img := &ImageInspect{
+		ID:          resp.Image.Id,
+		RepoTags:    resp.Image.RepoTags,
+		RepoDigests: resp.Image.RepoDigests,
+		Size:        resp.Image.Size_,
+	}
+	for _, v := range resp.Info {
+		var info criImageInfo
+		if err := json.Unmarshal([]byte(v), &info); err != nil || len(info.ImageSpec) == 0 {
+			continue
+		}
+		if err := img.applyImageSpec(info.ImageSpec); err != nil {
+			return nil, errors.Wrapf(err, "image %s", name)
+		}
+	}
+	return img, nil
+}
+
+// criInspectImage returns the metadata of an image, asking over the CRI socket or using crictl
+func criInspectImage(cr CommandRunner, socket string, name string) (*ImageInspect, error) {
+	if c, ok := criClientFor(cr, socket); ok {
+		img, err := c.InspectImage(name)
+		if err == nil || errors.Is(err, ErrImageNotFound) {
+			return img, err
+		}
+		klog.Infof("falling back to crictl: %v", err)
+	}
+
+	rr, err := cr.RunCmd(exec.Command("sudo", getCrictlPath(cr), "inspecti", "--output", "json", name))
+	if err != nil {
+		if strings.Contains(rr.Stderr.String(), "no such image") {
+			return nil, errors.Wrap(ErrImageNotFound, name)
+		}
+		return nil, errors.Wrapf(err, "crictl inspecti")
+	}
+	var status struct {
+		Status struct {
+			ID          string   `json:"id"`
+			RepoTags    []string `json:"repoTags"`
+			RepoDigests []string `json:"repoDigests"`
+			// uint64 fields are strings in the JSON of protobuf
+			Size string `json:"size"`
+		} `json:"status"`
+		Info criImageInfo `json:"info"`
+	}
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &status); err != nil {
+		return nil, errors.Wrap(err, "parsing crictl inspecti")
+	}
+	img := &ImageInspect{ID: status.Status.ID, RepoTags: status.Status.RepoTags, RepoDigests: status.Status.RepoDigests}
+	if status.Status.Size != "" {
+		if img.Size, err = strconv.ParseUint(status.Status.Size, 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "parsing the size of %s", name)
+		}
+	}
+	if len(status.Info.ImageSpec) > 0 {
+		if err := img.applyImageSpec(status.Info.ImageSpec); err != nil {
+			return nil, errors.Wrapf(err, "image %s", name)
+		}
+	}
+	return img, nil
+}
+
+// InspectImage returns the metadata of an image, or ErrImageNotFound
+func (r *Containerd) InspectImage(ctx context.Context, name string) (*ImageInspect, error) {
+	r = r.bind(ctx)
+	return criInspectImage(r.Runner, r.SocketPath(), name)
+}
+
+// InspectImage returns the metadata of an image, or ErrImageNotFound
+func (r *CRIO) InspectImage(ctx context.Context, name string) (*ImageInspect, error) {
+	r = r.bind(ctx)
+	return criInspectImage(r.Runner, r.SocketPath(), name)
+}
+
+// dockerImageInspect is the part of the output of docker image inspect minikube reports
+type dockerImageInspect struct {
+	ID           string   `json:"Id"`
+	RepoTags     []string `json:"RepoTags"`
+	RepoDigests  []string `json:"RepoDigests"`
+	Size         uint64   `json:"Size"`
+	Created      string   `json:"Created"`
+	Architecture string   `json:"Architecture"`
+	OS           string   `json:"Os"`
+	Variant      string   `json:"Variant"`
+	Config       v1.Config
+	RootFS       struct {
+		Layers []string `json:"Layers"`
+	} `json:"RootFS"`
+}
+
+// InspectImage returns the metadata of an image, or ErrImageNotFound
+func (r *Docker) InspectImage(ctx context.Context, name string) (*ImageInspect, error) {
+	r = r.bind(ctx)
+	rr, err := r.Runner.RunCmd(exec.Command("docker", "image", "inspect", name))
+	if err != nil {
+		if strings.Contains(rr.Stderr.String(), "No such image") {
+			return nil, errors.Wrap(ErrImageNotFound, name)
+		}
+		return nil, errors.Wrap(err, "docker image inspect")
+	}
+	var inspected []dockerImageInspect
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &inspected); err != nil {
+		return nil, errors.Wrap(err, "parsing docker image inspect")
+	}
+	if len(inspected) == 0 {
+		return nil, errors.Wrap(ErrImageNotFound, name)
+	}
+	d := inspected[0]
+	img := &ImageInspect{
+		ID:           d.ID,
+		RepoTags:     d.RepoTags,
+		RepoDigests:  d.RepoDigests,
+		Size:         d.Size,
+		Architecture: d.Architecture,
+		OS:           d.OS,
+		Variant:      d.Variant,
+		Config:       imageConfigOf(d.Config),
+		Layers:       d.RootFS.Layers,
+	}
+	if created, err := time.Parse(time.RFC3339Nano, d.Created); err == nil {
+		img.Created = imageCreated(created)
+	}
+	return img, nil
+}
+
+// InspectImage returns the metadata of an image, or ErrImageNotFound. Portoshim answers the CRI image status,
+// and portod its own one when portoshim is unreachable, which tells neither the config nor the layers of the image.
+func (r *Porto) InspectImage(ctx context.Context, name string) (*ImageInspect, error) {
+	r = r.bind(ctx)
+	img, err := criInspectImage(r.Runner, r.SocketPath(), name)
+	if err == nil || errors.Is(err, ErrImageNotFound) {
+		return img, err
+	}
+	api, apiErr := r.portoAPI()
+	if apiErr != nil {
+		return nil, err
+	}
+	defer api.Close()
+	klog.Infof("falling back to the porto API: %v", err)
+	li, err := api.ImageStatus(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "porto image status of %s", name)
+	}
+	size, _ := strconv.ParseUint(li.Size, 10, 64)
+	return &ImageInspect{ID: li.ID, RepoTags: li.RepoTags, RepoDigests: li.RepoDigests, Size: size, Created: li.Created}, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// testImageSpec is the OCI image config containerd reports in the verbose image status
+const testImageSpec = `{
+  "created": "2024-03-01T12:00:00.5Z",
+  "architecture": "arm64",
+  "variant": "v8",
+  "os": "linux",
+  "config": {
+    "User": "65532",
+    "Env": ["PATH=/usr/bin"],
+    "Entrypoint": ["/app"],
+    "Cmd": ["serve"],
+    "WorkingDir": "/srv",
+    "ExposedPorts": {"8080/tcp": {}, "443/tcp": {}},
+    "Labels": {"org.opencontainers.image.source": "https://example.com/app"}
+  },
+  "rootfs": {
+    "type": "layers",
+    "diff_ids": [
+      "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+      "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+    ]
+  }
+}`
+
+// testImageInspect is the metadata of the image of testImageSpec
+var testImageInspect = &ImageInspect{
+	ID:           "sha256:abc",
+	RepoTags:     []string{"example.com/app:v1"},
+	RepoDigests:  []string{"example.com/app@sha256:def"},
+	Size:         4096,
+	Created:      "2024-03-01T12:00:00Z",
+	Architecture: "arm64",
+	OS:           "linux",
+	Variant:      "v8",
+	Config: &ImageConfig{
+		User:         "65532",
+		Env:          []string{"PATH=/usr/bin"},
+		Entrypoint:   []string{"/app"},
+		Cmd:          []string{"serve"},
+		WorkingDir:   "/srv",
+		ExposedPorts: []string{"443/tcp", "8080/tcp"},
+		Labels:       map[string]string{"org.opencontainers.image.source": "https://example.com/app"},
+	},
+	Layers: []string{
+		"sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"sha256:2222222222222222222222222222222222222222222222222222222222222222",
+	},
+}
+
+func TestCRIInspectImage(t *testing.T) {
+	images := &fakeImageService{
+		images: []*runtimeapi.Image{{Id: "sha256:abc", RepoTags: []string{"example.com/app:v1"}, RepoDigests: []string{"example.com/app@sha256:def"}, Size_: 4096}},
+		info:   map[string]map[string]string{"sha256:abc": {"info": `{"chainID": "sha256:123", "imageSpec": ` + testImageSpec + `}`}},
+	}
+	socket := serveFakeCRI(t, images, &fakeRuntimeService{})
+	r := &Containerd{Runner: command.NewExecRunner(false), Socket: socket}
+
+	got, err := r.InspectImage(context.Background(), "example.com/app:v1")
+	if err != nil {
+		t.Fatalf("InspectImage: %v", err)
+	}
+	if diff := cmp.Diff(testImageInspect, got); diff != "" {
+		t.Errorf("InspectImage mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := r.InspectImage(context.Background(), "example.com/missing:v1"); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("InspectImage of a missing image = %v, want ErrImageNotFound", err)
+	}
+}
+
+func TestCrictlInspectImage(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo crictl inspecti --output json example.com/app:v1": `{
+  "status": {"id": "sha256:abc", "repoTags": ["example.com/app:v1"], "repoDigests": ["example.com/app@sha256:def"], "size": "4096"},
+  "info": {"chainID": "sha256:123", "imageSpec": ` + testImageSpec + `}
+}`,
+	})
+	got, err := criInspectImage(runner, "", "example.com/app:v1")
+	if err != nil {
+		t.Fatalf("criInspectImage: %v", err)
+	}
+	if diff := cmp.Diff(testImageInspect, got); diff != "" {
+		t.Errorf("criInspectImage mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerInspectImage(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"docker image inspect example.com/app:v1": `[{
+  "Id": "sha256:abc",
+  "RepoTags": ["example.com/app:v1"],
+  "RepoDigests": ["example.com/app@sha256:def"],
+  "Created": "2024-03-01T12:00:00.5Z",
+  "Size": 4096,
+  "Architecture": "arm64",
+  "Variant": "v8",
+  "Os": "linux",
+  "Config": {
+    "User": "65532",
+    "Env": ["PATH=/usr/bin"],
+    "Entrypoint": ["/app"],
+    "Cmd": ["serve"],
+    "WorkingDir": "/srv",
+    "ExposedPorts": {"8080/tcp": {}, "443/tcp": {}},
+    "Labels": {"org.opencontainers.image.source": "https://example.com/app"}
+  },
+  "RootFS": {"Type": "layers", "Layers": [
+    "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+    "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+  ]}
+}]`,
+	})
+	r := &Docker{Runner: runner}
+	got, err := r.InspectImage(context.Background(), "example.com/app:v1")
+	if err != nil {
+		t.Fatalf("InspectImage: %v", err)
+	}
+	if diff := cmp.Diff(testImageInspect, got); diff != "" {
+		t.Errorf("InspectImage mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// ImageSource is where the runtime of a node got an image from
+type ImageSource string
+
+const (
+	// ImagePreloaded images came with the preload tarball, or were in the runtime already
+	ImagePreloaded ImageSource = "preloaded"
+	// ImageCached images were loaded from the image cache of the host
+	ImageCached ImageSource = "cached"
+	// ImagePulled images were pulled from their registry
+	ImagePulled ImageSource = "pulled"
+	// ImageFailed images failed to be pulled
+	ImageFailed ImageSource = "failed"
+)
+
+// imageSourcesFile records where the images of the node came from since the last Enable, one "<source> <image>" line each.
+// It lives on the node, as the preload, the image cache and the addons each get the images with a runtime of their own.
+const imageSourcesFile = vmpath.GuestPersistentDir + "/image-sources"
+
+// resetImageSources forgets the image sources recorded before, so that only those of the current start are counted
+func resetImageSources(cr CommandRunner) {
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", imageSourcesFile)); err != nil {
+		klog.Warningf("unable to reset the image sources: %v", err)
+	}
+}
+
+// RecordImageSources records that the runtime of the node got imgs from source. It is best effort:
+// a failure to record only makes the summary of the start less accurate.
+func RecordImageSources(cr CommandRunner, source ImageSource, imgs ...string) {
+	if len(imgs) == 0 {
+		return
+	}
+	var sb strings.Builder
+	for _, img := range imgs {
+		fmt.Fprintf(&sb, "%s %s\n", source, img)
+	}
+	c := fmt.Sprintf("printf %%s %s >> %s", shellquote.Join(sb.String()), imageSourcesFile)
+	if _, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		klog.Warningf("unable to record %d %s images: %v", len(imgs), source, err)
+	}
+}
+
+// ImageSources returns where the runtime of the node got each image from since the last Enable.
+// An image recorded more than once has the source it first got in with: a pulled image is still there when the
+// preload is checked later on, while an image which failed to be pulled may be loaded from the cache afterwards.
+func ImageSources(cr CommandRunner) (map[string]ImageSource, error) {
+	// there is no record until an image is
+	c := fmt.Sprintf("cat %s 2>/dev/null; true", imageSourcesFile)
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c))
+	if err != nil {
+		return nil, errors.Wrap(err, "image sources")
+	}
+	sources := map[string]ImageSource{}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		source, img, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if s, seen := sources[img]; !seen || s == ImageFailed {
+			sources[img] = ImageSource(source)
+		}
+	}
+	return sources, nil
+}
+
+// CountImageSources returns the number of images of sources by ImageSource
+func CountImageSources(sources map[string]ImageSource) map[ImageSource]int {
+	counts := map[ImageSource]int{}
+	for _, s := range sources {
+		counts[s]++
+	}
+	return counts
+}
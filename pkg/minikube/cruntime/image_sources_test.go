@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// scriptLog is a fake runner keeping the scripts of the bash commands it runs
+type scriptLog struct {
+	*command.FakeCommandRunner
+	scripts []string
+}
+
+func (l *scriptLog) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	if len(cmd.Args) == 4 && cmd.Args[1] == "/bin/bash" {
+		l.scripts = append(l.scripts, cmd.Args[3])
+	}
+	return l.FakeCommandRunner.RunCmd(cmd)
+}
+
+func TestRecordImageSources(t *testing.T) {
+	runner := &scriptLog{FakeCommandRunner: command.NewFakeCommandRunner()}
+	RecordImageSources(runner, ImagePulled, "registry.k8s.io/pause:3.9", "docker.io/library/busybox:latest")
+	// nothing to record
+	RecordImageSources(runner, ImageCached)
+
+	want := []string{"printf %s 'pulled registry.k8s.io/pause:3.9\npulled docker.io/library/busybox:latest\n' >> /var/lib/minikube/image-sources"}
+	if diff := cmp.Diff(want, runner.scripts); diff != "" {
+		t.Errorf("RecordImageSources ran diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestImageSources(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "cat /var/lib/minikube/image-sources 2>/dev/null; true"`: `pulled registry.k8s.io/pause:3.9
+failed docker.io/istio/pilot:1.22.1
+preloaded registry.k8s.io/pause:3.9
+preloaded registry.k8s.io/etcd:3.5.9-0
+cached docker.io/istio/pilot:1.22.1
+preloaded registry.k8s.io/coredns/coredns:v1.10.1
+failed docker.io/istio/proxyv2:1.22.1
+`,
+	})
+	sources, err := ImageSources(runner)
+	if err != nil {
+		t.Fatalf("ImageSources: %v", err)
+	}
+	want := map[string]ImageSource{
+		"registry.k8s.io/pause:3.9":               ImagePulled,
+		"registry.k8s.io/etcd:3.5.9-0":            ImagePreloaded,
+		"registry.k8s.io/coredns/coredns:v1.10.1": ImagePreloaded,
+		"docker.io/istio/pilot:1.22.1":            ImageCached,
+		"docker.io/istio/proxyv2:1.22.1":          ImageFailed,
+	}
+	if diff := cmp.Diff(want, sources); diff != "" {
+		t.Errorf("ImageSources returned diff (-want +got):\n%s", diff)
+	}
+	wantCounts := map[ImageSource]int{ImagePreloaded: 2, ImageCached: 1, ImagePulled: 1, ImageFailed: 1}
+	if diff := cmp.Diff(wantCounts, CountImageSources(sources)); diff != "" {
+		t.Errorf("CountImageSources returned diff (-want +got):\n%s", diff)
+	}
+}
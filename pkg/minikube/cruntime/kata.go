@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// KataRuntimeClass is the name of the RuntimeClass, and of the CRI runtime handler, of Kata Containers
+const KataRuntimeClass = "kata"
+
+// ErrKataNotSupported is returned by runtimes which can't run pods in Kata Containers
+var ErrKataNotSupported = errors.New("Kata Containers are not supported by the container runtime")
+
+const (
+	// kataVersion is the release of Kata Containers installed into the nodes
+	kataVersion = "3.2.0"
+	// kataRoot is where the static release of Kata Containers unpacks to
+	kataRoot = "/opt/kata"
+	// kataShim is the containerd shim of Kata Containers, which containerd looks up in PATH for io.containerd.kata.v2
+	kataShim = "containerd-shim-kata-v2"
+	// crioKataConfigFile declares the kata runtime handler to cri-o
+	crioKataConfigFile = "/etc/crio/crio.conf.d/50-kata.conf"
+)
+
+// kataDir is where the release of Kata Containers is downloaded to
+var kataDir = path.Join(vmpath.GuestEphemeralDir, "kata")
+
+// kataSHA256 is the sha256 of the static release of kataVersion by GOARCH, which the download is verified against:
+// Kata Containers are not installed on architectures missing from it
+var kataSHA256 = map[string]string{}
+
+// kataURL returns the URL of the static release of Kata Containers for a node of the given GOARCH
+func kataURL(arch string) string {
+	return fmt.Sprintf("https://github.com/kata-containers/kata-containers/releases/download/%[1]s/kata-static-%[1]s-%[2]s.tar.xz", kataVersion, arch)
+}
+
+// installKata installs Kata Containers into /opt/kata of the node unless it is there already, verifying the release
+// against kataSHA256, and links its shim into PATH.
+// Kata runs each pod in a VM, so the node has to provide /dev/kvm, which takes nested virtualization in VM and KIC nodes.
+func installKata(cr CommandRunner) error {
+	if _, err := cr.RunCmd(exec.Command("test", "-c", "/dev/kvm")); err != nil {
+		return errors.New("Kata Containers run pods in VMs, which needs /dev/kvm on the node: enable nested virtualization for the driver, or use a driver which gives the node KVM")
+	}
+	if _, err := cr.RunCmd(exec.Command("test", "-x", path.Join(kataRoot, "bin", kataShim))); err != nil {
+		arch, err := NodeArch(cr)
+		if err != nil {
+			return err
+		}
+		sum, ok := kataSHA256[arch]
+		if !ok || sum == "" {
+			return fmt.Errorf("no sha256 is known for Kata Containers %s on %s to verify the download against", kataVersion, arch)
+		}
+		url := kataURL(arch)
+		klog.Infof("installing Kata Containers %s from %s", kataVersion, url)
+		archive := path.Join(kataDir, "kata-static.tar.xz")
+		steps := []struct {
+			name string
+			cmd  string
+		}{
+			{"prepare", fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", kataDir)},
+			{"download", fmt.Sprintf("curl -fsSL --retry 5 -o %s %s", archive, url)},
+			{"verify", fmt.Sprintf("echo '%s  %s' | sha256sum -c -", sum, archive)},
+			// the release holds ./opt/kata
+			{"unpack", fmt.Sprintf("tar -C / -xJf %s", archive)},
+		}
+		for _, s := range steps {
+			if _, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", s.cmd)); err != nil {
+				return errors.Wrapf(err, "kata: %s", s.name)
+			}
+		}
+		if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-rf", kataDir)); err != nil {
+			klog.Warningf("failed to remove %s: %v", kataDir, err)
+		}
+	}
+	for _, bin := range []string{kataShim, "kata-runtime"} {
+		if _, err := cr.RunCmd(exec.Command("sudo", "ln", "-sf", path.Join(kataRoot, "bin", bin), path.Join("/usr/local/bin", bin))); err != nil {
+			return errors.Wrapf(err, "linking %s", bin)
+		}
+	}
+	return nil
+}
+
+// InstallKata installs Kata Containers into the node and declares the kata runtime handler to containerd,
+// restarting containerd when that changed its configuration
+func (r *Containerd) InstallKata(ctx context.Context) error {
+	r = r.bind(ctx)
+	if err := installKata(r.Runner); err != nil {
+		return err
+	}
+	section := fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%s]`, KataRuntimeClass)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "grep", "-qF", section, containerdConfigFile)); err == nil {
+		return nil
+	}
+	c := exec.Command("sudo", "tee", "-a", containerdConfigFile)
+	c.Stdin = strings.NewReader(fmt.Sprintf("\n%s\n  runtime_type = \"io.containerd.kata.v2\"\n", section))
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrap(err, "declaring the kata runtime handler")
+	}
+	return r.Init.Restart("containerd")
+}
+
+// crioKataConfig declares the kata runtime handler to cri-o
+var crioKataConfig = fmt.Sprintf(`[crio.runtime.runtimes.%s]
+runtime_path = "%s/bin/%s"
+runtime_type = "vm"
+runtime_root = "/run/vc"
+privileged_without_host_devices = true
+`, KataRuntimeClass, kataRoot, kataShim)
+
+// InstallKata installs Kata Containers into the node and declares the kata runtime handler to cri-o,
+// restarting cri-o when that changed its configuration
+func (r *CRIO) InstallKata(ctx context.Context) error {
+	r = r.bind(ctx)
+	if err := installKata(r.Runner); err != nil {
+		return err
+	}
+	if rr, err := r.Runner.RunCmd(exec.Command("sudo", "cat", crioKataConfigFile)); err == nil && rr.Stdout.String() == crioKataConfig {
+		return nil
+	}
+	c := exec.Command("sudo", "tee", crioKataConfigFile)
+	c.Stdin = strings.NewReader(crioKataConfig)
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrap(err, "declaring the kata runtime handler")
+	}
+	return r.Init.Restart("crio")
+}
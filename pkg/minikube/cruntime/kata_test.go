@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// kataInstalled are the commands of a node which has KVM and Kata Containers installed already
+var kataInstalled = map[string]string{
+	"test -c /dev/kvm": "",
+	"test -x /opt/kata/bin/containerd-shim-kata-v2":                                            "",
+	"sudo ln -sf /opt/kata/bin/containerd-shim-kata-v2 /usr/local/bin/containerd-shim-kata-v2": "",
+	"sudo ln -sf /opt/kata/bin/kata-runtime /usr/local/bin/kata-runtime":                       "",
+}
+
+func TestInstallKata(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(kataInstalled)
+	// the kata runtime handler is declared already
+	runner.SetCommandToOutput(map[string]string{
+		`sudo grep -qF [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.kata] /etc/containerd/config.toml`: "",
+		"sudo cat /etc/crio/crio.conf.d/50-kata.conf":                                                              crioKataConfig,
+	})
+	for _, rt := range []string{"containerd", "crio"} {
+		cr, err := New(Config{Type: rt, Runner: runner})
+		if err != nil {
+			t.Fatalf("New(%s): %v", rt, err)
+		}
+		if !cr.Capabilities().SupportsKata {
+			t.Errorf("%s does not support Kata Containers", rt)
+		}
+		if err := cr.InstallKata(context.Background()); err != nil {
+			t.Errorf("InstallKata on %s: %v", rt, err)
+		}
+	}
+
+	for _, rt := range []string{"docker", "porto"} {
+		cr, err := New(Config{Type: rt, Runner: runner})
+		if err != nil {
+			t.Fatalf("New(%s): %v", rt, err)
+		}
+		if err := cr.InstallKata(context.Background()); !errors.Is(err, ErrKataNotSupported) {
+			t.Errorf("InstallKata on %s = %v, want ErrKataNotSupported", rt, err)
+		}
+	}
+}
+
+func TestInstallKataWithoutKVM(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{"uname -m": "x86_64"})
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	err = cr.InstallKata(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "/dev/kvm") {
+		t.Errorf("InstallKata without /dev/kvm = %v, want an error telling about it", err)
+	}
+}
+
+func TestInstallKataVerifiesDownload(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"test -c /dev/kvm": "",
+		"uname -m":         "aarch64",
+	})
+	// nothing is downloaded for an architecture without a pinned sha256
+	if err := installKata(runner); err == nil || !strings.Contains(err.Error(), "no sha256") {
+		t.Errorf("installKata on arm64 = %v, want an error about the missing sha256", err)
+	}
+
+	defer func(pinned map[string]string) { kataSHA256 = pinned }(kataSHA256)
+	kataSHA256 = map[string]string{"arm64": "0123abcd"}
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "rm -rf /var/tmp/minikube/kata && mkdir -p /var/tmp/minikube/kata"`:                           "",
+		`sudo /bin/bash -c "curl -fsSL --retry 5 -o /var/tmp/minikube/kata/kata-static.tar.xz ` + kataURL("arm64") + `"`: "",
+	})
+	// the download does not match, so it is not unpacked
+	if err := installKata(runner); err == nil || !strings.Contains(err.Error(), "kata: verify") {
+		t.Errorf("installKata of an unverified download = %v, want a verify error", err)
+	}
+}
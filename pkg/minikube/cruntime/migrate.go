@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// migrationDir is where the images of a cluster are exported to while it moves to another container runtime.
+// It is on the persistent disk, so that the images survive a restart of the node halfway through.
+var migrationDir = path.Join(vmpath.GuestPersistentDir, "runtime-migration")
+
+// migrationManifest lists the exported images left to import, so that a migration interrupted halfway resumes
+// on the next start of the node rather than losing them
+var migrationManifest = path.Join(migrationDir, "manifest.json")
+
+// migratedImage is an image exported from the runtime a cluster moves away from
+type migratedImage struct {
+	Tarball string   `json:"tarball"`
+	Tags    []string `json:"tags"`
+}
+
+// Migration moves an existing cluster from one container runtime to another, carrying over its images.
+// Export runs while the old runtime is still enabled, Import once the new one is.
+// The workloads are not carried over as such: kubelet recreates the pods in the new runtime when it starts again.
+type Migration struct {
+	From   Manager
+	Runner CommandRunner
+	Init   sysinit.Manager
+
+	images []migratedImage
+}
+
+// PendingMigration returns the migration an earlier start of the node left unfinished, with the images it has yet to
+// import, or nil if there is none
+func PendingMigration(cr CommandRunner) (*Migration, error) {
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("cat %s 2>/dev/null; true", migrationManifest)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", migrationManifest)
+	}
+	if strings.TrimSpace(rr.Stdout.String()) == "" {
+		return nil, nil
+	}
+	var images []migratedImage
+	if err := json.Unmarshal(rr.Stdout.Bytes(), &images); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", migrationManifest)
+	}
+	return &Migration{Runner: cr, images: images}, nil
+}
+
+// Pending returns the number of exported images the migration has yet to import
+func (m *Migration) Pending() int {
+	return len(m.images)
+}
+
+// MigrationSupported returns whether a cluster can be migrated from one container runtime to the other
+func MigrationSupported(from, to string) bool {
+	return from == "containerd" && to == "porto"
+}
+
+// Export stops kubelet, so that it does not restart pods in the old runtime, exports all tagged images of the old runtime
+// and removes its pods, so that none keeps running once the runtime is disabled.
+func (m *Migration) Export(ctx context.Context) error {
+	if err := m.Init.Stop("kubelet"); err != nil {
+		klog.Warningf("failed to stop kubelet before the runtime migration: %v", err)
+	}
+	images, err := m.From.ListImages(ListImagesOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "listing %s images", m.From.Name())
+	}
+	if _, err := m.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", migrationDir)); err != nil {
+		return errors.Wrapf(err, "creating %s", migrationDir)
+	}
+	m.images = nil
+	for i, img := range images {
+		tags := migratableTags(img.RepoTags)
+		if len(tags) == 0 {
+			klog.Infof("not migrating untagged image %s", img.ID)
+			continue
+		}
+		tarball := path.Join(migrationDir, fmt.Sprintf("image-%d.tar", i))
+		if err := m.From.SaveImage(ctx, tags[0], tarball); err != nil {
+			return errors.Wrapf(err, "exporting %s", tags[0])
+		}
+		m.images = append(m.images, migratedImage{Tarball: tarball, Tags: tags})
+	}
+	klog.Infof("exported %d images from %s", len(m.images), m.From.Name())
+	if err := m.writeManifest(); err != nil {
+		return err
+	}
+
+	crictl := getCrictlPath(m.Runner)
+	c := exec.Command("sudo", crictl, "--runtime-endpoint", "unix://"+m.From.SocketPath(), "rmp", "--all", "--force")
+	if _, err := m.Runner.RunCmd(c); err != nil {
+		return errors.Wrapf(err, "removing %s pods", m.From.Name())
+	}
+	return nil
+}
+
+// Import loads the exported images into the new runtime, tags them with the tags the export could not carry,
+// and removes the exported tarballs. The manifest is updated after each image, so that an interrupted import resumes
+// with the images it has not imported yet.
+func (m *Migration) Import(ctx context.Context, to Manager) error {
+	imported := 0
+	for len(m.images) > 0 {
+		img := m.images[0]
+		if err := to.LoadImage(ctx, img.Tarball); err != nil {
+			return errors.Wrapf(err, "importing %s", img.Tags[0])
+		}
+		for _, tag := range img.Tags[1:] {
+			if to.ImageExists(tag, "") {
+				continue
+			}
+			if err := to.TagImage(ctx, img.Tags[0], tag); err != nil {
+				return errors.Wrapf(err, "tagging %s", tag)
+			}
+		}
+		m.images = m.images[1:]
+		imported++
+		if err := m.writeManifest(); err != nil {
+			return err
+		}
+		if _, err := m.Runner.RunCmd(exec.Command("sudo", "rm", "-f", img.Tarball)); err != nil {
+			klog.Warningf("failed to remove %s: %v", img.Tarball, err)
+		}
+	}
+	klog.Infof("imported %d images into %s", imported, to.Name())
+	if _, err := m.Runner.RunCmd(exec.Command("sudo", "rm", "-rf", migrationDir)); err != nil {
+		klog.Warningf("failed to remove %s: %v", migrationDir, err)
+	}
+	return nil
+}
+
+// writeManifest records the images left to import on the node
+func (m *Migration) writeManifest() error {
+	b, err := json.Marshal(m.images)
+	if err != nil {
+		return err
+	}
+	if err := m.Runner.Copy(assets.NewMemoryAssetTarget(b, migrationManifest, "0644")); err != nil {
+		return errors.Wrapf(err, "writing %s", migrationManifest)
+	}
+	return nil
+}
+
+// migratableTags returns the tags of an image which can be exported, skipping the placeholders of untagged ones
+func migratableTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if t == "" || strings.Contains(t, "<none>") {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
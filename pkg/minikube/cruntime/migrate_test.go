@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// migrationManager records the image operations of a migration
+type migrationManager struct {
+	Manager
+	name   string
+	images []ListImage
+	ops    []string
+}
+
+func (m *migrationManager) Name() string       { return m.name }
+func (m *migrationManager) SocketPath() string { return "/run/containerd/containerd.sock" }
+func (m *migrationManager) ListImages(ListImagesOptions) ([]ListImage, error) {
+	return m.images, nil
+}
+func (m *migrationManager) SaveImage(_ context.Context, name, path string) error {
+	m.ops = append(m.ops, "save "+name+" "+path)
+	return nil
+}
+func (m *migrationManager) LoadImage(_ context.Context, path string) error {
+	m.ops = append(m.ops, "load "+path)
+	return nil
+}
+func (m *migrationManager) ImageExists(string, string) bool { return false }
+func (m *migrationManager) TagImage(_ context.Context, source, target string) error {
+	m.ops = append(m.ops, "tag "+source+" "+target)
+	return nil
+}
+
+func TestMigrationSupported(t *testing.T) {
+	if !MigrationSupported("containerd", "porto") {
+		t.Errorf("expected a migration from containerd to porto to be supported")
+	}
+	if MigrationSupported("docker", "porto") || MigrationSupported("porto", "containerd") {
+		t.Errorf("expected only migrations from containerd to porto to be supported")
+	}
+}
+
+func TestMigration(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		// whichever init system the node is detected to run
+		"systemctl --version":                               "systemd 252",
+		"sudo systemctl stop kubelet":                       "",
+		"sudo service kubelet stop":                         "",
+		"which crictl":                                      "/usr/bin/crictl\n",
+		"sudo mkdir -p /var/lib/minikube/runtime-migration": "",
+		"sudo /usr/bin/crictl --runtime-endpoint unix:///run/containerd/containerd.sock rmp --all --force": "",
+		"sudo rm -rf /var/lib/minikube/runtime-migration":                                                  "",
+		"sudo rm -f /var/lib/minikube/runtime-migration/image-0.tar":                                       "",
+		"sudo rm -f /var/lib/minikube/runtime-migration/image-3.tar":                                       "",
+	})
+	from := &migrationManager{name: "containerd", images: []ListImage{
+		{ID: "a", RepoTags: []string{"registry.k8s.io/pause:3.9"}},
+		{ID: "b"},
+		{ID: "c", RepoTags: []string{"<none>:<none>"}},
+		{ID: "d", RepoTags: []string{"docker.io/library/app:v1", "docker.io/library/app:latest"}},
+	}}
+	to := &migrationManager{name: "porto"}
+
+	m := &Migration{From: from, Runner: runner, Init: sysinit.New(runner)}
+	if err := m.Export(context.Background()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	manifest := `[{"tarball":"/var/lib/minikube/runtime-migration/image-0.tar","tags":["registry.k8s.io/pause:3.9"]},` +
+		`{"tarball":"/var/lib/minikube/runtime-migration/image-3.tar","tags":["docker.io/library/app:v1","docker.io/library/app:latest"]}]`
+	if got, _ := runner.GetFileToContents(assets.MemorySource); got != manifest {
+		t.Errorf("Export wrote the manifest %s, want %s", got, manifest)
+	}
+	if err := m.Import(context.Background(), to); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	wantSaved := []string{
+		"save registry.k8s.io/pause:3.9 /var/lib/minikube/runtime-migration/image-0.tar",
+		"save docker.io/library/app:v1 /var/lib/minikube/runtime-migration/image-3.tar",
+	}
+	if diff := cmp.Diff(wantSaved, from.ops); diff != "" {
+		t.Errorf("exported images diff (-want +got):\n%s", diff)
+	}
+	wantLoaded := []string{
+		"load /var/lib/minikube/runtime-migration/image-0.tar",
+		"load /var/lib/minikube/runtime-migration/image-3.tar",
+		"tag docker.io/library/app:v1 docker.io/library/app:latest",
+	}
+	if diff := cmp.Diff(wantLoaded, to.ops); diff != "" {
+		t.Errorf("imported images diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPendingMigration(t *testing.T) {
+	read := `sudo /bin/bash -c "cat /var/lib/minikube/runtime-migration/manifest.json 2>/dev/null; true"`
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{read: ""})
+	if m, err := PendingMigration(runner); err != nil || m != nil {
+		t.Errorf("PendingMigration() without a manifest = %v, %v, want none", m, err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		read: `[{"tarball":"/var/lib/minikube/runtime-migration/image-3.tar","tags":["docker.io/library/app:v1","docker.io/library/app:latest"]}]`,
+		"sudo rm -f /var/lib/minikube/runtime-migration/image-3.tar": "",
+		"sudo rm -rf /var/lib/minikube/runtime-migration":            "",
+	})
+	m, err := PendingMigration(runner)
+	if err != nil || m == nil || m.Pending() != 1 {
+		t.Fatalf("PendingMigration() = %v, %v, want one image left to import", m, err)
+	}
+	to := &migrationManager{name: "porto"}
+	if err := m.Import(context.Background(), to); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	want := []string{"load /var/lib/minikube/runtime-migration/image-3.tar", "tag docker.io/library/app:v1 docker.io/library/app:latest"}
+	if diff := cmp.Diff(want, to.ops); diff != "" {
+		t.Errorf("resumed import diff (-want +got):\n%s", diff)
+	}
+	if got, _ := runner.GetFileToContents(assets.MemorySource); got != "[]" {
+		t.Errorf("Import left the manifest %s, want no images left", got)
+	}
+}
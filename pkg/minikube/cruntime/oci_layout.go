@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// ociRefNameAnnotation is the annotation of the index of an OCI layout naming an image, either by tag or by reference
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// IsOCILayout returns whether dir is an OCI image layout directory, as ko, buildah and skopeo write them
+func IsOCILayout(dir string) bool {
+	fi, err := os.Stat(filepath.Join(dir, "oci-layout"))
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// ociLayoutImages returns the images of the OCI layout at dir by the references to load them as.
+// An image is named by the ref.name annotation of the index: a full reference is kept, a bare tag is applied to
+// the name of the directory, and images without the annotation are loaded as the latest tag of the directory.
+// Of multi-platform images, the one for the platform minikube runs on is picked.
+func ociLayoutImages(dir string) (map[name.Reference]v1.Image, error) {
+	idx, err := layout.ImageIndexFromPath(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading OCI layout %s", dir)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading the index of %s", dir)
+	}
+	repo := strings.ToLower(filepath.Base(filepath.Clean(dir)))
+
+	images := map[name.Reference]v1.Image{}
+	for _, desc := range m.Manifests {
+		var img v1.Image
+		switch {
+		case desc.MediaType.IsImage():
+			img, err = idx.Image(desc.Digest)
+		case desc.MediaType.IsIndex():
+			img, err = platformImage(idx, desc.Digest)
+		default:
+			klog.Infof("skipping %s of %s: unsupported media type %s", desc.Digest, dir, desc.MediaType)
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading image %s of %s", desc.Digest, dir)
+		}
+
+		ref, err := ociLayoutRef(repo, desc.Annotations[ociRefNameAnnotation])
+		if err != nil {
+			return nil, errors.Wrapf(err, "naming image %s of %s", desc.Digest, dir)
+		}
+		if _, ok := images[ref]; ok {
+			return nil, errors.Errorf("%s has several images named %s", dir, ref)
+		}
+		images[ref] = img
+	}
+	if len(images) == 0 {
+		return nil, errors.Errorf("%s has no images", dir)
+	}
+	return images, nil
+}
+
+// platformImage returns the image for the platform minikube runs on of the index with digest h within idx
+func platformImage(idx v1.ImageIndex, h v1.Hash) (v1.Image, error) {
+	child, err := idx.ImageIndex(h)
+	if err != nil {
+		return nil, err
+	}
+	m, err := child.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	want := v1.Platform{OS: "linux", Architecture: runtime.GOARCH}
+	for _, desc := range m.Manifests {
+		if desc.MediaType.IsImage() && desc.Platform != nil && desc.Platform.Satisfies(want) {
+			return child.Image(desc.Digest)
+		}
+	}
+	return nil, errors.Errorf("no image for %s/%s", want.OS, want.Architecture)
+}
+
+// ociLayoutRef returns the reference to load an image of the OCI layout of repo as, from its ref.name annotation
+func ociLayoutRef(repo string, refName string) (name.Reference, error) {
+	if refName == "" {
+		refName = "latest"
+	}
+	if !strings.ContainsAny(refName, "/:@") {
+		return name.NewTag(repo + ":" + refName)
+	}
+	return name.ParseReference(refName)
+}
+
+// loadDockerArchive writes images as a docker-archive stream, the format all runtimes load, into load
+func loadDockerArchive(images map[name.Reference]v1.Image, load func(io.Reader) error) error {
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := tarball.MultiRefWrite(images, pw)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	err := load(pr)
+	// unblocks the writer when load stopped reading early
+	pr.CloseWithError(io.ErrClosedPipe)
+	if werr := <-written; err == nil && werr != nil && werr != io.ErrClosedPipe {
+		return errors.Wrap(werr, "writing docker archive")
+	}
+	return err
+}
+
+// loadOCILayout loads the images of the OCI layout at dir by passing them to load as a docker-archive stream
+func loadOCILayout(dir string, load func(io.Reader) error) error {
+	images, err := ociLayoutImages(dir)
+	if err != nil {
+		return err
+	}
+	return loadDockerArchive(images, load)
+}
+
+// WriteOCILayout writes the images of the docker-archive tarball at archive into a new OCI image layout directory at dir,
+// an entry of the index for each tag, named by the full reference so that loading the layout restores the tags
+func WriteOCILayout(archive string, dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return errors.Errorf("%s already exists", dir)
+	}
+	opener := func() (io.ReadCloser, error) { return os.Open(archive) }
+	manifests, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return errors.Wrapf(err, "reading the manifest of %s", archive)
+	}
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return errors.Wrapf(err, "creating OCI layout %s", dir)
+	}
+	written := 0
+	for _, m := range manifests {
+		if len(m.RepoTags) == 0 {
+			klog.Infof("skipping untagged image %s of %s", m.Config, archive)
+			continue
+		}
+		for _, t := range m.RepoTags {
+			tag, err := name.NewTag(t)
+			if err != nil {
+				return errors.Wrapf(err, "parsing tag %s of %s", t, archive)
+			}
+			img, err := tarball.Image(opener, &tag)
+			if err != nil {
+				return errors.Wrapf(err, "reading %s from %s", t, archive)
+			}
+			if err := p.AppendImage(img, layout.WithAnnotations(map[string]string{ociRefNameAnnotation: t})); err != nil {
+				return errors.Wrapf(err, "writing %s to %s", t, dir)
+			}
+			written++
+		}
+	}
+	if written == 0 {
+		return errors.Errorf("%s has no tagged images", archive)
+	}
+	return nil
+}
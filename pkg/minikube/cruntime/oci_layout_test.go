@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// writeOCILayout writes an OCI layout named app with a random image for each of refNames, as its ref.name annotation
+func writeOCILayout(t *testing.T, refNames ...string) (string, map[string]v1.Image) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "App")
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatalf("layout.Write: %v", err)
+	}
+	images := map[string]v1.Image{}
+	for _, refName := range refNames {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random.Image: %v", err)
+		}
+		var opts []layout.Option
+		if refName != "" {
+			opts = append(opts, layout.WithAnnotations(map[string]string{ociRefNameAnnotation: refName}))
+		}
+		if err := p.AppendImage(img, opts...); err != nil {
+			t.Fatalf("AppendImage: %v", err)
+		}
+		images[refName] = img
+	}
+	return dir, images
+}
+
+func TestOCILayoutRef(t *testing.T) {
+	tests := []struct {
+		refName string
+		want    string
+	}{
+		{"", "app:latest"},
+		{"v1.2", "app:v1.2"},
+		{"example.com/team/app:dev", "example.com/team/app:dev"},
+		{"busybox:1.36", "busybox:1.36"},
+	}
+	for _, tc := range tests {
+		ref, err := ociLayoutRef("app", tc.refName)
+		if err != nil {
+			t.Errorf("ociLayoutRef(%q): %v", tc.refName, err)
+			continue
+		}
+		if ref.String() != tc.want {
+			t.Errorf("ociLayoutRef(%q) = %s, want %s", tc.refName, ref, tc.want)
+		}
+	}
+}
+
+func TestLoadOCILayout(t *testing.T) {
+	dir, images := writeOCILayout(t, "", "v2", "example.com/app:dev")
+	if !IsOCILayout(dir) {
+		t.Fatalf("IsOCILayout(%s) = false", dir)
+	}
+	if IsOCILayout(filepath.Dir(dir)) {
+		t.Errorf("IsOCILayout(%s) = true", filepath.Dir(dir))
+	}
+
+	var archive bytes.Buffer
+	err := loadOCILayout(dir, func(stream io.Reader) error {
+		_, err := io.Copy(&archive, stream)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("loadOCILayout: %v", err)
+	}
+
+	opener := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(archive.Bytes())), nil }
+	for refName, want := range map[string]string{"": "app:latest", "v2": "app:v2", "example.com/app:dev": "example.com/app:dev"} {
+		tag, err := name.NewTag(want)
+		if err != nil {
+			t.Fatalf("NewTag(%s): %v", want, err)
+		}
+		got, err := tarball.Image(opener, &tag)
+		if err != nil {
+			t.Errorf("image %s not in the archive: %v", want, err)
+			continue
+		}
+		gotID, err := got.ConfigName()
+		if err != nil {
+			t.Errorf("ConfigName of %s: %v", want, err)
+			continue
+		}
+		wantID, _ := images[refName].ConfigName()
+		if gotID != wantID {
+			t.Errorf("image %s has ID %s, want %s", want, gotID, wantID)
+		}
+	}
+}
+
+func TestPortoLoadImageFromLayout(t *testing.T) {
+	dir, images := writeOCILayout(t, "v1")
+	id, err := images["v1"].ConfigName()
+	if err != nil {
+		t.Fatalf("ConfigName: %v", err)
+	}
+
+	runner := command.NewFakeCommandRunner()
+	// docker-load did not keep the tag, so it is restored from the index of the layout
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-load /dev/stdin":           "",
+		"sudo portoctl docker-images":                    "ID   NAME\n",
+		"sudo portoctl docker-tag " + id.Hex + " app:v1": "",
+	})
+	r := &Porto{Runner: runner}
+	if err := r.LoadImageFromLayout(context.Background(), dir); err != nil {
+		t.Errorf("LoadImageFromLayout: %v", err)
+	}
+
+	if err := r.LoadImageFromLayout(context.Background(), t.TempDir()); err == nil {
+		t.Errorf("LoadImageFromLayout of a directory without a layout succeeded")
+	}
+}
+
+func TestWriteOCILayout(t *testing.T) {
+	images := map[name.Reference]v1.Image{}
+	for _, ref := range []string{"example.com/app:v1", "example.com/db:v2"} {
+		img, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random.Image: %v", err)
+		}
+		tag, err := name.NewTag(ref)
+		if err != nil {
+			t.Fatalf("NewTag(%s): %v", ref, err)
+		}
+		images[tag] = img
+	}
+	archive := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := tarball.MultiRefWriteToFile(archive, images); err != nil {
+		t.Fatalf("MultiRefWriteToFile: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	if err := WriteOCILayout(archive, dir); err != nil {
+		t.Fatalf("WriteOCILayout: %v", err)
+	}
+	got, err := ociLayoutImages(dir)
+	if err != nil {
+		t.Fatalf("ociLayoutImages: %v", err)
+	}
+	if len(got) != len(images) {
+		t.Errorf("layout has %d images, want %d", len(got), len(images))
+	}
+	for ref, img := range images {
+		want, _ := img.ConfigName()
+		var found bool
+		for gotRef, gotImg := range got {
+			if gotRef.String() != ref.String() {
+				continue
+			}
+			found = true
+			if id, _ := gotImg.ConfigName(); id != want {
+				t.Errorf("image %s has ID %s, want %s", ref, id, want)
+			}
+		}
+		if !found {
+			t.Errorf("image %s is not in the layout", ref)
+		}
+	}
+
+	if err := WriteOCILayout(archive, dir); err == nil {
+		t.Errorf("WriteOCILayout over an existing directory succeeded")
+	}
+}
@@ -17,21 +17,44 @@ limitations under the License.
 package cruntime
 
 import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blang/semver/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kballard/go-shellquote"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/klog/v2"
 
+	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/download"
+	"k8s.io/minikube/pkg/minikube/localpath"
 	"k8s.io/minikube/pkg/minikube/style"
 	"k8s.io/minikube/pkg/minikube/sysinit"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+	"k8s.io/minikube/pkg/util"
+	"k8s.io/minikube/pkg/util/retry"
 )
 
 // Porto contains porto runtime state
@@ -42,6 +65,33 @@ type Porto struct {
 	KubernetesVersion semver.Version
 	Init              sysinit.Manager
 	InsecureRegistry  []string
+	// Debug raises the log levels of portod and portoshim and logs CRI requests
+	Debug bool
+	// DownloadTmpfsMB is the size of the tmpfs portod downloads and unpacks image layers to, 0 keeps them on disk
+	DownloadTmpfsMB int
+	// FeatureGates enables experimental porto features in the portod configuration
+	FeatureGates FeatureGates
+	// Ulimits are the default ulimits of containers, in the format of ParseUlimits
+	Ulimits string
+	// AllowedUnsafeSysctls are the sysctls outside the safe set portod and kubelet let pods set
+	AllowedUnsafeSysctls []string
+	// BuildCacheMB is the size the layer cache of image builds is kept under, 0 disables the cache
+	BuildCacheMB int
+	// SocketUser is added to the porto group, which is given access to the portod and portoshim sockets; empty leaves them to root
+	SocketUser string
+	// RegistryCertsDir is the directory of the node portod reads registry certificates from, none when empty
+	RegistryCertsDir string
+
+	// change is what the last Enable changed on the node
+	change ConfigChange
+}
+
+// bind returns a copy of r which runs its commands within ctx
+func (r *Porto) bind(ctx context.Context) *Porto {
+	b := *r
+	b.Runner = withContext(ctx, r.Runner)
+	b.Init = sysinit.WithRunner(r.Init, b.Runner)
+	return &b
 }
 
 // Name is a human readable name for porto
@@ -65,8 +115,27 @@ func parsePortoVersion(line string) (string, error) {
 	return "", fmt.Errorf("unknown version: %q", line)
 }
 
+// portoAPI connects to the porto API of the node. Callers fall back to portoctl or crictl when it fails,
+// as runners which can't dial sockets, such as the one of the kic drivers, can't reach it.
+func (r *Porto) portoAPI() (*portoClient, error) {
+	c, err := dialPorto(r.Runner, r.PortodSocketPath())
+	if err != nil {
+		klog.Infof("porto API unavailable, falling back to the command line tools: %v", err)
+	}
+	return c, err
+}
+
 // Version retrieves the current version of this runtime
-func (r *Porto) Version() (string, error) {
+func (r *Porto) Version(ctx context.Context) (string, error) {
+	r = r.bind(ctx)
+	if api, err := r.portoAPI(); err == nil {
+		defer api.Close()
+		if v, err := api.Version(); err == nil {
+			return v, nil
+		}
+		klog.Warningf("porto API version: %v", err)
+	}
+
 	c := exec.Command("portod", "version")
 	rr, err := r.Runner.RunCmd(c)
 	if err != nil {
@@ -79,7 +148,7 @@ func (r *Porto) Version() (string, error) {
 	return version, nil
 }
 
-// SocketPath returns the path to the socket file for porto
+// SocketPath returns the path to the CRI socket of portoshim, which kubelet and crictl talk to
 func (r *Porto) SocketPath() string {
 	if r.Socket != "" {
 		return r.Socket
@@ -87,232 +156,1858 @@ func (r *Porto) SocketPath() string {
 	return "/run/portoshim.sock"
 }
 
+// PortodSocketPath returns the path to the socket portod serves its API on, which portoctl and the porto API client talk to.
+// Unlike the portoshim socket it can't be configured: portoctl only looks for portod there.
+func (r *Porto) PortodSocketPath() string {
+	return portodSocket
+}
+
+// portoctl returns the command running portoctl with args against the portod of the node.
+// It runs as root, as the portod socket is only accessible to root and the porto group.
+func (r *Porto) portoctl(args ...string) *exec.Cmd {
+	return exec.Command("sudo", append([]string{"portoctl"}, args...)...)
+}
+
 // Active returns if porto is active on the host
 func (r *Porto) Active() bool {
 	return r.Init.Active("porto")
 }
 
+// PortoMinISOVersion is the first minikube ISO which carries the porto packages
+const PortoMinISOVersion = "v1.32.1-1702708929-17806"
+
+// MissingPortoBinaries returns the binaries of porto and portoshim the node lacks, which are all of them
+// on an ISO predating PortoMinISOVersion
+func MissingPortoBinaries(cr CommandRunner) []string {
+	var missing []string
+	for _, b := range append(append([]string{}, portoBinaries...), "portoshim") {
+		// they are installed to /sbin, which only the PATH of sudo is sure to have
+		if _, err := cr.RunCmd(exec.Command("sudo", "which", b)); err != nil {
+			missing = append(missing, b)
+		}
+	}
+	return missing
+}
+
 // Available returns an error if it is not possible to use this runtime on a host
 func (r *Porto) Available() error {
 	c := exec.Command("which", "portoshim")
 	if _, err := r.Runner.RunCmd(c); err != nil {
 		return errors.Wrap(err, "check porto availability")
 	}
-	return checkCNIPlugins(r.KubernetesVersion)
+	if err := CheckPortoKernel(r.Runner); err != nil {
+		return err
+	}
+	return checkPortoCNIPlugins(r.Runner, r.KubernetesVersion)
 }
 
-// generatePortoConfig sets up /etc/porto/config.toml & /etc/porto/porto.conf.d/02-porto.conf
-func generatePortoConfig(cr CommandRunner, imageRepository string, kv semver.Version, cgroupDriver string, insecureRegistry []string, inUserNamespace bool) error {
-	return nil
+// portoKernelModules are the kernel modules porto needs, either loaded or built in
+var portoKernelModules = []string{"overlay", "br_netfilter", "veth"}
+
+// portoCgroupControllers are the cgroup controllers porto manages containers with
+var portoCgroupControllers = []string{"cpu", "cpuacct", "cpuset", "memory", "devices", "freezer", "pids", "blkio"}
+
+// ErrKernelPrerequisites is the error returned when the host kernel lacks features a runtime depends on
+type ErrKernelPrerequisites struct {
+	// Runtime is the name of the runtime which has the requirements
+	Runtime string
+	// MissingModules are the kernel modules that could not be loaded
+	MissingModules []string
+	// MissingControllers are the cgroup controllers that are not enabled
+	MissingControllers []string
+	// NoOverlayFS is set when the kernel does not support overlay filesystems
+	NoOverlayFS bool
 }
 
-// Enable idempotently enables porto on a host
-func (r *Porto) Enable(disOthers bool, cgroupDriver string, inUserNamespace bool) error {
-	if inUserNamespace {
-		if err := CheckKernelCompatibility(r.Runner, 5, 11); err != nil {
-			// For using overlayfs
-			return fmt.Errorf("kernel >= 5.11 is required for rootless mode: %w", err)
-		}
-		if err := CheckKernelCompatibility(r.Runner, 5, 13); err != nil {
-			// For avoiding SELinux error with overlayfs
-			klog.Warningf("kernel >= 5.13 is recommended for rootless mode %v", err)
+func (e ErrKernelPrerequisites) empty() bool {
+	return len(e.MissingModules) == 0 && len(e.MissingControllers) == 0 && !e.NoOverlayFS
+}
+
+func (e ErrKernelPrerequisites) Error() string {
+	var missing []string
+	if len(e.MissingModules) > 0 {
+		missing = append(missing, fmt.Sprintf("kernel modules: %s", strings.Join(e.MissingModules, ", ")))
+	}
+	if len(e.MissingControllers) > 0 {
+		missing = append(missing, fmt.Sprintf("cgroup controllers: %s", strings.Join(e.MissingControllers, ", ")))
+	}
+	if e.NoOverlayFS {
+		missing = append(missing, "overlay filesystem support")
+	}
+	return fmt.Sprintf("kernel is missing prerequisites for %s: %s", e.Runtime, strings.Join(missing, "; "))
+}
+
+var (
+	// criV1KubernetesVersion is the first Kubernetes version whose kubelet only speaks the v1 CRI API
+	criV1KubernetesVersion = semver.MustParse("1.26.0")
+	// criEventsKubernetesVersion is the first Kubernetes version whose kubelet relies on CRI container events (evented PLEG)
+	criEventsKubernetesVersion = semver.MustParse("1.31.0")
+	// portoshimEventsVersion is the first portoshim release implementing CRI container events
+	portoshimEventsVersion = semver.MustParse("1.0.10")
+)
+
+// ErrCRIVersion is the error returned when the CRI implementation of a runtime is too old for the Kubernetes version
+type ErrCRIVersion struct {
+	// Runtime is the name of the CRI implementation, as it announces itself
+	Runtime string
+	// Installed is the version of the CRI implementation
+	Installed string
+	// Required is the minimum version or CRI API version needed
+	Required string
+	// KubernetesVersion is the Kubernetes version which has the requirement
+	KubernetesVersion string
+	// Fatal is set when kubelet cannot work with the runtime at all, rather than missing out on some features
+	Fatal bool
+}
+
+func (e ErrCRIVersion) Error() string {
+	if e.Fatal {
+		return fmt.Sprintf("%s %s is not supported by Kubernetes %s, which requires %s", e.Runtime, e.Installed, e.KubernetesVersion, e.Required)
+	}
+	return fmt.Sprintf("%s %s lacks CRI features Kubernetes %s relies on, upgrade to %s or newer", e.Runtime, e.Installed, e.KubernetesVersion, e.Required)
+}
+
+// checkCRIVersion compares the CRI API and portoshim version announced over the CRI socket with what kubelet expects
+func (r *Porto) checkCRIVersion() error {
+	if r.KubernetesVersion.Equals(semver.Version{}) {
+		return nil
+	}
+	v, err := getCRIVersion(r.Runner, r.SocketPath())
+	if err != nil {
+		return errors.Wrap(err, "getting CRI version")
+	}
+	klog.Infof("%s %s announces CRI API %s", v.RuntimeName, v.RuntimeVersion, v.RuntimeAPIVersion)
+	return portoshimCompatible(v, r.KubernetesVersion)
+}
+
+// portoshimCompatible returns an ErrCRIVersion if the announced CRI version does not meet the requirements of Kubernetes k8s
+func portoshimCompatible(v criVersion, k8s semver.Version) error {
+	if k8s.GTE(criV1KubernetesVersion) && v.RuntimeAPIVersion != "v1" {
+		return &ErrCRIVersion{
+			Runtime:           v.RuntimeName,
+			Installed:         fmt.Sprintf("%s (CRI API %s)", v.RuntimeVersion, v.RuntimeAPIVersion),
+			Required:          "CRI API v1",
+			KubernetesVersion: k8s.String(),
+			Fatal:             true,
 		}
 	}
-	if disOthers {
-		if err := disableOthers(r, r.Runner); err != nil {
-			klog.Warningf("disableOthers: %v", err)
+	if k8s.LT(criEventsKubernetesVersion) {
+		return nil
+	}
+	installed, err := semver.ParseTolerant(v.RuntimeVersion)
+	if err != nil {
+		klog.Warningf("unable to parse %s version %q: %v", v.RuntimeName, v.RuntimeVersion, err)
+		return nil
+	}
+	if installed.LT(portoshimEventsVersion) {
+		return &ErrCRIVersion{
+			Runtime:           v.RuntimeName,
+			Installed:         v.RuntimeVersion,
+			Required:          portoshimEventsVersion.String(),
+			KubernetesVersion: k8s.String(),
 		}
 	}
-	if err := populateCRIConfig(r.Runner, r.SocketPath()); err != nil {
-		return err
+	return nil
+}
+
+// CheckPortoKernel checks the modules, cgroup controllers and filesystems porto requires, returning an ErrKernelPrerequisites
+// listing what the kernel of the node lacks
+func CheckPortoKernel(cr CommandRunner) error {
+	report := ErrKernelPrerequisites{Runtime: "porto"}
+	for _, m := range portoKernelModules {
+		// modprobe succeeds for modules which are built into the kernel as well
+		if _, err := cr.RunCmd(exec.Command("sudo", "modprobe", m)); err != nil {
+			klog.Warningf("unable to load kernel module %q: %v", m, err)
+			report.MissingModules = append(report.MissingModules, m)
+		}
 	}
 
-	if err := generatePortoConfig(r.Runner, r.ImageRepository, r.KubernetesVersion, cgroupDriver, r.InsecureRegistry, inUserNamespace); err != nil {
+	enabled, err := EnabledCgroupControllers(cr)
+	if err != nil {
 		return err
 	}
-	if err := enableIPForwarding(r.Runner); err != nil {
-		return err
+	for _, c := range portoCgroupControllers {
+		if !enabled[c] {
+			report.MissingControllers = append(report.MissingControllers, c)
+		}
 	}
-	if err := r.Init.Restart("porto"); err != nil {
-		return err
+
+	rr, err := cr.RunCmd(exec.Command("cat", "/proc/filesystems"))
+	if err != nil {
+		return errors.Wrap(err, "list filesystems")
 	}
+	report.NoOverlayFS = !hasFilesystem(rr.Stdout.String(), "overlay")
 
-	// HACK(ernado): porto is missing this image for some reason.
-	if err := r.PullImage("registry.k8s.io/pause:3.7"); err != nil {
-		return errors.Wrap(err, "pulling pause image")
+	if report.empty() {
+		return nil
 	}
+	return report
+}
 
-	return nil
+// portoSocketWait is how long Enable waits for portod and portoshim to create their sockets after a restart
+const portoSocketWait = 30 * time.Second
+
+// portoCgroupNSConf is the portod configuration deciding whether pod containers get their own cgroup namespace
+const portoCgroupNSConf = "/etc/portod.conf.d/50-minikube-cgroupns.conf"
+
+// ErrCgroupNamespaces is the error returned when the kernel does not provide cgroup namespaces but the runtime needs them
+type ErrCgroupNamespaces struct {
+	// Runtime is the name of the runtime which needs cgroup namespaces
+	Runtime string
 }
 
-// Disable idempotently disables porto on a host
-func (r *Porto) Disable() error {
-	return r.Init.ForceStop("porto")
+func (e ErrCgroupNamespaces) Error() string {
+	return fmt.Sprintf("%s requires cgroup namespaces in rootless mode, but the kernel does not provide them", e.Runtime)
 }
 
-// ImageExists checks if image exists based on image name and optionally image sha
-func (r *Porto) ImageExists(name string, sha string) bool {
-	klog.Infof("Checking existence of image with name %q and sha %q", name, sha)
-	c := exec.Command("sudo", "portoctl", "docker-images")
-	// note: image name and image id's sha can be on different lines
-	// TODO(ernado): RLY?
-	if rr, err := r.Runner.RunCmd(c); err != nil ||
-		!strings.Contains(rr.Output(), name) ||
-		(sha != "" && !strings.Contains(rr.Output(), sha)) {
+// hasCgroupNamespaces returns whether a cgroup namespace can be created on the node.
+// This fails on kernels built without them, and when user.max_cgroup_namespaces is 0.
+func hasCgroupNamespaces(cr CommandRunner) bool {
+	if _, err := cr.RunCmd(exec.Command("sudo", "unshare", "--cgroup", "true")); err != nil {
+		klog.Warningf("unable to create a cgroup namespace: %v", err)
 		return false
 	}
 	return true
 }
 
-// ListImages lists images managed by this container runtime
-func (r *Porto) ListImages(ListImagesOptions) ([]ListImage, error) {
-	return listCRIImages(r.Runner)
-}
+// configureCgroupNamespaces makes portod put pod containers into their own cgroup namespace when the kernel allows it,
+// so that they don't see the cgroup tree of the host. Without cgroup namespaces, rootless mode can't keep
+// the cgroups of the host and of other pods apart, so Enable fails instead.
+// Enable restarts portod afterwards.
+func (r *Porto) configureCgroupNamespaces(inUserNamespace bool) error {
+	available := hasCgroupNamespaces(r.Runner)
+	if !available {
+		if inUserNamespace {
+			return &ErrCgroupNamespaces{Runtime: r.Name()}
+		}
+		klog.Warningf("cgroup namespaces are not available, porto containers will see the cgroup tree of the node")
+	}
 
-// LoadImage loads an image into this runtime
-func (r *Porto) LoadImage(path string) error {
-	return errors.New("not implemented")
+	content := fmt.Sprintf(`container {
+  use_os_mode_cgroupns: %t
 }
-
-// PullImage pulls an image into this runtime
-func (r *Porto) PullImage(name string) error {
-	return pullCRIImage(r.Runner, name)
+`, available)
+	return writePortoConfig(r.Runner, portoCgroupNSConf, content)
 }
 
-// SaveImage save an image from this runtime
-func (r *Porto) SaveImage(name string, path string) error {
-	return errors.New("not implemented")
+// EnabledCgroupControllers returns which cgroup controllers the kernel of the node enables, by name
+func EnabledCgroupControllers(cr CommandRunner) (map[string]bool, error) {
+	rr, err := cr.RunCmd(exec.Command("cat", "/proc/cgroups"))
+	if err != nil {
+		return nil, errors.Wrap(err, "list cgroup controllers")
+	}
+	return parseCgroupControllers(rr.Stdout.String()), nil
 }
 
-// RemoveImage removes a image
-func (r *Porto) RemoveImage(name string) error {
-	return removeCRIImage(r.Runner, name)
+// parseCgroupControllers returns the enabled controllers listed in /proc/cgroups
+func parseCgroupControllers(s string) map[string]bool {
+	// #subsys_name	hierarchy	num_cgroups	enabled
+	// cpuset	0	97	1
+	enabled := map[string]bool{}
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		enabled[fields[0]] = fields[3] == "1"
+	}
+	return enabled
 }
 
-// TagImage tags an image in this runtime
-func (r *Porto) TagImage(source string, target string) error {
-	return errors.New("not implemented")
+// hasFilesystem returns if the filesystem is listed in /proc/filesystems
+func hasFilesystem(s string, name string) bool {
+	// nodev	overlay
+	//	ext4
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[len(fields)-1] == name {
+			return true
+		}
+	}
+	return false
 }
 
-// BuildImage builds an image into this runtime
-func (r *Porto) BuildImage(src string, file string, tag string, push bool, env []string, opts []string) error {
-	return errors.New("not implemented")
-}
+// portoFeaturesConf is the portod configuration enabling experimental features behind runtime feature gates
+const portoFeaturesConf = "/etc/portod.conf.d/40-minikube-features.conf"
 
-// PushImage pushes an image
-func (r *Porto) PushImage(name string) error {
-	return errors.New("not implemented")
+// portoFeatureConfig is the portod configuration each runtime feature gate adds when enabled
+var portoFeatureConfig = map[string]string{
+	FeaturePortoNativeNetworking: `network {
+  enabled: true
 }
-
-// CGroupDriver returns cgroup driver ("cgroupfs" or "systemd")
-func (r *Porto) CGroupDriver() (string, error) {
-	return "systemd", nil
+`,
+	FeatureSandboxCheckpointing: `container {
+  enable_checkpoint: true
 }
-
-// KubeletOptions returns kubelet options for a porto
-func (r *Porto) KubeletOptions() map[string]string {
-	return kubeletCRIOptions(r, r.KubernetesVersion)
+`,
+	FeatureSharedLayerCache: `volumes {
+  enable_shared_layers: true
 }
-
-// ListContainers returns a list of managed by this container runtime
-func (r *Porto) ListContainers(o ListContainersOptions) ([]string, error) {
-	return listCRIContainers(r.Runner, "", o)
+`,
 }
 
-// PauseContainers pauses a running container based on ID
-func (r *Porto) PauseContainers(ids []string) error {
-	return pauseCRIContainers(r.Runner, "", ids)
-}
+const (
+	// PortoUlimitsOption is the --extra-config=porto.<option> setting the default ulimits of containers, such as nofile=1048576
+	PortoUlimitsOption = "ulimits"
+	// PortoAllowedUnsafeSysctlsOption is the --extra-config=porto.<option> listing the unsafe sysctls pods may set
+	PortoAllowedUnsafeSysctlsOption = "allowed-unsafe-sysctls"
+	// portoLimitsConf is the portod configuration of the default ulimits and the allowed sysctls
+	portoLimitsConf = "/etc/portod.conf.d/30-minikube-limits.conf"
+)
 
-// UnpauseContainers unpauses a running container based on ID
-func (r *Porto) UnpauseContainers(ids []string) error {
-	return unpauseCRIContainers(r.Runner, "", ids)
+// ulimitNames are the resources of setrlimit(2), by the names porto knows them
+var ulimitNames = []string{"as", "core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice", "nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack"}
+
+// ParseUlimits parses the ulimits of --extra-config=porto.ulimits, comma-separated name=soft[:hard] pairs
+// such as nofile=1048576,nproc=65536:unlimited, into the ulimit format of porto: "nofile: 1048576 1048576; ...".
+// The hard limit defaults to the soft one.
+func ParseUlimits(value string) (string, error) {
+	limits := []string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, limit, ok := strings.Cut(pair, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid ulimit %q, expected name=soft[:hard]", pair)
+		}
+		name = strings.TrimSpace(name)
+		known := false
+		for _, n := range ulimitNames {
+			known = known || n == name
+		}
+		if !known {
+			return "", fmt.Errorf("unknown ulimit %q, known ulimits are: %s", name, strings.Join(ulimitNames, ", "))
+		}
+		soft, hard, ok := strings.Cut(strings.TrimSpace(limit), ":")
+		if !ok {
+			hard = soft
+		}
+		for _, l := range []string{soft, hard} {
+			if _, err := strconv.ParseUint(l, 10, 64); err != nil && l != "unlimited" {
+				return "", fmt.Errorf("invalid limit %q of ulimit %s, expected a number or unlimited", l, name)
+			}
+		}
+		limits = append(limits, fmt.Sprintf("%s: %s %s", name, soft, hard))
+	}
+	return strings.Join(limits, "; "), nil
 }
 
-// KillContainers removes containers based on ID
-func (r *Porto) KillContainers(ids []string) error {
-	return killCRIContainers(r.Runner, ids)
+// AllowedUnsafeSysctls returns the unsafe sysctls pods may set: those of --extra-config=porto.allowed-unsafe-sysctls
+// and kubelet.allowed-unsafe-sysctls together, so that portod and kubelet allow the same ones whichever is set
+func AllowedUnsafeSysctls(opts config.ExtraOptionSlice) []string {
+	seen := map[string]bool{}
+	sysctls := []string{}
+	for _, component := range []string{constants.Porto, "kubelet"} {
+		for _, s := range strings.Split(opts.Get(PortoAllowedUnsafeSysctlsOption, component), ",") {
+			s = strings.TrimSpace(s)
+			if s == "" || seen[s] {
+				continue
+			}
+			seen[s] = true
+			sysctls = append(sysctls, s)
+		}
+	}
+	sort.Strings(sysctls)
+	return sysctls
 }
 
-// StopContainers stops containers based on ID
-func (r *Porto) StopContainers(ids []string) error {
-	return stopCRIContainers(r.Runner, ids)
+// generateLimitsConfig writes the portod configuration of the default ulimits and the allowed sysctls,
+// removing it again when neither is set. Enable restarts portod afterwards.
+func (r *Porto) generateLimitsConfig() error {
+	var sb strings.Builder
+	if r.Ulimits != "" || len(r.AllowedUnsafeSysctls) > 0 {
+		sb.WriteString("container {\n")
+		if r.Ulimits != "" {
+			fmt.Fprintf(&sb, "  default_ulimit: %q\n", r.Ulimits)
+		}
+		if len(r.AllowedUnsafeSysctls) > 0 {
+			fmt.Fprintf(&sb, "  allowed_sysctls: %q\n", strings.Join(r.AllowedUnsafeSysctls, ";"))
+		}
+		sb.WriteString("}\n")
+	}
+	if sb.Len() == 0 {
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-f", portoLimitsConf)); err != nil {
+			return errors.Wrapf(err, "failed to remove %q", portoLimitsConf)
+		}
+		return nil
+	}
+
+	return writePortoConfig(r.Runner, portoLimitsConf, sb.String())
 }
 
-// ContainerLogCmd returns the command to retrieve the log for a container based on ID
-func (r *Porto) ContainerLogCmd(id string, len int, follow bool) string {
-	return criContainerLogCmd(r.Runner, id, len, follow)
+// generatePortoConfig writes the portod configuration of the enabled runtime feature gates and of the
+// directory of registry certificates, removing it again when there is neither. The fields the portod of the node
+// does not know are left out, rather than failing its restart, which Enable does afterwards.
+func generatePortoConfig(cr CommandRunner, featureGates FeatureGates, registryCertsDir string) error {
+	var sb strings.Builder
+	for _, name := range KnownFeatureGates() {
+		if !featureGates.Enabled(name) {
+			continue
+		}
+		klog.Infof("enabling runtime feature gate %s", name)
+		sb.WriteString(portoFeatureConfig[name])
+	}
+	if registryCertsDir != "" {
+		klog.Infof("reading registry certificates from %s", registryCertsDir)
+		sb.WriteString(portoRegistryCertsConfig(registryCertsDir))
+	}
+	if sb.Len() == 0 {
+		if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-f", portoFeaturesConf)); err != nil {
+			return errors.Wrapf(err, "failed to remove %q", portoFeaturesConf)
+		}
+		return nil
+	}
+
+	return writePortoConfig(cr, portoFeaturesConf, sb.String())
 }
 
-// SystemLogCmd returns the command to retrieve system logs
-func (r *Porto) SystemLogCmd(len int) string {
-	return fmt.Sprintf("sudo tail -n %d /var/log/portod.log", len)
+// Enable idempotently enables porto on a host
+func (r *Porto) Enable(ctx context.Context, disOthers bool, cgroupDriver string, inUserNamespace bool) error {
+	b := r.bind(ctx)
+	err := b.enable(disOthers, inUserNamespace)
+	r.change = b.change
+	return err
 }
 
-// Preload preloads the container runtime with k8s images
-func (r *Porto) Preload(cc config.ClusterConfig) error {
-	k8sVersion := cc.KubernetesConfig.KubernetesVersion
-	imageList, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
+// enable does the work of Enable
+func (r *Porto) enable(disOthers bool, inUserNamespace bool) error {
+	// fail early instead of waiting for kubeadm to time out on a kernel porto can't run on
+	if err := CheckPortoKernel(r.Runner); err != nil {
+		return err
+	}
+	if inUserNamespace {
+		if err := CheckKernelCompatibility(r.Runner, 5, 11); err != nil {
+			// For using overlayfs
+			return fmt.Errorf("kernel >= 5.11 is required for rootless mode: %w", err)
+		}
+		if err := CheckKernelCompatibility(r.Runner, 5, 13); err != nil {
+			// For avoiding SELinux error with overlayfs
+			klog.Warningf("kernel >= 5.13 is recommended for rootless mode %v", err)
+		}
+	}
+	// portod must not start on a store of another format, which it does when Enable restarts it
+	migrated, err := r.migrateStore()
 	if err != nil {
-		return errors.Wrap(err, "getting images")
+		return err
 	}
-	if portoImagesPreloaded(r.Runner, imageList) {
-		klog.Info("Images already preloaded, skipping extraction")
-		return nil
+	// the images of the previous start don't count towards the summary of this one
+	resetImageSources(r.Runner)
+	before, err := portoConfigDigests(r.Runner)
+	if err != nil {
+		return err
+	}
+	if err := r.configureCgroupNamespaces(inUserNamespace); err != nil {
+		return err
 	}
-	for _, img := range imageList {
-		if err := r.PullImage(img); err != nil {
-			return errors.Wrapf(err, "pulling image %q", img)
+	if disOthers {
+		if err := disableOthers(r, r.Runner); err != nil {
+			klog.Warningf("disableOthers: %v", err)
 		}
 	}
-	return r.Restart()
-}
+	if err := populateCRIConfigChecked(r.Runner, r.SocketPath(), r.Debug); err != nil {
+		return err
+	}
 
-// Restart restarts this container runtime on a host
-func (r *Porto) Restart() error {
-	return r.Init.Restart("porto")
+	if err := generatePortoConfig(r.Runner, r.FeatureGates, r.RegistryCertsDir); err != nil {
+		return err
+	}
+	if err := r.generateLimitsConfig(); err != nil {
+		return err
+	}
+	if err := r.configureDownloadTmpfs(); err != nil {
+		return err
+	}
+	if err := r.configureBuildCache(); err != nil {
+		return err
+	}
+	if err := r.configureSocketGroup(); err != nil {
+		return err
+	}
+	if err := enableIPForwarding(r.Runner); err != nil {
+		return err
+	}
+	if err := r.configurePortoshim(); err != nil {
+		return err
+	}
+	if err := r.configureDebug(); err != nil {
+		return err
+	}
+	after, err := portoConfigDigests(r.Runner)
+	if err != nil {
+		return err
+	}
+	r.change = ConfigChange{Files: changedFiles(before, after)}
+	if err := r.restartServices(); err != nil {
+		return err
+	}
+
+	if err := r.verifySockets(); err != nil {
+		return err
+	}
+	if migrated {
+		removeStoreBackup(r.Runner)
+	}
+	if r.FeatureGates.Enabled(FeatureKubeletMetaContainer) {
+		if err := r.ensureMetaContainer(); err != nil {
+			return errors.Wrap(err, "meta-container")
+		}
+	}
+
+	// portoshim does not pull the sandbox image on its own
+	if err := r.ensureSandboxImage(); err != nil {
+		return errors.Wrap(err, "pause image")
+	}
+
+	return nil
 }
 
-// portoImagesPreloaded returns true if all images have been preloaded
-func portoImagesPreloaded(runner command.Runner, images []string) bool {
-	rr, err := runner.RunCmd(exec.Command("sudo", "crictl", "images", "--output", "json"))
+// portoConfigDirs hold the configuration of portod and portoshim, of their own and of their systemd units
+var portoConfigDirs = []string{portoConfDir, "/etc/systemd/system/porto.service.d", "/etc/systemd/system/portoshim.service.d"}
+
+// portoKubeletConfig are the configuration files which change what kubelet sees of the runtime:
+// the CRI endpoint portoshim serves, and the cgroups of pod containers
+var portoKubeletConfig = []string{portoshimDropIn, portoCgroupNSConf}
+
+// portoConfigDigests returns the sha256 digests of the files in portoConfigDirs by path
+func portoConfigDigests(cr CommandRunner) (map[string]string, error) {
+	// the directories don't exist until Enable first writes to them
+	c := fmt.Sprintf("find %s -type f -exec sha256sum {} + 2>/dev/null; true", strings.Join(portoConfigDirs, " "))
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c))
 	if err != nil {
-		return false
+		return nil, errors.Wrap(err, "porto configuration digests")
 	}
+	digests := map[string]string{}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		if sum, file, ok := strings.Cut(strings.TrimSpace(line), "  "); ok {
+			digests[file] = sum
+		}
+	}
+	return digests, nil
+}
 
-	var jsonImages crictlImages
-	err = json.Unmarshal(rr.Stdout.Bytes(), &jsonImages)
-	if err != nil {
-		klog.Errorf("failed to unmarshal images, will assume images are not preloaded")
-		return false
+// changedFiles returns the files created, changed or removed between the digests before and after, sorted
+func changedFiles(before, after map[string]string) []string {
+	var files []string
+	for f, sum := range after {
+		if before[f] != sum {
+			files = append(files, f)
+		}
+	}
+	for f := range before {
+		if _, ok := after[f]; !ok {
+			files = append(files, f)
+		}
 	}
+	sort.Strings(files)
+	return files
+}
 
-	// Make sure images == imgs
-	for _, i := range images {
-		found := false
-		for _, ji := range jsonImages.Images {
-			for _, rt := range ji.RepoTags {
-				i = addRepoTagToImageName(i)
-				if i == rt {
-					found = true
-					break
-				}
-			}
-			if found {
-				break
+// touches returns whether change includes a file in one of dirs
+func (c ConfigChange) touches(dirs ...string) bool {
+	for _, f := range c.Files {
+		for _, d := range dirs {
+			if filepath.Dir(f) == d {
+				return true
 			}
+		}
+	}
+	return false
+}
 
+// restartServices restarts portod and portoshim when Enable changed their configuration, or when they are not running.
+// Restarts are skipped otherwise, as kubelet in PortoMetaContainer requires porto.service and systemd restarts it along with portod.
+func (r *Porto) restartServices() error {
+	if r.change.touches(portoConfigDirs[0], portoConfigDirs[1]) || !r.Init.Active("porto") {
+		if err := r.Init.Restart("porto"); err != nil {
+			return err
 		}
-		if !found {
-			klog.Infof("couldn't find preloaded image for %q. assuming images are not preloaded.", i)
-			return false
+		r.change.Restarted = true
+	}
+	// portoshim is not enabled by default on every base image, so start it along with portod
+	if r.change.Restarted || r.change.touches(portoConfigDirs[2]) || !r.Init.Active("portoshim") {
+		if err := r.Init.Restart("portoshim"); err != nil {
+			return err
 		}
+		r.change.Restarted = true
 	}
-	klog.Infof("all images are preloaded for porto runtime.")
-	return true
+	if !r.change.Restarted {
+		klog.Infof("porto configuration is unchanged, not restarting portod and portoshim")
+	}
+	return nil
 }
 
-// ImagesPreloaded returns true if all images have been preloaded
-func (r *Porto) ImagesPreloaded(images []string) bool {
-	return portoImagesPreloaded(r.Runner, images)
+func (r *Porto) appliedChange() ConfigChange {
+	return r.change
+}
+
+// ShouldRestartKubelet returns whether change touched the porto configuration kubelet reads
+func (r *Porto) ShouldRestartKubelet(change ConfigChange) bool {
+	for _, f := range change.Files {
+		for _, k := range portoKubeletConfig {
+			if f == k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySockets checks that portoctl reaches portod on PortodSocketPath and crictl reaches portoshim on SocketPath.
+// Image operations go through both, so when only one of them can reach its socket, because of its path or its
+// permissions, ImageExists and ListImages silently disagree; Enable fails naming the socket at fault instead.
+func (r *Porto) verifySockets() error {
+	endpoints := []struct {
+		service string
+		socket  string
+		tool    string
+		reach   func() error
+	}{
+		{"portod", r.PortodSocketPath(), "portoctl", func() error {
+			_, err := r.Runner.RunCmd(r.portoctl("list"))
+			return err
+		}},
+		{"portoshim", r.SocketPath(), "crictl", func() error {
+			_, err := getCRIVersion(r.Runner, r.SocketPath())
+			return err
+		}},
+	}
+	for _, e := range endpoints {
+		// the services were just restarted, give them a moment to create their sockets
+		exists := func() error {
+			if _, err := r.Runner.RunCmd(exec.Command("sudo", "test", "-S", e.socket)); err != nil {
+				return &retry.RetriableError{Err: err}
+			}
+			return nil
+		}
+		if err := retry.Expo(exists, 100*time.Millisecond, portoSocketWait); err != nil {
+			return errors.Wrapf(err, "%s socket %s", e.service, e.socket)
+		}
+		if err := e.reach(); err != nil {
+			return errors.Wrapf(err, "%s can't reach %s on %s", e.tool, e.service, e.socket)
+		}
+	}
+	return nil
+}
+
+// sandboxImage returns the pause image of the cluster
+func (r *Porto) sandboxImage() string {
+	return PortoSandboxImage(r.KubernetesVersion, r.ImageRepository)
+}
+
+// ensureSandboxImage makes sure the pause image portoshim runs pod sandboxes with is present.
+// It is loaded from the host image cache when it is there, so that air-gapped starts do not touch the network.
+func (r *Porto) ensureSandboxImage() error {
+	img := r.sandboxImage()
+	if r.ImageExists(img, "") {
+		return nil
+	}
+	cached := localpath.SanitizeCacheDir(filepath.Join(detect.ImageCacheDir(), img))
+	if _, err := os.Stat(cached); err != nil {
+		klog.Infof("%s is not in the image cache (%v), pulling it", img, err)
+		if err := r.PullImage(contextOf(r.Runner), img); err != nil {
+			RecordImageSources(r.Runner, ImageFailed, img)
+			return err
+		}
+		RecordImageSources(r.Runner, ImagePulled, img)
+		return nil
+	}
+
+	klog.Infof("loading %s from the image cache at %s", img, cached)
+	fa, err := assets.NewFileAsset(cached, path.Join(vmpath.GuestPersistentDir, "images"), filepath.Base(cached), "0644")
+	if err != nil {
+		return errors.Wrap(err, "getting file asset")
+	}
+	defer func() {
+		if err := fa.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", fa.GetSourcePath(), err)
+		}
+	}()
+	if err := r.Runner.Copy(fa); err != nil {
+		return errors.Wrap(err, "copying cached image")
+	}
+	defer func() {
+		if err := r.Runner.Remove(fa); err != nil {
+			klog.Warningf("unable to remove %s: %v", fa.GetTargetPath(), err)
+		}
+	}()
+	return r.LoadImage(contextOf(r.Runner), fa.GetTargetPath())
+}
+
+// portoDebugConfigs are the configuration files raising the log levels of portod and portoshim, by path
+var portoDebugConfigs = map[string]string{
+	"/etc/portod.conf.d/99-minikube-debug.conf": `log {
+  verbose: true
+  debug: true
+}
+`,
+	"/etc/systemd/system/portoshim.service.d/10-debug.conf": `[Service]
+ExecStart=
+ExecStart=/usr/sbin/portoshim -debug
+`,
+}
+
+// configureDebug writes portoDebugConfigs when Debug is set, so that portod and portoshim log at debug level.
+// They are removed again otherwise, so that turning --runtime-debug off takes effect.
+// Enable restarts both services afterwards.
+func (r *Porto) configureDebug() error {
+	if !r.Debug {
+		for target := range portoDebugConfigs {
+			if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-f", target)); err != nil {
+				return errors.Wrapf(err, "failed to remove %q", target)
+			}
+		}
+		return nil
+	}
+	for target, content := range portoDebugConfigs {
+		if filepath.Dir(target) == portoConfDir {
+			if err := writePortoConfig(r.Runner, target, content); err != nil {
+				return err
+			}
+			continue
+		}
+		targetDir := filepath.Dir(target)
+		c := exec.Command("sudo", "mkdir", "-p", targetDir)
+		if _, err := r.Runner.RunCmd(c); err != nil {
+			return errors.Wrapf(err, "failed to create directory %q", targetDir)
+		}
+		asset := assets.NewMemoryAssetTarget([]byte(content), target, "0644")
+		err := r.Runner.Copy(asset)
+		asset.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %q", target)
+		}
+	}
+	return nil
+}
+
+const (
+	// PortoStreamingPort is the port portoshim serves exec, attach and port-forward streams on
+	PortoStreamingPort = 10010
+	// portoStreamingAddress is the address portoshim serves streams on. kubelet proxies the streams of the
+	// API server to the URL portoshim returns, so it has to be reachable from the node, and only from the node.
+	portoStreamingAddress = "127.0.0.1"
+	// portoshimDropIn is the systemd drop-in configuring portoshim for the cluster
+	portoshimDropIn = "/etc/systemd/system/portoshim.service.d/20-minikube.conf"
+)
+
+const (
+	// PortoMetaContainer is the porto meta-container kubelet runs under with FeatureKubeletMetaContainer. It has no command
+	// of its own, and holds the resource guarantees and the accounting of the node components, apart from the pods.
+	PortoMetaContainer = "minikube"
+	// PortoKubeletContainer is the porto container kubelet runs in with FeatureKubeletMetaContainer
+	PortoKubeletContainer = PortoMetaContainer + "/kubelet"
+	// PortoKubeletExec is the command running kubelet in PortoKubeletContainer, followed by a command= property.
+	// portoctl exec stays in the foreground and destroys the container when it exits, so systemd supervises it as it
+	// would supervise kubelet. virt_mode=host and isolate=false leave kubelet the namespaces and privileges of the host.
+	PortoKubeletExec = "portoctl exec " + PortoKubeletContainer + " virt_mode=host isolate=false"
+)
+
+// portoMetaContainerProperties are the resource guarantees of PortoMetaContainer, so that pods can't starve kubelet
+var portoMetaContainerProperties = [][2]string{
+	{"memory_guarantee", "512M"},
+	{"cpu_guarantee", "0.1c"},
+}
+
+// ensureMetaContainer creates and starts PortoMetaContainer, unless portod has it already
+func (r *Porto) ensureMetaContainer() error {
+	if _, err := r.Runner.RunCmd(r.portoctl("get", PortoMetaContainer, "state")); err == nil {
+		return nil
+	}
+	klog.Infof("creating porto meta-container %s", PortoMetaContainer)
+	if _, err := r.Runner.RunCmd(r.portoctl("create", PortoMetaContainer)); err != nil {
+		return errors.Wrapf(err, "portoctl create %s", PortoMetaContainer)
+	}
+	for _, p := range portoMetaContainerProperties {
+		if _, err := r.Runner.RunCmd(r.portoctl("set", PortoMetaContainer, p[0], p[1])); err != nil {
+			return errors.Wrapf(err, "portoctl set %s %s", PortoMetaContainer, p[0])
+		}
+	}
+	// without a command, the container is a meta-container which only groups its children
+	if _, err := r.Runner.RunCmd(r.portoctl("start", PortoMetaContainer)); err != nil {
+		return errors.Wrapf(err, "portoctl start %s", PortoMetaContainer)
+	}
+	return nil
+}
+
+// PortoIsolation is an isolation level of porto, which pods select with the RuntimeClass of the same name as its handler
+type PortoIsolation struct {
+	// Handler is the name of the portoshim runtime handler, and of the RuntimeClass mapped to it
+	Handler string
+	// Properties are the porto properties portoshim sets on the containers of pods running with the handler
+	Properties string
+}
+
+// PortoIsolations are the isolation levels portoshim offers as runtime handlers
+var PortoIsolations = []PortoIsolation{
+	// an application container, the isolation of pods without a runtimeClassName
+	{Handler: "porto-container", Properties: "virt_mode=app;isolate=true"},
+	// a system container running its own init and users, closer to a virtual machine
+	{Handler: "porto-vm", Properties: "virt_mode=os;isolate=true"},
+}
+
+// portoshimRuntimeHandlers returns the runtime handlers of PortoIsolations in the format of PORTOSHIM_RUNTIME_HANDLERS,
+// such as porto-container:virt_mode=app;isolate=true,porto-vm:virt_mode=os;isolate=true
+func portoshimRuntimeHandlers() string {
+	handlers := []string{}
+	for _, i := range PortoIsolations {
+		handlers = append(handlers, i.Handler+":"+i.Properties)
+	}
+	return strings.Join(handlers, ",")
+}
+
+// PortoSandboxImage returns the pause image portoshim runs pod sandboxes with,
+// which is the one kubeadm expects for Kubernetes version kv.
+func PortoSandboxImage(kv semver.Version, imageRepository string) string {
+	return images.Pause(kv, imageRepository)
+}
+
+// portoshimConfig returns the systemd drop-in configuring portoshim:
+//
+// 1. The streaming server is pinned to portoStreamingAddress. Left alone, portoshim listens on a random port
+// of the node address and returns URLs kubelet can't always connect to, which breaks kubectl exec and
+// the exec terminal of the dashboard.
+//
+// 2. Pod sandboxes run sandboxImage, rather than the pause image built into portoshim, so that there is
+// only the one pause image kubeadm pulls.
+//
+// 3. The runtime handlers of PortoIsolations are declared, so that the RuntimeClasses the bootstrapper creates
+// for them resolve to an isolation level rather than failing the sandbox creation of their pods.
+func portoshimConfig(sandboxImage string) string {
+	return fmt.Sprintf(`[Service]
+Environment=PORTOSHIM_STREAMING_ADDRESS=%s
+Environment=PORTOSHIM_STREAMING_PORT=%d
+Environment=PORTOSHIM_SANDBOX_IMAGE=%s
+Environment="PORTOSHIM_RUNTIME_HANDLERS=%s"
+`, portoStreamingAddress, PortoStreamingPort, sandboxImage, portoshimRuntimeHandlers())
+}
+
+// configurePortoshim writes portoshimDropIn. Enable restarts portoshim afterwards.
+func (r *Porto) configurePortoshim() error {
+	targetDir := filepath.Dir(portoshimDropIn)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", targetDir)); err != nil {
+		return errors.Wrapf(err, "failed to create directory %q", targetDir)
+	}
+	asset := assets.NewMemoryAssetTarget([]byte(portoshimConfig(r.sandboxImage())), portoshimDropIn, "0644")
+	defer asset.Close()
+	if err := r.Runner.Copy(asset); err != nil {
+		return errors.Wrapf(err, "failed to create %q", portoshimDropIn)
+	}
+	return nil
+}
+
+const (
+	// PortoDownloadTmpfsOption is the --extra-config=porto.<option> which puts the layer download directory on a tmpfs
+	PortoDownloadTmpfsOption = "download-tmpfs"
+	// portoDownloadDir is the temporary directory portod downloads and unpacks image layers to when it is on a tmpfs
+	portoDownloadDir = "/run/porto-download"
+	// portoDownloadTmpfsDropIn is the systemd drop-in mounting portoDownloadDir before portod starts
+	portoDownloadTmpfsDropIn = "/etc/systemd/system/porto.service.d/20-download-tmpfs.conf"
+)
+
+// DownloadTmpfsSize returns the size in MiB of the tmpfs for porto layer downloads requested by value,
+// the setting of --extra-config=porto.download-tmpfs. "true" sizes it to a quarter of memoryMB,
+// anything else but "false" is read as a size such as "2g". 0 means no tmpfs.
+func DownloadTmpfsSize(value string, memoryMB int) (int, error) {
+	switch strings.ToLower(value) {
+	case "", "false":
+		return 0, nil
+	case "true":
+		return memoryMB / 4, nil
+	}
+	size, err := util.CalculateSizeInMB(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s size %q", PortoDownloadTmpfsOption, value)
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid %s size %q: must be positive", PortoDownloadTmpfsOption, value)
+	}
+	return size, nil
+}
+
+// configureDownloadTmpfs mounts a tmpfs of DownloadTmpfsMB for portod to download and unpack layers to,
+// as unpacking rather than the network dominates pull times on fast networks.
+// The drop-in is removed again when DownloadTmpfsMB is 0, so that the setting can be turned off.
+// Enable restarts portod afterwards.
+func (r *Porto) configureDownloadTmpfs() error {
+	if r.DownloadTmpfsMB <= 0 {
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-f", portoDownloadTmpfsDropIn)); err != nil {
+			return errors.Wrapf(err, "failed to remove %q", portoDownloadTmpfsDropIn)
+		}
+		return nil
+	}
+
+	klog.Infof("keeping porto layer downloads on a %dMiB tmpfs at %s", r.DownloadTmpfsMB, portoDownloadDir)
+	content := fmt.Sprintf(`[Service]
+Environment=TMPDIR=%[1]s
+ExecStartPre=/bin/mkdir -p %[1]s
+ExecStartPre=/bin/sh -c 'mountpoint -q %[1]s || mount -t tmpfs -o size=%[2]dm,mode=0700 tmpfs %[1]s'
+`, portoDownloadDir, r.DownloadTmpfsMB)
+	targetDir := filepath.Dir(portoDownloadTmpfsDropIn)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", targetDir)); err != nil {
+		return errors.Wrapf(err, "failed to create directory %q", targetDir)
+	}
+	asset := assets.NewMemoryAssetTarget([]byte(content), portoDownloadTmpfsDropIn, "0644")
+	defer asset.Close()
+	if err := r.Runner.Copy(asset); err != nil {
+		return errors.Wrapf(err, "failed to create %q", portoDownloadTmpfsDropIn)
+	}
+	// a tmpfs mounted by a previous start may have a different size
+	c := fmt.Sprintf("if mountpoint -q %[1]s; then mount -o remount,size=%[2]dm %[1]s; fi", portoDownloadDir, r.DownloadTmpfsMB)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrap(err, "resizing the download tmpfs")
+	}
+	return nil
+}
+
+const (
+	// PortoBuildCacheOption is the --extra-config=porto.<option> limiting the size of the layer cache of image builds
+	PortoBuildCacheOption = "build-cache-size"
+	// DefaultBuildCacheMB is the size the layer cache of image builds is kept under unless PortoBuildCacheOption says otherwise
+	DefaultBuildCacheMB = 10240
+)
+
+// BuildCacheSize returns the size in MiB the layer cache of image builds is kept under for the value of PortoBuildCacheOption:
+// DefaultBuildCacheMB when empty, 0 for "false" or "0" to disable the cache, or a size such as 20g.
+func BuildCacheSize(value string) (int, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return DefaultBuildCacheMB, nil
+	case "false", "0":
+		return 0, nil
+	}
+	size, err := util.CalculateSizeInMB(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s size %q", PortoBuildCacheOption, value)
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid %s size %q: must be positive", PortoBuildCacheOption, value)
+	}
+	return size, nil
+}
+
+// configureBuildCache creates the directory image builds cache their layers in. It is on the persistent disk of the node,
+// so that builds reuse layers across restarts until the node is deleted. When BuildCacheMB is 0 it is removed instead, to free the disk.
+func (r *Porto) configureBuildCache() error {
+	if r.BuildCacheMB <= 0 {
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-rf", vmpath.GuestBuildCacheDir)); err != nil {
+			return errors.Wrapf(err, "failed to remove %q", vmpath.GuestBuildCacheDir)
+		}
+		return nil
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", vmpath.GuestBuildCacheDir)); err != nil {
+		return errors.Wrapf(err, "failed to create %q", vmpath.GuestBuildCacheDir)
+	}
+	return nil
+}
+
+const (
+	// PortoRegistryCertsOption is the --extra-config=porto.<option> naming the directory of the node portod reads
+	// registry certificates from, in the certs.d layout of docker: one directory per registry host holding its
+	// ca.crt, client.cert and client.key. "true" names the target directory of --mount-string.
+	PortoRegistryCertsOption = "registry-certs-dir"
+)
+
+// RegistryCertsDir returns the directory of the node registry certificates are read from for value, the setting of
+// PortoRegistryCertsOption, with mountString the --mount-string of the cluster. Empty means the system certificates only.
+func RegistryCertsDir(value, mountString string) (string, error) {
+	switch value {
+	case "", "false":
+		return "", nil
+	case "true":
+		idx := strings.LastIndex(mountString, ":")
+		if idx == -1 || mountString[idx+1:] == "" {
+			return "", fmt.Errorf("%s=true takes the target directory of --mount-string, which is not set", PortoRegistryCertsOption)
+		}
+		value = mountString[idx+1:]
+	}
+	if !path.IsAbs(value) {
+		return "", fmt.Errorf("invalid %s value %q: must be true, false or an absolute path of the node", PortoRegistryCertsOption, value)
+	}
+	return path.Clean(value), nil
+}
+
+// portoRegistryCertsConfig returns the portod configuration reading registry certificates from dir. portod looks the
+// certificates of a registry up on every pull, so rotating them in a mounted directory takes effect without a restart.
+func portoRegistryCertsConfig(dir string) string {
+	return fmt.Sprintf(`images {
+  registry_certs_dir: %q
+}
+`, dir)
+}
+
+const (
+	// PortoNonRootSocketOption is the --extra-config=porto.<option> giving the user of the node access to the porto sockets without sudo
+	PortoNonRootSocketOption = "non-root-socket"
+	// portoSocketGroup is the group given access to the portod and portoshim sockets
+	portoSocketGroup = "porto"
+)
+
+// NonRootSocket returns whether the value of PortoNonRootSocketOption asks for access to the porto sockets without sudo
+func NonRootSocket(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q: must be true or false", PortoNonRootSocketOption, value)
+	}
+	return enabled, nil
+}
+
+// portoSocketGroupDropIns are the systemd drop-ins handing the socket of each porto service to portoSocketGroup once it is up
+var portoSocketGroupDropIns = []struct {
+	path   string
+	socket string
+}{
+	{"/etc/systemd/system/porto.service.d/30-socket-group.conf", portodSocket},
+	{"/etc/systemd/system/portoshim.service.d/30-socket-group.conf", "/run/portoshim.sock"},
+}
+
+// configureSocketGroup adds SocketUser to portoSocketGroup, creating the group where the node lacks it, and writes
+// portoSocketGroupDropIns, so that host tooling and the docker API shim talk to porto without sudo. The services recreate
+// their sockets on every start, so the drop-ins rather than a one-off chown keep them accessible.
+// When SocketUser is empty the drop-ins are removed and the sockets go back to root.
+func (r *Porto) configureSocketGroup() error {
+	if r.SocketUser == "" {
+		for _, d := range portoSocketGroupDropIns {
+			if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-f", d.path)); err != nil {
+				return errors.Wrapf(err, "failed to remove %q", d.path)
+			}
+		}
+		return nil
+	}
+
+	klog.Infof("giving %s access to the porto sockets through the %s group", r.SocketUser, portoSocketGroup)
+	c := fmt.Sprintf("getent group %[1]s >/dev/null || groupadd --system %[1]s", portoSocketGroup)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrapf(err, "creating the %s group", portoSocketGroup)
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "usermod", "-aG", portoSocketGroup, r.SocketUser)); err != nil {
+		return errors.Wrapf(err, "adding %s to the %s group", r.SocketUser, portoSocketGroup)
+	}
+	for _, d := range portoSocketGroupDropIns {
+		content := fmt.Sprintf(`[Service]
+ExecStartPost=/bin/sh -c 'for i in $(seq 1 100); do [ -S %[1]s ] && break; sleep 0.1; done; chgrp %[2]s %[1]s && chmod 0660 %[1]s'
+`, d.socket, portoSocketGroup)
+		targetDir := filepath.Dir(d.path)
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", targetDir)); err != nil {
+			return errors.Wrapf(err, "failed to create directory %q", targetDir)
+		}
+		asset := assets.NewMemoryAssetTarget([]byte(content), d.path, "0644")
+		err := r.Runner.Copy(asset)
+		asset.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %q", d.path)
+		}
+	}
+	return nil
+}
+
+// buildCacheArgs returns the buildctl arguments which export the layers of a build to the build cache,
+// and import those of previous builds unless opts, the --build-opt of "minikube image build", include no-cache.
+// Nothing is cached when BuildCacheMB is 0.
+func (r *Porto) buildCacheArgs(opts []string) []string {
+	if r.BuildCacheMB <= 0 {
+		return nil
+	}
+	args := []string{"--export-cache", fmt.Sprintf("type=local,dest=%s,mode=max", vmpath.GuestBuildCacheDir)}
+	for _, opt := range opts {
+		if opt == "no-cache" || opt == "no-cache=true" {
+			// the layers of this build still refresh the cache
+			return args
+		}
+	}
+	return append(args, "--import-cache", fmt.Sprintf("type=local,src=%s", vmpath.GuestBuildCacheDir))
+}
+
+// pruneBuildCache empties the build cache once it outgrows BuildCacheMB. The local cache of BuildKit keeps the layers
+// of earlier builds forever, so it is reset rather than trimmed, and the next build fills it again.
+func (r *Porto) pruneBuildCache() error {
+	if r.BuildCacheMB <= 0 {
+		return nil
+	}
+	rr, err := r.Runner.RunCmd(exec.Command("sudo", "du", "-sm", vmpath.GuestBuildCacheDir))
+	if err != nil {
+		return errors.Wrap(err, "build cache size")
+	}
+	fields := strings.Fields(rr.Stdout.String())
+	if len(fields) == 0 {
+		return fmt.Errorf("unexpected du output: %q", rr.Stdout.String())
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return errors.Wrapf(err, "parsing du output %q", rr.Stdout.String())
+	}
+	if size <= r.BuildCacheMB {
+		return nil
+	}
+	klog.Infof("build cache is %dMiB, over its %dMiB limit, emptying it", size, r.BuildCacheMB)
+	c := fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", vmpath.GuestBuildCacheDir)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrap(err, "emptying the build cache")
+	}
+	return nil
+}
+
+// Disable idempotently disables porto on a host
+func (r *Porto) Disable(ctx context.Context) error {
+	r = r.bind(ctx)
+	return r.Init.ForceStop("porto")
+}
+
+// ImageExists checks if image exists based on image name and optionally image sha
+func (r *Porto) ImageExists(name string, sha string) bool {
+	return imageExists(r, name, sha)
+}
+
+// ImageDigest returns the ID of an image based on name, asking portoshim for its CRI image status,
+// or looking it up in the images of portoctl where the runner can't reach the portoshim socket
+func (r *Porto) ImageDigest(name string) (string, error) {
+	if c, ok := criClientFor(r.Runner, r.SocketPath()); ok {
+		id, err := c.ImageID(name)
+		if err == nil || errors.Is(err, ErrImageNotFound) {
+			return id, err
+		}
+		klog.Infof("falling back to portoctl: %v", err)
+	}
+
+	images, err := r.portoctlImages()
+	if err != nil {
+		return "", errors.Wrap(err, "listing porto images")
+	}
+	for _, img := range images {
+		if img.Matches(name) {
+			return img.ID, nil
+		}
+	}
+	return "", errors.Wrap(ErrImageNotFound, name)
+}
+
+// ListImages lists images managed by this container runtime
+func (r *Porto) ListImages(ListImagesOptions) ([]ListImage, error) {
+	if api, err := r.portoAPI(); err == nil {
+		defer api.Close()
+		return api.ListImages()
+	}
+	images, err := r.portoctlImages()
+	if err != nil {
+		klog.Infof("falling back to crictl: %v", err)
+		return listCRIImages(r.Runner, r.SocketPath())
+	}
+	return portoListImages(images), nil
+}
+
+// LoadImage loads an image into this runtime
+func (r *Porto) LoadImage(ctx context.Context, path string) error {
+	r = r.bind(ctx)
+	klog.Infof("Loading image: %s", path)
+	c := r.portoctl("docker-load", path)
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrapf(err, "portoctl docker-load")
+	}
+	return r.retagLoadedImage(path)
+}
+
+// dockerArchiveManifest is an entry of the manifest.json of a docker-archive tarball
+type dockerArchiveManifest struct {
+	Config   string
+	RepoTags []string
+}
+
+// LoadImageStream loads an image from a stream in the docker save format, such as the output of "docker save",
+// without staging it as a tarball in the guest first, and restores the tags recorded in its manifest.json like
+// LoadImage does, reading the manifest from a copy of the stream as it is loaded.
+func (r *Porto) LoadImageStream(name string, stream io.Reader) error {
+	klog.Infof("Loading image %s from a stream", name)
+	pr, pw := io.Pipe()
+	type manifestResult struct {
+		manifest []byte
+		err      error
+	}
+	read := make(chan manifestResult, 1)
+	go func() {
+		manifest, err := readArchiveManifest(pr)
+		// drains the rest of the copy so that it never blocks the load
+		_, _ = io.Copy(io.Discard, pr)
+		read <- manifestResult{manifest, err}
+	}()
+
+	c := r.portoctl("docker-load", "/dev/stdin")
+	c.Stdin = io.TeeReader(stream, pw)
+	_, err := r.Runner.RunCmd(c)
+	pw.Close()
+	res := <-read
+	if err != nil {
+		return errors.Wrapf(err, "portoctl docker-load %s", name)
+	}
+	if res.err != nil {
+		klog.Warningf("unable to read the manifest of %s, not retagging: %v", name, res.err)
+		return nil
+	}
+	return r.retagArchive(name, res.manifest)
+}
+
+// LoadImageArchive loads a docker-archive tarball on the host, streaming it to portoctl docker-load instead of copying
+// it into the guest first, and restores the tags recorded in it like LoadImage does. The stream is read through wrap,
+// if not nil, which lets callers follow the transfer.
+func (r *Porto) LoadImageArchive(ctx context.Context, archive string, wrap func(io.Reader) io.Reader) error {
+	r = r.bind(ctx)
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var stream io.Reader = f
+	if wrap != nil {
+		stream = wrap(f)
+	}
+	return r.LoadImageStream(archive, stream)
+}
+
+// readArchiveManifest reads the manifest.json of a docker-archive stream, which may be gzipped
+func readArchiveManifest(stream io.Reader) ([]byte, error) {
+	br := bufio.NewReader(stream)
+	stream = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		stream = gz
+	}
+	tr := tar.NewReader(stream)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no manifest.json")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Clean(h.Name) == "manifest.json" {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// LoadImageFromLayout loads the images of an OCI image layout directory on the host, streaming them to portoctl
+// docker-load as a docker-archive, and restores the tags docker-load did not keep.
+func (r *Porto) LoadImageFromLayout(ctx context.Context, dir string) error {
+	r = r.bind(ctx)
+	images, err := ociLayoutImages(dir)
+	if err != nil {
+		return err
+	}
+	if err := loadDockerArchive(images, func(stream io.Reader) error { return r.LoadImageStream(dir, stream) }); err != nil {
+		return err
+	}
+	for ref, img := range images {
+		tag, ok := ref.(name.Tag)
+		if !ok {
+			continue
+		}
+		id, err := img.ConfigName()
+		if err != nil {
+			return errors.Wrapf(err, "getting the ID of %s", ref)
+		}
+		if err := r.retagImage(id.Hex, tag.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retagLoadedImage restores the tags recorded in a docker-archive tarball, which portoctl docker-load does not always keep
+func (r *Porto) retagLoadedImage(tarball string) error {
+	rr, err := r.Runner.RunCmd(exec.Command("sudo", "tar", "-xOf", tarball, "manifest.json"))
+	if err != nil {
+		klog.Warningf("unable to read the manifest of %s, not retagging: %v", tarball, err)
+		return nil
+	}
+	return r.retagArchive(tarball, rr.Stdout.Bytes())
+}
+
+// retagArchive tags the images of a docker-archive tarball as recorded in its manifest.json
+func (r *Porto) retagArchive(tarball string, manifest []byte) error {
+	var manifests []dockerArchiveManifest
+	if err := json.Unmarshal(manifest, &manifests); err != nil {
+		return errors.Wrapf(err, "parsing the manifest of %s", tarball)
+	}
+	for _, m := range manifests {
+		// "sha256:<id>" in go-containerregistry archives, "<id>.json" or "blobs/sha256/<id>" in docker ones
+		id := strings.TrimSuffix(path.Base(strings.TrimPrefix(m.Config, "sha256:")), ".json")
+		for _, tag := range m.RepoTags {
+			if err := r.retagImage(id, tag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// retagImage tags the image with ID id as tag, unless an image is known by tag already
+func (r *Porto) retagImage(id string, tag string) error {
+	if r.ImageExists(tag, "") {
+		return nil
+	}
+	return r.TagImage(contextOf(r.Runner), id, tag)
+}
+
+// PullImage pulls an image into this runtime, reporting how far it got if ctx has a PullProgress
+func (r *Porto) PullImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	if progress := pullProgressOf(ctx); progress != nil {
+		return pullCRIImageWithProgress(r.Runner, r.SocketPath(), name, progress)
+	}
+	return pullCRIImage(r.Runner, r.SocketPath(), name)
+}
+
+// SaveImage save an image from this runtime
+func (r *Porto) SaveImage(ctx context.Context, name string, path string) error {
+	return r.SaveImages(ctx, []string{name}, path)
+}
+
+// SaveImages saves images from this runtime into one docker-archive, the format portoctl docker-load loads
+func (r *Porto) SaveImages(ctx context.Context, names []string, path string) error {
+	r = r.bind(ctx)
+	klog.Infof("Saving images %s: %s", names, path)
+	c := r.portoctl(append([]string{"docker-save", path}, names...)...)
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrap(err, "portoctl docker-save")
+	}
+	return nil
+}
+
+// RemoveImage removes a image
+func (r *Porto) RemoveImage(ctx context.Context, name string) error {
+	r = r.bind(ctx)
+	if api, err := r.portoAPI(); err == nil {
+		defer api.Close()
+		klog.Infof("Removing image: %s", name)
+		err := api.RemoveImage(name)
+		if err == nil {
+			return nil
+		}
+		// the API refuses clients without write access to portod, crictl runs as root
+		klog.Warningf("porto API remove image %s: %v", name, err)
+	}
+	return removeCRIImage(r.Runner, r.SocketPath(), name)
+}
+
+// TagImage tags an image in this runtime
+func (r *Porto) TagImage(ctx context.Context, source string, target string) error {
+	r = r.bind(ctx)
+	klog.Infof("Tagging image %s: %s", source, target)
+	c := r.portoctl("docker-tag", source, target)
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrapf(err, "portoctl docker-tag")
+	}
+	return nil
+}
+
+// PushImage pushes an image
+func (r *Porto) PushImage(ctx context.Context, name string) error {
+	return errors.New("not implemented")
+}
+
+// CGroupDriver returns cgroup driver ("cgroupfs" or "systemd")
+func (r *Porto) CGroupDriver() (string, error) {
+	return "systemd", nil
+}
+
+// Capabilities returns the optional features porto supports. It can't push images yet,
+// and checkpoints containers only with the runtime feature gate for it.
+func (r *Porto) Capabilities() Capabilities {
+	return Capabilities{
+		SupportsBuild:      true,
+		SupportsLoad:       true,
+		SupportsLoadLayout: true,
+		SupportsSave:       true,
+		SupportsPause:      true,
+		SupportsCheckpoint: r.FeatureGates.Enabled(FeatureSandboxCheckpointing),
+	}
+}
+
+// RuntimeInfo describes porto on the host. Porto has no storage driver to choose, it keeps layers and volumes in its place.
+func (r *Porto) RuntimeInfo() (RuntimeInfo, error) {
+	version, err := r.Version(contextOf(r.Runner))
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	cgroupDriver, err := r.CGroupDriver()
+	if err != nil {
+		return RuntimeInfo{}, err
+	}
+	return RuntimeInfo{
+		Name:         r.Name(),
+		Version:      version,
+		Socket:       r.SocketPath(),
+		CgroupDriver: cgroupDriver,
+		StorageRoot:  portoPlace,
+		Rootless:     nodeRootless(r.Runner),
+	}, nil
+}
+
+// Healthy returns an error unless portod answers on its API, portoshim answers CRI requests,
+// and the sockets of both are accessible to the porto group when they are handed to it
+func (r *Porto) Healthy(ctx context.Context) error {
+	r = r.bind(ctx)
+	if err := r.portodAlive(); err != nil {
+		return errors.Wrap(err, "portod")
+	}
+	if err := criHealthy(r.Runner, r.SocketPath()); err != nil {
+		return errors.Wrap(err, "portoshim")
+	}
+	for _, s := range []string{r.PortodSocketPath(), r.SocketPath()} {
+		if err := r.checkSocketPermissions(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Daemons returns the states of portod and portoshim apart, as either runs, and fails, without the other:
+// portod serves portoctl and the porto API, portoshim serves the CRI requests of kubelet on top of it
+func (r *Porto) Daemons(ctx context.Context) []DaemonStatus {
+	r = r.bind(ctx)
+	portod := DaemonStatus{Name: "portod", Active: r.Init.Active("porto"), Socket: r.PortodSocketPath()}
+	if err := r.portodAlive(); err != nil {
+		portod.Error = err.Error()
+	} else {
+		portod.Reachable = true
+		if v, err := r.Version(ctx); err == nil {
+			portod.Version = v
+		}
+	}
+
+	shim := DaemonStatus{Name: "portoshim", Active: r.Init.Active("portoshim"), Socket: r.SocketPath()}
+	if v, err := getCRIVersion(r.Runner, r.SocketPath()); err != nil {
+		shim.Error = errors.Wrapf(err, "no answer to CRI requests on %s", r.SocketPath()).Error()
+	} else {
+		shim.Reachable = true
+		shim.Version = v.RuntimeVersion
+	}
+	return []DaemonStatus{portod, shim}
+}
+
+// portodAlive returns an error unless portod answers over its API, or to portoctl where the API is out of reach
+func (r *Porto) portodAlive() error {
+	if api, err := r.portoAPI(); err == nil {
+		defer api.Close()
+		_, err := api.Version()
+		return errors.Wrapf(err, "no answer on %s", r.PortodSocketPath())
+	}
+	if _, err := r.Runner.RunCmd(r.portoctl("list")); err != nil {
+		return errors.Wrapf(err, "no answer to portoctl on %s", r.PortodSocketPath())
+	}
+	return nil
+}
+
+// checkSocketPermissions returns an error if socket is not a socket, or if it belongs to portoSocketGroup without being
+// readable and writable by it, which configureSocketGroup sees to, or to another group while SocketUser expects portoSocketGroup
+func (r *Porto) checkSocketPermissions(socket string) error {
+	rr, err := r.Runner.RunCmd(exec.Command("sudo", "stat", "-c", "%F|%a|%G", socket))
+	if err != nil {
+		return errors.Wrapf(err, "socket %s", socket)
+	}
+	fields := strings.Split(strings.TrimSpace(rr.Stdout.String()), "|")
+	if len(fields) != 3 {
+		return errors.Errorf("unexpected stat output for %s: %q", socket, rr.Stdout.String())
+	}
+	kind, perm, group := fields[0], fields[1], fields[2]
+	if kind != "socket" {
+		return errors.Errorf("%s is a %s, not a socket", socket, kind)
+	}
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil {
+		return errors.Wrapf(err, "parsing the mode of %s", socket)
+	}
+	if group == portoSocketGroup && mode&0o060 != 0o060 {
+		return errors.Errorf("socket %s belongs to the %s group, which can't use it (mode %s)", socket, portoSocketGroup, perm)
+	}
+	if r.SocketUser != "" && group != portoSocketGroup {
+		return errors.Errorf("socket %s belongs to the %s group rather than %s, so %s can't use it without sudo", socket, group, portoSocketGroup, r.SocketUser)
+	}
+	return nil
+}
+
+// KubeletOptions returns kubelet options for a porto
+func (r *Porto) KubeletOptions() map[string]string {
+	opts := kubeletCRIOptions(r, r.KubernetesVersion)
+	opts["system-reserved"] = portoSystemReserved
+	opts["kube-reserved"] = portoKubeReserved
+	if r.FeatureGates.Enabled(FeatureKubeletMetaContainer) {
+		opts["kube-reserved"] = portoMetaContainerReserved
+	}
+	// kubelet rejects pods with sysctls it does not allow, before portod gets to check them against its own list
+	if len(r.AllowedUnsafeSysctls) > 0 {
+		opts["allowed-unsafe-sysctls"] = strings.Join(r.AllowedUnsafeSysctls, ",")
+	}
+	return opts
+}
+
+const (
+	// portoPlace is the place of portod, where it keeps image layers, volumes and docker images: the image filesystem of kubelet
+	portoPlace = "/place"
+
+	// portoSystemReserved is reserved from pods for portod and portoshim
+	portoSystemReserved = "cpu=100m,memory=128Mi"
+	// portoKubeReserved is reserved from pods for kubelet
+	portoKubeReserved = "cpu=100m,memory=256Mi"
+	// portoMetaContainerReserved is reserved from pods for kubelet when it runs in PortoMetaContainer, matching its guarantees.
+	// The CPU is kept as small as for kubelet of its own, as minikube nodes often have only 2 CPUs.
+	portoMetaContainerReserved = "cpu=100m,memory=512Mi"
+)
+
+// ListContainers returns a list of managed by this container runtime
+func (r *Porto) ListContainers(o ListContainersOptions) ([]string, error) {
+	return listCRIContainers(r.Runner, r.SocketPath(), "", o)
+}
+
+// ListPods returns the pod sandboxes of porto
+func (r *Porto) ListPods(o ListPodsOptions) ([]Pod, error) {
+	return listCRIPods(r.Runner, r.SocketPath(), o)
+}
+
+// ContainersForPod returns the IDs of the containers in state of a pod sandbox based on ID
+func (r *Porto) ContainersForPod(id string, state ContainerState) ([]string, error) {
+	return r.ListContainers(ListContainersOptions{State: state, PodIDs: []string{id}})
+}
+
+// PauseContainers pauses a running container based on ID
+func (r *Porto) PauseContainers(ids []string) error {
+	return pauseCRIContainers(r.Runner, "", ids)
+}
+
+// UnpauseContainers unpauses a running container based on ID
+func (r *Porto) UnpauseContainers(ids []string) error {
+	return unpauseCRIContainers(r.Runner, "", ids)
+}
+
+// KillContainers removes containers based on ID
+func (r *Porto) KillContainers(ids []string) error {
+	return killCRIContainers(r.Runner, r.SocketPath(), ids)
+}
+
+// StopContainers stops containers based on ID
+func (r *Porto) StopContainers(ids []string) error {
+	return stopCRIContainers(r.Runner, r.SocketPath(), ids)
+}
+
+// ContainerStats returns the resource usage of containers based on ID, or of all containers if none are given
+func (r *Porto) ContainerStats(ids []string) ([]ContainerStats, error) {
+	return criContainerStats(r.Runner, r.SocketPath(), ids)
+}
+
+// Events streams the lifecycle events of the containers until ctx is done
+func (r *Porto) Events(ctx context.Context) (<-chan ContainerEvent, error) {
+	return criEvents(ctx, r.Runner, r.SocketPath())
+}
+
+// portoContainerName returns the name of the porto container of the CRI container id. portoshim nests the
+// containers of a pod in the container of its sandbox, so the name ends with the ID rather than being it.
+func (r *Porto) portoContainerName(id string) (string, error) {
+	rr, err := r.Runner.RunCmd(r.portoctl("list", "-1"))
+	if err != nil {
+		return "", errors.Wrap(err, "portoctl list")
+	}
+	for _, name := range strings.Fields(rr.Stdout.String()) {
+		if name == id || strings.HasSuffix(name, "/"+id) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no porto container of container %s", id)
+}
+
+// ExecContainer runs a command in a container based on ID. portoctl exec runs it in a subcontainer,
+// which shares the namespaces and root of the container and is destroyed once the command exits.
+func (r *Porto) ExecContainer(id string, cmd []string) (*command.RunResult, error) {
+	parent, err := r.portoContainerName(id)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s/minikube-exec-%d", parent, time.Now().UnixNano())
+	c := r.portoctl("exec", name, "command="+shellquote.Join(cmd...), "isolate=false")
+	rr, err := r.Runner.RunCmd(c)
+	if err != nil {
+		return rr, errors.Wrapf(err, "portoctl exec in %s", parent)
+	}
+	return rr, nil
+}
+
+// checkpointingEnabled returns an error unless the runtime feature gate for checkpoints is enabled
+func (r *Porto) checkpointingEnabled() error {
+	if !r.FeatureGates.Enabled(FeatureSandboxCheckpointing) {
+		return fmt.Errorf("checkpointing containers requires --runtime-feature-gates=%s=true", FeatureSandboxCheckpointing)
+	}
+	return nil
+}
+
+// CheckpointContainer saves the state of a running container based on ID to dir on the node, using CRIU.
+// The container keeps running.
+func (r *Porto) CheckpointContainer(id string, dir string) error {
+	if err := r.checkpointingEnabled(); err != nil {
+		return err
+	}
+	name, err := r.portoContainerName(id)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Runner.RunCmd(r.portoctl("checkpoint", name, dir)); err != nil {
+		return errors.Wrapf(err, "portoctl checkpoint %s", name)
+	}
+	return nil
+}
+
+// RestoreContainer restores the state saved by CheckpointContainer into a container based on ID.
+// The container, which kubelet has created afresh, is stopped and its processes replaced by the checkpointed ones.
+func (r *Porto) RestoreContainer(id string, dir string) error {
+	if err := r.checkpointingEnabled(); err != nil {
+		return err
+	}
+	name, err := r.portoContainerName(id)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Runner.RunCmd(r.portoctl("stop", name)); err != nil {
+		return errors.Wrapf(err, "portoctl stop %s", name)
+	}
+	if _, err := r.Runner.RunCmd(r.portoctl("restore", name, dir)); err != nil {
+		return errors.Wrapf(err, "portoctl restore %s", name)
+	}
+	return nil
+}
+
+// ContainerLogCmd returns the command to retrieve the log for a container based on ID
+func (r *Porto) ContainerLogCmd(id string, len int, follow bool) string {
+	return criContainerLogCmd(r.Runner, id, len, follow)
+}
+
+// SystemLogCmd returns the command to retrieve system logs
+func (r *Porto) SystemLogCmd(len int) string {
+	return fmt.Sprintf("sudo tail -n %d /var/log/portod.log", len)
+}
+
+// Preload preloads the container runtime with k8s images
+func (r *Porto) Preload(ctx context.Context, cc config.ClusterConfig) error {
+	r = r.bind(ctx)
+	k8sVersion := cc.KubernetesConfig.KubernetesVersion
+	imageList, err := images.Kubeadm(cc.KubernetesConfig.ImageRepository, k8sVersion)
+	if err != nil {
+		return errors.Wrap(err, "getting images")
+	}
+	if portoImagesPreloaded(r.Runner, r.SocketPath(), imageList) {
+		klog.Info("Images already preloaded, skipping extraction")
+		RecordImageSources(r.Runner, ImagePreloaded, imageList...)
+		if err := r.retagMirrorImages(cc.KubernetesConfig.ImageRepository, k8sVersion, imageList); err != nil {
+			klog.Warningf("retagging mirror images: %v", err)
+		}
+		return nil
+	}
+	if download.PreloadExists(k8sVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver) {
+		if err := r.extractPreload(k8sVersion, cc.KubernetesConfig.ContainerRuntime); err != nil {
+			return err
+		}
+		RecordImageSources(r.Runner, ImagePreloaded, imageList...)
+		return nil
+	}
+	if err := r.pullImages(imageList, cc.PreloadConcurrency, nil); err != nil {
+		return err
+	}
+	if err := r.retagMirrorImages(cc.KubernetesConfig.ImageRepository, k8sVersion, imageList); err != nil {
+		klog.Warningf("retagging mirror images: %v", err)
+	}
+	return r.Restart()
+}
+
+// retagMirrorImages tags the images pulled from the mirror repository with their canonical names as well,
+// because kubelet and portoshim still ask for some of them, such as the pause image, by their registry.k8s.io
+// names and would pull them a second time otherwise. Images are told apart by digest, so that a canonical
+// name already tagging another image is moved to the mirrored one.
+// Callers only log the error, as the worst outcome is the duplicate pull.
+func (r *Porto) retagMirrorImages(mirror string, k8sVersion string, mirrored []string) error {
+	if mirror == "" {
+		return nil
+	}
+	canonical, err := images.Kubeadm("", k8sVersion)
+	if err != nil {
+		return errors.Wrap(err, "canonical images")
+	}
+	// both lists are built by the same function, so images match up by position
+	if len(canonical) != len(mirrored) {
+		return fmt.Errorf("mirror image list has %d images, the canonical one %d", len(mirrored), len(canonical))
+	}
+	var failed []string
+	for i, img := range mirrored {
+		if img == canonical[i] {
+			continue
+		}
+		// the canonical name may tag another version of the image, which is moved to the mirrored one
+		if c, err := r.ImageDigest(canonical[i]); err == nil {
+			if d, err := r.ImageDigest(img); err != nil || SameDigest(c, d) {
+				continue
+			}
+		}
+		if err := r.TagImage(contextOf(r.Runner), img, canonical[i]); err != nil {
+			failed = append(failed, fmt.Sprintf("%s as %s: %v", img, canonical[i], err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to tag %d images:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// PrefetchImages pulls the images which are missing from porto, concurrency at a time, so that pods using them
+// start right away rather than each waiting for its own pull. progress, if set, is called as every pull finishes.
+func (r *Porto) PrefetchImages(imgs []string, concurrency int, progress ImagePullProgress) error {
+	var missing []string
+	for _, img := range imgs {
+		if !r.ImageExists(img, "") {
+			missing = append(missing, img)
+		}
+	}
+	if len(missing) == 0 {
+		klog.Infof("all %d images are present, nothing to prefetch", len(imgs))
+		return nil
+	}
+	return r.pullImages(missing, concurrency, progress)
+}
+
+// pullImages pulls images with at most concurrency pulls in flight, and reports every image that failed
+func (r *Porto) pullImages(imgs []string, concurrency int, progress ImagePullProgress) error {
+	if concurrency < 1 {
+		concurrency = constants.DefaultPreloadConcurrency
+	}
+	klog.Infof("pulling %d images, %d at a time", len(imgs), concurrency)
+	start := time.Now()
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	var mu sync.Mutex
+	var failed []string
+	done := 0
+	for _, img := range imgs {
+		img := img
+		g.Go(func() error {
+			t := time.Now()
+			err := r.PullImage(contextOf(r.Runner), img)
+			if err != nil {
+				RecordImageSources(r.Runner, ImageFailed, img)
+			} else {
+				RecordImageSources(r.Runner, ImagePulled, img)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if progress != nil {
+				progress(done, len(imgs), img, err)
+			}
+			if err != nil {
+				klog.Warningf("[%d/%d] failed to pull %q after %s: %v", done, len(imgs), img, time.Since(t), err)
+				failed = append(failed, fmt.Sprintf("%s: %v", img, err))
+				return nil
+			}
+			klog.Infof("[%d/%d] pulled %q in %s", done, len(imgs), img, time.Since(t))
+			return nil
+		})
+	}
+	_ = g.Wait()
+	klog.Infof("pulled %d images in %s", len(imgs)-len(failed), time.Since(start))
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.Errorf("failed to pull %d of %d images:\n%s", len(failed), len(imgs), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// extractPreload copies the preload tarball over and unpacks the porto layer and image stores from it
+func (r *Porto) extractPreload(k8sVersion, cRuntime string) error {
+	tarballPath := download.TarballPath(k8sVersion, cRuntime)
+	targetDir := "/"
+	targetName := "preloaded.tar.lz4"
+	dest := path.Join(targetDir, targetName)
+
+	c := exec.Command("which", "lz4")
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return NewErrISOFeature("lz4")
+	}
+
+	// Copy over tarball into host
+	fa, err := assets.NewFileAsset(tarballPath, targetDir, targetName, "0644")
+	if err != nil {
+		return errors.Wrap(err, "getting file asset")
+	}
+	defer func() {
+		if err := fa.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", fa.GetSourcePath(), err)
+		}
+	}()
+
+	t := time.Now()
+	if err := r.Runner.Copy(fa); err != nil {
+		return errors.Wrap(err, "copying file")
+	}
+	klog.Infof("Took %f seconds to copy over tarball", time.Since(t).Seconds())
+
+	// portod caches the layer and image stores, so it must not be running while they change underneath it
+	if err := r.Init.Stop("porto"); err != nil {
+		return errors.Wrap(err, "stopping porto")
+	}
+
+	t = time.Now()
+	root := download.TarballRoot(cRuntime)
+	if rr, err := r.Runner.RunCmd(exec.Command("sudo", "tar", "--xattrs", "--xattrs-include", "security.capability", "-I", "lz4", "-C", root, "-xf", dest)); err != nil {
+		return errors.Wrapf(err, "extracting tarball: %s", rr.Output())
+	}
+	klog.Infof("Took %f seconds to extract the tarball", time.Since(t).Seconds())
+
+	//  remove the tarball in the VM
+	if err := r.Runner.Remove(fa); err != nil {
+		klog.Infof("error removing tarball: %v", err)
+	}
+
+	return r.Restart()
+}
+
+// Restart restarts this container runtime on a host
+func (r *Porto) Restart() error {
+	return r.Init.Restart("porto")
+}
+
+// portoImagesPreloaded returns true if all images have been preloaded.
+// Images are listed over the CRI socket when the runner can reach it, falling back to crictl otherwise.
+func portoImagesPreloaded(runner command.Runner, socket string, images []string) bool {
+	listed, err := listCRIImages(runner, socket)
+	if err != nil {
+		klog.Errorf("failed to list images, will assume images are not preloaded: %v", err)
+		return false
+	}
+
+	if i := missingImage(images, imageRefs(listed)); i != "" {
+		klog.Infof("couldn't find preloaded image for %q. assuming images are not preloaded.", i)
+		return false
+	}
+	klog.Infof("all images are preloaded for porto runtime.")
+	return true
+}
+
+// ImagesPreloaded returns true if all images have been preloaded
+func (r *Porto) ImagesPreloaded(images []string) bool {
+	return portoImagesPreloaded(r.Runner, r.SocketPath(), images)
+}
+
+// InstallWasmShim is not supported by porto
+func (r *Porto) InstallWasmShim(context.Context, WasmShim) error {
+	return ErrWasmNotSupported
+}
+
+// InstallKata is not supported by porto
+func (r *Porto) InstallKata(context.Context) error {
+	return ErrKataNotSupported
 }
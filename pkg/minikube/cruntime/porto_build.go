@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+const (
+	// portoBuildkitUnit is the transient unit of the buildkitd porto builds images with
+	portoBuildkitUnit = "minikube-buildkit-porto"
+	// portoBuildkitSocket is where the buildkitd of porto builds listens
+	portoBuildkitSocket = "/run/buildkit/porto.sock"
+	// portoBuildkitRoot is the state of the buildkitd of porto builds, apart from the one of the containerd worker
+	portoBuildkitRoot = "/var/lib/buildkit/porto"
+)
+
+// portoBuildDir is where builds export their image to, before porto loads it
+var portoBuildDir = path.Join(vmpath.GuestEphemeralDir, "build")
+
+// startBuildkit starts the buildkitd porto builds images with, unless it runs already. The buildkitd of the node runs
+// its builds through containerd, which porto nodes don't run, so this one runs them with the OCI worker instead.
+func (r *Porto) startBuildkit() error {
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "systemctl", "is-active", "--quiet", portoBuildkitUnit)); err == nil {
+		return nil
+	}
+	klog.Infof("starting %s", portoBuildkitUnit)
+	c := fmt.Sprintf(`systemctl reset-failed %[1]s 2>/dev/null; mkdir -p %[2]s && runc=$(command -v buildkit-runc || command -v runc) && `+
+		`systemd-run --unit=%[1]s $(command -v buildkitd) --addr unix://%[3]s --root %[2]s --oci-worker=true --oci-worker-binary="$runc" --containerd-worker=false`,
+		portoBuildkitUnit, portoBuildkitRoot, portoBuildkitSocket)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrap(err, "starting buildkitd")
+	}
+	wait := fmt.Sprintf("for i in $(seq 1 50); do [ -S %s ] && exit 0; sleep 0.2; done; exit 1", portoBuildkitSocket)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", wait)); err != nil {
+		return errors.Wrapf(err, "waiting for %s", portoBuildkitSocket)
+	}
+	return nil
+}
+
+// BuildImage builds an image with BuildKit in the node, streaming the build log to the terminal, and loads it into porto
+// tagged as tag. Porto can't push images, so builds can't be pushed either.
+func (r *Porto) BuildImage(ctx context.Context, src string, file string, tag string, push bool, env []string, opts []string) error {
+	r = r.bind(ctx)
+	if push {
+		return errors.New("porto can't push images, build without --push")
+	}
+	dir, err := downloadRemote(r.Runner, src)
+	if err != nil {
+		return err
+	}
+	return r.buildImage(ctx, dir, file, tag, env, opts, os.Stdout)
+}
+
+// BuildImageStream builds the image of the context in dir like BuildImage, writing the build log to out, and tags it
+// as every one of tags. porto-docker-shim serves "docker build" with it.
+func (r *Porto) BuildImageStream(ctx context.Context, dir string, file string, tags []string, opts []string, out io.Writer) error {
+	r = r.bind(ctx)
+	if len(tags) == 0 {
+		return r.buildImage(ctx, dir, file, "", nil, opts, out)
+	}
+	if err := r.buildImage(ctx, dir, file, tags[0], nil, opts, out); err != nil {
+		return err
+	}
+	for _, t := range tags[1:] {
+		if err := r.TagImage(ctx, tags[0], t); err != nil {
+			return errors.Wrapf(err, "tagging %s as %s", tags[0], t)
+		}
+	}
+	return nil
+}
+
+// buildImage builds the image of the context in dir with the Dockerfile file, writing the build log to out
+func (r *Porto) buildImage(ctx context.Context, dir string, file string, tag string, env []string, opts []string, out io.Writer) error {
+	dockerfileDir, dockerfile := dir, "Dockerfile"
+	if file != "" {
+		if !path.IsAbs(file) {
+			file = path.Join(dir, file)
+		}
+		dockerfileDir, dockerfile = path.Dir(file), path.Base(file)
+	}
+	if err := r.startBuildkit(); err != nil {
+		return err
+	}
+
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "mkdir", "-p", portoBuildDir)); err != nil {
+		return errors.Wrapf(err, "failed to create %q", portoBuildDir)
+	}
+	archive := path.Join(portoBuildDir, fmt.Sprintf("image-%d.tar", time.Now().UnixNano()))
+	defer func() {
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-f", archive)); err != nil {
+			klog.Warningf("failed to remove %s: %v", archive, err)
+		}
+	}()
+
+	output := "type=docker,dest=" + archive
+	if tag != "" {
+		// add default tag if missing
+		if !strings.Contains(tag, ":") {
+			tag += ":latest"
+		}
+		output += ",name=" + tag
+	}
+	klog.Infof("Building image: %s", dir)
+	args := []string{"buildctl", "--addr", "unix://" + portoBuildkitSocket, "build",
+		"--progress", "plain",
+		"--frontend", "dockerfile.v0",
+		"--local", fmt.Sprintf("context=%s", dir),
+		"--local", fmt.Sprintf("dockerfile=%s", dockerfileDir),
+		"--opt", fmt.Sprintf("filename=%s", dockerfile),
+		"--output", output}
+	args = append(args, r.buildCacheArgs(opts)...)
+	for _, opt := range opts {
+		args = append(args, "--"+opt)
+	}
+	c := exec.Command("sudo", args...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdout = out
+	c.Stderr = out
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrap(err, "buildctl build")
+	}
+
+	if err := r.LoadImage(ctx, archive); err != nil {
+		return errors.Wrap(err, "loading the built image")
+	}
+	if err := r.pruneBuildCache(); err != nil {
+		klog.Warningf("unable to prune the build cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// prefixRunner records the commands it runs, and answers them with the output of the prefix of outputs they start with,
+// failing those starting with a prefix of failing
+type prefixRunner struct {
+	*command.FakeCommandRunner
+	outputs map[string]string
+	failing []string
+	cmds    []string
+}
+
+func (r *prefixRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	c := strings.Join(cmd.Args, " ")
+	r.cmds = append(r.cmds, c)
+	rr := &command.RunResult{Args: cmd.Args}
+	for _, prefix := range r.failing {
+		if strings.HasPrefix(c, prefix) {
+			return rr, fmt.Errorf("%s failed", c)
+		}
+	}
+	for prefix, out := range r.outputs {
+		if strings.HasPrefix(c, prefix) {
+			rr.Stdout = *bytes.NewBufferString(out)
+		}
+	}
+	return rr, nil
+}
+
+// ranWith returns the command run starting with prefix, or "" if none did
+func (r *prefixRunner) ranWith(prefix string) string {
+	for _, c := range r.cmds {
+		if strings.HasPrefix(c, prefix) {
+			return c
+		}
+	}
+	return ""
+}
+
+func TestPortoBuildImage(t *testing.T) {
+	runner := &prefixRunner{
+		FakeCommandRunner: command.NewFakeCommandRunner(),
+		outputs: map[string]string{
+			"sudo tar -xOf":               `[{"Config":"blobs/sha256/0123abcd","RepoTags":["example.com/app:latest"]}]`,
+			"sudo portoctl docker-images": "ID   NAME\nsha256:0123abcd   example.com/app:latest\n",
+		},
+		failing: []string{"sudo systemctl is-active"},
+	}
+	r := &Porto{Runner: runner}
+	if err := r.BuildImage(context.Background(), "/var/lib/minikube/build/app", "build/Containerfile", "example.com/app", false, nil, []string{"no-cache"}); err != nil {
+		t.Fatalf("BuildImage: %v", err)
+	}
+
+	if runner.ranWith("sudo /bin/bash -c systemctl reset-failed "+portoBuildkitUnit) == "" {
+		t.Errorf("BuildImage did not start buildkitd, ran %v", runner.cmds)
+	}
+	build := runner.ranWith("sudo buildctl")
+	for _, want := range []string{
+		"--addr unix://" + portoBuildkitSocket,
+		"--progress plain",
+		"--local context=/var/lib/minikube/build/app",
+		"--local dockerfile=/var/lib/minikube/build/app/build",
+		"--opt filename=Containerfile",
+		",name=example.com/app:latest",
+		"--no-cache",
+	} {
+		if !strings.Contains(build, want) {
+			t.Errorf("buildctl command %q lacks %q", build, want)
+		}
+	}
+	archive := strings.TrimPrefix(strings.Fields(build[strings.Index(build, "dest="):])[0], "dest=")
+	archive = strings.Split(archive, ",")[0]
+	if runner.ranWith("sudo portoctl docker-load "+archive) == "" {
+		t.Errorf("BuildImage did not load %s, ran %v", archive, runner.cmds)
+	}
+
+	if err := r.BuildImage(context.Background(), "/var/lib/minikube/build/app", "", "example.com/app", true, nil, nil); err == nil {
+		t.Errorf("BuildImage with push succeeded")
+	}
+}
+
+func TestPortoBuildImageStream(t *testing.T) {
+	runner := &prefixRunner{
+		FakeCommandRunner: command.NewFakeCommandRunner(),
+		outputs: map[string]string{
+			"sudo tar -xOf":               `[{"Config":"blobs/sha256/0123abcd","RepoTags":["example.com/app:1"]}]`,
+			"sudo portoctl docker-images": "ID   NAME\nsha256:0123abcd   example.com/app:1\n",
+		},
+	}
+	r := &Porto{Runner: runner}
+	var out bytes.Buffer
+	if err := r.BuildImageStream(context.Background(), "/tmp/context", "", []string{"example.com/app:1", "example.com/app:latest"}, nil, &out); err != nil {
+		t.Fatalf("BuildImageStream: %v", err)
+	}
+	if build := runner.ranWith("sudo buildctl"); !strings.Contains(build, ",name=example.com/app:1") || !strings.Contains(build, "--local context=/tmp/context") {
+		t.Errorf("buildctl command %q does not build /tmp/context as example.com/app:1", build)
+	}
+	if runner.ranWith("sudo portoctl docker-tag example.com/app:1 example.com/app:latest") == "" {
+		t.Errorf("BuildImageStream did not tag the other tags, ran %v", runner.cmds)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// portodSocket is the socket portod serves its API on
+const portodSocket = "/run/portod.socket"
+
+// portoAPITimeout bounds a single call to the porto API
+const portoAPITimeout = 30 * time.Second
+
+// Field numbers of the porto API messages used here, from rpc.proto of porto
+const (
+	// TPortoRequest
+	portoReqVersion           protowire.Number = 14
+	portoReqDockerImageStatus protowire.Number = 300
+	portoReqDockerImageList   protowire.Number = 301
+	portoReqDockerImageRemove protowire.Number = 303
+
+	// TPortoResponse
+	portoRespError             protowire.Number = 1
+	portoRespErrorMsg          protowire.Number = 2
+	portoRespVersion           protowire.Number = 8
+	portoRespDockerImageStatus protowire.Number = 300
+	portoRespDockerImageList   protowire.Number = 301
+
+	// TVersionResponse
+	portoVersionTag protowire.Number = 1
+
+	// TDockerImageStatusRequest, TDockerImageRemoveRequest
+	portoImageName protowire.Number = 1
+
+	// TDockerImageStatusResponse
+	portoImageStatusImage protowire.Number = 1
+
+	// TDockerImageListResponse
+	portoImageListImages protowire.Number = 1
+
+	// TDockerImage
+	portoImageID      protowire.Number = 1
+	portoImageTags    protowire.Number = 2
+	portoImageDigests protowire.Number = 3
+	portoImageSize    protowire.Number = 5
+	portoImageCreated protowire.Number = 6
+)
+
+// PortoAPIError is an error returned by portod
+type PortoAPIError struct {
+	// Code is the EError of porto
+	Code uint64
+	// Msg is the message portod returned with it
+	Msg string
+}
+
+func (e *PortoAPIError) Error() string {
+	return fmt.Sprintf("porto error %d: %s", e.Code, e.Msg)
+}
+
+// portoClient is a minimal client of the porto API, which speaks length-prefixed protobuf messages over the portod socket.
+// Only the calls minikube needs are implemented, everything else still goes through portoctl.
+type portoClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// ctx is the context of the runner the client was dialed with, which calls give up with
+	ctx context.Context
+}
+
+// dialPorto connects to the porto API on socket of the node the runner runs commands on.
+// Calls of the client give up once the context the runner is bound to is done.
+func dialPorto(runner CommandRunner, socket string) (*portoClient, error) {
+	d, ok := runner.(command.SocketDialer)
+	if !ok {
+		return nil, fmt.Errorf("%T can't dial %s", runner, socket)
+	}
+	ctx := contextOf(runner)
+	dctx, cancel := context.WithTimeout(ctx, portoAPITimeout)
+	defer cancel()
+	conn, err := d.DialSocket(dctx, socket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial %s", socket)
+	}
+	return &portoClient{conn: conn, r: bufio.NewReader(conn), ctx: ctx}, nil
+}
+
+// Close closes the connection to portod
+func (c *portoClient) Close() error {
+	return c.conn.Close()
+}
+
+// call sends a request with the given field set to req, and returns the decoded response
+func (c *portoClient) call(field protowire.Number, req []byte) (portoMessage, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(portoAPITimeout)
+	if d, ok := c.ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// a deadline in the past interrupts the request in flight once the context is canceled
+	stop := context.AfterFunc(c.ctx, func() { _ = c.conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	msg := protowire.AppendBytes(protowire.AppendTag(nil, field, protowire.BytesType), req)
+	if _, err := c.conn.Write(protowire.AppendBytes(nil, msg)); err != nil {
+		return nil, errors.Wrap(err, "send request")
+	}
+
+	size, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response size")
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+	resp, err := decodePortoMessage(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode response")
+	}
+	if code := resp.uint(portoRespError); code != 0 {
+		return nil, &PortoAPIError{Code: code, Msg: resp.string(portoRespErrorMsg)}
+	}
+	return resp, nil
+}
+
+// Version returns the version of portod
+func (c *portoClient) Version() (string, error) {
+	resp, err := c.call(portoReqVersion, nil)
+	if err != nil {
+		return "", err
+	}
+	v, err := resp.message(portoRespVersion)
+	if err != nil {
+		return "", err
+	}
+	return v.string(portoVersionTag), nil
+}
+
+// ListImages lists the docker images of portod
+func (c *portoClient) ListImages() ([]ListImage, error) {
+	resp, err := c.call(portoReqDockerImageList, nil)
+	if err != nil {
+		return nil, err
+	}
+	list, err := resp.message(portoRespDockerImageList)
+	if err != nil {
+		return nil, err
+	}
+	imgs, err := list.messages(portoImageListImages)
+	if err != nil {
+		return nil, err
+	}
+	images := []ListImage{}
+	for _, img := range imgs {
+		images = append(images, portoListImage(img))
+	}
+	return images, nil
+}
+
+// ImageStatus returns the docker image known to portod by name, which may be a tag, a digest or an id
+func (c *portoClient) ImageStatus(name string) (ListImage, error) {
+	resp, err := c.call(portoReqDockerImageStatus, appendPortoString(nil, portoImageName, name))
+	if err != nil {
+		return ListImage{}, err
+	}
+	status, err := resp.message(portoRespDockerImageStatus)
+	if err != nil {
+		return ListImage{}, err
+	}
+	img, err := status.message(portoImageStatusImage)
+	if err != nil {
+		return ListImage{}, err
+	}
+	return portoListImage(img), nil
+}
+
+// RemoveImage removes a docker image from portod
+func (c *portoClient) RemoveImage(name string) error {
+	_, err := c.call(portoReqDockerImageRemove, appendPortoString(nil, portoImageName, name))
+	return err
+}
+
+// portoListImage converts a TDockerImage, whose creation time is in seconds since the epoch
+func portoListImage(img portoMessage) ListImage {
+	var created time.Time
+	if sec := img.uint(portoImageCreated); sec != 0 {
+		created = time.Unix(int64(sec), 0)
+	}
+	return ListImage{
+		ID:          img.string(portoImageID),
+		RepoTags:    img.strings(portoImageTags),
+		RepoDigests: img.strings(portoImageDigests),
+		Size:        strconv.FormatUint(img.uint(portoImageSize), 10),
+		Created:     imageCreated(created),
+	}
+}
+
+// appendPortoString appends a string field to a protobuf message
+func appendPortoString(b []byte, field protowire.Number, s string) []byte {
+	return protowire.AppendString(protowire.AppendTag(b, field, protowire.BytesType), s)
+}
+
+// portoMessage is a decoded protobuf message, holding the values of each field:
+// varints as uint64, and length-delimited values as []byte to be read as strings or nested messages.
+// Fields of other wire types are skipped, none of the messages read here use them.
+type portoMessage map[protowire.Number][]interface{}
+
+// decodePortoMessage decodes a protobuf message without its schema
+func decodePortoMessage(b []byte) (portoMessage, error) {
+	m := portoMessage{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			m[num] = append(m[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			m[num] = append(m[num], v)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return m, nil
+}
+
+// uint returns the last value of a varint field, or 0 if it is unset
+func (m portoMessage) uint(field protowire.Number) uint64 {
+	vs := m[field]
+	if len(vs) == 0 {
+		return 0
+	}
+	v, _ := vs[len(vs)-1].(uint64)
+	return v
+}
+
+// string returns the last value of a string field, or "" if it is unset
+func (m portoMessage) string(field protowire.Number) string {
+	vs := m.strings(field)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[len(vs)-1]
+}
+
+// strings returns the values of a repeated string field
+func (m portoMessage) strings(field protowire.Number) []string {
+	var ss []string
+	for _, v := range m[field] {
+		if b, ok := v.([]byte); ok {
+			ss = append(ss, string(b))
+		}
+	}
+	return ss
+}
+
+// message returns the last value of a nested message field, which must be set
+func (m portoMessage) message(field protowire.Number) (portoMessage, error) {
+	ms, err := m.messages(field)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return nil, fmt.Errorf("field %d is missing from the porto response", field)
+	}
+	return ms[len(ms)-1], nil
+}
+
+// messages returns the values of a repeated nested message field
+func (m portoMessage) messages(field protowire.Number) ([]portoMessage, error) {
+	var ms []portoMessage
+	for _, b := range m.strings(field) {
+		nested, err := decodePortoMessage([]byte(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %d", field)
+		}
+		ms = append(ms, nested)
+	}
+	return ms, nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+)
+
+// portoConfDir is the directory of the portod configuration, which portod merges in the order of the file names
+const portoConfDir = "/etc/portod.conf.d"
+
+// PortoConfigLine returns the release line, such as v5.3, of porto version, which PortoConfigKeys are kept by
+func PortoConfigLine(version string) (string, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing porto version %q", version)
+	}
+	return fmt.Sprintf("v%d.%d", v.Major, v.Minor), nil
+}
+
+// portoConfigKeysFor returns the version of the portod binary of the node and the PortoConfigKeys of its release line,
+// or nil keys when either is unknown, in which case the configuration is written as it is
+func portoConfigKeysFor(cr CommandRunner) (string, map[string][]string) {
+	version, err := installedPortoVersion(cr)
+	if err != nil {
+		klog.Warningf("unable to get the porto version to check the configuration against: %v", err)
+		return "", nil
+	}
+	line, err := PortoConfigLine(version)
+	if err != nil {
+		klog.Warningf("unable to check the configuration against porto %s: %v", version, err)
+		return version, nil
+	}
+	keys, ok := PortoConfigKeys[line]
+	if !ok {
+		klog.Warningf("the configuration keys of porto %s are unknown, run 'make update-porto-config-keys'", line)
+		return version, nil
+	}
+	return version, keys
+}
+
+// FilterPortoConfig returns conf, a portod configuration in the protobuf text format, without the fields keys does not
+// have, and the names of the fields it left out as section.key. keys are the fields of each section, as in PortoConfigKeys.
+// portod refuses to start on a configuration with a field it does not know, which an older or newer release may not.
+func FilterPortoConfig(conf string, keys map[string][]string) (string, []string) {
+	var kept strings.Builder
+	var dropped []string
+	section := ""
+	depth := 0
+	// skip is the depth of the content of the block being left out, 0 when there is none
+	skip := 0
+	for _, line := range strings.SplitAfter(conf, "\n") {
+		t := strings.TrimSpace(line)
+		opens := strings.HasSuffix(t, "{")
+		closes := t == "}"
+		if skip > 0 {
+			switch {
+			case opens:
+				depth++
+			case closes:
+				depth--
+				if depth < skip {
+					skip = 0
+				}
+			}
+			continue
+		}
+		if t == "" || strings.HasPrefix(t, "#") {
+			kept.WriteString(line)
+			continue
+		}
+		if closes {
+			depth--
+			kept.WriteString(line)
+			continue
+		}
+		name := strings.FieldsFunc(t, func(r rune) bool { return r == ':' || r == '{' || r == ' ' || r == '\t' })[0]
+		known := true
+		switch depth {
+		case 0:
+			_, known = keys[name]
+			if known {
+				section = name
+			}
+		case 1:
+			known = false
+			for _, k := range keys[section] {
+				known = known || k == name
+			}
+			if !known {
+				name = section + "." + name
+			}
+		}
+		if opens {
+			depth++
+		}
+		if !known {
+			dropped = append(dropped, name)
+			if opens {
+				skip = depth
+			}
+			continue
+		}
+		kept.WriteString(line)
+	}
+	return kept.String(), dropped
+}
+
+// writePortoConfig writes content to target of portoConfDir, without the fields the portod of the node does not know
+func writePortoConfig(cr CommandRunner, target, content string) error {
+	if version, keys := portoConfigKeysFor(cr); keys != nil {
+		var dropped []string
+		if content, dropped = FilterPortoConfig(content, keys); len(dropped) > 0 {
+			klog.Warningf("porto %s does not know %s, leaving them out of %s", version, strings.Join(dropped, ", "), target)
+		}
+	}
+	targetDir := filepath.Dir(target)
+	if _, err := cr.RunCmd(exec.Command("sudo", "mkdir", "-p", targetDir)); err != nil {
+		return errors.Wrapf(err, "failed to create directory %q", targetDir)
+	}
+	asset := assets.NewMemoryAssetTarget([]byte(content), target, "0644")
+	defer asset.Close()
+	if err := cr.Copy(asset); err != nil {
+		return errors.Wrapf(err, "failed to create %q", target)
+	}
+	return nil
+}
+
+// portoConfigOwned returns if minikube writes the file f of portoConfDir, rather than the ISO, kicbase or the user
+func portoConfigOwned(f string) bool {
+	return strings.Contains(filepath.Base(f), "-minikube-")
+}
+
+// prunePortoConfig removes the fields the portod of the node does not know from the files of portoConfDir minikube
+// writes, so that portod restarts after its binary was replaced by another release. The other files are left as they
+// are, with a warning about the fields portod will not know.
+func prunePortoConfig(cr CommandRunner) error {
+	version, keys := portoConfigKeysFor(cr)
+	if keys == nil {
+		return nil
+	}
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("ls %s/*.conf 2>/dev/null; true", portoConfDir)))
+	if err != nil {
+		return errors.Wrap(err, "listing the porto configuration")
+	}
+	for _, f := range strings.Fields(rr.Stdout.String()) {
+		rr, err := cr.RunCmd(exec.Command("sudo", "cat", f))
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", f)
+		}
+		content, dropped := FilterPortoConfig(rr.Stdout.String(), keys)
+		if len(dropped) == 0 {
+			continue
+		}
+		if !portoConfigOwned(f) {
+			klog.Warningf("porto %s does not know %s of %s, which minikube leaves as it is", version, strings.Join(dropped, ", "), f)
+			continue
+		}
+		klog.Warningf("porto %s does not know %s, removing them from %s", version, strings.Join(dropped, ", "), f)
+		asset := assets.NewMemoryAssetTarget([]byte(content), f, "0644")
+		err = cr.Copy(asset)
+		asset.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to update %q", f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file is to be generated by hack/update/porto_config_keys, but the table below was curated by hand from the
+// config.proto of porto v5.3 and might miss fields: 'make update-porto-config-keys' replaces it with the generated one.
+
+package cruntime
+
+// PortoConfigKeys are the fields of each section of the portod configuration each porto release line knows,
+// as declared by the config.proto of its packaged release. 'make update-porto-config-keys' refreshes the line the ISO ships.
+var PortoConfigKeys = map[string]map[string][]string{
+	"v5.3": {
+		"container": {
+			"allowed_sysctls",
+			"cpu_limit_scale",
+			"default_ulimit",
+			"detect_systemd",
+			"enable_blkio",
+			"enable_cgroup2",
+			"enable_checkpoint",
+			"enable_docker_mode",
+			"enable_numa_migration",
+			"enable_rw_cgroupfs",
+			"enable_rw_net_cgroups",
+			"enable_sched_idle",
+			"enable_systemd",
+			"memory_high_limit_proportion",
+			"propagate_cpu_guarantee",
+			"proportional_cpu_shares",
+			"use_os_mode_cgroupns",
+		},
+		"daemon": {
+			"docker_images_support",
+			"helpers_memory_limit",
+			"memory_limit",
+		},
+		"images": {
+			"registry_certs_dir",
+		},
+		"log": {
+			"debug",
+			"verbose",
+		},
+		"network": {
+			"enabled",
+		},
+		"volumes": {
+			"enable_shared_layers",
+		},
+	},
+}
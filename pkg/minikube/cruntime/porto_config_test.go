@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestFilterPortoConfig(t *testing.T) {
+	keys := map[string][]string{
+		"log":       {"verbose"},
+		"container": {"enable_systemd", "nested"},
+	}
+	conf := `# kept as it is
+log {
+  verbose: true
+  debug: true
+}
+container {
+  enable_systemd: true
+  nested {
+    anything: 1
+  }
+  gone {
+    inner {
+      x: 1
+    }
+  }
+  enable_gone: true
+}
+network {
+  enabled: true
+}`
+	want := `# kept as it is
+log {
+  verbose: true
+}
+container {
+  enable_systemd: true
+  nested {
+    anything: 1
+  }
+}
+`
+	got, dropped := FilterPortoConfig(conf, keys)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FilterPortoConfig mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"log.debug", "container.gone", "container.enable_gone", "network"}, dropped); diff != "" {
+		t.Errorf("FilterPortoConfig dropped mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortoConfigLine(t *testing.T) {
+	for version, want := range map[string]string{"5.3.33-alpha.3": "v5.3", "v5.4.0": "v5.4"} {
+		if got, err := PortoConfigLine(version); err != nil || got != want {
+			t.Errorf("PortoConfigLine(%q) = %q, %v, want %q", version, got, err, want)
+		}
+	}
+	if _, err := PortoConfigLine("unknown"); err == nil {
+		t.Errorf("PortoConfigLine: expected an error for an invalid version")
+	}
+}
+
+func TestWritePortoConfig(t *testing.T) {
+	const target = portoFeaturesConf
+	conf := "volumes {\n  enable_shared_layers: true\n}\nnetwork {\n  enabled: true\n}\n"
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p " + portoConfDir: "",
+		"portod --version":              "version: 5.3.33  /usr/sbin/portod",
+	})
+	orig := PortoConfigKeys
+	defer func() { PortoConfigKeys = orig }()
+	PortoConfigKeys = map[string]map[string][]string{"v5.3": {"volumes": {"enable_shared_layers"}}}
+
+	if err := writePortoConfig(runner, target, conf); err != nil {
+		t.Fatalf("writePortoConfig: %v", err)
+	}
+	if got, _ := runner.GetFileToContents(assets.MemorySource); got != "volumes {\n  enable_shared_layers: true\n}\n" {
+		t.Errorf("writePortoConfig: expected the network section to be left out, got %q", got)
+	}
+
+	// an unknown release line is written as it is
+	runner.SetCommandToOutput(map[string]string{"portod --version": "version: 6.0.1  /usr/sbin/portod"})
+	if err := writePortoConfig(runner, target, conf); err != nil {
+		t.Fatalf("writePortoConfig: %v", err)
+	}
+	if got, _ := runner.GetFileToContents(assets.MemorySource); got != conf {
+		t.Errorf("writePortoConfig: expected the configuration of an unknown porto line unfiltered, got %q", got)
+	}
+}
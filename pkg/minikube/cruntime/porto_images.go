@@ -0,0 +1,275 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// PortoImage is a row of portoctl docker-images: one reference of a docker image stored by portod
+type PortoImage struct {
+	// ID is the image ID, the digest of its config
+	ID string
+	// Repo is the repository of the reference, empty for an image which is neither tagged nor pulled by digest
+	Repo string
+	// Tag is the tag of the reference, empty when the image is referenced by digest only
+	Tag string
+	// Digest is the repository digest of the image, when portod knows it
+	Digest string
+	// Size is the size of the image in bytes
+	Size uint64
+	// Created is when the image was built, zero when portoctl does not print it
+	Created time.Time
+}
+
+// Ref returns the reference of the image: repo:tag, repo@digest for an untagged image, or empty
+func (i PortoImage) Ref() string {
+	switch {
+	case i.Repo == "":
+		return ""
+	case i.Tag != "":
+		return i.Repo + ":" + i.Tag
+	case i.Digest != "":
+		return i.Repo + "@" + i.Digest
+	}
+	return i.Repo
+}
+
+// Matches returns whether ref, a reference as given to pull or tag an image, names the image.
+// References are compared in their fully qualified form, so that "busybox" matches "docker.io/library/busybox:latest".
+func (i PortoImage) Matches(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if strings.TrimPrefix(i.ID, "sha256:") == strings.TrimPrefix(ref, "sha256:") {
+		return true
+	}
+	want, err := name.ParseReference(ref, name.WeakValidation)
+	if err != nil {
+		return false
+	}
+	for _, r := range []string{i.Ref(), i.digestRef()} {
+		if r == "" {
+			continue
+		}
+		if got, err := name.ParseReference(r, name.WeakValidation); err == nil && got.Name() == want.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// digestRef returns the reference of the image by its digest, or empty if portod does not know it
+func (i PortoImage) digestRef() string {
+	if i.Repo == "" || i.Digest == "" {
+		return ""
+	}
+	return i.Repo + "@" + i.Digest
+}
+
+// portoImageColumns are the columns of portoctl docker-images ParsePortoImages reads, others are skipped
+var portoImageColumns = map[string]bool{"ID": true, "NAME": true, "REPOSITORY": true, "TAG": true, "DIGEST": true, "SIZE": true, "CREATED": true}
+
+// ParsePortoImages parses the output of portoctl docker-images. portoctl has no machine-readable output for images,
+// so the table is read by its header: ID, and either NAME, the reference, or REPOSITORY and TAG, with optional DIGEST,
+// SIZE and CREATED columns. Rows are split on whitespace, or at the offsets of the header columns when a cell holds spaces,
+// as human readable sizes do. "<none>" cells are empty.
+func ParsePortoImages(out string) ([]PortoImage, error) {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	header := lines[0]
+	columns := strings.Fields(header)
+	var offsets []int
+	for i, at := 0, 0; i < len(columns); i++ {
+		at += strings.Index(header[at:], columns[i])
+		offsets = append(offsets, at)
+		at += len(columns[i])
+	}
+	if !slices.Contains(columns, "ID") || !(slices.Contains(columns, "NAME") || slices.Contains(columns, "REPOSITORY")) {
+		return nil, fmt.Errorf("unknown portoctl docker-images header %q", strings.TrimSpace(header))
+	}
+
+	var images []PortoImage
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		cells := strings.Fields(line)
+		if len(cells) != len(columns) {
+			cells = splitAtOffsets(line, offsets)
+		}
+		img, err := portoImageRow(columns, cells)
+		if err != nil {
+			return nil, errors.Wrapf(err, "portoctl docker-images row %q", strings.TrimSpace(line))
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// splitAtOffsets splits line into the cells starting at offsets
+func splitAtOffsets(line string, offsets []int) []string {
+	cells := make([]string, len(offsets))
+	for i, start := range offsets {
+		if start >= len(line) {
+			break
+		}
+		end := len(line)
+		if i+1 < len(offsets) && offsets[i+1] < end {
+			end = offsets[i+1]
+		}
+		cells[i] = strings.TrimSpace(line[start:end])
+	}
+	return cells
+}
+
+// portoImageRow returns the image of a row of cells under columns
+func portoImageRow(columns, cells []string) (PortoImage, error) {
+	var img PortoImage
+	for i, col := range columns {
+		if !portoImageColumns[col] || i >= len(cells) {
+			continue
+		}
+		v := cells[i]
+		if v == "<none>" {
+			continue
+		}
+		switch col {
+		case "ID":
+			img.ID = v
+		case "NAME":
+			img.Repo, img.Tag, img.Digest = splitImageRef(v, img.Digest)
+		case "REPOSITORY":
+			img.Repo = v
+		case "TAG":
+			img.Tag = v
+		case "DIGEST":
+			img.Digest = v
+		case "SIZE":
+			size, err := parseImageSize(v)
+			if err != nil {
+				return img, err
+			}
+			img.Size = size
+		case "CREATED":
+			img.Created = parseImageCreated(v)
+		}
+	}
+	if img.ID == "" {
+		return img, fmt.Errorf("no image ID")
+	}
+	return img, nil
+}
+
+// splitImageRef splits a reference into its repository, tag and digest, keeping digest when ref has none
+func splitImageRef(ref, digest string) (repo, tag, dgst string) {
+	repo, dgst = ref, digest
+	if r, d, ok := strings.Cut(ref, "@"); ok {
+		repo, dgst = r, d
+	}
+	// a colon after the last slash separates the tag, others separate the port of the registry
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, tag = repo[:i], repo[i+1:]
+	}
+	if tag == "<none>" {
+		tag = ""
+	}
+	if repo == "<none>" {
+		repo = ""
+	}
+	return repo, tag, dgst
+}
+
+// parseImageSize parses a size in bytes, or human readable in the decimal units docker uses
+func parseImageSize(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	n, err := units.FromHumanSize(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return uint64(n), nil
+}
+
+// imageCreatedLayouts are the layouts of absolute creation times in image listings
+var imageCreatedLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05 -0700 MST", "2006-01-02 15:04:05"}
+
+// parseImageCreated parses the creation time of an image. Relative times, such as "2 weeks ago",
+// are too coarse to be reported and give the zero time, as do times in unknown layouts.
+func parseImageCreated(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range imageCreatedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	klog.V(2).Infof("ignoring image creation time %q", s)
+	return time.Time{}
+}
+
+// portoListImages returns the images of PortoImage rows, merging the rows of each image ID
+func portoListImages(rows []PortoImage) []ListImage {
+	images := []ListImage{}
+	index := map[string]int{}
+	for _, row := range rows {
+		i, ok := index[row.ID]
+		if !ok {
+			i = len(images)
+			index[row.ID] = i
+			images = append(images, ListImage{ID: row.ID, RepoTags: []string{}, RepoDigests: []string{}, Size: strconv.FormatUint(row.Size, 10), Created: imageCreated(row.Created)})
+		}
+		if row.Tag != "" && !slices.Contains(images[i].RepoTags, row.Ref()) {
+			images[i].RepoTags = append(images[i].RepoTags, row.Ref())
+		}
+		if d := row.digestRef(); d != "" && !slices.Contains(images[i].RepoDigests, d) {
+			images[i].RepoDigests = append(images[i].RepoDigests, d)
+		}
+	}
+	return images
+}
+
+// portoctlImages lists the images of portod with portoctl docker-images
+func (r *Porto) portoctlImages() ([]PortoImage, error) {
+	rr, err := r.Runner.RunCmd(r.portoctl("docker-images"))
+	if err != nil {
+		return nil, errors.Wrap(err, "portoctl docker-images")
+	}
+	images, err := ParsePortoImages(rr.Stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("portoctl docker-images listed %d image references", len(images))
+	return images, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	pauseID   = "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c"
+	pauseSum  = "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097"
+	corednsID = "sha256:ead0a4a53df89fd173874b46093b6e62d8c72967bbf606d672c9e8c9b601a4fc"
+)
+
+func TestParsePortoImages(t *testing.T) {
+	var tests = []struct {
+		description string
+		out         string
+		want        []PortoImage
+	}{
+		{
+			description: "Names",
+			out: `ID                                                                        NAME                                   DIGEST                                                                    SIZE
+` + pauseID + `   registry.k8s.io/pause:3.9              ` + pauseSum + `   744 kB
+` + corednsID + `   registry.k8s.io/coredns/coredns:v1.10.1   <none>                                                                    53.6 MB
+` + corednsID + `   localhost:5000/coredns:dev             <none>                                                                    53.6 MB
+`,
+			want: []PortoImage{
+				{ID: pauseID, Repo: "registry.k8s.io/pause", Tag: "3.9", Digest: pauseSum, Size: 744000},
+				{ID: corednsID, Repo: "registry.k8s.io/coredns/coredns", Tag: "v1.10.1", Size: 53600000},
+				{ID: corednsID, Repo: "localhost:5000/coredns", Tag: "dev", Size: 53600000},
+			},
+		},
+		{
+			description: "RepositoryAndTag",
+			out: `REPOSITORY              TAG      ID                                                                        SIZE
+registry.k8s.io/pause   3.9      ` + pauseID + `   744000
+<none>                  <none>   ` + corednsID + `   53600000
+`,
+			want: []PortoImage{
+				{ID: pauseID, Repo: "registry.k8s.io/pause", Tag: "3.9", Size: 744000},
+				{ID: corednsID, Size: 53600000},
+			},
+		},
+		{
+			description: "DigestReference",
+			out:         "ID   NAME\n" + pauseID + "   registry.k8s.io/pause@" + pauseSum + "\n",
+			want:        []PortoImage{{ID: pauseID, Repo: "registry.k8s.io/pause", Digest: pauseSum}},
+		},
+		{
+			description: "Created",
+			out: fmt.Sprintf("%-74s %-40s %-8s %s\n", "ID", "NAME", "SIZE", "CREATED") +
+				fmt.Sprintf("%-74s %-40s %-8s %s\n", pauseID, "registry.k8s.io/pause:3.9", "744kB", "2023-05-17T09:21:46Z") +
+				fmt.Sprintf("%-74s %-40s %-8s %s\n", corednsID, "registry.k8s.io/coredns/coredns:v1.10.1", "53.6MB", "2 weeks ago"),
+			want: []PortoImage{
+				{ID: pauseID, Repo: "registry.k8s.io/pause", Tag: "3.9", Size: 744000, Created: time.Date(2023, 5, 17, 9, 21, 46, 0, time.UTC)},
+				{ID: corednsID, Repo: "registry.k8s.io/coredns/coredns", Tag: "v1.10.1", Size: 53600000},
+			},
+		},
+		{description: "Empty", out: "", want: nil},
+		{description: "HeaderOnly", out: "ID   NAME   SIZE\n", want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := ParsePortoImages(tc.out)
+			if err != nil {
+				t.Fatalf("ParsePortoImages: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParsePortoImages diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := ParsePortoImages("Usage: portoctl docker-images [-P place]\n"); err == nil {
+		t.Errorf("ParsePortoImages: expected an error for output without an image table")
+	}
+}
+
+func TestPortoImageMatches(t *testing.T) {
+	img := PortoImage{ID: pauseID, Repo: "docker.io/library/busybox", Tag: "latest", Digest: pauseSum}
+	for ref, want := range map[string]bool{
+		"busybox":                   true,
+		"busybox:latest":            true,
+		"docker.io/library/busybox": true,
+		"busybox@" + pauseSum:       true,
+		pauseID:                     true,
+		"e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c": true,
+		"busybox:1.36":               false,
+		"example.com/busybox:latest": false,
+		"":                           false,
+	} {
+		if got := img.Matches(ref); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestPortoListImages(t *testing.T) {
+	rows := []PortoImage{
+		{ID: pauseID, Repo: "registry.k8s.io/pause", Tag: "3.9", Digest: pauseSum, Size: 744000, Created: time.Date(2023, 5, 17, 9, 21, 46, 0, time.UTC)},
+		{ID: corednsID, Repo: "registry.k8s.io/coredns/coredns", Tag: "v1.10.1", Size: 53600000},
+		{ID: corednsID, Repo: "localhost:5000/coredns", Tag: "dev", Size: 53600000},
+		{ID: corednsID, Size: 53600000},
+	}
+	want := []ListImage{
+		{ID: pauseID, RepoTags: []string{"registry.k8s.io/pause:3.9"}, RepoDigests: []string{"registry.k8s.io/pause@" + pauseSum}, Size: "744000", Created: "2023-05-17T09:21:46Z"},
+		{ID: corednsID, RepoTags: []string{"registry.k8s.io/coredns/coredns:v1.10.1", "localhost:5000/coredns:dev"}, RepoDigests: []string{}, Size: "53600000"},
+	}
+	if diff := cmp.Diff(want, portoListImages(rows)); diff != "" {
+		t.Errorf("portoListImages diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// portoStoreFormat is an on-disk format of the layers and volumes porto keeps in portoPlace
+type portoStoreFormat struct {
+	// Since is the first porto release writing the format
+	Since semver.Version
+	// Format numbers the format, counting up from 1
+	Format int
+	// Migrate is the upstream tool shipped with Since which migrates a store of the previous format in place,
+	// run with the place as its last argument; empty when a store of the previous format has to be recreated
+	Migrate []string
+}
+
+// portoStoreFormats are the store formats of porto, oldest first. A release changing the format adds its entry here,
+// along with the migration tool it ships, so that nodes are migrated or refused rather than left to portod to fail on.
+var portoStoreFormats = []portoStoreFormat{
+	{Since: semver.MustParse("5.0.0"), Format: 1},
+}
+
+const (
+	// portoStoreFormatFile records the format of the store in the place itself, so that it goes wherever the store goes
+	portoStoreFormatFile = portoPlace + "/minikube-store-format"
+	// portoStoreBackup is where the store is copied to before it is migrated, until portod starts on the migrated one
+	portoStoreBackup = portoPlace + ".minikube-backup"
+)
+
+// portoStoreDirs are the directories of the place holding the store. The place is a store of porto 5, the format of
+// the first entry of portoStoreFormats, when they have content but no format is recorded: minikube only recorded it later.
+var portoStoreDirs = []string{portoPlace + "/porto_layers", portoPlace + "/porto_volumes", portoPlace + "/porto_storage"}
+
+// ErrPortoStore is the error returned when the store of porto on the node is in a format the installed portod can't use
+type ErrPortoStore struct {
+	// From is the format of the store
+	From int
+	// To is the format of the installed portod
+	To int
+	// Version is the version of the installed portod
+	Version string
+	// Err is the failure of the migration, which the store was restored from, or nil when there is no migration
+	Err error
+}
+
+func (e ErrPortoStore) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("migrating the porto store in %s from format %d to format %d of porto %s failed, it was restored from its backup: %v", portoPlace, e.From, e.To, e.Version, e.Err)
+	}
+	return fmt.Sprintf("the porto store in %s is in format %d, which porto %s can't migrate to its format %d", portoPlace, e.From, e.Version, e.To)
+}
+
+func (e ErrPortoStore) Unwrap() error {
+	return e.Err
+}
+
+// storeFormatFor returns the store format porto version writes
+func storeFormatFor(version string) (portoStoreFormat, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return portoStoreFormat{}, errors.Wrapf(err, "parsing porto version %q", version)
+	}
+	for i := len(portoStoreFormats) - 1; i >= 0; i-- {
+		if portoStoreFormats[i].Since.LTE(v) {
+			return portoStoreFormats[i], nil
+		}
+	}
+	return portoStoreFormat{}, fmt.Errorf("porto %s predates the known store formats", version)
+}
+
+// storeMigrations returns the migrations taking a store of format from to format to, one per format in between,
+// or an ErrPortoStore when one of them has no migration tool or the store is newer than to
+func storeMigrations(from, to int, version string) ([]portoStoreFormat, error) {
+	if from > to {
+		return nil, &ErrPortoStore{From: from, To: to, Version: version}
+	}
+	var steps []portoStoreFormat
+	for _, f := range portoStoreFormats {
+		if f.Format <= from || f.Format > to {
+			continue
+		}
+		if len(f.Migrate) == 0 {
+			return nil, &ErrPortoStore{From: from, To: to, Version: version}
+		}
+		steps = append(steps, f)
+	}
+	return steps, nil
+}
+
+// installedPortoVersion returns the version of the portod binary of the node, which may not be the one running
+// after an upgrade of the ISO or of porto in place
+func installedPortoVersion(cr CommandRunner) (string, error) {
+	rr, err := cr.RunCmd(exec.Command("portod", "--version"))
+	if err != nil {
+		return "", errors.Wrap(err, "portod --version")
+	}
+	return parsePortoVersion(rr.Stdout.String())
+}
+
+// storeFormat returns the format recorded for the store of the node, the first one for a store of before the record,
+// or 0 when there is no store yet
+func storeFormat(cr CommandRunner) (int, error) {
+	rr, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("cat %s 2>/dev/null; true", portoStoreFormatFile)))
+	if err != nil {
+		return 0, errors.Wrap(err, "reading the porto store format")
+	}
+	if s := strings.TrimSpace(rr.Stdout.String()); s != "" {
+		f, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, errors.Wrapf(err, "parsing the porto store format %q", s)
+		}
+		return f, nil
+	}
+	rr, err = cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", fmt.Sprintf("ls -A %s 2>/dev/null; true", strings.Join(portoStoreDirs, " "))))
+	if err != nil {
+		return 0, errors.Wrap(err, "listing the porto store")
+	}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		// ls names the directories it lists before their content
+		if line = strings.TrimSpace(line); line != "" && !strings.HasSuffix(line, ":") {
+			return portoStoreFormats[0].Format, nil
+		}
+	}
+	return 0, nil
+}
+
+// checkStoreMigration returns an ErrPortoStore when the store of the node can't be migrated to the format of porto
+// version, so that an upgrade can be refused before anything is replaced
+func checkStoreMigration(cr CommandRunner, version string) error {
+	to, err := storeFormatFor(version)
+	if err != nil {
+		return err
+	}
+	from, err := storeFormat(cr)
+	if err != nil || from == 0 {
+		return err
+	}
+	_, err = storeMigrations(from, to.Format, version)
+	return err
+}
+
+// recordStoreFormat records format as the format of the store of the node
+func recordStoreFormat(cr CommandRunner, format int) error {
+	c := fmt.Sprintf("mkdir -p %s && printf %d > %s", portoPlace, format, portoStoreFormatFile)
+	if _, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", c)); err != nil {
+		return errors.Wrap(err, "recording the porto store format")
+	}
+	return nil
+}
+
+// migrateStore migrates the store of the node to the format of the installed portod before portod starts on it,
+// as portod fails with errors which don't tell the store apart from anything else on a store of another format.
+// The store is backed up first and restored when a migration fails; it returns whether it migrated the store, whose
+// backup is kept until removeStoreBackup once portod runs on it. A store no migration tool can take to the new format
+// is refused with an ErrPortoStore, the node then having to be recreated.
+func (r *Porto) migrateStore() (bool, error) {
+	version, err := installedPortoVersion(r.Runner)
+	if err != nil {
+		return false, err
+	}
+	to, err := storeFormatFor(version)
+	if err != nil {
+		return false, err
+	}
+	from, err := storeFormat(r.Runner)
+	if err != nil {
+		return false, err
+	}
+	if from == to.Format {
+		return false, nil
+	}
+	if from == 0 {
+		// there is nothing to migrate, portod creates the store in its format
+		return false, recordStoreFormat(r.Runner, to.Format)
+	}
+	steps, err := storeMigrations(from, to.Format, version)
+	if err != nil {
+		return false, err
+	}
+	if len(steps) == 0 {
+		// the format was only not recorded yet
+		return false, recordStoreFormat(r.Runner, to.Format)
+	}
+
+	klog.Infof("migrating the porto store from format %d to %d of porto %s", from, to.Format, version)
+	if err := r.Init.Stop("portoshim"); err != nil {
+		klog.Warningf("failed to stop portoshim before the store migration: %v", err)
+	}
+	if err := r.Init.Stop("porto"); err != nil {
+		return false, errors.Wrap(err, "stopping porto")
+	}
+	// the place may be a filesystem of its own, so its content is copied rather than the directory moved
+	backup := fmt.Sprintf("rm -rf %[2]s && mkdir -p %[2]s && cp -a --reflink=auto %[1]s/. %[2]s/", portoPlace, portoStoreBackup)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", backup)); err != nil {
+		return false, errors.Wrapf(err, "backing up the porto store to %s before migrating it", portoStoreBackup)
+	}
+	for _, s := range steps {
+		args := append(append([]string{}, s.Migrate...), portoPlace)
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", args...)); err != nil {
+			if rerr := restoreStoreBackup(r.Runner); rerr != nil {
+				return false, errors.Wrapf(rerr, "after its migration failed with %v", err)
+			}
+			return false, &ErrPortoStore{From: from, To: to.Format, Version: version, Err: err}
+		}
+		if err := recordStoreFormat(r.Runner, s.Format); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// restoreStoreBackup puts the store back as it was before migrateStore, from the backup it made
+func restoreStoreBackup(cr CommandRunner) error {
+	restore := fmt.Sprintf("find %[1]s -mindepth 1 -maxdepth 1 -exec rm -rf {} + && cp -a %[2]s/. %[1]s/", portoPlace, portoStoreBackup)
+	if _, err := cr.RunCmd(exec.Command("sudo", "/bin/bash", "-c", restore)); err != nil {
+		return errors.Wrapf(err, "restoring the porto store from %s", portoStoreBackup)
+	}
+	return nil
+}
+
+// removeStoreBackup removes the backup migrateStore made, if any, now that portod runs on the migrated store
+func removeStoreBackup(cr CommandRunner) {
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-rf", portoStoreBackup)); err != nil {
+		klog.Warningf("failed to remove the porto store backup %s: %v", portoStoreBackup, err)
+	}
+}
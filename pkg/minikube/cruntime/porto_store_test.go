@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+// withStoreFormats replaces portoStoreFormats with a history of three formats for the duration of a test,
+// the second of which is migrated to by porto-store-migrate and the third of which has no migration
+func withStoreFormats(t *testing.T) {
+	saved := portoStoreFormats
+	t.Cleanup(func() { portoStoreFormats = saved })
+	portoStoreFormats = []portoStoreFormat{
+		{Since: semver.MustParse("5.0.0"), Format: 1},
+		{Since: semver.MustParse("6.0.0"), Format: 2, Migrate: []string{"porto-store-migrate", "--to", "2"}},
+		{Since: semver.MustParse("7.0.0"), Format: 3},
+	}
+}
+
+func TestStoreMigrations(t *testing.T) {
+	withStoreFormats(t)
+	tests := []struct {
+		version string
+		from    int
+		steps   int
+		refused bool
+	}{
+		{"5.3.31", 1, 0, false},
+		{"6.0.1-alpha.1", 1, 1, false},
+		{"6.1.0", 2, 0, false},
+		{"7.0.0", 2, 0, true},
+		{"7.0.0", 1, 0, true},
+		{"5.3.31", 2, 0, true},
+	}
+	for _, tc := range tests {
+		to, err := storeFormatFor(tc.version)
+		if err != nil {
+			t.Fatalf("storeFormatFor(%s): %v", tc.version, err)
+		}
+		steps, err := storeMigrations(tc.from, to.Format, tc.version)
+		var storeErr *ErrPortoStore
+		if refused := errors.As(err, &storeErr); refused != tc.refused {
+			t.Errorf("storeMigrations(%d, %s) = %v, want refused %v", tc.from, tc.version, err, tc.refused)
+		}
+		if len(steps) != tc.steps {
+			t.Errorf("storeMigrations(%d, %s) = %d steps, want %d", tc.from, tc.version, len(steps), tc.steps)
+		}
+	}
+	if _, err := storeFormatFor("4.18.0"); err == nil {
+		t.Errorf("storeFormatFor: expected an error for a porto predating the known formats")
+	}
+}
+
+func TestStoreFormat(t *testing.T) {
+	tests := []struct {
+		record string
+		store  string
+		want   int
+	}{
+		{"2\n", "", 2},
+		{"", "", 0},
+		{"", "/place/porto_layers:\n\n/place/porto_volumes:\n", 0},
+		{"", "/place/porto_layers:\nubuntu\n\n/place/porto_volumes:\n", 1},
+	}
+	for _, tc := range tests {
+		runner := command.NewFakeCommandRunner()
+		runner.SetCommandToOutput(map[string]string{
+			`sudo /bin/bash -c "cat /place/minikube-store-format 2>/dev/null; true"`:                                    tc.record,
+			`sudo /bin/bash -c "ls -A /place/porto_layers /place/porto_volumes /place/porto_storage 2>/dev/null; true"`: tc.store,
+		})
+		got, err := storeFormat(runner)
+		if err != nil || got != tc.want {
+			t.Errorf("storeFormat(%q, %q) = %d, %v, want %d", tc.record, tc.store, got, err, tc.want)
+		}
+	}
+}
+
+func TestMigrateStore(t *testing.T) {
+	withStoreFormats(t)
+	backup := `sudo /bin/bash -c "rm -rf /place.minikube-backup && mkdir -p /place.minikube-backup && cp -a --reflink=auto /place/. /place.minikube-backup/"`
+	restore := `sudo /bin/bash -c "find /place -mindepth 1 -maxdepth 1 -exec rm -rf {} + && cp -a /place.minikube-backup/. /place/"`
+	cmds := func(version string) map[string]string {
+		return map[string]string{
+			"portod --version": "version: " + version + "  /usr/sbin/portod",
+			`sudo /bin/bash -c "cat /place/minikube-store-format 2>/dev/null; true"`: "1",
+			"systemctl --version":           "systemd 252",
+			"sudo systemctl stop portoshim": "",
+			"sudo systemctl stop porto":     "",
+			"sudo service portoshim stop":   "",
+			"sudo service porto stop":       "",
+			"sudo systemctl daemon-reload":  "",
+			backup:                          "",
+			restore:                         "",
+			`sudo /bin/bash -c "mkdir -p /place && printf 2 > /place/minikube-store-format"`: "",
+		}
+	}
+
+	runner := command.NewFakeCommandRunner()
+	c := cmds("6.0.0")
+	c["sudo porto-store-migrate --to 2 /place"] = ""
+	runner.SetCommandToOutput(c)
+	r := &Porto{Runner: runner, Init: sysinit.New(runner)}
+	if migrated, err := r.migrateStore(); err != nil || !migrated {
+		t.Errorf("migrateStore to porto 6 = %v, %v, want a migration", migrated, err)
+	}
+
+	// the migration tool fails
+	runner = command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(cmds("6.0.0"))
+	r = &Porto{Runner: runner, Init: sysinit.New(runner)}
+	var storeErr *ErrPortoStore
+	if _, err := r.migrateStore(); !errors.As(err, &storeErr) || storeErr.Err == nil {
+		t.Errorf("migrateStore with a failing migration = %v, want an ErrPortoStore of the failure", err)
+	}
+
+	// nothing migrates a store of format 1 to format 3, so it is refused without touching it
+	runner = command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"portod --version": "version: 7.0.0  /usr/sbin/portod",
+		`sudo /bin/bash -c "cat /place/minikube-store-format 2>/dev/null; true"`: "1",
+	})
+	r = &Porto{Runner: runner, Init: sysinit.New(runner)}
+	if _, err := r.migrateStore(); !errors.As(err, &storeErr) || storeErr.Err != nil {
+		t.Errorf("migrateStore to porto 7 = %v, want it refused", err)
+	}
+	if err := checkStoreMigration(runner, "7.0.0"); !errors.As(err, &storeErr) {
+		t.Errorf("checkStoreMigration to porto 7 = %v, want it refused", err)
+	}
+}
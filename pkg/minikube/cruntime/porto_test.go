@@ -0,0 +1,1133 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/localpath"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+const (
+	procCgroups = `#subsys_name	hierarchy	num_cgroups	enabled
+cpuset	0	97	1
+cpu	0	97	1
+cpuacct	0	97	1
+blkio	0	97	1
+memory	0	97	1
+devices	0	97	1
+freezer	0	97	1
+net_cls	0	97	1
+perf_event	0	97	1
+hugetlb	0	97	1
+pids	0	97	1
+`
+	procFilesystems = `nodev	sysfs
+nodev	tmpfs
+nodev	proc
+nodev	cgroup2
+nodev	overlay
+	ext4
+`
+)
+
+func TestParsePortoVersion(t *testing.T) {
+	var tests = []struct {
+		version string
+		want    string
+	}{
+		{"version: 5.3.30-alpha.7  /usr/sbin/portod", "5.3.30-alpha.7"},
+		{"running: 5.3.31  /usr/sbin/portod", "5.3.31"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			got, err := parsePortoVersion(tc.version)
+			if err != nil {
+				t.Fatalf("parse(%s): %v", tc.version, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected version to be: %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckPortoKernel(t *testing.T) {
+	var tests = []struct {
+		description string
+		cgroups     string
+		filesystems string
+		modprobe    bool
+		want        *ErrKernelPrerequisites
+	}{
+		{"Supported", procCgroups, procFilesystems, true, nil},
+		{"NoModules", procCgroups, procFilesystems, false, &ErrKernelPrerequisites{
+			Runtime:        "porto",
+			MissingModules: portoKernelModules,
+		}},
+		{"NoFreezer", "#subsys_name	hierarchy	num_cgroups	enabled\ncpu	0	1	1\ncpuacct	0	1	1\ncpuset	0	1	1\nmemory	0	1	1\ndevices	0	1	1\nfreezer	0	1	0\npids	0	1	1\nblkio	0	1	1\n", procFilesystems, true, &ErrKernelPrerequisites{
+			Runtime:            "porto",
+			MissingControllers: []string{"freezer"},
+		}},
+		{"NoOverlay", procCgroups, "nodev	sysfs\n	ext4\n", true, &ErrKernelPrerequisites{
+			Runtime:     "porto",
+			NoOverlayFS: true,
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(map[string]string{
+				"cat /proc/cgroups":     tc.cgroups,
+				"cat /proc/filesystems": tc.filesystems,
+			})
+			if tc.modprobe {
+				for _, m := range portoKernelModules {
+					runner.SetCommandToOutput(map[string]string{"sudo modprobe " + m: ""})
+				}
+			}
+
+			err := CheckPortoKernel(runner)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("CheckPortoKernel: unexpected error: %v", err)
+				}
+				return
+			}
+			var got ErrKernelPrerequisites
+			if !errors.As(err, &got) {
+				t.Fatalf("CheckPortoKernel: expected ErrKernelPrerequisites, got %v", err)
+			}
+			if diff := cmp.Diff(*tc.want, got); diff != "" {
+				t.Errorf("CheckPortoKernel returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPortoPullImages(t *testing.T) {
+	imgs := []string{"registry.k8s.io/pause:3.9", "registry.k8s.io/etcd:3.5.9-0", "registry.k8s.io/coredns/coredns:v1.10.1"}
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"which crictl": "/usr/bin/crictl",
+		"sudo /usr/bin/crictl pull registry.k8s.io/pause:3.9":               "",
+		"sudo /usr/bin/crictl pull registry.k8s.io/coredns/coredns:v1.10.1": "",
+	})
+	r := &Porto{Runner: runner}
+
+	err := r.pullImages(imgs, 2, nil)
+	if err == nil {
+		t.Fatalf("pullImages: expected an error for the image that failed to pull")
+	}
+	if !strings.Contains(err.Error(), "failed to pull 1 of 3 images") || !strings.Contains(err.Error(), "registry.k8s.io/etcd:3.5.9-0") {
+		t.Errorf("pullImages: unexpected error: %v", err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{"sudo /usr/bin/crictl pull registry.k8s.io/etcd:3.5.9-0": ""})
+	if err := r.pullImages(imgs, 0, nil); err != nil {
+		t.Errorf("pullImages: unexpected error: %v", err)
+	}
+}
+
+func TestMissingPortoBinaries(t *testing.T) {
+	// an ISO with porto but without portoshim and portoinit
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo which portod":   "/sbin/portod",
+		"sudo which portoctl": "/sbin/portoctl",
+	})
+	if diff := cmp.Diff([]string{"portoinit", "portoshim"}, MissingPortoBinaries(runner)); diff != "" {
+		t.Errorf("MissingPortoBinaries returned diff (-want +got):\n%s", diff)
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		"sudo which portoinit": "/sbin/portoinit",
+		"sudo which portoshim": "/sbin/portoshim",
+	})
+	if missing := MissingPortoBinaries(runner); len(missing) != 0 {
+		t.Errorf("MissingPortoBinaries = %v, want none", missing)
+	}
+}
+
+func TestPortoshimCompatible(t *testing.T) {
+	var tests = []struct {
+		description string
+		version     string
+		api         string
+		k8s         string
+		want        *ErrCRIVersion
+	}{
+		{"Supported", "v1.0.11-alpha.11", "v1", "1.31.0", nil},
+		{"OldKubernetes", "v1.0.9", "v1", "1.28.4", nil},
+		{"NoEvents", "v1.0.9", "v1", "1.31.0", &ErrCRIVersion{
+			Runtime:           "portoshim",
+			Installed:         "v1.0.9",
+			Required:          "1.0.10",
+			KubernetesVersion: "1.31.0",
+		}},
+		{"V1Alpha2", "v1.0.9", "v1alpha2", "1.28.4", &ErrCRIVersion{
+			Runtime:           "portoshim",
+			Installed:         "v1.0.9 (CRI API v1alpha2)",
+			Required:          "CRI API v1",
+			KubernetesVersion: "1.28.4",
+			Fatal:             true,
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			out := "Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  " + tc.version + "\nRuntimeApiVersion:  " + tc.api + "\n"
+			err := portoshimCompatible(parseCRIVersion(out), semver.MustParse(tc.k8s))
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("portoshimCompatible: unexpected error: %v", err)
+				}
+				return
+			}
+			var got *ErrCRIVersion
+			if !errors.As(err, &got) {
+				t.Fatalf("portoshimCompatible: expected ErrCRIVersion, got %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("portoshimCompatible returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPortoSandboxImageFromCache(t *testing.T) {
+	t.Setenv("MINIKUBE_HOME", t.TempDir())
+	kv := semver.MustParse("1.30.0")
+	cached := localpath.SanitizeCacheDir(filepath.Join(detect.ImageCacheDir(), PortoSandboxImage(kv, "")))
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		t.Fatalf("creating image cache: %v", err)
+	}
+	if err := os.WriteFile(cached, []byte("image"), 0644); err != nil {
+		t.Fatalf("writing cached image: %v", err)
+	}
+
+	// no crictl pull is registered, so the image must come from the cache
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-images":                                  "",
+		"sudo portoctl docker-load /var/lib/minikube/images/pause_3.9": "",
+	})
+	r := &Porto{Runner: runner, KubernetesVersion: kv}
+	if err := r.ensureSandboxImage(); err != nil {
+		t.Errorf("ensureSandboxImage: %v", err)
+	}
+}
+
+func TestPortoLoadImageRetags(t *testing.T) {
+	tarball := "/var/lib/minikube/staging/load-1/busybox_latest"
+	id := "3f57d9401f8d42f986df300f0c69192fc41da28ccc8d797829467780db3dd741"
+	load := map[string]string{
+		"sudo portoctl docker-load " + tarball:        "",
+		"sudo tar -xOf " + tarball + " manifest.json": `[{"Config":"sha256:` + id + `","RepoTags":["busybox:latest"],"Layers":["1.tar.gz"]}]`,
+	}
+
+	var tests = []struct {
+		description string
+		images      string
+		tag         bool
+		wantErr     bool
+	}{
+		{"Untagged", "", true, false},
+		{"AlreadyTagged", "ID   NAME\n" + id + "   busybox:latest\n", false, false},
+		{"TagFails", "", false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(load)
+			runner.SetCommandToOutput(map[string]string{"sudo portoctl docker-images": tc.images})
+			if tc.tag {
+				runner.SetCommandToOutput(map[string]string{"sudo portoctl docker-tag " + id + " busybox:latest": ""})
+			}
+			r := &Porto{Runner: runner}
+			err := r.LoadImage(context.Background(), tarball)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("LoadImage: got error %v, want error: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadTmpfsSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "false", want: 0},
+		{value: "true", want: 1536},
+		{value: "TRUE", want: 1536},
+		{value: "2g", want: 2048},
+		{value: "512", want: 512},
+		{value: "0", wantErr: true},
+		{value: "lots", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := DownloadTmpfsSize(tc.value, 6144)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("DownloadTmpfsSize(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("DownloadTmpfsSize(%q) = %d, want %d", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseUlimits(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "nofile=1048576", want: "nofile: 1048576 1048576"},
+		{value: "nofile=1024:1048576, nproc=unlimited", want: "nofile: 1024 1048576; nproc: unlimited unlimited"},
+		{value: "nofile", wantErr: true},
+		{value: "files=1024", wantErr: true},
+		{value: "nofile=lots", wantErr: true},
+		{value: "nofile=1024:-1", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseUlimits(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseUlimits(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseUlimits(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAllowedUnsafeSysctls(t *testing.T) {
+	opts := config.ExtraOptionSlice{
+		{Component: "porto", Key: PortoAllowedUnsafeSysctlsOption, Value: "net.core.somaxconn,kernel.msgmax"},
+		{Component: "kubelet", Key: PortoAllowedUnsafeSysctlsOption, Value: "kernel.msgmax, net.ipv4.tcp_syncookies"},
+	}
+	want := []string{"kernel.msgmax", "net.core.somaxconn", "net.ipv4.tcp_syncookies"}
+	if diff := cmp.Diff(want, AllowedUnsafeSysctls(opts)); diff != "" {
+		t.Errorf("AllowedUnsafeSysctls returned diff (-want +got):\n%s", diff)
+	}
+	if got := AllowedUnsafeSysctls(nil); len(got) != 0 {
+		t.Errorf("AllowedUnsafeSysctls(nil) = %v, want none", got)
+	}
+}
+
+// stdinRunner reads the stdin of the commands it runs, like portoctl docker-load does
+type stdinRunner struct {
+	*command.FakeCommandRunner
+}
+
+func (r stdinRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	if cmd.Stdin != nil {
+		if _, err := io.Copy(io.Discard, cmd.Stdin); err != nil {
+			return nil, err
+		}
+	}
+	return r.FakeCommandRunner.RunCmd(cmd)
+}
+
+func TestPortoLoadImageStream(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-load /dev/stdin": "",
+	})
+	r := &Porto{Runner: stdinRunner{runner}}
+	// a stream without a manifest is loaded, but not retagged
+	if err := r.LoadImageStream("example.com/app:dev", strings.NewReader("tarball")); err != nil {
+		t.Errorf("LoadImageStream: %v", err)
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	manifest := `[{"Config":"sha256:0123abcd","RepoTags":["example.com/app:dev"]}]`
+	for _, f := range []struct{ name, body string }{{"0123abcd.json", "{}"}, {"manifest.json", manifest}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body))}); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// docker-load did not keep the tag, so it is restored from the manifest of the stream
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-images":                           "ID   NAME\n",
+		"sudo portoctl docker-tag 0123abcd example.com/app:dev": "",
+	})
+	if err := r.LoadImageStream("example.com/app:dev", &archive); err != nil {
+		t.Errorf("LoadImageStream: %v", err)
+	}
+}
+
+func TestPortoRetagMirrorImages(t *testing.T) {
+	const mirror = "mirror.example.com/k8s"
+	mirrored, err := images.Kubeadm(mirror, "v1.30.0")
+	if err != nil {
+		t.Fatalf("images.Kubeadm: %v", err)
+	}
+	canonical, err := images.Kubeadm("", "v1.30.0")
+	if err != nil {
+		t.Fatalf("images.Kubeadm: %v", err)
+	}
+
+	runner := command.NewFakeCommandRunner()
+	// the pause image is already known by its canonical name
+	pause := PortoSandboxImage(semver.MustParse("1.30.0"), "")
+	runner.SetCommandToOutput(map[string]string{"sudo portoctl docker-images": "ID   NAME\nsha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c   " + pause + "\n"})
+	tags := map[string]string{}
+	for i := range mirrored {
+		if canonical[i] != pause {
+			tags["sudo portoctl docker-tag "+mirrored[i]+" "+canonical[i]] = ""
+		}
+	}
+	runner.SetCommandToOutput(tags)
+
+	r := &Porto{Runner: runner}
+	if err := r.retagMirrorImages(mirror, "v1.30.0", mirrored); err != nil {
+		t.Errorf("retagMirrorImages: %v", err)
+	}
+	if err := r.retagMirrorImages("", "v1.30.0", canonical); err != nil {
+		t.Errorf("retagMirrorImages without a mirror: %v", err)
+	}
+	if err := r.retagMirrorImages(mirror, "v1.30.0", mirrored[1:]); err == nil {
+		t.Errorf("retagMirrorImages: expected an error for mismatched image lists")
+	}
+
+	// a canonical name tagging another digest than the mirrored image is moved to it, the others are left alone
+	var listed strings.Builder
+	listed.WriteString("ID   NAME\n")
+	for i := range mirrored {
+		id := fmt.Sprintf("sha256:%064d", i)
+		fmt.Fprintf(&listed, "%s   %s\n", id, mirrored[i])
+		if i == 0 {
+			id = fmt.Sprintf("sha256:%064d", 99)
+		}
+		fmt.Fprintf(&listed, "%s   %s\n", id, canonical[i])
+	}
+	stale := "sudo portoctl docker-tag " + mirrored[0] + " " + canonical[0]
+	rec := &recordingRunner{FakeCommandRunner: command.NewFakeCommandRunner()}
+	rec.SetCommandToOutput(map[string]string{"sudo portoctl docker-images": listed.String(), stale: ""})
+	r = &Porto{Runner: rec}
+	if err := r.retagMirrorImages(mirror, "v1.30.0", mirrored); err != nil {
+		t.Errorf("retagMirrorImages with a stale canonical tag: %v", err)
+	}
+	if !slices.Contains(rec.cmds, stale) {
+		t.Errorf("retagMirrorImages did not move the stale canonical tag, ran %v", rec.cmds)
+	}
+}
+
+// recordingRunner records the commands run by a FakeCommandRunner
+type recordingRunner struct {
+	*command.FakeCommandRunner
+	cmds []string
+}
+
+func (r *recordingRunner) RunCmd(cmd *exec.Cmd) (*command.RunResult, error) {
+	r.cmds = append(r.cmds, strings.Join(cmd.Args, " "))
+	return r.FakeCommandRunner.RunCmd(cmd)
+}
+
+func TestPortoConfigureCgroupNamespaces(t *testing.T) {
+	var tests = []struct {
+		description     string
+		available       bool
+		inUserNamespace bool
+		wantErr         bool
+		wantConf        string
+	}{
+		{"Available", true, false, false, "use_os_mode_cgroupns: true"},
+		{"AvailableRootless", true, true, false, "use_os_mode_cgroupns: true"},
+		{"Unavailable", false, false, false, "use_os_mode_cgroupns: false"},
+		{"UnavailableRootless", false, true, true, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			runner := command.NewFakeCommandRunner()
+			runner.SetCommandToOutput(map[string]string{"sudo mkdir -p /etc/portod.conf.d": ""})
+			if tc.available {
+				runner.SetCommandToOutput(map[string]string{"sudo unshare --cgroup true": ""})
+			}
+			r := &Porto{Runner: runner}
+			err := r.configureCgroupNamespaces(tc.inUserNamespace)
+			if tc.wantErr {
+				var nsErr *ErrCgroupNamespaces
+				if !errors.As(err, &nsErr) {
+					t.Fatalf("configureCgroupNamespaces: expected ErrCgroupNamespaces, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configureCgroupNamespaces: %v", err)
+			}
+			conf, err := runner.GetFileToContents(assets.MemorySource)
+			if err != nil {
+				t.Fatalf("%s was not written: %v", portoCgroupNSConf, err)
+			}
+			if !strings.Contains(conf, tc.wantConf) {
+				t.Errorf("%s = %q, want it to contain %q", portoCgroupNSConf, conf, tc.wantConf)
+			}
+		})
+	}
+}
+
+func TestGeneratePortoConfig(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p /etc/portod.conf.d": "",
+		"sudo rm -f " + portoFeaturesConf:  "",
+	})
+
+	gates, err := ParseFeatureGates("sandbox-checkpointing=true,shared-layer-cache=true")
+	if err != nil {
+		t.Fatalf("ParseFeatureGates: %v", err)
+	}
+	if err := generatePortoConfig(runner, gates, ""); err != nil {
+		t.Fatalf("generatePortoConfig: %v", err)
+	}
+	conf, err := runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("%s was not written: %v", portoFeaturesConf, err)
+	}
+	want := portoFeatureConfig[FeatureSandboxCheckpointing] + portoFeatureConfig[FeatureSharedLayerCache]
+	if conf != want {
+		t.Errorf("%s = %q, want %q", portoFeaturesConf, conf, want)
+	}
+
+	// without enabled gates the configuration is removed, which the fake runner only allows by its command
+	if err := generatePortoConfig(command.NewFakeCommandRunner(), FeatureGates{}, ""); err == nil {
+		t.Errorf("generatePortoConfig: expected the configuration to be removed")
+	}
+	if err := generatePortoConfig(runner, FeatureGates{}, ""); err != nil {
+		t.Errorf("generatePortoConfig without gates: %v", err)
+	}
+
+	// the registry certificates alone still need the configuration
+	if err := generatePortoConfig(runner, FeatureGates{}, "/etc/registry-certs"); err != nil {
+		t.Fatalf("generatePortoConfig with registry certificates: %v", err)
+	}
+	conf, err = runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("%s was not written: %v", portoFeaturesConf, err)
+	}
+	if want := "images {\n  registry_certs_dir: \"/etc/registry-certs\"\n}\n"; conf != want {
+		t.Errorf("%s = %q, want %q", portoFeaturesConf, conf, want)
+	}
+}
+
+func TestRegistryCertsDir(t *testing.T) {
+	tests := []struct {
+		value       string
+		mountString string
+		want        string
+		wantErr     bool
+	}{
+		{value: "", want: ""},
+		{value: "false", mountString: "/home/user/certs.d:/mnt/certs.d", want: ""},
+		{value: "true", mountString: "/home/user/certs.d:/mnt/certs.d", want: "/mnt/certs.d"},
+		{value: "true", mountString: `C:\Users\user\certs.d:/mnt/certs.d`, want: "/mnt/certs.d"},
+		{value: "true", wantErr: true},
+		{value: "/mnt/certs/registry.d/", want: "/mnt/certs/registry.d"},
+		{value: "certs.d", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value+"/"+tc.mountString, func(t *testing.T) {
+			got, err := RegistryCertsDir(tc.value, tc.mountString)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("RegistryCertsDir(%q, %q) error = %v, wantErr %v", tc.value, tc.mountString, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("RegistryCertsDir(%q, %q) = %q, want %q", tc.value, tc.mountString, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPortoGenerateLimitsConfig(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p /etc/portod.conf.d": "",
+		"sudo rm -f " + portoLimitsConf:    "",
+	})
+
+	r := &Porto{Runner: runner, Ulimits: "nofile: 1048576 1048576", AllowedUnsafeSysctls: []string{"kernel.msgmax", "net.core.somaxconn"}}
+	if err := r.generateLimitsConfig(); err != nil {
+		t.Fatalf("generateLimitsConfig: %v", err)
+	}
+	conf, err := runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("%s was not written: %v", portoLimitsConf, err)
+	}
+	want := "container {\n  default_ulimit: \"nofile: 1048576 1048576\"\n  allowed_sysctls: \"kernel.msgmax;net.core.somaxconn\"\n}\n"
+	if conf != want {
+		t.Errorf("%s = %q, want %q", portoLimitsConf, conf, want)
+	}
+
+	// without limits the configuration is removed, which the fake runner only allows by its command
+	if err := (&Porto{Runner: command.NewFakeCommandRunner()}).generateLimitsConfig(); err == nil {
+		t.Errorf("generateLimitsConfig: expected the configuration to be removed")
+	}
+	if err := (&Porto{Runner: runner}).generateLimitsConfig(); err != nil {
+		t.Errorf("generateLimitsConfig without limits: %v", err)
+	}
+}
+
+func TestPortoConfigurePortoshim(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p /etc/systemd/system/portoshim.service.d": "",
+	})
+	r := &Porto{Runner: runner, KubernetesVersion: semver.MustParse("1.30.0"), ImageRepository: "mirror.example.com/k8s"}
+	if err := r.configurePortoshim(); err != nil {
+		t.Fatalf("configurePortoshim: %v", err)
+	}
+	conf, err := runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("%s was not written: %v", portoshimDropIn, err)
+	}
+	want := []string{
+		"PORTOSHIM_STREAMING_ADDRESS=127.0.0.1\n",
+		"PORTOSHIM_STREAMING_PORT=10010\n",
+		// the pause image of kubeadm for the version, from the mirror
+		"PORTOSHIM_SANDBOX_IMAGE=mirror.example.com/k8s/pause:3.9\n",
+		`"PORTOSHIM_RUNTIME_HANDLERS=porto-container:virt_mode=app;isolate=true,porto-vm:virt_mode=os;isolate=true"`,
+	}
+	for _, want := range want {
+		if !strings.Contains(conf, want) {
+			t.Errorf("%s = %q, want it to contain %q", portoshimDropIn, conf, want)
+		}
+	}
+}
+
+func TestPortoVerifySockets(t *testing.T) {
+	const socket = "/run/custom-portoshim.sock"
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo test -S /run/portod.socket": "",
+		"sudo portoctl list":              "",
+		"sudo test -S " + socket:          "",
+		"which crictl":                    "/usr/bin/crictl",
+	})
+
+	// crictl can't reach portoshim
+	r := &Porto{Runner: runner, Socket: socket}
+	err := r.verifySockets()
+	if err == nil || !strings.Contains(err.Error(), "crictl can't reach portoshim on "+socket) {
+		t.Errorf("verifySockets = %v, want an error naming the portoshim socket", err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{"sudo /usr/bin/crictl version": "RuntimeName:  portoshim\nRuntimeApiVersion:  v1\n"})
+	if err := r.verifySockets(); err != nil {
+		t.Errorf("verifySockets: %v", err)
+	}
+}
+
+func TestPortoKubeletOptions(t *testing.T) {
+	var tests = []struct {
+		description string
+		gates       FeatureGates
+		sysctls     []string
+		want        map[string]string
+	}{
+		{"Default", nil, nil, map[string]string{
+			"kube-reserved": portoKubeReserved,
+		}},
+		{"MetaContainer", FeatureGates{FeatureKubeletMetaContainer: true}, nil, map[string]string{
+			"kube-reserved": portoMetaContainerReserved,
+		}},
+		{"UnsafeSysctls", nil, []string{"kernel.msgmax", "net.core.somaxconn"}, map[string]string{
+			"kube-reserved":          portoKubeReserved,
+			"allowed-unsafe-sysctls": "kernel.msgmax,net.core.somaxconn",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			r := &Porto{Runner: command.NewFakeCommandRunner(), KubernetesVersion: semver.MustParse("1.30.0"), FeatureGates: tc.gates, AllowedUnsafeSysctls: tc.sysctls}
+			want := map[string]string{
+				"container-runtime-endpoint": "unix:///run/portoshim.sock",
+				"system-reserved":            portoSystemReserved,
+			}
+			for k, v := range tc.want {
+				want[k] = v
+			}
+			if diff := cmp.Diff(want, r.KubeletOptions()); diff != "" {
+				t.Errorf("KubeletOptions returned diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPortoEnsureMetaContainer(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl create minikube":                    "",
+		"sudo portoctl set minikube memory_guarantee 512M": "",
+		"sudo portoctl set minikube cpu_guarantee 0.1c":    "",
+		"sudo portoctl start minikube":                     "",
+	})
+	r := &Porto{Runner: runner}
+	if err := r.ensureMetaContainer(); err != nil {
+		t.Fatalf("ensureMetaContainer: %v", err)
+	}
+
+	// an existing meta-container is left alone
+	runner = command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{"sudo portoctl get minikube state": "meta"})
+	r = &Porto{Runner: runner}
+	if err := r.ensureMetaContainer(); err != nil {
+		t.Errorf("ensureMetaContainer with an existing meta-container: %v", err)
+	}
+}
+
+func TestPortoCheckpointContainer(t *testing.T) {
+	const dir = "/var/lib/minikube/checkpoints/abc"
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl list -1":                    "pod1\npod1/abc\npod2\npod2/def\n",
+		"sudo portoctl checkpoint pod1/abc " + dir: "",
+		"sudo portoctl stop pod2/def":              "",
+		"sudo portoctl restore pod2/def " + dir:    "",
+	})
+
+	r := &Porto{Runner: runner}
+	if err := r.CheckpointContainer("abc", dir); err == nil || !strings.Contains(err.Error(), FeatureSandboxCheckpointing) {
+		t.Errorf("CheckpointContainer without the feature gate = %v, want an error naming it", err)
+	}
+
+	r.FeatureGates = FeatureGates{FeatureSandboxCheckpointing: true}
+	if err := r.CheckpointContainer("abc", dir); err != nil {
+		t.Errorf("CheckpointContainer: %v", err)
+	}
+	if err := r.RestoreContainer("def", dir); err != nil {
+		t.Errorf("RestoreContainer: %v", err)
+	}
+}
+
+func TestPortoPrefetchImages(t *testing.T) {
+	imgs := []string{"docker.io/istio/pilot:1.22.1", "docker.io/istio/proxyv2:1.22.1"}
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		// the proxy is already present, so only the pilot is pulled
+		"sudo portoctl docker-images": "ID                                                                      NAME\n" +
+			"sha256:3c2a4ff32d0ea6d8b3d0b3c5a7a5b3d7f8bb4e5a7bdbd4e0a1c8a8d1b6f6f0a1 docker.io/istio/proxyv2:1.22.1\n",
+		"which crictl": "/usr/bin/crictl",
+		"sudo /usr/bin/crictl pull docker.io/istio/pilot:1.22.1": "",
+	})
+	r := &Porto{Runner: runner}
+
+	var pulled []string
+	progress := func(done, total int, image string, err error) {
+		if err != nil {
+			t.Errorf("pulling %s: %v", image, err)
+		}
+		if total != 1 {
+			t.Errorf("got %d images to pull, want 1", total)
+		}
+		pulled = append(pulled, image)
+	}
+	if err := r.PrefetchImages(imgs, 2, progress); err != nil {
+		t.Errorf("PrefetchImages: %v", err)
+	}
+	if len(pulled) != 1 || pulled[0] != imgs[0] {
+		t.Errorf("pulled %v, want [%s]", pulled, imgs[0])
+	}
+}
+
+func TestPortoImagesPreloaded(t *testing.T) {
+	const digest = "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c"
+	listed := []*runtimeapi.Image{
+		{Id: "1", RepoTags: []string{"registry.k8s.io/pause:3.9"}},
+		{Id: "2", RepoTags: []string{"docker.io/kindest/kindnetd:v20230809-80a64d96"}, RepoDigests: []string{"docker.io/kindest/kindnetd@" + digest}},
+	}
+	tests := []struct {
+		images []string
+		want   bool
+	}{
+		{[]string{"registry.k8s.io/pause:3.9", "kindest/kindnetd:v20230809-80a64d96"}, true},
+		{[]string{"kindest/kindnetd:v20230809-80a64d96@" + digest}, true},
+		{[]string{"kindest/kindnetd@" + digest}, true},
+		{[]string{"kindest/kindnetd:v20230809-80a64d96@sha256:0000000000000000000000000000000000000000000000000000000000000000"}, false},
+		{[]string{"registry.k8s.io/pause:3.9", "registry.k8s.io/etcd:3.5.10-0"}, false},
+	}
+
+	socket := serveFakeCRI(t, &fakeImageService{images: listed}, &fakeRuntimeService{})
+
+	// the fake runner can't dial sockets, so it falls back to crictl
+	crictl := command.NewFakeCommandRunner()
+	out, err := json.Marshal(map[string]interface{}{"images": []map[string]interface{}{
+		{"id": "1", "repoTags": listed[0].RepoTags},
+		{"id": "2", "repoTags": listed[1].RepoTags, "repoDigests": listed[1].RepoDigests},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crictl.SetCommandToOutput(map[string]string{"sudo crictl images --output json": string(out)})
+
+	runners := map[string]command.Runner{"grpc": command.NewExecRunner(false), "crictl": crictl}
+	for name, runner := range runners {
+		for _, tc := range tests {
+			if got := portoImagesPreloaded(runner, socket, tc.images); got != tc.want {
+				t.Errorf("%s: portoImagesPreloaded(%v) = %t, want %t", name, tc.images, got, tc.want)
+			}
+		}
+	}
+}
+
+// fakePortod answers porto API requests on conn with the response for the field set in them
+func fakePortod(t *testing.T, conn net.Conn, responses map[protowire.Number][]byte) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Errorf("read request: %v", err)
+			return
+		}
+		num, _, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			t.Errorf("decode request: %v", protowire.ParseError(n))
+			return
+		}
+		resp, ok := responses[num]
+		if !ok {
+			// EError::InvalidMethod
+			resp = appendPortoString(protowire.AppendVarint(protowire.AppendTag(nil, portoRespError, protowire.VarintType), 3), portoRespErrorMsg, "invalid method")
+		}
+		if _, err := conn.Write(protowire.AppendBytes(nil, resp)); err != nil {
+			t.Errorf("write response: %v", err)
+			return
+		}
+	}
+}
+
+func TestPortoClient(t *testing.T) {
+	appendMessage := func(b []byte, field protowire.Number, m []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(b, field, protowire.BytesType), m)
+	}
+	image := appendPortoString(nil, portoImageID, "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c")
+	image = appendPortoString(image, portoImageTags, "registry.k8s.io/pause:3.9")
+	image = appendPortoString(image, portoImageDigests, "registry.k8s.io/pause@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097")
+	image = protowire.AppendVarint(protowire.AppendTag(image, portoImageSize, protowire.VarintType), 321520)
+	image = protowire.AppendVarint(protowire.AppendTag(image, portoImageCreated, protowire.VarintType), 1684315306)
+
+	server, client := net.Pipe()
+	go fakePortod(t, server, map[protowire.Number][]byte{
+		portoReqVersion:           appendMessage(nil, portoRespVersion, appendPortoString(nil, portoVersionTag, "5.3.30")),
+		portoReqDockerImageList:   appendMessage(nil, portoRespDockerImageList, appendMessage(nil, portoImageListImages, image)),
+		portoReqDockerImageStatus: appendMessage(nil, portoRespDockerImageStatus, appendMessage(nil, portoImageStatusImage, image)),
+	})
+	c := &portoClient{conn: client, r: bufio.NewReader(client), ctx: context.Background()}
+	defer c.Close()
+
+	v, err := c.Version()
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if v != "5.3.30" {
+		t.Errorf("Version = %q, want %q", v, "5.3.30")
+	}
+
+	want := ListImage{
+		ID:          "sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c",
+		RepoTags:    []string{"registry.k8s.io/pause:3.9"},
+		RepoDigests: []string{"registry.k8s.io/pause@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097"},
+		Size:        "321520",
+		Created:     "2023-05-17T09:21:46Z",
+	}
+	imgs, err := c.ListImages()
+	if err != nil {
+		t.Fatalf("ListImages: %v", err)
+	}
+	if diff := cmp.Diff([]ListImage{want}, imgs); diff != "" {
+		t.Errorf("ListImages mismatch (-want +got):\n%s", diff)
+	}
+	img, err := c.ImageStatus("registry.k8s.io/pause:3.9")
+	if err != nil {
+		t.Fatalf("ImageStatus: %v", err)
+	}
+	if diff := cmp.Diff(want, img); diff != "" {
+		t.Errorf("ImageStatus mismatch (-want +got):\n%s", diff)
+	}
+
+	var apiErr *PortoAPIError
+	if err := c.RemoveImage("registry.k8s.io/pause:3.9"); !errors.As(err, &apiErr) || apiErr.Code != 3 {
+		t.Errorf("RemoveImage: expected porto error 3, got %v", err)
+	}
+}
+
+func TestBuildCacheSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: DefaultBuildCacheMB},
+		{value: "false", want: 0},
+		{value: "0", want: 0},
+		{value: "20g", want: 20480},
+		{value: "512", want: 512},
+		{value: "-1", wantErr: true},
+		{value: "lots", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := BuildCacheSize(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("BuildCacheSize(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("BuildCacheSize(%q) = %d, want %d", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPortoBuildCacheArgs(t *testing.T) {
+	export := []string{"--export-cache", "type=local,dest=/var/lib/minikube/build-cache,mode=max"}
+	tests := []struct {
+		name string
+		size int
+		opts []string
+		want []string
+	}{
+		{name: "disabled", size: 0, want: nil},
+		{name: "cached", size: 1024, opts: []string{"opt=build-arg:A=1"}, want: append(export, "--import-cache", "type=local,src=/var/lib/minikube/build-cache")},
+		{name: "no-cache", size: 1024, opts: []string{"no-cache"}, want: export},
+		{name: "no-cache=true", size: 1024, opts: []string{"no-cache=true"}, want: export},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := (&Porto{BuildCacheMB: tc.size}).buildCacheArgs(tc.opts)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("buildCacheArgs(%q) returned diff (-want +got):\n%s", tc.opts, diff)
+			}
+		})
+	}
+}
+
+func TestPortoBuildCache(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p /var/lib/minikube/build-cache": "",
+		"sudo rm -rf /var/lib/minikube/build-cache":   "",
+		"sudo du -sm /var/lib/minikube/build-cache":   "2049\t/var/lib/minikube/build-cache\n",
+	})
+
+	if err := (&Porto{Runner: runner, BuildCacheMB: 2048}).configureBuildCache(); err != nil {
+		t.Errorf("configureBuildCache: %v", err)
+	}
+	if err := (&Porto{Runner: runner}).configureBuildCache(); err != nil {
+		t.Errorf("configureBuildCache without a cache: %v", err)
+	}
+
+	// within the limit nothing is removed, which the fake runner only allows by its command
+	if err := (&Porto{Runner: runner, BuildCacheMB: 4096}).pruneBuildCache(); err != nil {
+		t.Errorf("pruneBuildCache within the limit: %v", err)
+	}
+	if err := (&Porto{Runner: runner, BuildCacheMB: 2048}).pruneBuildCache(); err == nil {
+		t.Errorf("pruneBuildCache: expected the cache over its limit to be emptied")
+	}
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "rm -rf /var/lib/minikube/build-cache && mkdir -p /var/lib/minikube/build-cache"`: "",
+	})
+	if err := (&Porto{Runner: runner, BuildCacheMB: 2048}).pruneBuildCache(); err != nil {
+		t.Errorf("pruneBuildCache over the limit: %v", err)
+	}
+}
+
+func TestPortoSocketGroup(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo rm -f /etc/systemd/system/porto.service.d/30-socket-group.conf":          "",
+		"sudo rm -f /etc/systemd/system/portoshim.service.d/30-socket-group.conf":      "",
+		`sudo /bin/bash -c "getent group porto >/dev/null || groupadd --system porto"`: "",
+		"sudo usermod -aG porto docker":                                                "",
+		"sudo mkdir -p /etc/systemd/system/porto.service.d":                            "",
+		"sudo mkdir -p /etc/systemd/system/portoshim.service.d":                        "",
+	})
+
+	if err := (&Porto{Runner: runner}).configureSocketGroup(); err != nil {
+		t.Errorf("configureSocketGroup without a user: %v", err)
+	}
+	if err := (&Porto{Runner: runner, SocketUser: "docker"}).configureSocketGroup(); err != nil {
+		t.Fatalf("configureSocketGroup: %v", err)
+	}
+	// the portoshim drop-in is written last
+	got, err := runner.GetFileToContents(assets.MemorySource)
+	if err != nil {
+		t.Fatalf("portoshim drop-in: %v", err)
+	}
+	if want := "chgrp porto /run/portoshim.sock && chmod 0660 /run/portoshim.sock"; !strings.Contains(got, want) {
+		t.Errorf("portoshim drop-in %q does not contain %q", got, want)
+	}
+
+	for value, want := range map[string]bool{"": false, "true": true, "false": false} {
+		if got, err := NonRootSocket(value); err != nil || got != want {
+			t.Errorf("NonRootSocket(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := NonRootSocket("docker"); err == nil {
+		t.Errorf("NonRootSocket: expected an error for a value which is not a bool")
+	}
+}
+
+func TestPortoConfigureDebug(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo mkdir -p /etc/systemd/system/portoshim.service.d": "",
+		"sudo mkdir -p /etc/portod.conf.d":                      "",
+	})
+	if err := (&Porto{Runner: runner, Debug: true}).configureDebug(); err != nil {
+		t.Fatalf("configureDebug: %v", err)
+	}
+
+	// turning debug off removes the drop-ins, which the fake runner only allows by its command
+	if err := (&Porto{Runner: runner}).configureDebug(); err == nil {
+		t.Errorf("configureDebug: expected the debug configuration to be removed")
+	}
+	runner.SetCommandToOutput(map[string]string{
+		"sudo rm -f /etc/portod.conf.d/99-minikube-debug.conf":             "",
+		"sudo rm -f /etc/systemd/system/portoshim.service.d/10-debug.conf": "",
+	})
+	if err := (&Porto{Runner: runner}).configureDebug(); err != nil {
+		t.Errorf("configureDebug without debug: %v", err)
+	}
+}
+
+func TestPortoContainerName(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl list -1": "minikube\nminikube/kubelet\n2b7e1f0a\n2b7e1f0a/9c4d3e5f\n",
+	})
+	r := &Porto{Runner: runner}
+	got, err := r.portoContainerName("9c4d3e5f")
+	if err != nil || got != "2b7e1f0a/9c4d3e5f" {
+		t.Errorf("portoContainerName(9c4d3e5f) = %q, %v, want the container nested in its pod", got, err)
+	}
+	if got, err := r.portoContainerName("2b7e1f0a"); err != nil || got != "2b7e1f0a" {
+		t.Errorf("portoContainerName(2b7e1f0a) = %q, %v, want the sandbox container", got, err)
+	}
+	if _, err := r.portoContainerName("4f"); err == nil {
+		t.Errorf("portoContainerName of an unknown container succeeded")
+	}
+}
+
+func TestPortoConfigChange(t *testing.T) {
+	before := map[string]string{
+		portoshimDropIn:   "aaa",
+		portoLimitsConf:   "bbb",
+		portoCgroupNSConf: "ccc",
+	}
+	after := map[string]string{
+		portoshimDropIn:   "aaa",
+		portoCgroupNSConf: "ddd",
+		portoFeaturesConf: "eee",
+	}
+	want := []string{portoLimitsConf, portoFeaturesConf, portoCgroupNSConf}
+	if diff := cmp.Diff(want, changedFiles(before, after)); diff != "" {
+		t.Errorf("changedFiles diff (-want +got):\n%s", diff)
+	}
+
+	r := &Porto{}
+	var tests = []struct {
+		files []string
+		want  bool
+	}{
+		{nil, false},
+		{[]string{portoLimitsConf, portoDownloadTmpfsDropIn}, false},
+		{[]string{portoLimitsConf, portoshimDropIn}, true},
+		{[]string{portoCgroupNSConf}, true},
+	}
+	for _, tc := range tests {
+		if got := r.ShouldRestartKubelet(ConfigChange{Files: tc.files, Restarted: true}); got != tc.want {
+			t.Errorf("ShouldRestartKubelet(%v) = %v, want %v", tc.files, got, tc.want)
+		}
+	}
+}
+
+func TestPortoRestartServices(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	// whichever init system the node is detected to run
+	runner.SetCommandToOutput(map[string]string{
+		"systemctl --version":                                "systemd 252",
+		"sudo systemctl is-active --quiet service porto":     "",
+		"sudo systemctl is-active --quiet service portoshim": "",
+		"sudo service porto status":                          "",
+		"sudo service portoshim status":                      "",
+	})
+	r := &Porto{Runner: runner, Init: sysinit.New(runner)}
+
+	// both services are active and nothing changed, so nothing is restarted: no restart command is registered
+	if err := r.restartServices(); err != nil {
+		t.Fatalf("restartServices with an unchanged configuration: %v", err)
+	}
+	if AppliedChange(r).Restarted {
+		t.Errorf("restartServices restarted porto with an unchanged configuration")
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		"sudo systemctl daemon-reload":     "",
+		"sudo systemctl restart portoshim": "",
+		"sudo service portoshim restart":   "",
+	})
+	r.change = ConfigChange{Files: []string{portoshimDropIn}}
+	if err := r.restartServices(); err != nil {
+		t.Fatalf("restartServices with a changed portoshim configuration: %v", err)
+	}
+	if !AppliedChange(r).Restarted {
+		t.Errorf("restartServices did not restart portoshim for its changed configuration")
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/sbom"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// portoBinaries are the binaries of a porto release
+var portoBinaries = []string{"portod", "portoctl", "portoinit"}
+
+// portoUpgradeDir is where the porto release is downloaded and unpacked to during an in-place upgrade
+var portoUpgradeDir = path.Join(vmpath.GuestEphemeralDir, "porto-upgrade")
+
+// PortoCompatibility is the matrix of the porto release lines, such as v5.3, each portoshim release line works with.
+// A portoshim line missing from it works with no porto, so a new line has to be added here before the ISO can take it.
+var PortoCompatibility = map[string][]string{
+	"v1.0": {"v5.3"},
+}
+
+// PortoCompatible returns whether portoshim version portoshim works with porto version porto according to PortoCompatibility
+func PortoCompatible(porto, portoshim string) bool {
+	portoLine, err := PortoConfigLine(porto)
+	if err != nil {
+		return false
+	}
+	shimLine, err := PortoConfigLine(portoshim)
+	if err != nil {
+		return false
+	}
+	for _, line := range PortoCompatibility[shimLine] {
+		if line == portoLine {
+			return true
+		}
+	}
+	return false
+}
+
+// PortoUpgrade is an upgrade of porto on a node to the release the ISO of this minikube ships
+type PortoUpgrade struct {
+	// From is the version the node runs
+	From string
+	// To is the release to upgrade to
+	To sbom.Component
+}
+
+// NodeArch returns the GOARCH of a node, for the machine names uname reports
+func NodeArch(cr CommandRunner) (string, error) {
+	rr, err := cr.RunCmd(exec.Command("uname", "-m"))
+	if err != nil {
+		return "", errors.Wrap(err, "uname")
+	}
+	switch m := strings.TrimSpace(rr.Stdout.String()); m {
+	case "x86_64":
+		return "amd64", nil
+	case "aarch64", "arm64":
+		return "arm64", nil
+	default:
+		return "", fmt.Errorf("unsupported machine %q", m)
+	}
+}
+
+// PortoUpgradeFor returns the upgrade to the porto release among cs for arch, when the node runs an older version than it, or nil
+func PortoUpgradeFor(running string, arch string, cs []sbom.Component) (*PortoUpgrade, error) {
+	from, err := semver.ParseTolerant(running)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing porto version %q", running)
+	}
+	for _, c := range cs {
+		if c.Name != "porto" || c.Arch != arch {
+			continue
+		}
+		to, err := semver.ParseTolerant(c.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing porto version %q", c.Version)
+		}
+		if !from.LT(to) {
+			return nil, nil
+		}
+		return &PortoUpgrade{From: running, To: c}, nil
+	}
+	return nil, nil
+}
+
+// UpgradeInPlace replaces the porto binaries of the node with the release of u, stopping kubelet meanwhile so that it does not
+// give up on pods while the runtime is away, and restarts portod and portoshim. The release is verified against the sha256
+// of its architecture, and all of its binaries are staged next to the installed ones, before any of them is replaced by a
+// rename. When replacing them, or bringing portod and portoshim back on them, fails, the previous binaries and store are
+// put back and portod and portoshim restarted on them. The upgrade is refused before anything is replaced when the
+// portoshim of the node does not work with the release, or the release can't take the store of porto, which is otherwise
+// migrated to the format of the release before portod restarts on it. The fields of the configuration the release does not
+// know are removed before the restart, and Enable regenerates the configuration for the new version afterwards.
+func (r *Porto) UpgradeInPlace(u *PortoUpgrade) error {
+	if u.To.SHA256 == "" {
+		return fmt.Errorf("no sha256 is known for porto %s on %s to verify the download against", u.To.Version, u.To.Arch)
+	}
+	if err := r.checkPortoshimFor(u.To.Version); err != nil {
+		return err
+	}
+	if err := checkStoreMigration(r.Runner, u.To.Version); err != nil {
+		return err
+	}
+	klog.Infof("upgrading porto in place from %s to %s", u.From, u.To.Version)
+
+	if err := r.Init.Stop("kubelet"); err != nil {
+		klog.Warningf("failed to stop kubelet before the porto upgrade: %v", err)
+	}
+	// kubelet comes back whether the upgrade succeeds or not, on the old porto in the latter case
+	defer func() {
+		if err := r.Init.Start("kubelet"); err != nil {
+			klog.Warningf("failed to start kubelet after the porto upgrade: %v", err)
+		}
+	}()
+
+	archive := path.Join(portoUpgradeDir, "porto.tgz")
+	steps := []struct {
+		name string
+		cmd  string
+	}{
+		{"prepare", fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", portoUpgradeDir)},
+		{"download", fmt.Sprintf("curl -fsSL --retry 5 -o %s %s", archive, u.To.URL)},
+		{"verify", fmt.Sprintf("echo '%s  %s' | sha256sum -c -", u.To.SHA256, archive)},
+		{"unpack", fmt.Sprintf("tar -C %s -xzf %s", portoUpgradeDir, archive)},
+	}
+	for _, s := range steps {
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", s.cmd)); err != nil {
+			return errors.Wrapf(err, "porto upgrade: %s", s.name)
+		}
+	}
+	// every binary is staged as $dst.new before any of them is replaced
+	for _, bin := range portoBinaries {
+		// releases are unpacked flat or into a directory, depending on where they come from
+		stage := fmt.Sprintf(`src=$(find %[1]s -type f -name %[2]s | head -n 1) && test -n "$src" && dst=$(command -v %[2]s) && install -m 0755 "$src" "$dst.new"`, portoUpgradeDir, bin)
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", stage)); err != nil {
+			r.rollbackPortoBinaries()
+			return errors.Wrapf(err, "porto upgrade: stage %s", bin)
+		}
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-rf", portoUpgradeDir)); err != nil {
+		klog.Warningf("failed to remove %s: %v", portoUpgradeDir, err)
+	}
+	// the previous binary is kept as $dst.old, which rollbackPortoBinaries puts back
+	for _, bin := range portoBinaries {
+		swap := fmt.Sprintf(`dst=$(command -v %s) && ln -f "$dst" "$dst.old" && mv -f "$dst.new" "$dst"`, bin)
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", swap)); err != nil {
+			r.rollbackPortoBinaries()
+			return errors.Wrapf(err, "porto upgrade: install %s", bin)
+		}
+	}
+
+	migrated, err := r.startUpgradedPorto(u.To.Version)
+	if err != nil {
+		klog.Warningf("porto upgrade to %s failed, rolling back to %s: %v", u.To.Version, u.From, err)
+		r.rollbackPortoBinaries()
+		if migrated {
+			if err := restoreStoreBackup(r.Runner); err != nil {
+				klog.Warningf("failed to roll back the porto store: %v", err)
+			}
+		}
+		for _, svc := range []string{"porto", "portoshim"} {
+			if err := r.Init.Restart(svc); err != nil {
+				klog.Warningf("failed to restart %s after rolling back the porto upgrade: %v", svc, err)
+			}
+		}
+		return err
+	}
+	old := []string{}
+	for _, bin := range portoBinaries {
+		old = append(old, fmt.Sprintf(`rm -f "$(command -v %s).old"`, bin))
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", strings.Join(old, "; "))); err != nil {
+		klog.Warningf("failed to remove the previous porto binaries: %v", err)
+	}
+	if migrated {
+		removeStoreBackup(r.Runner)
+	}
+	return nil
+}
+
+// startUpgradedPorto restarts portod and portoshim on the porto binaries of version, migrating the store and pruning the
+// configuration for them first, and checks that both answer. It returns whether it migrated the store.
+func (r *Porto) startUpgradedPorto(version string) (bool, error) {
+	// the new release may write its store in another format, which it must not be restarted on
+	migrated, err := r.migrateStore()
+	if err != nil {
+		return migrated, err
+	}
+	// and may not know all the fields of the configuration written for the previous one
+	if err := prunePortoConfig(r.Runner); err != nil {
+		return migrated, errors.Wrap(err, "porto upgrade: pruning the configuration")
+	}
+	for _, svc := range []string{"porto", "portoshim"} {
+		if err := r.Init.Restart(svc); err != nil {
+			return migrated, errors.Wrapf(err, "restarting %s", svc)
+		}
+	}
+	if err := r.verifySockets(); err != nil {
+		return migrated, errors.Wrap(err, "porto upgrade")
+	}
+	v, err := r.Version(contextOf(r.Runner))
+	if err != nil {
+		return migrated, errors.Wrap(err, "porto version after the upgrade")
+	}
+	if want := strings.TrimPrefix(version, "v"); strings.TrimPrefix(v, "v") != want {
+		return migrated, fmt.Errorf("porto runs %s after the upgrade to %s", v, want)
+	}
+	return migrated, nil
+}
+
+// rollbackPortoBinaries puts the porto binaries UpgradeInPlace replaced back, and removes those it staged
+func (r *Porto) rollbackPortoBinaries() {
+	cmds := []string{}
+	for _, bin := range portoBinaries {
+		cmds = append(cmds, fmt.Sprintf(`dst=$(command -v %s) && rm -f "$dst.new" && if [ -e "$dst.old" ]; then mv -f "$dst.old" "$dst"; fi`, bin))
+	}
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", strings.Join(cmds, "; "))); err != nil {
+		klog.Warningf("failed to roll back the porto binaries: %v", err)
+	}
+}
+
+// checkPortoshimFor refuses an upgrade to porto version when the portoshim of the node does not work with it, as only
+// porto is upgraded in place
+func (r *Porto) checkPortoshimFor(version string) error {
+	v, err := getCRIVersion(r.Runner, r.SocketPath())
+	if err != nil {
+		klog.Warningf("unable to get the portoshim version to check porto %s against: %v", version, err)
+		return nil
+	}
+	if !PortoCompatible(version, v.RuntimeVersion) {
+		return fmt.Errorf("portoshim %s of the node does not work with porto %s, recreate the cluster to upgrade both", v.RuntimeVersion, version)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/sbom"
+	"k8s.io/minikube/pkg/minikube/sysinit"
+)
+
+var portoReleases = []sbom.Component{
+	{Name: "portoshim", Version: "v1.0.9", Arch: "amd64"},
+	{Name: "porto", Version: "v5.3.31", Arch: "amd64", URL: "https://example.com/porto_amd64.tgz", SHA256: "abc123"},
+	{Name: "porto", Version: "v5.3.32", Arch: "arm64", URL: "https://example.com/porto_arm64.tgz"},
+}
+
+func TestPortoUpgradeFor(t *testing.T) {
+	tests := []struct {
+		running string
+		arch    string
+		want    string
+	}{
+		{running: "5.3.30-alpha.7", arch: "amd64", want: "v5.3.31"},
+		{running: "5.3.30", arch: "amd64", want: "v5.3.31"},
+		{running: "5.3.31", arch: "amd64"},
+		{running: "5.4.0", arch: "amd64"},
+		{running: "5.3.30", arch: "arm64", want: "v5.3.32"},
+		{running: "5.3.30", arch: "riscv64"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.running+"/"+tc.arch, func(t *testing.T) {
+			u, err := PortoUpgradeFor(tc.running, tc.arch, portoReleases)
+			if err != nil {
+				t.Fatalf("PortoUpgradeFor: %v", err)
+			}
+			got := ""
+			if u != nil {
+				got = u.To.Version
+			}
+			if got != tc.want {
+				t.Errorf("PortoUpgradeFor(%q, %q) = %q, want %q", tc.running, tc.arch, got, tc.want)
+			}
+		})
+	}
+	if _, err := PortoUpgradeFor("unknown", "amd64", portoReleases); err == nil {
+		t.Errorf("PortoUpgradeFor: expected an error for an unparsable version")
+	}
+}
+
+func TestNodeArch(t *testing.T) {
+	for m, want := range map[string]string{"x86_64\n": "amd64", "aarch64\n": "arm64"} {
+		runner := command.NewFakeCommandRunner()
+		runner.SetCommandToOutput(map[string]string{"uname -m": m})
+		got, err := NodeArch(runner)
+		if err != nil || got != want {
+			t.Errorf("NodeArch(%q) = %q, %v, want %q", m, got, err, want)
+		}
+	}
+}
+
+func TestPortoUpgradeInPlace(t *testing.T) {
+	u := &PortoUpgrade{From: "5.3.30", To: portoReleases[1]}
+	dir := "/var/tmp/minikube/porto-upgrade"
+	// the scripts run with sudo /bin/bash -c, and the keys of their outputs
+	stage := func(bin string) string {
+		return fmt.Sprintf(`src=$(find %[1]s -type f -name %[2]s | head -n 1) && test -n "$src" && dst=$(command -v %[2]s) && install -m 0755 "$src" "$dst.new"`, dir, bin)
+	}
+	swap := func(bin string) string {
+		return fmt.Sprintf(`dst=$(command -v %s) && ln -f "$dst" "$dst.old" && mv -f "$dst.new" "$dst"`, bin)
+	}
+	rollback := `dst=$(command -v portod) && rm -f "$dst.new" && if [ -e "$dst.old" ]; then mv -f "$dst.old" "$dst"; fi; ` +
+		`dst=$(command -v portoctl) && rm -f "$dst.new" && if [ -e "$dst.old" ]; then mv -f "$dst.old" "$dst"; fi; ` +
+		`dst=$(command -v portoinit) && rm -f "$dst.new" && if [ -e "$dst.old" ]; then mv -f "$dst.old" "$dst"; fi`
+	bash := func(script string) string { return `sudo /bin/bash -c "` + script + `"` }
+	runner := &recordingRunner{FakeCommandRunner: command.NewFakeCommandRunner()}
+	ran := func(script string) int { return slices.Index(runner.cmds, "sudo /bin/bash -c "+script) }
+	cmds := map[string]string{
+		// whichever init system the node is detected to run
+		"systemctl --version":              "systemd 252",
+		"sudo systemctl stop kubelet":      "",
+		"sudo systemctl start kubelet":     "",
+		"sudo systemctl daemon-reload":     "",
+		"sudo systemctl restart porto":     "",
+		"sudo systemctl restart portoshim": "",
+		"sudo service kubelet stop":        "",
+		"sudo service kubelet start":       "",
+		"sudo service porto restart":       "",
+		"sudo service portoshim restart":   "",
+		"sudo rm -rf " + dir:               "",
+		"sudo test -S /run/portod.socket":  "",
+		"sudo test -S /run/portoshim.sock": "",
+		"sudo portoctl list":               "",
+		"sudo crictl version":              "Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  v1.0.11-alpha.11\nRuntimeApiVersion:  v1\n",
+		bash(`rm -f "$(command -v portod).old"; rm -f "$(command -v portoctl).old"; rm -f "$(command -v portoinit).old"`): "",
+		bash(rollback):     "",
+		"portod version":   "running: 5.3.31  /usr/sbin/portod",
+		"portod --version": "version: 5.3.31  /usr/sbin/portod",
+		`sudo /bin/bash -c "cat /place/minikube-store-format 2>/dev/null; true"`:             "1",
+		`sudo /bin/bash -c "ls /etc/portod.conf.d/*.conf 2>/dev/null; true"`:                 "/etc/portod.conf.d/40-minikube-features.conf\n/etc/portod.conf.d/k8s.conf\n",
+		"sudo cat /etc/portod.conf.d/40-minikube-features.conf":                              "container {\n  enable_systemd: true\n  enable_obsolete: true\n}\n",
+		"sudo cat /etc/portod.conf.d/k8s.conf":                                               "daemon {\n  enable_obsolete: true\n}\n",
+		`sudo /bin/bash -c "rm -rf ` + dir + ` && mkdir -p ` + dir + `"`:                     "",
+		`sudo /bin/bash -c "curl -fsSL --retry 5 -o ` + dir + `/porto.tgz ` + u.To.URL + `"`: "",
+		`sudo /bin/bash -c "echo 'abc123  ` + dir + `/porto.tgz' | sha256sum -c -"`:          "",
+		`sudo /bin/bash -c "tar -C ` + dir + ` -xzf ` + dir + `/porto.tgz"`:                  "",
+	}
+	for _, bin := range portoBinaries {
+		cmds[bash(stage(bin))] = ""
+		cmds[bash(swap(bin))] = ""
+	}
+	runner.SetCommandToOutput(cmds)
+	r := &Porto{Runner: runner, Init: sysinit.New(runner)}
+
+	if err := r.UpgradeInPlace(u); err != nil {
+		t.Fatalf("UpgradeInPlace: %v", err)
+	}
+	// every binary is staged before the first one is replaced
+	if last, first := ran(stage("portoinit")), ran(swap("portod")); last < 0 || first < last {
+		t.Errorf("UpgradeInPlace replaced portod before staging portoinit, ran %v", runner.cmds)
+	}
+	if ran(rollback) >= 0 {
+		t.Errorf("UpgradeInPlace rolled back a successful upgrade")
+	}
+	// the last file written, which would be k8s.conf if the files minikube does not own were pruned too
+	if got, _ := runner.GetFileToContents(assets.MemorySource); got != "container {\n  enable_systemd: true\n}\n" {
+		t.Errorf("UpgradeInPlace: expected the field porto 5.3 does not know to be removed from the minikube configuration only, got %q", got)
+	}
+
+	runner.SetCommandToOutput(map[string]string{"portod version": "running: 5.3.30  /usr/sbin/portod"})
+	runner.cmds = nil
+	if err := r.UpgradeInPlace(u); err == nil || !strings.Contains(err.Error(), "after the upgrade") {
+		t.Errorf("UpgradeInPlace: expected the version skew after the restart to fail, got %v", err)
+	}
+	if ran(rollback) < 0 {
+		t.Errorf("UpgradeInPlace did not put the previous binaries back after a failed upgrade, ran %v", runner.cmds)
+	}
+
+	if err := r.UpgradeInPlace(&PortoUpgrade{From: "5.3.30", To: portoReleases[2]}); err == nil {
+		t.Errorf("UpgradeInPlace: expected a release without a sha256 to be refused")
+	}
+
+	runner.SetCommandToOutput(map[string]string{"sudo crictl version": "Version:  0.1.0\nRuntimeName:  portoshim\nRuntimeVersion:  v1.0.11\nRuntimeApiVersion:  v1\n"})
+	next := &PortoUpgrade{From: "5.3.30", To: sbom.Component{Name: "porto", Version: "v5.4.0", Arch: "amd64", URL: "https://example.com/porto_amd64.tgz", SHA256: "abc123"}}
+	if err := r.UpgradeInPlace(next); err == nil || !strings.Contains(err.Error(), "portoshim v1.0.11") {
+		t.Errorf("UpgradeInPlace: expected an upgrade to a porto portoshim does not work with to be refused, got %v", err)
+	}
+}
+
+func TestPortoCompatible(t *testing.T) {
+	tests := []struct {
+		porto, portoshim string
+		want             bool
+	}{
+		{"v5.3.33-alpha.3", "v1.0.11-alpha.11", true},
+		{"5.3.30", "v1.0.9", true},
+		{"v5.4.0", "v1.0.11", false},
+		{"v5.3.33", "v1.1.0", false},
+		{"unknown", "v1.0.11", false},
+	}
+	for _, tc := range tests {
+		if got := PortoCompatible(tc.porto, tc.portoshim); got != tc.want {
+			t.Errorf("PortoCompatible(%q, %q) = %v, want %v", tc.porto, tc.portoshim, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// pullProgressInterval is how often a pull reports how far it got
+const pullProgressInterval = time.Second
+
+// PullStatus is how far the pull of an image got
+type PullStatus struct {
+	// Image is the image being pulled
+	Image string
+	// Bytes is how much the image filesystem of the runtime grew since the pull started, unpacked layers and all.
+	// The size of the image is not known up front: the registry only tells the compressed size of its layers.
+	Bytes int64
+	// Done is set once the pull finished, and Err when it failed
+	Done bool
+	Err  error
+}
+
+// PullProgress is called as the pulls of images advance, from concurrent pulls at once
+type PullProgress func(PullStatus)
+
+// pullProgressKey is the key of the PullProgress of a context
+type pullProgressKey struct{}
+
+// WithPullProgress returns a copy of ctx which has the pulls of the runtime operations it is passed to report to progress.
+// Porto reports its pulls to it, the other runtimes pull without reporting.
+func WithPullProgress(ctx context.Context, progress PullProgress) context.Context {
+	if progress == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, pullProgressKey{}, progress)
+}
+
+// pullProgressOf returns the progress the pulls within ctx report to, nil when they don't report
+func pullProgressOf(ctx context.Context) PullProgress {
+	p, _ := ctx.Value(pullProgressKey{}).(PullProgress)
+	return p
+}
+
+// pullCRIImageWithProgress pulls img like pullCRIImage, and reports to progress how far it got every pullProgressInterval,
+// from how much the image filesystem of the runtime grew since. That is an estimate, as concurrent pulls grow it as well.
+func pullCRIImageWithProgress(cr CommandRunner, socket string, img string, progress PullProgress) error {
+	status := PullStatus{Image: img}
+	progress(status)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pullCRIImage(cr, socket, img)
+	}()
+
+	c, watch := criClientFor(cr, socket)
+	var start uint64
+	if watch {
+		var err error
+		if start, err = c.ImageFsUsedBytes(); err != nil {
+			klog.Infof("unable to watch the pull of %s: %v", img, err)
+			watch = false
+		}
+	}
+	ticker := time.NewTicker(pullProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			status.Done, status.Err = true, err
+			progress(status)
+			return err
+		case <-ticker.C:
+			if !watch {
+				continue
+			}
+			used, err := c.ImageFsUsedBytes()
+			if err != nil || used < start {
+				continue
+			}
+			status.Bytes = int64(used - start)
+			progress(status)
+		}
+	}
+}
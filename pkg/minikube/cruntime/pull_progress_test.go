@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestPortoPullImageProgress(t *testing.T) {
+	images := &fakeImageService{}
+	socket := serveFakeCRI(t, images, &fakeRuntimeService{})
+	r := &Porto{Runner: command.NewExecRunner(false), Socket: socket}
+
+	var got []PullStatus
+	ctx := WithPullProgress(context.Background(), func(s PullStatus) { got = append(got, s) })
+	if err := r.PullImage(ctx, "busybox:latest"); err != nil {
+		t.Fatalf("PullImage: %v", err)
+	}
+	want := []PullStatus{
+		{Image: "busybox:latest"},
+		{Image: "busybox:latest", Done: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pull progress mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"busybox:latest"}, images.pulled); diff != "" {
+		t.Errorf("pulled images mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// ErrWasmNotSupported is returned by runtimes which can't run WebAssembly workloads through a shim
+var ErrWasmNotSupported = errors.New("WebAssembly shims are not supported by the container runtime")
+
+// wasmShimDir is where the release of a WebAssembly shim is downloaded and unpacked to
+var wasmShimDir = path.Join(vmpath.GuestEphemeralDir, "wasm-shim")
+
+// WasmShim is a containerd shim running WebAssembly workloads, which pods select with the RuntimeClass of its Handler
+type WasmShim struct {
+	// Name is the name of the shim in --wasm-shim
+	Name string
+	// Handler is the name of the CRI runtime handler of the shim, and of the RuntimeClass mapped to it
+	Handler string
+	// RuntimeType is the containerd runtime type the handler runs containers with
+	RuntimeType string
+	// Binary is the name of the shim binary containerd looks up in PATH for RuntimeType
+	Binary string
+	// Version is the release of the shim installed
+	Version string
+	// urlFormat is the URL of the release tarball, with the uname -m of the node to fill in
+	urlFormat string
+	// sha256 is the sha256 of the release tarball by uname -m, which the download is verified against:
+	// the shim is not installed on machines missing from it
+	sha256 map[string]string
+}
+
+// WasmShims are the WebAssembly shims minikube installs with --wasm-shim
+var WasmShims = []WasmShim{
+	{
+		Name:        "spin",
+		Handler:     "spin",
+		RuntimeType: "io.containerd.spin.v2",
+		Binary:      "containerd-shim-spin-v2",
+		Version:     "v0.15.1",
+		urlFormat:   "https://github.com/spinkube/containerd-shim-spin/releases/download/v0.15.1/containerd-shim-spin-v2-linux-%s.tar.gz",
+	},
+	{
+		Name:        "wasmtime",
+		Handler:     "wasmtime",
+		RuntimeType: "io.containerd.wasmtime.v1",
+		Binary:      "containerd-shim-wasmtime-v1",
+		Version:     "v0.5.0",
+		urlFormat:   "https://github.com/containerd/runwasi/releases/download/containerd-shim-wasmtime/v0.5.0/containerd-shim-wasmtime-%s-linux-musl.tar.gz",
+	},
+}
+
+// WasmShimNames returns the names of WasmShims
+func WasmShimNames() []string {
+	names := []string{}
+	for _, s := range WasmShims {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// WasmShimNamed returns the shim of WasmShims with the given name
+func WasmShimNamed(name string) (WasmShim, error) {
+	for _, s := range WasmShims {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return WasmShim{}, fmt.Errorf("unknown WebAssembly shim %q, valid ones are: %s", name, strings.Join(WasmShimNames(), ", "))
+}
+
+// URL returns the URL of the release tarball of the shim for a node of the given uname -m machine
+func (s WasmShim) URL(machine string) string {
+	return fmt.Sprintf(s.urlFormat, machine)
+}
+
+// containerdRuntimeConfig returns the section of the containerd configuration declaring the runtime handler of s
+func (s WasmShim) containerdRuntimeConfig() string {
+	return fmt.Sprintf(`
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%s]
+  runtime_type = %q
+`, s.Handler, s.RuntimeType)
+}
+
+// InstallWasmShim installs shim into the node unless it is there already, verifying its release against the pinned
+// sha256, and declares its runtime handler to containerd, restarting containerd when that changed its configuration
+func (r *Containerd) InstallWasmShim(ctx context.Context, shim WasmShim) error {
+	r = r.bind(ctx)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", "command -v "+shim.Binary)); err != nil {
+		rr, err := r.Runner.RunCmd(exec.Command("uname", "-m"))
+		if err != nil {
+			return errors.Wrap(err, "uname")
+		}
+		machine := strings.TrimSpace(rr.Stdout.String())
+		sum, ok := shim.sha256[machine]
+		if !ok || sum == "" {
+			return fmt.Errorf("no sha256 is known for the %s WebAssembly shim %s on %s to verify the download against", shim.Name, shim.Version, machine)
+		}
+		url := shim.URL(machine)
+		klog.Infof("installing the %s WebAssembly shim %s from %s", shim.Name, shim.Version, url)
+		archive := path.Join(wasmShimDir, "shim.tar.gz")
+		steps := []struct {
+			name string
+			cmd  string
+		}{
+			{"prepare", fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", wasmShimDir)},
+			{"download", fmt.Sprintf("curl -fsSL --retry 5 -o %s %s", archive, url)},
+			{"verify", fmt.Sprintf("echo '%s  %s' | sha256sum -c -", sum, archive)},
+			{"unpack", fmt.Sprintf("tar -C %s -xzf %s", wasmShimDir, archive)},
+			{"install", fmt.Sprintf(`src=$(find %s -type f -name %s | head -n 1) && test -n "$src" && install -m 0755 "$src" /usr/local/bin/%s`, wasmShimDir, shim.Binary, shim.Binary)},
+		}
+		for _, s := range steps {
+			if _, err := r.Runner.RunCmd(exec.Command("sudo", "/bin/bash", "-c", s.cmd)); err != nil {
+				return errors.Wrapf(err, "%s shim: %s", shim.Name, s.name)
+			}
+		}
+		if _, err := r.Runner.RunCmd(exec.Command("sudo", "rm", "-rf", wasmShimDir)); err != nil {
+			klog.Warningf("failed to remove %s: %v", wasmShimDir, err)
+		}
+	}
+
+	section := fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.%s]`, shim.Handler)
+	if _, err := r.Runner.RunCmd(exec.Command("sudo", "grep", "-qF", section, containerdConfigFile)); err == nil {
+		return nil
+	}
+	c := exec.Command("sudo", "tee", "-a", containerdConfigFile)
+	c.Stdin = strings.NewReader(shim.containerdRuntimeConfig())
+	if _, err := r.Runner.RunCmd(c); err != nil {
+		return errors.Wrapf(err, "declaring the %s runtime handler", shim.Handler)
+	}
+	return r.Init.Restart("containerd")
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cruntime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestWasmShimNamed(t *testing.T) {
+	for _, name := range WasmShimNames() {
+		s, err := WasmShimNamed(name)
+		if err != nil || s.Name != name {
+			t.Errorf("WasmShimNamed(%q) = %+v, %v", name, s, err)
+		}
+	}
+	if _, err := WasmShimNamed("wasmer"); err == nil {
+		t.Errorf("WasmShimNamed(wasmer) succeeded")
+	}
+}
+
+func TestInstallWasmShim(t *testing.T) {
+	shim, err := WasmShimNamed("spin")
+	if err != nil {
+		t.Fatalf("WasmShimNamed: %v", err)
+	}
+	// the shim is installed already, and its runtime handler is declared
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		`sudo /bin/bash -c "command -v containerd-shim-spin-v2"`:                                                   "/usr/local/bin/containerd-shim-spin-v2",
+		`sudo grep -qF [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.spin] /etc/containerd/config.toml`: "",
+	})
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := cr.InstallWasmShim(context.Background(), shim); err != nil {
+		t.Errorf("InstallWasmShim: %v", err)
+	}
+
+	for _, rt := range []string{"docker", "crio", "porto"} {
+		cr, err := New(Config{Type: rt, Runner: runner})
+		if err != nil {
+			t.Fatalf("New(%s): %v", rt, err)
+		}
+		if cr.Capabilities().SupportsWasm {
+			t.Errorf("%s supports WebAssembly shims", rt)
+		}
+		if err := cr.InstallWasmShim(context.Background(), shim); !errors.Is(err, ErrWasmNotSupported) {
+			t.Errorf("InstallWasmShim on %s = %v, want ErrWasmNotSupported", rt, err)
+		}
+	}
+}
+
+func TestInstallWasmShimVerifiesDownload(t *testing.T) {
+	shim := WasmShim{
+		Name:      "spin",
+		Handler:   "spin",
+		Binary:    "containerd-shim-spin-v2",
+		Version:   "v0.15.1",
+		urlFormat: "https://example.com/shim-%s.tar.gz",
+		sha256:    map[string]string{"x86_64": "0123abcd"},
+	}
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"uname -m": "aarch64",
+	})
+	cr, err := New(Config{Type: "containerd", Runner: runner})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	// nothing is downloaded for a machine without a pinned sha256
+	if err := cr.InstallWasmShim(context.Background(), shim); err == nil || !strings.Contains(err.Error(), "no sha256") {
+		t.Errorf("InstallWasmShim on aarch64 = %v, want an error about the missing sha256", err)
+	}
+
+	runner.SetCommandToOutput(map[string]string{
+		"uname -m": "x86_64",
+		`sudo /bin/bash -c "rm -rf /var/tmp/minikube/wasm-shim && mkdir -p /var/tmp/minikube/wasm-shim"`:                             "",
+		`sudo /bin/bash -c "curl -fsSL --retry 5 -o /var/tmp/minikube/wasm-shim/shim.tar.gz https://example.com/shim-x86_64.tar.gz"`: "",
+	})
+	// the download does not match, so it is not unpacked
+	if err := cr.InstallWasmShim(context.Background(), shim); err == nil || !strings.Contains(err.Error(), "verify") {
+		t.Errorf("InstallWasmShim of an unverified download = %v, want a verify error", err)
+	}
+}
+
+func TestWasmShimURL(t *testing.T) {
+	shim, err := WasmShimNamed("wasmtime")
+	if err != nil {
+		t.Fatalf("WasmShimNamed: %v", err)
+	}
+	want := "https://github.com/containerd/runwasi/releases/download/containerd-shim-wasmtime/v0.5.0/containerd-shim-wasmtime-aarch64-linux-musl.tar.gz"
+	if got := shim.URL("aarch64"); got != want {
+		t.Errorf("URL(aarch64) = %s, want %s", got, want)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/juju/mutex/v2"
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/out"
 	"k8s.io/minikube/pkg/minikube/style"
@@ -44,6 +45,11 @@ var (
 
 	releaseHost = "dl.k8s.io"
 	releasePath = ""
+
+	// downloadRetries is how many times a failed download is retried, resuming from where it stopped
+	downloadRetries = constants.DefaultDownloadRetries
+	// retryInterval is how long to wait before the first retry, it grows with every further one
+	retryInterval = 2 * time.Second
 )
 
 // SetAliyunMirror set the download host for Aliyun mirror
@@ -54,6 +60,11 @@ func SetAliyunMirror() {
 	releasePath = "/kubernetes-release"
 }
 
+// SetDownloadRetries sets how many times a failed download is retried
+func SetDownloadRetries(n int) {
+	downloadRetries = n
+}
+
 // CreateDstDownloadMock is the default mock implementation of download.
 func CreateDstDownloadMock(_, dst string) error {
 	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
@@ -105,12 +116,36 @@ func download(src, dst string) error {
 	}
 
 	klog.Infof("Downloading: %s -> %s", src, dst)
-	if err := client.Get(); err != nil {
+	if err := retryDownload(client.Get, tmpDst, downloadRetries); err != nil {
 		return errors.Wrapf(err, "getter: %+v", client)
 	}
 	return os.Rename(tmpDst, dst)
 }
 
+// retryDownload calls get up to retries more times until it succeeds.
+// The partial file at tmpDst is kept between attempts, so that servers supporting range requests
+// continue the download instead of starting over. A file which fails checksum verification can't be
+// continued though, so it is removed first.
+func retryDownload(get func() error, tmpDst string, retries int) error {
+	for attempt := 0; ; attempt++ {
+		err := get()
+		if err == nil {
+			return nil
+		}
+		var cerr *getter.ChecksumError
+		if errors.As(err, &cerr) {
+			if rerr := os.Remove(tmpDst); rerr != nil && !os.IsNotExist(rerr) {
+				klog.Warningf("unable to remove %s: %v", tmpDst, rerr)
+			}
+		}
+		if attempt >= retries {
+			return err
+		}
+		klog.Warningf("Download of %s failed, retrying (%d/%d): %v", tmpDst, attempt+1, retries, err)
+		time.Sleep(time.Duration(attempt+1) * retryInterval)
+	}
+}
+
 // withinUnitTset detects if we are in running within a unit-test
 func withinUnitTest() bool {
 	// Nope, it's the integration test
@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/go-getter"
 	"k8s.io/minikube/pkg/minikube/constants"
 )
 
@@ -242,3 +244,62 @@ func testPreloadWithCachedSizeZero(t *testing.T) {
 		t.Errorf("Expected only 1 download attempt but got %v!", downloadNum)
 	}
 }
+
+func TestRetryDownload(t *testing.T) {
+	retryInterval = 0
+
+	t.Run("SucceedsAfterRetries", func(t *testing.T) {
+		tmpDst := filepath.Join(t.TempDir(), "preload.download")
+		if err := os.WriteFile(tmpDst, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		attempts := 0
+		get := func() error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("connection reset by peer")
+			}
+			return nil
+		}
+		if err := retryDownload(get, tmpDst, 3); err != nil {
+			t.Errorf("retryDownload: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("got %d attempts, want 3", attempts)
+		}
+		// the partial file is kept so the next attempt can resume it
+		if _, err := os.Stat(tmpDst); err != nil {
+			t.Errorf("partial download was removed: %v", err)
+		}
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		attempts := 0
+		get := func() error {
+			attempts++
+			return fmt.Errorf("connection reset by peer")
+		}
+		if err := retryDownload(get, filepath.Join(t.TempDir(), "preload.download"), 2); err == nil {
+			t.Errorf("retryDownload: expected an error")
+		}
+		if attempts != 3 {
+			t.Errorf("got %d attempts, want 3", attempts)
+		}
+	})
+
+	t.Run("ChecksumMismatchRestarts", func(t *testing.T) {
+		tmpDst := filepath.Join(t.TempDir(), "preload.download")
+		if err := os.WriteFile(tmpDst, []byte("corrupt"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		get := func() error {
+			if _, err := os.Stat(tmpDst); err == nil {
+				return &getter.ChecksumError{File: tmpDst}
+			}
+			return nil
+		}
+		if err := retryDownload(get, tmpDst, 1); err != nil {
+			t.Errorf("retryDownload: %v", err)
+		}
+	})
+}
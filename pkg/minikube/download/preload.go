@@ -59,15 +59,25 @@ func TarballName(k8sVersion, containerRuntime string) string {
 		containerRuntime = "cri-o"
 	}
 	var storageDriver string
-	if containerRuntime == "cri-o" {
+	switch containerRuntime {
+	case "cri-o", "porto":
 		storageDriver = "overlay"
-	} else {
+	default:
 		storageDriver = "overlay2"
 	}
 	arch := detect.EffectiveArch()
 	return fmt.Sprintf("preloaded-images-k8s-%s-%s-%s-%s-%s.tar.lz4", PreloadVersion, k8sVersion, containerRuntime, storageDriver, arch)
 }
 
+// TarballRoot returns the directory on the node which the preload tarball of the container runtime is extracted to.
+// Porto keeps its images outside of /var, so its tarball is rooted at / and also holds ./var/lib/minikube/binaries.
+func TarballRoot(containerRuntime string) string {
+	if containerRuntime == "porto" {
+		return "/"
+	}
+	return "/var"
+}
+
 // returns the name of the checksum file
 func checksumName(k8sVersion, containerRuntime string) string {
 	return fmt.Sprintf("%s.checksum", TarballName(k8sVersion, containerRuntime))
@@ -103,6 +113,11 @@ func setPreloadState(k8sVersion, containerRuntime string, value bool) {
 }
 
 var checkRemotePreloadExists = func(k8sVersion, containerRuntime string) bool {
+	if containerRuntime == "porto" {
+		if exists, known := remotePortoPreloadExists(k8sVersion); known {
+			return exists
+		}
+	}
 	url := remoteTarballURL(k8sVersion, containerRuntime)
 	resp, err := http.Head(url)
 	if err != nil {
@@ -120,6 +135,21 @@ var checkRemotePreloadExists = func(k8sVersion, containerRuntime string) bool {
 	return true
 }
 
+// checkRemoteChecksumFile returns whether a checksum file is published at url
+var checkRemoteChecksumFile = func(url string) bool {
+	resp, err := http.Head(url)
+	if err != nil {
+		klog.Warningf("%s fetch error: %v", url, err)
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		klog.Infof("%s status code: %d", url, resp.StatusCode)
+		return false
+	}
+	return true
+}
+
 // PreloadExists returns true if there is a preloaded tarball that can be used
 func PreloadExists(k8sVersion, containerRuntime, driverName string, forcePreload ...bool) bool {
 	// TODO (#8166): Get rid of the need for this and viper at all
@@ -188,12 +218,18 @@ func Preload(k8sVersion, containerRuntime, driverName string) error {
 	var realPath string
 	if err != nil {
 		klog.Warningf("No checksum for preloaded tarball for k8s version %s: %v", k8sVersion, err)
-		realPath = targetPath
-		tmp, err := os.CreateTemp(targetDir(), TarballName(k8sVersion, containerRuntime)+".*")
-		if err != nil {
-			return errors.Wrap(err, "tempfile")
+		// porto preloads are published with their sha256 in the manifest and in a file next to them,
+		// which go-getter can verify against
+		if containerRuntime == "porto" {
+			if p, ok, err := findPortoPreload(k8sVersion); err == nil && ok && p.SHA256 != "" {
+				url += fmt.Sprintf("?checksum=sha256:%s", p.SHA256)
+			} else if sumURL := url + ".sha256"; checkRemoteChecksumFile(sumURL) {
+				url += fmt.Sprintf("?checksum=file:%s", sumURL)
+			}
 		}
-		targetPath = tmp.Name()
+		// a fixed name, so that an interrupted download is resumed by the next start
+		realPath = targetPath
+		targetPath += ".unverified"
 	} else if checksum != nil {
 		// add URL parameter for go-getter to automatically verify the checksum
 		url += fmt.Sprintf("?checksum=md5:%s", hex.EncodeToString(checksum))
@@ -203,8 +239,10 @@ func Preload(k8sVersion, containerRuntime, driverName string) error {
 		return errors.Wrapf(err, "download failed: %s", url)
 	}
 
-	if err := ensureChecksumValid(k8sVersion, containerRuntime, targetPath, checksum); err != nil {
-		return err
+	if checksum != nil {
+		if err := ensureChecksumValid(k8sVersion, containerRuntime, targetPath, checksum); err != nil {
+			return err
+		}
 	}
 
 	if realPath != "" {
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/detect"
+)
+
+// PortoPreloadManifestName is the name of the manifest of the porto preloads of PreloadVersion,
+// which is published in the directory of PreloadVersion of PreloadBucket
+const PortoPreloadManifestName = "porto-preloads.json"
+
+// portoPreloadManifestTimeout bounds fetching the manifest, which PreloadExists waits for on every start
+const portoPreloadManifestTimeout = 10 * time.Second
+
+// PortoPreload is a porto preload tarball the manifest lists
+type PortoPreload struct {
+	KubernetesVersion string `json:"kubernetesVersion"`
+	Arch              string `json:"arch"`
+	// Name is the TarballName of the preload, the name of its object in the directory of its Kubernetes version
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	// Porto is the version of porto which wrote the store of the tarball, if known
+	Porto string `json:"porto,omitempty"`
+}
+
+// PortoPreloadManifest lists the porto preloads published for PreloadVersion. It is uploaded after the tarballs
+// it lists and their checksums, so that it never lists a tarball which can't be downloaded yet.
+type PortoPreloadManifest struct {
+	PreloadVersion string         `json:"preloadVersion"`
+	Preloads       []PortoPreload `json:"preloads"`
+}
+
+// Find returns the preload of the manifest for Kubernetes version k8sVersion and arch
+func (m *PortoPreloadManifest) Find(k8sVersion, arch string) (PortoPreload, bool) {
+	for _, p := range m.Preloads {
+		if p.KubernetesVersion == k8sVersion && p.Arch == arch {
+			return p, true
+		}
+	}
+	return PortoPreload{}, false
+}
+
+// Add adds p to the manifest, replacing the preload of the same Kubernetes version and arch, if any.
+// The preloads are kept ordered by Kubernetes version and arch, so that the manifest only changes where p does.
+func (m *PortoPreloadManifest) Add(p PortoPreload) {
+	preloads := []PortoPreload{p}
+	for _, q := range m.Preloads {
+		if q.KubernetesVersion != p.KubernetesVersion || q.Arch != p.Arch {
+			preloads = append(preloads, q)
+		}
+	}
+	sort.Slice(preloads, func(i, j int) bool {
+		if c := semver.Compare(preloads[i].KubernetesVersion, preloads[j].KubernetesVersion); c != 0 {
+			return c < 0
+		}
+		return preloads[i].Arch < preloads[j].Arch
+	})
+	m.Preloads = preloads
+}
+
+// ParsePortoPreloadManifest parses the manifest b, failing for the manifest of another PreloadVersion
+func ParsePortoPreloadManifest(b []byte) (*PortoPreloadManifest, error) {
+	var m PortoPreloadManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "parsing the porto preload manifest")
+	}
+	if m.PreloadVersion != PreloadVersion {
+		return nil, fmt.Errorf("the porto preload manifest is of preload version %q, not %q", m.PreloadVersion, PreloadVersion)
+	}
+	return &m, nil
+}
+
+// portoPreloadManifestURL returns the URL of the manifest of the porto preloads of PreloadVersion
+func portoPreloadManifestURL() string {
+	return fmt.Sprintf("https://%s/%s/%s/%s", downloadHost, PreloadBucket, PreloadVersion, PortoPreloadManifestName)
+}
+
+// fetchPortoPreloadManifest returns the manifest of the porto preloads, or nil when none is published
+var fetchPortoPreloadManifest = func() (*PortoPreloadManifest, error) {
+	url := portoPreloadManifestURL()
+	client := &http.Client{Timeout: portoPreloadManifestTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching %s", url)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		// GCS answers 403 for objects missing from a bucket which can't be listed anonymously
+		klog.Infof("no porto preload manifest at %s: %s", url, resp.Status)
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", url)
+	}
+	return ParsePortoPreloadManifest(b)
+}
+
+// findPortoPreload returns the preload of the manifest for Kubernetes version k8sVersion on this host,
+// and false when the manifest has none. It fails when there is no manifest to look the preload up in.
+func findPortoPreload(k8sVersion string) (PortoPreload, bool, error) {
+	m, err := fetchPortoPreloadManifest()
+	if err != nil {
+		return PortoPreload{}, false, err
+	}
+	if m == nil {
+		return PortoPreload{}, false, fmt.Errorf("no porto preload manifest is published for preload version %s", PreloadVersion)
+	}
+	p, ok := m.Find(k8sVersion, detect.EffectiveArch())
+	if ok && p.Name != TarballName(k8sVersion, "porto") {
+		klog.Warningf("the porto preload manifest lists %s, not %s", p.Name, TarballName(k8sVersion, "porto"))
+		return PortoPreload{}, false, nil
+	}
+	return p, ok, nil
+}
+
+// remotePortoPreloadExists returns whether the manifest lists a porto preload for Kubernetes version k8sVersion,
+// and false for known when there is no manifest, as the porto preloads published before it have none
+func remotePortoPreloadExists(k8sVersion string) (exists bool, known bool) {
+	_, exists, err := findPortoPreload(k8sVersion)
+	if err != nil {
+		klog.Warningf("unable to look the porto preload up in the manifest: %v", err)
+		return false, false
+	}
+	if exists {
+		klog.Infof("Found porto preload of %s in the manifest", k8sVersion)
+	}
+	return exists, true
+}
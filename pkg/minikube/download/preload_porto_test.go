@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/detect"
+)
+
+func TestPortoPreloadManifestAdd(t *testing.T) {
+	m := &PortoPreloadManifest{PreloadVersion: PreloadVersion}
+	m.Add(PortoPreload{KubernetesVersion: "v1.30.0", Arch: "arm64", SHA256: "a"})
+	m.Add(PortoPreload{KubernetesVersion: "v1.9.0", Arch: "amd64", SHA256: "b"})
+	m.Add(PortoPreload{KubernetesVersion: "v1.30.0", Arch: "amd64", SHA256: "c"})
+	m.Add(PortoPreload{KubernetesVersion: "v1.30.0", Arch: "arm64", SHA256: "d"})
+
+	want := []PortoPreload{
+		{KubernetesVersion: "v1.9.0", Arch: "amd64", SHA256: "b"},
+		{KubernetesVersion: "v1.30.0", Arch: "amd64", SHA256: "c"},
+		{KubernetesVersion: "v1.30.0", Arch: "arm64", SHA256: "d"},
+	}
+	if diff := cmp.Diff(want, m.Preloads); diff != "" {
+		t.Errorf("Add mismatch (-want +got):\n%s", diff)
+	}
+	if p, ok := m.Find("v1.30.0", "arm64"); !ok || p.SHA256 != "d" {
+		t.Errorf("Find(v1.30.0, arm64) = %+v, %v, want the replaced preload", p, ok)
+	}
+	if _, ok := m.Find("v1.31.0", "amd64"); ok {
+		t.Errorf("Find(v1.31.0, amd64): expected no preload")
+	}
+}
+
+func TestParsePortoPreloadManifest(t *testing.T) {
+	b := []byte(fmt.Sprintf(`{"preloadVersion":%q,"preloads":[{"kubernetesVersion":"v1.30.0","arch":"amd64","name":"n","sha256":"s"}]}`, PreloadVersion))
+	m, err := ParsePortoPreloadManifest(b)
+	if err != nil {
+		t.Fatalf("ParsePortoPreloadManifest: %v", err)
+	}
+	if len(m.Preloads) != 1 || m.Preloads[0].SHA256 != "s" {
+		t.Errorf("ParsePortoPreloadManifest = %+v", m)
+	}
+	if _, err := ParsePortoPreloadManifest([]byte(`{"preloadVersion":"v1","preloads":[]}`)); err == nil {
+		t.Errorf("ParsePortoPreloadManifest: expected the manifest of another preload version to be refused")
+	}
+}
+
+func TestRemotePortoPreloadExists(t *testing.T) {
+	orig := fetchPortoPreloadManifest
+	defer func() { fetchPortoPreloadManifest = orig }()
+
+	const kv = "v1.30.0"
+	arch := detect.EffectiveArch()
+	tests := []struct {
+		name     string
+		manifest *PortoPreloadManifest
+		err      error
+		exists   bool
+		known    bool
+	}{
+		{name: "no manifest"},
+		{name: "unreachable", err: fmt.Errorf("timeout")},
+		{
+			name:     "listed",
+			manifest: &PortoPreloadManifest{Preloads: []PortoPreload{{KubernetesVersion: kv, Arch: arch, Name: TarballName(kv, "porto")}}},
+			exists:   true,
+			known:    true,
+		},
+		{
+			name:     "not listed",
+			manifest: &PortoPreloadManifest{Preloads: []PortoPreload{{KubernetesVersion: "v1.29.0", Arch: arch, Name: TarballName("v1.29.0", "porto")}}},
+			known:    true,
+		},
+		{
+			name:     "another tarball",
+			manifest: &PortoPreloadManifest{Preloads: []PortoPreload{{KubernetesVersion: kv, Arch: arch, Name: "preloaded-images.tar.lz4"}}},
+			known:    true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fetchPortoPreloadManifest = func() (*PortoPreloadManifest, error) { return tc.manifest, tc.err }
+			exists, known := remotePortoPreloadExists(kv)
+			if exists != tc.exists || known != tc.known {
+				t.Errorf("remotePortoPreloadExists = %v, %v, want %v, %v", exists, known, tc.exists, tc.known)
+			}
+		})
+	}
+}
@@ -68,6 +68,12 @@ func EventLog(name string) string {
 	return filepath.Join(Profile(name), "events.json")
 }
 
+// RuntimeTranscript returns the path to the provisioning transcript of a profile.
+// This log contains the commands run on the nodes, and their output, when started with --runtime-debug.
+func RuntimeTranscript(name string) string {
+	return filepath.Join(Profile(name), "runtime-transcript.txt")
+}
+
 // AuditLog returns the path to the audit log.
 // This log contains a history of commands run, by who, when, and what arguments.
 func AuditLog() string {
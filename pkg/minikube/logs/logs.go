@@ -182,10 +182,12 @@ func Output(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.Cluster
 
 	sort.Strings(names)
 	failed := []string{}
-	for i, name := range names {
-		if i > 0 {
-			out.Styled(style.None, "")
-		}
+	if err := outputRuntimeInfo(r); err != nil {
+		klog.Errorf("runtime info: %v", err)
+		failed = append(failed, "container runtime")
+	}
+	for _, name := range names {
+		out.Styled(style.None, "")
 		out.Styled(style.None, "==> {{.name}} <==", out.V{"name": name})
 		var b bytes.Buffer
 		c := exec.Command("/bin/bash", "-c", cmds[name])
@@ -214,6 +216,25 @@ func Output(r cruntime.Manager, bs bootstrapper.Bootstrapper, cfg config.Cluster
 	return nil
 }
 
+// outputRuntimeInfo displays what the container runtime reports of itself
+func outputRuntimeInfo(r cruntime.Manager) error {
+	out.Styled(style.None, "==> container runtime <==")
+	info, err := r.RuntimeInfo()
+	if err != nil {
+		return err
+	}
+	l := fmt.Sprintf("name: %s\nversion: %s\nsocket: %s\ncgroup driver: %s\n", info.Name, info.Version, info.Socket, info.CgroupDriver)
+	if info.StorageDriver != "" {
+		l += fmt.Sprintf("storage driver: %s\n", info.StorageDriver)
+	}
+	if info.StorageRoot != "" {
+		l += fmt.Sprintf("storage root: %s\n", info.StorageRoot)
+	}
+	l += fmt.Sprintf("rootless: %t\n", info.Rootless)
+	out.Styled(style.None, l)
+	return nil
+}
+
 // outputAudit displays the audit logs.
 func OutputAudit(lines int) error {
 	out.Styled(style.None, "")
@@ -253,8 +274,24 @@ func OutputLastStart() error {
 	return nil
 }
 
+// OutputRuntimeTranscript outputs the provisioning transcript recorded by the last start with --runtime-debug.
+func OutputRuntimeTranscript(profile string) error {
+	fp := localpath.RuntimeTranscript(profile)
+	b, err := os.ReadFile(fp)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %v", fp, err)
+	}
+	out.Styled(style.None, "")
+	out.Styled(style.None, "==> Runtime Transcript <==")
+	out.Styled(style.None, string(b))
+	return nil
+}
+
 // OutputOffline outputs logs that don't need a running cluster.
-func OutputOffline(lines int, logOutput *os.File) {
+func OutputOffline(profile string, lines int, logOutput *os.File) {
 	out.SetOutFile(logOutput)
 	defer out.SetOutFile(os.Stdout)
 	out.SetErrFile(logOutput)
@@ -265,6 +302,9 @@ func OutputOffline(lines int, logOutput *os.File) {
 	if err := OutputLastStart(); err != nil {
 		klog.Errorf("failed to output last start logs: %v", err)
 	}
+	if err := OutputRuntimeTranscript(profile); err != nil {
+		klog.Errorf("failed to output runtime transcript: %v", err)
+	}
 
 	out.Styled(style.None, "")
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package machine
 
 import (
+	"context"
 	"net/url"
 	"os"
 	"os/exec"
@@ -31,14 +32,11 @@ import (
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/localpath"
-	"k8s.io/minikube/pkg/minikube/vmpath"
 )
 
-// buildRoot is where images should be built from within the guest VM
-var buildRoot = path.Join(vmpath.GuestPersistentDir, "build")
-
 // BuildImage builds image to all profiles
 func BuildImage(path string, file string, tag string, push bool, env []string, opt []string, profiles []*config.Profile, allNodes bool, nodeName string) error {
 	api, err := NewAPIClient()
@@ -125,15 +123,28 @@ func BuildImage(path string, file string, tag string, push bool, env []string, o
 	return nil
 }
 
+// buildRuntime returns the runtime to build images with, set up with the build cache the cluster is configured with
+func buildRuntime(cr command.Runner, k8s config.KubernetesConfig) (cruntime.Manager, error) {
+	co := cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr}
+	if k8s.ContainerRuntime == constants.Porto {
+		size, err := cruntime.BuildCacheSize(k8s.ExtraOptions.Get(cruntime.PortoBuildCacheOption, constants.Porto))
+		if err != nil {
+			return nil, err
+		}
+		co.BuildCacheMB = size
+	}
+	return cruntime.New(co)
+}
+
 // buildImage builds a single image
 func buildImage(cr command.Runner, k8s config.KubernetesConfig, src string, file string, tag string, push bool, env []string, opt []string) error {
-	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+	r, err := buildRuntime(cr, k8s)
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
 	klog.Infof("Building image from url: %s", src)
 
-	err = r.BuildImage(src, file, tag, push, env, opt)
+	err = r.BuildImage(context.Background(), src, file, tag, push, env, opt)
 	if err != nil {
 		return errors.Wrapf(err, "%s build %s", r.Name(), src)
 	}
@@ -144,7 +155,7 @@ func buildImage(cr command.Runner, k8s config.KubernetesConfig, src string, file
 
 // transferAndBuildImage transfers and builds a single image
 func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src string, file string, tag string, push bool, env []string, opt []string) error {
-	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
+	r, err := buildRuntime(cr, k8s)
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
@@ -157,13 +168,14 @@ func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src s
 		return err
 	}
 
-	args := append([]string{"mkdir", "-p"}, buildRoot)
-	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
+	dir, err := newStagingDir(cr, "build", tag)
+	if err != nil {
 		return err
 	}
+	defer releaseStagingDir(cr, dir)
 
-	dst := path.Join(buildRoot, filename)
-	f, err := assets.NewFileAsset(src, buildRoot, filename, "0644")
+	dst := path.Join(dir, filename)
+	f, err := assets.NewFileAsset(src, dir, filename, "0644")
 	if err != nil {
 		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
 	}
@@ -177,33 +189,24 @@ func transferAndBuildImage(cr command.Runner, k8s config.KubernetesConfig, src s
 		return errors.Wrap(err, "transferring cached image")
 	}
 
-	context := path.Join(buildRoot, ".", strings.TrimSuffix(filename, filepath.Ext(filename)))
-	args = append([]string{"mkdir", "-p"}, context)
+	buildContext := path.Join(dir, ".", strings.TrimSuffix(filename, filepath.Ext(filename)))
+	args := append([]string{"mkdir", "-p"}, buildContext)
 	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
 		return err
 	}
-	args = append([]string{"tar", "-C", context, "-xf"}, dst)
+	args = append([]string{"tar", "-C", buildContext, "-xf"}, dst)
 	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
 		return err
 	}
 
 	if file != "" && !path.IsAbs(file) {
-		file = path.Join(context, file)
+		file = path.Join(buildContext, file)
 	}
-	err = r.BuildImage(context, file, tag, push, env, opt)
+	err = r.BuildImage(context.Background(), buildContext, file, tag, push, env, opt)
 	if err != nil {
 		return errors.Wrapf(err, "%s build %s", r.Name(), dst)
 	}
 
-	args = append([]string{"rm", "-rf"}, context)
-	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
-		return err
-	}
-	args = append([]string{"rm", "-f"}, dst)
-	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
-		return err
-	}
-
 	klog.Infof("Built %s from %s", tag, src)
 	return nil
 }
@@ -17,10 +17,17 @@ limitations under the License.
 package machine
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 )
 
@@ -257,3 +264,61 @@ func TestMergeImageLists(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadSharedCachedImage(t *testing.T) {
+	cacheDir := t.TempDir()
+	cached := filepath.Join(cacheDir, "registry.k8s.io", "pause_3.9")
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		t.Fatalf("creating image cache: %v", err)
+	}
+	if err := os.WriteFile(cached, []byte("image"), 0644); err != nil {
+		t.Fatalf("writing cached image: %v", err)
+	}
+
+	// no copy is registered: the image must be loaded from the mount
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-load /var/lib/minikube/image-cache/registry.k8s.io/pause_3.9": "",
+	})
+	r := &cruntime.Porto{Runner: runner}
+	if err := loadSharedCachedImage(r, "registry.k8s.io/pause:3.9", cacheDir); err != nil {
+		t.Errorf("loadSharedCachedImage: %v", err)
+	}
+	if err := loadSharedCachedImage(r, "registry.k8s.io/etcd:3.5.12-0", cacheDir); err == nil {
+		t.Errorf("loadSharedCachedImage: expected an error for an image missing from the cache")
+	}
+}
+
+func TestSortImagesBySize(t *testing.T) {
+	images := []ProfileImage{
+		{Profile: "p1", Runtime: "docker", ListImage: cruntime.ListImage{ID: "small", Size: "1024"}},
+		{Profile: "p2", Runtime: "porto", ListImage: cruntime.ListImage{ID: "unknown", Size: "?"}},
+		{Profile: "p2", Runtime: "porto", ListImage: cruntime.ListImage{ID: "big", Size: "734003200"}},
+	}
+	sortImagesBySize(images)
+	got := []string{}
+	for _, img := range images {
+		got = append(got, img.ID)
+	}
+	if want := []string{"big", "small", "unknown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortImagesBySize = %v, want %v", got, want)
+	}
+}
+
+func TestProfileImageMarshal(t *testing.T) {
+	img := ProfileImage{Profile: "p1", Runtime: "porto", ListImage: cruntime.ListImage{ID: "abc", RepoTags: []string{"busybox:latest"}, Size: "1"}}
+	b, err := json.Marshal(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"profile":"p1","runtime":"porto","id":"abc","repoDigests":null,"repoTags":["busybox:latest"],"size":"1"}`; string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+	y, err := yaml.Marshal(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(y), "\nid: abc\n") {
+		t.Errorf("yaml does not have the image fields inline:\n%s", y)
+	}
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package machine
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -31,16 +34,19 @@ import (
 
 	"github.com/docker/docker/client"
 	"github.com/docker/go-units"
+	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v2"
 	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/bootstrapper"
 	"k8s.io/minikube/pkg/minikube/command"
 	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
 	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/detect"
 	"k8s.io/minikube/pkg/minikube/image"
@@ -49,15 +55,9 @@ import (
 	"k8s.io/minikube/pkg/minikube/vmpath"
 )
 
-// loadRoot is where images should be loaded from within the guest VM
-var loadRoot = path.Join(vmpath.GuestPersistentDir, "images")
-
 // loadImageLock is used to serialize image loads to avoid overloading the guest VM
 var loadImageLock sync.Mutex
 
-// saveRoot is where images should be saved from within the guest VM
-var saveRoot = path.Join(vmpath.GuestPersistentDir, "images")
-
 // CacheImagesForBootstrapper will cache images for a bootstrapper
 func CacheImagesForBootstrapper(imageRepository, version string) error {
 	images, err := bootstrapper.GetCachedImageList(imageRepository, version)
@@ -74,6 +74,11 @@ func CacheImagesForBootstrapper(imageRepository, version string) error {
 
 // LoadCachedImages loads previously cached images into the container runtime
 func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []string, cacheDir string, overwrite bool) error {
+	return loadCachedImages(cc, runner, images, cacheDir, overwrite, nil)
+}
+
+// loadCachedImages loads previously cached images into the container runtime, reporting each load to rep
+func loadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []string, cacheDir string, overwrite bool, rep *loadReporter) error {
 	cr, err := cruntime.New(cruntime.Config{Type: cc.KubernetesConfig.ContainerRuntime, Runner: runner})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
@@ -82,6 +87,9 @@ func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 	// Skip loading images if images already exist
 	if !overwrite && cr.ImagesPreloaded(images) {
 		klog.Infof("Images are preloaded, skipping loading")
+		for _, image := range images {
+			rep.done(image, 0, nil)
+		}
 		return nil
 	}
 
@@ -93,6 +101,7 @@ func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 	}()
 
 	var g errgroup.Group
+	g.SetLimit(loadConcurrency)
 
 	var imgClient *client.Client
 	if cr.Name() == "Docker" {
@@ -111,10 +120,21 @@ func LoadCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 			// waiting for i/o timeout.
 			err := timedNeedsTransfer(imgClient, image, cr, 10*time.Second)
 			if err == nil {
+				rep.done(image, 0, nil)
 				return nil
 			}
 			klog.Infof("%q needs transfer: %v", image, err)
-			return transferAndLoadCachedImage(runner, cc.KubernetesConfig, image, cacheDir)
+			if cc.SharedImageCache && cacheDir == detect.ImageCacheDir() {
+				err = loadSharedCachedImage(cr, image, cacheDir)
+				rep.done(image, 0, err)
+			} else {
+				err = transferAndLoadCachedImage(runner, cc.KubernetesConfig, image, cacheDir, rep)
+			}
+			// porto clusters summarize where their images came from at the end of the start
+			if err == nil && cc.KubernetesConfig.ContainerRuntime == constants.Porto {
+				cruntime.RecordImageSources(runner, cruntime.ImageCached, image)
+			}
+			return err
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -146,36 +166,43 @@ func timedNeedsTransfer(imgClient *client.Client, imgName string, cr cruntime.Ma
 	}
 }
 
-// needsTransfer returns an error if an image needs to be retransferred
+// needsTransfer returns an error if an image needs to be retransferred, which is unless the runtime has it at the
+// digest it has on the host. The digests are compared rather than the names, as a mirror may tag another image alike.
 func needsTransfer(imgClient *client.Client, imgName string, cr cruntime.Manager) error {
 	imgDgst := ""         // for instance sha256:7c92a2c6bbcb6b6beff92d0a940779769c2477b807c202954c537e2e0deb9bed
 	if imgClient != nil { // if possible try to get img digest from Client lib which is 4s faster.
 		imgDgst = image.DigestByDockerLib(imgClient, imgName)
-		if imgDgst != "" {
-			if !cr.ImageExists(imgName, imgDgst) {
-				return fmt.Errorf("%q does not exist at hash %q in container runtime", imgName, imgDgst)
-			}
-			return nil
-		}
 	}
 	// if not found with method above try go-container lib (which is 4s slower)
-	imgDgst = image.DigestByGoLib(imgName)
+	if imgDgst == "" {
+		imgDgst = image.DigestByGoLib(imgName)
+	}
 	if imgDgst == "" {
 		return fmt.Errorf("got empty img digest %q for %s", imgDgst, imgName)
 	}
-	if !cr.ImageExists(imgName, imgDgst) {
-		return fmt.Errorf("%q does not exist at hash %q in container runtime", imgName, imgDgst)
+	d, err := cr.ImageDigest(imgName)
+	if err != nil {
+		return errors.Wrapf(err, "digest of %q in container runtime", imgName)
+	}
+	if !cruntime.SameDigest(d, imgDgst) {
+		return fmt.Errorf("%q is at hash %q in container runtime, not %q", imgName, d, imgDgst)
 	}
 	return nil
 }
 
 // LoadLocalImages loads images into the container runtime
 func LoadLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string) error {
+	return loadLocalImages(cc, runner, images, nil)
+}
+
+// loadLocalImages loads images into the container runtime, reporting each load to rep
+func loadLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string, rep *loadReporter) error {
 	var g errgroup.Group
+	g.SetLimit(loadConcurrency)
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return transferAndLoadImage(runner, cc.KubernetesConfig, image, image)
+			return transferAndLoadImage(runner, cc.KubernetesConfig, image, image, rep)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -187,28 +214,176 @@ func LoadLocalImages(cc *config.ClusterConfig, runner command.Runner, images []s
 
 // CacheAndLoadImages caches and loads images to all profiles
 func CacheAndLoadImages(images []string, profiles []*config.Profile, overwrite bool) error {
+	_, err := CacheAndLoadImagesReporting(images, profiles, overwrite, nil)
+	return err
+}
+
+// CacheAndLoadImagesReporting caches and loads images to all profiles like CacheAndLoadImages, reporting each load
+// to progress, and returns the outcome of loading each image into each running node
+func CacheAndLoadImagesReporting(images []string, profiles []*config.Profile, overwrite bool, progress LoadProgress) ([]LoadResult, error) {
 	if len(images) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// This is the most important thing
 	if err := image.SaveToDir(images, detect.ImageCacheDir(), overwrite); err != nil {
-		return errors.Wrap(err, "save to dir")
+		return nil, errors.Wrap(err, "save to dir")
+	}
+
+	return LoadImagesReporting(images, profiles, detect.ImageCacheDir(), overwrite, progress)
+}
+
+// StreamDaemonImages loads images from the host's docker or podman daemon into all porto profiles,
+// streaming "docker save" into the runtime instead of caching tarballs on the host or in the guest.
+// It returns the profiles which do not use porto, to be loaded through the image cache instead, and the outcome of
+// streaming each image into each running porto node, reporting each stream to progress.
+func StreamDaemonImages(images []string, profiles []*config.Profile, progress LoadProgress) ([]*config.Profile, []LoadResult, error) {
+	var rest []*config.Profile
+	var porto []*config.Profile
+	for _, p := range profiles {
+		if p.Config != nil && p.Config.KubernetesConfig.ContainerRuntime == constants.Porto {
+			porto = append(porto, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	if len(images) == 0 || len(porto) == 0 {
+		return rest, nil, nil
+	}
+
+	daemon, err := hostImageDaemon()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	api, err := NewAPIClient()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "api")
+	}
+	defer api.Close()
+
+	var results []LoadResult
+
+	for _, p := range porto {
+		for _, n := range p.Config.Nodes {
+			m := config.MachineName(*p.Config, n)
+
+			status, err := Status(api, m)
+			if err != nil {
+				klog.Warningf("error getting status for %s: %v", m, err)
+				continue
+			}
+			if status != state.Running.String() {
+				continue
+			}
+			h, err := api.Load(m)
+			if err != nil {
+				klog.Warningf("Failed to load machine %q: %v", m, err)
+				continue
+			}
+			runner, err := CommandRunner(h)
+			if err != nil {
+				return nil, nil, err
+			}
+			cr, err := cruntime.New(cruntime.Config{Type: p.Config.KubernetesConfig.ContainerRuntime, Runner: runner, Socket: p.Config.KubernetesConfig.CRISocket, ImageRepository: p.Config.KubernetesConfig.ImageRepository})
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "runtime")
+			}
+			r, ok := cr.(imageStreamLoader)
+			if !ok {
+				return nil, nil, fmt.Errorf("%s can't load images from a stream", cr.Name())
+			}
+			rep := newLoadReporter(m, progress)
+			var g errgroup.Group
+			g.SetLimit(loadConcurrency)
+			for _, img := range images {
+				img := img
+				g.Go(func() error {
+					err := streamDaemonImage(r, daemon, img, rep)
+					rep.done(img, 0, err)
+					return err
+				})
+			}
+			if err := g.Wait(); err != nil {
+				klog.Warningf("failed to stream images into %s: %v", m, err)
+			}
+			results = append(results, rep.outcomes()...)
+		}
+	}
+	return rest, results, nil
+}
+
+// hostImageDaemon returns the client of the image daemon running on the host, preferring docker over podman
+func hostImageDaemon() (string, error) {
+	for _, bin := range []string{oci.Docker, oci.Podman} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("neither %s nor %s was found in PATH", oci.Docker, oci.Podman)
+}
+
+// imageStreamLoader is a runtime which loads image archives from a stream, such as porto
+type imageStreamLoader interface {
+	LoadImageStream(name string, stream io.Reader) error
+}
+
+// streamDaemonImage pipes "<daemon> save img" on the host into porto on the node, reporting the transfer to rep.
+// The stream is the transfer, so unlike staged loads it does not take loadImageLock, which would serialize transfers too.
+func streamDaemonImage(r imageStreamLoader, daemon string, img string, rep *loadReporter) error {
+	save := exec.Command(daemon, "save", img)
+	var stderr bytes.Buffer
+	save.Stderr = &stderr
+	stream, err := save.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "stdout pipe")
+	}
+	if err := save.Start(); err != nil {
+		return errors.Wrapf(err, "%s save", daemon)
 	}
 
-	return DoLoadImages(images, profiles, detect.ImageCacheDir(), overwrite)
+	loadErr := r.LoadImageStream(img, rep.reader(img, stream, 0))
+	if loadErr != nil {
+		// nothing reads the stream anymore, so save would block forever
+		if err := save.Process.Kill(); err != nil {
+			klog.Warningf("unable to stop %s save: %v", daemon, err)
+		}
+	}
+	if err := save.Wait(); err != nil && loadErr == nil {
+		return errors.Wrapf(err, "%s save: %s", daemon, strings.TrimSpace(stderr.String()))
+	}
+	if loadErr != nil {
+		return loadErr
+	}
+	klog.Infof("Streamed %s from %s", img, daemon)
+	return nil
 }
 
 // DoLoadImages loads images to all profiles
 func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string, overwrite bool) error {
+	_, err := LoadImagesReporting(images, profiles, cacheDir, overwrite, nil)
+	return err
+}
+
+// LoadImagesReporting loads images to all profiles like DoLoadImages, reporting each load to progress,
+// and returns the outcome of loading each image into each running node
+func LoadImagesReporting(images []string, profiles []*config.Profile, cacheDir string, overwrite bool, progress LoadProgress) ([]LoadResult, error) {
 	api, err := NewAPIClient()
 	if err != nil {
-		return errors.Wrap(err, "api")
+		return nil, errors.Wrap(err, "api")
 	}
 	defer api.Close()
 
 	succeeded := []string{}
 	failed := []string{}
+	var results []LoadResult
+	// failNode records that none of the images could be loaded into the node m
+	failNode := func(m string, err error) {
+		failed = append(failed, m)
+		for _, img := range images {
+			results = append(results, LoadResult{Image: img, Node: m, Err: err})
+		}
+	}
 
 	for _, p := range profiles { // loading images to all running profiles
 		pName := p.Name // capture the loop variable
@@ -227,7 +402,7 @@ func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string,
 			status, err := Status(api, m)
 			if err != nil {
 				klog.Warningf("error getting status for %s: %v", m, err)
-				failed = append(failed, m)
+				failNode(m, err)
 				continue
 			}
 
@@ -235,20 +410,22 @@ func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string,
 				h, err := api.Load(m)
 				if err != nil {
 					klog.Warningf("Failed to load machine %q: %v", m, err)
-					failed = append(failed, m)
+					failNode(m, err)
 					continue
 				}
 				cr, err := CommandRunner(h)
 				if err != nil {
-					return err
+					return results, err
 				}
+				rep := newLoadReporter(m, progress)
 				if cacheDir != "" {
 					// loading image names, from cache
-					err = LoadCachedImages(c, cr, images, cacheDir, overwrite)
+					err = loadCachedImages(c, cr, images, cacheDir, overwrite, rep)
 				} else {
 					// loading image files
-					err = LoadLocalImages(c, cr, images)
+					err = loadLocalImages(c, cr, images, rep)
 				}
+				results = append(results, rep.outcomes()...)
 				if err != nil {
 					failed = append(failed, m)
 					klog.Warningf("Failed to load cached images for profile %s. make sure the profile is running. %v", pName, err)
@@ -262,18 +439,44 @@ func DoLoadImages(images []string, profiles []*config.Profile, cacheDir string,
 	klog.Infof("succeeded pushing to: %s", strings.Join(succeeded, " "))
 	klog.Infof("failed pushing to: %s", strings.Join(failed, " "))
 	// Live pushes are not considered a failure
-	return nil
+	return results, nil
 }
 
 // transferAndLoadCachedImage transfers and loads a single image from the cache
-func transferAndLoadCachedImage(cr command.Runner, k8s config.KubernetesConfig, imgName string, cacheDir string) error {
+func transferAndLoadCachedImage(cr command.Runner, k8s config.KubernetesConfig, imgName string, cacheDir string, rep *loadReporter) error {
 	src := filepath.Join(cacheDir, imgName)
 	src = localpath.SanitizeCacheDir(src)
-	return transferAndLoadImage(cr, k8s, src, imgName)
+	return transferAndLoadImage(cr, k8s, src, imgName, rep)
 }
 
-// transferAndLoadImage transfers and loads a single image
-func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src string, imgName string) error {
+// loadSharedCachedImage loads a single image straight from the host image cache mounted into the node,
+// so that profiles sharing the cache don't each receive a copy of it
+func loadSharedCachedImage(cr cruntime.Manager, imgName string, cacheDir string) error {
+	src := localpath.SanitizeCacheDir(filepath.Join(cacheDir, imgName))
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(cacheDir, src)
+	if err != nil {
+		return errors.Wrapf(err, "locating %s in the image cache", src)
+	}
+	dst := path.Join(vmpath.GuestImageCacheDir, filepath.ToSlash(rel))
+
+	loadImageLock.Lock()
+	defer loadImageLock.Unlock()
+
+	if err := cr.LoadImage(context.Background(), dst); err != nil {
+		return errors.Wrapf(err, "%s load %s", cr.Name(), dst)
+	}
+	klog.Infof("Loaded %s from the shared image cache", imgName)
+	return nil
+}
+
+// transferAndLoadImage transfers and loads a single image, reporting the transfer and its outcome to rep
+func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src string, imgName string, rep *loadReporter) (err error) {
+	var size int64
+	defer func() { rep.done(imgName, size, err) }()
+
 	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
@@ -283,14 +486,42 @@ func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src st
 		return err
 	}
 
+	if cruntime.IsOCILayout(src) {
+		loadImageLock.Lock()
+		defer loadImageLock.Unlock()
+		if err := r.LoadImageFromLayout(context.Background(), src); err != nil {
+			return errors.Wrapf(err, "%s load %s", r.Name(), src)
+		}
+		klog.Infof("Loaded OCI layout %s", src)
+		return nil
+	}
+
 	klog.Infof("Loading image from: %s", src)
 	filename := filepath.Base(src)
-	if _, err := os.Stat(src); err != nil {
+	fi, err := os.Stat(src)
+	if err != nil {
 		return err
 	}
+	size = fi.Size()
 
-	dst := path.Join(loadRoot, filename)
-	f, err := assets.NewFileAsset(src, loadRoot, filename, "0644")
+	if p, ok := r.(*cruntime.Porto); ok {
+		// porto loads the archive straight from the host, so there is nothing to stage in the guest
+		err := p.LoadImageArchive(context.Background(), src, func(stream io.Reader) io.Reader { return rep.reader(imgName, stream, size) })
+		if err != nil {
+			return errors.Wrapf(err, "%s load %s", r.Name(), src)
+		}
+		klog.Infof("Streamed and loaded %s", src)
+		return nil
+	}
+
+	dir, err := newStagingDir(cr, "load", imgName)
+	if err != nil {
+		return err
+	}
+	defer releaseStagingDir(cr, dir)
+
+	dst := path.Join(dir, filename)
+	f, err := assets.NewFileAsset(src, dir, filename, "0644")
 	if err != nil {
 		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
 	}
@@ -300,14 +531,15 @@ func transferAndLoadImage(cr command.Runner, k8s config.KubernetesConfig, src st
 		}
 	}()
 
-	if err := cr.Copy(f); err != nil {
+	if err := cr.Copy(rep.file(imgName, f)); err != nil {
 		return errors.Wrap(err, "transferring cached image")
 	}
+	rep.transferred(imgName, size, size)
 
 	loadImageLock.Lock()
 	defer loadImageLock.Unlock()
 
-	err = r.LoadImage(dst)
+	err = r.LoadImage(context.Background(), dst)
 	if err != nil {
 		return errors.Wrapf(err, "%s load %s", r.Name(), dst)
 	}
@@ -323,7 +555,7 @@ func removeExistingImage(r cruntime.Manager, src string, imgName string) error {
 		return nil
 	}
 
-	err := r.RemoveImage(imgName)
+	err := r.RemoveImage(context.Background(), imgName)
 	if err == nil {
 		return nil
 	}
@@ -360,16 +592,9 @@ func SaveCachedImages(cc *config.ClusterConfig, runner command.Runner, images []
 	return nil
 }
 
-// SaveLocalImages saves images from the container runtime
+// SaveLocalImages saves images from the container runtime into the one archive output
 func SaveLocalImages(cc *config.ClusterConfig, runner command.Runner, images []string, output string) error {
-	var g errgroup.Group
-	for _, image := range images {
-		image := image
-		g.Go(func() error {
-			return transferAndSaveImage(runner, cc.KubernetesConfig, output, image)
-		})
-	}
-	if err := g.Wait(); err != nil {
+	if err := transferAndSaveImages(runner, cc.KubernetesConfig, output, images); err != nil {
 		return errors.Wrap(err, "saving images")
 	}
 	klog.Infoln("Successfully saved all images")
@@ -462,16 +687,23 @@ func transferAndSaveCachedImage(cr command.Runner, k8s config.KubernetesConfig,
 
 // transferAndSaveImage transfers and loads a single image
 func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst string, imgName string) error {
+	return transferAndSaveImages(cr, k8s, dst, []string{imgName})
+}
+
+// transferAndSaveImages saves images into one archive in the node, and transfers it to dst
+func transferAndSaveImages(cr command.Runner, k8s config.KubernetesConfig, dst string, images []string) error {
 	r, err := cruntime.New(cruntime.Config{Type: k8s.ContainerRuntime, Runner: cr})
 	if err != nil {
 		return errors.Wrap(err, "runtime")
 	}
 
-	if !r.ImageExists(imgName, "") {
-		return errors.Errorf("image %s not found", imgName)
+	for _, imgName := range images {
+		if !r.ImageExists(imgName, "") {
+			return errors.Errorf("image %s not found", imgName)
+		}
 	}
 
-	klog.Infof("Saving image to: %s", dst)
+	klog.Infof("Saving images to: %s", dst)
 	filename := filepath.Base(dst)
 
 	_, err = os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, 0777)
@@ -479,7 +711,13 @@ func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst st
 		return err
 	}
 
-	f, err := assets.NewFileAsset(dst, saveRoot, filename, "0644")
+	dir, err := newStagingDir(cr, "save", strings.Join(images, " "))
+	if err != nil {
+		return err
+	}
+	defer releaseStagingDir(cr, dir)
+
+	f, err := assets.NewFileAsset(dst, dir, filename, "0644")
 	if err != nil {
 		return errors.Wrapf(err, "creating copyable file asset: %s", filename)
 	}
@@ -489,12 +727,8 @@ func transferAndSaveImage(cr command.Runner, k8s config.KubernetesConfig, dst st
 		}
 	}()
 
-	src := path.Join(saveRoot, filename)
-	args := append([]string{"rm", "-f"}, src)
-	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
-		return err
-	}
-	err = r.SaveImage(imgName, src)
+	src := path.Join(dir, filename)
+	err = r.SaveImages(context.Background(), images, src)
 	if err != nil {
 		return errors.Wrapf(err, "%s save %s", r.Name(), src)
 	}
@@ -521,7 +755,7 @@ func pullImages(cruntime cruntime.Manager, images []string) error {
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return cruntime.PullImage(image)
+			return cruntime.PullImage(context.Background(), image)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -602,7 +836,7 @@ func removeImages(cruntime cruntime.Manager, images []string) error {
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return cruntime.RemoveImage(image)
+			return cruntime.RemoveImage(context.Background(), image)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -686,6 +920,54 @@ func ListImages(profile *config.Profile, format string) error {
 		return errors.Wrapf(err, "error loading config for profile :%v", pName)
 	}
 
+	uniqueImages, err := listProfileImages(api, c)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "table":
+		var data [][]string
+		for _, item := range uniqueImages {
+			imageSize := humanImageSize(item.Size)
+			id := parseImageID(item.ID)
+			for _, img := range item.RepoTags {
+				imageName, tag := parseRepoTag(img)
+				if imageName == "" {
+					continue
+				}
+				data = append(data, []string{imageName, tag, id, imageSize})
+			}
+		}
+		renderImagesTable(data)
+	case "json":
+		json, err := json.Marshal(uniqueImages)
+		if err != nil {
+			klog.Warningf("Error marshalling images list: %v", err.Error())
+			return nil
+		}
+		fmt.Printf(string(json) + "\n")
+	case "yaml":
+		yaml, err := yaml.Marshal(uniqueImages)
+		if err != nil {
+			klog.Warningf("Error marshalling images list: %v", err.Error())
+			return nil
+		}
+		fmt.Printf(string(yaml) + "\n")
+	default:
+		res := []string{}
+		for _, item := range uniqueImages {
+			res = append(res, item.RepoTags...)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(res)))
+		fmt.Printf(strings.Join(res, "\n") + "\n")
+	}
+
+	return nil
+}
+
+// listProfileImages lists the images of the running nodes of a profile, merged into a single list
+func listProfileImages(api libmachine.API, c *config.ClusterConfig) ([]cruntime.ListImage, error) {
 	imageListsFromNodes := [][]cruntime.ListImage{}
 	for _, n := range c.Nodes {
 		m := config.MachineName(*c, n)
@@ -704,15 +986,15 @@ func ListImages(profile *config.Profile, format string) error {
 			}
 			runner, err := CommandRunner(h)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			cr, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
 			if err != nil {
-				return errors.Wrap(err, "error creating container runtime")
+				return nil, errors.Wrap(err, "error creating container runtime")
 			}
 			list, err := cr.ListImages(cruntime.ListImagesOptions{})
 			if err != nil {
-				klog.Warningf("Failed to list images for profile %s %v", pName, err.Error())
+				klog.Warningf("Failed to list images for profile %s %v", c.Name, err.Error())
 				continue
 			}
 			imageListsFromNodes = append(imageListsFromNodes, list)
@@ -720,12 +1002,45 @@ func ListImages(profile *config.Profile, format string) error {
 		}
 	}
 
-	uniqueImages := mergeImageLists(imageListsFromNodes)
+	return mergeImageLists(imageListsFromNodes), nil
+}
+
+// ProfileImage is an image of a profile, as listed by ListAllImages
+type ProfileImage struct {
+	Profile            string `json:"profile" yaml:"profile"`
+	Runtime            string `json:"runtime" yaml:"runtime"`
+	cruntime.ListImage `yaml:",inline"`
+}
+
+// ListAllImages lists the images of all profiles, whatever their container runtime, largest first
+func ListAllImages(format string) error {
+	api, err := NewAPIClient()
+	if err != nil {
+		return errors.Wrap(err, "error creating api client")
+	}
+	defer api.Close()
+
+	profiles, err := config.ListValidProfiles()
+	if err != nil {
+		return errors.Wrap(err, "listing profiles")
+	}
+	images := []ProfileImage{}
+	for _, p := range profiles {
+		list, err := listProfileImages(api, p.Config)
+		if err != nil {
+			klog.Warningf("Failed to list images for profile %s: %v", p.Name, err)
+			continue
+		}
+		for _, img := range list {
+			images = append(images, ProfileImage{Profile: p.Name, Runtime: p.Config.KubernetesConfig.ContainerRuntime, ListImage: img})
+		}
+	}
+	sortImagesBySize(images)
 
 	switch format {
 	case "table":
 		var data [][]string
-		for _, item := range uniqueImages {
+		for _, item := range images {
 			imageSize := humanImageSize(item.Size)
 			id := parseImageID(item.ID)
 			for _, img := range item.RepoTags {
@@ -733,36 +1048,48 @@ func ListImages(profile *config.Profile, format string) error {
 				if imageName == "" {
 					continue
 				}
-				data = append(data, []string{imageName, tag, id, imageSize})
+				data = append(data, []string{item.Profile, item.Runtime, imageName, tag, id, imageSize})
 			}
 		}
-		renderImagesTable(data)
+		renderTable([]string{"Profile", "Runtime", "Image", "Tag", "Image ID", "Size"}, data)
 	case "json":
-		json, err := json.Marshal(uniqueImages)
+		json, err := json.Marshal(images)
 		if err != nil {
 			klog.Warningf("Error marshalling images list: %v", err.Error())
 			return nil
 		}
 		fmt.Printf(string(json) + "\n")
 	case "yaml":
-		yaml, err := yaml.Marshal(uniqueImages)
+		yaml, err := yaml.Marshal(images)
 		if err != nil {
 			klog.Warningf("Error marshalling images list: %v", err.Error())
 			return nil
 		}
 		fmt.Printf(string(yaml) + "\n")
 	default:
-		res := []string{}
-		for _, item := range uniqueImages {
-			res = append(res, item.RepoTags...)
+		for _, item := range images {
+			for _, tag := range item.RepoTags {
+				fmt.Printf("%s\t%s\n", item.Profile, tag)
+			}
 		}
-		sort.Sort(sort.Reverse(sort.StringSlice(res)))
-		fmt.Printf(strings.Join(res, "\n") + "\n")
 	}
-
 	return nil
 }
 
+// sortImagesBySize sorts images by size, largest first. Sizes which are not a number of bytes go last.
+func sortImagesBySize(images []ProfileImage) {
+	size := func(img ProfileImage) float64 {
+		f, err := strconv.ParseFloat(img.Size, 64)
+		if err != nil {
+			return -1
+		}
+		return f
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		return size(images[i]) > size(images[j])
+	})
+}
+
 // mergeImageLists merges image lists from different nodes into a single list
 // all the repo tags of the same image will be preserved and grouped in one image item
 func mergeImageLists(lists [][]cruntime.ListImage) []cruntime.ListImage {
@@ -825,13 +1152,18 @@ func humanImageSize(imageSize string) string {
 
 // renderImagesTable renders pretty table for images list
 func renderImagesTable(images [][]string) {
+	renderTable([]string{"Image", "Tag", "Image ID", "Size"}, images)
+}
+
+// renderTable renders pretty table of rows under header
+func renderTable(header []string, rows [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Image", "Tag", "Image ID", "Size"})
+	table.SetHeader(header)
 	table.SetAutoFormatHeaders(false)
 	table.SetBorders(tablewriter.Border{Left: true, Top: true, Right: true, Bottom: true})
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	table.SetCenterSeparator("|")
-	table.AppendBulk(images)
+	table.AppendBulk(rows)
 	table.Render()
 }
 
@@ -877,7 +1209,7 @@ func TagImage(profile *config.Profile, source string, target string) error {
 			if err != nil {
 				return errors.Wrap(err, "error creating container runtime")
 			}
-			err = cruntime.TagImage(source, target)
+			err = cruntime.TagImage(context.Background(), source, target)
 			if err != nil {
 				failed = append(failed, m)
 				klog.Warningf("Failed to tag image for profile %s %v", pName, err.Error())
@@ -892,6 +1224,136 @@ func TagImage(profile *config.Profile, source string, target string) error {
 	return nil
 }
 
+// InspectImage prints the metadata of image, as the runtime of the first running node of profile having it knows it
+func InspectImage(profile *config.Profile, image string, format string) error {
+	api, err := NewAPIClient()
+	if err != nil {
+		return errors.Wrap(err, "error creating api client")
+	}
+	defer api.Close()
+
+	pName := profile.Name
+
+	c, err := config.Load(pName)
+	if err != nil {
+		klog.Errorf("Failed to load profile %q: %v", pName, err)
+		return errors.Wrapf(err, "error loading config for profile :%v", pName)
+	}
+
+	img, err := inspectProfileImage(api, c, image)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		json, err := json.MarshalIndent(img, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshalling image")
+		}
+		fmt.Println(string(json))
+	case "yaml":
+		yaml, err := yaml.Marshal(img)
+		if err != nil {
+			return errors.Wrap(err, "marshalling image")
+		}
+		fmt.Print(string(yaml))
+	default:
+		renderImageInspect(img)
+	}
+	return nil
+}
+
+// inspectProfileImage returns the metadata of image from the first running node of a profile having it
+func inspectProfileImage(api libmachine.API, c *config.ClusterConfig, image string) (*cruntime.ImageInspect, error) {
+	var lastErr error
+	for _, n := range c.Nodes {
+		m := config.MachineName(*c, n)
+
+		status, err := Status(api, m)
+		if err != nil {
+			klog.Warningf("error getting status for %s: %v", m, err)
+			continue
+		}
+		if status != state.Running.String() {
+			continue
+		}
+		h, err := api.Load(m)
+		if err != nil {
+			klog.Warningf("Failed to load machine %q: %v", m, err)
+			continue
+		}
+		runner, err := CommandRunner(h)
+		if err != nil {
+			return nil, err
+		}
+		cr, err := cruntime.New(cruntime.Config{Type: c.KubernetesConfig.ContainerRuntime, Runner: runner})
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating container runtime")
+		}
+		img, err := cr.InspectImage(context.Background(), image)
+		if err == nil {
+			return img, nil
+		}
+		if !errors.Is(err, cruntime.ErrImageNotFound) {
+			klog.Warningf("Failed to inspect image on %s: %v", m, err)
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, errors.Errorf("no running node in profile %s", c.Name)
+	}
+	return nil, lastErr
+}
+
+// renderImageInspect prints the metadata of an image for humans
+func renderImageInspect(img *cruntime.ImageInspect) {
+	field := func(name string, value string) {
+		if value != "" {
+			fmt.Printf("%-14s%s\n", name+":", value)
+		}
+	}
+	list := func(name string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Printf("%s:\n", name)
+		for _, v := range values {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+
+	field("ID", img.ID)
+	list("RepoTags", img.RepoTags)
+	list("RepoDigests", img.RepoDigests)
+	field("Size", units.HumanSizeWithPrecision(float64(img.Size), 3))
+	field("Created", img.Created)
+	platform := img.OS
+	if img.Architecture != "" {
+		platform += "/" + img.Architecture
+	}
+	if img.Variant != "" {
+		platform += "/" + img.Variant
+	}
+	field("Platform", strings.TrimPrefix(platform, "/"))
+	if cfg := img.Config; cfg != nil {
+		field("User", cfg.User)
+		field("WorkingDir", cfg.WorkingDir)
+		field("Entrypoint", strings.Join(cfg.Entrypoint, " "))
+		field("Cmd", strings.Join(cfg.Cmd, " "))
+		field("StopSignal", cfg.StopSignal)
+		list("Env", cfg.Env)
+		list("ExposedPorts", cfg.ExposedPorts)
+		labels := []string{}
+		for k, v := range cfg.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		list("Labels", labels)
+	}
+	list("Layers", img.Layers)
+}
+
 // pushImages pushes images from the container run time
 func pushImages(cruntime cruntime.Manager, images []string) error {
 	klog.Infof("PushImages start: %s", images)
@@ -906,7 +1368,7 @@ func pushImages(cruntime cruntime.Manager, images []string) error {
 	for _, image := range images {
 		image := image
 		g.Go(func() error {
-			return cruntime.PushImage(image)
+			return cruntime.PushImage(context.Background(), image)
 		})
 	}
 	if err := g.Wait(); err != nil {
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+)
+
+// CopyToPod copies the host file src to dst within a running container of the pod namespace/pod on the node.
+// The file is staged on the node first, then streamed into the container by the container runtime.
+// It returns cruntime.ErrPodNotFound when the node does not run the pod.
+func CopyToPod(cr command.Runner, src, namespace, pod, container, dst string) error {
+	id, err := cruntime.PodContainer(cr, namespace, pod, container)
+	if err != nil {
+		return err
+	}
+
+	dir, err := newStagingDir(cr, "cp", namespace+"/"+pod+":"+dst)
+	if err != nil {
+		return err
+	}
+	defer releaseStagingDir(cr, dir)
+
+	fa, err := assets.NewFileAsset(src, dir, path.Base(dst), "0644")
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", src)
+	}
+	defer func() {
+		_ = fa.Close()
+	}()
+	if err := cr.Copy(fa); err != nil {
+		return errors.Wrap(err, "staging file")
+	}
+
+	return cruntime.CopyIntoContainer(cr, id, path.Join(dir, path.Base(dst)), path.Dir(dst))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/out"
+)
+
+// loadConcurrency is how many images are transferred into a node at once
+const loadConcurrency = 4
+
+// LoadStatus is how far loading an image into a node got
+type LoadStatus struct {
+	// Image is the image, or the image file, being loaded
+	Image string
+	// Node is the machine the image is loaded into
+	Node string
+	// Bytes is how many of the TotalBytes bytes of the image are transferred, TotalBytes is 0 when the size is unknown
+	Bytes, TotalBytes int64
+	// Done is set once the image is loaded into the runtime, and Err when that failed
+	Done bool
+	Err  error
+}
+
+// LoadProgress is called as the loads of images advance, from concurrent loads at once
+type LoadProgress func(LoadStatus)
+
+// LoadResult is the outcome of loading an image into a node
+type LoadResult struct {
+	Image string
+	Node  string
+	Err   error
+}
+
+// LoadProgressBars returns a LoadProgress rendering the loads of images as a progress bar each.
+// Call stop once the loads are done to release the terminal.
+// The returned progress is nil when stdout is not a terminal, or with --output=json.
+func LoadProgressBars() (progress LoadProgress, stop func()) {
+	if out.JSON || !out.IsTerminal(os.Stdout) || detect.GithubActionRunner() {
+		return nil, func() {}
+	}
+	bars := &progressBars{}
+	progress = func(s LoadStatus) {
+		name := image.Tag(s.Image)
+		if _, err := os.Stat(s.Image); err == nil {
+			name = filepath.Base(s.Image)
+		}
+		bars.update(s.Node+"/"+s.Image, name, s.Bytes, s.TotalBytes, s.Done)
+	}
+	return progress, bars.stop
+}
+
+// loadReporter reports the progress of the loads of images into a node, and records their outcome.
+// A nil loadReporter reports nothing.
+type loadReporter struct {
+	node     string
+	progress LoadProgress
+
+	mu      sync.Mutex
+	results []LoadResult
+}
+
+// newLoadReporter returns a loadReporter of the loads into node, reporting to progress
+func newLoadReporter(node string, progress LoadProgress) *loadReporter {
+	return &loadReporter{node: node, progress: progress}
+}
+
+// transferred reports that bytes of the total bytes of img reached the node
+func (l *loadReporter) transferred(img string, bytes int64, total int64) {
+	if l == nil || l.progress == nil {
+		return
+	}
+	l.progress(LoadStatus{Image: img, Node: l.node, Bytes: bytes, TotalBytes: total})
+}
+
+// done records the outcome of loading img, err being nil once it is loaded into the runtime
+func (l *loadReporter) done(img string, total int64, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.results = append(l.results, LoadResult{Image: img, Node: l.node, Err: err})
+	l.mu.Unlock()
+	if l.progress != nil {
+		l.progress(LoadStatus{Image: img, Node: l.node, Bytes: total, TotalBytes: total, Done: true, Err: err})
+	}
+}
+
+// outcomes returns the outcome of the loads reported so far
+func (l *loadReporter) outcomes() []LoadResult {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LoadResult{}, l.results...)
+}
+
+// reader returns r counting the bytes of img read through it, out of total
+func (l *loadReporter) reader(img string, r io.Reader, total int64) io.Reader {
+	if l == nil || l.progress == nil {
+		return r
+	}
+	return &progressReader{Reader: r, read: func(n int64) { l.transferred(img, n, total) }}
+}
+
+// file returns f counting the bytes of img read from it, for runners which stream assets to the node
+func (l *loadReporter) file(img string, f assets.CopyableFile) assets.CopyableFile {
+	if l == nil || l.progress == nil {
+		return f
+	}
+	total := int64(f.GetLength())
+	return &progressFile{CopyableFile: f, read: func(n int64) { l.transferred(img, n, total) }}
+}
+
+// progressReader calls read with the bytes read through it so far
+type progressReader struct {
+	io.Reader
+	n    int64
+	read func(int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read(atomic.AddInt64(&r.n, int64(n)))
+	return n, err
+}
+
+// progressFile calls read with the bytes read from the file so far
+type progressFile struct {
+	assets.CopyableFile
+	n    int64
+	read func(int64)
+}
+
+func (f *progressFile) Read(p []byte) (int, error) {
+	n, err := f.CopyableFile.Read(p)
+	f.read(atomic.AddInt64(&f.n, int64(n)))
+	return n, err
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// recordedLoads is a LoadProgress recording the statuses it is called with
+type recordedLoads struct {
+	mu       sync.Mutex
+	statuses []LoadStatus
+}
+
+func (r *recordedLoads) progress(s LoadStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, s)
+}
+
+func TestLoadReporter(t *testing.T) {
+	var recorded recordedLoads
+	rep := newLoadReporter("m01", recorded.progress)
+
+	stream := rep.reader("app.tar", strings.NewReader("0123456789"), 10)
+	if _, err := io.Copy(io.Discard, stream); err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	rep.done("app.tar", 10, nil)
+	rep.done("broken.tar", 0, fmt.Errorf("no space left"))
+
+	last := recorded.statuses[len(recorded.statuses)-2]
+	if !last.Done || last.Bytes != 10 || last.Node != "m01" {
+		t.Errorf("status of the finished load = %+v, want 10 bytes done into m01", last)
+	}
+	var read int64
+	for _, s := range recorded.statuses {
+		if s.Image == "app.tar" && !s.Done {
+			read = s.Bytes
+		}
+	}
+	if read != 10 {
+		t.Errorf("reported %d bytes read, want 10", read)
+	}
+
+	results := rep.outcomes()
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("outcomes() = %+v, want app.tar loaded and broken.tar failed", results)
+	}
+
+	// a nil reporter reports nothing, for the loads nobody follows
+	var none *loadReporter
+	none.done("app.tar", 0, nil)
+	if none.outcomes() != nil {
+		t.Errorf("nil reporter recorded outcomes")
+	}
+}
+
+func TestTransferAndLoadImagePorto(t *testing.T) {
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	tag, err := name.NewTag("app:v1")
+	if err != nil {
+		t.Fatalf("NewTag: %v", err)
+	}
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	if err := tarball.WriteToFile(archive, tag, img); err != nil {
+		t.Fatalf("WriteToFile: %v", err)
+	}
+	fi, err := os.Stat(archive)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	id, err := img.ConfigName()
+	if err != nil {
+		t.Fatalf("ConfigName: %v", err)
+	}
+
+	// no copy is registered: the archive must be streamed from the host, and retagged from its manifest
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo portoctl docker-load /dev/stdin":           "",
+		"sudo portoctl docker-images":                    "ID   NAME\n",
+		"sudo portoctl docker-tag " + id.Hex + " app:v1": "",
+	})
+	var recorded recordedLoads
+	rep := newLoadReporter("m01", recorded.progress)
+	k8s := config.KubernetesConfig{ContainerRuntime: constants.Porto}
+	if err := transferAndLoadImage(runner, k8s, archive, archive, rep); err != nil {
+		t.Fatalf("transferAndLoadImage: %v", err)
+	}
+
+	results := rep.outcomes()
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("outcomes() = %+v, want %s loaded", results, archive)
+	}
+	last := recorded.statuses[len(recorded.statuses)-1]
+	if !last.Done || last.TotalBytes != fi.Size() {
+		t.Errorf("last status = %+v, want done with %d bytes", last, fi.Size())
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"bufio"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+// nodeSampleScript prints the number of CPUs, the CPU times since boot, the memory, and the disk of /var of a node
+const nodeSampleScript = `nproc; head -n1 /proc/stat; grep -E '^(MemTotal|MemAvailable):' /proc/meminfo; df -B1 /var | awk 'NR==2{print "disk", $2, $3}'`
+
+// NodeSample is the resource usage of a node at a point in time
+type NodeSample struct {
+	// Timestamp is when the usage was sampled
+	Timestamp time.Time
+	CPUs      int
+	// CPUBusy and CPUTotal are the jiffies the CPUs spent busy, and in total, since the node booted
+	CPUBusy, CPUTotal uint64
+	// MemoryTotalBytes and MemoryAvailableBytes are the memory of the node, and how much of it can be allocated without swapping
+	MemoryTotalBytes, MemoryAvailableBytes uint64
+	// DiskTotalBytes and DiskUsedBytes are the size of the filesystem of /var, where runtimes keep images and containers, and how much of it is used
+	DiskTotalBytes, DiskUsedBytes uint64
+}
+
+// SampleNode returns the resource usage of the node of cr
+func SampleNode(cr command.Runner) (NodeSample, error) {
+	rr, err := cr.RunCmd(exec.Command("sh", "-c", nodeSampleScript))
+	if err != nil {
+		return NodeSample{}, errors.Wrap(err, "sampling node usage")
+	}
+	s, err := parseNodeSample(rr.Stdout.String())
+	if err != nil {
+		return NodeSample{}, errors.Wrap(err, "parsing node usage")
+	}
+	s.Timestamp = time.Now()
+	return s, nil
+}
+
+// parseNodeSample parses the output of nodeSampleScript
+func parseNodeSample(out string) (NodeSample, error) {
+	var s NodeSample
+	num := func(v string) (uint64, error) {
+		return strconv.ParseUint(v, 10, 64)
+	}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "cpu":
+			// user nice system idle iowait irq softirq steal, guest time being counted in user already
+			times := fields[1:]
+			if len(times) > 8 {
+				times = times[:8]
+			}
+			for i, f := range times {
+				v, err := num(f)
+				if err != nil {
+					return s, errors.Wrapf(err, "cpu time %q", f)
+				}
+				s.CPUTotal += v
+				if i != 3 && i != 4 {
+					s.CPUBusy += v
+				}
+			}
+		case "MemTotal:", "MemAvailable:":
+			if len(fields) < 2 {
+				return s, errors.Errorf("malformed meminfo %q", sc.Text())
+			}
+			kb, err := num(fields[1])
+			if err != nil {
+				return s, errors.Wrapf(err, "meminfo %q", sc.Text())
+			}
+			if fields[0] == "MemTotal:" {
+				s.MemoryTotalBytes = kb * 1024
+			} else {
+				s.MemoryAvailableBytes = kb * 1024
+			}
+		case "disk":
+			if len(fields) != 3 {
+				return s, errors.Errorf("malformed disk usage %q", sc.Text())
+			}
+			if s.DiskTotalBytes, err = num(fields[1]); err != nil {
+				return s, errors.Wrapf(err, "disk size %q", fields[1])
+			}
+			if s.DiskUsedBytes, err = num(fields[2]); err != nil {
+				return s, errors.Wrapf(err, "disk usage %q", fields[2])
+			}
+		default:
+			if s.CPUs, err = strconv.Atoi(fields[0]); err != nil {
+				return s, errors.Errorf("unexpected line %q", sc.Text())
+			}
+		}
+	}
+	if s.CPUs == 0 || s.CPUTotal == 0 || s.MemoryTotalBytes == 0 {
+		return s, errors.Errorf("incomplete node usage %q", out)
+	}
+	return s, nil
+}
+
+// CPUNanoCores returns the CPU usage of the node in billionths of a core since the earlier sample prev,
+// 0 if prev is not earlier
+func (s NodeSample) CPUNanoCores(prev NodeSample) uint64 {
+	if s.CPUTotal <= prev.CPUTotal || s.CPUBusy < prev.CPUBusy {
+		return 0
+	}
+	busy := float64(s.CPUBusy-prev.CPUBusy) / float64(s.CPUTotal-prev.CPUTotal)
+	return uint64(busy * float64(s.CPUs) * 1e9)
+}
+
+// MemoryUsedBytes returns the memory of the node in use
+func (s NodeSample) MemoryUsedBytes() uint64 {
+	if s.MemoryAvailableBytes > s.MemoryTotalBytes {
+		return 0
+	}
+	return s.MemoryTotalBytes - s.MemoryAvailableBytes
+}
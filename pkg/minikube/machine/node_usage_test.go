@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestSampleNode(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		`sh -c "` + nodeSampleScript + `"`: `4
+cpu  1000 100 400 8000 300 50 50 100 20 0
+MemTotal:        8000000 kB
+MemAvailable:    6000000 kB
+disk 20000000000 5000000000
+`,
+	})
+	got, err := SampleNode(runner)
+	if err != nil {
+		t.Fatalf("SampleNode: %v", err)
+	}
+	got.Timestamp = got.Timestamp.Truncate(0)
+	want := NodeSample{
+		Timestamp:            got.Timestamp,
+		CPUs:                 4,
+		CPUBusy:              1700,
+		CPUTotal:             10000,
+		MemoryTotalBytes:     8192000000,
+		MemoryAvailableBytes: 6144000000,
+		DiskTotalBytes:       20000000000,
+		DiskUsedBytes:        5000000000,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SampleNode mismatch (-want +got):\n%s", diff)
+	}
+	if used := got.MemoryUsedBytes(); used != 2048000000 {
+		t.Errorf("MemoryUsedBytes() = %d, want 2048000000", used)
+	}
+
+	if _, err := parseNodeSample("4\n"); err == nil {
+		t.Errorf("parseNodeSample of an incomplete sample succeeded")
+	}
+}
+
+func TestNodeSampleCPUNanoCores(t *testing.T) {
+	prev := NodeSample{CPUs: 2, CPUBusy: 100, CPUTotal: 1000}
+	tests := []struct {
+		name string
+		cur  NodeSample
+		want uint64
+	}{
+		{"quarter busy", NodeSample{CPUs: 2, CPUBusy: 150, CPUTotal: 1200}, 500000000},
+		{"idle", NodeSample{CPUs: 2, CPUBusy: 100, CPUTotal: 1200}, 0},
+		{"not later", prev, 0},
+	}
+	for _, tc := range tests {
+		if got := tc.cur.CPUNanoCores(prev); got != tc.want {
+			t.Errorf("%s: CPUNanoCores() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
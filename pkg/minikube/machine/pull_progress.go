@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"os"
+	"sync"
+
+	"github.com/cheggaaa/pb/v3"
+	"k8s.io/klog/v2"
+	"k8s.io/minikube/pkg/minikube/cruntime"
+	"k8s.io/minikube/pkg/minikube/detect"
+	"k8s.io/minikube/pkg/minikube/image"
+	"k8s.io/minikube/pkg/minikube/out"
+	"k8s.io/minikube/pkg/minikube/out/register"
+)
+
+// PullProgressBars returns a cruntime.PullProgress rendering the pulls of images as a progress bar each,
+// or as download progress events with --output=json. Call stop once the pulls are done to release the terminal.
+// The returned progress is nil when stdout is not a terminal.
+func PullProgressBars() (progress cruntime.PullProgress, stop func()) {
+	if out.JSON {
+		return pullProgressJSON(), func() {}
+	}
+	if !out.IsTerminal(os.Stdout) || detect.GithubActionRunner() {
+		return nil, func() {}
+	}
+
+	bars := &progressBars{}
+	progress = func(s cruntime.PullStatus) {
+		// the size of images is only known once they are pulled
+		bars.update(s.Image, image.Tag(s.Image), s.Bytes, 0, s.Done)
+	}
+	return progress, bars.stop
+}
+
+// progressBars renders a progress bar each for transfers of images, keyed by what they transfer to where
+type progressBars struct {
+	mu   sync.Mutex
+	pool *pb.Pool
+	bars map[string]*pb.ProgressBar
+}
+
+// update sets the bar of key, labelled name, to current of total bytes, adding the bar unless the transfer is done already
+func (b *progressBars) update(key string, name string, current int64, total int64, done bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.bars[key]
+	if !ok {
+		if done {
+			return
+		}
+		p = pb.Full.New(0)
+		// abbreviate image name for progress
+		maxwidth := 30 - len("...")
+		if len(name) > maxwidth {
+			name = name[0:maxwidth] + "..."
+		}
+		p.Set("prefix", "    > "+name+": ")
+		p.Set(pb.Bytes, true)
+		// Just a hair less than 80 (standard terminal width) for aesthetics & pasting into docs
+		p.SetWidth(79)
+		if b.bars == nil {
+			b.bars = map[string]*pb.ProgressBar{}
+		}
+		b.bars[key] = p
+		if b.pool == nil {
+			var err error
+			if b.pool, err = pb.StartPool(p); err != nil {
+				klog.Warningf("unable to render progress: %v", err)
+			}
+		} else {
+			b.pool.Add(p)
+		}
+	}
+	// transfers of unknown size finish where they got to
+	if total > 0 || !done {
+		p.SetTotal(total)
+		p.SetCurrent(current)
+	}
+	if done {
+		p.Finish()
+	}
+}
+
+// stop releases the terminal once the transfers are done
+func (b *progressBars) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pool != nil {
+		if err := b.pool.Stop(); err != nil {
+			klog.Warningf("stopping progress: %v", err)
+		}
+	}
+}
+
+// pullProgressJSON returns a cruntime.PullProgress printing download events of the pulls, when they start and end,
+// as their size is not known to report the progress in between
+func pullProgressJSON() cruntime.PullProgress {
+	var mu sync.Mutex
+	printed := map[string]bool{}
+	return func(s cruntime.PullStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case !printed[s.Image]:
+			register.PrintDownload(s.Image)
+			printed[s.Image] = true
+		case s.Done:
+			register.PrintDownloadProgress(s.Image, "1")
+		}
+	}
+}
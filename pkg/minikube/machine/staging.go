@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"k8s.io/minikube/pkg/minikube/assets"
+	"k8s.io/minikube/pkg/minikube/command"
+	"k8s.io/minikube/pkg/minikube/vmpath"
+)
+
+// stagingRoot is where image operations stage tarballs and build contexts within the guest VM
+var stagingRoot = path.Join(vmpath.GuestPersistentDir, "staging")
+
+const (
+	// stagingManifest is the file in every staging directory recording the operation which created it
+	stagingManifest = "manifest.json"
+	// staleStagingAge is how long a staging directory may exist before it is considered leaked
+	staleStagingAge = time.Hour
+)
+
+// StagingEntry describes a directory in the staging area of a node
+type StagingEntry struct {
+	// Dir is the path of the staging directory within the guest
+	Dir string `json:"-"`
+	// Operation is the image operation which staged the files, such as "load", "save" or "build"
+	Operation string `json:"operation"`
+	// Subject is the image or file the operation was working on
+	Subject string `json:"subject"`
+	// Created is when the staging directory was created; it is zero when the manifest is missing
+	Created time.Time `json:"created"`
+}
+
+// newStagingDir creates a staging directory for an operation on subject, along with its manifest
+func newStagingDir(cr command.Runner, op string, subject string) (string, error) {
+	dir := path.Join(stagingRoot, fmt.Sprintf("%s-%s", op, uuid.New().String()))
+	if _, err := cr.RunCmd(exec.Command("sudo", "mkdir", "-p", dir)); err != nil {
+		return "", errors.Wrap(err, "creating staging directory")
+	}
+
+	b, err := json.Marshal(StagingEntry{Operation: op, Subject: subject, Created: time.Now()})
+	if err != nil {
+		return "", errors.Wrap(err, "marshalling staging manifest")
+	}
+	if err := cr.Copy(assets.NewMemoryAssetTarget(b, path.Join(dir, stagingManifest), "0644")); err != nil {
+		return "", errors.Wrap(err, "writing staging manifest")
+	}
+	return dir, nil
+}
+
+// releaseStagingDir removes a staging directory once the operation using it is done
+func releaseStagingDir(cr command.Runner, dir string) {
+	if _, err := cr.RunCmd(exec.Command("sudo", "rm", "-rf", dir)); err != nil {
+		klog.Warningf("unable to remove staging directory %s: %v", dir, err)
+	}
+}
+
+// ListStaging returns the directories in the staging area of a node
+func ListStaging(cr command.Runner) ([]StagingEntry, error) {
+	rr, err := cr.RunCmd(exec.Command("sudo", "find", stagingRoot, "-mindepth", "1", "-maxdepth", "1", "-type", "d"))
+	if err != nil {
+		if _, serr := cr.RunCmd(exec.Command("sudo", "test", "-d", stagingRoot)); serr != nil {
+			// nothing was ever staged on this node
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "listing staging directories")
+	}
+
+	var entries []StagingEntry
+	for _, dir := range strings.Split(strings.TrimSpace(rr.Stdout.String()), "\n") {
+		if dir == "" {
+			continue
+		}
+		e := StagingEntry{}
+		mr, err := cr.RunCmd(exec.Command("sudo", "cat", path.Join(dir, stagingManifest)))
+		if err != nil {
+			klog.Warningf("staging directory %s has no manifest: %v", dir, err)
+		} else if err := json.Unmarshal(mr.Stdout.Bytes(), &e); err != nil {
+			klog.Warningf("unable to parse the manifest of staging directory %s: %v", dir, err)
+		}
+		e.Dir = dir
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// StaleStaging returns the entries which were left behind by operations that never finished,
+// either because they are older than staleStagingAge or because they have no manifest
+func StaleStaging(entries []StagingEntry, now time.Time) []StagingEntry {
+	var stale []StagingEntry
+	for _, e := range entries {
+		if e.Created.IsZero() || now.Sub(e.Created) > staleStagingAge {
+			stale = append(stale, e)
+		}
+	}
+	return stale
+}
+
+// RemoveStaging removes the given staging directories from a node
+func RemoveStaging(cr command.Runner, entries []StagingEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	args := []string{"rm", "-rf"}
+	for _, e := range entries {
+		args = append(args, e.Dir)
+	}
+	if _, err := cr.RunCmd(exec.Command("sudo", args...)); err != nil {
+		return errors.Wrap(err, "removing staging directories")
+	}
+	return nil
+}
+
+// SweepStaging removes the stale entries from the staging area of a node.
+// With keep set, as it is for --runtime-debug, stale entries are only logged so they can be inspected.
+func SweepStaging(cr command.Runner, keep bool) error {
+	entries, err := ListStaging(cr)
+	if err != nil {
+		return err
+	}
+	stale := StaleStaging(entries, time.Now())
+	for _, e := range stale {
+		klog.Infof("stale staging directory %s from %s of %q created at %s", e.Dir, e.Operation, e.Subject, e.Created)
+	}
+	if keep {
+		return nil
+	}
+	return RemoveStaging(cr, stale)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/minikube/pkg/minikube/command"
+)
+
+func TestListStaging(t *testing.T) {
+	runner := command.NewFakeCommandRunner()
+	runner.SetCommandToOutput(map[string]string{
+		"sudo find /var/lib/minikube/staging -mindepth 1 -maxdepth 1 -type d":           "/var/lib/minikube/staging/load-1\n/var/lib/minikube/staging/build-2\n/var/lib/minikube/staging/save-3\n",
+		"sudo cat /var/lib/minikube/staging/load-1/manifest.json":                       `{"operation":"load","subject":"busybox:latest","created":"2024-03-01T10:00:00Z"}`,
+		"sudo cat /var/lib/minikube/staging/build-2/manifest.json":                      `{"operation":"build","subject":"app:dev","created":"2024-03-01T11:30:00Z"}`,
+		"sudo rm -rf /var/lib/minikube/staging/load-1 /var/lib/minikube/staging/save-3": "",
+	})
+
+	entries, err := ListStaging(runner)
+	if err != nil {
+		t.Fatalf("ListStaging: %v", err)
+	}
+	want := []StagingEntry{
+		{Dir: "/var/lib/minikube/staging/load-1", Operation: "load", Subject: "busybox:latest", Created: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{Dir: "/var/lib/minikube/staging/build-2", Operation: "build", Subject: "app:dev", Created: time.Date(2024, 3, 1, 11, 30, 0, 0, time.UTC)},
+		{Dir: "/var/lib/minikube/staging/save-3"},
+	}
+	if diff := cmp.Diff(want, entries); diff != "" {
+		t.Fatalf("ListStaging returned diff (-want +got):\n%s", diff)
+	}
+
+	stale := StaleStaging(entries, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	if diff := cmp.Diff([]StagingEntry{want[0], want[2]}, stale); diff != "" {
+		t.Errorf("StaleStaging returned diff (-want +got):\n%s", diff)
+	}
+	if err := RemoveStaging(runner, stale); err != nil {
+		t.Errorf("RemoveStaging: %v", err)
+	}
+}
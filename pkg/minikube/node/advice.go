@@ -69,6 +69,11 @@ func ExitIfFatal(err error, force bool) {
 		}, "The kubeadm binary within the Docker container is not executable")
 	}
 
+	if criErr, ok := err.(*cruntime.ErrCRIVersion); ok && criErr.Fatal {
+		exit.Message(reason.RuntimeCRIVersion, "{{.runtime}} {{.version}} is not supported by Kubernetes {{.k8sVersion}}, which requires {{.required}}",
+			out.V{"runtime": criErr.Runtime, "version": criErr.Installed, "k8sVersion": criErr.KubernetesVersion, "required": criErr.Required})
+	}
+
 	if rtErr, ok := err.(*cruntime.ErrServiceVersion); ok {
 		exit.Message(reason.Kind{
 			ID:       "PROVIDER_INVALID_VERSION",
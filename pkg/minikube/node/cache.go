@@ -32,6 +32,7 @@ import (
 	"k8s.io/minikube/pkg/drivers/kic"
 	"k8s.io/minikube/pkg/minikube/config"
 	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/minikube/cruntime"
 	"k8s.io/minikube/pkg/minikube/download"
 	"k8s.io/minikube/pkg/minikube/driver"
 	"k8s.io/minikube/pkg/minikube/exit"
@@ -42,6 +43,7 @@ import (
 	"k8s.io/minikube/pkg/minikube/out/register"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/util"
 )
 
 const (
@@ -72,7 +74,7 @@ func beginCacheKubernetesImages(g *errgroup.Group, imageRepository string, k8sVe
 }
 
 // handleDownloadOnly caches appropariate binaries and images
-func handleDownloadOnly(cacheGroup, kicGroup *errgroup.Group, k8sVersion, containerRuntime, driverName string) {
+func handleDownloadOnly(cacheGroup, kicGroup *errgroup.Group, imageRepository, k8sVersion, containerRuntime, driverName string) {
 	// If --download-only, complete the remaining downloads and exit.
 	if !viper.GetBool("download-only") {
 		return
@@ -92,6 +94,16 @@ func handleDownloadOnly(cacheGroup, kicGroup *errgroup.Group, k8sVersion, contai
 	if err := saveImagesToTarFromConfig(); err != nil {
 		exit.Error(reason.InetCacheTar, "Failed to cache images to tar", err)
 	}
+	// porto loads its pause image from the cache on start, so an air-gapped start can follow
+	if containerRuntime == constants.Porto {
+		kv, err := util.ParseKubernetesVersion(k8sVersion)
+		if err != nil {
+			exit.Error(reason.InetCacheTar, "Failed to parse Kubernetes version", err)
+		}
+		if err := image.SaveToDir([]string{cruntime.PortoSandboxImage(kv, imageRepository)}, detect.ImageCacheDir(), false); err != nil {
+			exit.Error(reason.InetCacheTar, "Failed to cache images to tar", err)
+		}
+	}
 	out.Step(style.Check, "Download complete!")
 	os.Exit(0)
 }
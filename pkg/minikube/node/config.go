@@ -17,6 +17,7 @@ limitations under the License.
 package node
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -73,7 +74,9 @@ func maskProxyPassword(v string) string {
 }
 
 func showVersionInfo(k8sVersion string, cr cruntime.Manager) {
-	version, _ := cr.Version()
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+	defer cancel()
+	version, _ := cr.Version(ctx)
 	register.Reg.SetStep(register.PreparingKubernetes)
 	out.Step(cr.Style(), "Preparing Kubernetes {{.k8sVersion}} on {{.runtime}} {{.runtimeVersion}} ...", out.V{"k8sVersion": k8sVersion, "runtime": cr.Name(), "runtimeVersion": version})
 	for _, v := range config.DockerOpt {
@@ -86,13 +89,15 @@ func showVersionInfo(k8sVersion string, cr cruntime.Manager) {
 }
 
 func showNoK8sVersionInfo(cr cruntime.Manager) {
-	err := cruntime.CheckCompatibility(cr)
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+	defer cancel()
+	err := cruntime.CheckCompatibility(ctx, cr)
 	if err != nil {
 		klog.Warningf("%s check compatibility failed: %v", cr.Name(), err)
 
 	}
 
-	version, err := cr.Version()
+	version, err := cr.Version(ctx)
 	if err != nil {
 		klog.Warningf("%s get version failed: %v", cr.Name(), err)
 	}
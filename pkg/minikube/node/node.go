@@ -51,6 +51,13 @@ func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool) error {
 		}
 	}
 
+	// an existing node records the runtime it ran, which differs when the cluster moves to another one
+	var migrateFrom string
+	if rt := cc.KubernetesConfig.ContainerRuntime; n.ContainerRuntime != "" && n.ContainerRuntime != rt {
+		migrateFrom = n.ContainerRuntime
+		n.ContainerRuntime = rt
+	}
+
 	if err := config.SaveNode(cc, &n); err != nil {
 		return errors.Wrap(err, "save node")
 	}
@@ -67,6 +74,7 @@ func Add(cc *config.ClusterConfig, n config.Node, delOnFail bool) error {
 		Cfg:            cc,
 		Node:           &n,
 		ExistingAddons: nil,
+		MigrateFrom:    migrateFrom,
 	}
 
 	_, err = Start(s, false)
@@ -17,6 +17,8 @@ limitations under the License.
 package node
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -31,6 +33,7 @@ import (
 	"github.com/blang/semver/v4"
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/host"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
@@ -40,6 +43,7 @@ import (
 	"k8s.io/minikube/pkg/addons"
 	"k8s.io/minikube/pkg/drivers/kic/oci"
 	"k8s.io/minikube/pkg/kapi"
+	"k8s.io/minikube/pkg/minikube/assets"
 	"k8s.io/minikube/pkg/minikube/bootstrapper"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/bsutil"
 	"k8s.io/minikube/pkg/minikube/bootstrapper/images"
@@ -63,7 +67,9 @@ import (
 	"k8s.io/minikube/pkg/minikube/proxy"
 	"k8s.io/minikube/pkg/minikube/reason"
 	"k8s.io/minikube/pkg/minikube/registry"
+	"k8s.io/minikube/pkg/minikube/sbom"
 	"k8s.io/minikube/pkg/minikube/style"
+	"k8s.io/minikube/pkg/minikube/sysinit"
 	"k8s.io/minikube/pkg/minikube/vmpath"
 	"k8s.io/minikube/pkg/network"
 	"k8s.io/minikube/pkg/util"
@@ -73,6 +79,9 @@ import (
 
 const waitTimeout = "wait-timeout"
 
+// postRuntimeHookName is the name the post-runtime hook is copied to the node as
+const postRuntimeHookName = "post-runtime-hook"
+
 var (
 	kicGroup   errgroup.Group
 	cacheGroup errgroup.Group
@@ -88,6 +97,8 @@ type Starter struct {
 	Cfg            *config.ClusterConfig
 	Node           *config.Node
 	ExistingAddons map[string]bool
+	// MigrateFrom is the container runtime an existing node moves away from, whose images are carried over to the new one
+	MigrateFrom string
 }
 
 // Start spins up a guest and starts the Kubernetes node.
@@ -97,9 +108,16 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 	if err != nil {
 		return nil, err
 	}
+	if starter.PreExists {
+		if err := upgradePorto(starter); err != nil {
+			return nil, errors.Wrap(err, "Failed to upgrade porto")
+		}
+	}
+	migration := exportForMigration(starter)
 	if stopk8s {
 		nv := semver.Version{Major: 0, Minor: 0, Patch: 0}
 		cr := configureRuntimes(starter.Runner, *starter.Cfg, nv)
+		importMigration(migration, cr)
 
 		showNoK8sVersionInfo(cr)
 
@@ -117,10 +135,24 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 
 	// configure the runtime (docker, containerd, crio)
 	cr := configureRuntimes(starter.Runner, *starter.Cfg, sv)
+	importMigration(migration, cr)
 
 	// check if installed runtime is compatible with current minikube code
-	if err = cruntime.CheckCompatibility(cr); err != nil {
-		return nil, err
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+	err = cruntime.CheckCompatibility(ctx, cr)
+	cancel()
+	if err != nil {
+		var criErr *cruntime.ErrCRIVersion
+		if !errors.As(err, &criErr) || criErr.Fatal {
+			return nil, err
+		}
+		out.WarningT("{{.runtime}} {{.version}} lacks CRI features Kubernetes {{.k8sVersion}} relies on, and the cluster may be degraded. Upgrade {{.runtime}} to {{.required}} or newer.",
+			out.V{"runtime": criErr.Runtime, "version": criErr.Installed, "k8sVersion": criErr.KubernetesVersion, "required": criErr.Required})
+	}
+
+	// remove what interrupted image operations left behind, unless debugging needs it
+	if err := machine.SweepStaging(starter.Runner, starter.Cfg.RuntimeDebug); err != nil {
+		klog.Warningf("unable to sweep the staging area: %v", err)
 	}
 
 	showVersionInfo(starter.Node.KubernetesVersion, cr)
@@ -145,6 +177,7 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to get bootstrapper")
 		}
+		trackRuntimeChange(bs, cr)
 
 		if err = bs.SetupCerts(*starter.Cfg, *starter.Node); err != nil {
 			return nil, errors.Wrap(err, "setting up certs")
@@ -227,6 +260,7 @@ func Start(starter Starter, apiServer bool) (*kubeconfig.Settings, error) {
 
 	klog.Infof("waiting for startup goroutines ...")
 	wg.Wait()
+	showImageSources(starter.Runner, *starter.Cfg)
 
 	// update config with enabled addons
 	if starter.ExistingAddons != nil {
@@ -275,6 +309,7 @@ func handleAPIServer(starter Starter, cr cruntime.Manager, hostIP net.IP) (*kube
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "Failed to setup kubeadm")
 	}
+	trackRuntimeChange(bs, cr)
 	err = bs.StartCluster(*starter.Cfg)
 	if err != nil {
 		ExitIfFatal(err, false)
@@ -379,7 +414,7 @@ func Provision(cc *config.ClusterConfig, n *config.Node, apiServer bool, delOnFa
 		return nil, false, nil, nil, errors.Wrap(err, "Failed to save config")
 	}
 
-	handleDownloadOnly(&cacheGroup, &kicGroup, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
+	handleDownloadOnly(&cacheGroup, &kicGroup, cc.KubernetesConfig.ImageRepository, n.KubernetesVersion, cc.KubernetesConfig.ContainerRuntime, cc.Driver)
 	if driver.IsKIC(cc.Driver) {
 		waitDownloadKicBaseImage(&kicGroup)
 	}
@@ -397,7 +432,18 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 		ImageRepository:   cc.KubernetesConfig.ImageRepository,
 		KubernetesVersion: kv,
 		InsecureRegistry:  cc.InsecureRegistry,
+		Debug:             cc.RuntimeDebug,
+		DownloadTmpfsMB:   portoDownloadTmpfs(cc),
+	}
+	gates, err := cruntime.ParseFeatureGates(cc.RuntimeFeatureGates)
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid runtime feature gates", err)
 	}
+	co.FeatureGates = gates
+	co.Ulimits, co.AllowedUnsafeSysctls = portoLimits(cc)
+	co.BuildCacheMB = portoBuildCache(cc)
+	co.SocketUser = portoSocketUser(cc)
+	co.RegistryCertsDir = portoRegistryCerts(cc)
 	if cc.GPUs != "" {
 		co.GPUs = true
 	}
@@ -436,7 +482,9 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 			KubernetesVersion: co.KubernetesVersion,
 			InsecureRegistry:  co.InsecureRegistry})
 		if err == nil {
-			err = containerd.Enable(false, cgroupDriver(cc), inUserNamespace) // do not disableOthers, as it's not primary cr
+			ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+			err = containerd.Enable(ctx, false, cgroupDriver(cc), inUserNamespace) // do not disableOthers, as it's not primary cr
+			cancel()
 		}
 		if err != nil {
 			klog.Warningf("cannot ensure containerd is configured properly and reloaded for docker - cluster might be unstable: %v", err)
@@ -444,10 +492,23 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 	}
 
 	disableOthers := !driver.BareMetal(cc.Driver)
-	if err = cr.Enable(disableOthers, cgroupDriver(cc), inUserNamespace); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	if err = cr.Enable(ctx, disableOthers, cgroupDriver(cc), inUserNamespace); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			exit.Message(reason.RuntimeEnable, "Enabling {{.runtime}} did not finish within {{.timeout}}, the runtime or one of its tools hangs", out.V{"runtime": cr.Name(), "timeout": cruntime.OperationTimeout})
+		}
+		var nsErr *cruntime.ErrCgroupNamespaces
+		if errors.As(err, &nsErr) {
+			exit.Message(reason.RuntimeCgroupNamespaces, "{{.runtime}} can't isolate containers in rootless mode without cgroup namespaces, which this kernel does not provide", out.V{"runtime": nsErr.Runtime})
+		}
+		exitPortoStore(err, cc.Name)
 		exit.Error(reason.RuntimeEnable, "Failed to enable container runtime", err)
 	}
 
+	installWasmShim(cc, cr)
+	installRuntimeClass(cc, cr)
+
 	// Wait for the CRI to be "live", before returning it
 	if err = waitForCRISocket(runner, cr.SocketPath(), 60, 1); err != nil {
 		exit.Error(reason.RuntimeEnable, "Failed to start container runtime", err)
@@ -458,9 +519,324 @@ func configureRuntimes(runner cruntime.CommandRunner, cc config.ClusterConfig, k
 		exit.Error(reason.RuntimeEnable, "Failed to start container runtime", err)
 	}
 
+	if err := runPostRuntimeHook(runner, cc, cr); err != nil {
+		exit.Error(reason.RuntimeHook, "Failed to run the post-runtime hook", err)
+	}
+
 	return cr
 }
 
+// installWasmShim installs the WebAssembly shim the cluster asks for, exiting when the runtime can't run it
+func installWasmShim(cc config.ClusterConfig, cr cruntime.Manager) {
+	if cc.WasmShim == "" {
+		return
+	}
+	shim, err := cruntime.WasmShimNamed(cc.WasmShim)
+	if err != nil {
+		exit.Message(reason.Usage, "{{.err}}", out.V{"err": err})
+	}
+	if !cr.Capabilities().SupportsWasm {
+		exit.Message(reason.RuntimeUnsupportedFeature, "The {{.runtime}} container runtime can't run WebAssembly workloads, --wasm-shim requires --container-runtime=containerd", out.V{"runtime": cr.Name()})
+	}
+	out.Step(style.SubStep, "Installing the {{.shim}} WebAssembly shim {{.version}} ...", out.V{"shim": shim.Name, "version": shim.Version})
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	if err := cr.InstallWasmShim(ctx, shim); err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to install the WebAssembly shim", err)
+	}
+}
+
+// installRuntimeClass installs the sandboxed runtime the cluster asks for, exiting when the container runtime can't run it
+func installRuntimeClass(cc config.ClusterConfig, cr cruntime.Manager) {
+	if cc.RuntimeClass != cruntime.KataRuntimeClass {
+		return
+	}
+	if !cr.Capabilities().SupportsKata {
+		exit.Message(reason.RuntimeUnsupportedFeature, "The {{.runtime}} container runtime can't run pods in Kata Containers, --runtime-class=kata requires --container-runtime=containerd or cri-o", out.V{"runtime": cr.Name()})
+	}
+	out.Step(style.SubStep, "Installing Kata Containers ...")
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	if err := cr.InstallKata(ctx); err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to install Kata Containers", err)
+	}
+}
+
+// runPostRuntimeHook copies the post-runtime hook of the cluster to the node and runs it, telling it about the runtime
+// it runs after in the environment
+func runPostRuntimeHook(runner cruntime.CommandRunner, cc config.ClusterConfig, cr cruntime.Manager) error {
+	if cc.PostRuntimeHook == "" || cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return nil
+	}
+	f, err := assets.NewFileAsset(cc.PostRuntimeHook, vmpath.GuestPersistentDir, postRuntimeHookName, "0755")
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", cc.PostRuntimeHook)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			klog.Warningf("error closing the file %s: %v", f.GetSourcePath(), err)
+		}
+	}()
+	if err := runner.Copy(f); err != nil {
+		return errors.Wrap(err, "copying the hook")
+	}
+
+	info, err := cr.RuntimeInfo()
+	if err != nil {
+		return errors.Wrap(err, "runtime info")
+	}
+	out.Step(style.SubStep, "Running the post-runtime hook {{.path}} ...", out.V{"path": cc.PostRuntimeHook})
+	c := exec.Command("sudo", "env",
+		"MINIKUBE_RUNTIME="+cc.KubernetesConfig.ContainerRuntime,
+		"MINIKUBE_CRI_SOCKET="+info.Socket,
+		"MINIKUBE_RUNTIME_VERSION="+info.Version,
+		"MINIKUBE_CGROUP_DRIVER="+info.CgroupDriver,
+		"MINIKUBE_PROFILE="+cc.Name,
+		path.Join(vmpath.GuestPersistentDir, postRuntimeHookName))
+	rr, err := runner.RunCmd(c)
+	if err != nil {
+		return err
+	}
+	klog.Infof("post-runtime hook output: %s", rr.Output())
+	return nil
+}
+
+// portoDownloadTmpfs returns the size in MiB of the tmpfs porto should download image layers to,
+// warning when it would take too much of the node memory
+func portoDownloadTmpfs(cc config.ClusterConfig) int {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return 0
+	}
+	size, err := cruntime.DownloadTmpfsSize(cc.KubernetesConfig.ExtraOptions.Get(cruntime.PortoDownloadTmpfsOption, bsutil.Porto), cc.Memory)
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid porto extra-config", err)
+	}
+	// tmpfs pages count against the memory of the node, which kubelet and the workloads need more
+	if size > 0 && size > cc.Memory/2 {
+		out.WarningT("The {{.size}}MiB tmpfs for porto layer downloads uses more than half of the {{.memory}}MiB of memory, consider a smaller porto.{{.option}} or a larger --memory", out.V{"size": size, "memory": cc.Memory, "option": cruntime.PortoDownloadTmpfsOption})
+	}
+	return size
+}
+
+// portoLimits returns the default ulimits of porto containers and the unsafe sysctls pods may set
+func portoLimits(cc config.ClusterConfig) (string, []string) {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return "", nil
+	}
+	ulimits, err := cruntime.ParseUlimits(cc.KubernetesConfig.ExtraOptions.Get(cruntime.PortoUlimitsOption, bsutil.Porto))
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid porto extra-config", err)
+	}
+	return ulimits, cruntime.AllowedUnsafeSysctls(cc.KubernetesConfig.ExtraOptions)
+}
+
+// portoBuildCache returns the size in MiB the layer cache of porto image builds is kept under
+func portoBuildCache(cc config.ClusterConfig) int {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return 0
+	}
+	size, err := cruntime.BuildCacheSize(cc.KubernetesConfig.ExtraOptions.Get(cruntime.PortoBuildCacheOption, bsutil.Porto))
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid porto extra-config", err)
+	}
+	return size
+}
+
+// portoSocketUser returns the user of the node to give access to the porto sockets without sudo, if the cluster asks for it
+func portoSocketUser(cc config.ClusterConfig) string {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return ""
+	}
+	enabled, err := cruntime.NonRootSocket(cc.KubernetesConfig.ExtraOptions.Get(cruntime.PortoNonRootSocketOption, bsutil.Porto))
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid porto extra-config", err)
+	}
+	switch {
+	case !enabled:
+		return ""
+	case driver.BareMetal(cc.Driver):
+		out.WarningT("Ignoring porto.{{.option}}, the none driver leaves the porto group of the host to its administrator", out.V{"option": cruntime.PortoNonRootSocketOption})
+		return ""
+	case driver.IsSSH(cc.Driver):
+		return cc.SSHUser
+	default:
+		// the user minikube logs into the ISO and the kicbase image as
+		return "docker"
+	}
+}
+
+// portoRegistryCerts returns the directory of the node portod reads registry certificates from, if the cluster sets one
+func portoRegistryCerts(cc config.ClusterConfig) string {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return ""
+	}
+	dir, err := cruntime.RegistryCertsDir(cc.KubernetesConfig.ExtraOptions.Get(cruntime.PortoRegistryCertsOption, bsutil.Porto), cc.MountString)
+	if err != nil {
+		exit.Error(reason.Usage, "Invalid porto extra-config", err)
+	}
+	// the certificates only follow the host when they come in through the mount
+	if dir != "" && !cc.Mount && !driver.BareMetal(cc.Driver) {
+		out.WarningT("porto.{{.option}} is set without --mount, the certificates in {{.dir}} have to be put on the node by hand", out.V{"option": cruntime.PortoRegistryCertsOption, "dir": dir})
+	}
+	return dir
+}
+
+// showImageSources prints how many of the images of a porto node were preloaded, loaded from the image cache or pulled,
+// so that a preload which silently stopped working, and the network pulls which replace it, stand out
+func showImageSources(runner cruntime.CommandRunner, cc config.ClusterConfig) {
+	if cc.KubernetesConfig.ContainerRuntime != constants.Porto {
+		return
+	}
+	sources, err := cruntime.ImageSources(runner)
+	if err != nil {
+		klog.Warningf("unable to summarize the image sources: %v", err)
+		return
+	}
+	if len(sources) == 0 {
+		return
+	}
+	counts := cruntime.CountImageSources(sources)
+	st := style.Porto
+	if counts[cruntime.ImageFailed] > 0 {
+		st = style.Warning
+	}
+	out.Step(st, "{{.total}} images: {{.preloaded}} preloaded, {{.cached}} cached, {{.pulled}} pulled, {{.failed}} failed", out.V{
+		"total":     len(sources),
+		"preloaded": counts[cruntime.ImagePreloaded],
+		"cached":    counts[cruntime.ImageCached],
+		"pulled":    counts[cruntime.ImagePulled],
+		"failed":    counts[cruntime.ImageFailed],
+	})
+}
+
+// upgradePorto upgrades porto on an existing node in place when this minikube ships a newer release than the node runs.
+// The upgrade happens with --upgrade-porto, or when the user agrees to it at the prompt; otherwise the skew is only reported.
+// A failed check for an upgrade is only logged, while a failed upgrade is returned.
+func upgradePorto(starter Starter) error {
+	if starter.Cfg.KubernetesConfig.ContainerRuntime != constants.Porto || driver.BareMetal(starter.Cfg.Driver) {
+		return nil
+	}
+	cr, err := cruntime.New(cruntime.Config{Type: constants.Porto, Runner: starter.Runner})
+	if err != nil {
+		klog.Warningf("porto runtime: %v", err)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.QueryTimeout)
+	defer cancel()
+	running, err := cr.Version(ctx)
+	if err != nil {
+		klog.Warningf("unable to get the porto version of the node: %v", err)
+		return nil
+	}
+	arch, err := cruntime.NodeArch(starter.Runner)
+	if err != nil {
+		klog.Warningf("unable to get the architecture of the node: %v", err)
+		return nil
+	}
+	cs, err := sbom.Components()
+	if err != nil {
+		klog.Warningf("unable to read the components this minikube ships: %v", err)
+		return nil
+	}
+	u, err := cruntime.PortoUpgradeFor(running, arch, cs)
+	if err != nil {
+		klog.Warningf("unable to check for a porto upgrade: %v", err)
+		return nil
+	}
+	if u == nil {
+		return nil
+	}
+	if !confirmPortoUpgrade(u) {
+		out.WarningT("The node runs porto {{.from}}, older than porto {{.to}} this minikube ships", out.V{"from": u.From, "to": u.To.Version})
+		out.Styled(style.Tip, "To upgrade it in place, run: minikube start --upgrade-porto")
+		return nil
+	}
+	out.Step(style.Sparkle, "Upgrading porto {{.from}} to {{.to}} ...", out.V{"from": u.From, "to": u.To.Version})
+	return cr.(*cruntime.Porto).UpgradeInPlace(u)
+}
+
+// exitPortoStore exits telling how to recreate the cluster when err is a store of porto the node can't migrate,
+// instead of leaving portod to fail on it
+func exitPortoStore(err error, profile string) {
+	var storeErr *cruntime.ErrPortoStore
+	if errors.As(err, &storeErr) {
+		exit.Message(reason.RuntimePortoStore, "{{.error}}. Delete and recreate the cluster to start over with an empty store: minikube delete -p {{.profile}} && minikube start -p {{.profile}}", out.V{"error": storeErr.Error(), "profile": profile})
+	}
+}
+
+// exportForMigration exports the images of the container runtime an existing node moves away from and removes its pods,
+// returning the migration to finish once the new runtime is enabled. A node which does not change runtimes returns
+// the migration an earlier start left unfinished, if any, so that the images it has yet to import are not lost.
+func exportForMigration(starter Starter) *cruntime.Migration {
+	to := starter.Cfg.KubernetesConfig.ContainerRuntime
+	if !starter.PreExists {
+		return nil
+	}
+	if !cruntime.MigrationSupported(starter.MigrateFrom, to) {
+		return pendingMigration(starter)
+	}
+	from, err := cruntime.New(cruntime.Config{Type: starter.MigrateFrom, Runner: starter.Runner})
+	if err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to migrate the container runtime", err)
+	}
+	out.Step(style.Sparkle, "Migrating {{.node}} from {{.from}} to {{.to}}, its pods are recreated ...", out.V{"node": starter.Node.Name, "from": starter.MigrateFrom, "to": to})
+	m := &cruntime.Migration{From: from, Runner: starter.Runner, Init: sysinit.New(starter.Runner)}
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	if err := m.Export(ctx); err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to export the images of the container runtime", err)
+	}
+	return m
+}
+
+// pendingMigration returns the migration an earlier start of the node left unfinished, or nil if there is none
+func pendingMigration(starter Starter) *cruntime.Migration {
+	m, err := cruntime.PendingMigration(starter.Runner)
+	if err != nil {
+		klog.Warningf("unable to check for an unfinished runtime migration: %v", err)
+		return nil
+	}
+	if m != nil {
+		out.Step(style.Sparkle, "Resuming the migration of {{.node}}, {{.count}} images are left to import ...", out.V{"node": starter.Node.Name, "count": m.Pending()})
+	}
+	return m
+}
+
+// importMigration imports the images exported by exportForMigration into the runtime the node moved to
+func importMigration(m *cruntime.Migration, cr cruntime.Manager) {
+	if m == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cruntime.OperationTimeout)
+	defer cancel()
+	if err := m.Import(ctx, cr); err != nil {
+		exit.Error(reason.RuntimeEnable, "Failed to import the images into the container runtime", err)
+	}
+}
+
+// trackRuntimeChange hands what configureRuntimes changed to bs, so that its kubelet restarts take it into account
+func trackRuntimeChange(bs bootstrapper.Bootstrapper, cr cruntime.Manager) {
+	if t, ok := bs.(interface{ RuntimeChanged(cruntime.Manager) }); ok {
+		t.RuntimeChanged(cr)
+	}
+}
+
+// confirmPortoUpgrade returns whether porto should be upgraded, asking the user unless --upgrade-porto was given
+func confirmPortoUpgrade(u *cruntime.PortoUpgrade) bool {
+	if viper.IsSet("upgrade-porto") || !viper.GetBool("interactive") || !isatty.IsTerminal(os.Stdin.Fd()) {
+		return viper.GetBool("upgrade-porto")
+	}
+	out.Styled(style.Notice, "The node runs porto {{.from}}, older than porto {{.to}} this minikube ships. kubelet is stopped while porto is upgraded.", out.V{"from": u.From, "to": u.To.Version})
+	out.String("Upgrade porto in place? [y/N]: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		klog.Warningf("reading the answer: %v", err)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // cgroupDriver returns cgroup driver that should be used to further configure container runtime, node(s) and cluster.
 // It is based on:
 // - (forced) user preference (set via flags or env), if present, or
@@ -647,6 +1023,9 @@ func startMachine(cfg *config.ClusterConfig, node *config.Node, delOnFail bool)
 	if err != nil {
 		return runner, preExists, m, host, errors.Wrap(err, "Failed to get command runner")
 	}
+	if cfg.RuntimeDebug {
+		runner = transcriptRunner(runner, cfg, node)
+	}
 
 	ip, err := validateNetwork(host, runner, cfg.KubernetesConfig.ImageRepository)
 	if err != nil {
@@ -670,6 +1049,27 @@ func startMachine(cfg *config.ClusterConfig, node *config.Node, delOnFail bool)
 	return runner, preExists, m, host, err
 }
 
+// transcriptRunner wraps the runner of the node to record the commands run on it to the transcript of the profile.
+// The transcript is started over when the primary control plane is provisioned, so it only covers the last start.
+func transcriptRunner(runner command.Runner, cfg *config.ClusterConfig, node *config.Node) command.Runner {
+	path := localpath.RuntimeTranscript(cfg.Name)
+	flag := os.O_APPEND | os.O_CREATE | os.O_WRONLY
+	if cp, err := config.PrimaryControlPlane(cfg); err == nil && cp.Name == node.Name {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		klog.Warningf("unable to create runtime transcript %s: %v", path, err)
+		return runner
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "==> %s (%s) <==\n", config.MachineName(*cfg, *node), time.Now().Format(time.RFC3339)); err != nil {
+		klog.Warningf("unable to write runtime transcript %s: %v", path, err)
+	}
+	klog.Infof("recording the commands run on %s to %s", config.MachineName(*cfg, *node), path)
+	return command.NewTranscriptRunner(runner, path)
+}
+
 // getPort asks the kernel for a free open port that is ready to use
 func getPort() (int, error) {
 	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
@@ -137,6 +137,7 @@ const (
 	ExRuntimeError       = 90
 	ExRuntimeNotRunning  = 93
 	ExRuntimeNotFound    = 95
+	ExRuntimeUnsupported = 96
 	ExRuntimeUnavailable = 99
 
 	// Error codes specific to the Kubernetes control plane
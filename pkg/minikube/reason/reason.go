@@ -288,6 +288,8 @@ var (
 	HostPurge = Kind{ID: "HOST_PURGE", ExitCode: ExHostError}
 	// minikube failed to persist profile config
 	HostSaveProfile = Kind{ID: "HOST_SAVE_PROFILE", ExitCode: ExHostConfig}
+	// minikube doctor found problems with the host or the cluster
+	HostDoctorProblems = Kind{ID: "HOST_DOCTOR_PROBLEMS", ExitCode: ExHostError, Style: style.Shrug}
 
 	// minikube could not find a provider for the selected driver
 	ProviderNotFound = Kind{ID: "PROVIDER_NOT_FOUND", ExitCode: ExProviderNotFound}
@@ -349,6 +351,16 @@ var (
 	GuestImagePush = Kind{ID: "GUEST_IMAGE_PUSH", ExitCode: ExGuestError}
 	// minikube failed to tag an image
 	GuestImageTag = Kind{ID: "GUEST_IMAGE_TAG", ExitCode: ExGuestError}
+	// minikube failed to inspect an image
+	GuestImageInspect = Kind{ID: "GUEST_IMAGE_INSPECT", ExitCode: ExGuestError}
+	// the image to inspect is not in the cluster
+	GuestImageNotFound = Kind{ID: "GUEST_IMAGE_NOT_FOUND", ExitCode: ExGuestNotFound}
+	// minikube failed to list the containers of a cluster node
+	GuestListContainers = Kind{ID: "GUEST_LIST_CONTAINERS", ExitCode: ExGuestError}
+	// minikube failed to checkpoint the containers of a cluster node
+	GuestCheckpoint = Kind{ID: "GUEST_CHECKPOINT", ExitCode: ExGuestError}
+	// minikube failed to restore the checkpointed containers of a cluster node
+	GuestRestore = Kind{ID: "GUEST_RESTORE", ExitCode: ExGuestError}
 	// minikube failed to load host
 	GuestLoadHost = Kind{ID: "GUEST_LOAD_HOST", ExitCode: ExGuestError}
 	// minkube failed to create a mount
@@ -439,6 +451,47 @@ var (
 	RuntimeEnable = Kind{ID: "RUNTIME_ENABLE", ExitCode: ExRuntimeError}
 	// minikube failed to cache images for the current container runtime
 	RuntimeCache = Kind{ID: "RUNTIME_CACHE", ExitCode: ExRuntimeError}
+	// the CRI implementation of the container runtime is too old for the requested Kubernetes version
+	RuntimeCRIVersion = Kind{
+		ID:       "RUNTIME_CRI_VERSION",
+		ExitCode: ExRuntimeUnsupported,
+		Advice:   translate.T("Upgrade the container runtime on the node, for example by running 'minikube delete' and starting with a newer ISO or kicbase image, or choose an older version with --kubernetes-version"),
+		Style:    style.Unsupported,
+	}
+	// the kernel lacks cgroup namespaces, which the container runtime requires in rootless mode
+	RuntimeCgroupNamespaces = Kind{
+		ID:       "RUNTIME_CGROUP_NAMESPACES",
+		ExitCode: ExRuntimeUnsupported,
+		Advice:   translate.T("Use a kernel with cgroup namespaces enabled (CONFIG_CGROUPS and a non-zero user.max_cgroup_namespaces sysctl), or run the driver in rootful mode"),
+		Style:    style.Unsupported,
+	}
+	// the ISO of the node predates the porto packages
+	RuntimePortoISO = Kind{
+		ID:       "RUNTIME_PORTO_ISO",
+		ExitCode: ExRuntimeUnavailable,
+		Advice:   translate.T("Start with an ISO which carries porto: 'minikube delete --purge' drops the cached ISO, so that the next start downloads the one this minikube defaults to"),
+		Style:    style.Unsupported,
+	}
+	// the store of porto on the node is in a format the installed porto can't migrate
+	RuntimePortoStore = Kind{
+		ID:       "RUNTIME_PORTO_STORE",
+		ExitCode: ExRuntimeUnsupported,
+		Advice:   translate.T("Delete and recreate the cluster with 'minikube delete' and 'minikube start': porto then starts on an empty store, and the images of the cluster are loaded or pulled again"),
+		Style:    style.Unsupported,
+	}
+	// the container runtime of the cluster lacks a feature the command needs
+	RuntimeUnsupportedFeature = Kind{
+		ID:       "RUNTIME_UNSUPPORTED_FEATURE",
+		ExitCode: ExRuntimeUnsupported,
+		Advice:   translate.T("Use a cluster started with another --container-runtime, such as containerd or docker, for this command"),
+		Style:    style.Unsupported,
+	}
+	// the post-runtime hook of the user failed on the node
+	RuntimeHook = Kind{
+		ID:       "RUNTIME_HOOK",
+		ExitCode: ExRuntimeError,
+		Advice:   translate.T("Fix the script given with --post-runtime-hook, or start without it by running 'minikube start --post-runtime-hook=\"\"'"),
+	}
 	// minikube failed to start an ssh-agent when executing docker-env
 	SSHAgentStart = Kind{ID: "SSH_AGENT_START", ExitCode: ExRuntimeError}
 
@@ -467,6 +520,8 @@ var (
 	EnvMultiConflict = Kind{ID: "ENV_MULTINODE_CONFLICT", ExitCode: ExGuestConflict}
 	// the podman service was unavailable to the cluster
 	EnvPodmanUnavailable = Kind{ID: "ENV_PODMAN_UNAVAILABLE", ExitCode: ExRuntimeUnavailable}
+	// the porto service was unavailable to the cluster
+	EnvPortoUnavailable = Kind{ID: "ENV_PORTO_UNAVAILABLE", ExitCode: ExRuntimeUnavailable}
 
 	// user attempted to use an addon that is not supported
 	AddonUnsupported = Kind{ID: "SVC_ADDON_UNSUPPORTED", ExitCode: ExSvcUnsupported}
@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sbom is the inventory of the third party binaries the minikube ISO ships, such as porto and portoshim,
+// which hack/update keeps in step with the buildroot packages, rendered as SPDX or CycloneDX documents.
+package sbom
+
+import (
+	// for the embedded inventory
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SPDX is the format of SPDX 2.3 JSON documents
+	SPDX = "spdx"
+	// CycloneDX is the format of CycloneDX 1.5 JSON documents
+	CycloneDX = "cyclonedx"
+
+	// NoAssertion is the license of components whose license is unknown, as SPDX spells it
+	NoAssertion = "NOASSERTION"
+)
+
+// Formats are the document formats Render supports
+var Formats = []string{SPDX, CycloneDX}
+
+//go:embed components.json
+var components []byte
+
+// Component is a binary the ISO ships for an architecture
+type Component struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Arch is the GOARCH of the binary
+	Arch string `json:"arch"`
+	// Repo is the source repository of the binary
+	Repo string `json:"repo"`
+	// License is the SPDX license identifier of the source repository, or NoAssertion
+	License string `json:"license"`
+	// URL is where the ISO build downloads the binary from
+	URL string `json:"url"`
+	// SHA256 is the checksum the ISO build verifies the download against, empty while the hash file has none
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Components returns the inventory of the ISO of this minikube version
+func Components() ([]Component, error) {
+	return ParseComponents(components)
+}
+
+// ParseComponents parses an inventory
+func ParseComponents(b []byte) ([]Component, error) {
+	var cs []Component
+	if err := json.Unmarshal(b, &cs); err != nil {
+		return nil, errors.Wrap(err, "parsing components")
+	}
+	return cs, nil
+}
+
+// purl returns the package URL of c, which scanners match against vulnerability databases
+func (c Component) purl() string {
+	u, err := url.Parse(c.Repo)
+	if err != nil || u.Host != "github.com" {
+		return fmt.Sprintf("pkg:generic/%s@%s?arch=%s", c.Name, url.PathEscape(c.Version), c.Arch)
+	}
+	return fmt.Sprintf("pkg:github/%s@%s?arch=%s", strings.Trim(u.Path, "/"), url.PathEscape(c.Version), c.Arch)
+}
+
+// ref returns the identifier of c within a document
+func (c Component) ref() string {
+	return fmt.Sprintf("%s-%s", c.Name, c.Arch)
+}
+
+// Render returns the document in format describing cs, the ISO of minikube version, as of created
+func Render(format string, cs []Component, version string, created time.Time) ([]byte, error) {
+	var doc interface{}
+	switch format {
+	case SPDX:
+		doc = spdxDocument(cs, version, created)
+	case CycloneDX:
+		doc = cycloneDXDocument(cs, version, created)
+	default:
+		return nil, fmt.Errorf("unknown SBOM format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	Homepage         string            `json:"homepage"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+func spdxDocument(cs []Component, version string, created time.Time) spdxDoc {
+	doc := spdxDoc{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              "minikube-iso-" + version,
+		DocumentNamespace: "https://minikube.sigs.k8s.io/sbom/iso/" + url.PathEscape(version),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: minikube-" + version},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+	for _, c := range cs {
+		id := "SPDXRef-Package-" + c.ref()
+		p := spdxPackage{
+			Name:             c.Name,
+			SPDXID:           id,
+			VersionInfo:      c.Version,
+			DownloadLocation: c.URL,
+			Homepage:         c.Repo,
+			// the license of the source, the binaries were not analyzed
+			LicenseConcluded: NoAssertion,
+			LicenseDeclared:  c.License,
+			CopyrightText:    NoAssertion,
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.purl()}},
+		}
+		if c.SHA256 != "" {
+			p.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: c.SHA256}}
+		}
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: id})
+	}
+	return doc
+}
+
+type cdxLicense struct {
+	License struct {
+		ID string `json:"id"`
+	} `json:"license"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	Type               string         `json:"type"`
+	BOMRef             string         `json:"bom-ref,omitempty"`
+	Name               string         `json:"name"`
+	Version            string         `json:"version"`
+	PURL               string         `json:"purl,omitempty"`
+	Licenses           []cdxLicense   `json:"licenses,omitempty"`
+	Hashes             []cdxHash      `json:"hashes,omitempty"`
+	ExternalReferences []cdxReference `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty  `json:"properties,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxDoc struct {
+	BOMFormat   string         `json:"bomFormat"`
+	SpecVersion string         `json:"specVersion"`
+	Version     int            `json:"version"`
+	Metadata    cdxMetadata    `json:"metadata"`
+	Components  []cdxComponent `json:"components"`
+}
+
+func cycloneDXDocument(cs []Component, version string, created time.Time) cdxDoc {
+	doc := cdxDoc{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Component: cdxComponent{Type: "operating-system", Name: "minikube-iso", Version: version},
+		},
+		Components: []cdxComponent{},
+	}
+	for _, c := range cs {
+		cc := cdxComponent{
+			Type:    "application",
+			BOMRef:  c.ref(),
+			Name:    c.Name,
+			Version: c.Version,
+			PURL:    c.purl(),
+			ExternalReferences: []cdxReference{
+				{Type: "vcs", URL: c.Repo},
+				{Type: "distribution", URL: c.URL},
+			},
+			Properties: []cdxProperty{{Name: "minikube:arch", Value: c.Arch}},
+		}
+		if c.License != "" && c.License != NoAssertion {
+			l := cdxLicense{}
+			l.License.ID = c.License
+			cc.Licenses = []cdxLicense{l}
+		}
+		if c.SHA256 != "" {
+			cc.Hashes = []cdxHash{{Alg: "SHA-256", Content: c.SHA256}}
+		}
+		doc.Components = append(doc.Components, cc)
+	}
+	return doc
+}
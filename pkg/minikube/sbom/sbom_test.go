@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestComponents(t *testing.T) {
+	cs, err := Components()
+	if err != nil {
+		t.Fatalf("Components: %v", err)
+	}
+	if len(cs) == 0 {
+		t.Fatal("the inventory is empty")
+	}
+	seen := map[string]bool{}
+	for _, c := range cs {
+		if c.Name == "" || c.Version == "" || c.Arch == "" || c.Repo == "" || c.License == "" || c.URL == "" {
+			t.Errorf("component %+v lacks fields", c)
+		}
+		if c.SHA256 != "" && len(c.SHA256) != 64 {
+			t.Errorf("component %s has a malformed sha256 %q", c.ref(), c.SHA256)
+		}
+		if seen[c.ref()] {
+			t.Errorf("component %s is listed twice", c.ref())
+		}
+		seen[c.ref()] = true
+	}
+}
+
+var testComponents = []Component{
+	{
+		Name: "porto", Version: "v5.3.30", Arch: "amd64", Repo: "https://github.com/go-faster/porto", License: "MIT",
+		URL: "https://example.com/porto-v5.3.30.tgz", SHA256: "e64a55d5f44d4b3bc1111b20efb0b020154cfb7e7ae28213968db18c846596dd",
+	},
+	{
+		Name: "portoshim", Version: "v1.0.9", Arch: "arm64", Repo: "https://github.com/go-faster/portoshim", License: NoAssertion,
+		URL: "https://example.com/portoshim_focal_v1.0.9_arm64.tgz",
+	},
+}
+
+var created = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRenderSPDX(t *testing.T) {
+	b, err := Render(SPDX, testComponents, "v1.33.0", created)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	var doc spdxDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.CreationInfo.Created != "2024-06-01T12:00:00Z" {
+		t.Errorf("unexpected document header %+v", doc)
+	}
+	want := []spdxPackage{
+		{
+			Name: "porto", SPDXID: "SPDXRef-Package-porto-amd64", VersionInfo: "v5.3.30", DownloadLocation: "https://example.com/porto-v5.3.30.tgz",
+			Homepage: "https://github.com/go-faster/porto", LicenseConcluded: NoAssertion, LicenseDeclared: "MIT", CopyrightText: NoAssertion,
+			Checksums:    []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: "e64a55d5f44d4b3bc1111b20efb0b020154cfb7e7ae28213968db18c846596dd"}},
+			ExternalRefs: []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:github/go-faster/porto@v5.3.30?arch=amd64"}},
+		},
+		{
+			Name: "portoshim", SPDXID: "SPDXRef-Package-portoshim-arm64", VersionInfo: "v1.0.9", DownloadLocation: "https://example.com/portoshim_focal_v1.0.9_arm64.tgz",
+			Homepage: "https://github.com/go-faster/portoshim", LicenseConcluded: NoAssertion, LicenseDeclared: NoAssertion, CopyrightText: NoAssertion,
+			ExternalRefs: []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:github/go-faster/portoshim@v1.0.9?arch=arm64"}},
+		},
+	}
+	if diff := cmp.Diff(want, doc.Packages); diff != "" {
+		t.Errorf("packages diff (-want +got):\n%s", diff)
+	}
+	if len(doc.Relationships) != 2 || doc.Relationships[1].RelatedSPDXElement != "SPDXRef-Package-portoshim-arm64" {
+		t.Errorf("unexpected relationships %+v", doc.Relationships)
+	}
+}
+
+func TestRenderCycloneDX(t *testing.T) {
+	b, err := Render(CycloneDX, testComponents, "v1.33.0", created)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	var doc cdxDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || doc.Metadata.Component.Version != "v1.33.0" {
+		t.Errorf("unexpected document header %+v", doc)
+	}
+	if len(doc.Components) != 2 {
+		t.Fatalf("got %d components, want 2", len(doc.Components))
+	}
+	porto, portoshim := doc.Components[0], doc.Components[1]
+	if len(porto.Licenses) != 1 || porto.Licenses[0].License.ID != "MIT" || len(porto.Hashes) != 1 {
+		t.Errorf("porto lacks its license or hash: %+v", porto)
+	}
+	// unknown licenses and missing hashes are left out rather than made up
+	if len(portoshim.Licenses) != 0 || len(portoshim.Hashes) != 0 {
+		t.Errorf("portoshim has a license or hash it should not: %+v", portoshim)
+	}
+	if portoshim.PURL != "pkg:github/go-faster/portoshim@v1.0.9?arch=arm64" {
+		t.Errorf("portoshim purl = %q", portoshim.PURL)
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, err := Render("swid", testComponents, "v1.33.0", created); err == nil {
+		t.Error("Render of an unknown format succeeded")
+	}
+}
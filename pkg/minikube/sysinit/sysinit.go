@@ -98,3 +98,14 @@ func New(r Runner) Manager {
 	}
 	return &OpenRC{r: r}
 }
+
+// WithRunner returns a manager of the same init system as m which runs its commands with r
+func WithRunner(m Manager, r Runner) Manager {
+	switch m.(type) {
+	case *Systemd:
+		return &Systemd{r: r}
+	case *OpenRC:
+		return &OpenRC{r: r}
+	}
+	return m
+}
@@ -31,6 +31,10 @@ const (
 	GuestCertAuthDir = "/usr/share/ca-certificates"
 	// GuestCertStoreDir is where system SSL certificates are installed
 	GuestCertStoreDir = "/etc/ssl/certs"
+	// GuestImageCacheDir is where the image cache of the host is mounted when it is shared with the node
+	GuestImageCacheDir = GuestPersistentDir + "/image-cache"
+	// GuestBuildCacheDir is where image builds cache their layers, on the persistent disk so that it survives restarts
+	GuestBuildCacheDir = GuestPersistentDir + "/build-cache"
 	// GuestGvisorDir is where gvisor bootstraps from
 	GuestGvisorDir = "/tmp/gvisor"
 )
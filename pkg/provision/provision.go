@@ -230,7 +230,7 @@ func setContainerRuntimeOptions(name string, p miniProvisioner) error {
 	switch c.KubernetesConfig.ContainerRuntime {
 	case "crio", "cri-o":
 		return setCrioOptions(p)
-	case "containerd":
+	case "containerd", "porto":
 		return nil
 	default:
 		_, err := p.GenerateDockerOptions(engine.DefaultPort)
@@ -0,0 +1,131 @@
+//go:build integration
+
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+	"k8s.io/minikube/pkg/util/retry"
+)
+
+// chaosServices are the services --runtime-chaos restarts
+var chaosServices = []string{"porto", "portoshim"}
+
+// startRuntimeChaos restarts portod or portoshim of the profile at random, every --runtime-chaos on average,
+// until the returned function is called or the test ends. That function then checks that the cluster recovered from the restarts.
+// Nothing is restarted unless --runtime-chaos is set and the container runtime is porto.
+func startRuntimeChaos(ctx context.Context, t *testing.T, profile string) (stop func()) {
+	if *runtimeChaosInterval <= 0 {
+		return func() {}
+	}
+	if ContainerRuntime() != constants.Porto {
+		t.Logf("skipping --runtime-chaos: only restarts porto, got %q", ContainerRuntime())
+		return func() {}
+	}
+
+	seed := *runtimeChaosSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Logf("restarting the container runtime every %s on average, reproduce with --runtime-chaos-seed=%d", *runtimeChaosInterval, seed)
+	rnd := rand.New(rand.NewSource(seed))
+
+	chaosCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	restarts := 0
+	go func() {
+		defer close(done)
+		for {
+			// exponentially distributed, so that restarts sometimes come in quick succession
+			wait := time.Duration(rnd.ExpFloat64() * float64(*runtimeChaosInterval))
+			select {
+			case <-chaosCtx.Done():
+				return
+			case <-time.After(wait):
+			}
+			svc := chaosServices[rnd.Intn(len(chaosServices))]
+			rr, err := Run(t, exec.CommandContext(chaosCtx, Target(), "-p", profile, "ssh", "sudo systemctl restart "+svc))
+			if err != nil {
+				if chaosCtx.Err() == nil {
+					t.Logf("failed to restart %s: args %q: %v", svc, rr.Command(), err)
+				}
+				continue
+			}
+			restarts++
+		}
+	}()
+
+	var once sync.Once
+	halt := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			t.Logf("restarted the container runtime %d times", restarts)
+			// a restart cut short by the cancellation may leave the service stopped, and ctx may be done by now
+			startCtx, cancel := context.WithTimeout(context.Background(), Minutes(1))
+			defer cancel()
+			for _, svc := range chaosServices {
+				if rr, err := Run(t, exec.CommandContext(startCtx, Target(), "-p", profile, "ssh", "sudo systemctl start "+svc)); err != nil {
+					t.Errorf("failed to start %s after the runtime chaos: args %q: %v", svc, rr.Command(), err)
+				}
+			}
+		})
+	}
+	// the runtime is left running for later tests even when the test fails before it stops the chaos
+	t.Cleanup(halt)
+
+	return func() {
+		halt()
+		validateRuntimeRecovery(ctx, t, profile)
+	}
+}
+
+// validateRuntimeRecovery makes sure the cluster is healthy again after restarts of the container runtime
+func validateRuntimeRecovery(ctx context.Context, t *testing.T, profile string) {
+	defer PostMortemLogs(t, profile)
+
+	// docs: Run `minikube status` until the kubelet and the apiserver are reported as Running again
+	status := func() error {
+		for _, key := range []string{"Host", "Kubelet", "APIServer"} {
+			if got := Status(ctx, t, Target(), profile, key, profile); got != "Running" {
+				return fmt.Errorf("%s is %q, want Running", key, got)
+			}
+		}
+		return nil
+	}
+	if err := retry.Expo(status, 2*time.Second, Minutes(5)); err != nil {
+		t.Fatalf("cluster did not recover from container runtime restarts: %v", err)
+	}
+
+	// docs: Make sure the node is Ready and the kube-system pods are running, as kubelet recreates what the restarts killed
+	rr, err := Run(t, exec.CommandContext(ctx, "kubectl", "--context", profile, "wait", "--for=condition=Ready", "node", "--all", "--timeout=5m"))
+	if err != nil {
+		t.Fatalf("node did not become Ready after container runtime restarts: args %q: %v", rr.Command(), err)
+	}
+	if _, err := PodWait(ctx, t, profile, "kube-system", "tier=control-plane", Minutes(5)); err != nil {
+		t.Errorf("control plane pods did not recover from container runtime restarts: %v", err)
+	}
+}
@@ -130,7 +130,8 @@ func TestFunctional(t *testing.T) {
 		}
 	}()
 
-	// Parallelized tests
+	// Parallelized tests, during which --runtime-chaos restarts the container runtime
+	stopChaos := startRuntimeChaos(ctx, t, profile)
 	t.Run("parallel", func(t *testing.T) {
 		tests := []struct {
 			name      string
@@ -174,7 +175,7 @@ func TestFunctional(t *testing.T) {
 			})
 		}
 	})
-
+	stopChaos()
 }
 
 func cleanupUnwantedImages(ctx context.Context, t *testing.T, profile string) {
@@ -231,12 +232,16 @@ func validateNodeLabels(ctx context.Context, t *testing.T, profile string) {
 // tagAndLoadImage is a helper function to pull, tag, load image (decreases cyclomatic complexity for linter).
 func tagAndLoadImage(ctx context.Context, t *testing.T, profile, taggedImage string) {
 	newPulledImage := fmt.Sprintf("%s:%s", addonResizer, "1.8.9")
-	rr, err := Run(t, exec.CommandContext(ctx, "docker", "pull", newPulledImage))
-	if err != nil {
-		t.Fatalf("failed to setup test (pull image): %v\n%s", err, rr.Output())
+	if HermeticImages() {
+		dockerLoadImageFixture(ctx, t, newPulledImage)
+	} else {
+		rr, err := Run(t, exec.CommandContext(ctx, "docker", "pull", newPulledImage))
+		if err != nil {
+			t.Fatalf("failed to setup test (pull image): %v\n%s", err, rr.Output())
+		}
 	}
 
-	rr, err = Run(t, exec.CommandContext(ctx, "docker", "tag", newPulledImage, taggedImage))
+	rr, err := Run(t, exec.CommandContext(ctx, "docker", "tag", newPulledImage, taggedImage))
 	if err != nil {
 		t.Fatalf("failed to setup test (tag image) : %v\n%s", err, rr.Output())
 	}
@@ -337,6 +342,12 @@ func validateImageCommands(ctx context.Context, t *testing.T, profile string) {
 			t.Fatalf("failed to get absolute path of file %q: %v", imageFile, err)
 		}
 
+		// the image is loaded into the docker daemon of the host, which ImageLoadDaemon loads it from
+		if HermeticImages() {
+			dockerLoadImageFixture(ctx, t, taggedImage)
+			return
+		}
+
 		pulledImage := fmt.Sprintf("%s:%s", addonResizer, "1.8.8")
 		rr, err := Run(t, exec.CommandContext(ctx, "docker", "pull", pulledImage))
 		if err != nil {
@@ -0,0 +1,104 @@
+//go:build integration
+
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// HermeticImages returns whether image tests should use generated image fixtures instead of pulling images
+// Runtimes without a docker daemon on the host side, such as porto, are tested this way so the tests work offline.
+func HermeticImages() bool {
+	return ContainerRuntime() == constants.Porto
+}
+
+// writeImageFixture writes a docker-archive tarball of a tiny image tagged as ref into dir and returns its path.
+// The image only depends on ref, so every run produces the same digest.
+func writeImageFixture(t *testing.T, ref string, dir string) string {
+	t.Helper()
+
+	tag, err := name.NewTag(ref)
+	if err != nil {
+		t.Fatalf("failed to parse fixture reference %q: %v", ref, err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte(ref + "\n")
+	hdr := &tar.Header{
+		Name:     "fixture",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Unix(0, 0),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("failed to write fixture layer header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("failed to write fixture layer: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close fixture layer: %v", err)
+	}
+
+	img, err := mutate.AppendLayers(empty.Image, static.NewLayer(buf.Bytes(), types.DockerLayer))
+	if err != nil {
+		t.Fatalf("failed to create fixture image: %v", err)
+	}
+	img, err = mutate.CreatedAt(img, v1.Time{Time: time.Unix(0, 0)})
+	if err != nil {
+		t.Fatalf("failed to set fixture creation time: %v", err)
+	}
+
+	path := filepath.Join(dir, strings.NewReplacer("/", "_", ":", "_").Replace(ref)+".tar")
+	if err := tarball.WriteToFile(path, tag, img); err != nil {
+		t.Fatalf("failed to write fixture %q: %v", path, err)
+	}
+	return path
+}
+
+// dockerLoadImageFixture generates the fixture image ref and loads it into the docker daemon of the host with
+// `docker load`, for the tests of `minikube image load --daemon` and `minikube image save --daemon`
+func dockerLoadImageFixture(ctx context.Context, t *testing.T, ref string) {
+	t.Helper()
+
+	fixture := writeImageFixture(t, ref, t.TempDir())
+	rr, err := Run(t, exec.CommandContext(ctx, "docker", "load", "-i", fixture))
+	if err != nil {
+		t.Fatalf("loading image fixture into docker: %v\n%s", err, rr.Output())
+	}
+}
@@ -0,0 +1,293 @@
+//go:build integration
+
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// imageParityRuntimes are the container runtimes TestImageParity compares, the first one being the reference
+var imageParityRuntimes = []string{constants.Docker, constants.Containerd, constants.CRIO, constants.Porto}
+
+// imageParityGaps lists, per runtime, the `minikube image` steps known not to work yet and why.
+// TestImageParity expects these to fail, and fails once one of them works, so remove the entry as the runtime catches up.
+var imageParityGaps = map[string]map[string]string{}
+
+// Images handled by TestImageParity, the only ones whose presence is compared across runtimes
+const (
+	parityFixture = "docker.io/minikube/parity:fixture"
+	parityTagged  = "docker.io/minikube/parity:tagged"
+	parityBuilt   = "docker.io/minikube/parity:built"
+	parityPulled  = "docker.io/kicbase/echo-server:1.0"
+	parityPushed  = "localhost:5000/minikube/parity:pushed"
+)
+
+// notImplemented matches the error of a runtime method that was never written
+var notImplemented = regexp.MustCompile(`(?i)not (yet )?implemented`)
+
+// imageParityStep is a `minikube image` subcommand run by TestImageParity, along with the commands it depends on
+type imageParityStep struct {
+	name string
+	// args returns the minikube commands of the step, given a directory shared by the steps of a profile
+	args func(dir string) [][]string
+	// check verifies the output of the last command, beyond its exit code
+	check func(dir string, output string) error
+}
+
+// imageParitySteps are run in order against a fresh cluster of every runtime
+var imageParitySteps = []imageParityStep{
+	{
+		name: "load",
+		args: func(dir string) [][]string { return [][]string{{"image", "load", filepath.Join(dir, "fixture.tar")}} },
+	},
+	{
+		name: "tag",
+		args: func(string) [][]string { return [][]string{{"image", "tag", parityFixture, parityTagged}} },
+	},
+	{
+		name: "ls",
+		args: func(string) [][]string {
+			var cmds [][]string
+			for _, format := range []string{"short", "table", "json", "yaml"} {
+				cmds = append(cmds, []string{"image", "ls", "--format", format})
+			}
+			return cmds
+		},
+		check: func(_ string, output string) error {
+			if !strings.Contains(output, "minikube/parity") {
+				return fmt.Errorf("expected the listed images to include minikube/parity")
+			}
+			return nil
+		},
+	},
+	{
+		name: "save",
+		args: func(dir string) [][]string {
+			return [][]string{{"image", "save", parityTagged, filepath.Join(dir, "saved.tar")}}
+		},
+		check: func(dir string, _ string) error {
+			fi, err := os.Stat(filepath.Join(dir, "saved.tar"))
+			if err != nil {
+				return err
+			}
+			if fi.Size() == 0 {
+				return fmt.Errorf("%s is empty", fi.Name())
+			}
+			return nil
+		},
+	},
+	{
+		name: "rm",
+		args: func(string) [][]string { return [][]string{{"image", "rm", parityTagged}} },
+	},
+	{
+		name: "load-saved",
+		args: func(dir string) [][]string { return [][]string{{"image", "load", filepath.Join(dir, "saved.tar")}} },
+	},
+	{
+		name: "pull",
+		args: func(string) [][]string { return [][]string{{"image", "pull", parityPulled}} },
+	},
+	{
+		name: "build",
+		args: func(string) [][]string {
+			return [][]string{{"image", "build", "-t", parityBuilt, "./testdata/image-build/test-normal"}}
+		},
+	},
+	{
+		name: "push",
+		args: func(string) [][]string {
+			return [][]string{{"image", "tag", parityFixture, parityPushed}, {"image", "push", parityPushed}}
+		},
+	},
+}
+
+// imageParityResult is what a user sees of a step: whether it worked, and which images it added or removed.
+// Changes are compared rather than whole listings, so a known gap does not show up again in every later step.
+type imageParityResult struct {
+	OK      bool
+	Added   []string
+	Removed []string
+}
+
+// TestImageParity runs every `minikube image` subcommand against every container runtime and compares what the user sees
+func TestImageParity(t *testing.T) {
+	// docs(skip): Skips on `none` driver as image commands are not supported
+	if NoneDriver() {
+		t.Skip("image commands are not available on the none driver")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(40))
+	defer cancel()
+
+	var mu sync.Mutex
+	results := map[string]map[string]imageParityResult{}
+
+	t.Run("group", func(t *testing.T) {
+		for _, rt := range imageParityRuntimes {
+			rt := rt
+			t.Run(rt, func(t *testing.T) {
+				MaybeParallel(t)
+				r := validateImageParity(ctx, t, rt)
+				mu.Lock()
+				results[rt] = r
+				mu.Unlock()
+			})
+		}
+	})
+
+	ref := imageParityRuntimes[0]
+	if results[ref] == nil {
+		t.Fatalf("no results from the %s reference run", ref)
+	}
+	for _, rt := range imageParityRuntimes[1:] {
+		if results[rt] == nil {
+			continue
+		}
+		for _, step := range imageParitySteps {
+			if _, ok := imageParityGaps[rt][step.name]; ok {
+				continue
+			}
+			want, got := results[ref][step.name], results[rt][step.name]
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("`minikube image %s` behaves differently on %s than on %s (-%s +%s):\n%s", step.name, rt, ref, ref, rt, diff)
+			}
+		}
+	}
+}
+
+// validateImageParity starts a cluster with the given runtime and runs the steps against it
+func validateImageParity(ctx context.Context, t *testing.T, rt string) map[string]imageParityResult {
+	profile := UniqueProfileName("image-" + rt)
+	defer CleanupWithLogs(t, profile, func() {})
+
+	args := []string{"start", "-p", profile, "--container-runtime=" + rt, "--addons=registry", "--wait=true"}
+	for _, a := range StartArgs() {
+		if !strings.HasPrefix(a, "--container-runtime=") {
+			args = append(args, a)
+		}
+	}
+	if rr, err := Run(t, exec.CommandContext(ctx, Target(), args...)); err != nil {
+		t.Fatalf("failed to start minikube with args: %q : %v", rr.Command(), err)
+	}
+
+	dir := t.TempDir()
+	fixture := writeImageFixture(t, parityFixture, dir)
+	if err := os.Rename(fixture, filepath.Join(dir, "fixture.tar")); err != nil {
+		t.Fatalf("failed to rename image fixture: %v", err)
+	}
+
+	results := map[string]imageParityResult{}
+	before := parityImages(ctx, t, profile)
+	for _, step := range imageParitySteps {
+		gap, isGap := imageParityGaps[rt][step.name]
+
+		ok, output := true, ""
+		for _, a := range step.args(dir) {
+			rr, err := Run(t, exec.CommandContext(ctx, Target(), append(append([]string{"-p", profile}, a...), "--alsologtostderr")...))
+			output = rr.Output()
+			if err != nil {
+				ok = false
+				if !isGap {
+					t.Errorf("%s failed on %s: %v\n%s", rr.Command(), rt, err, output)
+				}
+				break
+			}
+		}
+		if ok && step.check != nil {
+			if err := step.check(dir, output); err != nil {
+				ok = false
+				if !isGap {
+					t.Errorf("`minikube image %s` on %s: %v", step.name, rt, err)
+				}
+			}
+		}
+
+		switch {
+		case !isGap && notImplemented.MatchString(output):
+			t.Errorf("`minikube image %s` hits a path that is not implemented on %s; implement it or list it in imageParityGaps", step.name, rt)
+		case isGap && ok:
+			t.Errorf("`minikube image %s` works on %s now, remove it from imageParityGaps (%s)", step.name, rt, gap)
+		case isGap:
+			t.Logf("`minikube image %s` is a known gap on %s: %s", step.name, rt, gap)
+		}
+
+		after := parityImages(ctx, t, profile)
+		results[step.name] = imageParityResult{OK: ok, Added: missingImages(after, before), Removed: missingImages(before, after)}
+		before = after
+	}
+	return results
+}
+
+// parityImages returns the images handled by TestImageParity present in the cluster, normalized across runtimes
+func parityImages(ctx context.Context, t *testing.T, profile string) []string {
+	t.Helper()
+
+	rr, err := Run(t, exec.CommandContext(ctx, Target(), "-p", profile, "image", "ls", "--format", "short"))
+	if err != nil {
+		t.Errorf("listing images: %v\n%s", err, rr.Output())
+		return nil
+	}
+	want := map[string]bool{}
+	for _, ref := range []string{parityFixture, parityTagged, parityBuilt, parityPulled, parityPushed} {
+		want[normalizeImage(ref)] = true
+	}
+	images := []string{}
+	for _, line := range strings.Split(rr.Stdout.String(), "\n") {
+		if img := normalizeImage(strings.TrimSpace(line)); want[img] {
+			images = append(images, img)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// missingImages returns the images of a that are not in b
+func missingImages(a, b []string) []string {
+	missing := []string{}
+	for _, img := range a {
+		if i := sort.SearchStrings(b, img); i == len(b) || b[i] != img {
+			missing = append(missing, img)
+		}
+	}
+	return missing
+}
+
+// normalizeImage returns the canonical form of an image reference, so the spellings of the runtimes compare equal
+func normalizeImage(ref string) string {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return ref
+	}
+	return r.Name()
+}
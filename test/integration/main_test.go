@@ -43,6 +43,10 @@ var enableGvisor = flag.Bool("gvisor", false, "run gvisor integration test (slow
 var postMortemLogs = flag.Bool("postmortem-logs", true, "show logs after a failed test run")
 var timeOutMultiplier = flag.Float64("timeout-multiplier", 1, "multiply the timeout for the tests")
 
+// Flags for testing the recovery from container runtime restarts
+var runtimeChaosInterval = flag.Duration("runtime-chaos", 0, "restart portod or portoshim at random, every given duration on average, while the parallel functional tests run (0 disables)")
+var runtimeChaosSeed = flag.Int64("runtime-chaos-seed", 0, "seed of the --runtime-chaos restarts, to reproduce a run (0 picks one)")
+
 // Paths to files - normally set for CI
 var binaryPath = flag.String("binary", "../../out/minikube", "path to minikube binary")
 var testdataDir = flag.String("testdata-dir", "testdata", "the directory relative to test/integration where the testdata lives")
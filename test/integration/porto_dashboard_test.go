@@ -0,0 +1,207 @@
+//go:build integration
+
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-retryablehttp"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// dashboardTerminalMessage is a message of the exec terminal protocol of the dashboard
+type dashboardTerminalMessage struct {
+	Op        string
+	Data      string `json:",omitempty"`
+	SessionID string `json:",omitempty"`
+}
+
+// TestPortoDashboardExec checks that the exec terminal of the dashboard works on the porto container runtime,
+// where the streams go from the API server through kubelet to the streaming server of portoshim
+func TestPortoDashboardExec(t *testing.T) {
+	if NoneDriver() {
+		t.Skipf("skipping: none driver does not support porto")
+	}
+	if ContainerRuntime() != constants.Porto {
+		t.Skipf("skipping: only runs with --container-runtime=porto, got %q", ContainerRuntime())
+	}
+
+	MaybeParallel(t)
+	profile := UniqueProfileName("porto-dashboard")
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(20))
+	defer Cleanup(t, profile, cancel)
+
+	t.Run("StartCluster", func(t *testing.T) {
+		args := append([]string{"start", "-p", profile, "--memory=2200", "--wait=true", "--alsologtostderr", "-v=5"}, StartArgs()...)
+		rr, err := Run(t, exec.CommandContext(ctx, Target(), args...))
+		if err != nil {
+			t.Fatalf("failed to start minikube with args: %q : %v", rr.Command(), err)
+		}
+	})
+
+	t.Run("serial", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			validator validateFunc
+		}{
+			{"DeployBusybox", validatePortoDeployBusybox},
+			{"KubectlExec", validatePortoKubectlExec},
+			{"DashboardExec", validatePortoDashboardExec},
+		}
+		for _, tc := range tests {
+			tc := tc
+			if ctx.Err() == context.DeadlineExceeded {
+				t.Fatalf("Unable to run more tests (deadline exceeded)")
+			}
+			t.Run(tc.name, func(t *testing.T) {
+				tc.validator(ctx, t, profile)
+			})
+		}
+	})
+}
+
+// validatePortoDeployBusybox deploys the busybox pod the exec tests run commands in
+func validatePortoDeployBusybox(ctx context.Context, t *testing.T, profile string) {
+	defer PostMortemLogs(t, profile)
+
+	rr, err := Run(t, exec.CommandContext(ctx, "kubectl", "--context", profile, "create", "-f", filepath.Join(*testdataDir, "busybox.yaml")))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	if _, err := PodWait(ctx, t, profile, "default", "integration-test=busybox", Minutes(8)); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+}
+
+// validatePortoKubectlExec runs a command in the busybox pod with kubectl exec, which uses the same streams as the dashboard
+func validatePortoKubectlExec(ctx context.Context, t *testing.T, profile string) {
+	defer PostMortemLogs(t, profile)
+
+	// docs: Run `kubectl exec` in the busybox pod
+	rr, err := Run(t, exec.CommandContext(ctx, "kubectl", "--context", profile, "exec", "busybox", "--", "echo", "porto-kubectl-exec"))
+	if err != nil {
+		t.Fatalf("%s failed: %v", rr.Command(), err)
+	}
+	// docs: Make sure the output of the command comes back
+	if !strings.Contains(rr.Stdout.String(), "porto-kubectl-exec") {
+		t.Errorf("expected the output of %q to contain %q, got %q", rr.Command(), "porto-kubectl-exec", rr.Stdout.String())
+	}
+}
+
+// validatePortoDashboardExec runs a command in the busybox pod through the exec terminal of the dashboard
+func validatePortoDashboardExec(ctx context.Context, t *testing.T, profile string) {
+	defer PostMortemLogs(t, profile)
+
+	mctx, cancel := context.WithTimeout(ctx, Seconds(300))
+	defer cancel()
+
+	// docs: Run `minikube dashboard --url` to start the dashboard proxy
+	args := []string{"dashboard", "--url", "--port", "36196", "-p", profile, "--alsologtostderr", "-v=1"}
+	ss, err := Start(t, exec.CommandContext(mctx, Target(), args...))
+	if err != nil {
+		t.Fatalf("failed to run minikube dashboard. args %q : %v", args, err)
+	}
+	defer ss.Stop(t)
+
+	s, err := dashboardURL(ss.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", s, err)
+	}
+
+	// docs: Open a terminal session to the busybox container through the dashboard API
+	shell := u.JoinPath("api/v1/pod/default/busybox/shell/busybox")
+	resp, err := retryablehttp.Get(shell.String())
+	if err != nil {
+		t.Fatalf("failed to http get %q: %v", shell, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to read http response body from %q: %v", shell, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s returned status code %d, expected %d.\nbody:\n%s", shell, resp.StatusCode, http.StatusOK, body)
+	}
+	var session struct{ ID string }
+	if err := json.Unmarshal(body, &session); err != nil || session.ID == "" {
+		t.Fatalf("no terminal session in the response of %s: %v\nbody:\n%s", shell, err, body)
+	}
+
+	// docs: Connect to the session over the websocket of the dashboard, and run a command in it
+	ws := u.JoinPath("api/sockjs/websocket")
+	ws.Scheme = "ws"
+	conn, _, err := websocket.DefaultDialer.DialContext(mctx, ws.String(), nil)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", ws, err)
+	}
+	defer conn.Close()
+
+	for _, m := range []dashboardTerminalMessage{
+		{Op: "bind", SessionID: session.ID},
+		{Op: "stdin", Data: "echo porto-dashboard-$((6*7))\n"},
+	} {
+		if err := writeTerminalMessage(conn, m); err != nil {
+			t.Fatalf("failed to send %s to %s: %v", m.Op, ws, err)
+		}
+	}
+
+	// docs: Make sure the output of the command comes back through the terminal
+	// the shell echoes its input, so look for the evaluated expression rather than the command
+	want := "porto-dashboard-42"
+	var output strings.Builder
+	deadline := time.Now().Add(Seconds(60))
+	for !strings.Contains(output.String(), want) {
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			t.Fatalf("failed to set the read deadline: %v", err)
+		}
+		var m dashboardTerminalMessage
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("failed to read from %s: %v\noutput so far:\n%s", ws, err, output.String())
+		}
+		if m.Op == "toast" {
+			t.Fatalf("dashboard terminal error: %s\noutput so far:\n%s", m.Data, output.String())
+		}
+		if m.Op == "stdout" {
+			output.WriteString(m.Data)
+		}
+	}
+}
+
+// writeTerminalMessage sends a message of the exec terminal protocol of the dashboard, which sockjs carries as text
+func writeTerminalMessage(conn *websocket.Conn, m dashboardTerminalMessage) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
@@ -0,0 +1,70 @@
+//go:build integration
+
+/*
+Copyright 2024 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// TestPortoIngress is a smoke test of the ingress addon on the porto container runtime, where the controller binds to the node network
+func TestPortoIngress(t *testing.T) {
+	if NoneDriver() {
+		t.Skipf("skipping: none driver does not support ingress")
+	}
+	if ContainerRuntime() != constants.Porto {
+		t.Skipf("skipping: only runs with --container-runtime=porto, got %q", ContainerRuntime())
+	}
+
+	MaybeParallel(t)
+	profile := UniqueProfileName("porto-ingress")
+	ctx, cancel := context.WithTimeout(context.Background(), Minutes(15))
+	defer Cleanup(t, profile, cancel)
+
+	t.Run("StartCluster", func(t *testing.T) {
+		args := append([]string{"start", "-p", profile, "--memory=4096", "--wait=true", "--alsologtostderr", "-v=5"}, StartArgs()...)
+		rr, err := Run(t, exec.CommandContext(ctx, Target(), args...))
+		if err != nil {
+			t.Fatalf("failed to start minikube with args: %q : %v", rr.Command(), err)
+		}
+	})
+
+	t.Run("serial", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			validator validateFunc
+		}{
+			{"ValidateIngressAddonActivation", validateIngressAddonActivation},
+			{"ValidateIngressDNSAddonActivation", validateIngressDNSAddonActivation},
+			{"ValidateIngressAddons", validateIngressAddon},
+		}
+		for _, tc := range tests {
+			tc := tc
+			if ctx.Err() == context.DeadlineExceeded {
+				t.Fatalf("Unable to run more tests (deadline exceeded)")
+			}
+			t.Run(tc.name, func(t *testing.T) {
+				tc.validator(ctx, t, profile)
+			})
+		}
+	})
+}